@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -35,34 +36,49 @@ func NewFileHashManager(cacheDir string) (*FileHashManager, error) {
 
 // Load loads the file hash cache for a repository
 func (fhm *FileHashManager) Load(repoPath string) error {
+	cache, err := fhm.LoadCache(repoPath)
+	if err != nil {
+		return err
+	}
+
 	fhm.mux.Lock()
 	defer fhm.mux.Unlock()
+	fhm.cache = cache
+	return nil
+}
 
+// LoadCache reads the file hash cache for a repository without loading it into the manager
+// The returned cache is a private copy, so reading it never disturbs a job using the manager.
+func (fhm *FileHashManager) LoadCache(repoPath string) (*models.FileHashCache, error) {
 	cachePath := fhm.getCachePath(repoPath)
 
 	// If cache file doesn't exist, create new cache
 	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
-		fhm.cache = &models.FileHashCache{
+		return &models.FileHashCache{
 			RepoPath:  repoPath,
 			Hashes:    make(map[string]models.FileHash),
 			UpdatedAt: time.Now(),
-		}
-		return nil
+		}, nil
 	}
 
 	// Read existing cache
 	data, err := os.ReadFile(cachePath)
 	if err != nil {
-		return fmt.Errorf("failed to read cache file: %w", err)
+		return nil, fmt.Errorf("failed to read cache file: %w", err)
 	}
 
 	var cache models.FileHashCache
 	if err := json.Unmarshal(data, &cache); err != nil {
-		return fmt.Errorf("failed to parse cache file: %w", err)
+		return nil, fmt.Errorf("failed to parse cache file: %w", err)
 	}
 
-	fhm.cache = &cache
-	return nil
+	return &cache, nil
+}
+
+// Detached returns a manager for the same cache directory with nothing loaded
+// Its state is separate from fhm's, so loading a cache into it never disturbs fhm.
+func (fhm *FileHashManager) Detached() *FileHashManager {
+	return &FileHashManager{cacheDir: fhm.cacheDir}
 }
 
 // Save saves the file hash cache
@@ -82,13 +98,18 @@ func (fhm *FileHashManager) Save() error {
 	fhm.mux.RUnlock()
 
 	cacheCopy.UpdatedAt = time.Now()
+	return fhm.SaveCache(&cacheCopy)
+}
 
-	data, err := json.MarshalIndent(cacheCopy, "", "  ")
+// SaveCache writes a file hash cache for its repository without loading it into the manager
+// Used to restore a cache read with LoadCache, e.g. from an index export.
+func (fhm *FileHashManager) SaveCache(cache *models.FileHashCache) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal cache: %w", err)
 	}
 
-	cachePath := fhm.getCachePath(cacheCopy.RepoPath)
+	cachePath := fhm.getCachePath(cache.RepoPath)
 	if err := os.WriteFile(cachePath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write cache file: %w", err)
 	}
@@ -112,22 +133,27 @@ func (fhm *FileHashManager) NeedsReindex(filePath string) (bool, error) {
 		return false, fmt.Errorf("failed to compute file hash: %w", err)
 	}
 
-	// Check if file exists in cache with a single lock acquisition
+	return fhm.NeedsReindexHash(filePath, currentHash), nil
+}
+
+// NeedsReindexHash returns true if a file whose current content hashes to hash
+// (see HashContent) differs from the cached version
+// Thread-safe: uses read lock for concurrent access
+func (fhm *FileHashManager) NeedsReindexHash(filePath, hash string) bool {
 	fhm.mux.RLock()
 	defer fhm.mux.RUnlock()
 
-	// Re-check cache validity after expensive operation
 	if fhm.cache == nil {
-		return true, nil // Cache was cleared, reindex everything
+		return true // No cache loaded (or cache cleared), reindex everything
 	}
 
 	cached, exists := fhm.cache.Hashes[filePath]
 	if !exists {
-		return true, nil // New file
+		return true // New file
 	}
 
 	// Compare hashes
-	return cached.Hash != currentHash, nil
+	return cached.Hash != hash
 }
 
 // Update updates the hash for a file
@@ -139,6 +165,12 @@ func (fhm *FileHashManager) Update(filePath string, chunkCount int) error {
 		return fmt.Errorf("failed to compute file hash: %w", err)
 	}
 
+	return fhm.UpdateHash(filePath, hash, chunkCount)
+}
+
+// UpdateHash records a file's content hash (see HashContent) without reading the file
+// Thread-safe: uses write lock for concurrent access
+func (fhm *FileHashManager) UpdateHash(filePath, hash string, chunkCount int) error {
 	fhm.mux.Lock()
 	defer fhm.mux.Unlock()
 
@@ -152,10 +184,28 @@ func (fhm *FileHashManager) Update(filePath string, chunkCount int) error {
 		LastIndexed: time.Now(),
 		ChunkCount:  chunkCount,
 	}
+	delete(fhm.cache.Unindexed, filePath)
 
 	return nil
 }
 
+// MarkUnindexed records that a file was skipped or failed to index just now
+// Its hash entry, if any, is kept so the file is still reindexed on the next run, but
+// FindStaleFile no longer reports it until it is modified again.
+// Thread-safe: uses write lock for concurrent access
+func (fhm *FileHashManager) MarkUnindexed(filePath string) {
+	fhm.mux.Lock()
+	defer fhm.mux.Unlock()
+
+	if fhm.cache == nil {
+		return
+	}
+	if fhm.cache.Unindexed == nil {
+		fhm.cache.Unindexed = make(map[string]time.Time)
+	}
+	fhm.cache.Unindexed[filePath] = time.Now()
+}
+
 // Remove removes a file from the cache
 // Thread-safe: uses write lock for concurrent access
 func (fhm *FileHashManager) Remove(filePath string) {
@@ -164,7 +214,275 @@ func (fhm *FileHashManager) Remove(filePath string) {
 
 	if fhm.cache != nil {
 		delete(fhm.cache.Hashes, filePath)
+		delete(fhm.cache.Unindexed, filePath)
+	}
+}
+
+// Rename moves the cache entry for oldPath to newPath, keeping its hash and chunk count
+// Thread-safe: uses write lock for concurrent access
+func (fhm *FileHashManager) Rename(oldPath, newPath string) {
+	fhm.mux.Lock()
+	defer fhm.mux.Unlock()
+
+	if fhm.cache == nil {
+		return
 	}
+
+	entry, ok := fhm.cache.Hashes[oldPath]
+	if !ok {
+		return
+	}
+
+	delete(fhm.cache.Hashes, oldPath)
+	entry.Path = newPath
+	fhm.cache.Hashes[newPath] = entry
+}
+
+// IndexedFiles returns the set of file paths currently in the cache
+// Thread-safe: uses read lock for concurrent access
+func (fhm *FileHashManager) IndexedFiles() map[string]bool {
+	fhm.mux.RLock()
+	defer fhm.mux.RUnlock()
+
+	files := make(map[string]bool)
+	if fhm.cache == nil {
+		return files
+	}
+	for path := range fhm.cache.Hashes {
+		files[path] = true
+	}
+	return files
+}
+
+// Lookup returns the cache entry for a file
+// Thread-safe: uses read lock for concurrent access
+func (fhm *FileHashManager) Lookup(filePath string) (models.FileHash, bool) {
+	fhm.mux.RLock()
+	defer fhm.mux.RUnlock()
+
+	if fhm.cache == nil {
+		return models.FileHash{}, false
+	}
+	entry, ok := fhm.cache.Hashes[filePath]
+	return entry, ok
+}
+
+// DetectMovedFiles compares the cache against the files currently on disk
+// Cached files that disappeared are matched by content hash against files not yet in the cache.
+// Returns renames as a map of old path -> new path, and removed paths that had no match.
+func (fhm *FileHashManager) DetectMovedFiles(currentFiles []string) (map[string]string, []string, error) {
+	renames := make(map[string]string)
+	var removed []string
+
+	fhm.mux.RLock()
+	if fhm.cache == nil {
+		fhm.mux.RUnlock()
+		return renames, removed, nil
+	}
+
+	current := make(map[string]bool, len(currentFiles))
+	for _, path := range currentFiles {
+		current[path] = true
+	}
+
+	// Cached files that are no longer on disk, grouped by hash
+	disappeared := make(map[string][]string)
+	var disappearedCount int
+	for path, entry := range fhm.cache.Hashes {
+		if !current[path] {
+			disappeared[entry.Hash] = append(disappeared[entry.Hash], path)
+			disappearedCount++
+		}
+	}
+
+	// Files on disk that are not in the cache
+	var newFiles []string
+	for _, path := range currentFiles {
+		if _, ok := fhm.cache.Hashes[path]; !ok {
+			newFiles = append(newFiles, path)
+		}
+	}
+	fhm.mux.RUnlock()
+
+	if disappearedCount == 0 {
+		return renames, removed, nil
+	}
+
+	// Hash new files outside the lock (expensive operation)
+	for _, newPath := range newFiles {
+		hash, err := computeFileHash(newPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to compute file hash: %w", err)
+		}
+
+		candidates := disappeared[hash]
+		if len(candidates) == 0 {
+			continue
+		}
+
+		// Match one-to-one so copies of the same content are not all mapped to one file
+		renames[candidates[0]] = newPath
+		disappeared[hash] = candidates[1:]
+	}
+
+	for _, paths := range disappeared {
+		removed = append(removed, paths...)
+	}
+	sort.Strings(removed)
+
+	return renames, removed, nil
+}
+
+// FindStaleFile returns the first file that changed since it was last indexed according to cache,
+// using mtimes only
+// A file is stale if it is new, was modified after it was indexed (or last skipped or failed to
+// index), or a cached file disappeared. This is cheap compared to NeedsReindex because no file
+// content is hashed.
+func FindStaleFile(cache *models.FileHashCache, currentFiles []string) (string, bool) {
+	for _, path := range currentFiles {
+		cached, exists := cache.Hashes[path]
+		seenAt := cached.LastIndexed
+		if attempted, ok := cache.Unindexed[path]; ok {
+			exists = true
+			if attempted.After(seenAt) {
+				seenAt = attempted
+			}
+		}
+		if !exists {
+			return path, true // New file
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return path, true // Treat unreadable files as changed
+		}
+		if info.ModTime().After(seenAt) {
+			return path, true // Modified since last index
+		}
+	}
+
+	// Any cached file missing from disk means the index has stale paths
+	current := make(map[string]bool, len(currentFiles))
+	for _, path := range currentFiles {
+		current[path] = true
+	}
+	for path := range cache.Hashes {
+		if !current[path] {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// SetDirMtimes records the directory mtimes compared against on the next scan
+// Thread-safe: uses write lock for concurrent access
+func (fhm *FileHashManager) SetDirMtimes(mtimes map[string]time.Time) {
+	fhm.mux.Lock()
+	defer fhm.mux.Unlock()
+
+	if fhm.cache != nil {
+		fhm.cache.DirMtimes = mtimes
+	}
+}
+
+// SetDocumentPrefix records the prefix the cached files were embedded with
+// Thread-safe: uses write lock for concurrent access
+func (fhm *FileHashManager) SetDocumentPrefix(prefix string) {
+	fhm.mux.Lock()
+	defer fhm.mux.Unlock()
+
+	if fhm.cache != nil {
+		fhm.cache.DocumentPrefix = prefix
+	}
+}
+
+// SetGitHead records the commit whose blame the indexed chunks carry, saved with the cache
+// Thread-safe: uses write lock for concurrent access
+func (fhm *FileHashManager) SetGitHead(commit string) {
+	fhm.mux.Lock()
+	defer fhm.mux.Unlock()
+
+	if fhm.cache != nil {
+		fhm.cache.GitHead = commit
+	}
+}
+
+// GitHead returns the commit recorded with SetGitHead, empty if none
+// Thread-safe: uses read lock for concurrent access
+func (fhm *FileHashManager) GitHead() string {
+	fhm.mux.RLock()
+	defer fhm.mux.RUnlock()
+
+	if fhm.cache == nil {
+		return ""
+	}
+	return fhm.cache.GitHead
+}
+
+// DocumentPrefixChanged reports whether the loaded cache has files embedded with a prefix other than prefix
+// Thread-safe: uses read lock for concurrent access
+func (fhm *FileHashManager) DocumentPrefixChanged(prefix string) bool {
+	fhm.mux.RLock()
+	defer fhm.mux.RUnlock()
+
+	return fhm.cache != nil && len(fhm.cache.Hashes) > 0 && fhm.cache.DocumentPrefix != prefix
+}
+
+// Reset replaces the loaded cache with an empty one for repoPath, leaving the saved cache
+// file in place until the next Save
+func (fhm *FileHashManager) Reset(repoPath string) {
+	fhm.mux.Lock()
+	defer fhm.mux.Unlock()
+
+	fhm.cache = &models.FileHashCache{
+		RepoPath:  repoPath,
+		Hashes:    make(map[string]models.FileHash),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// DirSnapshot is a read-only view of the recorded directory mtimes and the files indexed in each
+type DirSnapshot struct {
+	mtimes map[string]time.Time
+	files  map[string][]string
+}
+
+// DirSnapshot captures the recorded directory mtimes and indexed files of the loaded cache
+// Thread-safe: uses read lock for concurrent access
+func (fhm *FileHashManager) DirSnapshot() *DirSnapshot {
+	fhm.mux.RLock()
+	defer fhm.mux.RUnlock()
+
+	snapshot := &DirSnapshot{
+		mtimes: make(map[string]time.Time),
+		files:  make(map[string][]string),
+	}
+	if fhm.cache == nil {
+		return snapshot
+	}
+
+	for dir, mtime := range fhm.cache.DirMtimes {
+		snapshot.mtimes[dir] = mtime
+	}
+	for path := range fhm.cache.Hashes {
+		dir := filepath.Dir(path)
+		snapshot.files[dir] = append(snapshot.files[dir], path)
+	}
+	for _, files := range snapshot.files {
+		sort.Strings(files)
+	}
+	return snapshot
+}
+
+// UnchangedFiles returns the indexed files directly in dir if its mtime equals the one
+// recorded at the last index; ok is false if the directory has to be rescanned
+func (s *DirSnapshot) UnchangedFiles(dir string, mtime time.Time) ([]string, bool) {
+	recorded, ok := s.mtimes[dir]
+	if !ok || !recorded.Equal(mtime) {
+		return nil, false
+	}
+	return s.files[dir], true
 }
 
 // GetStats returns statistics about the cache
@@ -220,6 +538,12 @@ func (fhm *FileHashManager) getCachePath(repoPath string) string {
 	return filepath.Join(fhm.cacheDir, filename)
 }
 
+// HashContent returns the hash recorded for a file with the given content
+// It equals the hash NeedsReindex and Update compute by reading the file.
+func HashContent(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
 // computeFileHash computes SHA256 hash of a file
 func computeFileHash(filePath string) (string, error) {
 	file, err := os.Open(filePath)
@@ -302,3 +302,168 @@ func TestNonExistentFile(t *testing.T) {
 		}
 	}
 }
+
+func TestDetectMovedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	cacheDir := filepath.Join(tmpDir, "cache")
+
+	manager, err := NewFileHashManager(cacheDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	repoDir := filepath.Join(tmpDir, "repo")
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+
+	oldFile := filepath.Join(repoDir, "Old.java")
+	deletedFile := filepath.Join(repoDir, "Deleted.java")
+	keptFile := filepath.Join(repoDir, "Kept.java")
+	for path, content := range map[string]string{
+		oldFile:     "class Renamed {}",
+		deletedFile: "class Deleted {}",
+		keptFile:    "class Kept {}",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	if err := manager.Load(repoDir); err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	for _, path := range []string{oldFile, deletedFile, keptFile} {
+		if err := manager.Update(path, 2); err != nil {
+			t.Fatalf("Failed to update: %v", err)
+		}
+	}
+
+	// Rename one file and delete another
+	newFile := filepath.Join(repoDir, "New.java")
+	if err := os.Rename(oldFile, newFile); err != nil {
+		t.Fatalf("Failed to rename: %v", err)
+	}
+	if err := os.Remove(deletedFile); err != nil {
+		t.Fatalf("Failed to remove: %v", err)
+	}
+
+	renames, removed, err := manager.DetectMovedFiles([]string{newFile, keptFile})
+	if err != nil {
+		t.Fatalf("DetectMovedFiles failed: %v", err)
+	}
+
+	if len(renames) != 1 || renames[oldFile] != newFile {
+		t.Errorf("Expected rename %s -> %s, got %v", oldFile, newFile, renames)
+	}
+	if len(removed) != 1 || removed[0] != deletedFile {
+		t.Errorf("Expected removed [%s], got %v", deletedFile, removed)
+	}
+
+	// Renaming the cache entry should make the new path up to date
+	manager.Rename(oldFile, newFile)
+	needsReindex, err := manager.NeedsReindex(newFile)
+	if err != nil {
+		t.Fatalf("NeedsReindex failed: %v", err)
+	}
+	if needsReindex {
+		t.Error("Expected renamed file to not need reindex")
+	}
+
+	stats := manager.GetStats()
+	if stats["total_files"] != 3 {
+		t.Errorf("Expected 3 cached files after rename, got %v", stats["total_files"])
+	}
+}
+
+func TestHashContent_MatchesFileHash(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.java")
+	content := []byte("public class Test {}\n")
+	if err := os.WriteFile(testFile, content, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	fileHash, err := computeFileHash(testFile)
+	if err != nil {
+		t.Fatalf("computeFileHash failed: %v", err)
+	}
+	if HashContent(content) != fileHash {
+		t.Errorf("Expected HashContent to match the file hash %s, got %s", fileHash, HashContent(content))
+	}
+
+	// A hash recorded from content is recognized by NeedsReindex, and vice versa
+	manager, err := NewFileHashManager(filepath.Join(tmpDir, "cache"))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := manager.Load(tmpDir); err != nil {
+		t.Fatalf("Failed to load cache: %v", err)
+	}
+	if !manager.NeedsReindexHash(testFile, HashContent(content)) {
+		t.Error("Expected a file missing from the cache to need reindexing")
+	}
+	if err := manager.UpdateHash(testFile, HashContent(content), 1); err != nil {
+		t.Fatalf("UpdateHash failed: %v", err)
+	}
+	if needsReindex, err := manager.NeedsReindex(testFile); err != nil || needsReindex {
+		t.Errorf("Expected the file to be up to date after UpdateHash, got %v (%v)", needsReindex, err)
+	}
+	if !manager.NeedsReindexHash(testFile, HashContent([]byte("changed"))) {
+		t.Error("Expected changed content to need reindexing")
+	}
+}
+
+func TestFindStaleFile_Unindexed(t *testing.T) {
+	repoDir := t.TempDir()
+	manager, err := NewFileHashManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := manager.Load(repoDir); err != nil {
+		t.Fatalf("Failed to load cache: %v", err)
+	}
+
+	indexed := filepath.Join(repoDir, "indexed.go")
+	skipped := filepath.Join(repoDir, "skipped.go")
+	for _, path := range []string{indexed, skipped} {
+		if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	past := time.Now().Add(-time.Hour)
+	for _, path := range []string{indexed, skipped} {
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatalf("Failed to touch file: %v", err)
+		}
+	}
+	if err := manager.Update(indexed, 1); err != nil {
+		t.Fatalf("Failed to update hash: %v", err)
+	}
+	files := []string{indexed, skipped}
+
+	if path, stale := FindStaleFile(manager.cache, files); !stale || path != skipped {
+		t.Fatalf("Expected the never-tried file to be stale, got %q (%v)", path, stale)
+	}
+
+	manager.MarkUnindexed(skipped)
+	if path, stale := FindStaleFile(manager.cache, files); stale {
+		t.Fatalf("Expected a file that failed since its last change not to be stale, got %q", path)
+	}
+
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(skipped, future, future); err != nil {
+		t.Fatalf("Failed to touch file: %v", err)
+	}
+	if path, stale := FindStaleFile(manager.cache, files); !stale || path != skipped {
+		t.Errorf("Expected the edited file to be stale, got %q (%v)", path, stale)
+	}
+
+	// Indexing it successfully replaces the record
+	if err := manager.Update(skipped, 1); err != nil {
+		t.Fatalf("Failed to update hash: %v", err)
+	}
+	if _, ok := manager.cache.Unindexed[skipped]; ok {
+		t.Error("Expected the unindexed record to be dropped once the file is indexed")
+	}
+}
@@ -2,7 +2,7 @@ package embeddings
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -17,9 +17,19 @@ type EmbeddingGenerator interface {
 
 // Batcher handles batch processing of embeddings
 type Batcher struct {
-	client    EmbeddingGenerator
-	batchSize int
-	workers   int
+	client       EmbeddingGenerator
+	batchSize    int
+	workers      int
+	maxRetries   int           // Retries per failed batch (0 = no retries)
+	retryBackoff time.Duration // Initial backoff, doubled after each retry
+	docPrefix    string        // Task prefix prepended to every chunk text
+}
+
+// BatchResult contains the outcome of a partial-failure tolerant embedding run
+type BatchResult struct {
+	Chunks         []models.CodeChunk // Chunks that were successfully embedded
+	FailedChunkIDs []string           // IDs of chunks whose batch failed after all retries
+	Errors         []error            // Final error for each failed batch
 }
 
 // NewBatcher creates a new embedding batcher
@@ -34,18 +44,57 @@ func NewBatcher(client EmbeddingGenerator, batchSize, workers int) *Batcher {
 	}
 }
 
+// SetRetryPolicy configures how many times a failed batch is retried
+// The backoff starts at initialBackoff and doubles after each attempt
+func (b *Batcher) SetRetryPolicy(maxRetries int, initialBackoff time.Duration) {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	b.maxRetries = maxRetries
+	b.retryBackoff = initialBackoff
+}
+
+// SetDocumentPrefix sets the task prefix prepended to chunk texts before embedding
+func (b *Batcher) SetDocumentPrefix(prefix string) {
+	b.docPrefix = prefix
+}
+
 // ProcessChunks generates embeddings for a slice of code chunks
+// Fails if any batch still fails after retries; use ProcessChunksPartial to keep successful batches
 func (b *Batcher) ProcessChunks(chunks []models.CodeChunk) ([]models.CodeChunk, error) {
+	result, err := b.ProcessChunksPartial(chunks)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Errors) > 0 {
+		return nil, result.Errors[0]
+	}
+
+	return result.Chunks, nil
+}
+
+// ProcessChunksPartial generates embeddings for a slice of code chunks, tolerating failed batches
+// Each failed batch is retried with exponential backoff; batches that still fail are reported
+// in FailedChunkIDs so callers can persist progress for the chunks that succeeded
+func (b *Batcher) ProcessChunksPartial(chunks []models.CodeChunk) (*BatchResult, error) {
+	return b.ProcessChunksWithProgress(chunks, nil)
+}
+
+// ProcessChunksWithProgress is ProcessChunksPartial, calling progress (if set) with the number
+// of chunks of each batch once it is done, embedded or failed; batches run concurrently, so
+// progress must be safe for concurrent use
+func (b *Batcher) ProcessChunksWithProgress(chunks []models.CodeChunk, progress func(chunks int)) (*BatchResult, error) {
 	if len(chunks) == 0 {
-		return chunks, nil
+		return &BatchResult{Chunks: chunks}, nil
 	}
 
-	log.Printf("Generating embeddings for %d chunks using %d workers...", len(chunks), b.workers)
+	slog.Info("Generating embeddings", "chunks", len(chunks), "workers", b.workers)
 	startTime := time.Now()
 
 	// Create batches
 	batches := b.createBatches(chunks)
-	log.Printf("Split into %d batches of ~%d chunks each", len(batches), b.batchSize)
+	slog.Debug("Split into batches", "batches", len(batches), "batch_size", b.batchSize)
 
 	// Process batches in parallel
 	results := make([][]models.CodeChunk, len(batches))
@@ -63,43 +112,73 @@ func (b *Batcher) ProcessChunks(chunks []models.CodeChunk) ([]models.CodeChunk,
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
-			processed, err := b.processBatch(batch, idx)
+			processed, err := b.processBatchWithRetry(batch, idx)
 			results[idx] = processed
 			errors[idx] = err
+			if progress != nil {
+				progress(len(batch))
+			}
 		}(i, batch)
 	}
 
 	wg.Wait()
 
-	// Check for errors
-	for i, err := range errors {
-		if err != nil {
-			return nil, fmt.Errorf("batch %d failed: %w", i, err)
+	// Combine results, collecting failed batches instead of aborting the run
+	result := &BatchResult{}
+	for i, batch := range results {
+		if errors[i] != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("batch %d failed: %w", i, errors[i]))
+			for _, chunk := range batches[i] {
+				result.FailedChunkIDs = append(result.FailedChunkIDs, chunk.ID)
+			}
+			continue
 		}
+		result.Chunks = append(result.Chunks, batch...)
 	}
 
-	// Combine results
-	var allChunks []models.CodeChunk
-	for _, batch := range results {
-		allChunks = append(allChunks, batch...)
+	duration := time.Since(startTime)
+	embeddingsPerSec := float64(len(result.Chunks)) / duration.Seconds()
+	slog.Info("Generated embeddings", "count", len(result.Chunks), "duration", duration,
+		"per_second", fmt.Sprintf("%.1f", embeddingsPerSec))
+	if len(result.FailedChunkIDs) > 0 {
+		slog.Warn("Batches failed after retries", "batches", len(result.Errors),
+			"chunks_not_embedded", len(result.FailedChunkIDs))
 	}
 
-	duration := time.Since(startTime)
-	embeddingsPerSec := float64(len(chunks)) / duration.Seconds()
-	log.Printf("Generated %d embeddings in %v (%.1f embeddings/sec)",
-		len(chunks), duration, embeddingsPerSec)
+	return result, nil
+}
+
+// processBatchWithRetry processes a batch, retrying with exponential backoff on failure
+func (b *Batcher) processBatchWithRetry(chunks []models.CodeChunk, batchIdx int) ([]models.CodeChunk, error) {
+	backoff := b.retryBackoff
+
+	var lastErr error
+	for attempt := 0; attempt <= b.maxRetries; attempt++ {
+		if attempt > 0 {
+			slog.Warn("Retrying batch", "batch", batchIdx, "attempt", attempt, "max_retries", b.maxRetries,
+				"backoff", backoff, "error", lastErr)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		processed, err := b.processBatch(chunks, batchIdx)
+		if err == nil {
+			return processed, nil
+		}
+		lastErr = err
+	}
 
-	return allChunks, nil
+	return nil, lastErr
 }
 
 // processBatch processes a single batch of chunks using batch embedding generation
 func (b *Batcher) processBatch(chunks []models.CodeChunk, batchIdx int) ([]models.CodeChunk, error) {
-	log.Printf("Processing batch %d with %d chunks...", batchIdx, len(chunks))
+	slog.Debug("Processing batch", "batch", batchIdx, "chunks", len(chunks))
 
 	// Extract all texts from chunks
 	texts := make([]string, len(chunks))
 	for i := range chunks {
-		texts[i] = chunks[i].Content
+		texts[i] = b.docPrefix + chunks[i].EmbeddingText()
 	}
 
 	// Generate embeddings for all chunks in this batch using concurrent requests
@@ -113,7 +192,7 @@ func (b *Batcher) processBatch(chunks []models.CodeChunk, batchIdx int) ([]model
 		chunks[i].Embedding = embeddings[i]
 	}
 
-	log.Printf("Batch %d: 100%% complete (%d/%d chunks processed)", batchIdx, len(chunks), len(chunks))
+	slog.Debug("Batch complete", "batch", batchIdx, "chunks", len(chunks))
 
 	return chunks, nil
 }
@@ -1,7 +1,11 @@
 package embeddings
 
 import (
+	"errors"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jamaly87/codebase-semantic-search/internal/models"
 )
@@ -148,6 +152,51 @@ func TestBatchProcessing(t *testing.T) {
 	}
 }
 
+// recordingMockClient records the texts it embeds
+type recordingMockClient struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+func (m *recordingMockClient) GenerateEmbedding(text string) ([]float32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.texts = append(m.texts, text)
+	return []float32{0.1}, nil
+}
+
+func (m *recordingMockClient) GenerateEmbeddings(texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i], _ = m.GenerateEmbedding(text)
+	}
+	return embeddings, nil
+}
+
+func TestBatcher_DocumentPrefix(t *testing.T) {
+	client := &recordingMockClient{}
+	batcher := NewBatcher(client, 2, 2)
+	batcher.SetDocumentPrefix("search_document: ")
+
+	chunks := []models.CodeChunk{
+		{ID: "1", Content: "func a() {}"},
+		{ID: "2", Content: "func b() {}"},
+		{ID: "3", Content: "func c() {}"},
+	}
+	if _, err := batcher.ProcessChunks(chunks); err != nil {
+		t.Fatalf("ProcessChunks failed: %v", err)
+	}
+
+	if len(client.texts) != len(chunks) {
+		t.Fatalf("Expected %d embedded texts, got %d", len(chunks), len(client.texts))
+	}
+	for _, text := range client.texts {
+		if !strings.HasPrefix(text, "search_document: func ") {
+			t.Errorf("Expected the document prefix before the chunk text, got %q", text)
+		}
+	}
+}
+
 func TestWorkerPoolSize(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -183,6 +232,212 @@ func TestWorkerPoolSize(t *testing.T) {
 	}
 }
 
+// concurrencyMockClient records the maximum number of concurrent batch requests
+type concurrencyMockClient struct {
+	mu        sync.Mutex
+	active    int
+	maxActive int
+}
+
+func (m *concurrencyMockClient) GenerateEmbedding(text string) ([]float32, error) {
+	return []float32{0.1, 0.2, 0.3}, nil
+}
+
+func (m *concurrencyMockClient) GenerateEmbeddings(texts []string) ([][]float32, error) {
+	m.mu.Lock()
+	m.active++
+	if m.active > m.maxActive {
+		m.maxActive = m.active
+	}
+	m.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	m.mu.Lock()
+	m.active--
+	m.mu.Unlock()
+
+	embeddings := make([][]float32, len(texts))
+	for i := range texts {
+		embeddings[i] = []float32{0.1, 0.2, 0.3}
+	}
+	return embeddings, nil
+}
+
+func TestWorkerConcurrencyLimit(t *testing.T) {
+	for _, workers := range []int{1, 3} {
+		client := &concurrencyMockClient{}
+		batcher := NewBatcher(client, 2, workers)
+
+		chunks := make([]models.CodeChunk, 40)
+		for i := range chunks {
+			chunks[i] = models.CodeChunk{ID: string(rune('a' + i)), Content: "code"}
+		}
+
+		if _, err := batcher.ProcessChunks(chunks); err != nil {
+			t.Fatalf("ProcessChunks failed: %v", err)
+		}
+		if client.maxActive > workers {
+			t.Errorf("Expected at most %d concurrent requests, got %d", workers, client.maxActive)
+		}
+		if workers > 1 && client.maxActive < 2 {
+			t.Errorf("Expected requests to run concurrently with %d workers, got max %d", workers, client.maxActive)
+		}
+	}
+}
+
+// failingMockClient fails any request containing a marker text a fixed number of times
+type failingMockClient struct {
+	mu       sync.Mutex
+	marker   string
+	failures int // Remaining failures (-1 = always fail)
+	calls    int
+}
+
+func (m *failingMockClient) GenerateEmbedding(text string) ([]float32, error) {
+	return []float32{0.1, 0.2, 0.3}, nil
+}
+
+func (m *failingMockClient) GenerateEmbeddings(texts []string) ([][]float32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+
+	for _, text := range texts {
+		if strings.Contains(text, m.marker) && m.failures != 0 {
+			if m.failures > 0 {
+				m.failures--
+			}
+			return nil, errors.New("ollama unavailable")
+		}
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for i := range texts {
+		embeddings[i] = []float32{0.1, 0.2, 0.3}
+	}
+	return embeddings, nil
+}
+
+func TestBatchRetryAndPartialRecovery(t *testing.T) {
+	newChunks := func() []models.CodeChunk {
+		return []models.CodeChunk{
+			{ID: "1", Content: "good one"},
+			{ID: "2", Content: "good two"},
+			{ID: "3", Content: "bad three"},
+			{ID: "4", Content: "good four"},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		failures       int
+		maxRetries     int
+		expectEmbedded int
+		expectFailed   []string
+	}{
+		{
+			name:           "transient failure recovered by retry",
+			failures:       2,
+			maxRetries:     3,
+			expectEmbedded: 4,
+		},
+		{
+			name:           "no retries configured",
+			failures:       1,
+			maxRetries:     0,
+			expectEmbedded: 2,
+			expectFailed:   []string{"3", "4"},
+		},
+		{
+			name:           "permanent failure keeps other batches",
+			failures:       -1,
+			maxRetries:     2,
+			expectEmbedded: 2,
+			expectFailed:   []string{"3", "4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &failingMockClient{marker: "bad", failures: tt.failures}
+			batcher := NewBatcher(client, 2, 1)
+			batcher.SetRetryPolicy(tt.maxRetries, time.Millisecond)
+
+			result, err := batcher.ProcessChunksPartial(newChunks())
+			if err != nil {
+				t.Fatalf("ProcessChunksPartial failed: %v", err)
+			}
+
+			if len(result.Chunks) != tt.expectEmbedded {
+				t.Errorf("Expected %d embedded chunks, got %d", tt.expectEmbedded, len(result.Chunks))
+			}
+			for _, chunk := range result.Chunks {
+				if len(chunk.Embedding) == 0 {
+					t.Errorf("Chunk %s missing embedding", chunk.ID)
+				}
+			}
+
+			if strings.Join(result.FailedChunkIDs, ",") != strings.Join(tt.expectFailed, ",") {
+				t.Errorf("Expected failed chunk IDs %v, got %v", tt.expectFailed, result.FailedChunkIDs)
+			}
+			if (len(result.Errors) > 0) != (len(tt.expectFailed) > 0) {
+				t.Errorf("Unexpected batch errors: %v", result.Errors)
+			}
+		})
+	}
+}
+
+func TestProcessChunks_FailsOnPermanentError(t *testing.T) {
+	client := &failingMockClient{marker: "bad", failures: -1}
+	batcher := NewBatcher(client, 2, 1)
+	batcher.SetRetryPolicy(2, time.Millisecond)
+
+	_, err := batcher.ProcessChunks([]models.CodeChunk{
+		{ID: "1", Content: "good"},
+		{ID: "2", Content: "bad"},
+	})
+	if err == nil {
+		t.Fatal("Expected error when a batch fails permanently")
+	}
+
+	// Initial attempt plus two retries
+	if client.calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", client.calls)
+	}
+}
+
+func TestProcessChunksWithProgress(t *testing.T) {
+	client := &failingMockClient{marker: "bad", failures: -1}
+	batcher := NewBatcher(client, 2, 2)
+
+	var mu sync.Mutex
+	var done []int
+	_, err := batcher.ProcessChunksWithProgress([]models.CodeChunk{
+		{ID: "1", Content: "good one"},
+		{ID: "2", Content: "good two"},
+		{ID: "3", Content: "bad three"},
+		{ID: "4", Content: "good four"},
+		{ID: "5", Content: "good five"},
+	}, func(chunks int) {
+		mu.Lock()
+		defer mu.Unlock()
+		done = append(done, chunks)
+	})
+	if err != nil {
+		t.Fatalf("ProcessChunksWithProgress failed: %v", err)
+	}
+
+	// Every batch is reported once it is done, the failed one included
+	total := 0
+	for _, n := range done {
+		total += n
+	}
+	if len(done) != 3 || total != 5 {
+		t.Errorf("Expected 3 batches of 5 chunks reported, got %v", done)
+	}
+}
+
 // Helper function to create batches (mimics internal logic)
 func createBatches(chunks []models.CodeChunk, batchSize int) [][]models.CodeChunk {
 	if len(chunks) == 0 {
@@ -2,13 +2,16 @@ package embeddings
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jamaly87/codebase-semantic-search/pkg/config"
 )
@@ -18,27 +21,40 @@ type Client struct {
 	config     *config.EmbeddingsConfig
 	httpClient *http.Client
 	baseURL    string
+	tokenizer  Tokenizer     // Truncates by tokens; nil falls back to fallbackMaxChars
+	requests   chan struct{} // Semaphore bounding requests in flight to Ollama
 }
 
+// Tokenizer truncates text to a token budget
+type Tokenizer interface {
+	// TruncateTokens returns text cut to maxTokens tokens and the number of tokens dropped
+	TruncateTokens(text string, maxTokens int) (string, int)
+}
+
+const (
+	// fallbackMaxChars limits texts when no tokenizer is set (~1000 tokens)
+	fallbackMaxChars = 4000
+	// contextBudgetRatio is the share of context_length used when truncating by tokens.
+	// The tokenizer (chunking.tokenizer_encoding, cl100k_base by default) is usually not the
+	// embedding model's own, which may produce more tokens for code, so some headroom is kept.
+	contextBudgetRatio = 0.75
+	// DefaultMaxConcurrentRequests bounds requests in flight when
+	// embeddings.max_concurrent_requests is unset
+	DefaultMaxConcurrentRequests = 16
+)
+
 // NewClient creates a new Ollama embeddings client
 func NewClient(cfg *config.EmbeddingsConfig) *Client {
-	// Configure HTTP transport for optimal connection reuse and pooling
-	transport := &http.Transport{
-		MaxIdleConns:        100,              // Maximum idle connections across all hosts
-		MaxIdleConnsPerHost: 100,              // Maximum idle connections per host (Ollama)
-		MaxConnsPerHost:     100,              // Maximum total connections per host
-		IdleConnTimeout:     90 * time.Second, // How long idle connections stay alive
-		DisableKeepAlives:   false,            // Enable keep-alive (connection reuse)
-		ForceAttemptHTTP2:   false,            // Stick with HTTP/1.1 for simplicity
-	}
+	concurrency := maxConcurrentRequests(cfg)
 
 	client := &Client{
 		config:  cfg,
 		baseURL: cfg.OllamaURL,
 		httpClient: &http.Client{
 			Timeout:   60 * time.Second, // Generous timeout for large batches
-			Transport: transport,
+			Transport: newTransport(concurrency),
 		},
+		requests: make(chan struct{}, concurrency),
 	}
 
 	// Log MRL configuration
@@ -47,10 +63,76 @@ func NewClient(cfg *config.EmbeddingsConfig) *Client {
 	return client
 }
 
+// newTransport returns an HTTP transport pooling connections to Ollama
+// Requests in flight never exceed the pool, so every request after the first few reuses an
+// idle keep-alive connection instead of opening a new one.
+func newTransport(poolSize int) *http.Transport {
+	return &http.Transport{
+		MaxIdleConns:        poolSize,         // Ollama is the only host
+		MaxIdleConnsPerHost: poolSize,         // Keep a connection for every request in flight
+		MaxConnsPerHost:     poolSize,         // Never open more than the pool keeps
+		IdleConnTimeout:     90 * time.Second, // How long idle connections stay alive
+		DisableKeepAlives:   false,            // Enable keep-alive (connection reuse)
+		ForceAttemptHTTP2:   false,            // Stick with HTTP/1.1 for simplicity
+	}
+}
+
+// maxConcurrentRequests returns the configured bound on requests in flight, or the default
+func maxConcurrentRequests(cfg *config.EmbeddingsConfig) int {
+	if cfg.MaxConcurrentRequests > 0 {
+		return cfg.MaxConcurrentRequests
+	}
+	return DefaultMaxConcurrentRequests
+}
+
+// SetTokenizer makes the client truncate over-long texts by token count against
+// embeddings.context_length instead of by characters
+func (c *Client) SetTokenizer(tokenizer Tokenizer) {
+	c.tokenizer = tokenizer
+}
+
+// truncate cuts text that would not fit the model's context, logging what was dropped
+func (c *Client) truncate(text string) string {
+	if c.tokenizer == nil || c.config.ContextLength <= 0 {
+		if len(text) <= fallbackMaxChars {
+			return text
+		}
+		cut := fallbackMaxChars
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		slog.Warn("Truncated embedding text", "kept_chars", cut, "dropped_chars", len(text)-cut)
+		return text[:cut]
+	}
+
+	maxTokens := int(float64(c.config.ContextLength) * contextBudgetRatio)
+	truncated, dropped := c.tokenizer.TruncateTokens(text, maxTokens)
+	if dropped > 0 {
+		slog.Warn("Truncated embedding text to fit the model context",
+			"max_tokens", maxTokens, "dropped_tokens", dropped, "dropped_chars", len(text)-len(truncated))
+	}
+	return truncated
+}
+
 // EmbedRequest represents a request to generate embeddings
 type EmbedRequest struct {
 	Model  string `json:"model"`
 	Prompt string `json:"prompt"`
+	// KeepAlive is how long Ollama keeps the model loaded after the request: a duration
+	// string ("10m") or a number of seconds (-1 keeps it loaded); nil uses Ollama's default
+	KeepAlive interface{} `json:"keep_alive,omitempty"`
+}
+
+// keepAliveValue converts the keep_alive setting for the request body
+// Ollama parses strings as durations, so plain numbers such as "-1" are sent as seconds.
+func keepAliveValue(keepAlive string) interface{} {
+	if keepAlive == "" {
+		return nil
+	}
+	if seconds, err := strconv.Atoi(keepAlive); err == nil {
+		return seconds
+	}
+	return keepAlive
 }
 
 // EmbedResponse represents the response from Ollama
@@ -60,18 +142,53 @@ type EmbedResponse struct {
 
 // GenerateEmbedding generates an embedding for a single text
 func (c *Client) GenerateEmbedding(text string) ([]float32, error) {
-	// Truncate text if it exceeds safe length
-	// nomic-embed-text has 8192 token limit (~4 chars per token)
-	// Use very conservative 4000 chars (~1000 tokens) to ensure we never exceed
-	// This is a safety net - chunker should already handle size limits
-	maxChars := 4000
-	if len(text) > maxChars {
-		text = text[:maxChars]
+	return c.generateEmbedding(context.Background(), text)
+}
+
+// GenerateEmbeddingContext generates an embedding for a single text, giving up when ctx is done
+func (c *Client) GenerateEmbeddingContext(ctx context.Context, text string) ([]float32, error) {
+	return c.generateEmbedding(ctx, text)
+}
+
+// generateEmbedding generates an embedding for a single text, cancelled with ctx
+func (c *Client) generateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	// Safety net: the chunker should already keep texts within the model's context
+	text = c.truncate(text)
+
+	embedding, err := c.requestEmbedding(ctx, text)
+	if err != nil {
+		return nil, err
 	}
 
+	// Validate we got the full dimension from the model
+	fullDim := c.config.FullDimension
+	if fullDim == 0 {
+		fullDim = 768 // Default for nomic-embed-text
+	}
+
+	if len(embedding) != fullDim {
+		return nil, dimensionMismatchError(c.config.Model, fullDim, len(embedding))
+	}
+
+	// Apply MRL dimension truncation if enabled
+	if c.config.UseMRL && c.config.Dimensions < fullDim {
+		embedding = applyMRL(embedding, c.config.Dimensions)
+	}
+
+	// Normalize if configured (after MRL slicing)
+	if c.config.Normalize {
+		embedding = normalize(embedding)
+	}
+
+	return embedding, nil
+}
+
+// requestEmbedding asks Ollama for the raw embedding of text, as the model returns it
+func (c *Client) requestEmbedding(ctx context.Context, text string) ([]float32, error) {
 	request := EmbedRequest{
-		Model:  c.config.Model,
-		Prompt: text,
+		Model:     c.config.Model,
+		Prompt:    text,
+		KeepAlive: keepAliveValue(c.config.KeepAlive),
 	}
 
 	reqBody, err := json.Marshal(request)
@@ -80,13 +197,21 @@ func (c *Client) GenerateEmbedding(text string) ([]float32, error) {
 	}
 
 	url := fmt.Sprintf("%s/api/embeddings", c.baseURL)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
+	// Wait for a pooled connection's turn
+	select {
+	case c.requests <- struct{}{}:
+		defer func() { <-c.requests }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
@@ -103,29 +228,15 @@ func (c *Client) GenerateEmbedding(text string) ([]float32, error) {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Validate we got the full dimension from the model
-	fullDim := c.config.FullDimension
-	if fullDim == 0 {
-		fullDim = 768 // Default for nomic-embed-text
-	}
-
-	if len(response.Embedding) != fullDim {
-		return nil, fmt.Errorf("expected %d dimensions from model, got %d", fullDim, len(response.Embedding))
-	}
-
-	embedding := response.Embedding
-
-	// Apply MRL dimension truncation if enabled
-	if c.config.UseMRL && c.config.Dimensions < fullDim {
-		embedding = applyMRL(embedding, c.config.Dimensions)
-	}
-
-	// Normalize if configured (after MRL slicing)
-	if c.config.Normalize {
-		embedding = normalize(embedding)
-	}
+	return response.Embedding, nil
+}
 
-	return embedding, nil
+// dimensionMismatchError explains a model/config dimension mismatch and how to fix it
+func dimensionMismatchError(model string, configured, actual int) error {
+	return fmt.Errorf("model %q returned %d-dimensional embeddings, but embeddings.full_dimension is %d: "+
+		"set embeddings.full_dimension to %d, then set embeddings.dimensions and vectordb.vector_size "+
+		"to the stored dimension (%d, or smaller with use_mrl: true), or switch to a model that outputs %d dimensions",
+		model, actual, configured, actual, actual, configured)
 }
 
 // GenerateEmbeddings generates embeddings for multiple texts (batch)
@@ -145,14 +256,10 @@ func (c *Client) GenerateEmbeddings(texts []string) ([][]float32, error) {
 	}
 
 	// Use concurrent requests with connection pooling for better performance
-	// The http.Client with keep-alive will reuse connections
+	// The http.Client with keep-alive will reuse connections, and the client's semaphore
+	// bounds requests in flight across all batches (embeddings.max_concurrent_requests)
 	embeddings := make([][]float32, len(texts))
 	errors := make([]error, len(texts))
-
-	// Limit concurrency to avoid overwhelming Ollama
-	// Use a semaphore to control concurrent requests
-	maxConcurrent := 10 // Process up to 10 requests concurrently
-	semaphore := make(chan struct{}, maxConcurrent)
 	var wg sync.WaitGroup
 
 	for i, text := range texts {
@@ -160,10 +267,6 @@ func (c *Client) GenerateEmbeddings(texts []string) ([][]float32, error) {
 		go func(idx int, txt string) {
 			defer wg.Done()
 
-			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
 			embedding, err := c.GenerateEmbedding(txt)
 			if err != nil {
 				errors[idx] = fmt.Errorf("failed to generate embedding for item %d: %w", idx, err)
@@ -195,6 +298,88 @@ func (c *Client) HealthCheck() error {
 	return nil
 }
 
+// WarmUp verifies the configured model is pulled and loads it into memory
+// Ollama loads models on the first request, so warming up keeps that delay out of
+// the first search or embedding batch, and a missing model fails fast with a hint
+// to run "ollama pull" instead of midway through indexing.
+func (c *Client) WarmUp(ctx context.Context) error {
+	start := time.Now()
+
+	if _, err := c.modelStatus(ctx); err != nil {
+		return fmt.Errorf("embedding model not available: %w", err)
+	}
+
+	if _, err := c.generateEmbedding(ctx, "warmup"); err != nil {
+		return fmt.Errorf("failed to load embedding model %q: %w", c.config.Model, err)
+	}
+
+	slog.Info("Embedding model warmed up", "model", c.config.Model, "duration", time.Since(start))
+	return nil
+}
+
+// ModelStatus describes Ollama reachability and whether the configured model is pulled
+type ModelStatus struct {
+	Reachable       bool     `json:"reachable"`
+	Model           string   `json:"model"`
+	ModelAvailable  bool     `json:"model_available"`
+	AvailableModels []string `json:"available_models,omitempty"`
+}
+
+// tagsResponse represents the response from Ollama's /api/tags endpoint
+type tagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+// ModelStatus checks if Ollama is reachable and the configured model is available
+// Unlike HealthCheck, this does not generate an embedding, so it is cheap to call
+func (c *Client) ModelStatus() (*ModelStatus, error) {
+	return c.modelStatus(context.Background())
+}
+
+// modelStatus checks the configured model against Ollama's tags endpoint, cancelled with ctx
+func (c *Client) modelStatus(ctx context.Context) (*ModelStatus, error) {
+	status := &ModelStatus{Model: c.config.Model}
+
+	url := fmt.Sprintf("%s/api/tags", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return status, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return status, fmt.Errorf("failed to reach ollama at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return status, fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(body))
+	}
+	status.Reachable = true
+
+	var tags tagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return status, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	for _, m := range tags.Models {
+		status.AvailableModels = append(status.AvailableModels, m.Name)
+		// Ollama reports untagged models with an implicit ":latest" suffix
+		if m.Name == c.config.Model || m.Name == c.config.Model+":latest" {
+			status.ModelAvailable = true
+		}
+	}
+
+	if !status.ModelAvailable {
+		return status, fmt.Errorf("model %q not found in ollama (run: ollama pull %s)", c.config.Model, c.config.Model)
+	}
+
+	return status, nil
+}
+
 // normalize performs L2 normalization on a vector
 func normalize(vec []float32) []float32 {
 	var sum float32
@@ -290,9 +475,9 @@ func (c *Client) logMRLConfig() {
 
 	if c.config.UseMRL {
 		reduction := float64(fullDim-c.config.Dimensions) / float64(fullDim) * 100
-		log.Printf("✓ MRL Enabled: %dd → %dd (%.0f%% smaller, ~95%% accuracy)",
-			fullDim, c.config.Dimensions, reduction)
+		slog.Info("MRL enabled", "full_dimensions", fullDim, "dimensions", c.config.Dimensions,
+			"reduction_percent", fmt.Sprintf("%.0f", reduction))
 	} else {
-		log.Printf("MRL Disabled: Using full %dd embeddings", fullDim)
+		slog.Info("MRL disabled, using full embeddings", "dimensions", fullDim)
 	}
 }
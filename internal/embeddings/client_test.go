@@ -1,8 +1,18 @@
 package embeddings
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/jamaly87/codebase-semantic-search/pkg/config"
 )
@@ -146,3 +156,358 @@ func TestEmbeddingValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestModelStatus(t *testing.T) {
+	tests := []struct {
+		name            string
+		handler         http.HandlerFunc
+		model           string
+		expectErr       bool
+		expectReachable bool
+		expectAvailable bool
+	}{
+		{
+			name: "model available",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"models":[{"name":"nomic-embed-text:latest"}]}`))
+			},
+			model:           "nomic-embed-text",
+			expectReachable: true,
+			expectAvailable: true,
+		},
+		{
+			name: "model not pulled",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"models":[{"name":"llama3:latest"}]}`))
+			},
+			model:           "nomic-embed-text",
+			expectErr:       true,
+			expectReachable: true,
+			expectAvailable: false,
+		},
+		{
+			name: "ollama error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "boom", http.StatusInternalServerError)
+			},
+			model:           "nomic-embed-text",
+			expectErr:       true,
+			expectReachable: false,
+			expectAvailable: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			client := NewClient(&config.EmbeddingsConfig{
+				Model:     tt.model,
+				OllamaURL: server.URL,
+			})
+
+			status, err := client.ModelStatus()
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Expected error=%v, got %v", tt.expectErr, err)
+			}
+			if status.Reachable != tt.expectReachable {
+				t.Errorf("Expected reachable=%v, got %v", tt.expectReachable, status.Reachable)
+			}
+			if status.ModelAvailable != tt.expectAvailable {
+				t.Errorf("Expected model available=%v, got %v", tt.expectAvailable, status.ModelAvailable)
+			}
+		})
+	}
+}
+
+func TestModelStatus_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close() // Nothing is listening anymore
+
+	client := NewClient(&config.EmbeddingsConfig{
+		Model:     "nomic-embed-text",
+		OllamaURL: url,
+	})
+
+	status, err := client.ModelStatus()
+	if err == nil {
+		t.Fatal("Expected error for unreachable ollama")
+	}
+	if status.Reachable {
+		t.Error("Expected reachable=false")
+	}
+}
+
+func TestGenerateEmbedding_DimensionMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"embedding":[0.1,0.2,0.3,0.4]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.EmbeddingsConfig{
+		Model:         "all-minilm",
+		OllamaURL:     server.URL,
+		FullDimension: 768,
+		Dimensions:    256,
+	})
+
+	_, err := client.GenerateEmbedding("func main() {}")
+	if err == nil {
+		t.Fatal("Expected dimension mismatch error")
+	}
+
+	message := err.Error()
+	for _, want := range []string{`"all-minilm"`, "768", "4-dimensional", "full_dimension", "vector_size"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Expected error to mention %q, got: %s", want, message)
+		}
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	tests := []struct {
+		name        string
+		tags        string
+		expectErr   string
+		expectEmbed bool
+	}{
+		{
+			name:        "model present is loaded",
+			tags:        `{"models":[{"name":"nomic-embed-text:latest"}]}`,
+			expectEmbed: true,
+		},
+		{
+			name:      "model absent suggests pulling it",
+			tags:      `{"models":[{"name":"llama3:latest"}]}`,
+			expectErr: "ollama pull nomic-embed-text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embedded := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/tags":
+					w.Write([]byte(tt.tags))
+				case "/api/embeddings":
+					embedded = true
+					json.NewEncoder(w).Encode(EmbedResponse{Embedding: make([]float32, 768)})
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			client := NewClient(&config.EmbeddingsConfig{
+				Model:         "nomic-embed-text",
+				OllamaURL:     server.URL,
+				FullDimension: 768,
+				Dimensions:    768,
+			})
+
+			err := client.WarmUp(context.Background())
+			if tt.expectErr == "" && err != nil {
+				t.Fatalf("WarmUp failed: %v", err)
+			}
+			if tt.expectErr != "" && (err == nil || !strings.Contains(err.Error(), tt.expectErr)) {
+				t.Fatalf("Expected error containing %q, got %v", tt.expectErr, err)
+			}
+			if embedded != tt.expectEmbed {
+				t.Errorf("Expected model load request=%v, got %v", tt.expectEmbed, embedded)
+			}
+		})
+	}
+}
+
+func TestNewClient_Transport(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured int
+		expected   int
+	}{
+		{"default pool", 0, DefaultMaxConcurrentRequests},
+		{"sized to max_concurrent_requests", 4, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&config.EmbeddingsConfig{MaxConcurrentRequests: tt.configured})
+
+			transport, ok := client.httpClient.Transport.(*http.Transport)
+			if !ok {
+				t.Fatalf("Expected an *http.Transport, got %T", client.httpClient.Transport)
+			}
+			if transport.MaxIdleConnsPerHost != tt.expected || transport.MaxConnsPerHost != tt.expected ||
+				transport.MaxIdleConns != tt.expected {
+				t.Errorf("Expected pool limits of %d, got idle=%d idle/host=%d conns/host=%d", tt.expected,
+					transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+			}
+			if transport.DisableKeepAlives {
+				t.Error("Expected keep-alives to be enabled")
+			}
+			if cap(client.requests) != tt.expected {
+				t.Errorf("Expected %d requests in flight at most, got %d", tt.expected, cap(client.requests))
+			}
+		})
+	}
+}
+
+func TestGenerateEmbeddings_BoundsRequestsInFlight(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := peak.Load()
+			if current <= seen || peak.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		json.NewEncoder(w).Encode(EmbedResponse{Embedding: make([]float32, 8)})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.EmbeddingsConfig{
+		Model:                 "nomic-embed-text",
+		OllamaURL:             server.URL,
+		FullDimension:         8,
+		Dimensions:            8,
+		MaxConcurrentRequests: 2,
+	})
+	texts := make([]string, 12)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text %d", i)
+	}
+	embeddings, err := client.GenerateEmbeddings(texts)
+	if err != nil {
+		t.Fatalf("GenerateEmbeddings failed: %v", err)
+	}
+
+	if len(embeddings) != len(texts) {
+		t.Errorf("Expected %d embeddings, got %d", len(texts), len(embeddings))
+	}
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", peak.Load())
+	}
+}
+
+func TestGenerateEmbeddingContext_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(&config.EmbeddingsConfig{Model: "nomic-embed-text", OllamaURL: server.URL})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.GenerateEmbeddingContext(ctx, "slow query"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the request to stop at the deadline, got %v", err)
+	}
+}
+
+func TestGenerateEmbedding_KeepAlive(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepAlive string
+		expect    interface{} // Decoded keep_alive, nil when absent
+	}{
+		{"duration", "10m", "10m"},
+		{"seconds", "-1", float64(-1)},
+		{"unset", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("Failed to decode request: %v", err)
+				}
+				json.NewEncoder(w).Encode(EmbedResponse{Embedding: make([]float32, 768)})
+			}))
+			defer server.Close()
+
+			client := NewClient(&config.EmbeddingsConfig{
+				Model:         "nomic-embed-text",
+				OllamaURL:     server.URL,
+				FullDimension: 768,
+				Dimensions:    768,
+				KeepAlive:     tt.keepAlive,
+			})
+			if _, err := client.GenerateEmbedding("keep me loaded"); err != nil {
+				t.Fatalf("GenerateEmbedding failed: %v", err)
+			}
+
+			value, present := body["keep_alive"]
+			if tt.expect == nil {
+				if present {
+					t.Errorf("Expected no keep_alive field, got %v", value)
+				}
+				return
+			}
+			if value != tt.expect {
+				t.Errorf("Expected keep_alive %v (%T), got %v (%T)", tt.expect, tt.expect, value, value)
+			}
+		})
+	}
+}
+
+// wordTokenizer treats every space-separated word as one token
+type wordTokenizer struct{}
+
+func (wordTokenizer) TruncateTokens(text string, maxTokens int) (string, int) {
+	words := strings.Fields(text)
+	if len(words) <= maxTokens {
+		return text, 0
+	}
+	return strings.Join(words[:maxTokens], " "), len(words) - maxTokens
+}
+
+func TestTruncate(t *testing.T) {
+	// 2000 four-letter words: 9999 chars, 2000 "tokens"
+	text := strings.TrimSpace(strings.Repeat("word ", 2000))
+
+	tests := []struct {
+		name          string
+		contextLength int
+		tokenizer     Tokenizer
+		expectedLen   int
+	}{
+		{"char fallback without tokenizer", 8192, nil, fallbackMaxChars},
+		{"char fallback without context length", 0, wordTokenizer{}, fallbackMaxChars},
+		// 8192 * 0.75 = 6144 tokens: the whole text fits, where the char heuristic would cut it
+		{"fits the token budget", 8192, wordTokenizer{}, len(text)},
+		// 1000 * 0.75 = 750 tokens
+		{"cut by tokens", 1000, wordTokenizer{}, 750*4 + 749},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&config.EmbeddingsConfig{ContextLength: tt.contextLength})
+			if tt.tokenizer != nil {
+				client.SetTokenizer(tt.tokenizer)
+			}
+			if got := len(client.truncate(text)); got != tt.expectedLen {
+				t.Errorf("Expected %d chars, got %d", tt.expectedLen, got)
+			}
+		})
+	}
+}
+
+func TestTruncate_CharFallbackKeepsRunes(t *testing.T) {
+	client := NewClient(&config.EmbeddingsConfig{})
+	truncated := client.truncate(strings.Repeat("ü", fallbackMaxChars))
+	if !utf8.ValidString(truncated) || len(truncated) > fallbackMaxChars {
+		t.Errorf("Expected valid UTF-8 within %d bytes, got %d bytes", fallbackMaxChars, len(truncated))
+	}
+}
@@ -0,0 +1,63 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// ProbeDimension embeds a trivial string and returns the model's output dimension
+func (c *Client) ProbeDimension(ctx context.Context) (int, error) {
+	embedding, err := c.requestEmbedding(ctx, "dimension probe")
+	if err != nil {
+		return 0, fmt.Errorf("failed to probe embedding dimension of %q: %w", c.config.Model, err)
+	}
+	if len(embedding) == 0 {
+		return 0, fmt.Errorf("model %q returned an empty embedding", c.config.Model)
+	}
+	return len(embedding), nil
+}
+
+// ReconcileDimensions checks the configured dimensions against the model's detected output dimension
+// With embeddings.auto_detect_dimension, full_dimension is set to the detected dimension and
+// vectordb.vector_size to the dimension actually stored (after MRL truncation); otherwise a
+// mismatch is returned as an error explaining the fix.
+func ReconcileDimensions(cfg *config.Config, detected int) error {
+	emb := &cfg.Embeddings
+	fullDim := emb.FullDimension
+	if fullDim == 0 {
+		fullDim = 768 // Default for nomic-embed-text
+	}
+
+	if !emb.AutoDetectDimension {
+		if fullDim != detected {
+			return dimensionMismatchError(emb.Model, fullDim, detected)
+		}
+		if stored := storedDimension(emb, detected); cfg.VectorDB.VectorSize != stored {
+			return fmt.Errorf("vectordb.vector_size is %d, but model %q embeddings are stored with %d dimensions: "+
+				"set vectordb.vector_size to %d, or enable embeddings.auto_detect_dimension", cfg.VectorDB.VectorSize, emb.Model, stored, stored)
+		}
+		return nil
+	}
+
+	if emb.FullDimension != detected {
+		slog.Info("Using detected embedding dimension", "model", emb.Model, "detected", detected, "configured", emb.FullDimension)
+		emb.FullDimension = detected
+	}
+	if stored := storedDimension(emb, detected); cfg.VectorDB.VectorSize != stored {
+		slog.Info("Adjusted vector size to the stored embedding dimension", "vector_size", stored, "configured", cfg.VectorDB.VectorSize)
+		cfg.VectorDB.VectorSize = stored
+	}
+	return nil
+}
+
+// storedDimension returns the length of the vectors stored for a model with fullDim output,
+// which is smaller than fullDim when MRL truncation applies
+func storedDimension(emb *config.EmbeddingsConfig, fullDim int) int {
+	if emb.UseMRL && emb.Dimensions < fullDim {
+		return len(applyMRL(make([]float32, fullDim), emb.Dimensions))
+	}
+	return fullDim
+}
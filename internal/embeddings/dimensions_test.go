@@ -0,0 +1,110 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// newDimensionServer returns a mock Ollama whose model outputs dim-dimensional embeddings
+func newDimensionServer(t *testing.T, dim int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(EmbedResponse{Embedding: make([]float32, dim)})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProbeDimension(t *testing.T) {
+	server := newDimensionServer(t, 1024)
+	client := NewClient(&config.EmbeddingsConfig{Model: "mxbai-embed-large", OllamaURL: server.URL, FullDimension: 768})
+
+	// The probe reports the model's dimension even when the config disagrees
+	dim, err := client.ProbeDimension(context.Background())
+	if err != nil {
+		t.Fatalf("ProbeDimension failed: %v", err)
+	}
+	if dim != 1024 {
+		t.Errorf("Expected 1024 dimensions, got %d", dim)
+	}
+
+	empty := NewClient(&config.EmbeddingsConfig{Model: "broken", OllamaURL: newDimensionServer(t, 0).URL})
+	if _, err := empty.ProbeDimension(context.Background()); err == nil {
+		t.Error("Expected an error for an empty embedding")
+	}
+}
+
+func TestReconcileDimensions(t *testing.T) {
+	tests := []struct {
+		name           string
+		detected       int
+		fullDimension  int
+		dimensions     int
+		useMRL         bool
+		autoDetect     bool
+		vectorSize     int
+		wantFull       int
+		wantVectorSize int
+		wantErr        string
+	}{
+		{"matching config is kept", 768, 768, 256, true, true, 256, 768, 256, ""},
+		{"larger model keeps the MRL size", 1024, 768, 256, true, true, 256, 1024, 256, ""},
+		{"without MRL the vector size follows the model", 384, 768, 768, false, true, 768, 384, 384, ""},
+		{"MRL target above the model dimension", 384, 768, 512, true, true, 512, 384, 384, ""},
+		{"unset full dimension", 1024, 0, 0, false, true, 768, 1024, 1024, ""},
+		{"mismatch without auto-detection", 1024, 768, 256, true, false, 256, 768, 256, "full_dimension"},
+		{"vector size mismatch without auto-detection", 768, 768, 256, true, false, 768, 768, 768, "vector_size"},
+		{"matching config without auto-detection", 768, 768, 256, true, false, 256, 768, 256, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Embeddings.FullDimension = tt.fullDimension
+			cfg.Embeddings.Dimensions = tt.dimensions
+			cfg.Embeddings.UseMRL = tt.useMRL
+			cfg.Embeddings.AutoDetectDimension = tt.autoDetect
+			cfg.VectorDB.VectorSize = tt.vectorSize
+
+			// Detect through a mock Ollama, as the server does at startup
+			client := NewClient(&cfg.Embeddings)
+			client.baseURL = newDimensionServer(t, tt.detected).URL
+			detected, err := client.ProbeDimension(context.Background())
+			if err != nil {
+				t.Fatalf("ProbeDimension failed: %v", err)
+			}
+
+			err = ReconcileDimensions(cfg, detected)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error mentioning %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("ReconcileDimensions failed: %v", err)
+			}
+			if cfg.Embeddings.FullDimension != tt.wantFull {
+				t.Errorf("Expected full_dimension %d, got %d", tt.wantFull, cfg.Embeddings.FullDimension)
+			}
+			if cfg.VectorDB.VectorSize != tt.wantVectorSize {
+				t.Errorf("Expected vector_size %d, got %d", tt.wantVectorSize, cfg.VectorDB.VectorSize)
+			}
+
+			// Once reconciled, embeddings come back at the stored size
+			if tt.wantErr == "" {
+				embedding, err := client.GenerateEmbedding("func main() {}")
+				if err != nil {
+					t.Fatalf("GenerateEmbedding failed after reconciling: %v", err)
+				}
+				if len(embedding) != cfg.VectorDB.VectorSize {
+					t.Errorf("Expected %d-dimensional embeddings, got %d", cfg.VectorDB.VectorSize, len(embedding))
+				}
+			}
+		})
+	}
+}
@@ -2,17 +2,20 @@ package indexer
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"regexp"
 	"strings"
 	"sync"
 
-	"github.com/google/uuid"
 	"github.com/jamaly87/codebase-semantic-search/internal/models"
 	"github.com/jamaly87/codebase-semantic-search/pkg/config"
 	sitter "github.com/smacker/go-tree-sitter"
 	"github.com/smacker/go-tree-sitter/java"
 	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/kotlin"
+	"github.com/smacker/go-tree-sitter/php"
+	"github.com/smacker/go-tree-sitter/ruby"
+	"github.com/smacker/go-tree-sitter/swift"
 	"github.com/smacker/go-tree-sitter/typescript/typescript"
 )
 
@@ -39,12 +42,40 @@ const (
 	nodeTypeTSInterface       = "interface_declaration"
 	nodeTypeTSTypeAlias       = "type_alias_declaration"
 
+	// Kotlin node types (interfaces and enums are class_declaration too)
+	nodeTypeKotlinClass       = "class_declaration"
+	nodeTypeKotlinObject      = "object_declaration"
+	nodeTypeKotlinFunction    = "function_declaration"
+
+	// Ruby node types ("def self.x" is a singleton_method)
+	nodeTypeRubyClass           = "class"
+	nodeTypeRubyModule          = "module"
+	nodeTypeRubyMethod          = "method"
+	nodeTypeRubySingletonMethod = "singleton_method"
+
+	// PHP node types (HTML outside <?php ?> tags is a text node)
+	nodeTypePHPClass     = "class_declaration"
+	nodeTypePHPInterface = "interface_declaration"
+	nodeTypePHPTrait     = "trait_declaration"
+	nodeTypePHPEnum      = "enum_declaration"
+	nodeTypePHPFunction  = "function_definition"
+	nodeTypePHPMethod    = "method_declaration"
+	nodeTypePHPNamespace = "namespace_definition"
+	nodeTypePHPProgram   = "program"
+
+	// Swift node types (classes, structs, enums, actors and extensions are all "class_declaration")
+	nodeTypeSwiftClass    = "class_declaration"
+	nodeTypeSwiftProtocol = "protocol_declaration"
+	nodeTypeSwiftFunction = "function_declaration"
+	nodeTypeSwiftInit     = "init_declaration"
+
 	// Common identifier node types
 	nodeTypeIdentifier        = "identifier"
 	nodeTypeName              = "name"
 	nodeTypePropertyID        = "property_identifier"
 	nodeTypeTypeID            = "type_identifier"
 	nodeTypeVariableDecl      = "variable_declarator"
+	nodeTypeSimpleID          = "simple_identifier" // Kotlin function names
 )
 
 // Chunking constants
@@ -103,18 +134,40 @@ func (ac *ASTChunker) initializeParsers() {
 	tsParser.SetLanguage(typescript.GetLanguage())
 	ac.parsers["typescript"] = tsParser
 
-	log.Println("✓ AST parsers initialized: Java, JavaScript, TypeScript")
+	// Kotlin parser
+	kotlinParser := sitter.NewParser()
+	kotlinParser.SetLanguage(kotlin.GetLanguage())
+	ac.parsers["kotlin"] = kotlinParser
+
+	// Ruby parser
+	rubyParser := sitter.NewParser()
+	rubyParser.SetLanguage(ruby.GetLanguage())
+	ac.parsers["ruby"] = rubyParser
+
+	// PHP parser (the grammar handles HTML mixed with PHP)
+	phpParser := sitter.NewParser()
+	phpParser.SetLanguage(php.GetLanguage())
+	ac.parsers["php"] = phpParser
+
+	// Swift parser
+	swiftParser := sitter.NewParser()
+	swiftParser.SetLanguage(swift.GetLanguage())
+	ac.parsers["swift"] = swiftParser
+
+	slog.Debug("AST parsers initialized", "languages", "Java, JavaScript, TypeScript, Kotlin, Ruby, PHP, Swift")
 }
 
 // ChunkByAST extracts semantic chunks (functions, classes, methods) using AST
 // Supports hierarchical chunking for large classes/interfaces
+// Code the parser could not make sense of is returned as broken regions, whole lines
+// in document order, for the caller to chunk another way.
 // Thread-safe: uses mutex to protect Tree-sitter parser access
-func (ac *ASTChunker) ChunkByAST(repoPath, filePath, language, content string, cfg *config.ChunkingConfig) ([]models.CodeChunk, error) {
+func (ac *ASTChunker) ChunkByAST(repoPath, filePath, language, content string, cfg *config.ChunkingConfig) ([]models.CodeChunk, []brokenRegion, error) {
 	ac.mux.Lock()
 	parser, err := ac.getParser(language)
 	if err != nil {
 		ac.mux.Unlock()
-		return nil, fmt.Errorf("parser not available for %s: %w", language, err)
+		return nil, nil, fmt.Errorf("parser not available for %s: %w", language, err)
 	}
 
 	// Parse the code (Tree-sitter parsers are NOT thread-safe)
@@ -123,14 +176,73 @@ func (ac *ASTChunker) ChunkByAST(repoPath, filePath, language, content string, c
 	ac.mux.Unlock()
 
 	if tree == nil {
-		return nil, fmt.Errorf("failed to parse file")
+		return nil, nil, fmt.Errorf("failed to parse file")
 	}
 
 	// Extract semantic nodes with hierarchical chunking if enabled
 	// Tree operations are safe to do without the lock
 	chunks := ac.extractSemanticNodes(tree, repoPath, filePath, language, content, cfg)
 
-	return chunks, nil
+	// Tree-sitter recovers from syntax errors by wrapping broken code in ERROR nodes,
+	// whose code would be silently dropped; hand it back to the caller instead
+	var broken []brokenRegion
+	if root := tree.RootNode(); root != nil && root.HasError() {
+		broken = mergeRegions(ac.errorRegions(root, content, ac.getSemanticNodeTypes(language)), content)
+	}
+
+	return chunks, broken, nil
+}
+
+// brokenRegion is a run of whole lines holding code the parser could not make sense of
+type brokenRegion struct {
+	startLine  int // 1-based file line of the region's first line
+	content    string
+	start, end int // byte offsets of the lines in the file
+}
+
+// errorRegions returns the whole lines of each ERROR node outside the semantic nodes
+// A semantic node's chunk already holds any broken code inside it.
+func (ac *ASTChunker) errorRegions(node *sitter.Node, content string, nodeTypes map[string]bool) []brokenRegion {
+	if node == nil {
+		return nil
+	}
+	if node.IsError() {
+		start := strings.LastIndexByte(content[:node.StartByte()], '\n') + 1
+		end := len(content)
+		if i := strings.IndexByte(content[node.EndByte():], '\n'); i >= 0 {
+			end = int(node.EndByte()) + i
+		}
+		return []brokenRegion{{
+			startLine: int(node.StartPoint().Row) + 1,
+			content:   content[start:end],
+			start:     start,
+			end:       end,
+		}}
+	}
+	if nodeTypes[node.Type()] {
+		return nil
+	}
+
+	var regions []brokenRegion
+	for i := 0; i < int(node.ChildCount()); i++ {
+		regions = append(regions, ac.errorRegions(node.Child(i), content, nodeTypes)...)
+	}
+	return regions
+}
+
+// mergeRegions joins regions, in document order, that share a line
+func mergeRegions(regions []brokenRegion, content string) []brokenRegion {
+	var merged []brokenRegion
+	for _, region := range regions {
+		if n := len(merged); n > 0 && region.start <= merged[n-1].end {
+			last := &merged[n-1]
+			last.end = max(last.end, region.end)
+			last.content = content[last.start:last.end]
+			continue
+		}
+		merged = append(merged, region)
+	}
+	return merged
 }
 
 // getParser returns a Tree-sitter parser for the given language
@@ -163,7 +275,7 @@ func (ac *ASTChunker) extractSemanticNodes(tree *sitter.Tree, repoPath, filePath
 	ac.walkTree(root, content, nodeTypes, func(node *sitter.Node, nodeType string) {
 		// Check if this is a large class/interface that should be split hierarchically
 		if cfg.EnableHierarchicalChunking && ac.isLargeClassOrInterface(node, nodeType, content, maxChunkSize) {
-			hierarchicalChunks := ac.createHierarchicalChunks(node, repoPath, filePath, language, content, nodeType, maxChunkSize)
+			hierarchicalChunks := ac.createHierarchicalChunks(node, repoPath, filePath, language, content, nodeType, maxChunkSize, overlapFraction(cfg))
 			chunks = append(chunks, hierarchicalChunks...)
 		} else {
 			// Regular chunking for smaller nodes
@@ -171,7 +283,7 @@ func (ac *ASTChunker) extractSemanticNodes(tree *sitter.Tree, repoPath, filePath
 			if chunk != nil {
 				// If chunk is still too large, split it intelligently
 				if len(chunk.Content) > maxChunkSize {
-					splitChunks := ac.splitLargeChunk(chunk, content, maxChunkSize)
+					splitChunks := ac.splitLargeChunk(chunk, content, maxChunkSize, overlapFraction(cfg))
 					chunks = append(chunks, splitChunks...)
 				} else {
 					chunks = append(chunks, *chunk)
@@ -210,6 +322,31 @@ func (ac *ASTChunker) getSemanticNodeTypes(language string) map[string]bool {
 			nodeTypeJSMethod,
 			nodeTypeJSArrowFunction,
 		},
+		"kotlin": {
+			nodeTypeKotlinClass,
+			nodeTypeKotlinObject,
+			nodeTypeKotlinFunction,
+		},
+		"ruby": {
+			nodeTypeRubyClass,
+			nodeTypeRubyModule,
+			nodeTypeRubyMethod,
+			nodeTypeRubySingletonMethod,
+		},
+		"php": {
+			nodeTypePHPClass,
+			nodeTypePHPInterface,
+			nodeTypePHPTrait,
+			nodeTypePHPEnum,
+			nodeTypePHPFunction,
+			nodeTypePHPMethod,
+		},
+		"swift": {
+			nodeTypeSwiftClass,
+			nodeTypeSwiftProtocol,
+			nodeTypeSwiftFunction,
+			nodeTypeSwiftInit,
+		},
 	}
 
 	types := nodeTypesMap[language]
@@ -250,6 +387,11 @@ func (ac *ASTChunker) walkTree(node *sitter.Node, content string, nodeTypes map[
 		return
 	}
 
+	// Broken code is chunked whole by the caller (see errorRegions)
+	if node.IsError() {
+		return
+	}
+
 	nodeType := node.Type()
 
 	// Check if this is a semantic node we care about
@@ -289,10 +431,7 @@ func (ac *ASTChunker) createChunkFromNode(node *sitter.Node, repoPath, filePath,
 		return nil
 	}
 
-	// Ensure chunk doesn't exceed safe size
-	if len(chunkContent) > defaultMaxChunkSizeBytes {
-		chunkContent = chunkContent[:defaultMaxChunkSizeBytes]
-	}
+	// Oversized nodes are split by the caller (splitLargeChunk), never truncated here
 
 	// Get line numbers
 	startPoint := node.StartPoint()
@@ -301,10 +440,10 @@ func (ac *ASTChunker) createChunkFromNode(node *sitter.Node, repoPath, filePath,
 	endLine := int(endPoint.Row) + 1
 
 	// Extract function/class name
-	name := ac.extractNodeName(node, content)
+	name := ac.nodeName(node, language, content)
 
 	chunk := &models.CodeChunk{
-		ID:        uuid.New().String(),
+		ID:        models.ChunkID(repoPath, filePath, startLine, endLine, chunkContent),
 		RepoPath:  repoPath,
 		FilePath:  filePath,
 		ChunkType: models.ChunkTypeFunction,
@@ -322,6 +461,11 @@ func (ac *ASTChunker) createChunkFromNode(node *sitter.Node, repoPath, filePath,
 		nodeTypeJavaEnum,
 		nodeTypeJSClass,
 		nodeTypeTSInterface,
+		nodeTypeKotlinObject,
+		nodeTypeRubyClass,
+		nodeTypeRubyModule,
+		nodeTypePHPTrait,
+		nodeTypeSwiftProtocol,
 	}
 
 	functionNodeTypes := []string{
@@ -331,6 +475,11 @@ func (ac *ASTChunker) createChunkFromNode(node *sitter.Node, repoPath, filePath,
 		nodeTypeJavaConstructor,
 		nodeTypeJSArrowFunction,
 		nodeTypeJSFunctionExpr,
+		nodeTypeKotlinFunction,
+		nodeTypeRubyMethod,
+		nodeTypeRubySingletonMethod,
+		nodeTypePHPFunction,
+		nodeTypeSwiftInit,
 	}
 
 	switch {
@@ -343,7 +492,20 @@ func (ac *ASTChunker) createChunkFromNode(node *sitter.Node, repoPath, filePath,
 	default:
 		// Log unexpected node types for debugging (but don't fail)
 		// This helps identify if Tree-sitter grammar changes
-		log.Printf("Unexpected node type in createChunkFromNode: %q (file: %s)", nodeType, filePath)
+		slog.Warn("Unexpected node type in createChunkFromNode", "type", nodeType, "file", filePath)
+	}
+
+	// Ruby methods keep the class or module they are defined in
+	if language == "ruby" && chunk.FunctionName != "" {
+		chunk.ClassName = rubyQualifiedName(node.Parent(), content)
+	}
+	// PHP methods keep their class, interface or trait, qualified by its namespace
+	if language == "php" && nodeType == nodeTypePHPMethod {
+		chunk.ClassName = phpEnclosingClass(node, content)
+	}
+	// Swift methods keep their enclosing type, qualified by the types it is nested in
+	if language == "swift" && chunk.FunctionName != "" {
+		chunk.ClassName = swiftQualifiedName(node.Parent(), content)
 	}
 
 	return chunk
@@ -368,7 +530,8 @@ func (ac *ASTChunker) extractNodeName(node *sitter.Node, content string) string
 		// Check for identifier or name node
 		// These node types are consistent across Tree-sitter grammars
 		if childType == nodeTypeIdentifier || childType == nodeTypeName ||
-		   childType == nodeTypePropertyID || childType == nodeTypeTypeID {
+		   childType == nodeTypePropertyID || childType == nodeTypeTypeID ||
+		   childType == nodeTypeSimpleID {
 			start := child.StartByte()
 			end := child.EndByte()
 			if int(start) < int(end) && int(end) <= len(content) {
@@ -388,6 +551,138 @@ func (ac *ASTChunker) extractNodeName(node *sitter.Node, content string) string
 	return ""
 }
 
+// nodeName returns the name of a function/class node, using language-specific rules where needed
+func (ac *ASTChunker) nodeName(node *sitter.Node, language, content string) string {
+	switch language {
+	case "ruby":
+		return rubyNodeName(node, content)
+	case "php":
+		return phpNodeName(node, content)
+	case "swift":
+		return swiftNodeName(node, content)
+	}
+	return ac.extractNodeName(node, content)
+}
+
+// rubyNodeName returns a Ruby method's name (without "self." for singleton methods),
+// or a class or module's name qualified by the modules and classes it is nested in
+func rubyNodeName(node *sitter.Node, content string) string {
+	switch node.Type() {
+	case nodeTypeRubyClass, nodeTypeRubyModule:
+		return rubyQualifiedName(node, content)
+	}
+	if name := node.ChildByFieldName("name"); name != nil {
+		return name.Content([]byte(content))
+	}
+	return ""
+}
+
+// rubyQualifiedName returns the "::"-joined names of node and the classes and modules
+// enclosing it (e.g. Billing::Invoices::Generator)
+func rubyQualifiedName(node *sitter.Node, content string) string {
+	var names []string
+	for n := node; n != nil; n = n.Parent() {
+		if n.Type() != nodeTypeRubyClass && n.Type() != nodeTypeRubyModule {
+			continue
+		}
+		if name := n.ChildByFieldName("name"); name != nil {
+			names = append([]string{name.Content([]byte(content))}, names...)
+		}
+	}
+	return strings.Join(names, "::")
+}
+
+// phpNodeName returns a PHP function or method's name, or a class, interface, trait or enum's
+// name qualified by its namespace (e.g. App\Http\Controllers\UserController)
+func phpNodeName(node *sitter.Node, content string) string {
+	name := node.ChildByFieldName("name")
+	if name == nil {
+		return ""
+	}
+	switch node.Type() {
+	case nodeTypePHPClass, nodeTypePHPInterface, nodeTypePHPTrait, nodeTypePHPEnum:
+		if namespace := phpNamespace(node, content); namespace != "" {
+			return namespace + `\` + name.Content([]byte(content))
+		}
+	}
+	return name.Content([]byte(content))
+}
+
+// phpEnclosingClass returns the qualified name of the class-like declaration a method belongs to
+func phpEnclosingClass(node *sitter.Node, content string) string {
+	for n := node.Parent(); n != nil; n = n.Parent() {
+		switch n.Type() {
+		case nodeTypePHPClass, nodeTypePHPInterface, nodeTypePHPTrait, nodeTypePHPEnum:
+			return phpNodeName(n, content)
+		}
+	}
+	return ""
+}
+
+// phpNamespace returns the namespace a declaration is in: the enclosing braced
+// "namespace X { ... }" block, or else the last "namespace X;" statement before it
+func phpNamespace(node *sitter.Node, content string) string {
+	src := []byte(content)
+	for n := node; n.Parent() != nil; n = n.Parent() {
+		parent := n.Parent()
+		switch parent.Type() {
+		case nodeTypePHPNamespace:
+			if name := parent.ChildByFieldName("name"); name != nil {
+				return name.Content(src)
+			}
+			return ""
+		case nodeTypePHPProgram:
+			namespace := ""
+			for i := 0; i < int(parent.NamedChildCount()); i++ {
+				child := parent.NamedChild(i)
+				if child.StartByte() >= n.StartByte() {
+					break
+				}
+				if child.Type() != nodeTypePHPNamespace || child.ChildByFieldName("body") != nil {
+					continue
+				}
+				if name := child.ChildByFieldName("name"); name != nil {
+					namespace = name.Content(src)
+				}
+			}
+			return namespace
+		}
+	}
+	return ""
+}
+
+// swiftNodeName returns a Swift function's name ("init" for initializers), or a type's name
+// qualified by the types it is nested in (e.g. Outer.Inner); an extension is named after the
+// type it extends
+func swiftNodeName(node *sitter.Node, content string) string {
+	switch node.Type() {
+	case nodeTypeSwiftClass, nodeTypeSwiftProtocol:
+		return swiftQualifiedName(node, content)
+	case nodeTypeSwiftInit:
+		return "init"
+	}
+	if name := node.ChildByFieldName("name"); name != nil {
+		return name.Content([]byte(content))
+	}
+	return ""
+}
+
+// swiftQualifiedName returns the "."-joined names of node and the types and extensions
+// enclosing it, outermost first
+func swiftQualifiedName(node *sitter.Node, content string) string {
+	var names []string
+	for n := node; n != nil; n = n.Parent() {
+		if n.Type() != nodeTypeSwiftClass && n.Type() != nodeTypeSwiftProtocol {
+			continue
+		}
+		// Extensions name a user_type (e.g. Outer.Inner), other declarations a type_identifier
+		if name := n.ChildByFieldName("name"); name != nil {
+			names = append([]string{name.Content([]byte(content))}, names...)
+		}
+	}
+	return strings.Join(names, ".")
+}
+
 // contains checks if a slice contains a string
 func contains(slice []string, str string) bool {
 	for _, s := range slice {
@@ -414,6 +709,10 @@ func (ac *ASTChunker) Close() {
 // nodeType is a string returned by Tree-sitter's node.Type() method, which is defined by the grammar.
 // For Java: classes are "class_declaration", interfaces are "interface_declaration", enums are "enum_declaration"
 // For JavaScript/TypeScript: classes are "class_declaration", interfaces are "interface_declaration"
+// For Kotlin: classes, interfaces and enums are "class_declaration", objects are "object_declaration"
+// For Ruby: classes are "class", modules are "module"
+// For PHP: classes, interfaces and enums share the Java names, traits are "trait_declaration"
+// For Swift: classes, structs, enums, actors and extensions are "class_declaration", protocols are "protocol_declaration"
 func (ac *ASTChunker) isLargeClassOrInterface(node *sitter.Node, nodeType string, content string, maxSize int) bool {
 	// Only split classes and interfaces
 	// These node types are defined by Tree-sitter grammars and are consistent for each language
@@ -423,6 +722,11 @@ func (ac *ASTChunker) isLargeClassOrInterface(node *sitter.Node, nodeType string
 		nodeTypeJavaEnum,
 		nodeTypeJSClass,
 		nodeTypeTSInterface,
+		nodeTypeKotlinObject,
+		nodeTypeRubyClass,
+		nodeTypeRubyModule,
+		nodeTypePHPTrait,
+		nodeTypeSwiftProtocol,
 	}
 
 	if !contains(classNodeTypes, nodeType) {
@@ -441,11 +745,11 @@ func (ac *ASTChunker) isLargeClassOrInterface(node *sitter.Node, nodeType string
 
 // createHierarchicalChunks creates a class summary chunk + individual method chunks
 // This allows better search granularity for large classes
-func (ac *ASTChunker) createHierarchicalChunks(node *sitter.Node, repoPath, filePath, language, content, nodeType string, maxSize int) []models.CodeChunk {
+func (ac *ASTChunker) createHierarchicalChunks(node *sitter.Node, repoPath, filePath, language, content, nodeType string, maxSize int, overlap float64) []models.CodeChunk {
 	var chunks []models.CodeChunk
 
 	// Extract class name and create summary chunk
-	className := ac.extractNodeName(node, content)
+	className := ac.nodeName(node, language, content)
 	startPoint := node.StartPoint()
 	endPoint := node.EndPoint()
 	startLine := int(startPoint.Row) + 1
@@ -454,7 +758,6 @@ func (ac *ASTChunker) createHierarchicalChunks(node *sitter.Node, repoPath, file
 	// Create class summary chunk (signature + fields + brief method list)
 	summaryContent := ac.createClassSummary(node, content, language)
 	summaryChunk := &models.CodeChunk{
-		ID:        uuid.New().String(),
 		RepoPath:  repoPath,
 		FilePath:  filePath,
 		ChunkType: models.ChunkTypeClass,
@@ -465,10 +768,10 @@ func (ac *ASTChunker) createHierarchicalChunks(node *sitter.Node, repoPath, file
 		ClassName: className,
 	}
 
-	// Ensure summary doesn't exceed max size
-	if len(summaryChunk.Content) > maxSize {
-		summaryChunk.Content = summaryChunk.Content[:maxSize]
-	}
+	// Ensure summary doesn't exceed max size; it is a synthesized outline, so dropping
+	// trailing lines loses no code (the methods get their own chunks)
+	summaryChunk.Content = truncateAtLineBoundary(summaryChunk.Content, maxSize)
+	summaryChunk.ID = models.ChunkID(repoPath, filePath, startLine, endLine, summaryChunk.Content)
 
 	chunks = append(chunks, *summaryChunk)
 	summaryChunkID := summaryChunk.ID
@@ -484,7 +787,7 @@ func (ac *ASTChunker) createHierarchicalChunks(node *sitter.Node, repoPath, file
 
 			// If method is still too large, split it
 			if len(methodChunk.Content) > maxSize {
-				splitChunks := ac.splitLargeChunk(methodChunk, content, maxSize)
+				splitChunks := ac.splitLargeChunk(methodChunk, content, maxSize, overlap)
 				chunks = append(chunks, splitChunks...)
 			} else {
 				chunks = append(chunks, *methodChunk)
@@ -552,7 +855,7 @@ func (ac *ASTChunker) createClassSummary(node *sitter.Node, content string, lang
 						// Get first line (signature) and truncate if too long
 						sig := strings.TrimSpace(methodLines[0])
 						if len(sig) > methodSignatureMaxLength {
-							sig = sig[:methodSignatureMaxLength] + "..."
+							sig = truncateAtRuneBoundary(sig, methodSignatureMaxLength) + "..."
 						}
 						summary.WriteString(fmt.Sprintf("// - %s\n", sig))
 					}
@@ -574,6 +877,10 @@ func (ac *ASTChunker) extractMethodNodes(classNode *sitter.Node, language string
 		"java":       {nodeTypeJavaMethod, nodeTypeJavaConstructor},
 		"javascript": {nodeTypeJSMethod, nodeTypeJSFunction},
 		"typescript": {nodeTypeJSMethod, nodeTypeJSFunction},
+		"kotlin":     {nodeTypeKotlinFunction},
+		"ruby":       {nodeTypeRubyMethod, nodeTypeRubySingletonMethod},
+		"php":        {nodeTypePHPMethod},
+		"swift":      {nodeTypeSwiftFunction, nodeTypeSwiftInit},
 	}
 
 	types := methodTypes[language]
@@ -613,6 +920,18 @@ func (ac *ASTChunker) isMethodStart(line string, language string) bool {
 			`^\s*(public|private|protected)?\s*\w+\s*\(`,
 			`^\s*async\s+\w+\s*\(`,
 		},
+		"kotlin": {
+			`^\s*(@\w+\s+)*(\w+\s+)*fun\s`,
+		},
+		"ruby": {
+			`^\s*((private|protected|public)\s+)?def\s`,
+		},
+		"php": {
+			`^\s*((public|private|protected|static|abstract|final)\s+)*function\s`,
+		},
+		"swift": {
+			`^\s*(@\w+\s+)*(\w+\s+)*(func\s|init[?!]?\s*[(<])`,
+		},
 	}
 
 	langPatterns := patterns[language]
@@ -630,88 +949,45 @@ func (ac *ASTChunker) isMethodStart(line string, language string) bool {
 	return false
 }
 
-// splitLargeChunk splits a large chunk at natural boundaries (statements, blocks)
-func (ac *ASTChunker) splitLargeChunk(chunk *models.CodeChunk, fullContent string, maxSize int) []models.CodeChunk {
-	var splitChunks []models.CodeChunk
-
-	// For now, use simple line-based splitting with overlap
-	// In a more sophisticated implementation, we'd parse the chunk's AST
-	// and split at statement boundaries
+// splitLargeChunk splits a large chunk into multiple complete chunks at line boundaries
+// Every line of the original chunk ends up in some chunk; consecutive chunks overlap slightly
+// for context and share the original's function/class names and parent.
+// overlapFraction sets the overlap as a fraction of each piece's lines (0 = the default overlap).
+func (ac *ASTChunker) splitLargeChunk(chunk *models.CodeChunk, fullContent string, maxSize int, overlapFraction float64) []models.CodeChunk {
 	lines := strings.Split(chunk.Content, "\n")
-	currentChunk := strings.Builder{}
-	currentStartLine := chunk.StartLine
 
 	// Determine overlap lines proportionally to the chunk size:
 	// use ~10% of total lines, with at least 1 and at most 10 lines of overlap.
 	overlapLines := len(lines) / overlapLinesRatio
-	if overlapLines < minOverlapLines {
-		overlapLines = minOverlapLines
-	} else if overlapLines > maxOverlapLines {
-		overlapLines = maxOverlapLines
-	}
-	for i, line := range lines {
-		currentChunk.WriteString(line)
-		currentChunk.WriteString("\n")
-
-		// If we've accumulated enough content, create a chunk
-		if currentChunk.Len() > maxSize && i < len(lines)-1 {
-			chunkContent := currentChunk.String()
-			if len(chunkContent) > maxSize {
-				chunkContent = chunkContent[:maxSize]
-			}
-
-			newChunk := &models.CodeChunk{
-				ID:           uuid.New().String(),
-				RepoPath:     chunk.RepoPath,
-				FilePath:     chunk.FilePath,
-				ChunkType:    chunk.ChunkType,
-				Content:      chunkContent,
-				Language:     chunk.Language,
-				StartLine:    currentStartLine,
-				EndLine:      chunk.StartLine + i,
-				FunctionName: chunk.FunctionName,
-				ClassName:    chunk.ClassName,
-				ParentChunkID: chunk.ParentChunkID,
-			}
-
-			splitChunks = append(splitChunks, *newChunk)
-
-			// Start next chunk with overlap
-			currentChunk.Reset()
-			overlapStart := i - overlapLines
-			if overlapStart < 0 {
-				overlapStart = 0
-			}
-			for j := overlapStart; j <= i; j++ {
-				currentChunk.WriteString(lines[j])
-				currentChunk.WriteString("\n")
-			}
-			currentStartLine = chunk.StartLine + overlapStart
-		}
-	}
-
-	// Add remaining content as final chunk
-	if currentChunk.Len() > 0 {
-		chunkContent := currentChunk.String()
-		if len(chunkContent) > maxSize {
-			chunkContent = chunkContent[:maxSize]
+	if overlapFraction > 0 {
+		// A fraction of the lines a piece of maxSize bytes holds, not of the whole chunk:
+		// an overlap close to the piece size would advance each piece by only a few lines
+		linesPerPiece := float64(maxSize) * float64(len(lines)) / float64(max(len(chunk.Content), 1))
+		overlapLines = int(linesPerPiece * overlapFraction)
+	}
+	overlapLines = min(max(overlapLines, minOverlapLines), maxOverlapLines)
+
+	spans := splitLinesBySize(lines, chunk.StartLine, maxSize, overlapLines)
+	splitChunks := make([]models.CodeChunk, 0, len(spans))
+	for _, span := range spans {
+		chunkContent := span.content()
+		if strings.TrimSpace(chunkContent) == "" {
+			continue
 		}
 
-		finalChunk := &models.CodeChunk{
-			ID:           uuid.New().String(),
-			RepoPath:     chunk.RepoPath,
-			FilePath:     chunk.FilePath,
-			ChunkType:    chunk.ChunkType,
-			Content:      chunkContent,
-			Language:     chunk.Language,
-			StartLine:    currentStartLine,
-			EndLine:      chunk.EndLine,
-			FunctionName: chunk.FunctionName,
-			ClassName:    chunk.ClassName,
+		splitChunks = append(splitChunks, models.CodeChunk{
+			ID:            models.ChunkID(chunk.RepoPath, chunk.FilePath, span.startLine, span.endLine(), chunkContent),
+			RepoPath:      chunk.RepoPath,
+			FilePath:      chunk.FilePath,
+			ChunkType:     chunk.ChunkType,
+			Content:       chunkContent,
+			Language:      chunk.Language,
+			StartLine:     span.startLine,
+			EndLine:       span.endLine(),
+			FunctionName:  chunk.FunctionName,
+			ClassName:     chunk.ClassName,
 			ParentChunkID: chunk.ParentChunkID,
-		}
-
-		splitChunks = append(splitChunks, *finalChunk)
+		})
 	}
 
 	return splitChunks
@@ -719,14 +995,14 @@ func (ac *ASTChunker) splitLargeChunk(chunk *models.CodeChunk, fullContent strin
 
 // LogParserStatus logs which languages have AST parsing available
 func (ac *ASTChunker) LogParserStatus() {
-	languages := []string{"java", "javascript", "typescript", "go", "python", "rust"}
+	languages := []string{"java", "javascript", "typescript", "kotlin", "ruby", "php", "swift", "go", "python", "rust"}
 
-	log.Println("AST Parser Status:")
+	slog.Debug("AST parser status")
 	for _, lang := range languages {
 		available := "✗ Not available (using token-based fallback)"
 		if ac.CanParseLanguage(lang) {
 			available = "✓ Available"
 		}
-		log.Printf("  %s: %s", lang, available)
+		slog.Debug("AST parser", "language", lang, "status", available)
 	}
 }
\ No newline at end of file
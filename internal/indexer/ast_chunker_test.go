@@ -1,8 +1,10 @@
 package indexer
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/jamaly87/codebase-semantic-search/internal/models"
 	"github.com/jamaly87/codebase-semantic-search/pkg/config"
@@ -48,7 +50,7 @@ func TestASTChunker_HierarchicalChunking(t *testing.T) {
 	// Make it large enough to potentially trigger hierarchical chunking
 	largeClassContent := largeClass + strings.Repeat("\n    // Additional content line\n", 200)
 
-	chunks, err := chunker.ChunkByAST("/repo", "/LargeService.java", "java", largeClassContent, cfg)
+	chunks, _, err := chunker.ChunkByAST("/repo", "/LargeService.java", "java", largeClassContent, cfg)
 	if err != nil {
 		t.Fatalf("ChunkByAST failed: %v", err)
 	}
@@ -103,7 +105,7 @@ func TestASTChunker_LargeNodeSplitting(t *testing.T) {
     }
 }`
 
-	chunks, err := chunker.ChunkByAST("/repo", "/Test.java", "java", largeFunction, cfg)
+	chunks, _, err := chunker.ChunkByAST("/repo", "/Test.java", "java", largeFunction, cfg)
 	if err != nil {
 		t.Fatalf("ChunkByAST failed: %v", err)
 	}
@@ -139,7 +141,7 @@ func TestASTChunker_IsLargeClassOrInterface(t *testing.T) {
     public void method() {}
 }`
 
-	chunks, err := chunker.ChunkByAST("/repo", "/Small.java", "java", smallClass, cfg)
+	chunks, _, err := chunker.ChunkByAST("/repo", "/Small.java", "java", smallClass, cfg)
 	if err != nil {
 		t.Fatalf("ChunkByAST failed: %v", err)
 	}
@@ -174,7 +176,7 @@ func TestASTChunker_ExtractMethodNodes(t *testing.T) {
 	}
 
 	// Test through ChunkByAST which will use extractMethodNodes internally
-	chunks, err := chunker.ChunkByAST("/repo", "/Test.java", "java", javaClass, cfg)
+	chunks, _, err := chunker.ChunkByAST("/repo", "/Test.java", "java", javaClass, cfg)
 	if err != nil {
 		t.Fatalf("ChunkByAST failed: %v", err)
 	}
@@ -208,6 +210,10 @@ func TestASTChunker_CanParseLanguage(t *testing.T) {
 		{"java", true},
 		{"javascript", true},
 		{"typescript", true},
+		{"kotlin", true},
+		{"ruby", true},
+		{"php", true},
+		{"swift", true},
 		{"go", false},
 		{"python", false},
 		{"rust", false},
@@ -223,3 +229,623 @@ func TestASTChunker_CanParseLanguage(t *testing.T) {
 	}
 }
 
+
+func TestASTChunker_DeterministicChunkIDs(t *testing.T) {
+	chunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+
+	cfg := &config.ChunkingConfig{
+		EnableHierarchicalChunking: true,
+		MaxChunkSizeBytes:          4000,
+	}
+
+	content := `public class Service {
+    public void run() {
+        System.out.println("run");
+    }
+}`
+
+	first, _, err := chunker.ChunkByAST("/repo", "/Service.java", "java", content, cfg)
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+	second, _, err := chunker.ChunkByAST("/repo", "/Service.java", "java", content, cfg)
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+
+	if len(first) == 0 || len(first) != len(second) {
+		t.Fatalf("Expected the same non-empty chunks on both runs, got %d and %d", len(first), len(second))
+	}
+	for i := range first {
+		if first[i].ID != second[i].ID {
+			t.Errorf("Chunk %d: expected identical IDs for identical content, got %s and %s", i, first[i].ID, second[i].ID)
+		}
+	}
+
+	changed, _, err := chunker.ChunkByAST("/repo", "/Service.java", "java", strings.Replace(content, `"run"`, `"running"`, 1), cfg)
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+	if changed[0].ID == first[0].ID {
+		t.Error("Expected changed content to produce a different ID")
+	}
+
+	moved, _, err := chunker.ChunkByAST("/repo", "/Other.java", "java", content, cfg)
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+	if moved[0].ID == first[0].ID {
+		t.Error("Expected the same content in a different file to produce a different ID")
+	}
+}
+
+func TestASTChunker_OversizedFunctionSplitIntoCompleteChunks(t *testing.T) {
+	chunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+
+	cfg := &config.ChunkingConfig{
+		MaxChunkSizeBytes: 1000,
+	}
+
+	var body strings.Builder
+	for i := 0; i < 300; i++ {
+		body.WriteString(fmt.Sprintf("        System.out.println(\"Line %d\");\n", i))
+	}
+	source := "public class Test {\n    public void largeMethod() {\n" + body.String() + "    }\n}"
+	sourceLines := strings.Split(source, "\n")
+
+	chunks, _, err := chunker.ChunkByAST("/repo", "/Test.java", "java", source, cfg)
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+
+	var methodChunks []models.CodeChunk
+	for _, chunk := range chunks {
+		if chunk.FunctionName == "largeMethod" {
+			methodChunks = append(methodChunks, chunk)
+		}
+	}
+	if len(methodChunks) < 2 {
+		t.Fatalf("Expected oversized method to be split into multiple chunks, got %d", len(methodChunks))
+	}
+
+	covered := make(map[int]bool)
+	for i, chunk := range methodChunks {
+		if len(chunk.Content) > cfg.MaxChunkSizeBytes {
+			t.Errorf("Chunk %d exceeds max size: %d bytes (max: %d)", i, len(chunk.Content), cfg.MaxChunkSizeBytes)
+		}
+
+		// Each chunk must be exactly the source lines it claims to cover, with no cut lines
+		// (the node's first line starts at the declaration, without its indentation)
+		chunkLines := strings.Split(chunk.Content, "\n")
+		if len(chunkLines) != chunk.EndLine-chunk.StartLine+1 {
+			t.Errorf("Chunk %d has %d lines but claims lines %d-%d", i, len(chunkLines), chunk.StartLine, chunk.EndLine)
+			continue
+		}
+		for k, line := range chunkLines {
+			sourceLine := sourceLines[chunk.StartLine-1+k]
+			if line != sourceLine && line != strings.TrimLeft(sourceLine, " ") {
+				t.Errorf("Chunk %d line %d = %q, want %q", i, chunk.StartLine+k, line, sourceLine)
+			}
+		}
+		for line := chunk.StartLine; line <= chunk.EndLine; line++ {
+			covered[line] = true
+		}
+	}
+
+	// Every line of the method (lines 2 through the closing brace) is in some chunk
+	for line := 2; line <= len(sourceLines)-1; line++ {
+		if !covered[line] {
+			t.Errorf("Line %d of the method is missing from all chunks", line)
+		}
+	}
+}
+
+func TestASTChunker_HonorsConfiguredMaxChunkSize(t *testing.T) {
+	chunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+
+	// Larger than the 4000-byte default, so the method must not be truncated or split
+	cfg := &config.ChunkingConfig{
+		MaxChunkSizeBytes: 10000,
+	}
+
+	source := "public class Test {\n    public void bigMethod() {\n" +
+		strings.Repeat("        System.out.println(\"Line\");\n", 150) + "    }\n}"
+
+	chunks, _, err := chunker.ChunkByAST("/repo", "/Test.java", "java", source, cfg)
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+
+	var methodChunks []models.CodeChunk
+	for _, chunk := range chunks {
+		if chunk.FunctionName == "bigMethod" {
+			methodChunks = append(methodChunks, chunk)
+		}
+	}
+	if len(methodChunks) != 1 {
+		t.Fatalf("Expected 1 chunk for the method, got %d", len(methodChunks))
+	}
+	if !strings.HasSuffix(strings.TrimSpace(methodChunks[0].Content), "}") {
+		t.Error("Expected the method chunk to contain the complete method")
+	}
+	if len(methodChunks[0].Content) <= defaultMaxChunkSizeBytes {
+		t.Errorf("Expected method larger than the default limit, got %d bytes", len(methodChunks[0].Content))
+	}
+}
+
+func TestASTChunker_SyntaxErrorsReported(t *testing.T) {
+	chunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+
+	cfg := &config.ChunkingConfig{MaxChunkSizeBytes: 4000}
+
+	tests := []struct {
+		name         string
+		language     string
+		content      string
+		keepFunction string // a well-formed function that must still get its own chunk
+		brokenMarker string // code that must be in a broken region, empty when there is none
+	}{
+		{
+			name:         "valid java",
+			language:     "java",
+			content:      "public class Ok {\n    public void run() {\n        int total = compute();\n    }\n}",
+			keepFunction: "run",
+		},
+		{
+			// The method's chunk already holds its broken code
+			name:         "java with a broken method",
+			language:     "java",
+			content:      "public class Broken {\n    public void run( {\n        int total = ;\n}",
+			keepFunction: "run",
+		},
+		{
+			name:         "java with stray code after the class",
+			language:     "java",
+			content:      "public class Ok {\n    public void run() {\n        int total = compute();\n    }\n}\n\n}} int brokenJavaMarker = = ;\n",
+			keepFunction: "run",
+			brokenMarker: "brokenJavaMarker",
+		},
+		{
+			name:     "typescript with a broken statement between functions",
+			language: "typescript",
+			content: "export function parse(input: string): string[] {\n  return input.split(',')\n}\n\n" +
+				")) const brokenTsMarker = = 1\n\n" +
+				"export function render(items: string[]): string {\n  return items.join(', ')\n}\n",
+			keepFunction: "render",
+			brokenMarker: "brokenTsMarker",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, broken, err := chunker.ChunkByAST("/repo", "/file", tt.language, tt.content, cfg)
+			if err != nil {
+				t.Fatalf("ChunkByAST failed: %v", err)
+			}
+
+			if tt.keepFunction != "" {
+				found := false
+				for _, chunk := range chunks {
+					if chunk.FunctionName == tt.keepFunction {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("Expected a chunk for %s, got %+v", tt.keepFunction, chunks)
+				}
+			}
+
+			if tt.brokenMarker == "" {
+				if len(broken) != 0 {
+					t.Errorf("Expected no broken regions, got %+v", broken)
+				}
+				return
+			}
+			found := false
+			for _, region := range broken {
+				lines := strings.Split(tt.content, "\n")
+				want := strings.Join(lines[region.startLine-1:region.startLine-1+strings.Count(region.content, "\n")+1], "\n")
+				if region.content != want {
+					t.Errorf("Expected region to cover whole lines from %d, got %q", region.startLine, region.content)
+				}
+				if strings.Contains(region.content, tt.brokenMarker) {
+					found = true
+				}
+				if tt.keepFunction != "" && strings.Contains(region.content, tt.keepFunction) {
+					t.Errorf("Expected well-formed %s outside the broken regions, got %q", tt.keepFunction, region.content)
+				}
+			}
+			if !found {
+				t.Errorf("Expected %q in a broken region, got %+v", tt.brokenMarker, broken)
+			}
+		})
+	}
+}
+
+func TestASTChunker_Kotlin(t *testing.T) {
+	chunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+
+	content := `package demo
+
+interface Greeter {
+    fun greet(name: String): String
+}
+
+object Registry {
+    fun register(user: User) {
+        println(user)
+    }
+}
+
+class Service(private val repo: Repo) : Greeter {
+    override fun greet(name: String): String = "hi $name"
+
+    suspend fun find(id: Int): User? {
+        return repo.load(id)
+    }
+}
+
+fun String.shout(): String = uppercase()
+`
+	chunks, _, err := chunker.ChunkByAST("/repo", "/repo/Service.kt", "kotlin", content, &config.ChunkingConfig{MaxChunkSizeBytes: 4000})
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+
+	classes := make(map[string]bool)
+	functions := make(map[string]bool)
+	for _, chunk := range chunks {
+		if chunk.ClassName != "" {
+			classes[chunk.ClassName] = true
+		}
+		if chunk.FunctionName != "" {
+			functions[chunk.FunctionName] = true
+		}
+	}
+
+	for _, name := range []string{"Greeter", "Registry", "Service"} {
+		if !classes[name] {
+			t.Errorf("Expected a chunk for class %s, got classes %v", name, classes)
+		}
+	}
+	for _, name := range []string{"register", "greet", "find", "shout"} {
+		if !functions[name] {
+			t.Errorf("Expected a chunk for function %s, got functions %v", name, functions)
+		}
+	}
+}
+
+func TestASTChunker_Ruby(t *testing.T) {
+	chunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+
+	content := `module Billing
+  module Invoices
+    class Generator < Base
+      def self.build(account)
+        new(account)
+      end
+
+      def call
+        line_items.sum(&:total)
+      end
+    end
+  end
+end
+
+class Reports::Monthly
+  def totals?
+    true
+  end
+end
+
+def helper_method
+  puts "top level"
+end
+`
+	chunks, _, err := chunker.ChunkByAST("/repo", "/repo/app/billing.rb", "ruby", content, &config.ChunkingConfig{MaxChunkSizeBytes: 4000})
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+
+	classes := make(map[string]bool)
+	methodClasses := make(map[string]string)
+	for _, chunk := range chunks {
+		if chunk.FunctionName != "" {
+			methodClasses[chunk.FunctionName] = chunk.ClassName
+		} else if chunk.ClassName != "" {
+			classes[chunk.ClassName] = true
+		}
+	}
+
+	// Nested modules and classes are qualified with their enclosing names
+	for _, name := range []string{"Billing", "Billing::Invoices", "Billing::Invoices::Generator", "Reports::Monthly"} {
+		if !classes[name] {
+			t.Errorf("Expected a chunk for %s, got classes %v", name, classes)
+		}
+	}
+
+	// "def self.build" is named build; methods keep their enclosing class
+	expected := map[string]string{
+		"build":         "Billing::Invoices::Generator",
+		"call":          "Billing::Invoices::Generator",
+		"totals?":       "Reports::Monthly",
+		"helper_method": "",
+	}
+	for name, class := range expected {
+		got, ok := methodClasses[name]
+		if !ok {
+			t.Errorf("Expected a chunk for method %s, got methods %v", name, methodClasses)
+			continue
+		}
+		if got != class {
+			t.Errorf("Expected method %s in class %q, got %q", name, class, got)
+		}
+	}
+}
+
+func TestASTChunker_ClassSummaryKeepsValidUTF8(t *testing.T) {
+	chunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+
+	// A long method signature of multibyte characters is shortened in the class summary
+	var body strings.Builder
+	body.WriteString("public class Greeter {\n")
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&body, "    public String greet%d(String 名前, String 挨拶の言葉, String 敬称, String 絵文字の種類) {\n        return 名前 + 挨拶の言葉 + 敬称 + 絵文字;\n    }\n", i)
+	}
+	body.WriteString("}\n")
+
+	cfg := &config.ChunkingConfig{MaxChunkSizeBytes: 3000, EnableHierarchicalChunking: true}
+	chunks, _, err := chunker.ChunkByAST("/repo", "/repo/Greeter.java", "java", body.String(), cfg)
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+	if len(chunks) == 0 {
+		t.Fatal("Expected chunks")
+	}
+	for _, chunk := range chunks {
+		if !utf8.ValidString(chunk.Content) {
+			t.Errorf("Chunk at lines %d-%d is not valid UTF-8", chunk.StartLine, chunk.EndLine)
+		}
+	}
+}
+
+func TestASTChunker_PHP(t *testing.T) {
+	chunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+
+	content := `<?php
+namespace App\Http\Controllers;
+
+use Illuminate\Http\Request;
+
+interface Greeter
+{
+    public function greet(string $name): string;
+}
+
+trait Loggable
+{
+    public function log(string $message): void
+    {
+        logger()->info($message);
+    }
+}
+
+class UserController extends Controller implements Greeter
+{
+    use Loggable;
+
+    public static function greet(string $name): string
+    {
+        return "Hello {$name}";
+    }
+}
+
+function helper($value)
+{
+    return $value * 2;
+}
+`
+	chunks, _, err := chunker.ChunkByAST("/repo", "/repo/app/Http/Controllers/UserController.php", "php", content, &config.ChunkingConfig{MaxChunkSizeBytes: 4000})
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+
+	classes := make(map[string]bool)
+	functions := make(map[string]bool) // Class::function
+	for _, chunk := range chunks {
+		if chunk.FunctionName != "" {
+			functions[chunk.ClassName+"::"+chunk.FunctionName] = true
+		} else if chunk.ClassName != "" {
+			classes[chunk.ClassName] = true
+		}
+	}
+
+	// Classes, interfaces and traits are qualified with their namespace
+	for _, name := range []string{`App\Http\Controllers\Greeter`, `App\Http\Controllers\Loggable`, `App\Http\Controllers\UserController`} {
+		if !classes[name] {
+			t.Errorf("Expected a chunk for %s, got classes %v", name, classes)
+		}
+	}
+
+	// Methods keep their enclosing class; functions have none
+	for _, name := range []string{
+		`App\Http\Controllers\Greeter::greet`,
+		`App\Http\Controllers\Loggable::log`,
+		`App\Http\Controllers\UserController::greet`,
+		"::helper",
+	} {
+		if !functions[name] {
+			t.Errorf("Expected a chunk for %s, got functions %v", name, functions)
+		}
+	}
+
+	// Large classes are split into a summary and method chunks
+	chunks, _, err = chunker.ChunkByAST("/repo", "/repo/app/Http/Controllers/UserController.php", "php", content,
+		&config.ChunkingConfig{MaxChunkSizeBytes: 120, EnableHierarchicalChunking: true})
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+	var summary, method bool
+	for _, chunk := range chunks {
+		if chunk.ClassName != `App\Http\Controllers\UserController` {
+			continue
+		}
+		switch chunk.ChunkType {
+		case models.ChunkTypeClass:
+			summary = strings.HasPrefix(chunk.Content, "class UserController")
+		case models.ChunkTypeMethod:
+			method = method || chunk.FunctionName == "greet"
+		}
+	}
+	if !summary || !method {
+		t.Errorf("Expected a class summary and a greet method chunk, got %+v", chunks)
+	}
+}
+
+func TestASTChunker_PHPMixedHTML(t *testing.T) {
+	chunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+
+	content := `<html>
+<body>
+<?php
+namespace Views {
+    function renderTitle($title)
+    {
+        return htmlspecialchars($title);
+    }
+}
+?>
+<h1><?= Views\renderTitle($title) ?></h1>
+</body>
+</html>
+`
+	chunks, _, err := chunker.ChunkByAST("/repo", "/repo/views/page.php", "php", content, &config.ChunkingConfig{MaxChunkSizeBytes: 4000})
+	if err != nil {
+		t.Fatalf("Expected HTML mixed with PHP to parse, got %v", err)
+	}
+	if len(chunks) != 1 || chunks[0].FunctionName != "renderTitle" {
+		t.Fatalf("Expected the renderTitle function, got %+v", chunks)
+	}
+	if chunks[0].StartLine != 5 || chunks[0].EndLine != 8 {
+		t.Errorf("Expected lines 5-8, got %d-%d", chunks[0].StartLine, chunks[0].EndLine)
+	}
+}
+
+func TestASTChunker_Swift(t *testing.T) {
+	chunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+
+	content := `import Foundation
+
+protocol Greeter {
+    func greet(name: String) -> String
+}
+
+final class Outer: Greeter {
+    struct Inner {
+        var count = 0
+
+        func size() -> Int {
+            return count
+        }
+    }
+
+    init(value: Int) {
+        print(value)
+    }
+
+    func greet(name: String) -> String {
+        return "Hello \(name)"
+    }
+}
+
+extension Outer.Inner: Equatable {
+    func describe() -> String {
+        return "Inner(\(count))"
+    }
+}
+
+func helper<T>(_ value: T) -> T {
+    return value
+}
+`
+	chunks, _, err := chunker.ChunkByAST("/repo", "/repo/App/Outer.swift", "swift", content, &config.ChunkingConfig{MaxChunkSizeBytes: 4000})
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+
+	classes := make(map[string]bool)
+	functions := make(map[string]bool) // Type.function
+	for _, chunk := range chunks {
+		if chunk.FunctionName != "" {
+			functions[chunk.ClassName+"."+chunk.FunctionName] = true
+		} else if chunk.ClassName != "" {
+			classes[chunk.ClassName] = true
+		}
+	}
+
+	// Nested types are qualified by their enclosing type; extensions take the extended type's name
+	for _, name := range []string{"Greeter", "Outer", "Outer.Inner"} {
+		if !classes[name] {
+			t.Errorf("Expected a chunk for %s, got classes %v", name, classes)
+		}
+	}
+
+	// Methods keep their enclosing type, including in extensions; functions have none
+	for _, name := range []string{"Outer.Inner.size", "Outer.init", "Outer.greet", "Outer.Inner.describe", ".helper"} {
+		if !functions[name] {
+			t.Errorf("Expected a chunk for %s, got functions %v", name, functions)
+		}
+	}
+
+	// Large types are split into a summary and method chunks
+	chunks, _, err = chunker.ChunkByAST("/repo", "/repo/App/Outer.swift", "swift", content,
+		&config.ChunkingConfig{MaxChunkSizeBytes: 150, EnableHierarchicalChunking: true})
+	if err != nil {
+		t.Fatalf("ChunkByAST failed: %v", err)
+	}
+	var summary, method bool
+	for _, chunk := range chunks {
+		if chunk.ClassName != "Outer" {
+			continue
+		}
+		switch chunk.ChunkType {
+		case models.ChunkTypeClass:
+			summary = strings.HasPrefix(chunk.Content, "final class Outer")
+		case models.ChunkTypeMethod:
+			method = method || chunk.FunctionName == "greet"
+		}
+	}
+	if !summary || !method {
+		t.Errorf("Expected a class summary and a greet method chunk, got %+v", chunks)
+	}
+}
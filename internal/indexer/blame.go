@@ -0,0 +1,165 @@
+package indexer
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// uncommittedHash is the commit git blame reports for lines not committed yet
+const uncommittedHash = "0000000000000000000000000000000000000000"
+
+// blameLine is the last commit that touched a line, empty for uncommitted lines
+type blameLine struct {
+	commit string
+	author string
+	time   time.Time
+}
+
+// isGitWorkTree reports whether repoPath is inside a git work tree
+func isGitWorkTree(repoPath string) bool {
+	_, err := runGit(repoPath, "rev-parse", "--is-inside-work-tree")
+	return err == nil
+}
+
+// blameFile returns the blame of each line of a file in the working tree, indexed by line - 1
+// Fails for files git doesn't track.
+func blameFile(repoPath, filePath string) ([]blameLine, error) {
+	relPath, err := filepath.Rel(repoPath, filePath)
+	if err != nil {
+		return nil, err
+	}
+	out, err := runGit(repoPath, "blame", "--line-porcelain", "--", filepath.ToSlash(relPath))
+	if err != nil {
+		return nil, err
+	}
+	return parseBlamePorcelain(out), nil
+}
+
+// parseBlamePorcelain parses `git blame --line-porcelain` output, where every line's
+// content (prefixed by a tab) follows a header naming its commit and author
+func parseBlamePorcelain(out string) []blameLine {
+	var lines []blameLine
+	var current blameLine
+	newEntry := true
+
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			if current.commit == uncommittedHash {
+				current = blameLine{}
+			}
+			lines = append(lines, current)
+			newEntry = true
+		case newEntry:
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			current = blameLine{commit: fields[0]}
+			newEntry = false
+		case strings.HasPrefix(line, "author "):
+			current.author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-time "):
+			if seconds, err := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64); err == nil {
+				current.time = time.Unix(seconds, 0).UTC()
+			}
+		}
+	}
+	return lines
+}
+
+// attachBlame records the most recent commit among each chunk's lines in its Metadata
+// Chunks whose lines are all uncommitted get no blame metadata.
+func attachBlame(chunks []models.CodeChunk, lines []blameLine) {
+	for i := range chunks {
+		var latest blameLine
+		for line := chunks[i].StartLine; line <= chunks[i].EndLine && line <= len(lines); line++ {
+			if b := lines[line-1]; b.commit != "" && (latest.commit == "" || b.time.After(latest.time)) {
+				latest = b
+			}
+		}
+		if latest.commit == "" {
+			continue
+		}
+
+		if chunks[i].Metadata == nil {
+			chunks[i].Metadata = make(map[string]interface{})
+		}
+		chunks[i].Metadata[models.MetadataLastCommit] = latest.commit
+		chunks[i].Metadata[models.MetadataLastAuthor] = latest.author
+		chunks[i].Metadata[models.MetadataLastCommitDate] = latest.time.Format(time.RFC3339)
+	}
+}
+
+// addBlame attaches blame metadata to a file's chunks, leaving them as they are when the
+// file can't be blamed (e.g. it is untracked)
+func addBlame(repoPath, filePath string, chunks []models.CodeChunk) {
+	lines, err := blameFile(repoPath, filePath)
+	if err != nil {
+		slog.Debug("Skipping blame metadata", "file", filePath, "error", err)
+		return
+	}
+	attachBlame(chunks, lines)
+}
+
+// gitHead returns the commit checked out in repoPath
+func gitHead(repoPath string) (string, error) {
+	out, err := runGit(repoPath, "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// filesCommittedBetween returns the absolute paths of files under repoPath that differ between
+// the commits from and to
+func filesCommittedBetween(repoPath, from, to string) (map[string]bool, error) {
+	out, err := runGit(repoPath, "diff", "--name-only", "--no-renames", "--relative", "-z", from, to, "--")
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s..%s: %w", from, to, err)
+	}
+
+	files := make(map[string]bool)
+	for _, name := range strings.Split(out, "\x00") {
+		if name != "" {
+			files[filepath.Join(repoPath, filepath.FromSlash(name))] = true
+		}
+	}
+	return files, nil
+}
+
+// filesToReblame returns the commit checked out in the job's repository and the files whose
+// blame may have changed since the last index: those touched by the commits made since,
+// even if their content didn't change
+// When the last index recorded no commit (it had no blame) or the commit can't be diffed
+// (e.g. history was rewritten), the loaded cache is reset so every file is reindexed.
+func filesToReblame(job *models.IndexJob, settings *repoSettings) (string, map[string]bool) {
+	head, err := gitHead(job.RepoPath)
+	if err != nil {
+		slog.Warn("Failed to read the checked out commit, blame may be out of date", "job", job.ID, "error", err)
+		return "", nil
+	}
+
+	indexedHead := settings.hashes.GitHead()
+	if indexedHead == head || len(settings.hashes.IndexedFiles()) == 0 {
+		return head, nil
+	}
+	if indexedHead != "" {
+		files, err := filesCommittedBetween(job.RepoPath, indexedHead, head)
+		if err == nil {
+			slog.Info("Refreshing blame of files committed since the last index", "job", job.ID, "since", indexedHead, "files", len(files))
+			return head, files
+		}
+		slog.Warn("Failed to list files committed since the last index", "job", job.ID, "error", err)
+	}
+
+	slog.Warn("Blame metadata of the last index is out of date, reindexing every file", "job", job.ID)
+	settings.hashes.Reset(job.RepoPath)
+	return head, nil
+}
@@ -0,0 +1,197 @@
+package indexer
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+func TestBlameFile(t *testing.T) {
+	repoDir := newTestGitRepo(t, map[string]string{
+		"Auth.java": "public class Auth {\n    public void login() {\n    }\n}\n",
+	})
+	writeTestFiles(t, repoDir, map[string]string{
+		"Auth.java": "public class Auth {\n    public void login() {\n        check();\n    }\n}\n",
+	})
+	gitInRepo(t, repoDir, "-c", "user.name=alice", "commit", "-q", "-a", "-m", "add check", "--date=2030-01-02T03:04:05Z")
+
+	lines, err := blameFile(repoDir, filepath.Join(repoDir, "Auth.java"))
+	if err != nil {
+		t.Fatalf("blameFile failed: %v", err)
+	}
+	if len(lines) != 5 {
+		t.Fatalf("Expected blame for 5 lines, got %d", len(lines))
+	}
+	if lines[0].author != "test" || lines[2].author != "alice" {
+		t.Errorf("Expected line 1 by test and line 3 by alice, got %q and %q", lines[0].author, lines[2].author)
+	}
+	if lines[0].commit == lines[2].commit || len(lines[2].commit) != 40 {
+		t.Errorf("Expected distinct full commit hashes, got %q and %q", lines[0].commit, lines[2].commit)
+	}
+
+	// Uncommitted lines carry no commit
+	writeTestFiles(t, repoDir, map[string]string{
+		"Auth.java": "// wip\npublic class Auth {\n    public void login() {\n        check();\n    }\n}\n",
+	})
+	lines, err = blameFile(repoDir, filepath.Join(repoDir, "Auth.java"))
+	if err != nil {
+		t.Fatalf("blameFile failed: %v", err)
+	}
+	if lines[0].commit != "" || lines[3].author != "alice" {
+		t.Errorf("Expected an uncommitted first line and alice on line 4, got %+v", lines)
+	}
+
+	// Untracked files can't be blamed
+	writeTestFiles(t, repoDir, map[string]string{"New.java": "class New {}\n"})
+	if _, err := blameFile(repoDir, filepath.Join(repoDir, "New.java")); err == nil {
+		t.Error("Expected an error blaming an untracked file")
+	}
+}
+
+func TestAttachBlame(t *testing.T) {
+	older := blameLine{commit: "aaaa", author: "old", time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	newer := blameLine{commit: "bbbb", author: "new", time: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	lines := []blameLine{older, newer, older, {}, {}}
+
+	chunks := []models.CodeChunk{
+		{StartLine: 1, EndLine: 3},
+		{StartLine: 3, EndLine: 3, Metadata: map[string]interface{}{"kept": true}},
+		{StartLine: 4, EndLine: 5},
+	}
+	attachBlame(chunks, lines)
+
+	if chunks[0].Metadata[models.MetadataLastCommit] != "bbbb" || chunks[0].Metadata[models.MetadataLastAuthor] != "new" {
+		t.Errorf("Expected the most recent commit of the chunk, got %v", chunks[0].Metadata)
+	}
+	if chunks[0].Metadata[models.MetadataLastCommitDate] != "2021-01-01T00:00:00Z" {
+		t.Errorf("Expected an RFC 3339 date, got %v", chunks[0].Metadata[models.MetadataLastCommitDate])
+	}
+	if chunks[1].Metadata[models.MetadataLastCommit] != "aaaa" || chunks[1].Metadata["kept"] != true {
+		t.Errorf("Expected blame added to existing metadata, got %v", chunks[1].Metadata)
+	}
+	if chunks[2].Metadata != nil {
+		t.Errorf("Expected no blame for uncommitted lines, got %v", chunks[2].Metadata)
+	}
+}
+
+func TestIndex_GitBlame(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	idx.config.Indexing.GitBlame = true
+	repoDir := newTestGitRepo(t, map[string]string{
+		"Auth.java": "public class Auth {\n    public void login() {\n        System.out.println(\"login\");\n    }\n}\n",
+	})
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+
+	if len(store.chunks) == 0 {
+		t.Fatal("Expected chunks to be stored")
+	}
+	for _, chunk := range store.chunks {
+		commit, _ := chunk.Metadata[models.MetadataLastCommit].(string)
+		if len(commit) != 40 || chunk.Metadata[models.MetadataLastAuthor] != "test" {
+			t.Errorf("Expected blame metadata on %s:%d, got %v", chunk.FilePath, chunk.StartLine, chunk.Metadata)
+		}
+		if date, _ := chunk.Metadata[models.MetadataLastCommitDate].(string); !strings.Contains(date, "T") {
+			t.Errorf("Expected a commit date, got %v", chunk.Metadata[models.MetadataLastCommitDate])
+		}
+	}
+
+	// Outside a git repository indexing still works, without blame
+	idx, store, _ = newIncrementalTestIndexer(t)
+	idx.config.Indexing.GitBlame = true
+	plainDir := t.TempDir()
+	writeTestFiles(t, plainDir, map[string]string{
+		"Auth.java": "public class Auth {\n    public void login() {\n        System.out.println(\"login\");\n    }\n}\n",
+	})
+	if job, _ := idx.Index(plainDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+	for _, chunk := range store.chunks {
+		if _, ok := chunk.Metadata[models.MetadataLastCommit]; ok {
+			t.Errorf("Expected no blame metadata outside git, got %v", chunk.Metadata)
+		}
+	}
+}
+
+func TestIndex_GitBlameRefreshedAfterCommit(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	idx.config.Indexing.GitBlame = true
+	repoDir := newTestGitRepo(t, map[string]string{
+		"Auth.java": "public class Auth {\n    public void login() {\n        System.out.println(\"login\");\n    }\n}\n",
+	})
+	newPath := filepath.Join(repoDir, "Session.java")
+	writeTestFiles(t, repoDir, map[string]string{
+		"Session.java": "public class Session {\n    public void open() {\n        System.out.println(\"open\");\n    }\n}\n",
+	})
+
+	// Untracked, so indexed without blame
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+	for _, chunk := range store.chunks {
+		if _, ok := chunk.Metadata[models.MetadataLastCommit]; chunk.FilePath == newPath && ok {
+			t.Fatalf("Expected no blame for the untracked file, got %v", chunk.Metadata)
+		}
+	}
+
+	// Committing it changes its blame but not its content
+	gitInRepo(t, repoDir, "-c", "user.name=alice", "add", "Session.java")
+	gitInRepo(t, repoDir, "-c", "user.name=alice", "commit", "-q", "-m", "add session")
+
+	job, _ := idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+	if stats := job.GetStats(); stats.FilesUnchanged != 1 {
+		t.Errorf("Expected only the committed file to be reindexed, got %d unchanged", stats.FilesUnchanged)
+	}
+	found := false
+	for _, chunk := range store.chunks {
+		if chunk.FilePath == newPath {
+			found = true
+			if chunk.Metadata[models.MetadataLastAuthor] != "alice" {
+				t.Errorf("Expected the committed file's blame to be refreshed, got %v", chunk.Metadata)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("Expected chunks for the committed file")
+	}
+}
+
+func TestIndex_GitBlameEnabledLater(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	repoDir := newTestGitRepo(t, map[string]string{
+		"Auth.java": "public class Auth {\n    public void login() {\n        System.out.println(\"login\");\n    }\n}\n",
+	})
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+
+	// The index holds no blame, so every file is reindexed once blame is enabled
+	idx.config.Indexing.GitBlame = true
+	job, _ := idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+	if stats := job.GetStats(); stats.FilesUnchanged != 0 {
+		t.Errorf("Expected every file to be reindexed, got %d unchanged", stats.FilesUnchanged)
+	}
+	for _, chunk := range store.chunks {
+		if chunk.Metadata[models.MetadataLastAuthor] != "test" {
+			t.Errorf("Expected blame metadata on %s:%d, got %v", chunk.FilePath, chunk.StartLine, chunk.Metadata)
+		}
+	}
+
+	// Nothing changed since, so nothing is reindexed
+	job, _ = idx.Index(repoDir, false, false)
+	if stats := job.GetStats(); stats.FilesUnchanged != 1 {
+		t.Errorf("Expected the file to be unchanged, got %d unchanged", stats.FilesUnchanged)
+	}
+}
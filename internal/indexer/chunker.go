@@ -3,8 +3,11 @@ package indexer
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"reflect"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/jamaly87/codebase-semantic-search/internal/models"
 	"github.com/jamaly87/codebase-semantic-search/pkg/config"
@@ -28,6 +31,128 @@ const (
 	LargeFileOverlapRatio = 7
 )
 
+// normalizeLineEndings converts CRLF line endings to LF, so no line keeps a trailing \r
+// Line numbers are unchanged: each CRLF still ends exactly one line.
+func normalizeLineEndings(content string) string {
+	return strings.ReplaceAll(content, "\r\n", "\n")
+}
+
+// splitLines splits content into lines, without the phantom empty line after a trailing newline
+func splitLines(content string) []string {
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// lineSpan is a run of consecutive source lines starting at startLine (1-based)
+type lineSpan struct {
+	startLine int
+	lines     []string
+}
+
+// endLine returns the last source line covered by the span
+func (ls lineSpan) endLine() int {
+	return ls.startLine + len(ls.lines) - 1
+}
+
+// content joins the span's lines
+func (ls lineSpan) content() string {
+	return strings.Join(ls.lines, "\n")
+}
+
+// splitLinesBySize splits lines into spans of at most maxBytes, breaking only at line boundaries
+// Each span after the first repeats up to overlapLines trailing lines of the previous span,
+// as long as the overlap still fits. A single line longer than maxBytes is the only thing that
+// gets cut, and it is cut at rune boundaries into consecutive pieces on the same line number.
+func splitLinesBySize(lines []string, startLine, maxBytes, overlapLines int) []lineSpan {
+	var spans []lineSpan
+	var current []string
+	currentBytes := 0
+	currentStart := startLine
+
+	flush := func() {
+		if len(current) > 0 {
+			spans = append(spans, lineSpan{startLine: currentStart, lines: current})
+		}
+	}
+
+	for i, line := range lines {
+		lineNum := startLine + i
+		lineBytes := len(line) + 1 // Including the newline joining it to the next line
+
+		if len(line) > maxBytes {
+			// No line boundary to split at: emit the line in pieces
+			flush()
+			for _, piece := range splitStringBySize(line, maxBytes) {
+				spans = append(spans, lineSpan{startLine: lineNum, lines: []string{piece}})
+			}
+			current, currentBytes, currentStart = nil, 0, lineNum+1
+			continue
+		}
+
+		if currentBytes+lineBytes > maxBytes+1 && len(current) > 0 {
+			flush()
+
+			// Carry trailing lines over as context, keeping room for the new line
+			overlapStart := len(current)
+			overlapBytes := 0
+			for j := len(current) - 1; j >= 0 && len(current)-j <= overlapLines; j-- {
+				if overlapBytes+len(current[j])+1+lineBytes > maxBytes+1 {
+					break
+				}
+				overlapBytes += len(current[j]) + 1
+				overlapStart = j
+			}
+
+			overlap := append([]string(nil), current[overlapStart:]...)
+			current, currentBytes, currentStart = overlap, overlapBytes, lineNum-len(overlap)
+		}
+
+		current = append(current, line)
+		currentBytes += lineBytes
+	}
+	flush()
+
+	return spans
+}
+
+// splitStringBySize cuts s into pieces of at most maxBytes without splitting UTF-8 characters
+func splitStringBySize(s string, maxBytes int) []string {
+	var pieces []string
+	for len(s) > maxBytes {
+		piece := truncateAtRuneBoundary(s, maxBytes)
+		pieces = append(pieces, piece)
+		s = s[len(piece):]
+	}
+	return append(pieces, s)
+}
+
+// truncateAtRuneBoundary returns the longest prefix of s of at most maxBytes that does not
+// end inside a UTF-8 character. If even the first character does not fit, it is kept whole,
+// so the result is never empty for non-empty s.
+func truncateAtRuneBoundary(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		_, cut = utf8.DecodeRuneInString(s)
+	}
+	return s[:cut]
+}
+
+// truncateAtLineBoundary shortens s to at most maxBytes, dropping whole trailing lines
+func truncateAtLineBoundary(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	if cut := strings.LastIndexByte(s[:maxBytes+1], '\n'); cut > 0 {
+		return s[:cut]
+	}
+	return truncateAtRuneBoundary(s, maxBytes)
+}
+
 // Chunker splits code files into semantic chunks using AST and token-aware strategies
 type Chunker struct {
 	config       *config.ChunkingConfig
@@ -41,14 +166,18 @@ func NewChunker(cfg *config.ChunkingConfig) *Chunker {
 	// Create AST chunker (tries to use Tree-sitter when available)
 	astChunker, err := NewASTChunker()
 	if err != nil {
-		log.Printf("Warning: AST chunker initialization failed: %v", err)
+		slog.Warn("AST chunker initialization failed", "error", err)
 	}
 
 	// Create token-based chunker (fallback strategy)
-	tokenChunker, err := NewTokenChunker(DefaultMaxTokens, DefaultOverlapTokens)
+	tokenChunker, err := NewTokenChunkerWithEncoding(DefaultMaxTokens, DefaultOverlapTokens, cfg.TokenizerEncoding)
 	if err != nil {
 		log.Fatalf("Failed to create token chunker: %v", err)
 	}
+	tokenChunker.SetMaxChunkBytes(cfg.MaxChunkSizeBytes)
+	if err := tokenChunker.SetBoundaryPatterns(cfg.BoundaryPatterns); err != nil {
+		slog.Warn("Ignoring chunking.boundary_patterns", "error", err)
+	}
 
 	chunker := &Chunker{
 		config:       cfg,
@@ -65,6 +194,28 @@ func NewChunker(cfg *config.ChunkingConfig) *Chunker {
 	return chunker
 }
 
+// withConfig returns a chunker sharing c's parsers and tokenizer but using cfg
+func (c *Chunker) withConfig(cfg *config.ChunkingConfig) *Chunker {
+	tokenChunker := c.tokenChunker
+	if tokenChunker != nil && cfg.MaxChunkSizeBytes != c.config.MaxChunkSizeBytes {
+		tokenChunker = tokenChunker.withMaxChunkBytes(cfg.MaxChunkSizeBytes)
+	}
+	if tokenChunker != nil && !reflect.DeepEqual(cfg.BoundaryPatterns, c.config.BoundaryPatterns) {
+		if withPatterns, err := tokenChunker.withBoundaryPatterns(cfg.BoundaryPatterns); err == nil {
+			tokenChunker = withPatterns
+		} else {
+			slog.Warn("Ignoring chunking.boundary_patterns", "error", err)
+		}
+	}
+
+	return &Chunker{
+		config:       cfg,
+		langDetector: c.langDetector,
+		astChunker:   c.astChunker,
+		tokenChunker: tokenChunker,
+	}
+}
+
 // ChunkFile splits a file into semantic chunks using the best available strategy
 // Strategy priority:
 //  1. AST-based (if Tree-sitter parser available for language) - 80-95% accuracy
@@ -72,57 +223,221 @@ func NewChunker(cfg *config.ChunkingConfig) *Chunker {
 //
 // File-level chunks are REMOVED entirely to prevent context length errors
 // Uses adaptive chunking based on file size for optimal chunk granularity
+// Files of unsupported types return ErrUnsupportedFile, unless chunking.unsupported_files is
+// "text" and the file is text, which is then token-chunked with language "text".
 func (c *Chunker) ChunkFile(repoPath, filePath string) ([]models.CodeChunk, error) {
-	// Detect language
-	lang, ok := c.langDetector.Detect(filePath)
-	if !ok {
-		return nil, fmt.Errorf("unsupported file type: %s", filePath)
-	}
+	chunks, _, err := c.chunkFile(repoPath, filePath)
+	return chunks, err
+}
 
-	// Read file content
+// chunkFile implements ChunkFile and also reports whether AST chunking was
+// attempted but failed, so the file was chunked by the token-based fallback
+func (c *Chunker) chunkFile(repoPath, filePath string) ([]models.CodeChunk, bool, error) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, false, fmt.Errorf("failed to read file: %w", err)
+	}
+	return c.chunkContent(repoPath, filePath, content)
+}
+
+// chunkContent implements chunkFile for content already read into memory
+// The content is converted to a string once and shared, read-only, by the AST and token chunkers.
+func (c *Chunker) chunkContent(repoPath, filePath string, content []byte) ([]models.CodeChunk, bool, error) {
+	// Detect language
+	lang, ok := c.langDetector.Detect(filePath)
+	if !ok {
+		if c.config.UnsupportedFiles != UnsupportedFilesText || !isText(content) {
+			return nil, false, fmt.Errorf("%w: %s", ErrUnsupportedFile, filePath)
+		}
+		lang = plainText
 	}
 
-	fileContent := string(content)
+	fileContent := normalizeLineEndings(string(content))
 	if strings.TrimSpace(fileContent) == "" {
-		return nil, nil // Skip empty files
+		return nil, false, nil // Skip empty files
 	}
 
 	// Calculate file size in lines for adaptive chunking
-	fileLines := strings.Count(fileContent, "\n") + 1
+	fileLines := len(splitLines(fileContent))
 	maxTokens, overlapTokens := c.calculateOptimalChunkSize(fileLines)
 
 	var chunks []models.CodeChunk
+	usedFallback := false
+
+	// Single-file components hold several languages, each chunked on its own
+	if sfcLanguages[lang.Name] {
+		sfcChunks, usedFallback, err := c.chunkSFC(repoPath, filePath, lang.Name, fileContent, maxTokens, overlapTokens)
+		if err != nil {
+			return nil, usedFallback, fmt.Errorf("token chunking failed: %w", err)
+		}
+		return c.prepareForEmbedding(c.attachImports(c.dropTrivialChunks(c.filterChunkTypes(sfcChunks)), lang.Name, fileContent)), usedFallback, nil
+	}
 
 	// Strategy 1: Try AST-based chunking (highest accuracy)
 	if c.astChunker != nil && c.astChunker.CanParseLanguage(lang.Name) {
-		astChunks, err := c.astChunker.ChunkByAST(repoPath, filePath, lang.Name, fileContent, c.config)
+		astChunks, partial, err := c.chunkByAST(repoPath, filePath, lang.Name, fileContent, maxTokens, overlapTokens)
 		if err == nil && len(astChunks) > 0 {
-			log.Printf("✓ AST chunking: %s (%d chunks, %d lines)", filePath, len(astChunks), fileLines)
-			return astChunks, nil
+			slog.Debug("AST chunking", "file", filePath, "chunks", len(astChunks), "lines", fileLines)
+			return c.prepareForEmbedding(c.attachImports(c.dropTrivialChunks(c.filterChunkTypes(astChunks)), lang.Name, fileContent)), partial, nil
 		}
-		// If AST parsing failed, fall through to token-based
+		// If AST parsing failed or found nothing usable, fall through to token-based
 		if err != nil {
-			log.Printf("AST parsing failed for %s: %v, falling back to token-based", filePath, err)
+			slog.Info("AST parsing failed, falling back to token-based chunking", "file", filePath, "error", err)
+		} else {
+			slog.Info("AST parsing found no semantic nodes, falling back to token-based chunking", "file", filePath)
 		}
+		usedFallback = true
 	}
 
 	// Strategy 2: Token-aware chunking (fallback for all languages)
 	// Pass limits directly to avoid race conditions from SetLimits
 	tokenChunks, err := c.tokenChunker.ChunkByTokensWithLimits(repoPath, filePath, lang.Name, fileContent, maxTokens, overlapTokens)
 	if err != nil {
-		return nil, fmt.Errorf("token chunking failed: %w", err)
+		return nil, usedFallback, fmt.Errorf("token chunking failed: %w", err)
 	}
 
 	if len(tokenChunks) > 0 {
-		log.Printf("✓ Token chunking: %s (%d chunks, %d lines, %d tokens/chunk)", filePath, len(tokenChunks), fileLines, maxTokens)
+		slog.Debug("Token chunking", "file", filePath, "chunks", len(tokenChunks), "lines", fileLines, "max_tokens", maxTokens)
 	}
 
 	chunks = append(chunks, tokenChunks...)
 
-	return chunks, nil
+	return c.prepareForEmbedding(c.attachImports(c.dropTrivialChunks(c.filterChunkTypes(chunks)), lang.Name, fileContent)), usedFallback, nil
+}
+
+// filterChunkTypes drops chunks whose type is not in Chunking.IndexChunkTypes
+func (c *Chunker) filterChunkTypes(chunks []models.CodeChunk) []models.CodeChunk {
+	if len(c.config.IndexChunkTypes) == 0 {
+		return chunks
+	}
+
+	filtered := chunks[:0]
+	for _, chunk := range chunks {
+		for _, allowed := range c.config.IndexChunkTypes {
+			if string(chunk.ChunkType) == allowed {
+				filtered = append(filtered, chunk)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// dropTrivialChunks drops chunks below Chunking.MinChunkLength for their language, such as
+// one-line getters that add little but dilute the index
+// If every chunk is that small, they are all kept so the file can still be found.
+func (c *Chunker) dropTrivialChunks(chunks []models.CodeChunk) []models.CodeChunk {
+	if c.config.MinChunkLength == (config.MinChunkLength{}) && len(c.config.MinChunkLengthByLanguage) == 0 {
+		return chunks
+	}
+
+	var kept []models.CodeChunk
+	for _, chunk := range chunks {
+		if !c.isTrivial(&chunk) {
+			kept = append(kept, chunk)
+		}
+	}
+	if len(kept) == 0 {
+		return chunks
+	}
+	if dropped := len(chunks) - len(kept); dropped > 0 {
+		slog.Debug("Dropped trivial chunks", "file", chunks[0].FilePath, "dropped", dropped)
+	}
+	return kept
+}
+
+// isTrivial reports whether a chunk is shorter than the minimum chunk length for its language
+func (c *Chunker) isTrivial(chunk *models.CodeChunk) bool {
+	minLength, ok := c.config.MinChunkLengthByLanguage[chunk.Language]
+	if !ok {
+		minLength = c.config.MinChunkLength
+	}
+	if minLength.Chars > 0 && utf8.RuneCountInString(strings.TrimSpace(chunk.Content)) < minLength.Chars {
+		return true
+	}
+	return minLength.Lines > 0 && chunk.EndLine-chunk.StartLine+1 < minLength.Lines
+}
+
+// validateChunkTypes checks that every configured chunk type is a known type
+func validateChunkTypes(types []string) error {
+	for _, name := range types {
+		known := false
+		for _, chunkType := range models.ChunkTypes {
+			if name == string(chunkType) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown chunk type %q in chunking.index_chunk_types (valid types: %v)", name, models.ChunkTypes)
+		}
+	}
+	return nil
+}
+
+// validateBoundaryPatterns checks that every chunking.boundary_patterns regex compiles
+func validateBoundaryPatterns(patterns map[string][]string) error {
+	_, err := compileBoundaryPatterns(patterns)
+	return err
+}
+
+// chunkByAST runs the AST chunker, converting a parser panic into an error
+// so one pathological file cannot take down the indexing run
+// Code the parser wrapped in syntax errors is chunked by tokens, which is reported as a fallback.
+func (c *Chunker) chunkByAST(repoPath, filePath, language, content string, maxTokens, overlapTokens int) (chunks []models.CodeChunk, usedFallback bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			chunks, usedFallback, err = nil, false, fmt.Errorf("AST chunker panicked: %v", r)
+		}
+	}()
+
+	chunks, broken, err := c.astChunker.ChunkByAST(repoPath, filePath, language, content, c.config)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(broken) == 0 {
+		return chunks, false, nil
+	}
+
+	slog.Info("AST parsing hit syntax errors, chunking the broken code by tokens", "file", filePath, "regions", len(broken))
+	for _, region := range broken {
+		regionChunks, err := c.tokenChunker.ChunkByTokensWithLimits(repoPath, filePath, language, region.content, maxTokens, overlapTokens)
+		if err != nil {
+			return nil, true, fmt.Errorf("token chunking failed: %w", err)
+		}
+		chunks = append(chunks, relocateChunks(regionChunks, embeddedRegion{language: language, startLine: region.startLine})...)
+	}
+	return chunks, true, nil
+}
+
+// prepareForEmbedding sets the text to embed for each chunk, leaving Content untouched for display
+// The text follows the embed strategy, with comments stripped and the file's imports, path and
+// symbol name prepended, as configured.
+func (c *Chunker) prepareForEmbedding(chunks []models.CodeChunk) []models.CodeChunk {
+	fullText := c.config.EmbedStrategy == "" || c.config.EmbedStrategy == EmbedStrategyFull
+	if !c.config.StripComments && !c.config.IncludeImports && !c.config.EmbedFilePath && !c.config.EmbedSymbolName && fullText {
+		return chunks
+	}
+
+	for i := range chunks {
+		text := embedSource(&chunks[i], c.config)
+		if c.config.StripComments {
+			stripped := stripComments(text, chunks[i].Language, c.config.KeepDocComments)
+			// Chunks that are entirely comments keep their original text, otherwise they would embed nothing
+			if strings.TrimSpace(stripped) != "" {
+				text = stripped
+			}
+		}
+		if imports, ok := chunks[i].Metadata[models.MetadataImports].(string); ok && imports != "" && c.config.IncludeImports {
+			text = importContextPrefix + imports + "\n" + text
+		}
+		text = nameContext(&chunks[i], c.config) + text
+		if text != chunks[i].Content {
+			chunks[i].EmbedContent = text
+		}
+	}
+
+	return chunks
 }
 
 // calculateOptimalChunkSize determines optimal chunk size based on file size
@@ -147,10 +462,24 @@ func (c *Chunker) calculateOptimalChunkSize(fileLines int) (maxTokens, overlapTo
 		overlapTokens = DefaultOverlapTokens
 	}
 
+	if fraction := overlapFraction(c.config); fraction > 0 {
+		overlapTokens = int(float64(maxTokens) * fraction)
+	}
+
 	return maxTokens, overlapTokens
 }
 
+// overlapFraction returns chunking.overlap_fraction, or 0 (absolute overlap) when it's not
+// between 0 and 1
+func overlapFraction(cfg *config.ChunkingConfig) float64 {
+	if cfg == nil || cfg.OverlapFraction <= 0 || cfg.OverlapFraction >= 1 {
+		return 0
+	}
+	return cfg.OverlapFraction
+}
+
 // GetStats returns statistics about chunking
+// Includes the total and a count per chunk type (function, class, method, file)
 func (c *Chunker) GetStats(chunks []models.CodeChunk) map[string]int {
 	stats := map[string]int{
 		"total":    len(chunks),
@@ -158,9 +487,7 @@ func (c *Chunker) GetStats(chunks []models.CodeChunk) map[string]int {
 	}
 
 	for _, chunk := range chunks {
-		if chunk.ChunkType == models.ChunkTypeFunction {
-			stats["function"]++
-		}
+		stats[string(chunk.ChunkType)]++
 	}
 
 	return stats
@@ -1,10 +1,13 @@
 package indexer
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/jamaly87/codebase-semantic-search/internal/models"
 	"github.com/jamaly87/codebase-semantic-search/pkg/config"
@@ -263,3 +266,549 @@ func generateJavaFile(lines int) string {
 	return sb.String()
 }
 
+
+func TestSplitLinesBySize(t *testing.T) {
+	t.Run("splits at line boundaries without losing lines", func(t *testing.T) {
+		lines := []string{"aaaa", "bbbb", "cccc", "dddd", "eeee"}
+		spans := splitLinesBySize(lines, 10, 9, 0)
+
+		var rejoined []string
+		for _, span := range spans {
+			if len(span.content()) > 9 {
+				t.Errorf("Span %q exceeds limit", span.content())
+			}
+			rejoined = append(rejoined, span.lines...)
+		}
+		if strings.Join(rejoined, "\n") != strings.Join(lines, "\n") {
+			t.Errorf("Expected all lines preserved in order, got %v", rejoined)
+		}
+		if spans[0].startLine != 10 || spans[len(spans)-1].endLine() != 14 {
+			t.Errorf("Unexpected line range: %d-%d", spans[0].startLine, spans[len(spans)-1].endLine())
+		}
+	})
+
+	t.Run("overlap repeats trailing lines", func(t *testing.T) {
+		lines := []string{"aaaa", "bbbb", "cccc", "dddd"}
+		spans := splitLinesBySize(lines, 1, 14, 1)
+		if len(spans) != 2 {
+			t.Fatalf("Expected 2 spans, got %d", len(spans))
+		}
+		if spans[1].startLine != 3 || spans[1].lines[0] != "cccc" {
+			t.Errorf("Expected second span to start with overlap line 3, got line %d %q", spans[1].startLine, spans[1].lines[0])
+		}
+	})
+
+	t.Run("long line is cut into pieces on the same line", func(t *testing.T) {
+		long := strings.Repeat("x", 25)
+		spans := splitLinesBySize([]string{"short", long, "tail"}, 1, 10, 0)
+
+		var pieces []string
+		for _, span := range spans {
+			if len(span.content()) > 10 {
+				t.Errorf("Span %q exceeds limit", span.content())
+			}
+			if span.startLine == 2 {
+				pieces = append(pieces, span.content())
+			}
+		}
+		if strings.Join(pieces, "") != long {
+			t.Errorf("Expected long line to be preserved across pieces, got %v", pieces)
+		}
+	})
+
+	t.Run("multibyte line is cut between characters", func(t *testing.T) {
+		long := strings.Repeat("日本語🚀", 20)
+		spans := splitLinesBySize([]string{long}, 1, 10, 0)
+
+		var pieces []string
+		for _, span := range spans {
+			if !utf8.ValidString(span.content()) {
+				t.Errorf("Span %q is not valid UTF-8", span.content())
+			}
+			if len(span.content()) > 10 {
+				t.Errorf("Span %q exceeds limit", span.content())
+			}
+			pieces = append(pieces, span.content())
+		}
+		if strings.Join(pieces, "") != long {
+			t.Error("Expected multibyte line to be preserved across pieces")
+		}
+	})
+}
+
+func TestTruncateAtRuneBoundary(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		maxBytes int
+		expected string
+	}{
+		{"fits", "héllo", 10, "héllo"},
+		{"ascii", "hello", 3, "hel"},
+		{"cut inside two-byte char", "hé", 2, "h"},
+		{"cut inside CJK", "日本語", 5, "日"},
+		{"cut inside emoji", "a🚀b", 3, "a"},
+		{"first char larger than limit", "🚀🚀", 2, "🚀"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateAtRuneBoundary(tt.input, tt.maxBytes)
+			if got != tt.expected {
+				t.Errorf("truncateAtRuneBoundary(%q, %d) = %q, expected %q", tt.input, tt.maxBytes, got, tt.expected)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("Expected valid UTF-8, got %q", got)
+			}
+		})
+	}
+}
+
+func TestTruncateAtLineBoundary(t *testing.T) {
+	content := "line one\nline two\nline three"
+	if got := truncateAtLineBoundary(content, 100); got != content {
+		t.Errorf("Expected short content unchanged, got %q", got)
+	}
+	if got := truncateAtLineBoundary(content, 20); got != "line one\nline two" {
+		t.Errorf("Expected truncation at line boundary, got %q", got)
+	}
+
+	// A single multibyte line is cut between characters
+	if got := truncateAtLineBoundary("コード🚀コード", 11); got != "コード" {
+		t.Errorf("Expected truncation at a rune boundary, got %q", got)
+	}
+}
+
+// newFallbackTestChunker creates a chunker with both AST and token strategies,
+// skipping when the parser is unavailable
+func newFallbackTestChunker(t *testing.T) *Chunker {
+	t.Helper()
+
+	astChunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+	tokenChunker, err := NewTokenChunkerWithEncoding(DefaultMaxTokens, DefaultOverlapTokens, ApproximateEncoding)
+	if err != nil {
+		t.Skipf("Tokenizer not available: %v", err)
+	}
+
+	return &Chunker{
+		config:       &config.ChunkingConfig{MaxChunkSizeBytes: 4000},
+		langDetector: NewLanguageDetector(),
+		astChunker:   astChunker,
+		tokenChunker: tokenChunker,
+	}
+}
+
+func TestChunker_FallbackOnSyntaxErrors(t *testing.T) {
+	chunker := newFallbackTestChunker(t)
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name         string
+		file         string
+		content      string
+		expectMarker string
+		fallback     bool
+	}{
+		{
+			// The broken code stays in its method's chunk, so no tokens are needed
+			name:         "java with a broken method",
+			file:         "Broken.java",
+			content:      "public class Broken {\n    public void run( {\n        String marker = \"brokenJavaMarker\";\n}\n",
+			expectMarker: "brokenJavaMarker",
+			fallback:     false,
+		},
+		{
+			name:         "java with stray code after the class",
+			file:         "Stray.java",
+			content:      "public class Stray {\n    public void run() {\n        int total = compute();\n    }\n}\n\n}} String strayJavaMarker = = ;\n",
+			expectMarker: "strayJavaMarker",
+			fallback:     true,
+		},
+		{
+			name:         "broken typescript",
+			file:         "broken.ts",
+			content:      "export function parse(input: string): string[] {\n  return input.split(',')\n}\n\n)) const brokenTsMarker = = 1\n",
+			expectMarker: "brokenTsMarker",
+			fallback:     true,
+		},
+		{
+			name:         "valid java",
+			file:         "Ok.java",
+			content:      "public class Ok {\n    public void run() {\n        String marker = \"validJavaMarker\";\n    }\n}\n",
+			expectMarker: "validJavaMarker",
+			fallback:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(tmpDir, tt.file)
+			if err := os.WriteFile(filePath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			chunks, usedFallback, err := chunker.chunkFile(tmpDir, filePath)
+			if err != nil {
+				t.Fatalf("chunkFile failed: %v", err)
+			}
+			if usedFallback != tt.fallback {
+				t.Errorf("Expected fallback=%v, got %v", tt.fallback, usedFallback)
+			}
+
+			found := false
+			for _, chunk := range chunks {
+				if strings.Contains(chunk.Content, tt.expectMarker) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected the file's code to be indexed, got %d chunks without %q", len(chunks), tt.expectMarker)
+			}
+		})
+	}
+}
+
+func TestChunker_IndexChunkTypes(t *testing.T) {
+	astChunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+	defer astChunker.Close()
+
+	tmpDir := t.TempDir()
+	content := `export function formatName(user: User): string {
+  return user.first + " " + user.last;
+}
+
+export class UserService {
+  private users: User[] = [];
+
+  add(user: User): void {
+    this.users.push(user);
+    console.log("added user", user.first, user.last, this.users.length);
+  }
+
+  find(first: string): User | undefined {
+    const match = this.users.find(u => u.first === first);
+    console.log("looked up user", first, match !== undefined);
+    return match;
+  }
+}
+`
+	filePath := filepath.Join(tmpDir, "users.ts")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		expect  []models.ChunkType
+	}{
+		{"all types by default", nil, []models.ChunkType{models.ChunkTypeFunction, models.ChunkTypeClass, models.ChunkTypeMethod}},
+		{"functions only", []string{"function"}, []models.ChunkType{models.ChunkTypeFunction}},
+		{"class and methods", []string{"class", "method"}, []models.ChunkType{models.ChunkTypeClass, models.ChunkTypeMethod}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunker := &Chunker{
+				config: &config.ChunkingConfig{
+					EnableHierarchicalChunking: true,
+					MaxChunkSizeBytes:          200, // Small enough to split the class into methods
+					IndexChunkTypes:            tt.allowed,
+				},
+				langDetector: NewLanguageDetector(),
+				astChunker:   astChunker,
+			}
+
+			chunks, err := chunker.ChunkFile(tmpDir, filePath)
+			if err != nil {
+				t.Fatalf("ChunkFile failed: %v", err)
+			}
+
+			seen := make(map[models.ChunkType]bool)
+			for _, chunk := range chunks {
+				seen[chunk.ChunkType] = true
+			}
+			for _, chunkType := range tt.expect {
+				if !seen[chunkType] {
+					t.Errorf("Expected %s chunks, got types %v", chunkType, seen)
+				}
+			}
+			if len(seen) != len(tt.expect) {
+				t.Errorf("Expected only %v chunks, got types %v", tt.expect, seen)
+			}
+		})
+	}
+}
+
+func TestChunker_MinChunkLength(t *testing.T) {
+	astChunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+	defer astChunker.Close()
+	tokenChunker, err := NewTokenChunkerWithEncoding(DefaultMaxTokens, DefaultOverlapTokens, ApproximateEncoding)
+	if err != nil {
+		t.Fatalf("Failed to create token chunker: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	content := `export class Point {
+  private _x = 0;
+
+  get x() { return this._x; }
+
+  moveBy(dx: number, dy: number): void {
+    this._x += dx;
+    this._y += dy;
+    console.log("moved point", this._x, this._y, "by", dx, dy, "units");
+  }
+}
+`
+	filePath := filepath.Join(tmpDir, "point.ts")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	tinyPath := filepath.Join(tmpDir, "tiny.ts")
+	if err := os.WriteFile(tinyPath, []byte("export function id(x) { return x; }\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	chunkNames := func(cfg *config.ChunkingConfig, path string) []string {
+		chunker := &Chunker{config: cfg, langDetector: NewLanguageDetector(), astChunker: astChunker, tokenChunker: tokenChunker}
+		chunks, err := chunker.ChunkFile(tmpDir, path)
+		if err != nil {
+			t.Fatalf("ChunkFile failed: %v", err)
+		}
+		var names []string
+		for _, chunk := range chunks {
+			names = append(names, string(chunk.ChunkType)+":"+chunk.FunctionName)
+		}
+		return names
+	}
+	// Small enough to split the class into methods
+	base := config.ChunkingConfig{EnableHierarchicalChunking: true, MaxChunkSizeBytes: 120}
+
+	all := chunkNames(&base, filePath)
+	if !slices.Contains(all, "method:x") || !slices.Contains(all, "method:moveBy") {
+		t.Fatalf("Expected the getter and moveBy methods without a minimum, got %v", all)
+	}
+
+	tests := []struct {
+		name string
+		cfg  func(cfg *config.ChunkingConfig)
+	}{
+		{"chars", func(cfg *config.ChunkingConfig) { cfg.MinChunkLength = config.MinChunkLength{Chars: 40} }},
+		{"lines", func(cfg *config.ChunkingConfig) { cfg.MinChunkLength = config.MinChunkLength{Lines: 2} }},
+		{"per language", func(cfg *config.ChunkingConfig) {
+			cfg.MinChunkLength = config.MinChunkLength{Chars: 1000}
+			cfg.MinChunkLengthByLanguage = map[string]config.MinChunkLength{"typescript": {Chars: 40}}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			tt.cfg(&cfg)
+			names := chunkNames(&cfg, filePath)
+			if slices.Contains(names, "method:x") {
+				t.Errorf("Expected the one-line getter to be dropped, got %v", names)
+			}
+			if !slices.Contains(names, "method:moveBy") {
+				t.Errorf("Expected moveBy to remain, got %v", names)
+			}
+
+			// A file with only trivial chunks keeps them
+			if tiny := chunkNames(&cfg, tinyPath); len(tiny) == 0 {
+				t.Error("Expected a file's only chunks to be kept")
+			}
+		})
+	}
+
+	// Another language's minimum doesn't apply
+	cfg := base
+	cfg.MinChunkLengthByLanguage = map[string]config.MinChunkLength{"java": {Chars: 40}}
+	if names := chunkNames(&cfg, filePath); !slices.Contains(names, "method:x") {
+		t.Errorf("Expected a java minimum not to drop typescript chunks, got %v", names)
+	}
+}
+
+func TestValidateChunkTypes(t *testing.T) {
+	if err := validateChunkTypes([]string{"function", "class", "method", "file"}); err != nil {
+		t.Errorf("Expected known chunk types to be valid, got %v", err)
+	}
+	if err := validateChunkTypes(nil); err != nil {
+		t.Errorf("Expected an empty list to be valid, got %v", err)
+	}
+
+	err := validateChunkTypes([]string{"function", "functions"})
+	if err == nil || !strings.Contains(err.Error(), `"functions"`) {
+		t.Errorf("Expected an error naming the unknown type, got %v", err)
+	}
+}
+
+// newApproximateTestChunker creates a chunker whose token strategy uses the approximate
+// tokenizer, so it works without downloading an encoding
+func newApproximateTestChunker(t *testing.T) *Chunker {
+	t.Helper()
+
+	tokenChunker, err := NewTokenChunkerWithEncoding(DefaultMaxTokens, DefaultOverlapTokens, ApproximateEncoding)
+	if err != nil {
+		t.Fatalf("Failed to create token chunker: %v", err)
+	}
+	astChunker, err := NewASTChunker()
+	if err != nil {
+		astChunker = nil
+	}
+	return &Chunker{
+		config:       &config.ChunkingConfig{MaxChunkSizeBytes: 4000},
+		langDetector: NewLanguageDetector(),
+		astChunker:   astChunker,
+		tokenChunker: tokenChunker,
+	}
+}
+
+func TestChunker_LineEndings(t *testing.T) {
+	chunker := newApproximateTestChunker(t)
+
+	goSource := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n"
+	javaSource := "public class Greeter {\n    public void greet() {\n        System.out.println(\"hi\");\n    }\n}\n"
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"go with LF", "main.go", goSource},
+		{"go with CRLF", "main.go", strings.ReplaceAll(goSource, "\n", "\r\n")},
+		{"go without trailing newline", "main.go", strings.TrimSuffix(goSource, "\n")},
+		{"go with CRLF and no trailing newline", "main.go", strings.TrimSuffix(strings.ReplaceAll(goSource, "\n", "\r\n"), "\r\n")},
+		{"java with CRLF", "Greeter.java", strings.ReplaceAll(javaSource, "\n", "\r\n")},
+		{"java without trailing newline", "Greeter.java", strings.TrimSuffix(javaSource, "\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lineCount := len(strings.Split(strings.TrimSuffix(strings.ReplaceAll(tt.content, "\r\n", "\n"), "\n"), "\n"))
+
+			chunks, _, err := chunker.chunkContent("/repo", "/repo/"+tt.file, []byte(tt.content))
+			if err != nil {
+				t.Fatalf("chunkContent failed: %v", err)
+			}
+			if len(chunks) == 0 {
+				t.Fatal("Expected chunks")
+			}
+
+			lastLine := 0
+			for _, chunk := range chunks {
+				if strings.Contains(chunk.Content, "\r") {
+					t.Errorf("Expected no carriage returns in chunk content, got %q", chunk.Content)
+				}
+				if strings.TrimSpace(chunk.Content) == "" {
+					t.Errorf("Expected no empty chunks, got one at lines %d-%d", chunk.StartLine, chunk.EndLine)
+				}
+				if chunk.EndLine-chunk.StartLine+1 != len(strings.Split(chunk.Content, "\n")) {
+					t.Errorf("Expected lines %d-%d to match the chunk's %d lines", chunk.StartLine, chunk.EndLine, len(strings.Split(chunk.Content, "\n")))
+				}
+				lastLine = max(lastLine, chunk.EndLine)
+			}
+			if lastLine != lineCount {
+				t.Errorf("Expected chunks to end on the file's last line %d, got %d", lineCount, lastLine)
+			}
+		})
+	}
+}
+
+func TestChunker_OverlapFraction(t *testing.T) {
+	chunker := newApproximateTestChunker(t)
+	chunker.config = &config.ChunkingConfig{
+		SmallFileMaxTokens:  100,
+		MediumFileMaxTokens: 200,
+		LargeFileMaxTokens:  400,
+		MaxChunkSizeBytes:   4000,
+		OverlapFraction:     0.25,
+	}
+
+	// Distinct lines of 5 approximate tokens each, none a boundary
+	var lines []string
+	for i := 0; i < 400; i++ {
+		lines = append(lines, fmt.Sprintf("\tvalue%04d = 12345", i))
+	}
+	content := strings.Join(lines, "\n")
+
+	for _, fileLines := range []int{100, 2000, 8000} {
+		maxTokens, overlapTokens := chunker.calculateOptimalChunkSize(fileLines)
+		if overlapTokens != maxTokens/4 {
+			t.Errorf("Expected an overlap of a quarter of %d tokens for a %d-line file, got %d", maxTokens, fileLines, overlapTokens)
+		}
+
+		chunks, err := chunker.tokenChunker.ChunkByTokensWithLimits("/repo", "/repo/main.go", "go", content, maxTokens, overlapTokens)
+		if err != nil {
+			t.Fatalf("ChunkByTokensWithLimits failed: %v", err)
+		}
+		if len(chunks) < 2 {
+			t.Fatalf("Expected several chunks with %d max tokens, got %d", maxTokens, len(chunks))
+		}
+
+		// Leading lines of the second chunk repeated from the first
+		first := strings.Split(chunks[0].Content, "\n")
+		second := strings.Split(chunks[1].Content, "\n")
+		overlap := 0
+		for overlap < len(second) && slices.Contains(first, second[overlap]) {
+			overlap++
+		}
+		if tokens := overlap * 5; tokens < overlapTokens-5 || float64(tokens) > float64(overlapTokens)*maxOverlapExcessRatio {
+			t.Errorf("Expected about %d tokens of overlap with %d max tokens, got %d lines (%d tokens)", overlapTokens, maxTokens, overlap, tokens)
+		}
+	}
+
+	// Without a fraction the tier's absolute overlap is kept
+	chunker.config.OverlapFraction = 0
+	if _, overlapTokens := chunker.calculateOptimalChunkSize(100); overlapTokens != 100/SmallFileOverlapRatio {
+		t.Errorf("Expected the absolute small-file overlap %d, got %d", 100/SmallFileOverlapRatio, overlapTokens)
+	}
+}
+
+func TestSplitLargeChunk_OverlapFraction(t *testing.T) {
+	ac := &ASTChunker{}
+
+	for _, lineCount := range []int{200, 800} {
+		var lines []string
+		for i := 0; i < lineCount; i++ {
+			lines = append(lines, fmt.Sprintf("line%04d", i))
+		}
+		chunk := &models.CodeChunk{RepoPath: "/repo", FilePath: "/repo/Big.java", Content: strings.Join(lines, "\n"), StartLine: 1}
+
+		// The overlap is a fraction of each piece (half the chunk), capped at maxOverlapLines
+		split := ac.splitLargeChunk(chunk, chunk.Content, len(chunk.Content)/2+1, 0.05)
+		if len(split) < 2 {
+			t.Fatalf("Expected the %d-line chunk to be split, got %d chunks", lineCount, len(split))
+		}
+		expected := min(lineCount/40, maxOverlapLines)
+		if overlap := split[0].EndLine - split[1].StartLine + 1; overlap != expected {
+			t.Errorf("Expected %d lines of overlap for a %d-line chunk, got %d", expected, lineCount, overlap)
+		}
+	}
+}
+
+func TestSplitLargeChunk_OverlapFractionBoundsChunkCount(t *testing.T) {
+	ac := &ASTChunker{}
+
+	var lines []string
+	for i := 0; i < 500; i++ {
+		lines = append(lines, fmt.Sprintf("    total += compute(value%03d, offset)", i))
+	}
+	chunk := &models.CodeChunk{RepoPath: "/repo", FilePath: "/repo/Big.java", Content: strings.Join(lines, "\n"), StartLine: 1}
+
+	base := len(ac.splitLargeChunk(chunk, chunk.Content, 4000, 0))
+	for _, fraction := range []float64{0.1, 0.2, 0.5} {
+		// An overlap of a fraction f of each piece makes at most about 1/(1-f) times the pieces
+		if n := len(ac.splitLargeChunk(chunk, chunk.Content, 4000, fraction)); n > 2*base+1 {
+			t.Errorf("Expected overlap fraction %.1f to keep the split near %d chunks, got %d", fraction, base, n)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package indexer
+
+import (
+	"strings"
+)
+
+// commentStyle describes how comments are written in a language
+type commentStyle int
+
+const (
+	commentStyleNone  commentStyle = iota
+	commentStyleC                  // // line and /* block */ comments
+	commentStyleShell              // # line comments
+)
+
+// commentStyles maps language names to their comment syntax
+var commentStyles = map[string]commentStyle{
+	"java":       commentStyleC,
+	"javascript": commentStyleC,
+	"typescript": commentStyleC,
+	"kotlin":     commentStyleC,
+	"go":         commentStyleC,
+	"php":        commentStyleC, // # comments are kept: "#[" starts an attribute
+	"swift":      commentStyleC,
+	"python":     commentStyleShell,
+	"ruby":       commentStyleShell,
+}
+
+// stripComments removes comments from source code so they do not dilute embeddings
+// String literals are respected, so "//" inside a URL string is kept.
+// With keepDocComments set, /** ... */ doc comments are preserved.
+// Languages without a known comment syntax are returned unchanged.
+func stripComments(content, language string, keepDocComments bool) string {
+	var stripped string
+	switch commentStyles[language] {
+	case commentStyleC:
+		stripped = stripCStyleComments(content, keepDocComments)
+	case commentStyleShell:
+		stripped = stripShellComments(content)
+	default:
+		return content
+	}
+	return tidyStrippedLines(stripped)
+}
+
+// stripCStyleComments removes // and /* */ comments outside string literals
+func stripCStyleComments(content string, keepDocComments bool) string {
+	var out strings.Builder
+	out.Grow(len(content))
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			end := skipQuoted(content, i, c)
+			out.WriteString(content[i:end])
+			i = end
+
+		case strings.HasPrefix(content[i:], "//"):
+			// Drop the comment but keep the newline
+			end := strings.IndexByte(content[i:], '\n')
+			if end == -1 {
+				i = len(content)
+			} else {
+				i += end
+			}
+
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end == -1 {
+				end = len(content)
+			} else {
+				end = i + 2 + end + 2
+			}
+			isDoc := strings.HasPrefix(content[i:], "/**") && !strings.HasPrefix(content[i:], "/**/")
+			if keepDocComments && isDoc {
+				out.WriteString(content[i:end])
+			} else {
+				// Keep line structure so surrounding code stays readable
+				out.WriteString(strings.Repeat("\n", strings.Count(content[i:end], "\n")))
+			}
+			i = end
+
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.String()
+}
+
+// stripShellComments removes # comments outside string literals
+func stripShellComments(content string) string {
+	var out strings.Builder
+	out.Grow(len(content))
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case strings.HasPrefix(content[i:], `"""`) || strings.HasPrefix(content[i:], `'''`):
+			// Triple-quoted strings (docstrings) are code, not comments
+			quote := content[i : i+3]
+			end := strings.Index(content[i+3:], quote)
+			if end == -1 {
+				end = len(content)
+			} else {
+				end = i + 3 + end + 3
+			}
+			out.WriteString(content[i:end])
+			i = end
+
+		case c == '"' || c == '\'':
+			end := skipQuoted(content, i, c)
+			out.WriteString(content[i:end])
+			i = end
+
+		case c == '#':
+			end := strings.IndexByte(content[i:], '\n')
+			if end == -1 {
+				i = len(content)
+			} else {
+				i += end
+			}
+
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.String()
+}
+
+// skipQuoted returns the index just past the string literal starting at start
+// Backslash escapes are honored; unterminated single-line strings end at the newline.
+func skipQuoted(content string, start int, quote byte) int {
+	for i := start + 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++ // Skip escaped character
+		case quote:
+			return i + 1
+		case '\n':
+			if quote != '`' {
+				return i
+			}
+		}
+	}
+	return len(content)
+}
+
+// tidyStrippedLines trims trailing whitespace and collapses the blank lines left behind by removed comments
+func tidyStrippedLines(content string) string {
+	lines := strings.Split(content, "\n")
+	result := make([]string, 0, len(lines))
+	previousBlank := false
+
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		blank := line == ""
+		if blank && previousBlank {
+			continue
+		}
+		result = append(result, line)
+		previousBlank = blank
+	}
+
+	return strings.TrimSpace(strings.Join(result, "\n"))
+}
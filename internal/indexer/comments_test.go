@@ -0,0 +1,68 @@
+package indexer
+
+import (
+	"testing"
+)
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name            string
+		language        string
+		keepDocComments bool
+		input           string
+		expected        string
+	}{
+		{
+			name:     "java line and block comments",
+			language: "java",
+			input:    "/* header */\nint x = 1; // counter\n/*\n * multi\n */\nint y = 2;",
+			expected: "int x = 1;\n\nint y = 2;",
+		},
+		{
+			name:     "comment markers inside strings are kept",
+			language: "javascript",
+			input:    "const url = \"https://example.com\"; // link\nconst s = '/* not a comment */';",
+			expected: "const url = \"https://example.com\";\nconst s = '/* not a comment */';",
+		},
+		{
+			name:     "template literals and escaped quotes",
+			language: "typescript",
+			input:    "const a = `line // one\nline two`;\nconst b = \"say \\\"hi\\\" // still string\"; // gone",
+			expected: "const a = `line // one\nline two`;\nconst b = \"say \\\"hi\\\" // still string\";",
+		},
+		{
+			name:            "doc comments kept when enabled",
+			language:        "java",
+			keepDocComments: true,
+			input:           "/** Does work */\n/* internal */\nvoid work() {}",
+			expected:        "/** Does work */\n\nvoid work() {}",
+		},
+		{
+			name:     "doc comments stripped when disabled",
+			language: "java",
+			input:    "/** Does work */\nvoid work() {}",
+			expected: "void work() {}",
+		},
+		{
+			name:     "python hash comments",
+			language: "python",
+			input:    "# module comment\nx = \"#not a comment\"  # trailing\ndef f():\n    '''docstring # kept'''\n    return x",
+			expected: "x = \"#not a comment\"\ndef f():\n    '''docstring # kept'''\n    return x",
+		},
+		{
+			name:     "unknown language unchanged",
+			language: "cobol",
+			input:    "// not touched",
+			expected: "// not touched",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stripComments(tt.input, tt.language, tt.keepDocComments)
+			if got != tt.expected {
+				t.Errorf("stripComments() =\n%q\nwant\n%q", got, tt.expected)
+			}
+		})
+	}
+}
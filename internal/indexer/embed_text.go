@@ -0,0 +1,142 @@
+package indexer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// Strategies for chunking.embed_strategy, selecting the text embedded for function and method chunks
+const (
+	EmbedStrategyFull      = "full"      // The whole chunk (default)
+	EmbedStrategySignature = "signature" // The leading doc comment and the signature
+	EmbedStrategyHead      = "head"      // The first embed_head_lines lines
+)
+
+const (
+	// DefaultEmbedHeadLines is how many lines the "head" strategy embeds when unset
+	DefaultEmbedHeadLines = 10
+	// maxSignatureLines caps a signature with no body opener in sight (e.g. an expression body)
+	maxSignatureLines = 8
+)
+
+// Prefixes of the name context lines prepended to embedded texts
+const (
+	filePathContextPrefix = "file: "
+	symbolContextPrefix   = "symbol: "
+)
+
+// validateEmbedStrategy checks chunking.embed_strategy
+func validateEmbedStrategy(strategy string) error {
+	switch strategy {
+	case "", EmbedStrategyFull, EmbedStrategySignature, EmbedStrategyHead:
+		return nil
+	default:
+		return fmt.Errorf("unknown chunking.embed_strategy %q (valid: %s, %s, %s)",
+			strategy, EmbedStrategyFull, EmbedStrategySignature, EmbedStrategyHead)
+	}
+}
+
+// embedSource returns the part of a chunk's content to embed under chunking.embed_strategy
+// Only function and method chunks found by the AST chunker are shortened; others embed in full.
+func embedSource(chunk *models.CodeChunk, cfg *config.ChunkingConfig) string {
+	if chunk.FunctionName == "" || (chunk.ChunkType != models.ChunkTypeFunction && chunk.ChunkType != models.ChunkTypeMethod) {
+		return chunk.Content
+	}
+
+	switch cfg.EmbedStrategy {
+	case EmbedStrategySignature:
+		return signatureWithDoc(chunk.Content, chunk.Language)
+	case EmbedStrategyHead:
+		n := cfg.EmbedHeadLines
+		if n <= 0 {
+			n = DefaultEmbedHeadLines
+		}
+		lines := strings.Split(chunk.Content, "\n")
+		if len(lines) <= n {
+			return chunk.Content
+		}
+		return strings.Join(lines[:n], "\n")
+	default:
+		return chunk.Content
+	}
+}
+
+// signatureWithDoc returns a function's leading doc comment and its signature, up to the
+// line opening the body
+func signatureWithDoc(content, language string) string {
+	lines := strings.Split(content, "\n")
+
+	i := 0
+	for i < len(lines) && isCommentLine(strings.TrimSpace(lines[i])) {
+		i++
+	}
+	for n := 0; i < len(lines) && n < maxSignatureLines; n++ {
+		trimmed := strings.TrimSpace(lines[i])
+		i++
+		if trimmed == "" || strings.HasPrefix(trimmed, "@") || strings.HasPrefix(trimmed, "#[") {
+			continue // Annotations and attributes precede the signature
+		}
+		if strings.Contains(trimmed, "{") || strings.HasSuffix(trimmed, "=>") {
+			break
+		}
+		// Ruby bodies aren't delimited: the signature ends with its parameter list
+		if language == "ruby" && !strings.HasSuffix(trimmed, ",") && !strings.HasSuffix(trimmed, "(") {
+			break
+		}
+	}
+	return strings.Join(lines[:i], "\n")
+}
+
+// isCommentLine reports whether a trimmed line is (part of) a comment
+func isCommentLine(trimmed string) bool {
+	for _, prefix := range []string{"//", "/*", "*", "#"} {
+		if strings.HasPrefix(trimmed, prefix) && !strings.HasPrefix(trimmed, "#[") {
+			return true
+		}
+	}
+	return false
+}
+
+// nameContext returns the lines naming a chunk's file and symbol, embedded before its text
+// under chunking.embed_file_path and chunking.embed_symbol_name, or "" when neither applies
+func nameContext(chunk *models.CodeChunk, cfg *config.ChunkingConfig) string {
+	var header strings.Builder
+	if cfg.EmbedFilePath && chunk.FilePath != "" {
+		header.WriteString(filePathContextPrefix + repoRelativePath(chunk.RepoPath, chunk.FilePath) + "\n")
+	}
+	if cfg.EmbedSymbolName {
+		if symbol := symbolName(chunk); symbol != "" {
+			header.WriteString(symbolContextPrefix + symbol + "\n")
+		}
+	}
+	return header.String()
+}
+
+// repoRelativePath returns filePath relative to repoPath with forward slashes, or filePath
+// when it isn't inside the repository
+func repoRelativePath(repoPath, filePath string) string {
+	if repoPath == "" {
+		return filePath
+	}
+	rel, err := filepath.Rel(repoPath, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filePath
+	}
+	return filepath.ToSlash(rel)
+}
+
+// symbolName returns a chunk's qualified name: Class.method, the function or the class
+func symbolName(chunk *models.CodeChunk) string {
+	switch {
+	case chunk.ClassName != "" && chunk.FunctionName != "" && chunk.ClassName != chunk.FunctionName:
+		return chunk.ClassName + "." + chunk.FunctionName
+	case chunk.FunctionName != "":
+		return chunk.FunctionName
+	default:
+		return chunk.ClassName
+	}
+}
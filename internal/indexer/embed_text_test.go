@@ -0,0 +1,211 @@
+package indexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+func TestEmbedStrategy(t *testing.T) {
+	goFunc := `// Retry calls fn until it succeeds or attempts run out,
+// waiting longer after each failure.
+func Retry(attempts int, fn func() error) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		time.Sleep(time.Duration(i) * time.Second)
+	}
+	return err
+}`
+	javaMethod := `@Override
+public List<User> findActive(
+        UserFilter filter,
+        int limit) {
+    return repository.query(filter).stream().limit(limit).toList();
+}`
+	rubyMethod := "def charge(amount, currency)\n  gateway.charge(amount, currency)\nend"
+
+	tests := []struct {
+		name     string
+		strategy string
+		chunk    models.CodeChunk
+		expected string
+	}{
+		{
+			name:     "full",
+			strategy: EmbedStrategyFull,
+			chunk:    models.CodeChunk{ChunkType: models.ChunkTypeFunction, FunctionName: "Retry", Language: "go", Content: goFunc},
+			expected: goFunc,
+		},
+		{
+			name:     "signature with doc comment",
+			strategy: EmbedStrategySignature,
+			chunk:    models.CodeChunk{ChunkType: models.ChunkTypeFunction, FunctionName: "Retry", Language: "go", Content: goFunc},
+			expected: "// Retry calls fn until it succeeds or attempts run out,\n// waiting longer after each failure.\nfunc Retry(attempts int, fn func() error) error {",
+		},
+		{
+			name:     "multi-line signature with annotation",
+			strategy: EmbedStrategySignature,
+			chunk:    models.CodeChunk{ChunkType: models.ChunkTypeMethod, FunctionName: "findActive", Language: "java", Content: javaMethod},
+			expected: "@Override\npublic List<User> findActive(\n        UserFilter filter,\n        int limit) {",
+		},
+		{
+			name:     "ruby signature",
+			strategy: EmbedStrategySignature,
+			chunk:    models.CodeChunk{ChunkType: models.ChunkTypeMethod, FunctionName: "charge", Language: "ruby", Content: rubyMethod},
+			expected: "def charge(amount, currency)",
+		},
+		{
+			name:     "head",
+			strategy: EmbedStrategyHead,
+			chunk:    models.CodeChunk{ChunkType: models.ChunkTypeFunction, FunctionName: "Retry", Language: "go", Content: goFunc},
+			expected: strings.Join(strings.Split(goFunc, "\n")[:4], "\n"),
+		},
+		{
+			name:     "class chunks embed in full",
+			strategy: EmbedStrategySignature,
+			chunk:    models.CodeChunk{ChunkType: models.ChunkTypeClass, ClassName: "Users", Language: "java", Content: javaMethod},
+			expected: javaMethod,
+		},
+		{
+			name:     "token chunks embed in full",
+			strategy: EmbedStrategyHead,
+			chunk:    models.CodeChunk{ChunkType: models.ChunkTypeFunction, Language: "go", Content: goFunc},
+			expected: goFunc,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunker := newApproximateTestChunker(t)
+			chunker.config.EmbedStrategy = tt.strategy
+			chunker.config.EmbedHeadLines = 4
+
+			chunks := chunker.prepareForEmbedding([]models.CodeChunk{tt.chunk})
+			if got := chunks[0].EmbeddingText(); got != tt.expected {
+				t.Errorf("Expected embedded text %q, got %q", tt.expected, got)
+			}
+			if chunks[0].Content != tt.chunk.Content {
+				t.Errorf("Expected the stored content to stay whole, got %q", chunks[0].Content)
+			}
+		})
+	}
+}
+
+func TestEmbedStrategy_ChunkFile(t *testing.T) {
+	chunker := newApproximateTestChunker(t)
+	if chunker.astChunker == nil {
+		t.Skip("AST chunker not available")
+	}
+	chunker.config.EmbedStrategy = EmbedStrategySignature
+
+	source := "/** Issues the invoice for an order. */\nexport function issue(order: Order): Invoice {\n  const invoice = new Invoice(order);\n  invoice.total = order.sum();\n  return invoice;\n}\n"
+	chunks, _, err := chunker.chunkContent("/repo", "/repo/billing.ts", []byte(source))
+	if err != nil {
+		t.Fatalf("chunkContent failed: %v", err)
+	}
+
+	found := false
+	for _, chunk := range chunks {
+		if chunk.FunctionName != "issue" {
+			continue
+		}
+		found = true
+		if got := chunk.EmbeddingText(); !strings.HasSuffix(got, "function issue(order: Order): Invoice {") {
+			t.Errorf("Expected the signature to be embedded, got %q", got)
+		}
+		if !strings.Contains(chunk.Content, "return invoice") {
+			t.Errorf("Expected the full method to be stored, got %q", chunk.Content)
+		}
+	}
+	if !found {
+		t.Fatalf("Expected a chunk for issue, got %+v", chunks)
+	}
+}
+
+func TestValidateEmbedStrategy(t *testing.T) {
+	for _, strategy := range []string{"", EmbedStrategyFull, EmbedStrategySignature, EmbedStrategyHead} {
+		if err := validateEmbedStrategy(strategy); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", strategy, err)
+		}
+	}
+	if err := validateEmbedStrategy("summary"); err == nil || !strings.Contains(err.Error(), `"summary"`) {
+		t.Errorf("Expected an error naming the unknown strategy, got %v", err)
+	}
+}
+
+func TestNameContext(t *testing.T) {
+	chunk := &models.CodeChunk{
+		RepoPath:     "/repo",
+		FilePath:     "/repo/src/auth/UserAuthenticator.java",
+		ClassName:    "UserAuthenticator",
+		FunctionName: "verify",
+		ChunkType:    models.ChunkTypeMethod,
+	}
+
+	tests := []struct {
+		name   string
+		path   bool
+		symbol bool
+		want   string
+	}{
+		{"disabled", false, false, ""},
+		{"path", true, false, "file: src/auth/UserAuthenticator.java\n"},
+		{"symbol", false, true, "symbol: UserAuthenticator.verify\n"},
+		{"both", true, true, "file: src/auth/UserAuthenticator.java\nsymbol: UserAuthenticator.verify\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.ChunkingConfig{EmbedFilePath: tt.path, EmbedSymbolName: tt.symbol}
+			if got := nameContext(chunk, cfg); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	class := &models.CodeChunk{FilePath: "/elsewhere/Util.java", ClassName: "Util", ChunkType: models.ChunkTypeClass}
+	cfg := &config.ChunkingConfig{EmbedFilePath: true, EmbedSymbolName: true}
+	if got, want := nameContext(class, cfg), "file: /elsewhere/Util.java\nsymbol: Util\n"; got != want {
+		t.Errorf("Expected %q for a class outside any repository, got %q", want, got)
+	}
+}
+
+func TestChunker_EmbedNames(t *testing.T) {
+	chunker := newApproximateTestChunker(t)
+	chunker.config.EmbedFilePath = true
+	chunker.config.EmbedSymbolName = true
+
+	source := "export function authenticate(user: User): boolean {\n  return checkPassword(user.password);\n}\n"
+	chunks, _, err := chunker.chunkContent("/repo", "/repo/src/UserAuthenticator.ts", []byte(source))
+	if err != nil {
+		t.Fatalf("chunkContent failed: %v", err)
+	}
+	if len(chunks) == 0 {
+		t.Fatal("Expected chunks")
+	}
+
+	found := false
+	for _, chunk := range chunks {
+		text := chunk.EmbeddingText()
+		if !strings.HasPrefix(text, "file: src/UserAuthenticator.ts\n") {
+			t.Errorf("Expected the file path to be embedded first, got %q", text)
+		}
+		if chunk.FunctionName == "authenticate" {
+			found = true
+			if !strings.Contains(text, "symbol: authenticate\n") {
+				t.Errorf("Expected the function name to be embedded, got %q", text)
+			}
+		}
+		if strings.Contains(chunk.Content, "file: ") || !strings.HasSuffix(text, chunk.Content) {
+			t.Errorf("Expected the stored content to be unchanged and embedded after the names, got %q", chunk.Content)
+		}
+	}
+	if chunker.astChunker != nil && !found {
+		t.Errorf("Expected a chunk for authenticate, got %+v", chunks)
+	}
+}
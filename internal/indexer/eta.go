@@ -0,0 +1,93 @@
+package indexer
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// etaProgress is the state of a running job an ETA is estimated from
+type etaProgress struct {
+	filesDone  int
+	filesTotal int
+	chunks     int           // Chunks found so far, or in total once chunked
+	embedded   int           // Chunks embedded so far
+	elapsed    time.Duration // Since the job started
+	chunking   time.Duration // How long chunking took, 0 while files are still being chunked
+}
+
+// estimateRemaining estimates how long a running job still needs, and whether it can tell
+func (idx *Indexer) estimateRemaining(job *models.IndexJob, now time.Time) (time.Duration, bool) {
+	filesDone, _ := job.GetProgress()
+	chunks, chunkedAt := job.GetChunkingProgress()
+	p := etaProgress{
+		filesDone:  filesDone,
+		filesTotal: job.GetFilesTotal(),
+		chunks:     chunks,
+		embedded:   job.GetChunksEmbedded(),
+		elapsed:    now.Sub(job.StartTime),
+	}
+	if !chunkedAt.IsZero() {
+		p.chunking = chunkedAt.Sub(job.StartTime)
+	}
+
+	embedTime := func(int) time.Duration { return 0 }
+	if idx.batcher != nil {
+		embedTime = idx.batcher.EstimateTime
+	}
+	return estimateETA(p, embedTime)
+}
+
+// estimateETA estimates the time left of a job from its progress
+// While files are being chunked, the rest of the chunking is projected from the observed rate
+// per file, and the chunks found so far are scaled to the whole run to estimate embedding with
+// embedTime. Once chunked, the rest of the embedding is projected from the observed rate per
+// chunk, or until the first batch is done, the embedding estimate is reduced by the time
+// already spent on it. Returns false when there is nothing to go on yet (no file processed) or
+// the job has outrun the estimate.
+func estimateETA(p etaProgress, embedTime func(chunks int) time.Duration) (time.Duration, bool) {
+	if p.chunking > 0 {
+		embedding := p.elapsed - p.chunking
+		if p.embedded > 0 && embedding > 0 {
+			chunksLeft := max(p.chunks-p.embedded, 0)
+			remaining := time.Duration(float64(embedding) / float64(p.embedded) * float64(chunksLeft))
+			return remaining, remaining > 0
+		}
+		remaining := embedTime(p.chunks) - embedding
+		return remaining, remaining > 0
+	}
+	if p.filesDone <= 0 || p.filesTotal <= 0 {
+		return 0, false
+	}
+
+	filesLeft := p.filesTotal - p.filesDone
+	if filesLeft < 0 {
+		filesLeft = 0
+	}
+	chunking := time.Duration(float64(p.elapsed) / float64(p.filesDone) * float64(filesLeft))
+	projectedChunks := int(math.Round(float64(p.chunks) * float64(p.filesTotal) / float64(p.filesDone)))
+	return chunking + embedTime(projectedChunks), true
+}
+
+// formatETA describes a remaining duration for people, e.g. "about 2 minutes"
+func formatETA(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "less than a minute"
+	case d < 90*time.Minute:
+		return plural(int(math.Round(d.Minutes())), "about %d minute")
+	default:
+		return plural(int(math.Round(d.Hours())), "about %d hour")
+	}
+}
+
+// plural formats n into format, adding an "s" unless n is 1
+func plural(n int, format string) string {
+	s := fmt.Sprintf(format, n)
+	if n != 1 {
+		s += "s"
+	}
+	return s
+}
@@ -0,0 +1,103 @@
+package indexer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/embeddings"
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// perChunk estimates embedding at a fixed time per chunk
+func perChunk(d time.Duration) func(int) time.Duration {
+	return func(chunks int) time.Duration { return time.Duration(chunks) * d }
+}
+
+func TestEstimateETA_WhileChunking(t *testing.T) {
+	// A quarter of the files chunked in 30s, finding 50 chunks
+	eta, ok := estimateETA(etaProgress{filesDone: 25, filesTotal: 100, chunks: 50, elapsed: 30 * time.Second}, perChunk(100*time.Millisecond))
+	if !ok {
+		t.Fatal("Expected an ETA once files have been processed")
+	}
+	// 90s more chunking, then 200 projected chunks at 100ms each
+	if want := 90*time.Second + 20*time.Second; eta != want {
+		t.Errorf("Expected ETA %v, got %v", want, eta)
+	}
+}
+
+func TestEstimateETA_WhileEmbedding(t *testing.T) {
+	// Chunked 400 chunks in 10s, embedding for 15s since
+	p := etaProgress{filesDone: 100, filesTotal: 100, chunks: 400, elapsed: 25 * time.Second, chunking: 10 * time.Second}
+	eta, ok := estimateETA(p, perChunk(100*time.Millisecond))
+	if !ok || eta != 25*time.Second {
+		t.Errorf("Expected 25s left of the 40s embedding estimate, got %v (ok=%v)", eta, ok)
+	}
+
+	// Past the estimate, there's no telling
+	p.elapsed = time.Minute
+	if _, ok := estimateETA(p, perChunk(100*time.Millisecond)); ok {
+		t.Error("Expected no ETA once the embedding estimate is exceeded")
+	}
+}
+
+func TestEstimateETA_ObservedEmbeddingRate(t *testing.T) {
+	// 100 of 400 chunks embedded in the 20s since chunking, far slower than the static estimate
+	p := etaProgress{filesDone: 100, filesTotal: 100, chunks: 400, embedded: 100, elapsed: 30 * time.Second, chunking: 10 * time.Second}
+	eta, ok := estimateETA(p, perChunk(time.Millisecond))
+	if !ok || eta != time.Minute {
+		t.Errorf("Expected 60s left at the observed 5 chunks/s, got %v (ok=%v)", eta, ok)
+	}
+
+	// Every chunk embedded: only storing is left
+	p.embedded = 400
+	if _, ok := estimateETA(p, perChunk(time.Millisecond)); ok {
+		t.Error("Expected no ETA once every chunk is embedded")
+	}
+}
+
+func TestEstimateETA_NoProgress(t *testing.T) {
+	if _, ok := estimateETA(etaProgress{filesTotal: 100, elapsed: time.Second}, perChunk(time.Millisecond)); ok {
+		t.Error("Expected no ETA before any file is processed")
+	}
+}
+
+func TestFormatETA(t *testing.T) {
+	tests := []struct {
+		eta  time.Duration
+		want string
+	}{
+		{20 * time.Second, "less than a minute"},
+		{70 * time.Second, "about 1 minute"},
+		{110 * time.Second, "about 2 minutes"},
+		{3 * time.Hour, "about 3 hours"},
+	}
+	for _, tt := range tests {
+		if got := formatETA(tt.eta); got != tt.want {
+			t.Errorf("formatETA(%v) = %q, want %q", tt.eta, got, tt.want)
+		}
+	}
+}
+
+func TestGetRepoIndex_RunningETA(t *testing.T) {
+	idx := &Indexer{
+		batcher: embeddings.NewBatcher(nil, 10, 1),
+		jobs:    make(map[string]*models.IndexJob),
+	}
+	job := &models.IndexJob{ID: "job", RepoPath: "/repo", Status: models.IndexStatusRunning, StartTime: time.Now().Add(-time.Minute)}
+	job.SetFilesTotal(40)
+	job.UpdateProgress(10, 0.25)
+	job.RecordChunks(100)
+	idx.jobs[job.ID] = job
+
+	repoIndex, err := idx.GetRepoIndex("/repo")
+	if err != nil {
+		t.Fatalf("GetRepoIndex failed: %v", err)
+	}
+	// About 3 more minutes of chunking at the observed rate, plus a little embedding
+	if repoIndex.ETASeconds < 180 || repoIndex.ETASeconds > 200 {
+		t.Errorf("Expected an ETA of about 3 minutes, got %ds", repoIndex.ETASeconds)
+	}
+	if repoIndex.ETA != "about 3 minutes" {
+		t.Errorf("Expected a readable ETA, got %q", repoIndex.ETA)
+	}
+}
@@ -0,0 +1,168 @@
+package indexer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/cache"
+)
+
+// FileExplanation reports why a file was or wasn't indexed
+type FileExplanation struct {
+	FilePath     string `json:"file_path"`
+	Language     string `json:"language,omitempty"`   // Empty if the file type is unsupported
+	IgnoredBy    string `json:"ignored_by,omitempty"` // Ignore pattern matching the file or a parent directory
+	SizeBytes    int64  `json:"size_bytes"`
+	MaxSizeBytes int64  `json:"max_size_bytes"`
+	Lines        int    `json:"lines,omitempty"` // Only counted when a line filter is configured
+	// SkipReason is the first scan check the file fails (a SkipReason* constant), empty if it is indexable
+	SkipReason string `json:"skip_reason,omitempty"`
+	InCache    bool   `json:"in_cache"`
+	ChunkCount int    `json:"chunk_count"`
+	Modified   bool   `json:"modified"` // Content changed since the file was indexed
+}
+
+// hiddenDirReason is reported as IgnoredBy for files under a hidden directory
+const hiddenDirReason = "hidden directory"
+
+// Explain runs the scan checks for a single file and reports the outcome of each
+// filePath may be absolute or relative to repoPath.
+func (s *Scanner) Explain(repoPath, filePath string) (*FileExplanation, error) {
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(repoPath, filePath)
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("path is a directory: %s", filePath)
+	}
+
+	relPath, err := filepath.Rel(repoPath, filePath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("file %s is not inside repository %s", filePath, repoPath)
+	}
+
+	explanation := &FileExplanation{
+		FilePath:     filePath,
+		SizeBytes:    info.Size(),
+		MaxSizeBytes: s.maxFileSizeBytes,
+	}
+	explanation.Language, _ = s.languageOf(filePath)
+	explanation.IgnoredBy = s.ignoredBy(relPath)
+
+	// Same order of checks as Scan
+	switch {
+	case explanation.IgnoredBy != "":
+		explanation.SkipReason = SkipReasonIgnored
+	case s.tooDeep(filepath.Dir(relPath)):
+		explanation.SkipReason = SkipReasonTooDeep
+	case explanation.Language == "":
+		explanation.SkipReason = SkipReasonUnsupported
+	case info.Size() > s.maxFileSizeBytes:
+		explanation.SkipReason = SkipReasonTooLarge
+	case s.config.MinLines > 0 || s.config.MaxLines > 0:
+		lines, err := countLines(filePath)
+		if err != nil {
+			explanation.SkipReason = SkipReasonReadError
+			break
+		}
+		explanation.Lines = lines
+		if s.config.MinLines > 0 && lines < s.config.MinLines {
+			explanation.SkipReason = SkipReasonTooFewLines
+		} else if s.config.MaxLines > 0 && lines > s.config.MaxLines {
+			explanation.SkipReason = SkipReasonTooManyLines
+		}
+	}
+	if explanation.SkipReason == "" && s.skipsGenerated() {
+		generated, err := fileIsGenerated(filePath, s.config.GeneratedMarkers)
+		if err != nil {
+			explanation.SkipReason = SkipReasonReadError
+		} else if generated {
+			explanation.SkipReason = SkipReasonGenerated
+		}
+	}
+
+	return explanation, nil
+}
+
+// ignoredBy returns what excludes relPath from a scan: a hidden or ignored parent directory,
+// or an ignore pattern matching the file itself. Returns "" if nothing does.
+func (s *Scanner) ignoredBy(relPath string) string {
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for i, name := range parts[:len(parts)-1] {
+		if strings.HasPrefix(name, ".") {
+			return hiddenDirReason
+		}
+		if pattern, ok := s.ignoreMatcher.MatchingPattern(strings.Join(parts[:i+1], "/")); ok {
+			return pattern
+		}
+	}
+
+	pattern, _ := s.ignoreMatcher.MatchingPattern(relPath)
+	return pattern
+}
+
+// ExplainFile reports why a file was or wasn't indexed: the scan checks it passes or fails,
+// and whether it is in the hash cache with how many chunks it produced
+func (idx *Indexer) ExplainFile(repoPath, filePath string) (*FileExplanation, error) {
+	settings, err := idx.settingsFor(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation, err := settings.scanner.Explain(repoPath, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// A private copy, so a job using the shared manager keeps its loaded cache
+	hashCache, err := idx.hashManager.LoadCache(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hash cache: %w", err)
+	}
+	if entry, ok := hashCache.Hashes[explanation.FilePath]; ok {
+		explanation.InCache = true
+		explanation.ChunkCount = entry.ChunkCount
+		if content, err := os.ReadFile(explanation.FilePath); err == nil {
+			explanation.Modified = cache.HashContent(content) != entry.Hash
+		}
+	}
+
+	return explanation, nil
+}
+
+// Summary describes the outcome in one sentence
+func (e *FileExplanation) Summary() string {
+	switch e.SkipReason {
+	case SkipReasonIgnored:
+		if e.IgnoredBy == hiddenDirReason {
+			return "Not indexed: the file is inside a hidden directory"
+		}
+		return fmt.Sprintf("Not indexed: matched ignore pattern %q", e.IgnoredBy)
+	case SkipReasonUnsupported:
+		return fmt.Sprintf("Not indexed: unsupported file type %q", filepath.Ext(e.FilePath))
+	case SkipReasonTooLarge:
+		return fmt.Sprintf("Not indexed: %d bytes exceeds the %d byte size limit", e.SizeBytes, e.MaxSizeBytes)
+	case SkipReasonTooFewLines:
+		return fmt.Sprintf("Not indexed: %d lines is below min_lines", e.Lines)
+	case SkipReasonTooManyLines:
+		return fmt.Sprintf("Not indexed: %d lines exceeds max_lines", e.Lines)
+	case SkipReasonReadError:
+		return "Not indexed: the file could not be read"
+	}
+
+	switch {
+	case !e.InCache:
+		return "Indexable but not in the index: reindex the repository; if it is still missing, chunking or embedding failed for it (see the server log)"
+	case e.ChunkCount == 0:
+		return "Indexed, but produced no chunks"
+	case e.Modified:
+		return fmt.Sprintf("Indexed (%d chunks), but changed since; reindex to update it", e.ChunkCount)
+	default:
+		return fmt.Sprintf("Indexed (%d chunks)", e.ChunkCount)
+	}
+}
@@ -0,0 +1,152 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+func TestExplain_SkipReasons(t *testing.T) {
+	repoDir := t.TempDir()
+	files := map[string]string{
+		"src/Main.java":        "public class Main {\n}\n",
+		"build/Out.java":       "public class Out {}\n",
+		"src/Model.gen.java":   "public class Model {}\n",
+		".hidden/Secret.java":  "public class Secret {}\n",
+		"README.md":            "# readme\n",
+		"src/Big.java":         "public class Big {}\n" + strings.Repeat("// padding\n", 200),
+		"src/Tiny.java":        "class T {}\n",
+		"src/nested/Deep.java": "public class Deep {\n}\n",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(repoDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	cfg := &config.IndexingConfig{MaxFileSizeMB: 1, MinLines: 2}
+	scanner := NewScanner(cfg, []string{"build/**", "*.gen.java"})
+	// A size limit small enough for Big.java but not the others
+	scanner.maxFileSizeBytes = 1024
+
+	tests := []struct {
+		path       string
+		skipReason string
+		ignoredBy  string
+		language   string
+	}{
+		{"src/Main.java", "", "", "java"},
+		{"src/nested/Deep.java", "", "", "java"},
+		{"build/Out.java", SkipReasonIgnored, "build/**", "java"},
+		{"src/Model.gen.java", SkipReasonIgnored, "*.gen.java", "java"},
+		{".hidden/Secret.java", SkipReasonIgnored, hiddenDirReason, "java"},
+		{"README.md", SkipReasonUnsupported, "", ""},
+		{"src/Big.java", SkipReasonTooLarge, "", "java"},
+		{"src/Tiny.java", SkipReasonTooFewLines, "", "java"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			explanation, err := scanner.Explain(repoDir, tt.path)
+			if err != nil {
+				t.Fatalf("Explain failed: %v", err)
+			}
+			if explanation.SkipReason != tt.skipReason {
+				t.Errorf("Expected skip reason %q, got %q", tt.skipReason, explanation.SkipReason)
+			}
+			if explanation.IgnoredBy != tt.ignoredBy {
+				t.Errorf("Expected ignored by %q, got %q", tt.ignoredBy, explanation.IgnoredBy)
+			}
+			if explanation.Language != tt.language {
+				t.Errorf("Expected language %q, got %q", tt.language, explanation.Language)
+			}
+			if explanation.FilePath != filepath.Join(repoDir, tt.path) {
+				t.Errorf("Expected absolute path, got %s", explanation.FilePath)
+			}
+		})
+	}
+
+	// Explain agrees with what a scan picks up
+	result, err := scanner.Scan(repoDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Files) != 2 {
+		t.Errorf("Expected the scan to find the 2 indexable files, got %v", result.Files)
+	}
+
+	if _, err := scanner.Explain(repoDir, "src/Missing.java"); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	if _, err := scanner.Explain(repoDir, filepath.Join(t.TempDir(), "Elsewhere.java")); err == nil {
+		t.Error("Expected an error for a file outside the repository")
+	}
+}
+
+func TestExplainFile_CacheAndChunks(t *testing.T) {
+	idx, _, _ := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+
+	path := filepath.Join(repoDir, "Service.java")
+	content := "public class Service {\n    public void run() {\n        System.out.println(\"run\");\n    }\n}\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	explanation, err := idx.ExplainFile(repoDir, "Service.java")
+	if err != nil {
+		t.Fatalf("ExplainFile failed: %v", err)
+	}
+	if explanation.InCache || explanation.SkipReason != "" {
+		t.Errorf("Expected an indexable file not yet in the cache, got %+v", explanation)
+	}
+	if !strings.Contains(explanation.Summary(), "not in the index") {
+		t.Errorf("Unexpected summary: %s", explanation.Summary())
+	}
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+
+	explanation, err = idx.ExplainFile(repoDir, path)
+	if err != nil {
+		t.Fatalf("ExplainFile failed: %v", err)
+	}
+	if !explanation.InCache || explanation.ChunkCount == 0 || explanation.Modified {
+		t.Errorf("Expected an indexed, unmodified file with chunks, got %+v", explanation)
+	}
+
+	if err := os.WriteFile(path, []byte(content+"// changed\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+	explanation, err = idx.ExplainFile(repoDir, path)
+	if err != nil {
+		t.Fatalf("ExplainFile failed: %v", err)
+	}
+	if !explanation.Modified {
+		t.Error("Expected a modified file to be reported as changed since indexing")
+	}
+
+	// Explaining leaves the cache loaded by a job indexing another repository alone
+	otherFile := filepath.Join(t.TempDir(), "Other.java")
+	if err := idx.hashManager.Load(filepath.Dir(otherFile)); err != nil {
+		t.Fatalf("Failed to load cache: %v", err)
+	}
+	if err := idx.hashManager.UpdateHash(otherFile, "hash", 1); err != nil {
+		t.Fatalf("Failed to update hash: %v", err)
+	}
+	if _, err := idx.ExplainFile(repoDir, path); err != nil {
+		t.Fatalf("ExplainFile failed: %v", err)
+	}
+	if _, ok := idx.hashManager.Lookup(otherFile); !ok {
+		t.Error("Expected the loaded cache of the repository being indexed to be left in place")
+	}
+}
@@ -0,0 +1,89 @@
+package indexer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// generatedHeaderBytes is how much of the start of a file is searched for generated-code markers,
+// enough to get past a license header
+const generatedHeaderBytes = 4096
+
+// commentLeaders start the comment lines recognized in a file's leading comment block
+var commentLeaders = []string{"//", "/*", "*", "#", "--", "<!--", ";"}
+
+// isGenerated reports whether content declares itself generated: a comment line of its leading
+// comment block starts with one of markers (e.g. "// Code generated ... DO NOT EDIT.",
+// " * @generated")
+// Only the comments before the first line of code count, and only at the start of a comment,
+// so files that merely mention a marker (in prose or a string) aren't flagged.
+func isGenerated(content []byte, markers []string) bool {
+	header := content[:min(len(content), generatedHeaderBytes)]
+	lines := bufio.NewScanner(bytes.NewReader(header))
+	inBlock := false
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		text, isComment := commentText(line, inBlock)
+		switch {
+		case line == "" || line == "<?php":
+			continue
+		case !isComment:
+			return false // First line of code: the header is over
+		}
+		if strings.HasPrefix(line, "/*") || strings.HasPrefix(line, "<!--") {
+			inBlock = true
+		}
+		if strings.Contains(line, "*/") || strings.Contains(line, "-->") {
+			inBlock = false
+		}
+
+		for _, marker := range markers {
+			if marker != "" && strings.HasPrefix(text, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// commentText returns the text of a comment line without its comment leader, and whether
+// line is a comment at all (inBlock: inside a block comment, where any line is one)
+func commentText(line string, inBlock bool) (string, bool) {
+	for _, leader := range commentLeaders {
+		if rest, ok := strings.CutPrefix(line, leader); ok {
+			return strings.TrimSpace(strings.TrimLeft(rest, "/*#-;! ")), true
+		}
+	}
+	return line, inBlock
+}
+
+// fileIsGenerated reads the header of the file at path and checks it for markers
+func fileIsGenerated(path string, markers []string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	header := make([]byte, generatedHeaderBytes)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return isGenerated(header[:n], markers), nil
+}
+
+// markGenerated flags the chunks of a generated file, so searches rank them down
+func markGenerated(chunks []models.CodeChunk) {
+	for i := range chunks {
+		if chunks[i].Metadata == nil {
+			chunks[i].Metadata = make(map[string]interface{})
+		}
+		chunks[i].Metadata[models.MetadataGenerated] = true
+	}
+}
@@ -0,0 +1,116 @@
+package indexer
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+func TestIsGenerated(t *testing.T) {
+	markers := config.DefaultConfig().Indexing.GeneratedMarkers
+
+	tests := []struct {
+		name    string
+		content string
+		markers []string
+		want    bool
+	}{
+		{"go header", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage pb\n", markers, true},
+		{"@generated", "/**\n * @generated SignedSource<<abc>>\n */\nexport const x = 1;\n", markers, true},
+		{"after license", "// Copyright 2024\n// Licensed under MIT\n\n// Code generated by mockgen.\npackage mocks\n", markers, true},
+		{"handwritten", "package auth\n\nfunc Login() {}\n", markers, false},
+		{"marker past the header", strings.Repeat("// padding\n", 500) + "// Code generated\n", markers, false},
+		{"no markers", "// Code generated by protoc-gen-go. DO NOT EDIT.\n", nil, false},
+		{"empty marker ignored", "package auth\n", []string{""}, false},
+		{"c# header", "// <auto-generated>\n//     This code was generated by a tool.\n// </auto-generated>\nnamespace Api {}\n", markers, true},
+		{"inside block comment", "/*\n Copyright 2024\n\n @generated by codegen\n*/\nexport {}\n", markers, true},
+		{"shebang and hash comments", "#!/usr/bin/env python\n# DO NOT EDIT: generated by make\nx = 1\n", markers, true},
+		{"marker quoted in a header comment", "// Package indexer flags files with \"Code generated\" or \"@generated\" headers.\npackage indexer\n", markers, false},
+		{"marker after the first line of code", "package indexer\n\n// Code generated markers are detected here\nconst marker = \"@generated\"\n", markers, false},
+		{"marker in a string", "const header = \"// Code generated. DO NOT EDIT.\"\n", markers, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isGenerated([]byte(tt.content), tt.markers); got != tt.want {
+				t.Errorf("Expected generated=%v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// This package's own source quotes the markers without being generated
+	if generated, err := fileIsGenerated("generated.go", markers); err != nil || generated {
+		t.Errorf("Expected generated.go to be treated as hand-written, got generated=%v err=%v", generated, err)
+	}
+}
+
+func TestScan_SkipGenerated(t *testing.T) {
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{
+		"api/service.pb.go": "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n",
+		"api/service.go":    "package api\n\nfunc Serve() {}\n",
+	})
+
+	cfg := config.DefaultConfig().Indexing
+	cfg.SkipGenerated = true
+	result, err := NewScanner(&cfg, nil).Scan(repoDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	if len(result.Files) != 1 || filepath.Base(result.Files[0]) != "service.go" {
+		t.Errorf("Expected only the handwritten file, got %v", result.Files)
+	}
+	if result.SkipReasons[SkipReasonGenerated] != 1 {
+		t.Errorf("Expected 1 file skipped as generated, got %v", result.SkipReasons)
+	}
+
+	explanation, err := NewScanner(&cfg, nil).Explain(repoDir, filepath.Join(repoDir, "api/service.pb.go"))
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if explanation.SkipReason != SkipReasonGenerated {
+		t.Errorf("Expected the generated file to be explained as skipped, got %q", explanation.SkipReason)
+	}
+
+	// Without skipping, generated files are indexed (and ranked down when searching)
+	cfg.SkipGenerated = false
+	result, err = NewScanner(&cfg, nil).Scan(repoDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Files) != 2 {
+		t.Errorf("Expected both files, got %v", result.Files)
+	}
+}
+
+func TestIndex_MarksGeneratedChunks(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{
+		"src/client.ts": "// Code generated by openapi-generator. DO NOT EDIT.\nexport function getUser() {\n  return fetch('/user');\n}\n",
+		"src/app.ts":    "export function start() {\n  return getUser();\n}\n",
+	})
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+
+	generatedPath := filepath.Join(repoDir, "src/client.ts")
+	marked := 0
+	for _, chunk := range store.chunks {
+		generated, _ := chunk.Metadata[models.MetadataGenerated].(bool)
+		if generated != (chunk.FilePath == generatedPath) {
+			t.Errorf("Expected %s to be marked generated=%v", chunk.FilePath, !generated)
+		}
+		if generated {
+			marked++
+		}
+	}
+	if marked == 0 || marked == len(store.chunks) {
+		t.Errorf("Expected only the generated file's chunks to be marked, got %d of %d", marked, len(store.chunks))
+	}
+}
@@ -0,0 +1,176 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// ErrNotGitRepository is returned when a changed-files index is requested outside a git work tree
+var ErrNotGitRepository = errors.New("not a git repository")
+
+// runGit runs a git command in repoPath and returns its standard output
+func runGit(repoPath string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", repoPath}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// changedFilesSince returns the absolute paths of files under repoPath that differ between
+// gitRef and the working tree, including files deleted since gitRef
+// Untracked files are not included; renames are reported as a deletion plus an addition.
+func changedFilesSince(repoPath, gitRef string) ([]string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git is not installed: %w", err)
+	}
+	if _, err := runGit(repoPath, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotGitRepository, repoPath)
+	}
+	if gitRef == "" || strings.HasPrefix(gitRef, "-") {
+		return nil, fmt.Errorf("invalid git ref: %q", gitRef)
+	}
+	if _, err := runGit(repoPath, "rev-parse", "--verify", "--quiet", gitRef+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown git ref %q", gitRef)
+	}
+
+	// --relative limits the diff to repoPath and reports paths relative to it
+	out, err := runGit(repoPath, "diff", "--name-only", "--no-renames", "--relative", "-z", gitRef, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, name := range strings.Split(out, "\x00") {
+		if name != "" {
+			files = append(files, filepath.Join(repoPath, filepath.FromSlash(name)))
+		}
+	}
+	return files, nil
+}
+
+// IndexChangedSince reindexes only the files that changed between gitRef and the working tree
+// Changed files that pass the scan checks are reindexed, and chunks of deleted files are removed.
+// Runs in the background when configured, like Index.
+func (idx *Indexer) IndexChangedSince(repoPath, gitRef string) (*models.IndexJob, error) {
+	settings, err := idx.settingsFor(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := changedFilesSince(repoPath, gitRef)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := idx.startJob(repoPath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Background = settings.config.Indexing.Background
+	if job.Background {
+		go idx.doIndexChanged(job, settings, gitRef, changed)
+	} else {
+		idx.doIndexChanged(job, settings, gitRef, changed)
+	}
+
+	return job, nil
+}
+
+// doIndexChanged reindexes the given changed files and removes the deleted ones
+func (idx *Indexer) doIndexChanged(job *models.IndexJob, settings *repoSettings, gitRef string, changed []string) {
+	defer idx.running.Done()
+	defer func() {
+		job.Finish()
+		idx.indexChanged(job)
+	}()
+
+	slog.Info("Starting indexing of changed files", "job", job.ID, "repo", job.RepoPath, "since", gitRef, "changed", len(changed))
+
+	incremental := settings.config.Indexing.Incremental
+	if incremental {
+		if err := idx.hashManager.Load(job.RepoPath); err != nil {
+			slog.Warn("Failed to load hash cache", "job", job.ID, "error", err)
+		}
+		// Reindexing only the changed files would mix vectors embedded with two prefixes
+		if idx.hashManager.DocumentPrefixChanged(idx.documentPrefix) {
+			job.Fail("The document prefix changed since the last index, so every file must be embedded again. Run index_codebase instead.")
+			return
+		}
+	}
+
+	// Sort changed paths into files to reindex and files to remove, applying the scan checks
+	var files, removed []string
+	skipReasons := make(map[string]int)
+	for _, path := range changed {
+		explanation, err := settings.scanner.Explain(job.RepoPath, path)
+		if errors.Is(err, fs.ErrNotExist) {
+			removed = append(removed, path)
+			continue
+		}
+		if err != nil {
+			slog.Warn("Skipping changed file", "job", job.ID, "file", path, "error", err)
+			continue
+		}
+		if explanation.SkipReason != "" {
+			// The file may have been indexed before it started failing the checks (e.g. it grew too large)
+			skipReasons[explanation.SkipReason]++
+			removed = append(removed, path)
+			continue
+		}
+		files = append(files, path)
+	}
+
+	job.SetFilesTotal(len(files))
+	slog.Info("Found changed files to process", "job", job.ID, "files", len(files), "removed", len(removed))
+	if len(skipReasons) > 0 {
+		slog.Info("Skipped changed files", "job", job.ID, "reasons", skipReasons)
+	}
+	if exceedsLimit(job, "files", len(files), settings.config.Indexing.MaxFiles, "max_files") {
+		return
+	}
+
+	if idx.stopAtCheckpoint(job, "removing files") {
+		return
+	}
+
+	idx.removeFiles(context.Background(), job, removed)
+
+	allChunks := idx.processFilesInParallel(job, settings, files, !incremental, nil)
+	job.SetChunksTotal(len(allChunks))
+	if exceedsLimit(job, "chunks", len(allChunks), settings.config.Indexing.MaxChunks, "max_chunks") {
+		return
+	}
+
+	if idx.stopAtCheckpoint(job, "embedding") {
+		return
+	}
+
+	if !idx.embedAndStore(job, allChunks) {
+		return
+	}
+	idx.hashManager.SetDocumentPrefix(idx.documentPrefix)
+	if incremental && !idx.saveCache(job) {
+		return
+	}
+
+	job.Complete()
+	slog.Info("Indexing of changed files completed", "job", job.ID, "duration", time.Since(job.StartTime))
+}
@@ -0,0 +1,216 @@
+package indexer
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// newTestGitRepo creates a git repository with the given files committed, skipping without git
+func newTestGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, files)
+	gitInRepo(t, repoDir, "init", "-q")
+	gitInRepo(t, repoDir, "add", "-A")
+	gitInRepo(t, repoDir, "commit", "-q", "-m", "initial")
+	return repoDir
+}
+
+// gitInRepo runs a git command in repoDir with a fixed identity
+func gitInRepo(t *testing.T, repoDir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+// writeTestFiles writes files relative to dir, creating directories as needed
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+}
+
+func TestChangedFilesSince(t *testing.T) {
+	repoDir := newTestGitRepo(t, map[string]string{
+		"src/Kept.java":    "public class Kept {}\n",
+		"src/Changed.java": "public class Changed {}\n",
+		"src/Deleted.java": "public class Deleted {}\n",
+	})
+
+	writeTestFiles(t, repoDir, map[string]string{
+		"src/Changed.java": "public class Changed { int x; }\n",
+		"src/Added.java":   "public class Added {}\n",
+		"Untracked.java":   "public class Untracked {}\n",
+	})
+	if err := os.Remove(filepath.Join(repoDir, "src/Deleted.java")); err != nil {
+		t.Fatalf("Failed to delete file: %v", err)
+	}
+	gitInRepo(t, repoDir, "add", "src/Added.java")
+
+	changed, err := changedFilesSince(repoDir, "HEAD")
+	if err != nil {
+		t.Fatalf("changedFilesSince failed: %v", err)
+	}
+	sort.Strings(changed)
+
+	expected := []string{
+		filepath.Join(repoDir, "src/Added.java"),
+		filepath.Join(repoDir, "src/Changed.java"),
+		filepath.Join(repoDir, "src/Deleted.java"),
+	}
+	if len(changed) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, changed)
+	}
+	for i := range expected {
+		if changed[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], changed[i])
+		}
+	}
+
+	// Paths are limited to, and relative to, a subdirectory of the work tree
+	changed, err = changedFilesSince(filepath.Join(repoDir, "src"), "HEAD")
+	if err != nil {
+		t.Fatalf("changedFilesSince failed for a subdirectory: %v", err)
+	}
+	if len(changed) != 3 || filepath.Dir(changed[0]) != filepath.Join(repoDir, "src") {
+		t.Errorf("Expected the 3 changes under src, got %v", changed)
+	}
+
+	if _, err := changedFilesSince(repoDir, "no-such-ref"); err == nil {
+		t.Error("Expected an error for an unknown ref")
+	}
+	if _, err := changedFilesSince(repoDir, "--output=/tmp/x"); err == nil {
+		t.Error("Expected an error for a ref that looks like an option")
+	}
+}
+
+func TestChangedFilesSince_NotGitRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	_, err := changedFilesSince(t.TempDir(), "HEAD")
+	if !errors.Is(err, ErrNotGitRepository) {
+		t.Errorf("Expected ErrNotGitRepository, got %v", err)
+	}
+}
+
+func TestIndexChangedSince(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	repoDir := newTestGitRepo(t, map[string]string{
+		"Kept.java":    "public class Kept {\n    public void keep() {\n        System.out.println(\"keep\");\n    }\n}\n",
+		"Changed.java": "public class Changed {\n    public void before() {\n        System.out.println(\"before\");\n    }\n}\n",
+		"Deleted.java": "public class Deleted {\n    public void gone() {\n        System.out.println(\"gone\");\n    }\n}\n",
+	})
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Initial indexing failed: %s", job.Error)
+	}
+	gitInRepo(t, repoDir, "tag", "indexed")
+	keptChunks := store.countByFile(filepath.Join(repoDir, "Kept.java"))
+
+	writeTestFiles(t, repoDir, map[string]string{
+		"Changed.java": "public class Changed {\n    public void after() {\n        System.out.println(\"after\");\n    }\n}\n",
+		"Added.java":   "public class Added {\n    public void added() {\n        System.out.println(\"added\");\n    }\n}\n",
+		"notes.txt":    "not source code\n",
+	})
+	if err := os.Remove(filepath.Join(repoDir, "Deleted.java")); err != nil {
+		t.Fatalf("Failed to delete file: %v", err)
+	}
+	gitInRepo(t, repoDir, "add", "-A")
+	gitInRepo(t, repoDir, "commit", "-q", "-m", "change")
+
+	job, err := idx.IndexChangedSince(repoDir, "indexed")
+	if err != nil {
+		t.Fatalf("IndexChangedSince failed: %v", err)
+	}
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing changed files failed: %s", job.Error)
+	}
+
+	// Only the changed and added source files are processed; notes.txt is unsupported
+	if job.GetFilesTotal() != 2 {
+		t.Errorf("Expected 2 files to process, got %d", job.GetFilesTotal())
+	}
+	if n := store.countByFile(filepath.Join(repoDir, "Deleted.java")); n != 0 {
+		t.Errorf("Expected deleted file's chunks to be removed, got %d", n)
+	}
+	if n := store.countByFile(filepath.Join(repoDir, "Added.java")); n == 0 {
+		t.Error("Expected chunks for the added file")
+	}
+	if n := store.countByFile(filepath.Join(repoDir, "Kept.java")); n != keptChunks {
+		t.Errorf("Expected unchanged file's %d chunks to be kept, got %d", keptChunks, n)
+	}
+	for _, chunk := range store.chunks {
+		if chunk.FilePath == filepath.Join(repoDir, "Changed.java") && chunk.FunctionName == "before" {
+			t.Error("Expected the changed file's old chunks to be replaced")
+		}
+	}
+
+	// The cache reflects the changes, so a following incremental run has nothing to do
+	if job, _ := idx.Index(repoDir, false, false); job.GetFilesTotal() != 3 || job.ChunksTotal != 0 {
+		t.Errorf("Expected no files to reindex after the changed-files run, got %d chunks", job.ChunksTotal)
+	}
+
+	if _, err := idx.IndexChangedSince(t.TempDir(), "HEAD"); !errors.Is(err, ErrNotGitRepository) {
+		t.Errorf("Expected ErrNotGitRepository outside a git repository, got %v", err)
+	}
+}
+
+func TestIndexChangedSince_RemovesNewlySkippedFiles(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	repoDir := newTestGitRepo(t, map[string]string{
+		"Kept.java":    "public class Kept {\n    public void keep() {}\n}\n",
+		"Ignored.java": "public class Ignored {\n    public void before() {}\n}\n",
+	})
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Initial indexing failed: %s", job.Error)
+	}
+	gitInRepo(t, repoDir, "tag", "indexed")
+	ignoredPath := filepath.Join(repoDir, "Ignored.java")
+	if store.countByFile(ignoredPath) == 0 {
+		t.Fatal("Expected chunks for Ignored.java after initial indexing")
+	}
+
+	// The file changes and is now ignored
+	writeTestFiles(t, repoDir, map[string]string{
+		"Ignored.java": "public class Ignored {\n    public void after() {}\n}\n",
+	})
+	gitInRepo(t, repoDir, "commit", "-q", "-am", "change")
+	idx.config.Ignore.Patterns = append(idx.config.Ignore.Patterns, "Ignored.java")
+	idx.scanner = newConfiguredScanner(idx.config)
+
+	job, err := idx.IndexChangedSince(repoDir, "indexed")
+	if err != nil || job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing changed files failed: %v %+v", err, job)
+	}
+	if n := store.countByFile(ignoredPath); n != 0 {
+		t.Errorf("Expected the newly ignored file's chunks to be removed, got %d", n)
+	}
+	if _, ok := idx.hashManager.Lookup(ignoredPath); ok {
+		t.Error("Expected the newly ignored file to be dropped from the cache")
+	}
+	if store.countByFile(filepath.Join(repoDir, "Kept.java")) == 0 {
+		t.Error("Expected the unchanged file's chunks to be kept")
+	}
+}
@@ -0,0 +1,112 @@
+package indexer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// maxImports caps the imports attached to a chunk, keeping the import context compact
+const maxImports = 30
+
+// importContextPrefix introduces the import context prepended to the text embedded for a chunk
+const importContextPrefix = "imports: "
+
+// jsImportPatterns match ES module imports, re-exports and CommonJS requires
+var jsImportPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^import\s+(?:type\s+)?(?:[^'"]*?\s+from\s+)?['"]([^'"]+)['"]`),
+	regexp.MustCompile(`^\}\s*from\s+['"]([^'"]+)['"]`), // Last line of a multi-line import
+	regexp.MustCompile(`^export\s+(?:type\s+)?(?:\*|\{[^}]*\})(?:\s+as\s+\w+)?\s+from\s+['"]([^'"]+)['"]`),
+	regexp.MustCompile(`^(?:(?:export\s+)?(?:const|let|var)\s+[^=]+=\s*)?require\(\s*['"]([^'"]+)['"]\s*\)`),
+}
+
+// importPatterns maps language names to patterns matching an import statement on one line
+// The first group captures the imported module, package or class.
+var importPatterns = map[string][]*regexp.Regexp{
+	"java":       {regexp.MustCompile(`^import\s+(?:static\s+)?([\w.]+(?:\.\*)?)\s*;`)},
+	"kotlin":     {regexp.MustCompile(`^import\s+([\w.]+(?:\.\*)?)`)},
+	"javascript": jsImportPatterns,
+	"typescript": jsImportPatterns,
+	"vue":        jsImportPatterns,
+	"svelte":     jsImportPatterns,
+	"go":         {regexp.MustCompile(`^import\s+(?:[\w.]+\s+)?"([^"]+)"`)},
+	"ruby":       {regexp.MustCompile(`^require(?:_relative)?\s*\(?\s*['"]([^'"]+)['"]`)},
+	"php": {
+		regexp.MustCompile(`^use\s+(?:function\s+|const\s+)?\\?([\w\\]+)`),
+		regexp.MustCompile(`^(?:require|include)(?:_once)?\s*\(?\s*['"]([^'"]+)['"]`),
+	},
+	"swift": {regexp.MustCompile(`^(?:@\w+\s+)*import\s+(?:(?:typealias|struct|class|enum|protocol|let|var|func)\s+)?([\w.]+)`)},
+}
+
+// goImportSpec matches one import of a Go import ( ... ) block
+var goImportSpec = regexp.MustCompile(`^(?:[\w.]+\s+)?"([^"]+)"`)
+
+// extractImports returns the modules imported by a file, in order and without duplicates
+// Languages without known import syntax have none.
+func extractImports(content, language string) []string {
+	patterns := importPatterns[language]
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	var imports []string
+	seen := make(map[string]bool)
+	add := func(module string) {
+		if module != "" && !seen[module] && len(imports) < maxImports {
+			seen[module] = true
+			imports = append(imports, module)
+		}
+	}
+
+	inGoBlock := false
+	for _, line := range splitLines(content) {
+		trimmed := strings.TrimSpace(line)
+		if language == "go" {
+			switch {
+			case strings.HasPrefix(trimmed, "import ("):
+				inGoBlock = true
+				continue
+			case inGoBlock && trimmed == ")":
+				inGoBlock = false
+				continue
+			case inGoBlock:
+				if m := goImportSpec.FindStringSubmatch(trimmed); m != nil {
+					add(m[1])
+				}
+				continue
+			}
+		}
+		for _, pattern := range patterns {
+			if m := pattern.FindStringSubmatch(trimmed); m != nil {
+				add(m[1])
+				break
+			}
+		}
+	}
+	return imports
+}
+
+// attachImports records the file's imports in the Metadata of its function and method chunks,
+// when chunking.include_imports is enabled
+func (c *Chunker) attachImports(chunks []models.CodeChunk, language, content string) []models.CodeChunk {
+	if !c.config.IncludeImports || len(chunks) == 0 {
+		return chunks
+	}
+	imports := extractImports(content, language)
+	if len(imports) == 0 {
+		return chunks
+	}
+
+	joined := strings.Join(imports, ", ")
+	for i := range chunks {
+		if chunks[i].ChunkType != models.ChunkTypeFunction && chunks[i].ChunkType != models.ChunkTypeMethod {
+			continue
+		}
+		if chunks[i].Metadata == nil {
+			chunks[i].Metadata = make(map[string]interface{})
+		}
+		chunks[i].Metadata[models.MetadataImports] = joined
+	}
+	return chunks
+}
@@ -0,0 +1,160 @@
+package indexer
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+func TestExtractImports(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "java",
+			language: "java",
+			content:  "package com.example;\n\nimport java.util.List;\nimport static org.junit.Assert.*;\nimport java.util.List;\n\npublic class A {}\n",
+			expected: []string{"java.util.List", "org.junit.Assert.*"},
+		},
+		{
+			name:     "typescript",
+			language: "typescript",
+			content:  "import axios from 'axios';\nimport type { User } from \"./models\";\nimport {\n  a,\n  b,\n} from '@app/utils';\nimport './polyfills';\nexport * from './types';\nconst fs = require('fs');\n",
+			expected: []string{"axios", "./models", "@app/utils", "./polyfills", "./types", "fs"},
+		},
+		{
+			name:     "go",
+			language: "go",
+			content:  "package main\n\nimport \"os\"\n\nimport (\n\t\"fmt\"\n\tlog \"log/slog\"\n)\n\nfunc main() {}\n",
+			expected: []string{"os", "fmt", "log/slog"},
+		},
+		{
+			name:     "ruby",
+			language: "ruby",
+			content:  "require 'json'\nrequire_relative \"lib/helper\"\n\nclass A; end\n",
+			expected: []string{"json", "lib/helper"},
+		},
+		{
+			name:     "php",
+			language: "php",
+			content:  "<?php\nnamespace App;\n\nuse App\\Models\\User;\nuse function App\\helpers\\format;\nrequire_once 'vendor/autoload.php';\n",
+			expected: []string{`App\Models\User`, `App\helpers\format`, "vendor/autoload.php"},
+		},
+		{
+			name:     "swift",
+			language: "swift",
+			content:  "import Foundation\n@testable import MyApp\nimport struct SwiftUI.Color\n\nlet imported = true\n",
+			expected: []string{"Foundation", "MyApp", "SwiftUI.Color"},
+		},
+		{
+			name:     "unknown language",
+			language: "cobol",
+			content:  "import foo;\n",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractImports(tt.content, tt.language); !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected imports %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestChunker_IncludeImports(t *testing.T) {
+	chunker := newApproximateTestChunker(t)
+	if chunker.astChunker == nil {
+		t.Skip("AST chunker not available")
+	}
+	chunker.config.IncludeImports = true
+
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected string
+	}{
+		{
+			name: "java",
+			file: "UserService.java",
+			content: `package com.example;
+
+import java.util.List;
+import com.example.repo.UserRepository;
+
+public class UserService {
+    public List<String> names(UserRepository repo) {
+        return repo.findNames();
+    }
+}
+`,
+			expected: "java.util.List, com.example.repo.UserRepository",
+		},
+		{
+			name: "typescript",
+			file: "client.ts",
+			content: `import axios from 'axios';
+import { User } from './models';
+
+export async function fetchUser(id: string): Promise<User> {
+  const response = await axios.get('/users/' + id);
+  return response.data;
+}
+`,
+			expected: "axios, ./models",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, _, err := chunker.chunkContent("/repo", "/repo/"+tt.file, []byte(tt.content))
+			if err != nil {
+				t.Fatalf("chunkContent failed: %v", err)
+			}
+
+			functions := 0
+			for _, chunk := range chunks {
+				imports, _ := chunk.Metadata[models.MetadataImports].(string)
+				switch chunk.ChunkType {
+				case models.ChunkTypeFunction, models.ChunkTypeMethod:
+					functions++
+					if imports != tt.expected {
+						t.Errorf("Expected imports %q on %s, got %q", tt.expected, chunk.FunctionName, imports)
+					}
+					if !strings.HasPrefix(chunk.EmbeddingText(), importContextPrefix+tt.expected+"\n") {
+						t.Errorf("Expected the embedded text to start with the imports, got %q", chunk.EmbeddingText())
+					}
+					if strings.Contains(chunk.Content, "import ") {
+						t.Errorf("Expected the displayed content to be left as it was, got %q", chunk.Content)
+					}
+				default:
+					if imports != "" {
+						t.Errorf("Expected no imports on a %s chunk, got %q", chunk.ChunkType, imports)
+					}
+				}
+			}
+			if functions == 0 {
+				t.Fatalf("Expected function chunks, got %+v", chunks)
+			}
+		})
+	}
+
+	// Disabled by default
+	chunker.config.IncludeImports = false
+	chunks, _, err := chunker.chunkContent("/repo", "/repo/"+tests[0].file, []byte(tests[0].content))
+	if err != nil {
+		t.Fatalf("chunkContent failed: %v", err)
+	}
+	for _, chunk := range chunks {
+		if _, ok := chunk.Metadata[models.MetadataImports]; ok || chunk.EmbedContent != "" {
+			t.Errorf("Expected no import context when disabled, got %+v", chunk)
+		}
+	}
+}
@@ -2,8 +2,16 @@ package indexer
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,27 +25,94 @@ import (
 
 // Indexing configuration constants
 const (
-	// DefaultParallelWorkers is the default number of parallel workers for file processing
+	// DefaultParallelWorkers is the default number of concurrent embedding requests
 	DefaultParallelWorkers = 4
 	// ProgressLogInterval is the interval at which to log progress updates (every N files)
 	ProgressLogInterval = 10
+	// WarmUpMinChunks is the run size from which the embedding model is warmed up first
+	WarmUpMinChunks = 50
 )
 
+// ErrIndexingInProgress is returned when a repository is already being indexed
+var ErrIndexingInProgress = errors.New("indexing already in progress")
+
+// ErrShuttingDown is returned when indexing is requested after Shutdown
+var ErrShuttingDown = errors.New("indexer is shutting down")
+
+// ErrDryRunOnly is returned when an indexer created by NewDryRunIndexer is asked to store chunks
+var ErrDryRunOnly = errors.New("indexer has no vector database and can only run dry runs")
+
+// ModelWarmer loads the embedding model before a large embedding run
+type ModelWarmer interface {
+	WarmUp(ctx context.Context) error
+}
+
+// VectorStore is the subset of vector database operations used by the indexer
+type VectorStore interface {
+	UpsertChunks(ctx context.Context, chunks []models.CodeChunk) error
+	CountChunks(ctx context.Context, repoPath string) (int, error)
+	DeleteByFile(ctx context.Context, repoPath, filePath string) error
+	RenameFile(ctx context.Context, repoPath, oldPath, newPath string) error
+}
+
 // Indexer orchestrates the code indexing process
 type Indexer struct {
 	config           *config.Config
 	scanner          *Scanner
 	chunker          *Chunker
 	hashManager      *cache.FileHashManager
-	embeddingsClient *embeddings.Client
+	embeddingsClient ModelWarmer
 	batcher          *embeddings.Batcher
-	vectorDB         *vectordb.Client
+	vectorDB         VectorStore
 	jobs             map[string]*models.IndexJob
 	jobsMux          sync.RWMutex
+	readFile         func(name string) ([]byte, error) // Reads files to index, os.ReadFile
+	stopping         chan struct{}                     // Closed by Shutdown; jobs stop at the next checkpoint
+	running          sync.WaitGroup                    // Jobs started and not yet finished
+	onIndexChanged   func(repoPath string)             // Called after a job may have changed a repository's index
+	documentPrefix   string                            // Task prefix documents are embedded with, recorded in the hash cache
+}
+
+// repoSettings holds the configuration and components used to index one repository
+type repoSettings struct {
+	config  *config.Config
+	scanner *Scanner
+	chunker *Chunker
+	hashes  *cache.FileHashManager // The shared hash cache, or a private one for dry runs
 }
 
 // NewIndexer creates a new code indexer
 func NewIndexer(cfg *config.Config) (*Indexer, error) {
+	idx, err := newIndexer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create vector database client
+	vectorDB, err := vectordb.NewClient(&cfg.VectorDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vector DB client: %w", err)
+	}
+
+	// Initialize vector DB (create collection if needed)
+	ctx := context.Background()
+	if err := vectorDB.Initialize(ctx); err != nil {
+		return nil, fmt.Errorf("failed to initialize vector DB: %w", err)
+	}
+	idx.vectorDB = vectorDB
+
+	return idx, nil
+}
+
+// NewDryRunIndexer creates an indexer that can only run dry runs
+// It never connects to Qdrant, so a repository can be previewed before anything is running;
+// every other operation needs an indexer created by NewIndexer.
+func NewDryRunIndexer(cfg *config.Config) (*Indexer, error) {
+	return newIndexer(cfg)
+}
+
+// newIndexer creates an indexer without a vector database client
+func newIndexer(cfg *config.Config) (*Indexer, error) {
 	// Create cache directory
 	hashManager, err := cache.NewFileHashManager(cfg.Cache.Directory)
 	if err != nil {
@@ -45,32 +120,39 @@ func NewIndexer(cfg *config.Config) (*Indexer, error) {
 	}
 
 	// Create scanner with ignore patterns
-	scanner := NewScanner(&cfg.Indexing, cfg.Ignore.Patterns)
+	scanner := newConfiguredScanner(cfg)
 
 	// Create chunker
+	if err := validateChunkTypes(cfg.Chunking.IndexChunkTypes); err != nil {
+		return nil, err
+	}
+	if err := validateEmbedStrategy(cfg.Chunking.EmbedStrategy); err != nil {
+		return nil, err
+	}
+	if err := validateUnsupportedFiles(cfg.Chunking.UnsupportedFiles); err != nil {
+		return nil, err
+	}
+	if err := validateBoundaryPatterns(cfg.Chunking.BoundaryPatterns); err != nil {
+		return nil, err
+	}
+	if _, err := resolveEncoding(cfg.Chunking.TokenizerEncoding); err != nil {
+		return nil, err
+	}
 	chunker := NewChunker(&cfg.Chunking)
 
 	// Create embeddings client
 	embeddingsClient := embeddings.NewClient(&cfg.Embeddings)
+	embeddingsClient.SetTokenizer(chunker.tokenChunker)
 
 	// Create batcher
 	batcher := embeddings.NewBatcher(
 		embeddingsClient,
 		cfg.Embeddings.BatchSize,
-		cfg.Indexing.ParallelWorkers,
+		embeddingWorkers(&cfg.Indexing),
 	)
-
-	// Create vector database client
-	vectorDB, err := vectordb.NewClient(&cfg.VectorDB)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create vector DB client: %w", err)
-	}
-
-	// Initialize vector DB (create collection if needed)
-	ctx := context.Background()
-	if err := vectorDB.Initialize(ctx); err != nil {
-		return nil, fmt.Errorf("failed to initialize vector DB: %w", err)
-	}
+	batcher.SetRetryPolicy(cfg.Embeddings.MaxRetries, time.Duration(cfg.Embeddings.RetryBackoffMs)*time.Millisecond)
+	_, documentPrefix := cfg.Embeddings.TaskPrefixes()
+	batcher.SetDocumentPrefix(documentPrefix)
 
 	return &Indexer{
 		config:           cfg,
@@ -79,133 +161,578 @@ func NewIndexer(cfg *config.Config) (*Indexer, error) {
 		hashManager:      hashManager,
 		embeddingsClient: embeddingsClient,
 		batcher:          batcher,
-		vectorDB:         vectorDB,
 		jobs:             make(map[string]*models.IndexJob),
+		readFile:         os.ReadFile,
+		stopping:         make(chan struct{}),
+		documentPrefix:   documentPrefix,
+	}, nil
+}
+
+// settingsFor returns the settings for a repository
+// The repository's .semantic-search.yaml, if any, is merged over the global config,
+// and the scanner and chunker are rebuilt to use the merged settings.
+func (idx *Indexer) settingsFor(repoPath string) (*repoSettings, error) {
+	cfg, err := idx.config.ForRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == idx.config {
+		return idx.globalSettings(), nil
+	}
+	if err := validateChunkTypes(cfg.Chunking.IndexChunkTypes); err != nil {
+		return nil, err
+	}
+	if err := validateEmbedStrategy(cfg.Chunking.EmbedStrategy); err != nil {
+		return nil, err
+	}
+	if err := validateUnsupportedFiles(cfg.Chunking.UnsupportedFiles); err != nil {
+		return nil, err
+	}
+	if err := validateBoundaryPatterns(cfg.Chunking.BoundaryPatterns); err != nil {
+		return nil, err
+	}
+	// The tokenizer is shared with the embeddings client, so it can't differ per repository
+	if cfg.Chunking.TokenizerEncoding != idx.config.Chunking.TokenizerEncoding {
+		return nil, fmt.Errorf("chunking.tokenizer_encoding cannot be set in %s: it applies to every repository", config.RepoConfigFile)
+	}
+
+	slog.Info("Using repository config overrides", "repo", repoPath, "file", config.RepoConfigFile)
+	return &repoSettings{
+		config:  cfg,
+		scanner: newConfiguredScanner(cfg),
+		chunker: idx.chunker.withConfig(&cfg.Chunking),
+		hashes:  idx.hashManager,
 	}, nil
 }
 
+// newConfiguredScanner creates a scanner using the indexing, ignore and unsupported file settings of cfg
+func newConfiguredScanner(cfg *config.Config) *Scanner {
+	scanner := NewScanner(&cfg.Indexing, cfg.Ignore.Patterns)
+	scanner.SetIgnoreCaseInsensitive(cfg.Ignore.CaseInsensitive)
+	scanner.SetUnsupportedFiles(cfg.Chunking.UnsupportedFiles)
+	return scanner
+}
+
+// filterLanguages returns the files detected as one of languages
+func (idx *Indexer) filterLanguages(files []string, languages []string) []string {
+	var filtered []string
+	for _, file := range files {
+		if lang, ok := idx.chunker.langDetector.Detect(file); ok && slices.Contains(languages, lang.Name) {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
+
+// languageNames returns the names of the supported languages, sorted
+func (idx *Indexer) languageNames() []string {
+	var names []string
+	for _, lang := range idx.chunker.langDetector.GetAllLanguages() {
+		names = append(names, lang.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// globalSettings returns the settings from the global config
+func (idx *Indexer) globalSettings() *repoSettings {
+	return &repoSettings{config: idx.config, scanner: idx.scanner, chunker: idx.chunker, hashes: idx.hashManager}
+}
+
 // Index indexes a repository
-func (idx *Indexer) Index(repoPath string, forceReindex bool) (*models.IndexJob, error) {
-	// Create job
-	job := &models.IndexJob{
-		ID:       fmt.Sprintf("job-%d", time.Now().UnixNano()),
-		RepoPath: repoPath,
-		Status:   models.IndexStatusRunning,
-		StartTime: time.Now(),
+// With dryRun set, files are scanned and chunked but nothing is embedded, stored, or cached;
+// dry runs always run synchronously so the returned job holds the final stats
+func (idx *Indexer) Index(repoPath string, forceReindex, dryRun bool) (*models.IndexJob, error) {
+	return idx.IndexLanguages(repoPath, nil, forceReindex, dryRun)
+}
+
+// IndexLanguages indexes only the repository's files of the given languages (every language
+// when empty), e.g. to reindex one stack after a refactor without a full reindex
+// Chunks and cached hashes of other languages' files are left as they are.
+func (idx *Indexer) IndexLanguages(repoPath string, languages []string, forceReindex, dryRun bool) (*models.IndexJob, error) {
+	for _, name := range languages {
+		if _, ok := idx.chunker.langDetector.GetLanguage(name); !ok {
+			return nil, fmt.Errorf("unknown language %q (supported: %s)", name, strings.Join(idx.languageNames(), ", "))
+		}
 	}
 
-	// Store job
-	idx.jobsMux.Lock()
-	idx.jobs[job.ID] = job
-	idx.jobsMux.Unlock()
+	settings, err := idx.settingsFor(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := idx.startJob(repoPath, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	job.Languages = languages
+	job.Background = settings.config.Indexing.Background && !dryRun
 
 	// Run indexing
-	if idx.config.Indexing.Background {
+	if job.Background {
 		// Run in background
-		go idx.doIndex(job, forceReindex)
+		go idx.doIndex(job, settings, forceReindex)
 	} else {
 		// Run synchronously
-		idx.doIndex(job, forceReindex)
+		idx.doIndex(job, settings, forceReindex)
+	}
+
+	return job, nil
+}
+
+// startJob creates and stores a running job, unless the repository is already being indexed
+// (dry runs only read, from a private copy of the hash cache, so they may run alongside)
+func (idx *Indexer) startJob(repoPath string, dryRun bool) (*models.IndexJob, error) {
+	job := &models.IndexJob{
+		ID:        fmt.Sprintf("job-%d", time.Now().UnixNano()),
+		RepoPath:  repoPath,
+		Status:    models.IndexStatusRunning,
+		StartTime: time.Now(),
+		DryRun:    dryRun,
 	}
 
+	if idx.vectorDB == nil && !dryRun {
+		return nil, ErrDryRunOnly
+	}
+
+	idx.jobsMux.Lock()
+	defer idx.jobsMux.Unlock()
+	if idx.isStopping() {
+		return nil, ErrShuttingDown
+	}
+	if running := idx.runningJobLocked(repoPath); running != nil && !dryRun {
+		return nil, fmt.Errorf("%w for %s (job %s); use get_index_status to follow it", ErrIndexingInProgress, repoPath, running.ID)
+	}
+	idx.jobs[job.ID] = job
+	// Added under jobsMux, so Shutdown never waits while a job is being added
+	idx.running.Add(1)
 	return job, nil
 }
 
+// Shutdown stops accepting new jobs, signals running jobs to stop at their next checkpoint
+// and waits for them to finish, or until ctx is done
+// Jobs stop before embedding or storing anything, leaving the cache unsaved, so an interrupted
+// repository is simply reprocessed on the next run.
+func (idx *Indexer) Shutdown(ctx context.Context) error {
+	idx.jobsMux.Lock()
+	if !idx.isStopping() {
+		close(idx.stopping)
+	}
+	idx.jobsMux.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		idx.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for indexing jobs to stop: %w", ctx.Err())
+	}
+}
+
+// Close closes the vector database connection
+func (idx *Indexer) Close() error {
+	if closer, ok := idx.vectorDB.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// isStopping reports whether Shutdown has been called
+func (idx *Indexer) isStopping() bool {
+	select {
+	case <-idx.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// stopAtCheckpoint cancels the job if Shutdown has been called, returning whether it did
+// Only called before anything is written to the vector database or the cache.
+func (idx *Indexer) stopAtCheckpoint(job *models.IndexJob, phase string) bool {
+	if !idx.isStopping() {
+		return false
+	}
+	job.Cancel(fmt.Sprintf("Indexing stopped by server shutdown before %s. Cache was NOT updated - files will be reprocessed on next attempt.", phase))
+	slog.Warn("Indexing stopped by shutdown", "job", job.ID, "phase", phase)
+	return true
+}
+
 // doIndex performs the actual indexing
-func (idx *Indexer) doIndex(job *models.IndexJob, forceReindex bool) {
+func (idx *Indexer) doIndex(job *models.IndexJob, settings *repoSettings, forceReindex bool) {
+	defer idx.running.Done()
 	defer func() {
-		job.EndTime = time.Now()
+		job.Finish()
+		idx.indexChanged(job)
 	}()
 
-	log.Printf("[%s] Starting indexing for %s", job.ID, job.RepoPath)
+	slog.Info("Starting indexing", "job", job.ID, "repo", job.RepoPath)
+
+	// Dry runs never update the cache, so they read a private copy instead of loading it into the
+	// shared manager, where it would swap out the cache of a job indexing another repository
+	if job.DryRun {
+		dryRunSettings := *settings
+		dryRunSettings.hashes = idx.hashManager.Detached()
+		settings = &dryRunSettings
+	}
 
 	// Load file hash cache
-	if !forceReindex && idx.config.Indexing.Incremental {
-		if err := idx.hashManager.Load(job.RepoPath); err != nil {
-			log.Printf("[%s] Warning: Failed to load hash cache: %v", job.ID, err)
+	// Runs limited to some languages load it even when forced, keeping other languages' hashes
+	if (!forceReindex || len(job.Languages) > 0) && settings.config.Indexing.Incremental {
+		if err := settings.hashes.Load(job.RepoPath); err != nil {
+			slog.Warn("Failed to load hash cache", "job", job.ID, "error", err)
 		}
+		// Vectors embedded with another document prefix can't be compared with new queries,
+		// so every file is embedded again, unchanged or not
+		if settings.hashes.DocumentPrefixChanged(idx.documentPrefix) {
+			slog.Warn("Document prefix changed since the last index, reindexing every file", "job", job.ID, "prefix", idx.documentPrefix)
+			settings.hashes.Reset(job.RepoPath)
+		}
+	}
+
+	// Blame changes with new commits, even for files whose content is unchanged
+	var head string
+	var reblame map[string]bool
+	if settings.config.Indexing.GitBlame && !job.DryRun && isGitWorkTree(job.RepoPath) {
+		head, reblame = filesToReblame(job, settings)
 	}
 
 	// Scan repository
-	log.Printf("[%s] Scanning repository...", job.ID)
-	scanResult, err := idx.scanner.Scan(job.RepoPath)
+	slog.Info("Scanning repository", "job", job.ID)
+	var dirCache DirCache
+	if !forceReindex && settings.config.Indexing.Incremental && settings.config.Indexing.SkipUnchangedDirs {
+		dirCache = settings.hashes.DirSnapshot()
+	}
+	scanResult, err := settings.scanner.ScanIncremental(job.RepoPath, dirCache)
 	if err != nil {
-		job.Status = models.IndexStatusFailed
-		job.Error = fmt.Sprintf("scan failed: %v", err)
-		log.Printf("[%s] Scan failed: %v", job.ID, err)
+		job.Fail(fmt.Sprintf("scan failed: %v", err))
+		slog.Error("Scan failed", "job", job.ID, "error", err)
+		return
+	}
+
+	// Files in unchanged directories are neither read nor hashed, unless they need new blame
+	filesToProcess := scanResult.Files
+	if len(scanResult.UnchangedFiles) > 0 {
+		unchanged := scanResult.UnchangedFiles
+		if len(reblame) > 0 {
+			unchanged = slices.DeleteFunc(slices.Clone(unchanged), func(path string) bool { return reblame[path] })
+		}
+		filesToProcess = withoutFiles(scanResult.Files, unchanged)
+		slog.Info("Skipped files in unchanged directories", "job", job.ID, "files", len(scanResult.UnchangedFiles))
+	}
+	if len(job.Languages) > 0 {
+		filesToProcess = idx.filterLanguages(filesToProcess, job.Languages)
+		slog.Info("Indexing selected languages only", "job", job.ID, "languages", job.Languages, "files", len(filesToProcess))
+	}
+
+	job.SetFilesTotal(len(filesToProcess))
+	slog.Info("Found files to process", "job", job.ID, "files", job.GetFilesTotal())
+	if scanResult.SkippedFiles > 0 {
+		slog.Info("Skipped files", "job", job.ID, "count", scanResult.SkippedFiles, "reasons", scanResult.SkipReasons)
+	}
+	if scanResult.DepthLimitedDirs > 0 {
+		slog.Info("Skipped directories beyond max_depth", "job", job.ID, "dirs", scanResult.DepthLimitedDirs, "max_depth", settings.config.Indexing.MaxDepth)
+	}
+	if exceedsLimit(job, "files", len(scanResult.Files), settings.config.Indexing.MaxFiles, "max_files") {
+		return
+	}
+
+	if idx.stopAtCheckpoint(job, "chunking") {
 		return
 	}
 
-	job.SetFilesTotal(len(scanResult.Files))
-	log.Printf("[%s] Found %d files to process", job.ID, job.GetFilesTotal())
+	// Re-point renamed files and drop deleted ones before deciding what to reindex
+	if !forceReindex && settings.config.Indexing.Incremental && !job.DryRun {
+		idx.reconcileMovedFiles(job, scanResult.Files)
+	}
 
 	// Process files in parallel using worker pool
-	allChunks := idx.processFilesInParallel(job, scanResult.Files, forceReindex)
+	allChunks := idx.processFilesInParallel(job, settings, filesToProcess, forceReindex, reblame)
 
-	job.ChunksTotal = len(allChunks)
+	job.SetChunksTotal(len(allChunks))
 
 	filesIndexed, _ := job.GetProgress()
-	log.Printf("[%s] Generated %d chunks from %d files", job.ID, len(allChunks), filesIndexed)
+	slog.Info("Generated chunks", "job", job.ID, "chunks", len(allChunks), "files", filesIndexed)
+	if exceedsLimit(job, "chunks", len(allChunks), settings.config.Indexing.MaxChunks, "max_chunks") {
+		return
+	}
 
-	// Phase 3: Generate embeddings
-	if len(allChunks) > 0 {
-		log.Printf("[%s] Generating embeddings for %d chunks...", job.ID, len(allChunks))
-		embeddingStart := time.Now()
-
-		chunksWithEmbeddings, err := idx.batcher.ProcessChunks(allChunks)
-		if err != nil {
-			job.Status = models.IndexStatusFailed
-			job.Error = fmt.Sprintf("Embedding generation failed: %v. Cache was NOT updated - files will be reprocessed on next attempt.", err)
-			log.Printf("[%s] Embedding generation failed: %v", job.ID, err)
-			// DO NOT save cache - let next indexing attempt retry these files
+	if idx.stopAtCheckpoint(job, "embedding") {
+		return
+	}
+
+	// Dry run: report what would be embedded and stop before touching Ollama or Qdrant
+	if job.DryRun {
+		idx.recordChunkStats(job, allChunks)
+		job.Complete()
+		slog.Info("Dry run completed", "job", job.ID, "duration", time.Since(job.StartTime))
+		return
+	}
+
+	if !idx.embedAndStore(job, allChunks) {
+		return
+	}
+
+	// CRITICAL: Save hash cache ONLY after successful Qdrant storage
+	// This prevents false positives where cache says files are indexed but they're not in Qdrant
+	if settings.config.Indexing.Incremental {
+		var dirMtimes map[string]time.Time
+		if settings.config.Indexing.SkipUnchangedDirs {
+			dirMtimes = completeDirMtimes(scanResult, settings.hashes.IndexedFiles())
+		}
+		settings.hashes.SetDirMtimes(dirMtimes)
+		settings.hashes.SetDocumentPrefix(idx.documentPrefix)
+		// A run limited to some languages leaves the others' blame as it was
+		if len(job.Languages) == 0 {
+			settings.hashes.SetGitHead(head)
+		}
+		if !idx.saveCache(job) {
 			return
 		}
+	}
 
-		embeddingDuration := time.Since(embeddingStart)
-		log.Printf("[%s] Generated embeddings in %v", job.ID, embeddingDuration)
+	// Update job status
+	job.Complete()
+	slog.Info("Indexing completed successfully", "job", job.ID, "duration", time.Since(job.StartTime))
+}
 
-		// Phase 4: Store in vector database
-		log.Printf("[%s] Storing chunks in vector database...", job.ID)
-		storageStart := time.Now()
+// exceedsLimit fails the job when count is over limit (0 = no limit), before anything is
+// embedded or cached, and returns whether it did
+// Dry runs are never aborted, so they can be used to size a repository against the limits.
+func exceedsLimit(job *models.IndexJob, what string, count, limit int, setting string) bool {
+	if job.DryRun || limit <= 0 || count <= limit {
+		return false
+	}
+	job.Fail(fmt.Sprintf("Indexing aborted: found %d %s, over the indexing.%s limit of %d. Cache was NOT updated. "+
+		"Refine ignore_patterns (in the config or the repository's %s) to exclude vendored, generated or build directories, or raise the limit.",
+		count, what, setting, limit, config.RepoConfigFile))
+	slog.Error("Indexing limit exceeded", "job", job.ID, what, count, "limit", limit)
+	return true
+}
 
-		ctx := context.Background()
-		if err := idx.vectorDB.UpsertChunks(ctx, chunksWithEmbeddings); err != nil {
-			job.Status = models.IndexStatusFailed
-			job.Error = fmt.Sprintf("Vector database storage failed: %v. Cache was NOT updated - files will be reprocessed on next attempt. Check if Qdrant is running: docker-compose ps", err)
-			log.Printf("[%s] Vector storage failed: %v", job.ID, err)
-			// DO NOT save cache - let next indexing attempt retry these files
-			return
+// embedAndStore embeds chunks and stores them, replacing the old chunks of their files
+// On failure the job is marked failed and false is returned; the caller must not save the cache,
+// so the files are reprocessed on the next attempt.
+func (idx *Indexer) embedAndStore(job *models.IndexJob, allChunks []models.CodeChunk) bool {
+	if len(allChunks) == 0 {
+		return true
+	}
+
+	// Phase 3: Generate embeddings
+	// Fail fast if the model is missing, rather than after embedding half the repository
+	if len(allChunks) >= WarmUpMinChunks && idx.embeddingsClient != nil {
+		if err := idx.embeddingsClient.WarmUp(context.Background()); err != nil {
+			job.Fail(fmt.Sprintf("Embedding model unavailable: %v", err))
+			slog.Error("Embedding model warmup failed", "job", job.ID, "error", err)
+			return false
 		}
+	}
+
+	slog.Info("Generating embeddings", "job", job.ID, "chunks", len(allChunks))
+	embeddingStart := time.Now()
 
-		storageDuration := time.Since(storageStart)
-		log.Printf("[%s] Stored chunks in %v", job.ID, storageDuration)
+	batchResult, err := idx.batcher.ProcessChunksWithProgress(allChunks, job.RecordEmbedded)
+	if err == nil && len(batchResult.Chunks) == 0 && len(batchResult.Errors) > 0 {
+		// Nothing succeeded, so there is no partial progress worth keeping
+		err = batchResult.Errors[0]
+	}
+	if err != nil {
+		job.Fail(fmt.Sprintf("Embedding generation failed: %v. Cache was NOT updated - files will be reprocessed on next attempt.", err))
+		slog.Error("Embedding generation failed", "job", job.ID, "error", err)
+		// DO NOT save cache - let next indexing attempt retry these files
+		return false
 	}
 
-	// CRITICAL: Save hash cache ONLY after successful Qdrant storage
-	// This prevents false positives where cache says files are indexed but they're not in Qdrant
-	if idx.config.Indexing.Incremental {
-		if err := idx.hashManager.Save(); err != nil {
-			log.Printf("[%s] Warning: Failed to save hash cache: %v", job.ID, err)
-			job.Status = models.IndexStatusFailed
-			job.Error = fmt.Sprintf("Cache save failed: %v. Chunks are in Qdrant but cache is inconsistent. Run with force_reindex=true to fix.", err)
-			return
+	chunksWithEmbeddings := batchResult.Chunks
+	if len(batchResult.FailedChunkIDs) > 0 {
+		chunksWithEmbeddings = idx.dropFailedFiles(job, allChunks, batchResult)
+		job.SetChunksTotal(len(chunksWithEmbeddings))
+	}
+
+	embeddingDuration := time.Since(embeddingStart)
+	slog.Info("Generated embeddings", "job", job.ID, "duration", embeddingDuration)
+
+	idx.recordChunkStats(job, chunksWithEmbeddings)
+
+	if idx.stopAtCheckpoint(job, "storing chunks") {
+		return false
+	}
+
+	// Phase 4: Store in vector database
+	slog.Info("Storing chunks in vector database", "job", job.ID)
+	storageStart := time.Now()
+
+	ctx := context.Background()
+	idx.deleteReplacedChunks(ctx, job, chunksWithEmbeddings)
+	if err := idx.vectorDB.UpsertChunks(ctx, chunksWithEmbeddings); err != nil {
+		job.Fail(fmt.Sprintf("Vector database storage failed: %v. Cache was NOT updated - files will be reprocessed on next attempt. Check if Qdrant is running: docker-compose ps", err))
+		slog.Error("Vector storage failed", "job", job.ID, "error", err)
+		// DO NOT save cache - let next indexing attempt retry these files
+		return false
+	}
+
+	storageDuration := time.Since(storageStart)
+	slog.Info("Stored chunks", "job", job.ID, "duration", storageDuration)
+	return true
+}
+
+// saveCache saves the hash cache, marking the job failed and returning false if that fails
+func (idx *Indexer) saveCache(job *models.IndexJob) bool {
+	if err := idx.hashManager.Save(); err != nil {
+		slog.Warn("Failed to save hash cache", "job", job.ID, "error", err)
+		job.Fail(fmt.Sprintf("Cache save failed: %v. Chunks are in Qdrant but cache is inconsistent. Run with force_reindex=true to fix.", err))
+		return false
+	}
+	return true
+}
+
+// dropFailedFiles handles a partially failed embedding run
+// Files with any chunk that failed to embed are excluded entirely, recorded as failed,
+// and removed from the hash cache so they are retried on the next run.
+// Returns the embedded chunks belonging to fully successful files.
+func (idx *Indexer) dropFailedFiles(job *models.IndexJob, allChunks []models.CodeChunk, result *embeddings.BatchResult) []models.CodeChunk {
+	failedIDs := make(map[string]bool, len(result.FailedChunkIDs))
+	for _, id := range result.FailedChunkIDs {
+		failedIDs[id] = true
+	}
+
+	failedFiles := make(map[string]bool)
+	for _, chunk := range allChunks {
+		if failedIDs[chunk.ID] {
+			failedFiles[chunk.FilePath] = true
 		}
 	}
 
-	// Update job status
-	job.Status = models.IndexStatusCompleted
-	job.EndTime = time.Now()
-	log.Printf("[%s] Indexing completed successfully in %v", job.ID, time.Since(job.StartTime))
+	batchErr := result.Errors[0]
+	for filePath := range failedFiles {
+		idx.hashManager.Remove(filePath)
+		idx.hashManager.MarkUnindexed(filePath)
+		job.AddFailedFile(filePath, fmt.Errorf("embedding failed: %w", batchErr))
+	}
+
+	kept := make([]models.CodeChunk, 0, len(result.Chunks))
+	for _, chunk := range result.Chunks {
+		if !failedFiles[chunk.FilePath] {
+			kept = append(kept, chunk)
+		}
+	}
+
+	slog.Warn("Files failed to embed and will be retried on the next run", "job", job.ID, "files", len(failedFiles))
+	return kept
 }
 
-// processFilesInParallel processes files in parallel using a worker pool pattern
-func (idx *Indexer) processFilesInParallel(job *models.IndexJob, files []string, forceReindex bool) []models.CodeChunk {
-	// Determine number of workers
-	numWorkers := idx.config.Indexing.ParallelWorkers
-	if numWorkers <= 0 {
-		numWorkers = DefaultParallelWorkers
+// deleteReplacedChunks removes the old chunks of every file before its new chunks are stored
+// Chunk IDs are deterministic, so unchanged chunks are simply rewritten, but chunks whose content
+// or position changed would otherwise be left behind under their old IDs. The cache isn't
+// consulted: a file whose entry was dropped (e.g. after failing to embed) may still have chunks.
+func (idx *Indexer) deleteReplacedChunks(ctx context.Context, job *models.IndexJob, chunks []models.CodeChunk) {
+	replaced := make(map[string]bool)
+	for _, chunk := range chunks {
+		if !replaced[chunk.FilePath] {
+			replaced[chunk.FilePath] = true
+			if err := idx.vectorDB.DeleteByFile(ctx, job.RepoPath, chunk.FilePath); err != nil {
+				slog.Warn("Failed to delete old chunks", "job", job.ID, "file", chunk.FilePath, "error", err)
+			}
+		}
+	}
+}
+
+// recordChunkStats records the chunk type and language breakdown and embedded size on the job
+func (idx *Indexer) recordChunkStats(job *models.IndexJob, chunks []models.CodeChunk) {
+	chunksByType := idx.chunker.GetStats(chunks)
+	delete(chunksByType, "total")
+
+	var bytesEmbedded int64
+	for _, chunk := range chunks {
+		bytesEmbedded += int64(len(chunk.EmbeddingText()))
+	}
+
+	avgChunkBytes := 0
+	if len(chunks) > 0 {
+		avgChunkBytes = int(bytesEmbedded / int64(len(chunks)))
+	}
+
+	job.SetChunkStats(chunksByType, bytesEmbedded, avgChunkBytes)
+
+	languageFiles := make(map[string]map[string]bool)
+	for _, chunk := range chunks {
+		if languageFiles[chunk.Language] == nil {
+			languageFiles[chunk.Language] = make(map[string]bool)
+		}
+		languageFiles[chunk.Language][chunk.FilePath] = true
+	}
+	filesByLanguage := make(map[string]int, len(languageFiles))
+	for language, files := range languageFiles {
+		filesByLanguage[language] = len(files)
+	}
+	job.SetFilesByLanguage(filesByLanguage)
+}
+
+// reconcileMovedFiles keeps the index clean across renames and deletions
+// Renamed files (same content under a new path) have their chunks re-pointed instead of re-embedded,
+// and files that disappeared without a match have their chunks deleted
+func (idx *Indexer) reconcileMovedFiles(job *models.IndexJob, files []string) {
+	renames, removed, err := idx.hashManager.DetectMovedFiles(files)
+	if err != nil {
+		slog.Warn("Failed to detect moved files", "job", job.ID, "error", err)
+		return
+	}
+
+	ctx := context.Background()
+	for oldPath, newPath := range renames {
+		if err := idx.vectorDB.RenameFile(ctx, job.RepoPath, oldPath, newPath); err != nil {
+			// Fall back to deleting the old path; the new one isn't cached, so it is reindexed
+			slog.Warn("Failed to re-point chunks for renamed file, reindexing it", "job", job.ID, "file", oldPath, "error", err)
+			removed = append(removed, oldPath)
+			continue
+		}
+		idx.hashManager.Rename(oldPath, newPath)
+		slog.Info("Detected rename", "job", job.ID, "from", oldPath, "to", newPath)
 	}
 
+	idx.removeFiles(ctx, job, removed)
+}
+
+// removeFiles deletes the chunks of files that are no longer indexed (deleted, or now skipped by
+// the scan checks) and drops them from the cache
+func (idx *Indexer) removeFiles(ctx context.Context, job *models.IndexJob, paths []string) {
+	for _, path := range paths {
+		if err := idx.vectorDB.DeleteByFile(ctx, job.RepoPath, path); err != nil {
+			slog.Warn("Failed to delete chunks for removed file", "job", job.ID, "file", path, "error", err)
+			continue
+		}
+		idx.hashManager.Remove(path)
+		slog.Info("Removed chunks for file no longer indexed", "job", job.ID, "file", path)
+	}
+}
+
+// chunkWorkers returns the number of concurrent file chunkers
+func chunkWorkers(cfg *config.IndexingConfig) int {
+	if cfg.ChunkWorkers > 0 {
+		return cfg.ChunkWorkers
+	}
+	return runtime.NumCPU()
+}
+
+// embeddingWorkers returns the number of concurrent embedding requests
+func embeddingWorkers(cfg *config.IndexingConfig) int {
+	if cfg.ParallelWorkers > 0 {
+		return cfg.ParallelWorkers
+	}
+	return DefaultParallelWorkers
+}
+
+// processFilesInParallel processes files in parallel using a worker pool pattern
+// Files in refresh are reprocessed even when their content is unchanged.
+func (idx *Indexer) processFilesInParallel(job *models.IndexJob, settings *repoSettings, files []string, forceReindex bool, refresh map[string]bool) []models.CodeChunk {
+	// Chunking is CPU/IO-bound, so it is sized independently of the embedding workers
+	numWorkers := chunkWorkers(&settings.config.Indexing)
+
 	// Channel for file paths
 	fileChan := make(chan string, len(files))
 	for _, filePath := range files {
@@ -216,6 +743,18 @@ func (idx *Indexer) processFilesInParallel(job *models.IndexJob, files []string,
 	// Channel for chunks from workers
 	chunkChan := make(chan []models.CodeChunk, numWorkers*2)
 
+	blame := settings.config.Indexing.GitBlame && !job.DryRun && isGitWorkTree(job.RepoPath)
+	if settings.config.Indexing.GitBlame && !blame && !job.DryRun {
+		slog.Info("Not a git repository, skipping blame metadata", "job", job.ID, "repo", job.RepoPath)
+	}
+
+	// Files that can't be indexed are recorded, so they aren't reported stale until they change
+	markUnindexed := func(filePath string) {
+		if settings.config.Indexing.Incremental && !job.DryRun {
+			settings.hashes.MarkUnindexed(filePath)
+		}
+	}
+
 	// Track progress atomically
 	var processedFiles int64
 	var allChunks []models.CodeChunk
@@ -225,7 +764,7 @@ func (idx *Indexer) processFilesInParallel(job *models.IndexJob, files []string,
 	var wg sync.WaitGroup
 
 	// Start workers
-	log.Printf("[%s] Starting %d workers for parallel processing", job.ID, numWorkers)
+	slog.Debug("Starting chunk workers", "job", job.ID, "workers", numWorkers)
 	filesTotal := job.GetFilesTotal()
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
@@ -233,43 +772,85 @@ func (idx *Indexer) processFilesInParallel(job *models.IndexJob, files []string,
 			defer wg.Done()
 
 			for filePath := range fileChan {
+				// The remaining files are left unprocessed; the job stops after chunking
+				if idx.isStopping() {
+					return
+				}
+
+				// Read the file once; the hash check, chunkers and cache update share the content
+				content, err := idx.readFile(filePath)
+				if err != nil {
+					slog.Warn("Failed to read file", "job", job.ID, "file", filePath, "error", err)
+					job.AddFailedFile(filePath, fmt.Errorf("failed to read file: %w", err))
+					markUnindexed(filePath)
+					atomic.AddInt64(&processedFiles, 1)
+					current := atomic.LoadInt64(&processedFiles)
+					job.UpdateProgress(int(current), float64(current)/float64(filesTotal))
+					continue
+				}
+				hash := cache.HashContent(content)
+
 				// Check if file needs reindexing
-				if !forceReindex && idx.config.Indexing.Incremental {
-					needsReindex, err := idx.hashManager.NeedsReindex(filePath)
-					if err != nil {
-						log.Printf("[%s] Warning: Failed to check hash for %s: %v", job.ID, filePath, err)
-					} else if !needsReindex {
-						// Skip file, it hasn't changed
-						atomic.AddInt64(&processedFiles, 1)
-						current := atomic.LoadInt64(&processedFiles)
-						job.UpdateProgress(int(current), float64(current)/float64(filesTotal))
-						continue
-					}
+				if !forceReindex && settings.config.Indexing.Incremental && !refresh[filePath] && !settings.hashes.NeedsReindexHash(filePath, hash) {
+					// Skip file, it hasn't changed
+					job.RecordUnchangedFile()
+					atomic.AddInt64(&processedFiles, 1)
+					current := atomic.LoadInt64(&processedFiles)
+					job.UpdateProgress(int(current), float64(current)/float64(filesTotal))
+					continue
 				}
 
 				// Chunk file
-				chunks, err := idx.chunker.ChunkFile(job.RepoPath, filePath)
+				chunks, usedFallback, err := settings.chunker.chunkContent(job.RepoPath, filePath, content)
+				if usedFallback {
+					job.RecordASTFallback(filePath)
+				}
+				if errors.Is(err, ErrUnsupportedFile) {
+					// Not a failure: the file isn't indexable under chunking.unsupported_files
+					slog.Debug("Skipping unsupported file", "job", job.ID, "file", filePath)
+					if settings.config.Chunking.UnsupportedFiles != UnsupportedFilesSkip {
+						job.RecordUnsupportedFile()
+					}
+					markUnindexed(filePath)
+					atomic.AddInt64(&processedFiles, 1)
+					current := atomic.LoadInt64(&processedFiles)
+					job.UpdateProgress(int(current), float64(current)/float64(filesTotal))
+					continue
+				}
 				if err != nil {
-					log.Printf("[%s] Warning: Failed to chunk %s: %v", job.ID, filePath, err)
+					slog.Warn("Failed to chunk file", "job", job.ID, "file", filePath, "error", err)
+					job.AddFailedFile(filePath, err)
+					markUnindexed(filePath)
 					atomic.AddInt64(&processedFiles, 1)
 					current := atomic.LoadInt64(&processedFiles)
 					job.UpdateProgress(int(current), float64(current)/float64(filesTotal))
 					continue
 				}
 
-				// Add timestamp to chunks
+				if blame {
+					addBlame(job.RepoPath, filePath, chunks)
+				}
+				if isGenerated(content, settings.config.Indexing.GeneratedMarkers) {
+					markGenerated(chunks)
+				}
+
+				// Add timestamp (and content hash) to chunks
 				now := time.Now()
 				for i := range chunks {
 					chunks[i].IndexedAt = now
+					if settings.config.Indexing.ContentHash {
+						chunks[i].ContentHash = contentHash(chunks[i].Content)
+					}
 				}
 
 				// Send chunks to channel
 				chunkChan <- chunks
+				job.RecordChunks(len(chunks))
 
 				// Update hash cache
-				if idx.config.Indexing.Incremental {
-					if err := idx.hashManager.Update(filePath, len(chunks)); err != nil {
-						log.Printf("[%s] Warning: Failed to update hash for %s: %v", job.ID, filePath, err)
+				if settings.config.Indexing.Incremental && !job.DryRun {
+					if err := settings.hashes.UpdateHash(filePath, hash, len(chunks)); err != nil {
+						slog.Warn("Failed to update hash", "job", job.ID, "file", filePath, "error", err)
 					}
 				}
 
@@ -281,8 +862,8 @@ func (idx *Indexer) processFilesInParallel(job *models.IndexJob, files []string,
 				// Log progress periodically
 				if current%ProgressLogInterval == 0 || current == 1 {
 					_, progress := job.GetProgress()
-					log.Printf("[%s] Progress: %d/%d files (%.1f%%)",
-						job.ID, current, filesTotal, progress*100)
+					slog.Debug("Progress", "job", job.ID, "files", current, "total", filesTotal,
+						"percent", fmt.Sprintf("%.1f", progress*100))
 				}
 			}
 		}(i)
@@ -305,12 +886,104 @@ func (idx *Indexer) processFilesInParallel(job *models.IndexJob, files []string,
 
 	// Wait for chunk collection to finish
 	<-done
+	job.MarkChunked()
 
 	finalProcessed := atomic.LoadInt64(&processedFiles)
-	log.Printf("[%s] Generated %d chunks from %d files", job.ID, len(allChunks), finalProcessed)
+	slog.Info("Generated chunks", "job", job.ID, "chunks", len(allChunks), "files", finalProcessed)
+	if failed := job.GetFailedFiles(); len(failed) > 0 {
+		slog.Warn("Files failed to chunk", "job", job.ID, "files", len(failed))
+	}
 	return allChunks
 }
 
+// CheckStale reports whether a repository has files that changed since it was last indexed
+// Only file mtimes are compared against the hash cache, so no content is hashed or embedded.
+// Returns the first stale file found, if any.
+func (idx *Indexer) CheckStale(repoPath string) (string, bool, error) {
+	settings, err := idx.settingsFor(repoPath)
+	if err != nil {
+		return "", false, err
+	}
+	if !settings.config.Indexing.Incremental {
+		return "", false, fmt.Errorf("staleness check requires incremental indexing")
+	}
+
+	scanResult, err := settings.scanner.Scan(repoPath)
+	if err != nil {
+		return "", false, fmt.Errorf("scan failed: %w", err)
+	}
+
+	// Read a private copy: loading into the shared manager would swap out the cache of a job
+	// indexing another repository
+	hashCache, err := idx.hashManager.LoadCache(repoPath)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to load hash cache: %w", err)
+	}
+
+	path, stale := cache.FindStaleFile(hashCache, scanResult.Files)
+	return path, stale, nil
+}
+
+// IsIndexing returns true if an indexing job is currently running for the repository
+func (idx *Indexer) IsIndexing(repoPath string) bool {
+	idx.jobsMux.RLock()
+	defer idx.jobsMux.RUnlock()
+
+	return idx.runningJobLocked(repoPath) != nil
+}
+
+// runningJobLocked returns the running (non dry-run) job for repoPath, or nil
+// The caller must hold jobsMux.
+func (idx *Indexer) runningJobLocked(repoPath string) *models.IndexJob {
+	for _, job := range idx.jobs {
+		if job.RepoPath == repoPath && !job.DryRun && isRunning(job) {
+			return job
+		}
+	}
+	return nil
+}
+
+// isRunning reports whether a job is still running
+func isRunning(job *models.IndexJob) bool {
+	status, _ := job.GetStatus()
+	return status == models.IndexStatusRunning
+}
+
+// withoutFiles returns files minus the paths in exclude
+func withoutFiles(files, exclude []string) []string {
+	excluded := make(map[string]bool, len(exclude))
+	for _, path := range exclude {
+		excluded[path] = true
+	}
+	kept := make([]string, 0, len(files)-len(exclude))
+	for _, path := range files {
+		if !excluded[path] {
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
+
+// completeDirMtimes returns the scanned directory mtimes, leaving out directories
+// with a file that is not in the cache (e.g. it failed), so they are rescanned next time
+func completeDirMtimes(scanResult *ScanResult, indexed map[string]bool) map[string]time.Time {
+	mtimes := make(map[string]time.Time, len(scanResult.DirMtimes))
+	for dir, mtime := range scanResult.DirMtimes {
+		mtimes[dir] = mtime
+	}
+	for _, path := range scanResult.Files {
+		if !indexed[path] {
+			delete(mtimes, filepath.Dir(path))
+		}
+	}
+	return mtimes
+}
+
+// Tokenizer returns the tokenizer used for token-based chunking
+func (idx *Indexer) Tokenizer() *TokenChunker {
+	return idx.chunker.tokenChunker
+}
+
 // GetJob returns a job by ID
 func (idx *Indexer) GetJob(jobID string) (*models.IndexJob, error) {
 	idx.jobsMux.RLock()
@@ -330,21 +1003,24 @@ func (idx *Indexer) GetJob(jobID string) (*models.IndexJob, error) {
 func (idx *Indexer) GetRepoIndex(repoPath string) (*models.RepoIndex, error) {
 	// Check if there's an active indexing job for this repo
 	idx.jobsMux.RLock()
-	for _, job := range idx.jobs {
-		if job.RepoPath == repoPath && job.Status == models.IndexStatusRunning {
-			idx.jobsMux.RUnlock()
-			filesIndexed, _ := job.GetProgress()
-			return &models.RepoIndex{
-				RepoPath:    repoPath,
-				TotalFiles:  filesIndexed,
-				TotalChunks: job.ChunksTotal,
-				Languages:   make(map[string]int),
-				LastIndexed: job.StartTime,
-				Status:      models.IndexStatusRunning,
-			}, nil
+	job := idx.runningJobLocked(repoPath)
+	idx.jobsMux.RUnlock()
+	if job != nil {
+		filesIndexed, _ := job.GetProgress()
+		repoIndex := &models.RepoIndex{
+			RepoPath:    repoPath,
+			TotalFiles:  filesIndexed,
+			TotalChunks: job.GetChunksTotal(),
+			Languages:   make(map[string]int),
+			LastIndexed: job.StartTime,
+			Status:      models.IndexStatusRunning,
 		}
+		if eta, ok := idx.estimateRemaining(job, time.Now()); ok {
+			repoIndex.ETASeconds = int(eta.Round(time.Second).Seconds())
+			repoIndex.ETA = formatETA(eta)
+		}
+		return repoIndex, nil
 	}
-	idx.jobsMux.RUnlock()
 
 	// Query Qdrant for actual chunk count (source of truth)
 	ctx := context.Background()
@@ -389,7 +1065,41 @@ func (idx *Indexer) GetRepoIndex(repoPath string) (*models.RepoIndex, error) {
 	}, nil
 }
 
+// SetOnIndexChanged sets a function called after each job that may have changed a repository's
+// index, whether it completed or not, e.g. to invalidate cached search results
+func (idx *Indexer) SetOnIndexChanged(fn func(repoPath string)) {
+	idx.onIndexChanged = fn
+}
+
+// indexChanged reports that a job may have changed its repository's index
+// Dry runs store nothing, so they aren't reported.
+func (idx *Indexer) indexChanged(job *models.IndexJob) {
+	if idx.onIndexChanged != nil && !job.DryRun {
+		idx.onIndexChanged(job.RepoPath)
+	}
+}
+
 // ClearCache clears the cache for a repository
 func (idx *Indexer) ClearCache(repoPath string) error {
 	return idx.hashManager.Clear(repoPath)
 }
+
+// HashCache returns a copy of a repository's file hash cache, e.g. to export it with the index
+func (idx *Indexer) HashCache(repoPath string) (*models.FileHashCache, error) {
+	return idx.hashManager.LoadCache(repoPath)
+}
+
+// RestoreHashCache replaces a repository's file hash cache, e.g. after importing its index, so
+// the next incremental index only reindexes files changed since the cache was saved
+func (idx *Indexer) RestoreHashCache(hashCache *models.FileHashCache) error {
+	if hashCache.RepoPath == "" {
+		return fmt.Errorf("the hash cache has no repository path")
+	}
+	if idx.IsIndexing(hashCache.RepoPath) {
+		return fmt.Errorf("%w for %s; restore once it finishes", ErrIndexingInProgress, hashCache.RepoPath)
+	}
+	if hashCache.Hashes == nil {
+		hashCache.Hashes = make(map[string]models.FileHash)
+	}
+	return idx.hashManager.SaveCache(hashCache)
+}
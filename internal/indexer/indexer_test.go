@@ -0,0 +1,1539 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/cache"
+	"github.com/jamaly87/codebase-semantic-search/internal/embeddings"
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// newTestIndexer creates an indexer that only uses AST chunking,
+// so it needs neither Qdrant, Ollama, nor the tokenizer download
+func newTestIndexer(t *testing.T) *Indexer {
+	t.Helper()
+
+	astChunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Indexing.ParallelWorkers = 2
+	cfg.Indexing.Incremental = false
+
+	return &Indexer{
+		config: cfg,
+		chunker: &Chunker{
+			config:       &cfg.Chunking,
+			langDetector: NewLanguageDetector(),
+			astChunker:   astChunker,
+		},
+		jobs:     make(map[string]*models.IndexJob),
+		readFile: os.ReadFile,
+		stopping: make(chan struct{}),
+	}
+}
+
+func TestProcessFiles_ReportsFailedFiles(t *testing.T) {
+	idx := newTestIndexer(t)
+	tmpDir := t.TempDir()
+
+	goodFile := filepath.Join(tmpDir, "Good.java")
+	if err := os.WriteFile(goodFile, []byte("public class Good {\n    public void run() {\n        System.out.println(\"ok\");\n    }\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// A file that disappears between scan and chunking cannot be read
+	missingFile := filepath.Join(tmpDir, "Missing.java")
+
+	job := &models.IndexJob{ID: "test-job", RepoPath: tmpDir}
+	job.SetFilesTotal(2)
+
+	chunks := idx.processFilesInParallel(job, idx.globalSettings(), []string{goodFile, missingFile}, true, nil)
+
+	if len(chunks) == 0 {
+		t.Error("Expected chunks from the readable file")
+	}
+
+	failed := job.GetFailedFiles()
+	if len(failed) != 1 {
+		t.Fatalf("Expected 1 failed file, got %d: %+v", len(failed), failed)
+	}
+	if failed[0].Path != missingFile {
+		t.Errorf("Expected failed path %s, got %s", missingFile, failed[0].Path)
+	}
+	if failed[0].Error == "" {
+		t.Error("Expected failure reason to be recorded")
+	}
+
+	filesIndexed, _ := job.GetProgress()
+	if filesIndexed != 2 {
+		t.Errorf("Expected both files counted as processed, got %d", filesIndexed)
+	}
+}
+
+// Run with -race: status readers must not race with the chunk workers updating the job
+func TestIndexJob_ConcurrentStatusReads(t *testing.T) {
+	idx := newTestIndexer(t)
+	tmpDir := t.TempDir()
+
+	var files []string
+	for i := 0; i < 40; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("Service%d.java", i))
+		source := fmt.Sprintf("public class Service%d {\n    public void run() {\n        System.out.println(\"%d\");\n    }\n}\n", i, i)
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		files = append(files, path)
+	}
+
+	job := &models.IndexJob{ID: "race-job", RepoPath: tmpDir, Status: models.IndexStatusRunning, StartTime: time.Now()}
+	job.SetFilesTotal(len(files))
+	idx.jobs[job.ID] = job
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				snapshot := job.Snapshot()
+				if snapshot.FilesIndexed > snapshot.FilesTotal {
+					t.Errorf("Inconsistent snapshot: %d of %d files", snapshot.FilesIndexed, snapshot.FilesTotal)
+				}
+				if _, err := idx.GetRepoIndex(tmpDir); err != nil {
+					t.Errorf("GetRepoIndex failed: %v", err)
+				}
+				idx.IsIndexing(tmpDir)
+			}
+		}()
+	}
+
+	chunks := idx.processFilesInParallel(job, idx.globalSettings(), files, true, nil)
+	job.SetChunksTotal(len(chunks))
+	close(done) // GetRepoIndex of a finished job queries the vector database, which there isn't
+	readers.Wait()
+	job.Complete()
+	job.Finish()
+
+	snapshot := job.Snapshot()
+	if snapshot.Status != models.IndexStatusCompleted || snapshot.EndTime.IsZero() {
+		t.Errorf("Expected a completed job with an end time, got %+v", snapshot)
+	}
+	if snapshot.FilesIndexed != len(files) || snapshot.ChunksTotal != len(chunks) || snapshot.Progress != 1 {
+		t.Errorf("Expected every file processed, got %+v", snapshot)
+	}
+	if idx.IsIndexing(tmpDir) {
+		t.Error("Expected the completed job not to count as indexing")
+	}
+}
+
+// mockVectorStore keeps chunks in memory, keyed by chunk ID
+type mockVectorStore struct {
+	mu      sync.Mutex
+	chunks  map[string]models.CodeChunk
+	upserts int
+}
+
+func TestProcessFiles_RecordsASTFallback(t *testing.T) {
+	idx := newTestIndexer(t)
+	tokenChunker, err := NewTokenChunker(DefaultMaxTokens, DefaultOverlapTokens)
+	if err != nil {
+		t.Skipf("Tokenizer not available: %v", err)
+	}
+	idx.chunker.tokenChunker = tokenChunker
+	tmpDir := t.TempDir()
+
+	goodFile := filepath.Join(tmpDir, "Good.java")
+	brokenFile := filepath.Join(tmpDir, "Broken.java")
+	if err := os.WriteFile(goodFile, []byte("public class Good {\n    public void run() {}\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(brokenFile, []byte("public class Broken {\n    public void run( {\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	job := &models.IndexJob{ID: "test-job", RepoPath: tmpDir}
+	job.SetFilesTotal(2)
+
+	chunks := idx.processFilesInParallel(job, idx.globalSettings(), []string{goodFile, brokenFile}, true, nil)
+
+	brokenChunks := 0
+	for _, chunk := range chunks {
+		if chunk.FilePath == brokenFile {
+			brokenChunks++
+		}
+	}
+	if brokenChunks == 0 {
+		t.Error("Expected the broken file to be indexed via fallback")
+	}
+
+	stats := job.GetStats()
+	if len(stats.ASTFallbackFiles) != 1 || stats.ASTFallbackFiles[0] != brokenFile {
+		t.Errorf("Expected only %s recorded as AST fallback, got %v", brokenFile, stats.ASTFallbackFiles)
+	}
+	if len(job.GetFailedFiles()) != 0 {
+		t.Errorf("Expected no failed files, got %+v", job.GetFailedFiles())
+	}
+}
+
+func newMockVectorStore() *mockVectorStore {
+	return &mockVectorStore{chunks: make(map[string]models.CodeChunk)}
+}
+
+func (m *mockVectorStore) UpsertChunks(ctx context.Context, chunks []models.CodeChunk) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.upserts++
+	for _, chunk := range chunks {
+		m.chunks[chunk.ID] = chunk
+	}
+	return nil
+}
+
+func (m *mockVectorStore) CountChunks(ctx context.Context, repoPath string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, chunk := range m.chunks {
+		if chunk.RepoPath == repoPath {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockVectorStore) DeleteByFile(ctx context.Context, repoPath, filePath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, chunk := range m.chunks {
+		if chunk.RepoPath == repoPath && chunk.FilePath == filePath {
+			delete(m.chunks, id)
+		}
+	}
+	return nil
+}
+
+func (m *mockVectorStore) RenameFile(ctx context.Context, repoPath, oldPath, newPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, chunk := range m.chunks {
+		if chunk.RepoPath == repoPath && chunk.FilePath == oldPath {
+			delete(m.chunks, id)
+			chunk.FilePath = newPath
+			chunk.ID = models.ChunkID(repoPath, newPath, chunk.StartLine, chunk.EndLine, chunk.Content)
+			m.chunks[chunk.ID] = chunk
+		}
+	}
+	return nil
+}
+
+// countByFile returns the number of stored chunks for a file
+func (m *mockVectorStore) countByFile(filePath string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, chunk := range m.chunks {
+		if chunk.FilePath == filePath {
+			count++
+		}
+	}
+	return count
+}
+
+// mockEmbeddings returns a fixed embedding for every text
+// Batches containing failOn (if set) fail
+type mockEmbeddings struct {
+	calls  int64
+	failOn string
+	mu     sync.Mutex
+	texts  []string // Every text that was embedded
+}
+
+func (m *mockEmbeddings) GenerateEmbedding(text string) ([]float32, error) {
+	atomic.AddInt64(&m.calls, 1)
+	return []float32{0.1, 0.2, 0.3}, nil
+}
+
+func (m *mockEmbeddings) GenerateEmbeddings(texts []string) ([][]float32, error) {
+	for _, text := range texts {
+		if m.failOn != "" && strings.Contains(text, m.failOn) {
+			return nil, errors.New("embedding service unavailable")
+		}
+	}
+	m.mu.Lock()
+	m.texts = append(m.texts, texts...)
+	m.mu.Unlock()
+	result := make([][]float32, len(texts))
+	for i, text := range texts {
+		result[i], _ = m.GenerateEmbedding(text)
+	}
+	return result, nil
+}
+
+// mockWarmer records warmups and fails with err (if set)
+type mockWarmer struct {
+	calls int
+	err   error
+}
+
+func (m *mockWarmer) WarmUp(ctx context.Context) error {
+	m.calls++
+	return m.err
+}
+
+// newIncrementalTestIndexer creates a test indexer with an in-memory vector store and hash cache
+func TestWorkerCounts(t *testing.T) {
+	tests := []struct {
+		name            string
+		parallelWorkers int
+		chunkWorkers    int
+		expectChunk     int
+		expectEmbedding int
+	}{
+		{"independent settings", 2, 8, 8, 2},
+		{"throttled embeddings keep chunk workers", 1, 6, 6, 1},
+		{"defaults", 0, 0, runtime.NumCPU(), DefaultParallelWorkers},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.IndexingConfig{ParallelWorkers: tt.parallelWorkers, ChunkWorkers: tt.chunkWorkers}
+
+			if got := chunkWorkers(cfg); got != tt.expectChunk {
+				t.Errorf("Expected %d chunk workers, got %d", tt.expectChunk, got)
+			}
+			if got := embeddingWorkers(cfg); got != tt.expectEmbedding {
+				t.Errorf("Expected %d embedding workers, got %d", tt.expectEmbedding, got)
+			}
+		})
+	}
+}
+
+func newIncrementalTestIndexer(t *testing.T) (*Indexer, *mockVectorStore, *mockEmbeddings) {
+	t.Helper()
+
+	idx := newTestIndexer(t)
+	idx.config.Indexing.Incremental = true
+	idx.config.Indexing.Background = false
+
+	hashManager, err := cache.NewFileHashManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create hash manager: %v", err)
+	}
+
+	store := newMockVectorStore()
+	embedder := &mockEmbeddings{}
+
+	idx.hashManager = hashManager
+	idx.scanner = NewScanner(&idx.config.Indexing, nil)
+	idx.batcher = embeddings.NewBatcher(embedder, 4, 1)
+	idx.vectorDB = store
+
+	return idx, store, embedder
+}
+
+func TestIndex_RenamedFile(t *testing.T) {
+	idx, store, embedder := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+
+	oldPath := filepath.Join(repoDir, "OldName.java")
+	content := "public class Service {\n    public void run() {\n        System.out.println(\"run\");\n    }\n}\n"
+	if err := os.WriteFile(oldPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	job, _ := idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Initial indexing failed: %s", job.Error)
+	}
+	initialChunks := store.countByFile(oldPath)
+	if initialChunks == 0 {
+		t.Fatal("Expected chunks for original path")
+	}
+	callsBefore := atomic.LoadInt64(&embedder.calls)
+
+	// Rename the file without changing its content
+	newPath := filepath.Join(repoDir, "NewName.java")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatalf("Failed to rename: %v", err)
+	}
+
+	job, _ = idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+
+	if n := store.countByFile(oldPath); n != 0 {
+		t.Errorf("Expected no chunks for old path, got %d", n)
+	}
+	if n := store.countByFile(newPath); n != initialChunks {
+		t.Errorf("Expected %d chunks for new path, got %d", initialChunks, n)
+	}
+	if calls := atomic.LoadInt64(&embedder.calls); calls != callsBefore {
+		t.Errorf("Expected renamed file to not be re-embedded, got %d new embedding calls", calls-callsBefore)
+	}
+
+	// Recreating the old path with the same content leaves the renamed file's chunks alone
+	if err := os.WriteFile(oldPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to recreate test file: %v", err)
+	}
+	job, _ = idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+	if n := store.countByFile(oldPath); n != initialChunks {
+		t.Errorf("Expected %d chunks for recreated old path, got %d", initialChunks, n)
+	}
+	if n := store.countByFile(newPath); n != initialChunks {
+		t.Errorf("Expected %d chunks for new path to be kept, got %d", initialChunks, n)
+	}
+}
+
+// failingRenameStore is a mockVectorStore whose renames fail
+type failingRenameStore struct {
+	*mockVectorStore
+}
+
+func (f *failingRenameStore) RenameFile(ctx context.Context, repoPath, oldPath, newPath string) error {
+	return errors.New("rename failed")
+}
+
+func TestIndex_RenamedFileFallsBackToReindex(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	idx.vectorDB = &failingRenameStore{mockVectorStore: store}
+	repoDir := t.TempDir()
+
+	oldPath := filepath.Join(repoDir, "OldName.java")
+	if err := os.WriteFile(oldPath, []byte("public class Service {\n    public void run() {}\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Initial indexing failed: %s", job.Error)
+	}
+	initialChunks := store.countByFile(oldPath)
+
+	newPath := filepath.Join(repoDir, "NewName.java")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatalf("Failed to rename: %v", err)
+	}
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+
+	if n := store.countByFile(oldPath); n != 0 {
+		t.Errorf("Expected the old path's chunks to be deleted, got %d", n)
+	}
+	if n := store.countByFile(newPath); n != initialChunks {
+		t.Errorf("Expected the new path to be reindexed with %d chunks, got %d", initialChunks, n)
+	}
+}
+
+func TestIndex_DocumentPrefixChange(t *testing.T) {
+	idx, _, embedder := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{
+		"Service.java": "public class Service {\n    public void run() {}\n}\n",
+	})
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Initial indexing failed: %s", job.Error)
+	}
+
+	// Unchanged files are embedded again once the prefix changes...
+	idx.documentPrefix = config.NomicDocumentPrefix
+	callsBefore := atomic.LoadInt64(&embedder.calls)
+	job, _ := idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+	if unchanged := job.GetStats().FilesUnchanged; unchanged != 0 || atomic.LoadInt64(&embedder.calls) == callsBefore {
+		t.Errorf("Expected every file re-embedded after a prefix change, got %d unchanged", unchanged)
+	}
+
+	// ...and only once
+	job, _ = idx.Index(repoDir, false, false)
+	if unchanged := job.GetStats().FilesUnchanged; unchanged != 1 {
+		t.Errorf("Expected the file unchanged once embedded with the new prefix, got %d unchanged", unchanged)
+	}
+}
+
+func TestIndex_DeletedFile(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+
+	path := filepath.Join(repoDir, "Gone.java")
+	if err := os.WriteFile(path, []byte("public class Gone {\n    public void run() {}\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Initial indexing failed: %s", job.Error)
+	}
+	if store.countByFile(path) == 0 {
+		t.Fatal("Expected chunks for file")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove: %v", err)
+	}
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+	if n := store.countByFile(path); n != 0 {
+		t.Errorf("Expected chunks for deleted file to be removed, got %d", n)
+	}
+}
+
+func TestIndex_Stats(t *testing.T) {
+	idx, _, _ := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+
+	first := filepath.Join(repoDir, "First.java")
+	second := filepath.Join(repoDir, "Second.java")
+	for path, class := range map[string]string{first: "First", second: "Second"} {
+		content := "public class " + class + " {\n    public void run() {\n        System.out.println(\"run\");\n    }\n}\n"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	job, _ := idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+
+	stats := job.GetStats()
+	if len(stats.ChunksByType) == 0 {
+		t.Error("Expected chunk type breakdown to be populated")
+	}
+	total := 0
+	for _, count := range stats.ChunksByType {
+		total += count
+	}
+	if total != job.ChunksTotal {
+		t.Errorf("Chunk type breakdown sums to %d, expected %d", total, job.ChunksTotal)
+	}
+	if stats.BytesEmbedded == 0 || stats.AvgChunkBytes == 0 {
+		t.Errorf("Expected embedded byte stats, got %+v", stats)
+	}
+	if stats.FilesUnchanged != 0 {
+		t.Errorf("Expected no unchanged files on first run, got %d", stats.FilesUnchanged)
+	}
+
+	// Modify one file; the other should be reported as unchanged
+	if err := os.WriteFile(first, []byte("public class First {\n    public void walk() {}\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+
+	job, _ = idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+
+	stats = job.GetStats()
+	if stats.FilesUnchanged != 1 {
+		t.Errorf("Expected 1 unchanged file, got %d", stats.FilesUnchanged)
+	}
+	if stats.FilesFailed != 0 {
+		t.Errorf("Expected 0 failed files, got %d", stats.FilesFailed)
+	}
+}
+
+func TestCheckStale(t *testing.T) {
+	idx, _, _ := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+
+	path := filepath.Join(repoDir, "Service.java")
+	if err := os.WriteFile(path, []byte("public class Service {\n    public void run() {}\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// Never indexed: everything is stale
+	if _, stale, err := idx.CheckStale(repoDir); err != nil || !stale {
+		t.Fatalf("Expected unindexed repo to be stale, got stale=%v err=%v", stale, err)
+	}
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+
+	// Fresh after indexing
+	if stalePath, stale, err := idx.CheckStale(repoDir); err != nil || stale {
+		t.Fatalf("Expected fresh repo, got stale=%v (%s) err=%v", stale, stalePath, err)
+	}
+
+	// Modified file is stale
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("Failed to touch file: %v", err)
+	}
+	stalePath, stale, err := idx.CheckStale(repoDir)
+	if err != nil || !stale {
+		t.Fatalf("Expected modified repo to be stale, got stale=%v err=%v", stale, err)
+	}
+	if stalePath != path {
+		t.Errorf("Expected stale path %s, got %s", path, stalePath)
+	}
+
+	// Reindexing makes it fresh again
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+	if err := os.Chtimes(path, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to touch file: %v", err)
+	}
+	if _, stale, _ := idx.CheckStale(repoDir); stale {
+		t.Error("Expected repo to be fresh after reindexing")
+	}
+
+	// A new file makes it stale
+	newPath := filepath.Join(repoDir, "Other.java")
+	if err := os.WriteFile(newPath, []byte("public class Other {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if stalePath, stale, _ := idx.CheckStale(repoDir); !stale || stalePath != newPath {
+		t.Errorf("Expected new file %s to be stale, got stale=%v (%s)", newPath, stale, stalePath)
+	}
+}
+
+func TestCheckStale_FailedFilesNotStale(t *testing.T) {
+	idx, _, embedder := newIncrementalTestIndexer(t)
+	idx.batcher = embeddings.NewBatcher(embedder, 1, 1) // One chunk per batch so files fail independently
+	embedder.failOn = "FAIL_ME"
+	repoDir := t.TempDir()
+
+	writeTestFiles(t, repoDir, map[string]string{
+		"Service.java": "public class Service {\n    public void run() {}\n}\n",
+		"Broken.java":  "public class Broken {\n    public void FAIL_ME() {}\n}\n",
+	})
+
+	job, _ := idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+	if len(job.GetFailedFiles()) != 1 {
+		t.Fatalf("Expected Broken.java to fail, got %v", job.GetFailedFiles())
+	}
+
+	// The failed file has no hash, but nothing changed since it was tried
+	if stalePath, stale, err := idx.CheckStale(repoDir); err != nil || stale {
+		t.Fatalf("Expected fresh repo, got stale=%v (%s) err=%v", stale, stalePath, err)
+	}
+
+	// Editing it makes the repository stale again
+	brokenPath := filepath.Join(repoDir, "Broken.java")
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(brokenPath, future, future); err != nil {
+		t.Fatalf("Failed to touch file: %v", err)
+	}
+	if stalePath, stale, _ := idx.CheckStale(repoDir); !stale || stalePath != brokenPath {
+		t.Errorf("Expected %s to be stale, got stale=%v (%s)", brokenPath, stale, stalePath)
+	}
+}
+
+func TestCheckStale_LeavesLoadedCacheAlone(t *testing.T) {
+	idx, _, _ := newIncrementalTestIndexer(t)
+	indexingDir := t.TempDir()
+	searchedDir := t.TempDir()
+
+	indexingFile := filepath.Join(indexingDir, "Indexing.java")
+	if err := os.WriteFile(indexingFile, []byte("public class Indexing {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(searchedDir, "Searched.java"), []byte("public class Searched {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// A job indexing another repository has its cache loaded
+	if err := idx.hashManager.Load(indexingDir); err != nil {
+		t.Fatalf("Failed to load cache: %v", err)
+	}
+	if err := idx.hashManager.Update(indexingFile, 1); err != nil {
+		t.Fatalf("Failed to update hash: %v", err)
+	}
+
+	if _, stale, err := idx.CheckStale(searchedDir); err != nil || !stale {
+		t.Fatalf("Expected unindexed repo to be stale, got stale=%v err=%v", stale, err)
+	}
+
+	if _, ok := idx.hashManager.Lookup(indexingFile); !ok {
+		t.Error("Expected the loaded cache of the repository being indexed to be left in place")
+	}
+}
+
+func TestIndex_PartialEmbeddingFailure(t *testing.T) {
+	idx, store, embedder := newIncrementalTestIndexer(t)
+	idx.batcher = embeddings.NewBatcher(embedder, 1, 1) // One chunk per batch so files fail independently
+	embedder.failOn = "BROKEN"
+	repoDir := t.TempDir()
+
+	goodPath := filepath.Join(repoDir, "Good.java")
+	brokenPath := filepath.Join(repoDir, "Broken.java")
+	if err := os.WriteFile(goodPath, []byte("public class Good {\n    public void run() {}\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(brokenPath, []byte("public class Broken {\n    // BROKEN\n    public void run() {}\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	job, _ := idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Expected partial success to complete, got %s: %s", job.Status, job.Error)
+	}
+
+	failed := job.GetFailedFiles()
+	if len(failed) != 1 || failed[0].Path != brokenPath {
+		t.Fatalf("Expected %s to be reported as failed, got %+v", brokenPath, failed)
+	}
+	if store.countByFile(goodPath) == 0 {
+		t.Error("Expected chunks for the successful file to be stored")
+	}
+	if n := store.countByFile(brokenPath); n != 0 {
+		t.Errorf("Expected no chunks for the failed file, got %d", n)
+	}
+
+	// The successful file is cached; the failed one is retried on the next run
+	embedder.failOn = ""
+	job, _ = idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Retry run failed: %s", job.Error)
+	}
+	if stats := job.GetStats(); stats.FilesUnchanged != 1 {
+		t.Errorf("Expected the successful file to be unchanged on retry, got %d unchanged", stats.FilesUnchanged)
+	}
+	if store.countByFile(brokenPath) == 0 {
+		t.Error("Expected the previously failed file to be indexed on retry")
+	}
+}
+
+func TestIndex_WarmUp(t *testing.T) {
+	writeFiles := func(t *testing.T, count int) string {
+		repoDir := t.TempDir()
+		for i := 0; i < count; i++ {
+			content := fmt.Sprintf("public class Service%d {\n    public void run() {}\n}\n", i)
+			if err := os.WriteFile(filepath.Join(repoDir, fmt.Sprintf("Service%d.java", i)), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+		}
+		return repoDir
+	}
+
+	t.Run("missing model fails large runs before embedding", func(t *testing.T) {
+		idx, store, embedder := newIncrementalTestIndexer(t)
+		warmer := &mockWarmer{err: errors.New(`model "nomic-embed-text" not found in ollama (run: ollama pull nomic-embed-text)`)}
+		idx.embeddingsClient = warmer
+
+		job, _ := idx.Index(writeFiles(t, WarmUpMinChunks), false, false)
+		if job.Status != models.IndexStatusFailed {
+			t.Fatalf("Expected indexing to fail, got %s", job.Status)
+		}
+		if !strings.Contains(job.Error, "ollama pull") {
+			t.Errorf("Expected error to suggest pulling the model, got: %s", job.Error)
+		}
+		if embedder.calls != 0 || len(store.chunks) != 0 {
+			t.Errorf("Expected nothing embedded or stored, got %d embeddings and %d chunks", embedder.calls, len(store.chunks))
+		}
+	})
+
+	t.Run("small runs skip warmup", func(t *testing.T) {
+		idx, _, _ := newIncrementalTestIndexer(t)
+		warmer := &mockWarmer{}
+		idx.embeddingsClient = warmer
+
+		job, _ := idx.Index(writeFiles(t, 1), false, false)
+		if job.Status != models.IndexStatusCompleted {
+			t.Fatalf("Indexing failed: %s", job.Error)
+		}
+		if warmer.calls != 0 {
+			t.Errorf("Expected no warmup for a small run, got %d", warmer.calls)
+		}
+	})
+}
+
+func TestIndex_RepoConfigOverrides(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	content := "public class Service {\n    public void run() {}\n}\n"
+
+	newRepo := func(repoConfig string) string {
+		repoDir := t.TempDir()
+		for _, name := range []string{"Keep.java", "Skip.java"} {
+			if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+		}
+		if repoConfig != "" {
+			if err := os.WriteFile(filepath.Join(repoDir, config.RepoConfigFile), []byte(repoConfig), 0644); err != nil {
+				t.Fatalf("Failed to write repo config: %v", err)
+			}
+		}
+		return repoDir
+	}
+
+	overridden := newRepo("ignore_patterns:\n  patterns: [\"Skip.java\"]\n")
+	job, err := idx.Index(overridden, false, false)
+	if err != nil || job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %v %+v", err, job)
+	}
+	if store.countByFile(filepath.Join(overridden, "Skip.java")) != 0 {
+		t.Error("Expected the repo's ignore pattern to skip Skip.java")
+	}
+	if store.countByFile(filepath.Join(overridden, "Keep.java")) == 0 {
+		t.Error("Expected Keep.java to be indexed")
+	}
+
+	// A repository without overrides uses the global config
+	plain := newRepo("")
+	if job, _ := idx.Index(plain, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+	if store.countByFile(filepath.Join(plain, "Skip.java")) == 0 {
+		t.Error("Expected Skip.java to be indexed without repo overrides")
+	}
+
+	// Invalid overrides are rejected before a job starts
+	if _, err := idx.Index(newRepo("chunking:\n  index_chunk_types: [functions]\n"), false, false); err == nil {
+		t.Error("Expected an invalid repo config to be rejected")
+	}
+}
+
+func TestIndex_RepoConfigBackground(t *testing.T) {
+	idx, _, _ := newIncrementalTestIndexer(t)
+	idx.config.Indexing.Background = true
+
+	// A repository turning background indexing off runs synchronously, and says so on the job
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{
+		"Service.java":        "public class Service {\n    public void run() {}\n}\n",
+		config.RepoConfigFile: "indexing:\n  background: false\n",
+	})
+	job, err := idx.Index(repoDir, false, false)
+	if err != nil {
+		t.Fatalf("Indexing failed to start: %v", err)
+	}
+	if job.Background {
+		t.Error("Expected the repository's config to make the job synchronous")
+	}
+	if status, reason := job.GetStatus(); status != models.IndexStatusCompleted {
+		t.Errorf("Expected the synchronous job to have completed, got %s: %s", status, reason)
+	}
+}
+
+func TestIndex_StableChunkIDs(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+
+	path := filepath.Join(repoDir, "Service.java")
+	content := "public class Service {\n    public void run() {\n        System.out.println(\"run\");\n    }\n}\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	job, _ := idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Initial indexing failed: %s", job.Error)
+	}
+	initialChunks := store.countByFile(path)
+	if initialChunks == 0 {
+		t.Fatal("Expected chunks after initial indexing")
+	}
+
+	// Forcing a reindex of unchanged content overwrites the same points
+	job, _ = idx.Index(repoDir, true, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Forced reindex failed: %s", job.Error)
+	}
+	if n := store.countByFile(path); n != initialChunks {
+		t.Errorf("Expected %d chunks after forced reindex, got %d (duplicates created)", initialChunks, n)
+	}
+
+	// Changing the file replaces its chunks instead of leaving stale ones behind
+	changed := strings.Replace(content, "\"run\"", "\"running\"", 1)
+	if err := os.WriteFile(path, []byte(changed), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+	job, _ = idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+	if n := store.countByFile(path); n != initialChunks {
+		t.Errorf("Expected %d chunks after change, got %d", initialChunks, n)
+	}
+	store.mu.Lock()
+	for _, chunk := range store.chunks {
+		if strings.Contains(chunk.Content, "\"run\"") {
+			t.Errorf("Found stale chunk with old content: %s", chunk.ID)
+		}
+	}
+	store.mu.Unlock()
+
+	// A file dropped from the cache (as after failing to embed) still has its chunks replaced
+	idx.hashManager.Remove(path)
+	if err := idx.hashManager.Save(); err != nil {
+		t.Fatalf("Failed to save cache: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+	job, _ = idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+	if n := store.countByFile(path); n != initialChunks {
+		t.Errorf("Expected %d chunks after reindexing an uncached file, got %d", initialChunks, n)
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, chunk := range store.chunks {
+		if strings.Contains(chunk.Content, "\"running\"") {
+			t.Errorf("Found stale chunk of a file missing from the cache: %s", chunk.ID)
+		}
+	}
+}
+
+func TestIndex_DryRun(t *testing.T) {
+	idx, store, embedder := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+
+	files := map[string]string{
+		"Service.java": "public class Service {\n    public void run() {}\n}\n",
+		"app.js":       "function start() {\n    return 1;\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	job, _ := idx.Index(repoDir, false, true)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Dry run failed: %s", job.Error)
+	}
+	if !job.DryRun {
+		t.Error("Expected job to be marked as a dry run")
+	}
+
+	if calls := atomic.LoadInt64(&embedder.calls); calls != 0 {
+		t.Errorf("Expected no embedding calls in dry run, got %d", calls)
+	}
+	if store.upserts != 0 {
+		t.Errorf("Expected no upserts in dry run, got %d", store.upserts)
+	}
+
+	if job.GetFilesTotal() != 2 {
+		t.Errorf("Expected 2 files scanned, got %d", job.GetFilesTotal())
+	}
+	if job.ChunksTotal == 0 {
+		t.Error("Expected chunk count to be reported")
+	}
+	stats := job.GetStats()
+	if stats.FilesByLanguage["java"] != 1 || stats.FilesByLanguage["javascript"] != 1 {
+		t.Errorf("Unexpected language breakdown: %v", stats.FilesByLanguage)
+	}
+
+	// The hash cache must not be updated, so a real run still indexes everything
+	job, _ = idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing after dry run failed: %s", job.Error)
+	}
+	if unchanged := job.GetStats().FilesUnchanged; unchanged != 0 {
+		t.Errorf("Expected no files skipped after dry run, got %d", unchanged)
+	}
+	if store.upserts == 0 {
+		t.Error("Expected real run to store chunks")
+	}
+}
+
+func TestIndex_DryRunUsesPrivateCache(t *testing.T) {
+	idx, _, _ := newIncrementalTestIndexer(t)
+	indexingDir := t.TempDir()
+	dryRunDir := t.TempDir()
+	writeTestFiles(t, dryRunDir, map[string]string{
+		"Service.java": "public class Service {\n    public void run() {}\n}\n",
+	})
+	if job, _ := idx.Index(dryRunDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+
+	// A job indexing another repository has its cache loaded
+	indexingFile := filepath.Join(indexingDir, "Indexing.java")
+	writeTestFiles(t, indexingDir, map[string]string{"Indexing.java": "public class Indexing {}\n"})
+	if err := idx.hashManager.Load(indexingDir); err != nil {
+		t.Fatalf("Failed to load cache: %v", err)
+	}
+	if err := idx.hashManager.Update(indexingFile, 1); err != nil {
+		t.Fatalf("Failed to update hash: %v", err)
+	}
+
+	job, _ := idx.Index(dryRunDir, false, true)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Dry run failed: %s", job.Error)
+	}
+	if unchanged := job.GetStats().FilesUnchanged; unchanged != 1 {
+		t.Errorf("Expected the dry run to find the indexed file unchanged, got %d unchanged", unchanged)
+	}
+	if _, ok := idx.hashManager.Lookup(indexingFile); !ok {
+		t.Error("Expected the loaded cache of the repository being indexed to be left in place")
+	}
+}
+
+func TestIndex_ReportsIndexChanges(t *testing.T) {
+	idx, _, _ := newIncrementalTestIndexer(t)
+	var changed []string
+	idx.SetOnIndexChanged(func(repoPath string) {
+		changed = append(changed, repoPath)
+	})
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{
+		"Service.java": "public class Service {\n    public void run() {}\n}\n",
+	})
+
+	// Dry runs store nothing
+	if job, _ := idx.Index(repoDir, false, true); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Dry run failed: %s", job.Error)
+	}
+	if len(changed) != 0 {
+		t.Errorf("Expected no change reported for a dry run, got %v", changed)
+	}
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+	if len(changed) != 1 || changed[0] != repoDir {
+		t.Errorf("Expected one change reported for %s, got %v", repoDir, changed)
+	}
+}
+
+func TestIndex_Languages(t *testing.T) {
+	idx, store, embedder := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{
+		"src/Service.java": "public class Service {\n    public void run() {}\n}\n",
+		"web/api.ts":       "export function fetchUsers() {\n  return fetch('/users');\n}\n",
+		"web/view.ts":      "export function render() {\n  return null;\n}\n",
+	})
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Initial indexing failed: %s", job.Error)
+	}
+
+	// Both stacks change, but only TypeScript is reindexed
+	javaPath := filepath.Join(repoDir, "src/Service.java")
+	tsPath := filepath.Join(repoDir, "web/api.ts")
+	writeTestFiles(t, repoDir, map[string]string{
+		"src/Service.java": "public class Service {\n    public void stop() {}\n}\n",
+		"web/api.ts":       "export function fetchOrders() {\n  return fetch('/orders');\n}\n",
+	})
+	embedder.texts = nil
+
+	job, err := idx.IndexLanguages(repoDir, []string{"typescript"}, false, false)
+	if err != nil || job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing TypeScript failed: %v %s", err, job.Error)
+	}
+	if job.GetFilesTotal() != 2 || job.GetStats().FilesUnchanged != 1 {
+		t.Errorf("Expected the 2 TypeScript files to be processed (1 unchanged), got %d files, %d unchanged",
+			job.GetFilesTotal(), job.GetStats().FilesUnchanged)
+	}
+	for _, text := range embedder.texts {
+		if strings.Contains(text, "class Service") || strings.Contains(text, "stop()") {
+			t.Errorf("Expected no Java code to be embedded, got %q", text)
+		}
+	}
+
+	var javaContent, tsContent string
+	for _, chunk := range store.chunks {
+		switch chunk.FilePath {
+		case javaPath:
+			javaContent += chunk.Content
+		case tsPath:
+			tsContent += chunk.Content
+		}
+	}
+	if !strings.Contains(javaContent, "run()") || strings.Contains(javaContent, "stop()") {
+		t.Errorf("Expected the Java chunks to be left as indexed before, got %q", javaContent)
+	}
+	if !strings.Contains(tsContent, "fetchOrders") || strings.Contains(tsContent, "fetchUsers") {
+		t.Errorf("Expected the TypeScript chunks to be replaced, got %q", tsContent)
+	}
+
+	// The Java change is still picked up by the next full run
+	job, _ = idx.Index(repoDir, false, false)
+	if job.GetStats().FilesUnchanged != 2 {
+		t.Errorf("Expected only the Java file to be reprocessed, got %d unchanged files", job.GetStats().FilesUnchanged)
+	}
+
+	if _, err := idx.IndexLanguages(repoDir, []string{"cobol"}, false, false); err == nil || !strings.Contains(err.Error(), "unknown language") {
+		t.Errorf("Expected an unknown language to be rejected, got %v", err)
+	}
+}
+
+func TestIndex_StripCommentsForEmbedding(t *testing.T) {
+	idx, store, embedder := newIncrementalTestIndexer(t)
+	idx.config.Chunking.StripComments = true
+	idx.config.Chunking.KeepDocComments = false
+	repoDir := t.TempDir()
+
+	path := filepath.Join(repoDir, "Service.java")
+	content := `/*
+ * LICENSE HEADER: Copyright Example Corp
+ */
+public class Service {
+    /** Runs the service */
+    public void run() {
+        // TODO remove debug output
+        System.out.println("run");
+    }
+}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	job, _ := idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+
+	embedder.mu.Lock()
+	defer embedder.mu.Unlock()
+	if len(embedder.texts) == 0 {
+		t.Fatal("Expected texts to be embedded")
+	}
+	for _, text := range embedder.texts {
+		for _, comment := range []string{"LICENSE HEADER", "TODO remove", "Runs the service"} {
+			if strings.Contains(text, comment) {
+				t.Errorf("Expected comment %q to be stripped before embedding, got:\n%s", comment, text)
+			}
+		}
+	}
+
+	// Stored content (used for previews) keeps the original text
+	foundComment := false
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, chunk := range store.chunks {
+		if strings.Contains(chunk.Content, "TODO remove debug output") {
+			foundComment = true
+		}
+	}
+	if !foundComment {
+		t.Error("Expected stored chunk content to keep the original comments")
+	}
+}
+
+// blockingVectorStore signals on started and holds every upsert until release is closed
+type blockingVectorStore struct {
+	*mockVectorStore
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (b *blockingVectorStore) UpsertChunks(ctx context.Context, chunks []models.CodeChunk) error {
+	b.once.Do(func() { close(b.started) })
+	<-b.release
+	return b.mockVectorStore.UpsertChunks(ctx, chunks)
+}
+
+func TestIndex_RejectsConcurrentRunForSameRepo(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	blocking := &blockingVectorStore{mockVectorStore: store, started: make(chan struct{}), release: make(chan struct{})}
+	idx.vectorDB = blocking
+
+	repoDir := t.TempDir()
+	content := "public class Service {\n    public void run() {\n        System.out.println(\"run\");\n    }\n}\n"
+	if err := os.WriteFile(filepath.Join(repoDir, "Service.java"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	type indexResult struct {
+		job *models.IndexJob
+		err error
+	}
+	first := make(chan indexResult, 1)
+	go func() {
+		job, err := idx.Index(repoDir, false, false)
+		first <- indexResult{job, err}
+	}()
+
+	// Wait until the first run is mid-way, then start two more
+	select {
+	case <-blocking.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("First indexing run never reached the vector store")
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = idx.Index(repoDir, false, false)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if !errors.Is(err, ErrIndexingInProgress) {
+			t.Errorf("Expected concurrent run %d to be rejected, got %v", i, err)
+		}
+	}
+
+	// A dry run only reads, so it is allowed alongside
+	if job, err := idx.Index(repoDir, false, true); err != nil || job.Status != models.IndexStatusCompleted {
+		t.Errorf("Expected the dry run to complete, got %v", err)
+	}
+	// ...without replacing the running job's hashes with the cache on disk
+	if _, ok := idx.hashManager.Lookup(filepath.Join(repoDir, "Service.java")); !ok {
+		t.Error("Expected the running job's hash cache to be left in place by the dry run")
+	}
+
+	close(blocking.release)
+	result := <-first
+	if result.err != nil || result.job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Expected the first run to complete, got %v", result.err)
+	}
+
+	// Once it finished, the repository can be indexed again
+	if _, err := idx.Index(repoDir, true, false); err != nil {
+		t.Errorf("Expected a new run after completion, got %v", err)
+	}
+}
+
+func TestIndex_SkipUnchangedDirs(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	idx.config.Indexing.SkipUnchangedDirs = true
+	repoDir := t.TempDir()
+
+	write := func(rel, class string) string {
+		path := filepath.Join(repoDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		content := fmt.Sprintf("public class %s {\n    public void run() {\n        System.out.println(\"%s\");\n    }\n}\n", class, class)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		return path
+	}
+	stable := write("stable/Stable.java", "Stable")
+	write("stable/nested/Nested.java", "Nested")
+	write("changed/Changed.java", "Changed")
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted || job.GetFilesTotal() != 3 {
+		t.Fatalf("Initial indexing failed: %s (files %d)", job.Error, job.GetFilesTotal())
+	}
+
+	// Adding files changes the mtime of their directory only, even when it is nested
+	added := write("changed/Added.java", "Added")
+	nestedAdded := write("stable/nested/More.java", "More")
+	future := time.Now().Add(time.Minute)
+	for _, dir := range []string{filepath.Dir(added), filepath.Dir(nestedAdded)} {
+		if err := os.Chtimes(dir, future, future); err != nil {
+			t.Fatalf("Failed to touch directory: %v", err)
+		}
+	}
+
+	job, _ := idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+
+	// stable/Stable.java is listed from the cache; the two changed directories are rescanned
+	if files := job.GetFilesTotal(); files != 4 {
+		t.Errorf("Expected the 4 files of the changed directories to be processed, got %d", files)
+	}
+	for _, path := range []string{added, nestedAdded} {
+		if store.countByFile(path) == 0 {
+			t.Errorf("Expected %s to be indexed", path)
+		}
+	}
+	if store.countByFile(stable) == 0 {
+		t.Error("Expected the skipped file's chunks to be kept")
+	}
+
+	// Without the flag every file is processed again
+	idx.config.Indexing.SkipUnchangedDirs = false
+	if job, _ := idx.Index(repoDir, false, false); job.GetFilesTotal() != 5 {
+		t.Errorf("Expected all 5 files processed without skip_unchanged_dirs, got %d", job.GetFilesTotal())
+	}
+}
+
+func TestIndex_ReadsEachFileOnce(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{
+		"Alpha.java": "public class Alpha {\n    public void run() {\n        System.out.println(\"alpha\");\n    }\n}\n",
+		"Beta.java":  "public class Beta {\n    public void run() {\n        System.out.println(\"beta\");\n    }\n}\n",
+	})
+
+	var readsMux sync.Mutex
+	reads := make(map[string]int)
+	idx.readFile = func(name string) ([]byte, error) {
+		readsMux.Lock()
+		reads[name]++
+		readsMux.Unlock()
+		return os.ReadFile(name)
+	}
+
+	// The hash check, chunking and cache update of a changed file share one read
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+	for _, name := range []string{"Alpha.java", "Beta.java"} {
+		path := filepath.Join(repoDir, name)
+		if reads[path] != 1 {
+			t.Errorf("Expected %s to be read once, got %d reads", name, reads[path])
+		}
+		if store.countByFile(path) == 0 {
+			t.Errorf("Expected chunks for %s", name)
+		}
+
+		// The cached hash is that of the content that was chunked
+		content, _ := os.ReadFile(path)
+		if cached, ok := idx.hashManager.Lookup(path); !ok || cached.Hash != cache.HashContent(content) {
+			t.Errorf("Expected the cached hash of %s to match its content", name)
+		}
+	}
+
+	// Unchanged files are read once for the hash check and not chunked
+	reads = make(map[string]int)
+	if job, _ := idx.Index(repoDir, false, false); job.ChunksTotal != 0 {
+		t.Errorf("Expected no chunks for unchanged files, got %d", job.ChunksTotal)
+	}
+	for path, n := range reads {
+		if n != 1 {
+			t.Errorf("Expected %s to be read once, got %d reads", path, n)
+		}
+	}
+}
+
+func TestIndex_Limits(t *testing.T) {
+	files := map[string]string{
+		"Alpha.java": "public class Alpha {\n    public void run() {\n        System.out.println(\"alpha\");\n    }\n}\n",
+		"Beta.java":  "public class Beta {\n    public void run() {\n        System.out.println(\"beta\");\n    }\n}\n",
+		"Gamma.java": "public class Gamma {\n    public void run() {\n        System.out.println(\"gamma\");\n    }\n}\n",
+	}
+
+	tests := []struct {
+		name      string
+		maxFiles  int
+		maxChunks int
+		setting   string
+	}{
+		{"too many files", 2, 0, "indexing.max_files"},
+		{"too many chunks", 0, 2, "indexing.max_chunks"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx, store, embedder := newIncrementalTestIndexer(t)
+			idx.config.Indexing.MaxFiles = tt.maxFiles
+			idx.config.Indexing.MaxChunks = tt.maxChunks
+			repoDir := t.TempDir()
+			writeTestFiles(t, repoDir, files)
+
+			job, _ := idx.Index(repoDir, false, false)
+			if job.Status != models.IndexStatusFailed {
+				t.Fatalf("Expected the job to fail, got %s", job.Status)
+			}
+			if !strings.Contains(job.Error, tt.setting) || !strings.Contains(job.Error, "ignore_patterns") {
+				t.Errorf("Expected the error to name %s and suggest ignore patterns, got %q", tt.setting, job.Error)
+			}
+			if atomic.LoadInt64(&embedder.calls) != 0 || store.upserts != 0 {
+				t.Errorf("Expected nothing embedded or stored, got %d embeddings and %d upserts", embedder.calls, store.upserts)
+			}
+
+			// A dry run reports the size instead of aborting
+			job, _ = idx.Index(repoDir, false, true)
+			if job.Status != models.IndexStatusCompleted || job.GetFilesTotal() != 3 {
+				t.Errorf("Expected a completed dry run over 3 files, got %s (%s)", job.Status, job.Error)
+			}
+
+			// Without the limits every file is processed, as the aborted run saved no cache
+			idx.config.Indexing.MaxFiles = 0
+			idx.config.Indexing.MaxChunks = 0
+			job, _ = idx.Index(repoDir, false, false)
+			if job.Status != models.IndexStatusCompleted {
+				t.Fatalf("Expected indexing without limits to succeed: %s", job.Error)
+			}
+			if stats := job.GetStats(); stats.FilesUnchanged != 0 {
+				t.Errorf("Expected no files cached by the aborted run, got %d unchanged", stats.FilesUnchanged)
+			}
+		})
+	}
+}
+
+func TestShutdown_StopsRunningIndex(t *testing.T) {
+	idx, store, embedder := newIncrementalTestIndexer(t)
+	idx.config.Indexing.Background = true
+	idx.config.Indexing.ChunkWorkers = 1
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{
+		"Alpha.java": "public class Alpha {\n    public void run() {\n        System.out.println(\"alpha\");\n    }\n}\n",
+		"Beta.java":  "public class Beta {\n    public void run() {\n        System.out.println(\"beta\");\n    }\n}\n",
+	})
+
+	// Hold the job in the middle of chunking until shutdown has been signalled
+	reading := make(chan struct{}, 2)
+	release := make(chan struct{})
+	idx.readFile = func(name string) ([]byte, error) {
+		reading <- struct{}{}
+		<-release
+		return os.ReadFile(name)
+	}
+
+	job, err := idx.Index(repoDir, false, false)
+	if err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	<-reading
+
+	shutdownDone := make(chan error, 1)
+	go func() { shutdownDone <- idx.Shutdown(context.Background()) }()
+	<-idx.stopping
+
+	if _, err := idx.Index(repoDir, false, false); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected ErrShuttingDown for a new job, got %v", err)
+	}
+	select {
+	case err := <-shutdownDone:
+		t.Fatalf("Expected shutdown to wait for the running job, returned %v", err)
+	default:
+	}
+
+	close(release)
+	if err := <-shutdownDone; err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	// The job stopped before embedding: nothing stored, nothing cached
+	if job.Status != models.IndexStatusCancelled {
+		t.Errorf("Expected the job to be cancelled, got %s (%s)", job.Status, job.Error)
+	}
+	if job.EndTime.IsZero() {
+		t.Error("Expected the job to have an end time")
+	}
+	if atomic.LoadInt64(&embedder.calls) != 0 || store.upserts != 0 {
+		t.Errorf("Expected nothing embedded or stored, got %d embeddings and %d upserts", embedder.calls, store.upserts)
+	}
+
+	// A restarted indexer processes every file again
+	idx.stopping = make(chan struct{})
+	idx.config.Indexing.Background = false
+	idx.readFile = os.ReadFile
+	job, err = idx.Index(repoDir, false, false)
+	if err != nil || job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Expected indexing after restart to succeed: %v %s", err, job.Error)
+	}
+	if stats := job.GetStats(); stats.FilesUnchanged != 0 || store.countByFile(filepath.Join(repoDir, "Beta.java")) == 0 {
+		t.Errorf("Expected both files to be reindexed, got %d unchanged", stats.FilesUnchanged)
+	}
+}
+
+func TestShutdown_TimesOut(t *testing.T) {
+	idx, _, _ := newIncrementalTestIndexer(t)
+	idx.config.Indexing.Background = true
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{"Alpha.java": "public class Alpha {}\n"})
+
+	release := make(chan struct{})
+	defer close(release)
+	reading := make(chan struct{}, 1)
+	idx.readFile = func(name string) ([]byte, error) {
+		reading <- struct{}{}
+		<-release
+		return os.ReadFile(name)
+	}
+
+	if _, err := idx.Index(repoDir, false, false); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	<-reading
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := idx.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a timeout while a job is stuck, got %v", err)
+	}
+}
+
+func TestRestoreHashCache(t *testing.T) {
+	idx, _, embedder := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{
+		"Service.java": "public class Service {\n    public void run() {}\n}\n",
+	})
+
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+	exported, err := idx.HashCache(repoDir)
+	if err != nil {
+		t.Fatalf("HashCache failed: %v", err)
+	}
+
+	// As on a machine the index was imported into: the chunks are stored but the cache is gone
+	if err := idx.ClearCache(repoDir); err != nil {
+		t.Fatalf("ClearCache failed: %v", err)
+	}
+	if err := idx.RestoreHashCache(exported); err != nil {
+		t.Fatalf("RestoreHashCache failed: %v", err)
+	}
+
+	embedded := len(embedder.texts)
+	if job, _ := idx.Index(repoDir, false, false); job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Reindexing failed: %s", job.Error)
+	}
+	if len(embedder.texts) != embedded {
+		t.Errorf("Expected the restored cache to skip unchanged files, got %d texts embedded", len(embedder.texts)-embedded)
+	}
+
+	if err := idx.RestoreHashCache(&models.FileHashCache{}); err == nil {
+		t.Error("Expected an error for a cache without a repository path")
+	}
+}
+
+func TestIndex_DryRunOnlyIndexer(t *testing.T) {
+	idx, _, _ := newIncrementalTestIndexer(t)
+	// Like an indexer created by NewDryRunIndexer
+	idx.vectorDB = nil
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{
+		"Service.java": "public class Service {\n    public void run() {}\n}\n",
+	})
+
+	if _, err := idx.Index(repoDir, false, false); !errors.Is(err, ErrDryRunOnly) {
+		t.Fatalf("Expected ErrDryRunOnly for a real run, got %v", err)
+	}
+
+	job, err := idx.Index(repoDir, false, true)
+	if err != nil {
+		t.Fatalf("Dry run failed to start: %v", err)
+	}
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Dry run failed: %s", job.Error)
+	}
+	if job.ChunksTotal == 0 {
+		t.Error("Expected chunk count to be reported")
+	}
+}
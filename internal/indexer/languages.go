@@ -2,6 +2,7 @@ package indexer
 
 import (
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/jamaly87/codebase-semantic-search/internal/models"
@@ -31,11 +32,40 @@ func NewLanguageDetector() *LanguageDetector {
 			Extensions: []string{".js", ".jsx", ".mjs", ".cjs"},
 			Parser:     "tree-sitter-javascript",
 		},
+		"kotlin": {
+			Name:       "kotlin",
+			Extensions: []string{".kt", ".kts"},
+			Parser:     "tree-sitter-kotlin",
+		},
+		"ruby": {
+			Name:       "ruby",
+			Extensions: []string{".rb"},
+			Parser:     "tree-sitter-ruby",
+		},
+		"php": {
+			Name:       "php",
+			Extensions: []string{".php"},
+			Parser:     "tree-sitter-php",
+		},
+		"swift": {
+			Name:       "swift",
+			Extensions: []string{".swift"},
+			Parser:     "tree-sitter-swift",
+		},
 		"go": {
 			Name:       "go",
 			Extensions: []string{".go"},
 			Parser:     "tree-sitter-go",
 		},
+		// Single-file components: <script> and <style> blocks are chunked as JS/TS/CSS
+		"vue": {
+			Name:       "vue",
+			Extensions: []string{".vue"},
+		},
+		"svelte": {
+			Name:       "svelte",
+			Extensions: []string{".svelte"},
+		},
 	}
 
 	// Build extension map
@@ -88,3 +118,36 @@ func (ld *LanguageDetector) GetAllLanguages() []*models.Language {
 	}
 	return langs
 }
+
+// LanguageSupportBuiltIn labels languages the detector recognizes without any configuration
+const LanguageSupportBuiltIn = "built-in"
+
+// LanguageSupport reports how the indexer handles a language
+type LanguageSupport struct {
+	Name       string   `json:"name"`
+	Extensions []string `json:"extensions"`
+	// ASTChunking is false when no Tree-sitter parser is initialized, so files are chunked by tokens
+	ASTChunking bool `json:"ast_chunking"`
+	// Support is LanguageSupportBuiltIn: detection doesn't depend on the config
+	Support string `json:"support"`
+	// BoundaryPatterns is true when chunking.boundary_patterns sets the language's chunk boundaries
+	BoundaryPatterns bool `json:"boundary_patterns,omitempty"`
+}
+
+// SupportedLanguages reports every detected language, sorted by name
+func (idx *Indexer) SupportedLanguages() []LanguageSupport {
+	langs := idx.chunker.langDetector.GetAllLanguages()
+	sort.Slice(langs, func(i, j int) bool { return langs[i].Name < langs[j].Name })
+
+	support := make([]LanguageSupport, 0, len(langs))
+	for _, lang := range langs {
+		support = append(support, LanguageSupport{
+			Name:             lang.Name,
+			Extensions:       lang.Extensions,
+			ASTChunking:      idx.chunker.astChunker != nil && idx.chunker.astChunker.CanParseLanguage(lang.Name),
+			Support:          LanguageSupportBuiltIn,
+			BoundaryPatterns: len(idx.config.Chunking.BoundaryPatterns[lang.Name]) > 0,
+		})
+	}
+	return support
+}
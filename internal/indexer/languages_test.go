@@ -0,0 +1,52 @@
+package indexer
+
+import "testing"
+
+func TestSupportedLanguages(t *testing.T) {
+	idx := newTestIndexer(t)
+
+	languages := idx.SupportedLanguages()
+	if len(languages) != len(idx.chunker.langDetector.GetAllLanguages()) {
+		t.Fatalf("Expected every detected language to be reported, got %+v", languages)
+	}
+
+	seen := make(map[string]bool)
+	for i, lang := range languages {
+		if i > 0 && languages[i-1].Name >= lang.Name {
+			t.Errorf("Expected languages sorted by name, got %s before %s", languages[i-1].Name, lang.Name)
+		}
+		seen[lang.Name] = true
+
+		// AST availability matches the parsers actually initialized
+		_, hasParser := idx.chunker.astChunker.parsers[lang.Name]
+		if lang.ASTChunking != hasParser {
+			t.Errorf("%s: expected ast_chunking=%v, got %v", lang.Name, hasParser, lang.ASTChunking)
+		}
+		if len(lang.Extensions) == 0 {
+			t.Errorf("%s: expected extensions", lang.Name)
+		}
+	}
+	for name := range idx.chunker.astChunker.parsers {
+		if !seen[name] {
+			t.Errorf("Expected %s, which has a parser, to be reported", name)
+		}
+	}
+
+	// Go is detected without a parser; every language is built in
+	for _, lang := range languages {
+		if lang.Support != LanguageSupportBuiltIn || lang.BoundaryPatterns {
+			t.Errorf("%s: expected a built-in language without boundary patterns, got %+v", lang.Name, lang)
+		}
+		if lang.Name == "go" && lang.ASTChunking {
+			t.Errorf("Expected go to fall back to token chunking, got %+v", lang)
+		}
+	}
+
+	// User-set boundary patterns are reported
+	idx.config.Chunking.BoundaryPatterns = map[string][]string{"go": {`^func `}}
+	for _, lang := range idx.SupportedLanguages() {
+		if lang.BoundaryPatterns != (lang.Name == "go") {
+			t.Errorf("%s: expected boundary_patterns=%v, got %v", lang.Name, lang.Name == "go", lang.BoundaryPatterns)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package indexer
+
+import "fmt"
+
+// Chunking strategies reported by a FilePreview
+const (
+	StrategyAST           = "ast"            // Tree-sitter chunks (functions, classes, methods)
+	StrategyToken         = "token"          // Token-based chunks; no parser for the language
+	StrategyTokenFallback = "token_fallback" // Token-based chunks after AST parsing failed
+)
+
+// ChunkPreview describes one chunk a file would be split into
+type ChunkPreview struct {
+	ChunkType    string `json:"chunk_type"`
+	StartLine    int    `json:"start_line"`
+	EndLine      int    `json:"end_line"`
+	FunctionName string `json:"function_name,omitempty"`
+	ClassName    string `json:"class_name,omitempty"`
+	Bytes        int    `json:"bytes"`
+	Tokens       int    `json:"tokens"` // Tokens of the text that would be embedded
+	Content      string `json:"content,omitempty"`
+}
+
+// FilePreview reports how a file would be chunked with the repository's settings
+type FilePreview struct {
+	FilePath      string         `json:"file_path"`
+	Language      string         `json:"language"`
+	Lines         int            `json:"lines"`
+	Strategy      string         `json:"strategy"`              // A Strategy* constant
+	MaxTokens     int            `json:"max_tokens"`            // Token limit for the file's size (adaptive chunking)
+	OverlapTokens int            `json:"overlap_tokens"`        // Token overlap between token-based chunks
+	SkipReason    string         `json:"skip_reason,omitempty"` // Why indexing would skip the file, if it would
+	Stats         map[string]int `json:"stats"`                 // Chunk counts, total and by type
+	Chunks        []ChunkPreview `json:"chunks"`
+}
+
+// PreviewChunks chunks a file as indexing would, without embedding, storing or caching anything
+// filePath may be absolute or relative to repoPath. Files indexing would skip are still chunked,
+// with the reason in SkipReason. Chunk content is only included with includeContent.
+func (idx *Indexer) PreviewChunks(repoPath, filePath string, includeContent bool) (*FilePreview, error) {
+	settings, err := idx.settingsFor(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation, err := settings.scanner.Explain(repoPath, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if explanation.Language == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFile, explanation.FilePath)
+	}
+
+	content, err := idx.readFile(explanation.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	chunker := settings.chunker
+	chunks, usedFallback, err := chunker.chunkContent(repoPath, explanation.FilePath, content)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := len(splitLines(normalizeLineEndings(string(content))))
+	maxTokens, overlapTokens := chunker.calculateOptimalChunkSize(lines)
+	preview := &FilePreview{
+		FilePath:      explanation.FilePath,
+		Language:      explanation.Language,
+		Lines:         lines,
+		Strategy:      StrategyToken,
+		MaxTokens:     maxTokens,
+		OverlapTokens: overlapTokens,
+		SkipReason:    explanation.SkipReason,
+		Stats:         chunker.GetStats(chunks),
+		Chunks:        make([]ChunkPreview, 0, len(chunks)),
+	}
+	switch {
+	case usedFallback:
+		preview.Strategy = StrategyTokenFallback
+	case chunker.astChunker != nil && chunker.astChunker.CanParseLanguage(explanation.Language):
+		preview.Strategy = StrategyAST
+	}
+
+	for _, chunk := range chunks {
+		chunkPreview := ChunkPreview{
+			ChunkType:    string(chunk.ChunkType),
+			StartLine:    chunk.StartLine,
+			EndLine:      chunk.EndLine,
+			FunctionName: chunk.FunctionName,
+			ClassName:    chunk.ClassName,
+			Bytes:        len(chunk.Content),
+		}
+		if chunker.tokenChunker != nil {
+			chunkPreview.Tokens = chunker.tokenChunker.countTokens(chunk.EmbeddingText())
+		}
+		if includeContent {
+			chunkPreview.Content = chunk.Content
+		}
+		preview.Chunks = append(preview.Chunks, chunkPreview)
+	}
+	return preview, nil
+}
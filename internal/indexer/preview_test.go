@@ -0,0 +1,130 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPreviewChunks_AST(t *testing.T) {
+	idx, store, embedder := newIncrementalTestIndexer(t)
+	tokenChunker, err := NewTokenChunkerWithEncoding(DefaultMaxTokens, DefaultOverlapTokens, ApproximateEncoding)
+	if err != nil {
+		t.Fatalf("Failed to create token chunker: %v", err)
+	}
+	idx.chunker.tokenChunker = tokenChunker
+	repoDir := t.TempDir()
+
+	content := "public class Service {\n    public void run() {\n        System.out.println(\"run\");\n    }\n\n    public void stop() {\n        System.out.println(\"stop\");\n    }\n}\n"
+	if err := os.WriteFile(filepath.Join(repoDir, "Service.java"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	preview, err := idx.PreviewChunks(repoDir, "Service.java", false)
+	if err != nil {
+		t.Fatalf("PreviewChunks failed: %v", err)
+	}
+	if preview.Strategy != StrategyAST || preview.Language != "java" || preview.Lines != 9 {
+		t.Errorf("Expected AST chunking of a 9-line java file, got %s, %s, %d lines", preview.Strategy, preview.Language, preview.Lines)
+	}
+	if len(preview.Chunks) != 3 || preview.Stats["total"] != 3 {
+		t.Fatalf("Expected the class and its 2 methods, got %+v", preview.Chunks)
+	}
+	var names []string
+	for _, chunk := range preview.Chunks {
+		if chunk.Content != "" {
+			t.Error("Expected no content without include_content")
+		}
+		if chunk.StartLine < 1 || chunk.EndLine < chunk.StartLine || chunk.Bytes == 0 || chunk.Tokens == 0 {
+			t.Errorf("Expected a line range, size and token count, got %+v", chunk)
+		}
+		names = append(names, chunk.ClassName+"."+chunk.FunctionName)
+	}
+	if got := strings.Join(names, " "); got != "Service. .run .stop" {
+		t.Errorf("Expected the class chunk then the run and stop methods, got %q", got)
+	}
+	if preview.Chunks[1].StartLine != 2 || preview.Chunks[1].EndLine != 4 {
+		t.Errorf("Expected run at lines 2-4, got %d-%d", preview.Chunks[1].StartLine, preview.Chunks[1].EndLine)
+	}
+
+	// Nothing is embedded, stored or cached
+	if store.upserts != 0 || atomic.LoadInt64(&embedder.calls) != 0 {
+		t.Errorf("Expected a preview not to embed or store chunks, got %d upserts and %d embedding calls", store.upserts, atomic.LoadInt64(&embedder.calls))
+	}
+	if _, ok := idx.hashManager.Lookup(filepath.Join(repoDir, "Service.java")); ok {
+		t.Error("Expected a preview not to update the hash cache")
+	}
+
+	preview, err = idx.PreviewChunks(repoDir, filepath.Join(repoDir, "Service.java"), true)
+	if err != nil {
+		t.Fatalf("PreviewChunks failed: %v", err)
+	}
+	if !strings.Contains(preview.Chunks[len(preview.Chunks)-1].Content, "System.out.println") {
+		t.Error("Expected chunk content with include_content")
+	}
+}
+
+func TestPreviewChunks_ReflectsSettings(t *testing.T) {
+	idx, _, _ := newIncrementalTestIndexer(t)
+	tokenChunker, err := NewTokenChunkerWithEncoding(DefaultMaxTokens, DefaultOverlapTokens, ApproximateEncoding)
+	if err != nil {
+		t.Fatalf("Failed to create token chunker: %v", err)
+	}
+	idx.chunker.tokenChunker = tokenChunker
+	idx.config.Chunking.SmallFileMaxTokens = 40
+	idx.config.Chunking.OverlapFraction = 0.25
+
+	repoDir := t.TempDir()
+	// Go files are chunked by tokens: there is no Go parser
+	var b strings.Builder
+	for i := 0; i < 30; i++ {
+		b.WriteString("value = compute(value) + offset * factor\n")
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "calc.go"), []byte(b.String()), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	preview, err := idx.PreviewChunks(repoDir, "calc.go", false)
+	if err != nil {
+		t.Fatalf("PreviewChunks failed: %v", err)
+	}
+	if preview.MaxTokens != 40 || preview.OverlapTokens != 10 {
+		t.Errorf("Expected the configured limits 40/10, got %d/%d", preview.MaxTokens, preview.OverlapTokens)
+	}
+	if preview.Strategy != StrategyToken || len(preview.Chunks) < 2 {
+		t.Fatalf("Expected several token-based chunks, got %s with %d chunks", preview.Strategy, len(preview.Chunks))
+	}
+	for _, chunk := range preview.Chunks {
+		// Chunks only exceed the limit when extended to a boundary, and this file has none
+		if chunk.Tokens == 0 || chunk.Tokens > preview.MaxTokens+tokenChunker.countTokens("value = compute(value) + offset * factor") {
+			t.Errorf("Expected chunks of about %d tokens, got %d (lines %d-%d)", preview.MaxTokens, chunk.Tokens, chunk.StartLine, chunk.EndLine)
+		}
+	}
+
+	// A larger limit yields fewer chunks
+	idx.config.Chunking.SmallFileMaxTokens = 200
+	larger, err := idx.PreviewChunks(repoDir, "calc.go", false)
+	if err != nil {
+		t.Fatalf("PreviewChunks failed: %v", err)
+	}
+	if len(larger.Chunks) >= len(preview.Chunks) {
+		t.Errorf("Expected fewer chunks with a larger token limit, got %d and %d", len(larger.Chunks), len(preview.Chunks))
+	}
+}
+
+func TestPreviewChunks_Errors(t *testing.T) {
+	idx, _, _ := newIncrementalTestIndexer(t)
+	repoDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoDir, "data.bin"), []byte{0, 1, 2}, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if _, err := idx.PreviewChunks(repoDir, "missing.java", false); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	if _, err := idx.PreviewChunks(repoDir, "data.bin", false); err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("Expected an unsupported file type error, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/embeddings"
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/internal/vectordb"
+)
+
+// reembedPageSize is how many stored chunks are embedded and upserted at a time when re-embedding
+const reembedPageSize = 256
+
+// ChunkScroller pages through the chunks stored in a collection
+type ChunkScroller interface {
+	ScrollByRepo(ctx context.Context, repoPath string, batch int, withVectors bool, visit func(chunk models.CodeChunk) bool) error
+}
+
+// ChunkUpserter stores embedded chunks
+type ChunkUpserter interface {
+	UpsertChunks(ctx context.Context, chunks []models.CodeChunk) error
+}
+
+// CollectionUpserter stores embedded chunks in a collection it can delete again
+type CollectionUpserter interface {
+	ChunkUpserter
+	DeleteCollection(ctx context.Context) error
+}
+
+// ReembedResult reports a re-embedding run
+type ReembedResult struct {
+	Model          string
+	Collection     string
+	VectorSize     int
+	ChunksEmbedded int // Chunks stored in the new collection
+	ChunksFailed   int // Chunks whose batch still failed after retries, missing from the new collection
+	Duration       time.Duration
+}
+
+// Reembed embeds the chunks stored for repoPath (every repository when empty) again with model,
+// and upserts them into collection, a new collection sized for the model's embeddings
+// Files are neither rescanned nor rechunked: the content stored with each chunk is embedded,
+// prepared as when indexing (e.g. with comments stripped). Searching with the new model
+// afterwards takes setting embeddings.model and vectordb.collection_name to the new values;
+// with collection_per_model, collection is suffixed the same way the configured name is.
+// A run that fails deletes the new collection again.
+func (idx *Indexer) Reembed(ctx context.Context, repoPath, model, collection string) (*ReembedResult, error) {
+	source, ok := idx.vectorDB.(ChunkScroller)
+	if !ok {
+		return nil, fmt.Errorf("the vector store can't list its chunks")
+	}
+	if collection == idx.config.VectorDB.CollectionName {
+		return nil, fmt.Errorf("collection %q is the current collection; re-embed into a new one", collection)
+	}
+	if repoPath != "" && idx.IsIndexing(repoPath) {
+		return nil, fmt.Errorf("%w for %s; re-embed once it finishes", ErrIndexingInProgress, repoPath)
+	}
+
+	// The current settings with the new model, sized by probing it
+	cfg := *idx.config
+	cfg.Embeddings.Model = model
+	cfg.Embeddings.AutoDetectDimension = true
+	cfg.VectorDB.CollectionName = collection
+	cfg.VectorDB.AutoCreateCollection = true // Creating the new collection is the point
+
+	client := embeddings.NewClient(&cfg.Embeddings)
+	client.SetTokenizer(idx.chunker.tokenChunker)
+	detected, err := client.ProbeDimension(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := embeddings.ReconcileDimensions(&cfg, detected); err != nil {
+		return nil, err
+	}
+	collection = cfg.ResolveCollectionName()
+	cfg.VectorDB.CollectionName = collection
+	if collection == idx.config.VectorDB.CollectionName {
+		return nil, fmt.Errorf("collection %q is the current collection; re-embed into a new one", collection)
+	}
+
+	target, err := vectordb.NewClient(&cfg.VectorDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vector DB client: %w", err)
+	}
+	defer target.Close()
+
+	exists, err := target.CollectionExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("collection %q already exists; choose a new name or delete it first", collection)
+	}
+	if err := target.Initialize(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create collection %q: %w", collection, err)
+	}
+
+	batcher := embeddings.NewBatcher(client, cfg.Embeddings.BatchSize, embeddingWorkers(&cfg.Indexing))
+	batcher.SetRetryPolicy(cfg.Embeddings.MaxRetries, time.Duration(cfg.Embeddings.RetryBackoffMs)*time.Millisecond)
+	_, documentPrefix := cfg.Embeddings.TaskPrefixes()
+	batcher.SetDocumentPrefix(documentPrefix)
+
+	slog.Info("Re-embedding stored chunks", "repo", repoPath, "model", model, "collection", collection, "dimensions", cfg.VectorDB.VectorSize)
+	result, err := idx.reembedInto(ctx, source, repoPath, batcher, target)
+	if result != nil {
+		result.Model = model
+		result.Collection = collection
+		result.VectorSize = cfg.VectorDB.VectorSize
+	}
+	return result, err
+}
+
+// reembedInto runs reembed into target, a collection created for the run, and deletes
+// target again when the run fails, so no partial collection is left behind
+func (idx *Indexer) reembedInto(ctx context.Context, source ChunkScroller, repoPath string, batcher *embeddings.Batcher, target CollectionUpserter) (*ReembedResult, error) {
+	result, err := idx.reembed(ctx, source, repoPath, batcher, target)
+	if err != nil {
+		// The run may have failed because ctx was cancelled
+		if dropErr := target.DeleteCollection(context.WithoutCancel(ctx)); dropErr != nil {
+			slog.Warn("Failed to delete the partially re-embedded collection", "error", dropErr)
+		}
+	}
+	return result, err
+}
+
+// reembed pages through the chunks stored in source, embeds them with batcher and upserts them
+// into target
+// Returns the partial result with the error when a page can't be stored or the indexer stops.
+func (idx *Indexer) reembed(ctx context.Context, source ChunkScroller, repoPath string, batcher *embeddings.Batcher, target ChunkUpserter) (*ReembedResult, error) {
+	start := time.Now()
+	result := &ReembedResult{}
+	chunkers := make(map[string]*Chunker) // Chunker with each repository's settings
+
+	var page []models.CodeChunk
+	var pageErr error
+	flush := func() {
+		for i := range page {
+			idx.reembedChunker(chunkers, page[i].RepoPath).prepareForEmbedding(page[i : i+1])
+		}
+		embedded, err := batcher.ProcessChunksPartial(page)
+		if err != nil {
+			pageErr = err
+			return
+		}
+		if err := target.UpsertChunks(ctx, embedded.Chunks); err != nil {
+			pageErr = fmt.Errorf("failed to store re-embedded chunks: %w", err)
+			return
+		}
+		result.ChunksEmbedded += len(embedded.Chunks)
+		result.ChunksFailed += len(embedded.FailedChunkIDs)
+		page = nil
+	}
+
+	err := source.ScrollByRepo(ctx, repoPath, reembedPageSize, false, func(chunk models.CodeChunk) bool {
+		switch {
+		case idx.isStopping():
+			pageErr = ErrShuttingDown
+		case ctx.Err() != nil:
+			pageErr = ctx.Err()
+		default:
+			page = append(page, chunk)
+			if len(page) >= reembedPageSize {
+				flush()
+			}
+		}
+		return pageErr == nil
+	})
+	if err == nil && pageErr == nil && len(page) > 0 {
+		flush()
+	}
+	result.Duration = time.Since(start)
+
+	if pageErr != nil {
+		return result, pageErr
+	}
+	if err != nil {
+		return result, err
+	}
+	slog.Info("Re-embedded stored chunks", "chunks", result.ChunksEmbedded, "failed", result.ChunksFailed, "duration", result.Duration)
+	return result, nil
+}
+
+// reembedChunker returns the chunker preparing a repository's chunks for embedding, falling back
+// to the global settings when the repository's can't be read (e.g. it was moved)
+func (idx *Indexer) reembedChunker(chunkers map[string]*Chunker, repoPath string) *Chunker {
+	if chunker, ok := chunkers[repoPath]; ok {
+		return chunker
+	}
+	chunker := idx.chunker
+	if settings, err := idx.settingsFor(repoPath); err == nil {
+		chunker = settings.chunker
+	} else {
+		slog.Debug("Using global chunking settings to re-embed", "repo", repoPath, "error", err)
+	}
+	chunkers[repoPath] = chunker
+	return chunker
+}
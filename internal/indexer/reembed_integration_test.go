@@ -0,0 +1,123 @@
+//go:build integration
+
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/embeddings"
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/internal/vectordb"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// sizedEmbeddings returns constant embeddings of a fixed dimension
+type sizedEmbeddings struct {
+	dimensions int
+}
+
+func (s *sizedEmbeddings) GenerateEmbedding(text string) ([]float32, error) {
+	vector := make([]float32, s.dimensions)
+	for i := range vector {
+		vector[i] = float32(len(text)%7+1) / float32(i+1)
+	}
+	return vector, nil
+}
+
+func (s *sizedEmbeddings) GenerateEmbeddings(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = s.GenerateEmbedding(text)
+	}
+	return vectors, nil
+}
+
+// newIntegrationCollection creates a collection of the given dimension in the local Qdrant,
+// skipping the test when Qdrant isn't reachable
+func newIntegrationCollection(t *testing.T, name string, dimensions int) *vectordb.Client {
+	t.Helper()
+	cfg := config.DefaultConfig().VectorDB
+	cfg.CollectionName = name
+	cfg.VectorSize = dimensions
+
+	client, err := vectordb.NewClient(&cfg)
+	if err != nil {
+		t.Skipf("Qdrant not available: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := client.CollectionExists(ctx); err != nil {
+		client.Close()
+		t.Skipf("Qdrant not available: %v", err)
+	}
+	if err := client.Initialize(ctx); err != nil {
+		client.Close()
+		t.Fatalf("Failed to create collection %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		client.DeleteCollection(context.Background())
+		client.Close()
+	})
+	return client
+}
+
+func TestReembed_IntoCollectionOfDifferentDimension(t *testing.T) {
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+	source := newIntegrationCollection(t, fmt.Sprintf("reembed_source_%d", suffix), 8)
+	target := newIntegrationCollection(t, fmt.Sprintf("reembed_target_%d", suffix), 4)
+
+	repoPath := t.TempDir()
+	oldEmbedder := &sizedEmbeddings{dimensions: 8}
+	var chunks []models.CodeChunk
+	for i := 0; i < 300; i++ {
+		content := fmt.Sprintf("func f%d() {}", i)
+		embedding, _ := oldEmbedder.GenerateEmbedding(content)
+		chunks = append(chunks, models.CodeChunk{
+			ID:        fmt.Sprintf("00000000-0000-0000-0000-%012d", i),
+			RepoPath:  repoPath,
+			FilePath:  repoPath + "/main.go",
+			Language:  "go",
+			ChunkType: models.ChunkTypeFunction,
+			Content:   content,
+			StartLine: i + 1,
+			EndLine:   i + 1,
+			Embedding: embedding,
+		})
+	}
+	if err := source.UpsertChunks(ctx, chunks); err != nil {
+		t.Fatalf("Failed to store source chunks: %v", err)
+	}
+
+	idx := newTestIndexer(t)
+	batcher := embeddings.NewBatcher(&sizedEmbeddings{dimensions: 4}, 32, 2)
+	result, err := idx.reembed(ctx, source, repoPath, batcher, target)
+	if err != nil {
+		t.Fatalf("reembed failed: %v", err)
+	}
+	if result.ChunksEmbedded != len(chunks) || result.ChunksFailed != 0 {
+		t.Errorf("Expected %d chunks re-embedded, got %d (%d failed)", len(chunks), result.ChunksEmbedded, result.ChunksFailed)
+	}
+
+	count, err := target.CountChunks(ctx, repoPath)
+	if err != nil {
+		t.Fatalf("Failed to count target chunks: %v", err)
+	}
+	if count != len(chunks) {
+		t.Errorf("Expected %d chunks in the new collection, got %d", len(chunks), count)
+	}
+	info, err := target.CollectionInfo(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read the new collection: %v", err)
+	}
+	if info.VectorSize != 4 {
+		t.Errorf("Expected the new collection to hold 4-dimension vectors, got %d", info.VectorSize)
+	}
+
+	// The source collection is left as it was
+	if count, _ := source.CountChunks(ctx, repoPath); count != len(chunks) {
+		t.Errorf("Expected the source collection to keep its %d chunks, got %d", len(chunks), count)
+	}
+}
@@ -0,0 +1,178 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/embeddings"
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// mockChunkSource pages through a fixed set of stored chunks like ScrollByRepo
+type mockChunkSource struct {
+	chunks []models.CodeChunk
+	pages  int
+}
+
+func (m *mockChunkSource) ScrollByRepo(ctx context.Context, repoPath string, batch int, withVectors bool, visit func(chunk models.CodeChunk) bool) error {
+	var matching []models.CodeChunk
+	for _, chunk := range m.chunks {
+		if repoPath == "" || chunk.RepoPath == repoPath {
+			matching = append(matching, chunk)
+		}
+	}
+	for start := 0; start < len(matching); start += batch {
+		m.pages++
+		for _, chunk := range matching[start:min(start+batch, len(matching))] {
+			if !visit(chunk) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// failingUpserter rejects every upsert
+type failingUpserter struct{}
+
+func (failingUpserter) UpsertChunks(ctx context.Context, chunks []models.CodeChunk) error {
+	return errors.New("qdrant unavailable")
+}
+
+// droppableUpserter records upserts into a collection and whether it was deleted
+type droppableUpserter struct {
+	ChunkUpserter
+	deleted bool
+}
+
+func (d *droppableUpserter) DeleteCollection(ctx context.Context) error {
+	d.deleted = true
+	return nil
+}
+
+func newReembedTestSource(repoA, repoB string) *mockChunkSource {
+	source := &mockChunkSource{}
+	for i := 0; i < 300; i++ {
+		content := fmt.Sprintf("func f%d() {}", i)
+		switch i {
+		case 0:
+			content = "// explains f0\nfunc f0() {}"
+		case 150:
+			content = "func FAIL_ME() {}"
+		}
+		source.chunks = append(source.chunks, models.CodeChunk{
+			ID: fmt.Sprintf("a%d", i), RepoPath: repoA, FilePath: repoA + "/a.go", Language: "go", Content: content,
+			Embedding: []float32{9, 9, 9, 9, 9},
+		})
+	}
+	source.chunks = append(source.chunks,
+		models.CodeChunk{ID: "b0", RepoPath: repoB, FilePath: repoB + "/b.go", Language: "go", Content: "func b0() {}"},
+		models.CodeChunk{ID: "b1", RepoPath: repoB, FilePath: repoB + "/b.go", Language: "go", Content: "func b1() {}"},
+	)
+	return source
+}
+
+func TestReembed_EmbedsStoredChunks(t *testing.T) {
+	idx := newTestIndexer(t)
+	idx.config.Chunking.StripComments = true
+	repoA, repoB := t.TempDir(), t.TempDir()
+	source := newReembedTestSource(repoA, repoB)
+	embedder := &mockEmbeddings{failOn: "FAIL_ME"}
+	target := newMockVectorStore()
+
+	result, err := idx.reembed(context.Background(), source, repoA, embeddings.NewBatcher(embedder, 10, 2), target)
+	if err != nil {
+		t.Fatalf("reembed failed: %v", err)
+	}
+
+	// The batch of 10 holding FAIL_ME fails; the others are stored
+	if result.ChunksEmbedded != 290 || result.ChunksFailed != 10 {
+		t.Errorf("Expected 290 chunks re-embedded and 10 failed, got %d and %d", result.ChunksEmbedded, result.ChunksFailed)
+	}
+	if source.pages != 2 {
+		t.Errorf("Expected the 300 chunks to be read in 2 pages, got %d", source.pages)
+	}
+	if len(target.chunks) != 290 || target.countByFile(repoB+"/b.go") != 0 {
+		t.Fatalf("Expected only the repository's 290 embedded chunks to be stored, got %d", len(target.chunks))
+	}
+	for id, chunk := range target.chunks {
+		if len(chunk.Embedding) != 3 {
+			t.Errorf("Expected chunk %s to carry the new 3-dimension embedding, got %v", id, chunk.Embedding)
+			break
+		}
+	}
+	if stored := target.chunks["a0"]; stored.Content != "// explains f0\nfunc f0() {}" || stored.FilePath != repoA+"/a.go" {
+		t.Errorf("Expected the stored payload to be kept, got %+v", stored)
+	}
+
+	// Content is prepared as when indexing
+	for _, text := range embedder.texts {
+		if strings.Contains(text, "explains f0") {
+			t.Errorf("Expected comments to be stripped before embedding, got %q", text)
+		}
+	}
+}
+
+func TestReembed_AllRepositories(t *testing.T) {
+	idx := newTestIndexer(t)
+	source := newReembedTestSource(t.TempDir(), t.TempDir())
+	target := newMockVectorStore()
+
+	result, err := idx.reembed(context.Background(), source, "", embeddings.NewBatcher(&mockEmbeddings{}, 50, 1), target)
+	if err != nil {
+		t.Fatalf("reembed failed: %v", err)
+	}
+	if result.ChunksEmbedded != 302 || len(target.chunks) != 302 {
+		t.Errorf("Expected every repository's 302 chunks, got %d stored", len(target.chunks))
+	}
+}
+
+func TestReembed_StopsWhenStoreFails(t *testing.T) {
+	idx := newTestIndexer(t)
+	source := newReembedTestSource(t.TempDir(), t.TempDir())
+
+	result, err := idx.reembed(context.Background(), source, "", embeddings.NewBatcher(&mockEmbeddings{}, 50, 1), failingUpserter{})
+	if err == nil || !strings.Contains(err.Error(), "qdrant unavailable") {
+		t.Fatalf("Expected the store error, got %v", err)
+	}
+	if result.ChunksEmbedded != 0 || source.pages != 1 {
+		t.Errorf("Expected re-embedding to stop at the first page, got %d chunks over %d pages", result.ChunksEmbedded, source.pages)
+	}
+}
+
+func TestReembed_DeletesTargetOnFailure(t *testing.T) {
+	idx := newTestIndexer(t)
+	source := newReembedTestSource(t.TempDir(), t.TempDir())
+
+	failed := &droppableUpserter{ChunkUpserter: failingUpserter{}}
+	if _, err := idx.reembedInto(context.Background(), source, "", embeddings.NewBatcher(&mockEmbeddings{}, 50, 1), failed); err == nil {
+		t.Fatal("Expected the store error")
+	}
+	if !failed.deleted {
+		t.Error("Expected the partial collection to be deleted")
+	}
+
+	stored := &droppableUpserter{ChunkUpserter: newMockVectorStore()}
+	if _, err := idx.reembedInto(context.Background(), source, "", embeddings.NewBatcher(&mockEmbeddings{}, 50, 1), stored); err != nil {
+		t.Fatalf("reembedInto failed: %v", err)
+	}
+	if stored.deleted {
+		t.Error("Expected the completed collection to be kept")
+	}
+}
+
+func TestReembed_RejectsCurrentCollection(t *testing.T) {
+	idx := newTestIndexer(t)
+	idx.vectorDB = struct {
+		*mockVectorStore
+		*mockChunkSource
+	}{newMockVectorStore(), &mockChunkSource{}}
+
+	_, err := idx.Reembed(context.Background(), "", "mxbai-embed-large", idx.config.VectorDB.CollectionName)
+	if err == nil || !strings.Contains(err.Error(), "current collection") {
+		t.Errorf("Expected re-embedding into the current collection to be rejected, got %v", err)
+	}
+}
@@ -1,11 +1,13 @@
 package indexer
 
 import (
+	"bytes"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/jamaly87/codebase-semantic-search/pkg/config"
 	"github.com/jamaly87/codebase-semantic-search/pkg/ignore"
@@ -17,6 +19,7 @@ type Scanner struct {
 	ignoreMatcher   *ignore.Matcher
 	langDetector    *LanguageDetector
 	maxFileSizeBytes int64
+	unsupportedFiles string // A chunking.unsupported_files mode
 }
 
 // NewScanner creates a new file scanner
@@ -29,17 +32,66 @@ func NewScanner(cfg *config.IndexingConfig, ignorePatterns []string) *Scanner {
 	}
 }
 
+// SetIgnoreCaseInsensitive makes ignore patterns match paths regardless of letter case
+func (s *Scanner) SetIgnoreCaseInsensitive(caseInsensitive bool) {
+	s.ignoreMatcher.SetCaseInsensitive(caseInsensitive)
+}
+
+// SetUnsupportedFiles sets what scans do with files of unsupported extensions
+// (an UnsupportedFiles* mode; empty reports them)
+func (s *Scanner) SetUnsupportedFiles(mode string) {
+	s.unsupportedFiles = mode
+}
+
+// Skip reasons reported in ScanResult.SkipReasons
+const (
+	SkipReasonIgnored      = "ignored"
+	SkipReasonUnsupported  = "unsupported_language"
+	SkipReasonStatError    = "stat_error"
+	SkipReasonTooLarge     = "too_large"
+	SkipReasonTooFewLines  = "too_few_lines"
+	SkipReasonTooManyLines = "too_many_lines"
+	SkipReasonReadError    = "read_error"
+	SkipReasonTooDeep      = "too_deep"
+	SkipReasonGenerated    = "generated"
+)
+
 // ScanResult contains the results of a directory scan
 type ScanResult struct {
 	Files      []string          // List of file paths to index
 	TotalFiles int               // Total files found
 	SkippedFiles int             // Files skipped (too large, ignored, etc.)
+	SkipReasons map[string]int   // Count of skipped files per reason
 	Languages  map[string]int    // Count of files per language
 	Errors     []error           // Errors encountered during scan
+	// Files of unchanged directories, taken from the cache without being read (also in Files)
+	UnchangedFiles []string
+	DirMtimes      map[string]time.Time // Modification time of every scanned directory
+	// Directories not descended into because they are deeper than Indexing.MaxDepth
+	DepthLimitedDirs int
+}
+
+// DirCache lets a scan reuse the indexed files of directories unchanged since the last index
+type DirCache interface {
+	// UnchangedFiles returns the files indexed directly in dir, or false if dir's mtime changed
+	UnchangedFiles(dir string, mtime time.Time) ([]string, bool)
+}
+
+// skip records a skipped file with the reason it was skipped
+func (r *ScanResult) skip(reason string) {
+	r.SkippedFiles++
+	r.SkipReasons[reason]++
 }
 
 // Scan scans a repository directory for indexable files
 func (s *Scanner) Scan(repoPath string) (*ScanResult, error) {
+	return s.ScanIncremental(repoPath, nil)
+}
+
+// ScanIncremental scans like Scan, but lists the files of directories that dirCache reports
+// as unchanged from the cache instead of reading them. Subdirectories are still visited,
+// since a directory's mtime does not change when something below it does.
+func (s *Scanner) ScanIncremental(repoPath string, dirCache DirCache) (*ScanResult, error) {
 	// Verify directory exists
 	info, err := os.Stat(repoPath)
 	if err != nil {
@@ -50,10 +102,13 @@ func (s *Scanner) Scan(repoPath string) (*ScanResult, error) {
 	}
 
 	result := &ScanResult{
-		Files:     make([]string, 0),
-		Languages: make(map[string]int),
-		Errors:    make([]error, 0),
+		Files:       make([]string, 0),
+		SkipReasons: make(map[string]int),
+		Languages:   make(map[string]int),
+		Errors:      make([]error, 0),
+		DirMtimes:   make(map[string]time.Time),
 	}
+	reusedDirs := make(map[string]bool)
 
 	// Walk the directory tree
 	err = filepath.WalkDir(repoPath, func(path string, d fs.DirEntry, err error) error {
@@ -73,20 +128,37 @@ func (s *Scanner) Scan(repoPath string) (*ScanResult, error) {
 			if s.shouldIgnoreDir(relPath, d.Name()) {
 				return fs.SkipDir
 			}
+			if s.tooDeep(relPath) {
+				result.DepthLimitedDirs++
+				return fs.SkipDir
+			}
+			if s.reuseUnchangedDir(result, repoPath, path, d, dirCache) {
+				reusedDirs[path] = true
+			}
+			return nil
+		}
+
+		// Files of unchanged directories were already listed from the cache
+		if reusedDirs[filepath.Dir(path)] {
 			return nil
 		}
 
 		// Skip files that match ignore patterns
 		if s.ignoreMatcher.ShouldIgnore(relPath) {
-			result.SkippedFiles++
+			result.skip(SkipReasonIgnored)
+			return nil
+		}
+
+		// Check if file is supported language
+		language, supported := s.languageOf(path)
+		if !supported && s.unsupportedFiles == UnsupportedFilesSkip {
 			return nil
 		}
 
 		result.TotalFiles++
 
-		// Check if file is supported language
-		if !s.langDetector.IsSupported(path) {
-			result.SkippedFiles++
+		if !supported {
+			result.skip(SkipReasonUnsupported)
 			return nil
 		}
 
@@ -94,22 +166,52 @@ func (s *Scanner) Scan(repoPath string) (*ScanResult, error) {
 		fileInfo, err := d.Info()
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Errorf("failed to get file info for %s: %w", path, err))
-			result.SkippedFiles++
+			result.skip(SkipReasonStatError)
 			return nil
 		}
 
 		if fileInfo.Size() > s.maxFileSizeBytes {
-			result.SkippedFiles++
+			result.skip(SkipReasonTooLarge)
 			return nil
 		}
 
+		// Check line count (only read the file when a line filter is configured)
+		if s.config.MinLines > 0 || s.config.MaxLines > 0 {
+			lines, err := countLines(path)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("failed to count lines in %s: %w", path, err))
+				result.skip(SkipReasonReadError)
+				return nil
+			}
+			if s.config.MinLines > 0 && lines < s.config.MinLines {
+				result.skip(SkipReasonTooFewLines)
+				return nil
+			}
+			if s.config.MaxLines > 0 && lines > s.config.MaxLines {
+				result.skip(SkipReasonTooManyLines)
+				return nil
+			}
+		}
+
+		// Check for a generated-code header (only read the file when generated files are skipped)
+		if s.skipsGenerated() {
+			generated, err := fileIsGenerated(path, s.config.GeneratedMarkers)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("failed to read %s: %w", path, err))
+				result.skip(SkipReasonReadError)
+				return nil
+			}
+			if generated {
+				result.skip(SkipReasonGenerated)
+				return nil
+			}
+		}
+
 		// Add to results
 		result.Files = append(result.Files, path)
 
 		// Track language stats
-		if lang, ok := s.langDetector.Detect(path); ok {
-			result.Languages[lang.Name]++
-		}
+		result.Languages[language]++
 
 		return nil
 	})
@@ -121,6 +223,69 @@ func (s *Scanner) Scan(repoPath string) (*ScanResult, error) {
 	return result, nil
 }
 
+// skipsGenerated reports whether files marked generated are left out of scans
+func (s *Scanner) skipsGenerated() bool {
+	return s.config.SkipGenerated && len(s.config.GeneratedMarkers) > 0
+}
+
+// reuseUnchangedDir records a directory's mtime and, if dirCache reports it unchanged,
+// adds its cached files to the result. Returns true if the files were reused.
+func (s *Scanner) reuseUnchangedDir(result *ScanResult, repoPath, dir string, d fs.DirEntry, dirCache DirCache) bool {
+	info, err := d.Info()
+	if err != nil {
+		return false
+	}
+	result.DirMtimes[dir] = info.ModTime()
+
+	if dirCache == nil {
+		return false
+	}
+	files, ok := dirCache.UnchangedFiles(dir, info.ModTime())
+	if !ok {
+		return false
+	}
+
+	for _, path := range files {
+		// Ignore patterns or languages may have changed since the files were indexed
+		relPath, err := filepath.Rel(repoPath, path)
+		if err != nil || s.ignoreMatcher.ShouldIgnore(relPath) {
+			continue
+		}
+		// Cached files of unsupported extensions were indexed as text; they aren't read again
+		language := plainText.Name
+		if lang, ok := s.langDetector.Detect(path); ok {
+			language = lang.Name
+		} else if s.unsupportedFiles != UnsupportedFilesText {
+			continue
+		}
+
+		result.TotalFiles++
+		result.Files = append(result.Files, path)
+		result.UnchangedFiles = append(result.UnchangedFiles, path)
+		result.Languages[language]++
+	}
+	return true
+}
+
+// countLines returns the number of lines in a file
+// A trailing line without a newline is counted; an empty file has zero lines
+func countLines(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(content) == 0 {
+		return 0, nil
+	}
+
+	lines := bytes.Count(content, []byte("\n"))
+	if content[len(content)-1] != '\n' {
+		lines++
+	}
+	return lines, nil
+}
+
 // shouldIgnoreDir returns true if a directory should be ignored
 func (s *Scanner) shouldIgnoreDir(relPath, dirName string) bool {
 	// Always skip hidden directories
@@ -132,7 +297,38 @@ func (s *Scanner) shouldIgnoreDir(relPath, dirName string) bool {
 	return s.ignoreMatcher.ShouldIgnore(relPath)
 }
 
+// tooDeep reports whether a directory is more than Indexing.MaxDepth levels below the repository root
+// The root itself is level 0, so files directly in it are always scanned.
+func (s *Scanner) tooDeep(relDir string) bool {
+	return s.config.MaxDepth > 0 && dirDepth(relDir) > s.config.MaxDepth
+}
+
+// dirDepth returns how many levels a directory, relative to the repository root, is below it
+func dirDepth(relDir string) int {
+	if relDir == "." || relDir == "" {
+		return 0
+	}
+	return strings.Count(filepath.ToSlash(relDir), "/") + 1
+}
+
 // IsSupported returns true if the file is a supported language
 func (s *Scanner) IsSupported(filePath string) bool {
-	return s.langDetector.IsSupported(filePath)
+	_, ok := s.languageOf(filePath)
+	return ok
+}
+
+// languageOf returns the language a file is indexed as
+// Files of unsupported extensions are indexed as plain text in the "text" mode of
+// chunking.unsupported_files, if their start has no binary content.
+func (s *Scanner) languageOf(path string) (string, bool) {
+	if lang, ok := s.langDetector.Detect(path); ok {
+		return lang.Name, true
+	}
+	if s.unsupportedFiles != UnsupportedFilesText {
+		return "", false
+	}
+	if text, err := fileIsText(path); err != nil || !text {
+		return "", false
+	}
+	return plainText.Name, true
 }
@@ -3,7 +3,9 @@ package indexer
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jamaly87/codebase-semantic-search/pkg/config"
 	"github.com/jamaly87/codebase-semantic-search/pkg/ignore"
@@ -169,6 +171,86 @@ func TestFileSizeLimit(t *testing.T) {
 	}
 }
 
+func TestLineCountLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	oneLineFile := filepath.Join(tmpDir, "index.ts")
+	normalFile := filepath.Join(tmpDir, "service.ts")
+	hugeFile := filepath.Join(tmpDir, "generated.ts")
+
+	files := map[string]string{
+		oneLineFile: "export * from './service';",
+		normalFile:  strings.Repeat("const x = 1;\n", 50),
+		hugeFile:    strings.Repeat("const y = 2;\n", 5000),
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		minLines      int
+		maxLines      int
+		expectedFiles []string
+		expectReasons map[string]int
+	}{
+		{
+			name:          "no limits keeps everything",
+			expectedFiles: []string{oneLineFile, normalFile, hugeFile},
+			expectReasons: map[string]int{},
+		},
+		{
+			name:          "min and max lines",
+			minLines:      2,
+			maxLines:      1000,
+			expectedFiles: []string{normalFile},
+			expectReasons: map[string]int{
+				SkipReasonTooFewLines:  1,
+				SkipReasonTooManyLines: 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.IndexingConfig{
+				MaxFileSizeMB: 1,
+				MinLines:      tt.minLines,
+				MaxLines:      tt.maxLines,
+			}
+
+			result, err := NewScanner(cfg, []string{}).Scan(tmpDir)
+			if err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+
+			if len(result.Files) != len(tt.expectedFiles) {
+				t.Fatalf("Expected %d files, got %d: %v", len(tt.expectedFiles), len(result.Files), result.Files)
+			}
+			found := make(map[string]bool)
+			for _, f := range result.Files {
+				found[f] = true
+			}
+			for _, f := range tt.expectedFiles {
+				if !found[f] {
+					t.Errorf("Expected %s to be scanned", f)
+				}
+			}
+
+			for reason, count := range tt.expectReasons {
+				if result.SkipReasons[reason] != count {
+					t.Errorf("Expected %d files skipped for %s, got %d", count, reason, result.SkipReasons[reason])
+				}
+			}
+			if result.SkippedFiles != len(files)-len(tt.expectedFiles) {
+				t.Errorf("Expected %d skipped files, got %d", len(files)-len(tt.expectedFiles), result.SkippedFiles)
+			}
+		})
+	}
+}
+
 func TestSupportedExtensions(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -181,6 +263,11 @@ func TestSupportedExtensions(t *testing.T) {
 		"test.jsx":   true,  // Supported
 		"test.mjs":   true,  // Supported
 		"test.go":    true,  // Supported (added)
+		"test.kt":    true,  // Supported
+		"test.kts":   true,  // Supported
+		"test.rb":    true,  // Supported
+		"test.php":   true,  // Supported
+		"test.swift": true,  // Supported
 		"test.py":    false, // Not supported (yet)
 		"test.txt":   false, // Not supported
 		"test.md":    false, // Not supported
@@ -221,6 +308,59 @@ func TestSupportedExtensions(t *testing.T) {
 	}
 }
 
+func TestSupportedLanguages_Kotlin(t *testing.T) {
+	detector := NewLanguageDetector()
+
+	for _, path := range []string{"app/src/main/Main.kt", "build.gradle.kts", "Upper.KT"} {
+		lang, ok := detector.Detect(path)
+		if !ok || lang.Name != "kotlin" {
+			t.Errorf("Expected %s to be detected as kotlin, got %v", path, lang)
+		}
+	}
+}
+
+func TestSupportedLanguages_Ruby(t *testing.T) {
+	detector := NewLanguageDetector()
+
+	for _, path := range []string{"app/models/user.rb", "Upper.RB"} {
+		lang, ok := detector.Detect(path)
+		if !ok || lang.Name != "ruby" {
+			t.Errorf("Expected %s to be detected as ruby, got %v", path, lang)
+		}
+	}
+	if _, ok := detector.Detect("Gemfile"); ok {
+		t.Error("Expected files without an extension to stay unsupported")
+	}
+}
+
+func TestSupportedLanguages_PHP(t *testing.T) {
+	detector := NewLanguageDetector()
+
+	for _, path := range []string{"app/Models/User.php", "resources/views/home.blade.php", "Upper.PHP"} {
+		lang, ok := detector.Detect(path)
+		if !ok || lang.Name != "php" {
+			t.Errorf("Expected %s to be detected as php, got %v", path, lang)
+		}
+	}
+	if _, ok := detector.Detect("composer.json"); ok {
+		t.Error("Expected composer.json to stay unsupported")
+	}
+}
+
+func TestSupportedLanguages_Swift(t *testing.T) {
+	detector := NewLanguageDetector()
+
+	for _, path := range []string{"App/ContentView.swift", "Package.swift", "Upper.SWIFT"} {
+		lang, ok := detector.Detect(path)
+		if !ok || lang.Name != "swift" {
+			t.Errorf("Expected %s to be detected as swift, got %v", path, lang)
+		}
+	}
+	if _, ok := detector.Detect("Info.plist"); ok {
+		t.Error("Expected Info.plist to stay unsupported")
+	}
+}
+
 func TestEmptyRepository(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -280,6 +420,63 @@ func TestNestedDirectories(t *testing.T) {
 	}
 }
 
+func TestMaxDepth(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestFiles(t, tmpDir, map[string]string{
+		"root.java":            "class Root {}",
+		"a/one.java":           "class One {}",
+		"a/b/two.java":         "class Two {}",
+		"a/b/c/three.java":     "class Three {}",
+		"x/y/z/w/deepest.java": "class Deepest {}",
+	})
+
+	cfg := &config.IndexingConfig{MaxFileSizeMB: 1, MaxDepth: 2}
+	scanner := NewScanner(cfg, []string{})
+
+	result, err := scanner.Scan(tmpDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, file := range result.Files {
+		rel, _ := filepath.Rel(tmpDir, file)
+		found[filepath.ToSlash(rel)] = true
+	}
+	for _, path := range []string{"root.java", "a/one.java", "a/b/two.java"} {
+		if !found[path] {
+			t.Errorf("Expected %s within the depth limit to be found", path)
+		}
+	}
+	for _, path := range []string{"a/b/c/three.java", "x/y/z/w/deepest.java"} {
+		if found[path] {
+			t.Errorf("Expected %s beyond the depth limit to be excluded", path)
+		}
+	}
+	// a/b/c and x/y/z are skipped; nothing below them is visited
+	if result.DepthLimitedDirs != 2 {
+		t.Errorf("Expected 2 depth-limited directories, got %d", result.DepthLimitedDirs)
+	}
+
+	explanation, err := scanner.Explain(tmpDir, "a/b/c/three.java")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if explanation.SkipReason != SkipReasonTooDeep {
+		t.Errorf("Expected skip reason %q, got %q", SkipReasonTooDeep, explanation.SkipReason)
+	}
+
+	// Unlimited by default
+	cfg.MaxDepth = 0
+	result, err = scanner.Scan(tmpDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Files) != 5 || result.DepthLimitedDirs != 0 {
+		t.Errorf("Expected all 5 files without a depth limit, got %d (%d dirs skipped)", len(result.Files), result.DepthLimitedDirs)
+	}
+}
+
 func TestIgnoreMatcher(t *testing.T) {
 	patterns := []string{
 		"node_modules/**",
@@ -312,9 +509,98 @@ func TestIgnoreMatcher(t *testing.T) {
 	}
 }
 
+func TestIgnoreCaseInsensitive(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestFiles(t, tmpDir, map[string]string{
+		"src/Main.java":         "public class Main {\n}\n",
+		"Node_Modules/Lib.java": "public class Lib {\n}\n",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Ignore.Patterns = []string{"node_modules/**"}
+
+	for _, caseInsensitive := range []bool{false, true} {
+		cfg.Ignore.CaseInsensitive = caseInsensitive
+		result, err := newConfiguredScanner(cfg).Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+
+		expected := 2
+		if caseInsensitive {
+			expected = 1
+		}
+		if len(result.Files) != expected {
+			t.Errorf("case_insensitive=%v: expected %d files, got %v", caseInsensitive, expected, result.Files)
+		}
+	}
+}
+
 // Helper function to check if a file path contains a directory name
 func pathContainsDir(s, substr string) bool {
 	return filepath.Base(filepath.Dir(s)) == substr ||
 		filepath.Base(s) == substr ||
 		len(filepath.SplitList(s)) > 0 && filepath.SplitList(s)[0] == substr
 }
+
+// fakeDirCache reports the directories in files as unchanged, with the given cached files
+type fakeDirCache struct {
+	files map[string][]string
+}
+
+func (f fakeDirCache) UnchangedFiles(dir string, mtime time.Time) ([]string, bool) {
+	files, ok := f.files[dir]
+	return files, ok
+}
+
+func TestScanIncremental_ReusesUnchangedDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, path := range []string{"cached/A.java", "cached/OnDiskOnly.java", "cached/sub/Sub.java", "fresh/F.java"} {
+		fullPath := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte("public class X {}"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	cachedDir := filepath.Join(tmpDir, "cached")
+	dirCache := fakeDirCache{files: map[string][]string{
+		cachedDir: {
+			filepath.Join(cachedDir, "A.java"),
+			filepath.Join(cachedDir, "Model.gen.java"), // Ignored since it was indexed
+		},
+	}}
+
+	scanner := NewScanner(&config.IndexingConfig{MaxFileSizeMB: 1}, []string{"*.gen.java"})
+	result, err := scanner.ScanIncremental(tmpDir, dirCache)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	var rel []string
+	for _, path := range result.Files {
+		r, _ := filepath.Rel(tmpDir, path)
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	got := strings.Join(rel, ",")
+	// The unchanged directory is listed from the cache, not read; its subdirectory is still scanned
+	for _, want := range []string{"cached/A.java", "cached/sub/Sub.java", "fresh/F.java"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %s in scan result, got %s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"OnDiskOnly.java", "Model.gen.java"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Expected %s to be left out, got %s", unwanted, got)
+		}
+	}
+
+	if len(result.UnchangedFiles) != 1 || result.UnchangedFiles[0] != filepath.Join(cachedDir, "A.java") {
+		t.Errorf("Expected only cached/A.java reported as unchanged, got %v", result.UnchangedFiles)
+	}
+	if _, ok := result.DirMtimes[filepath.Join(tmpDir, "fresh")]; !ok {
+		t.Error("Expected directory mtimes to be recorded")
+	}
+}
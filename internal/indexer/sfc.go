@@ -0,0 +1,177 @@
+package indexer
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// sfcLanguages are single-file component formats whose <script> and <style> blocks are
+// chunked in their own language
+var sfcLanguages = map[string]bool{
+	"vue":    true,
+	"svelte": true,
+}
+
+// sfcOpenTag matches an opening <script> or <style> tag and captures its name and attributes
+var sfcOpenTag = regexp.MustCompile(`(?i)<(script|style)\b([^>]*)>`)
+
+// sfcLangAttr captures the value of a lang="..." (or type="...") attribute
+var sfcLangAttr = regexp.MustCompile(`(?i)\b(?:lang|type)\s*=\s*["']?([\w/+-]+)`)
+
+// embeddedRegion is a block of one language inside a file of another, such as a <script> in a .vue file
+type embeddedRegion struct {
+	language  string
+	startLine int // 1-based file line of the region's first line
+	content   string
+	// tagStart and tagEnd are the byte offsets of the whole block, tags included
+	tagStart, tagEnd int
+}
+
+// extractEmbeddedRegions returns the <script> and <style> blocks of a single-file component
+// A block's content starts right after its opening tag, so its line 1 is the tag's line.
+// Unterminated blocks are ignored.
+func extractEmbeddedRegions(content string) []embeddedRegion {
+	var regions []embeddedRegion
+	lower := strings.ToLower(content)
+
+	for offset := 0; offset < len(content); {
+		loc := sfcOpenTag.FindStringSubmatchIndex(content[offset:])
+		if loc == nil {
+			break
+		}
+		tag := strings.ToLower(content[offset+loc[2] : offset+loc[3]])
+		attrs := content[offset+loc[4] : offset+loc[5]]
+		bodyStart := offset + loc[1]
+
+		closeRel := strings.Index(lower[bodyStart:], "</"+tag)
+		if closeRel < 0 {
+			break
+		}
+		bodyEnd := bodyStart + closeRel
+		tagEnd := len(content)
+		if end := strings.IndexByte(content[bodyEnd:], '>'); end >= 0 {
+			tagEnd = bodyEnd + end + 1
+		}
+
+		if body := content[bodyStart:bodyEnd]; strings.TrimSpace(body) != "" {
+			regions = append(regions, embeddedRegion{
+				language:  embeddedLanguage(tag, attrs),
+				startLine: strings.Count(content[:bodyStart], "\n") + 1,
+				content:   body,
+				tagStart:  offset + loc[0],
+				tagEnd:    tagEnd,
+			})
+		}
+		offset = tagEnd
+	}
+
+	return regions
+}
+
+// embeddedLanguage maps a <script> or <style> tag's lang attribute to a language name
+func embeddedLanguage(tag, attrs string) string {
+	lang := ""
+	if m := sfcLangAttr.FindStringSubmatch(attrs); m != nil {
+		lang = strings.ToLower(m[1])
+	}
+
+	if tag == "style" {
+		switch lang {
+		case "", "css", "text/css", "postcss":
+			return "css"
+		default:
+			return lang // scss, less, stylus, ...
+		}
+	}
+
+	switch lang {
+	case "ts", "tsx", "typescript", "text/typescript":
+		return "typescript"
+	default:
+		return "javascript"
+	}
+}
+
+// maskEmbeddedRegions blanks out the regions' blocks, tags included, keeping newlines so the
+// remaining markup stays on its original lines
+func maskEmbeddedRegions(content string, regions []embeddedRegion) string {
+	var out strings.Builder
+	out.Grow(len(content))
+	last := 0
+	for _, region := range regions {
+		out.WriteString(content[last:region.tagStart])
+		out.WriteString(strings.Repeat("\n", strings.Count(content[region.tagStart:region.tagEnd], "\n")))
+		last = region.tagEnd
+	}
+	out.WriteString(content[last:])
+	return out.String()
+}
+
+// relocateChunks moves chunks of a region's content to their file lines and tags them with the
+// region's language, regenerating IDs (and parent links) since those depend on the lines
+func relocateChunks(chunks []models.CodeChunk, region embeddedRegion) []models.CodeChunk {
+	lineOffset := region.startLine - 1
+	ids := make(map[string]string, len(chunks))
+	for i := range chunks {
+		chunk := &chunks[i]
+		chunk.StartLine += lineOffset
+		chunk.EndLine += lineOffset
+		chunk.Language = region.language
+		newID := models.ChunkID(chunk.RepoPath, chunk.FilePath, chunk.StartLine, chunk.EndLine, chunk.Content)
+		ids[chunk.ID] = newID
+		chunk.ID = newID
+	}
+	for i := range chunks {
+		if newID, ok := ids[chunks[i].ParentChunkID]; ok {
+			chunks[i].ParentChunkID = newID
+		}
+	}
+	return chunks
+}
+
+// chunkSFC chunks a single-file component: each <script>/<style> block in its own language,
+// and the remaining markup by tokens in the component's language
+func (c *Chunker) chunkSFC(repoPath, filePath, language, content string, maxTokens, overlapTokens int) ([]models.CodeChunk, bool, error) {
+	regions := extractEmbeddedRegions(content)
+	var chunks []models.CodeChunk
+	usedFallback := false
+
+	for _, region := range regions {
+		regionChunks, fallback, err := c.chunkRegion(repoPath, filePath, region, maxTokens, overlapTokens)
+		if err != nil {
+			return nil, usedFallback, err
+		}
+		usedFallback = usedFallback || fallback
+		chunks = append(chunks, relocateChunks(regionChunks, region)...)
+	}
+
+	if markup := maskEmbeddedRegions(content, regions); strings.TrimSpace(markup) != "" {
+		markupChunks, err := c.tokenChunker.ChunkByTokensWithLimits(repoPath, filePath, language, markup, maxTokens, overlapTokens)
+		if err != nil {
+			return nil, usedFallback, err
+		}
+		chunks = append(chunks, markupChunks...)
+	}
+
+	return chunks, usedFallback, nil
+}
+
+// chunkRegion chunks one embedded region's content, by AST when its language has a parser
+func (c *Chunker) chunkRegion(repoPath, filePath string, region embeddedRegion, maxTokens, overlapTokens int) ([]models.CodeChunk, bool, error) {
+	usedFallback := false
+	if c.astChunker != nil && c.astChunker.CanParseLanguage(region.language) {
+		astChunks, partial, err := c.chunkByAST(repoPath, filePath, region.language, region.content, maxTokens, overlapTokens)
+		if err == nil && len(astChunks) > 0 {
+			return astChunks, partial, nil
+		}
+		slog.Info("AST parsing of embedded region failed, falling back to token-based chunking",
+			"file", filePath, "language", region.language, "line", region.startLine, "error", err)
+		usedFallback = true
+	}
+
+	chunks, err := c.tokenChunker.ChunkByTokensWithLimits(repoPath, filePath, region.language, region.content, maxTokens, overlapTokens)
+	return chunks, usedFallback, err
+}
@@ -0,0 +1,158 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+const sampleSFC = `<template>
+  <button @click="increment">{{ count }}</button>
+</template>
+
+<script setup lang="ts">
+import { ref } from 'vue'
+
+export function increment(): void {
+  count.value++
+}
+</script>
+
+<style scoped>
+button { color: red; }
+</style>
+`
+
+func TestExtractEmbeddedRegions(t *testing.T) {
+	regions := extractEmbeddedRegions(sampleSFC)
+	if len(regions) != 2 {
+		t.Fatalf("Expected script and style regions, got %+v", regions)
+	}
+
+	script, style := regions[0], regions[1]
+	if script.language != "typescript" || script.startLine != 5 {
+		t.Errorf("Expected a typescript region starting on line 5, got %s on line %d", script.language, script.startLine)
+	}
+	if !strings.Contains(script.content, "export function increment") || strings.Contains(script.content, "</script>") {
+		t.Errorf("Expected the script body without tags, got %q", script.content)
+	}
+	if style.language != "css" || style.startLine != 13 {
+		t.Errorf("Expected a css region starting on line 13, got %s on line %d", style.language, style.startLine)
+	}
+
+	// Line 1 of a region is its opening tag's line
+	lines := strings.Split(script.content, "\n")
+	for i, line := range lines {
+		if strings.Contains(line, "export function increment") && script.startLine+i != 8 {
+			t.Errorf("Expected the function on file line 8, got %d", script.startLine+i)
+		}
+	}
+
+	// Masking keeps the template on its lines and drops the blocks
+	masked := maskEmbeddedRegions(sampleSFC, regions)
+	if strings.Count(masked, "\n") != strings.Count(sampleSFC, "\n") {
+		t.Error("Expected masking to keep the line count")
+	}
+	if strings.Contains(masked, "script") || strings.Contains(masked, "button {") {
+		t.Errorf("Expected script and style blocks to be masked, got %q", masked)
+	}
+	if !strings.HasPrefix(masked, "<template>") {
+		t.Errorf("Expected the template to be kept, got %q", masked)
+	}
+}
+
+func TestEmbeddedLanguage(t *testing.T) {
+	tests := []struct {
+		tag, attrs, expect string
+	}{
+		{"script", "", "javascript"},
+		{"script", ` lang="ts"`, "typescript"},
+		{"script", ` setup lang='tsx'`, "typescript"},
+		{"script", ` type="module"`, "javascript"},
+		{"style", "", "css"},
+		{"style", ` lang="scss" scoped`, "scss"},
+	}
+	for _, tt := range tests {
+		if got := embeddedLanguage(tt.tag, tt.attrs); got != tt.expect {
+			t.Errorf("embeddedLanguage(%q, %q) = %q, expected %q", tt.tag, tt.attrs, got, tt.expect)
+		}
+	}
+}
+
+func TestExtractEmbeddedRegions_Unterminated(t *testing.T) {
+	regions := extractEmbeddedRegions("<template><p/></template>\n<script>\nconst x = 1\n")
+	if len(regions) != 0 {
+		t.Errorf("Expected an unterminated script to be ignored, got %+v", regions)
+	}
+}
+
+func TestChunker_SFCScriptRegion(t *testing.T) {
+	astChunker, err := NewASTChunker()
+	if err != nil {
+		t.Skipf("AST chunker not available: %v", err)
+	}
+	defer astChunker.Close()
+
+	chunker := &Chunker{
+		config:       &config.ChunkingConfig{MaxChunkSizeBytes: 4000},
+		langDetector: NewLanguageDetector(),
+		astChunker:   astChunker,
+	}
+
+	region := extractEmbeddedRegions(sampleSFC)[0]
+	chunks, usedFallback, err := chunker.chunkRegion("/repo", "/repo/Counter.vue", region, DefaultMaxTokens, DefaultOverlapTokens)
+	if err != nil || usedFallback {
+		t.Fatalf("Expected the script to be chunked by AST, got fallback=%v err=%v", usedFallback, err)
+	}
+	chunks = relocateChunks(chunks, region)
+
+	found := false
+	for _, chunk := range chunks {
+		if chunk.Language != "typescript" {
+			t.Errorf("Expected typescript chunks, got %s", chunk.Language)
+		}
+		if chunk.ID != models.ChunkID(chunk.RepoPath, chunk.FilePath, chunk.StartLine, chunk.EndLine, chunk.Content) {
+			t.Error("Expected chunk IDs to match the relocated lines")
+		}
+		if strings.Contains(chunk.Content, "function increment") {
+			found = true
+			if chunk.StartLine != 8 || chunk.EndLine != 10 {
+				t.Errorf("Expected the function on file lines 8-10, got %d-%d", chunk.StartLine, chunk.EndLine)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a chunk for increment, got %+v", chunks)
+	}
+}
+
+func TestChunker_SFCFile(t *testing.T) {
+	chunker := newFallbackTestChunker(t)
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "Counter.vue")
+	if err := os.WriteFile(filePath, []byte(sampleSFC), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	chunks, err := chunker.ChunkFile(tmpDir, filePath)
+	if err != nil {
+		t.Fatalf("ChunkFile failed: %v", err)
+	}
+
+	languages := make(map[string]bool)
+	for _, chunk := range chunks {
+		languages[chunk.Language] = true
+		if chunk.Language == "vue" && strings.Contains(chunk.Content, "<script") {
+			t.Errorf("Expected script blocks to be left out of template chunks, got %q", chunk.Content)
+		}
+	}
+	for _, lang := range []string{"typescript", "css", "vue"} {
+		if !languages[lang] {
+			t.Errorf("Expected %s chunks, got languages %v", lang, languages)
+		}
+	}
+}
@@ -6,15 +6,13 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/google/uuid"
 	"github.com/jamaly87/codebase-semantic-search/internal/models"
-	"github.com/pkoukk/tiktoken-go"
 )
 
 const (
 	// maxOverlapExcessRatio defines the maximum allowed excess for overlap as a ratio (1.2 = 20% excess)
 	maxOverlapExcessRatio = 1.2
-	// maxChunkSizeBytes is the maximum safe chunk size in bytes (~4000 chars ~ 1000 tokens)
+	// maxChunkSizeBytes is the default maximum chunk size in bytes (~4000 chars ~ 1000 tokens)
 	maxChunkSizeBytes = 4000
 	// boundaryLookaheadLines is the number of lines to look ahead when searching for natural boundaries
 	boundaryLookaheadLines = 10
@@ -22,19 +20,25 @@ const (
 
 // TokenChunker splits code into chunks based on token count (model-aware)
 type TokenChunker struct {
-	tokenizer *tiktoken.Tiktoken
-	maxTokens int
-	overlap   int
-	mux       sync.RWMutex // For thread-safe limit updates
+	tokenizer     tokenizer
+	maxTokens     int
+	overlap       int
+	maxChunkBytes int                         // Hard byte limit per chunk (0 = maxChunkSizeBytes)
+	boundaries    map[string][]*regexp.Regexp // Extra boundary patterns per language (chunking.boundary_patterns)
+	mux           sync.RWMutex                // For thread-safe limit updates
 }
 
-// NewTokenChunker creates a new token-based chunker
+// NewTokenChunker creates a new token-based chunker using DefaultTokenizerEncoding
 func NewTokenChunker(maxTokens, overlap int) (*TokenChunker, error) {
-	// Use cl100k_base encoding (used by gpt-3.5-turbo and gpt-4)
-	// This is compatible with most modern LLMs
-	tokenizer, err := tiktoken.GetEncoding("cl100k_base")
+	return NewTokenChunkerWithEncoding(maxTokens, overlap, "")
+}
+
+// NewTokenChunkerWithEncoding creates a token-based chunker counting tokens with the given
+// chunking.tokenizer_encoding (see resolveEncoding)
+func NewTokenChunkerWithEncoding(maxTokens, overlap int, encoding string) (*TokenChunker, error) {
+	tokenizer, err := newTokenizer(encoding)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get tokenizer: %w", err)
+		return nil, err
 	}
 
 	return &TokenChunker{
@@ -66,7 +70,7 @@ func (tc *TokenChunker) ChunkByTokensWithLimits(repoPath, filePath, language, co
 func (tc *TokenChunker) chunkWithLimits(repoPath, filePath, language, content string, maxTokens, overlap int) ([]models.CodeChunk, error) {
 
 	// Split content into lines for boundary detection
-	lines := strings.Split(content, "\n")
+	lines := splitLines(normalizeLineEndings(content))
 
 	var chunks []models.CodeChunk
 	var currentLines []string
@@ -77,7 +81,7 @@ func (tc *TokenChunker) chunkWithLimits(repoPath, filePath, language, content st
 	for i < len(lines) {
 		line := lines[i]
 		// Count tokens in this line
-		lineTokens := len(tc.tokenizer.Encode(line, nil, nil))
+		lineTokens := tc.countTokens(line)
 
 		// Check if adding this line would exceed max tokens
 		if currentTokens+lineTokens > maxTokens && len(currentLines) > 0 {
@@ -85,11 +89,11 @@ func (tc *TokenChunker) chunkWithLimits(repoPath, filePath, language, content st
 			boundaryFound := false
 			for j := i; j < i+boundaryLookaheadLines && j < len(lines); j++ {
 				trimmed := strings.TrimSpace(lines[j])
-				if IsBoundary(trimmed, language) {
+				if tc.isBoundary(trimmed, language) {
 					// Found a boundary, extend to there
 					for k := i; k <= j; k++ {
 						currentLines = append(currentLines, lines[k])
-						currentTokens += len(tc.tokenizer.Encode(lines[k], nil, nil))
+						currentTokens += tc.countTokens(lines[k])
 					}
 					i = j + 1
 					boundaryFound = true
@@ -98,10 +102,7 @@ func (tc *TokenChunker) chunkWithLimits(repoPath, filePath, language, content st
 			}
 
 			// Create chunk
-			chunk := tc.createChunk(repoPath, filePath, language, currentLines, startLine)
-			if chunk != nil {
-				chunks = append(chunks, *chunk)
-			}
+			chunks = append(chunks, tc.createChunks(repoPath, filePath, language, currentLines, startLine)...)
 
 			// Create overlap for next chunk
 			overlapLines := tc.calculateOverlapLines(currentLines, overlap)
@@ -122,39 +123,131 @@ func (tc *TokenChunker) chunkWithLimits(repoPath, filePath, language, content st
 
 	// Add remaining chunk
 	if len(currentLines) > 0 {
-		chunk := tc.createChunk(repoPath, filePath, language, currentLines, startLine)
-		if chunk != nil {
-			chunks = append(chunks, *chunk)
-		}
+		chunks = append(chunks, tc.createChunks(repoPath, filePath, language, currentLines, startLine)...)
 	}
 
 	return chunks, nil
 }
 
-// createChunk creates a code chunk from lines
-func (tc *TokenChunker) createChunk(repoPath, filePath, language string, lines []string, startLine int) *models.CodeChunk {
-	content := strings.Join(lines, "\n")
+// SetMaxChunkBytes sets the hard byte limit per chunk
+func (tc *TokenChunker) SetMaxChunkBytes(maxBytes int) {
+	tc.mux.Lock()
+	defer tc.mux.Unlock()
+	tc.maxChunkBytes = maxBytes
+}
+
+// withMaxChunkBytes returns a chunker sharing tc's tokenizer with a different byte limit
+func (tc *TokenChunker) withMaxChunkBytes(maxBytes int) *TokenChunker {
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	return &TokenChunker{
+		tokenizer:     tc.tokenizer,
+		maxTokens:     tc.maxTokens,
+		overlap:       tc.overlap,
+		maxChunkBytes: maxBytes,
+		boundaries:    tc.boundaries,
+	}
+}
+
+// SetBoundaryPatterns sets extra boundary regexes per language, matched in addition to the
+// built-in patterns of GetLanguagePatterns
+func (tc *TokenChunker) SetBoundaryPatterns(patterns map[string][]string) error {
+	boundaries, err := compileBoundaryPatterns(patterns)
+	if err != nil {
+		return err
+	}
+	tc.mux.Lock()
+	defer tc.mux.Unlock()
+	tc.boundaries = boundaries
+	return nil
+}
+
+// withBoundaryPatterns returns a chunker sharing tc's tokenizer with different extra boundary patterns
+func (tc *TokenChunker) withBoundaryPatterns(patterns map[string][]string) (*TokenChunker, error) {
+	boundaries, err := compileBoundaryPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	return &TokenChunker{
+		tokenizer:     tc.tokenizer,
+		maxTokens:     tc.maxTokens,
+		overlap:       tc.overlap,
+		maxChunkBytes: tc.maxChunkBytes,
+		boundaries:    boundaries,
+	}, nil
+}
+
+// isBoundary checks if a line matches a built-in or configured boundary pattern for the language
+func (tc *TokenChunker) isBoundary(line, language string) bool {
+	if IsBoundary(line, language) {
+		return true
+	}
+	tc.mux.RLock()
+	extra := tc.boundaries[language]
+	tc.mux.RUnlock()
 
-	// Skip empty chunks
-	if strings.TrimSpace(content) == "" {
-		return nil
+	line = strings.TrimSpace(line)
+	for _, re := range extra {
+		if re.MatchString(line) {
+			return true
+		}
 	}
+	return false
+}
 
-	// Ensure chunk doesn't exceed safe size
-	if len(content) > maxChunkSizeBytes {
-		content = content[:maxChunkSizeBytes]
+// compileBoundaryPatterns compiles chunking.boundary_patterns, keyed by language
+func compileBoundaryPatterns(patterns map[string][]string) (map[string][]*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, nil
 	}
+	compiled := make(map[string][]*regexp.Regexp, len(patterns))
+	for language, list := range patterns {
+		for _, pattern := range list {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid chunking.boundary_patterns regex %q for %s: %w", pattern, language, err)
+			}
+			compiled[language] = append(compiled[language], re)
+		}
+	}
+	return compiled, nil
+}
 
-	return &models.CodeChunk{
-		ID:        uuid.New().String(),
-		RepoPath:  repoPath,
-		FilePath:  filePath,
-		ChunkType: models.ChunkTypeFunction, // Using function type for semantic chunks
-		Content:   content,
-		Language:  language,
-		StartLine: startLine,
-		EndLine:   startLine + len(lines) - 1,
+// createChunks creates code chunks from lines
+// Lines that exceed the byte limit (e.g. very long lines) are split at line boundaries
+// into several chunks instead of being truncated.
+func (tc *TokenChunker) createChunks(repoPath, filePath, language string, lines []string, startLine int) []models.CodeChunk {
+	tc.mux.RLock()
+	maxBytes := tc.maxChunkBytes
+	tc.mux.RUnlock()
+	if maxBytes <= 0 {
+		maxBytes = maxChunkSizeBytes
 	}
+
+	var chunks []models.CodeChunk
+	for _, span := range splitLinesBySize(lines, startLine, maxBytes, 0) {
+		content := span.content()
+
+		// Skip empty chunks
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+
+		chunks = append(chunks, models.CodeChunk{
+			ID:        models.ChunkID(repoPath, filePath, span.startLine, span.endLine(), content),
+			RepoPath:  repoPath,
+			FilePath:  filePath,
+			ChunkType: models.ChunkTypeFunction, // Using function type for semantic chunks
+			Content:   content,
+			Language:  language,
+			StartLine: span.startLine,
+			EndLine:   span.endLine(),
+		})
+	}
+
+	return chunks
 }
 
 // calculateOverlapLines returns lines to overlap with next chunk
@@ -170,7 +263,7 @@ func (tc *TokenChunker) calculateOverlapLines(lines []string, overlapTokens int)
 	// Work backwards from end
 	for i := len(lines) - 1; i >= 0; i-- {
 		line := lines[i]
-		lineTokens := len(tc.tokenizer.Encode(line, nil, nil))
+		lineTokens := tc.countTokens(line)
 
 		// Check if adding this line would exceed the overlap limit
 		if currentOverlap+lineTokens > overlapTokens {
@@ -198,7 +291,13 @@ func (tc *TokenChunker) calculateOverlapLines(lines []string, overlapTokens int)
 
 // countTokens counts total tokens in text
 func (tc *TokenChunker) countTokens(text string) int {
-	return len(tc.tokenizer.Encode(text, nil, nil))
+	return tc.tokenizer.count(text)
+}
+
+// TruncateTokens cuts text to at most maxTokens tokens
+// It returns the kept text and the number of tokens dropped (0 if text already fits).
+func (tc *TokenChunker) TruncateTokens(text string, maxTokens int) (string, int) {
+	return tc.tokenizer.truncate(text, maxTokens)
 }
 
 // SetLimits updates the max tokens and overlap for adaptive chunking
@@ -259,6 +358,14 @@ func GetLanguagePatterns(language string) []string {
 			`^\s*type\s+\w+\s*=`,
 			`^\s*(const|let|var)\s+\w+\s*=\s*(async\s+)?\([^)]*\)\s*=>`,
 		},
+		"kotlin": {
+			`^\s*((public|private|protected|internal|override|open|abstract|suspend|inline|operator|infix|tailrec)\s+)*fun\s+`,
+			`^\s*((public|private|protected|internal|open|abstract|sealed|data|enum|inner|annotation|value)\s+)*class\s+\w+`,
+			`^\s*((public|private|protected|internal|data)\s+)*object\s+\w+`,
+			`^\s*companion\s+object\b`,
+			`^\s*((public|private|protected|internal|sealed|fun)\s+)*interface\s+\w+`,
+			`^\s*@\w+`, // Annotations
+		},
 		"go": {
 			`^\s*func\s+\w+`,
 			`^\s*func\s+\([^)]+\)\s+\w+`,
@@ -271,6 +378,26 @@ func GetLanguagePatterns(language string) []string {
 			`^\s*async\s+def\s+\w+`,
 			`^\s*@\w+`, // Decorators
 		},
+		"ruby": {
+			`^\s*((private|protected|public)\s+)?def\s+(self\.)?\w+`,
+			`^\s*class\s+[A-Z]\w*`,
+			`^\s*class\s*<<\s*self\b`,
+			`^\s*module\s+[A-Z]\w*`,
+		},
+		"php": {
+			`^\s*((public|private|protected|static|abstract|final)\s+)*function\s+&?\w+`,
+			`^\s*((abstract|final|readonly)\s+)*class\s+\w+`,
+			`^\s*interface\s+\w+`,
+			`^\s*trait\s+\w+`,
+			`^\s*enum\s+\w+`,
+		},
+		"swift": {
+			`^\s*(@\w+\s+)*((public|private|fileprivate|internal|open|static|class|override|final|mutating|nonisolated)\s+)*func\s+`,
+			`^\s*(@\w+\s+)*((public|private|fileprivate|internal|open|override|convenience|required)\s+)*init[?!]?\s*[(<]`,
+			`^\s*(@\w+\s+)*((public|private|fileprivate|internal|open|final|indirect)\s+)*(class|struct|enum|actor)\s+\w+`,
+			`^\s*(@\w+\s+)*((public|private|fileprivate|internal)\s+)*protocol\s+\w+`,
+			`^\s*((public|private|fileprivate|internal)\s+)*extension\s+\w+`,
+		},
 		"rust": {
 			`^\s*(pub\s+)?fn\s+\w+`,
 			`^\s*(pub\s+)?struct\s+\w+`,
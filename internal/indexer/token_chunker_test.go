@@ -1,8 +1,12 @@
 package indexer
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+	"unicode/utf8"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
 )
 
 func TestTokenChunker_SetLimits(t *testing.T) {
@@ -211,3 +215,244 @@ func generateTestContent(lines int) string {
 	return sb.String()
 }
 
+
+func TestIsBoundary_Kotlin(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected bool
+	}{
+		{"fun main() {", true},
+		{"    private suspend fun load(id: Int): User {", true},
+		{"override fun toString() = name", true},
+		{"data class User(val name: String)", true},
+		{"sealed class Result<out T> {", true},
+		{"object Registry {", true},
+		{"companion object {", true},
+		{"interface Greeter {", true},
+		{"fun interface Mapper {", true},
+		{"@Composable", true},
+		{"val funny = 1", false},
+		{"return user.name", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsBoundary(tt.line, "kotlin"); got != tt.expected {
+			t.Errorf("IsBoundary(%q, kotlin) = %v, expected %v", tt.line, got, tt.expected)
+		}
+	}
+}
+
+func TestIsBoundary_Ruby(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected bool
+	}{
+		{"def call", true},
+		{"  def self.build(account)", true},
+		{"  private def secret", true},
+		{"class Generator < Base", true},
+		{"  class << self", true},
+		{"module Billing", true},
+		{"  defaults = {}", false},
+		{"  klass = User", false},
+		{"  module_function", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsBoundary(tt.line, "ruby"); got != tt.expected {
+			t.Errorf("IsBoundary(%q, ruby) = %v, expected %v", tt.line, got, tt.expected)
+		}
+	}
+}
+
+func TestIsBoundary_PHP(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected bool
+	}{
+		{"function helper($value)", true},
+		{"    public static function greet(string $name): string", true},
+		{"    private function &reference()", true},
+		{"abstract class Controller", true},
+		{"final class UserController extends Controller", true},
+		{"interface Greeter", true},
+		{"trait Loggable", true},
+		{"    $classes = [];", false},
+		{"    $callback = function () {", false},
+		{"    return $this->functions;", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsBoundary(tt.line, "php"); got != tt.expected {
+			t.Errorf("IsBoundary(%q, php) = %v, expected %v", tt.line, got, tt.expected)
+		}
+	}
+}
+
+func TestIsBoundary_Swift(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected bool
+	}{
+		{"func helper<T>(_ value: T) -> T {", true},
+		{"    public static func make() -> Outer {", true},
+		{"    @MainActor func refresh() async {", true},
+		{"    override init(frame: CGRect) {", true},
+		{"    convenience init?(json: [String: Any]) {", true},
+		{"final class Outer: Greeter {", true},
+		{"    struct Inner {", true},
+		{"indirect enum Tree {", true},
+		{"public protocol Greeter {", true},
+		{"extension Outer.Inner: Equatable {", true},
+		{"actor Counter {", true},
+		{"    let classes = [String]()", false},
+		{"    return initialValue", false},
+		{"    self.structure = structure", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsBoundary(tt.line, "swift"); got != tt.expected {
+			t.Errorf("IsBoundary(%q, swift) = %v, expected %v", tt.line, got, tt.expected)
+		}
+	}
+}
+
+func TestTokenChunker_KotlinBoundaries(t *testing.T) {
+	chunker, err := NewTokenChunker(40, 0)
+	if err != nil {
+		t.Skipf("Tokenizer not available: %v", err)
+	}
+
+	content := `class Repository {
+    private val cache = mutableMapOf<Int, String>()
+    private val loads = mutableListOf<Int>()
+    private var hits = 0
+}
+
+fun loadUser(id: Int): String {
+    val name = "user-$id"
+    println("loading $name from the repository cache")
+    return name
+}
+
+object Registry {
+    val users = listOf("alice", "bob", "carol", "dave")
+}
+`
+	chunks, err := chunker.ChunkByTokens("/repo", "/repo/Repo.kt", "kotlin", content)
+	if err != nil {
+		t.Fatalf("ChunkByTokens failed: %v", err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("Expected the file to be split into several chunks, got %d", len(chunks))
+	}
+
+	// Full chunks are extended up to the next Kotlin declaration (fun, class, object)
+	for _, chunk := range chunks[:len(chunks)-1] {
+		lines := strings.Split(strings.TrimRight(chunk.Content, "\n "), "\n")
+		lastLine := lines[len(lines)-1]
+		if !IsBoundary(lastLine, "kotlin") {
+			t.Errorf("Expected chunk ending at line %d to end at a declaration, got %q", chunk.EndLine, lastLine)
+		}
+	}
+}
+
+func TestTokenChunker_BoundaryPatterns(t *testing.T) {
+	chunker, err := NewTokenChunkerWithEncoding(30, 0, ApproximateEncoding)
+	if err != nil {
+		t.Fatalf("Failed to create token chunker: %v", err)
+	}
+
+	// Test blocks match none of the built-in TypeScript patterns
+	var b strings.Builder
+	for i := 0; i < 6; i++ {
+		fmt.Fprintf(&b, "it('handles case %d', () => {\n", i)
+		fmt.Fprintf(&b, "  const value = compute(%d);\n", i)
+		fmt.Fprintf(&b, "  expect(value).toBeGreaterThan(%d);\n", i)
+		b.WriteString("});\n")
+	}
+	content := b.String()
+
+	endsAtTestBlock := func(chunks []models.CodeChunk) bool {
+		for _, chunk := range chunks[:len(chunks)-1] {
+			lines := strings.Split(strings.TrimRight(chunk.Content, "\n "), "\n")
+			if !strings.HasPrefix(lines[len(lines)-1], "it(") {
+				return false
+			}
+		}
+		return true
+	}
+
+	chunks, err := chunker.ChunkByTokens("/repo", "/repo/app.test.ts", "typescript", content)
+	if err != nil {
+		t.Fatalf("ChunkByTokens failed: %v", err)
+	}
+	if len(chunks) < 2 || endsAtTestBlock(chunks) {
+		t.Fatalf("Expected built-in patterns alone not to split at test blocks, got %d chunks", len(chunks))
+	}
+
+	if err := chunker.SetBoundaryPatterns(map[string][]string{"typescript": {`^it\(`}}); err != nil {
+		t.Fatalf("SetBoundaryPatterns failed: %v", err)
+	}
+	chunks, err = chunker.ChunkByTokens("/repo", "/repo/app.test.ts", "typescript", content)
+	if err != nil {
+		t.Fatalf("ChunkByTokens failed: %v", err)
+	}
+	if len(chunks) < 2 || !endsAtTestBlock(chunks) {
+		for _, chunk := range chunks {
+			t.Logf("chunk %d-%d:\n%s", chunk.StartLine, chunk.EndLine, chunk.Content)
+		}
+		t.Fatal("Expected every full chunk to extend to the next test block")
+	}
+
+	// Patterns of other languages don't apply
+	if chunker.isBoundary("it('x', () => {", "javascript") {
+		t.Error("Expected typescript boundary patterns not to apply to javascript")
+	}
+}
+
+func TestTokenChunker_InvalidBoundaryPattern(t *testing.T) {
+	chunker, err := NewTokenChunkerWithEncoding(30, 0, ApproximateEncoding)
+	if err != nil {
+		t.Fatalf("Failed to create token chunker: %v", err)
+	}
+	err = chunker.SetBoundaryPatterns(map[string][]string{"java": {`^@Bean\b`, `(unclosed`}})
+	if err == nil || !strings.Contains(err.Error(), "(unclosed") {
+		t.Fatalf("Expected an error naming the invalid regex, got %v", err)
+	}
+	if err := validateBoundaryPatterns(map[string][]string{"java": {`^@Bean\b`}}); err != nil {
+		t.Errorf("Expected valid patterns to pass, got %v", err)
+	}
+}
+
+func TestTokenChunker_TruncateTokens(t *testing.T) {
+	chunker, err := NewTokenChunker(200, 20)
+	if err != nil {
+		t.Skipf("Tokenizer not available: %v", err)
+	}
+
+	text := strings.Repeat("func handle(ctx) { return 42 }\n", 100)
+	total := chunker.countTokens(text)
+
+	truncated, dropped := chunker.TruncateTokens(text, 50)
+	if got := chunker.countTokens(truncated); got > 50 {
+		t.Errorf("Expected at most 50 tokens, got %d", got)
+	}
+	if dropped != total-50 {
+		t.Errorf("Expected %d dropped tokens, got %d", total-50, dropped)
+	}
+	if !strings.HasPrefix(text, truncated) {
+		t.Error("Expected the truncated text to be a prefix of the input")
+	}
+
+	// Text that fits is returned unchanged
+	if kept, dropped := chunker.TruncateTokens(text, total); kept != text || dropped != 0 {
+		t.Errorf("Expected text within the budget to be unchanged, dropped %d tokens", dropped)
+	}
+
+	// A cut inside a multi-byte character never yields invalid UTF-8
+	kept, _ := chunker.TruncateTokens(strings.Repeat("日本語のコード ", 50), 7)
+	if !utf8.ValidString(kept) {
+		t.Errorf("Expected valid UTF-8, got %q", kept)
+	}
+}
@@ -0,0 +1,116 @@
+package indexer
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const (
+	// DefaultTokenizerEncoding is the tiktoken encoding used when chunking.tokenizer_encoding is unset
+	DefaultTokenizerEncoding = tiktoken.MODEL_CL100K_BASE
+	// ApproximateEncoding estimates tokens from text length instead of running a tokenizer,
+	// for embedding models whose own tokenizer tiktoken doesn't have (e.g. nomic-embed-text)
+	ApproximateEncoding = "approximate"
+	// approxCharsPerToken is the approximation's characters per token, roughly what BPE
+	// tokenizers average on source code
+	approxCharsPerToken = 4
+)
+
+// tiktokenEncodings are the encodings tiktoken can load
+var tiktokenEncodings = []string{
+	tiktoken.MODEL_O200K_BASE,
+	tiktoken.MODEL_CL100K_BASE,
+	tiktoken.MODEL_P50K_BASE,
+	tiktoken.MODEL_P50K_EDIT,
+	tiktoken.MODEL_R50K_BASE,
+}
+
+// tokenizer counts tokens and cuts text to a token budget
+type tokenizer interface {
+	count(text string) int
+	// truncate returns text cut to at most maxTokens tokens and the number of tokens dropped
+	truncate(text string, maxTokens int) (string, int)
+}
+
+// resolveEncoding maps chunking.tokenizer_encoding to a tiktoken encoding or ApproximateEncoding
+// The setting is an encoding name, an OpenAI model name (e.g. "gpt-4o"), "approximate",
+// or empty for DefaultTokenizerEncoding.
+func resolveEncoding(name string) (string, error) {
+	switch {
+	case name == "":
+		return DefaultTokenizerEncoding, nil
+	case name == ApproximateEncoding:
+		return name, nil
+	}
+	for _, encoding := range tiktokenEncodings {
+		if name == encoding {
+			return name, nil
+		}
+	}
+	if encoding, ok := tiktoken.MODEL_TO_ENCODING[name]; ok {
+		return encoding, nil
+	}
+	for prefix, encoding := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(name, prefix) {
+			return encoding, nil
+		}
+	}
+	return "", fmt.Errorf("unknown chunking.tokenizer_encoding %q: use a tiktoken encoding (%s), an OpenAI model name, "+
+		"or %q to estimate %d characters per token for other models", name, strings.Join(tiktokenEncodings, ", "), ApproximateEncoding, approxCharsPerToken)
+}
+
+// newTokenizer returns the tokenizer for a chunking.tokenizer_encoding setting
+// tiktoken encodings are downloaded on first use.
+func newTokenizer(name string) (tokenizer, error) {
+	encoding, err := resolveEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+	if encoding == ApproximateEncoding {
+		return approximateTokenizer{}, nil
+	}
+
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tokenizer: %w", err)
+	}
+	return tiktokenTokenizer{enc: enc}, nil
+}
+
+// tiktokenTokenizer counts tokens with a tiktoken encoding
+type tiktokenTokenizer struct {
+	enc *tiktoken.Tiktoken
+}
+
+func (t tiktokenTokenizer) count(text string) int {
+	return len(t.enc.Encode(text, nil, nil))
+}
+
+func (t tiktokenTokenizer) truncate(text string, maxTokens int) (string, int) {
+	tokens := t.enc.Encode(text, nil, nil)
+	if maxTokens <= 0 || len(tokens) <= maxTokens {
+		return text, 0
+	}
+	// A token may end inside a multi-byte character; drop the partial rune
+	truncated := strings.ToValidUTF8(t.enc.Decode(tokens[:maxTokens]), "")
+	return truncated, len(tokens) - maxTokens
+}
+
+// approximateTokenizer estimates one token per approxCharsPerToken characters
+type approximateTokenizer struct{}
+
+func (approximateTokenizer) count(text string) int {
+	return (utf8.RuneCountInString(text) + approxCharsPerToken - 1) / approxCharsPerToken
+}
+
+func (a approximateTokenizer) truncate(text string, maxTokens int) (string, int) {
+	tokens := a.count(text)
+	if maxTokens <= 0 || tokens <= maxTokens {
+		return text, 0
+	}
+	runes := []rune(text)
+	return string(runes[:maxTokens*approxCharsPerToken]), tokens - maxTokens
+}
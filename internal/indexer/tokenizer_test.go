@@ -0,0 +1,123 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+func TestResolveEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect string
+	}{
+		{"", DefaultTokenizerEncoding},
+		{"cl100k_base", "cl100k_base"},
+		{"o200k_base", "o200k_base"},
+		{"r50k_base", "r50k_base"},
+		{"gpt-4o", "o200k_base"},
+		{"gpt-4", "cl100k_base"},
+		{"gpt-4-0613", "cl100k_base"}, // Model prefix
+		{"approximate", ApproximateEncoding},
+	}
+	for _, tt := range tests {
+		got, err := resolveEncoding(tt.name)
+		if err != nil {
+			t.Errorf("resolveEncoding(%q) failed: %v", tt.name, err)
+			continue
+		}
+		if got != tt.expect {
+			t.Errorf("resolveEncoding(%q) = %q, expected %q", tt.name, got, tt.expect)
+		}
+	}
+}
+
+func TestResolveEncoding_Unknown(t *testing.T) {
+	for _, name := range []string{"nomic-embed-text", "cl100k", "bogus"} {
+		_, err := resolveEncoding(name)
+		if err == nil {
+			t.Errorf("Expected an error for unknown encoding %q", name)
+			continue
+		}
+		for _, hint := range []string{name, "tokenizer_encoding", "cl100k_base", ApproximateEncoding} {
+			if !strings.Contains(err.Error(), hint) {
+				t.Errorf("Expected the error for %q to mention %q, got: %v", name, hint, err)
+			}
+		}
+		if _, err := NewTokenChunkerWithEncoding(200, 20, name); err == nil {
+			t.Errorf("Expected NewTokenChunkerWithEncoding to reject %q", name)
+		}
+	}
+}
+
+func TestTokenChunker_ApproximateEncoding(t *testing.T) {
+	chunker, err := NewTokenChunkerWithEncoding(200, 20, ApproximateEncoding)
+	if err != nil {
+		t.Fatalf("Failed to create token chunker: %v", err)
+	}
+	if _, ok := chunker.tokenizer.(approximateTokenizer); !ok {
+		t.Fatalf("Expected the approximate tokenizer, got %T", chunker.tokenizer)
+	}
+
+	// One token per 4 characters, rounded up
+	if n := chunker.countTokens("12345678"); n != 2 {
+		t.Errorf("Expected 2 tokens for 8 characters, got %d", n)
+	}
+	if n := chunker.countTokens("héllo"); n != 2 {
+		t.Errorf("Expected characters, not bytes, to be counted, got %d tokens", n)
+	}
+
+	text := strings.Repeat("ab", 50) // 100 characters, 25 tokens
+	truncated, dropped := chunker.TruncateTokens(text, 10)
+	if len(truncated) != 40 || dropped != 15 {
+		t.Errorf("Expected 40 characters kept and 15 tokens dropped, got %d and %d", len(truncated), dropped)
+	}
+	if kept, dropped := chunker.TruncateTokens(text, 25); kept != text || dropped != 0 {
+		t.Error("Expected text within the budget to be kept")
+	}
+
+	// Token limits drive chunking with the approximation too
+	lines := make([]string, 40)
+	for i := range lines {
+		lines[i] = "value := compute(input, 42)"
+	}
+	chunks, err := chunker.ChunkByTokensWithLimits("/repo", "/repo/a.go", "go", strings.Join(lines, "\n"), 50, 0)
+	if err != nil {
+		t.Fatalf("Chunking failed: %v", err)
+	}
+	if len(chunks) < 2 {
+		t.Errorf("Expected the 40 lines to be split at 50 approximate tokens, got %d chunks", len(chunks))
+	}
+	for _, chunk := range chunks {
+		if n := chunker.countTokens(chunk.Content); n > 50*maxOverlapExcessRatio {
+			t.Errorf("Expected chunks within the token budget, got %d tokens", n)
+		}
+	}
+}
+
+func TestNewIndexer_UnknownTokenizerEncoding(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Cache.Directory = t.TempDir()
+	cfg.Chunking.TokenizerEncoding = "nomic-embed-text"
+
+	_, err := NewIndexer(cfg)
+	if err == nil || !strings.Contains(err.Error(), "tokenizer_encoding") {
+		t.Fatalf("Expected NewIndexer to reject the unknown encoding, got %v", err)
+	}
+}
+
+func TestSettingsFor_TokenizerEncodingIsGlobal(t *testing.T) {
+	idx := newTestIndexer(t)
+	repoDir := t.TempDir()
+	override := "chunking:\n  tokenizer_encoding: approximate\n"
+	if err := os.WriteFile(filepath.Join(repoDir, config.RepoConfigFile), []byte(override), 0644); err != nil {
+		t.Fatalf("Failed to write repo config: %v", err)
+	}
+
+	if _, err := idx.settingsFor(repoDir); err == nil || !strings.Contains(err.Error(), "tokenizer_encoding") {
+		t.Errorf("Expected a repository override of tokenizer_encoding to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package indexer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// Modes for chunking.unsupported_files, selecting what happens to files of unsupported extensions
+const (
+	UnsupportedFilesReport = "report" // Skipped and counted as unsupported_language (default)
+	UnsupportedFilesSkip   = "skip"   // Skipped silently, not counted in scan totals
+	UnsupportedFilesText   = "text"   // Text files chunked by tokens as plain text
+)
+
+// ErrUnsupportedFile is returned when chunking a file whose type is not supported
+var ErrUnsupportedFile = errors.New("unsupported file type")
+
+// plainText is the language of unsupported files indexed as text
+var plainText = &models.Language{Name: "text"}
+
+// textSniffBytes is how much of the start of a file is checked for binary content
+const textSniffBytes = 8192
+
+// validateUnsupportedFiles checks chunking.unsupported_files
+func validateUnsupportedFiles(mode string) error {
+	switch mode {
+	case "", UnsupportedFilesReport, UnsupportedFilesSkip, UnsupportedFilesText:
+		return nil
+	default:
+		return fmt.Errorf("unknown chunking.unsupported_files %q (valid: %s, %s, %s)",
+			mode, UnsupportedFilesReport, UnsupportedFilesSkip, UnsupportedFilesText)
+	}
+}
+
+// isText reports whether content looks like text: its start has no NUL byte, which binary
+// formats (images, archives, compiled code) almost always contain
+func isText(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), textSniffBytes)], 0) < 0
+}
+
+// fileIsText reads the start of the file at path and checks that it is text
+func fileIsText(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	header := make([]byte, textSniffBytes)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return isText(header[:n]), nil
+}
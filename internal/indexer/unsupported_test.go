@@ -0,0 +1,169 @@
+package indexer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+func TestScan_UnsupportedFiles(t *testing.T) {
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{
+		"main.go":         "package main\n\nfunc main() {}\n",
+		"db/schema.sql":   "CREATE TABLE users (id INTEGER PRIMARY KEY);\n",
+		"deploy/app.conf": "listen 8080\n",
+	})
+	if err := os.WriteFile(filepath.Join(repoDir, "logo.png"), []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		mode        string
+		files       int
+		total       int
+		unsupported int
+		textFiles   int
+	}{
+		{mode: "", files: 1, total: 4, unsupported: 3},
+		{mode: UnsupportedFilesReport, files: 1, total: 4, unsupported: 3},
+		{mode: UnsupportedFilesSkip, files: 1, total: 1, unsupported: 0},
+		{mode: UnsupportedFilesText, files: 3, total: 4, unsupported: 1, textFiles: 2}, // The binary image is still unsupported
+	}
+	for _, tt := range tests {
+		cfg := config.DefaultConfig().Indexing
+		scanner := NewScanner(&cfg, nil)
+		scanner.SetUnsupportedFiles(tt.mode)
+		result, err := scanner.Scan(repoDir)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if len(result.Files) != tt.files || result.TotalFiles != tt.total {
+			t.Errorf("%q: expected %d of %d files, got %d of %d: %v", tt.mode, tt.files, tt.total, len(result.Files), result.TotalFiles, result.Files)
+		}
+		if result.SkipReasons[SkipReasonUnsupported] != tt.unsupported {
+			t.Errorf("%q: expected %d files skipped as unsupported, got %v", tt.mode, tt.unsupported, result.SkipReasons)
+		}
+		if result.Languages["text"] != tt.textFiles || result.Languages["go"] != 1 {
+			t.Errorf("%q: expected %d text files and 1 go file, got %v", tt.mode, tt.textFiles, result.Languages)
+		}
+	}
+
+	// Explain agrees with the scan
+	cfg := config.DefaultConfig().Indexing
+	scanner := NewScanner(&cfg, nil)
+	scanner.SetUnsupportedFiles(UnsupportedFilesText)
+	for file, language := range map[string]string{"db/schema.sql": "text", "logo.png": ""} {
+		explanation, err := scanner.Explain(repoDir, file)
+		if err != nil {
+			t.Fatalf("Explain failed: %v", err)
+		}
+		if explanation.Language != language {
+			t.Errorf("Expected %s explained as language %q, got %q", file, language, explanation.Language)
+		}
+	}
+}
+
+func TestChunkFile_UnsupportedFiles(t *testing.T) {
+	tokenChunker, err := NewTokenChunkerWithEncoding(DefaultMaxTokens, DefaultOverlapTokens, ApproximateEncoding)
+	if err != nil {
+		t.Fatalf("Failed to create token chunker: %v", err)
+	}
+	repoDir := t.TempDir()
+	sqlPath := filepath.Join(repoDir, "schema.sql")
+	binPath := filepath.Join(repoDir, "data.bin")
+	if err := os.WriteFile(sqlPath, []byte("CREATE TABLE users (\n  id INTEGER PRIMARY KEY,\n  email TEXT NOT NULL\n);\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(binPath, []byte{'x', 0, 1, 2}, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	for _, mode := range []string{"", UnsupportedFilesReport, UnsupportedFilesSkip, UnsupportedFilesText} {
+		cfg := config.DefaultConfig().Chunking
+		cfg.UnsupportedFiles = mode
+		chunker := &Chunker{config: &cfg, langDetector: NewLanguageDetector(), tokenChunker: tokenChunker}
+
+		if _, err := chunker.ChunkFile(repoDir, binPath); !errors.Is(err, ErrUnsupportedFile) {
+			t.Errorf("%q: expected a binary file to be unsupported, got %v", mode, err)
+		}
+
+		chunks, err := chunker.ChunkFile(repoDir, sqlPath)
+		if mode != UnsupportedFilesText {
+			if !errors.Is(err, ErrUnsupportedFile) {
+				t.Errorf("%q: expected ErrUnsupportedFile, got %v", mode, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Expected the SQL file to be chunked as text, got %v", err)
+		}
+		if len(chunks) == 0 || chunks[0].Language != "text" || !strings.Contains(chunks[0].Content, "CREATE TABLE users") {
+			t.Errorf("Expected text chunks of the SQL file, got %+v", chunks)
+		}
+	}
+
+	if err := validateUnsupportedFiles("index"); err == nil {
+		t.Error("Expected an unknown unsupported_files mode to be rejected")
+	}
+}
+
+func TestIndex_UnsupportedFilesText(t *testing.T) {
+	idx, store, _ := newIncrementalTestIndexer(t)
+	tokenChunker, err := NewTokenChunkerWithEncoding(DefaultMaxTokens, DefaultOverlapTokens, ApproximateEncoding)
+	if err != nil {
+		t.Fatalf("Failed to create token chunker: %v", err)
+	}
+	idx.chunker.tokenChunker = tokenChunker
+	idx.config.Chunking.UnsupportedFiles = UnsupportedFilesText
+	idx.scanner = newConfiguredScanner(idx.config)
+
+	repoDir := t.TempDir()
+	writeTestFiles(t, repoDir, map[string]string{
+		"db/schema.sql": "CREATE TABLE users (id INTEGER PRIMARY KEY);\n",
+		"src/app.ts":    "export function start() {\n  return 1;\n}\n",
+	})
+
+	job, _ := idx.Index(repoDir, false, false)
+	if job.Status != models.IndexStatusCompleted {
+		t.Fatalf("Indexing failed: %s", job.Error)
+	}
+	if store.countByFile(filepath.Join(repoDir, "db/schema.sql")) == 0 {
+		t.Error("Expected the SQL file to be indexed as text")
+	}
+	if stats := job.GetStats(); stats.FilesByLanguage["text"] != 1 || stats.FilesFailed != 0 {
+		t.Errorf("Expected 1 text file and no failures, got %+v", stats)
+	}
+}
+
+func TestProcessFiles_UnsupportedFiles(t *testing.T) {
+	for mode, counted := range map[string]int{UnsupportedFilesReport: 1, UnsupportedFilesSkip: 0} {
+		idx := newTestIndexer(t)
+		idx.config.Chunking.UnsupportedFiles = mode
+		tmpDir := t.TempDir()
+
+		// A file that reaches chunking with an unsupported type is skipped, not failed
+		notesFile := filepath.Join(tmpDir, "notes.txt")
+		if err := os.WriteFile(notesFile, []byte("release checklist\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		job := &models.IndexJob{ID: "test-job", RepoPath: tmpDir}
+		job.SetFilesTotal(1)
+		if chunks := idx.processFilesInParallel(job, idx.globalSettings(), []string{notesFile}, true, nil); len(chunks) != 0 {
+			t.Errorf("%s: expected no chunks, got %d", mode, len(chunks))
+		}
+
+		stats := job.GetStats()
+		if stats.FilesFailed != 0 || stats.FilesUnsupported != counted {
+			t.Errorf("%s: expected %d unsupported and no failed files, got %+v", mode, counted, stats)
+		}
+		if filesIndexed, _ := job.GetProgress(); filesIndexed != 1 {
+			t.Errorf("%s: expected the file counted as processed, got %d", mode, filesIndexed)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/cache"
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// verifyPageSize is how many stored chunks are fetched at a time when verifying an index
+const verifyPageSize = 512
+
+// maxReportedMismatches caps the mismatching chunks listed in a VerifyResult; all are counted
+const maxReportedMismatches = 100
+
+// ChunkMismatch is a stored chunk whose content no longer matches its content hash
+type ChunkMismatch struct {
+	ID         string `json:"id"`
+	FilePath   string `json:"file_path"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	StoredHash string `json:"stored_hash"`
+	ActualHash string `json:"actual_hash"`
+}
+
+// VerifyResult reports an index integrity check
+type VerifyResult struct {
+	RepoPath       string          `json:"repo_path"`
+	ChunksChecked  int             `json:"chunks_checked"`
+	ChunksUnhashed int             `json:"chunks_unhashed"` // Stored without a hash (content_hash off, or an older version); not checked
+	MismatchCount  int             `json:"mismatch_count"`
+	Mismatches     []ChunkMismatch `json:"mismatches"`     // The first maxReportedMismatches mismatching chunks
+	OrphanedFiles  []string        `json:"orphaned_files"` // Files with stored chunks that no longer exist on disk
+	UncachedFiles  []string        `json:"uncached_files"` // Files on disk with stored chunks but no hash cache entry
+	MissingFiles   []string        `json:"missing_files"`  // Files in the hash cache with chunks, none of them stored
+	Duration       time.Duration   `json:"-"`
+}
+
+// Consistent reports whether the check found no mismatching chunks, and no files orphaned or
+// out of step with the hash cache
+func (r *VerifyResult) Consistent() bool {
+	return r.MismatchCount == 0 && len(r.OrphanedFiles) == 0 && len(r.UncachedFiles) == 0 && len(r.MissingFiles) == 0
+}
+
+// Summary describes the result in one sentence
+func (r *VerifyResult) Summary() string {
+	if r.Consistent() {
+		return fmt.Sprintf("Index is consistent: %d chunks verified", r.ChunksChecked-r.ChunksUnhashed)
+	}
+	return fmt.Sprintf("Found %d chunks not matching their content hash, %d orphaned files, %d files missing from the hash cache "+
+		"and %d cached files without chunks; reindex with force_reindex=true to repair",
+		r.MismatchCount, len(r.OrphanedFiles), len(r.UncachedFiles), len(r.MissingFiles))
+}
+
+// contentHash returns the hash stored with a chunk of the given content
+func contentHash(content string) string {
+	return cache.HashContent([]byte(content))
+}
+
+// VerifyIndex audits a repository's stored chunks without reindexing: each chunk's content is
+// hashed again and compared with the hash stored when it was indexed, files whose chunks are
+// stored but which no longer exist on disk are reported as orphaned, and the stored files are
+// compared with the hash cache, which incremental indexing trusts to skip unchanged files
+func (idx *Indexer) VerifyIndex(ctx context.Context, repoPath string) (*VerifyResult, error) {
+	source, ok := idx.vectorDB.(ChunkScroller)
+	if !ok {
+		return nil, fmt.Errorf("the vector store can't list its chunks")
+	}
+	if idx.IsIndexing(repoPath) {
+		return nil, fmt.Errorf("%w for %s; verify once it finishes", ErrIndexingInProgress, repoPath)
+	}
+
+	// A private copy, so a job using the shared manager keeps its loaded cache
+	hashCache, err := idx.hashManager.LoadCache(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hash cache: %w", err)
+	}
+	return verifyChunks(ctx, source, hashCache, repoPath)
+}
+
+// verifyChunks pages through a repository's chunks in source and checks their content hashes,
+// and their files against the disk and hashCache
+func verifyChunks(ctx context.Context, source ChunkScroller, hashCache *models.FileHashCache, repoPath string) (*VerifyResult, error) {
+	start := time.Now()
+	result := &VerifyResult{
+		RepoPath:      repoPath,
+		Mismatches:    []ChunkMismatch{},
+		OrphanedFiles: []string{},
+		UncachedFiles: []string{},
+		MissingFiles:  []string{},
+	}
+	files := make(map[string]bool)
+
+	err := source.ScrollByRepo(ctx, repoPath, verifyPageSize, false, func(chunk models.CodeChunk) bool {
+		result.ChunksChecked++
+		files[chunk.FilePath] = true
+		if chunk.ContentHash == "" {
+			result.ChunksUnhashed++
+			return ctx.Err() == nil
+		}
+		if actual := contentHash(chunk.Content); actual != chunk.ContentHash {
+			result.MismatchCount++
+			if len(result.Mismatches) < maxReportedMismatches {
+				result.Mismatches = append(result.Mismatches, ChunkMismatch{
+					ID:         chunk.ID,
+					FilePath:   chunk.FilePath,
+					StartLine:  chunk.StartLine,
+					EndLine:    chunk.EndLine,
+					StoredHash: chunk.ContentHash,
+					ActualHash: actual,
+				})
+			}
+		}
+		return ctx.Err() == nil
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored chunks: %w", err)
+	}
+
+	// Without incremental indexing there is no cache to compare with
+	cached := len(hashCache.Hashes) > 0
+	for filePath := range files {
+		if _, err := os.Stat(filePath); errors.Is(err, fs.ErrNotExist) {
+			result.OrphanedFiles = append(result.OrphanedFiles, filePath)
+		} else if _, ok := hashCache.Hashes[filePath]; cached && !ok {
+			// Never reindexed until it changes, or a force reindex
+			result.UncachedFiles = append(result.UncachedFiles, filePath)
+		}
+	}
+	for filePath, entry := range hashCache.Hashes {
+		// Skipped as unchanged, so its chunks are never restored
+		if entry.ChunkCount > 0 && !files[filePath] {
+			result.MissingFiles = append(result.MissingFiles, filePath)
+		}
+	}
+	sort.Strings(result.OrphanedFiles)
+	sort.Strings(result.UncachedFiles)
+	sort.Strings(result.MissingFiles)
+	result.Duration = time.Since(start)
+
+	slog.Info("Verified index", "repo", repoPath, "chunks", result.ChunksChecked,
+		"mismatches", result.MismatchCount, "orphaned_files", len(result.OrphanedFiles),
+		"uncached_files", len(result.UncachedFiles), "missing_files", len(result.MissingFiles), "duration", result.Duration)
+	return result, nil
+}
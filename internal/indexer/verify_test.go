@@ -0,0 +1,154 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+func TestProcessFiles_ContentHash(t *testing.T) {
+	idx := newTestIndexer(t)
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "Service.java")
+	if err := os.WriteFile(file, []byte("public class Service {\n    public void run() {}\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	job := &models.IndexJob{ID: "test-job", RepoPath: tmpDir}
+	job.SetFilesTotal(1)
+	chunks := idx.processFilesInParallel(job, idx.globalSettings(), []string{file}, true, nil)
+	if len(chunks) == 0 {
+		t.Fatal("Expected chunks")
+	}
+	for _, chunk := range chunks {
+		if chunk.ContentHash == "" || chunk.ContentHash != contentHash(chunk.Content) {
+			t.Errorf("Expected the hash of the chunk's content, got %q", chunk.ContentHash)
+		}
+	}
+
+	idx.config.Indexing.ContentHash = false
+	chunks = idx.processFilesInParallel(job, idx.globalSettings(), []string{file}, true, nil)
+	for _, chunk := range chunks {
+		if chunk.ContentHash != "" {
+			t.Errorf("Expected no hash with content_hash disabled, got %q", chunk.ContentHash)
+		}
+	}
+}
+
+func TestVerifyChunks(t *testing.T) {
+	repoDir := t.TempDir()
+	kept := filepath.Join(repoDir, "kept.go")
+	if err := os.WriteFile(kept, []byte("package kept\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	deleted := filepath.Join(repoDir, "deleted.go")
+
+	chunk := func(id, filePath, content string) models.CodeChunk {
+		return models.CodeChunk{ID: id, RepoPath: repoDir, FilePath: filePath, Content: content, StartLine: 1, EndLine: 3, ContentHash: contentHash(content)}
+	}
+	tampered := chunk("tampered", kept, "func Original() {}")
+	tampered.Content = "func Tampered() {}"
+	unhashed := chunk("unhashed", kept, "func Legacy() {}")
+	unhashed.ContentHash = ""
+	otherRepo := chunk("other", "/other/repo/gone.go", "func Other() {}")
+	otherRepo.RepoPath = "/other/repo"
+	source := &mockChunkSource{chunks: []models.CodeChunk{
+		chunk("ok", kept, "func Kept() {}"),
+		tampered,
+		unhashed,
+		chunk("orphan", deleted, "func Gone() {}"),
+		otherRepo,
+	}}
+
+	hashCache := &models.FileHashCache{RepoPath: repoDir, Hashes: map[string]models.FileHash{
+		kept: {Path: kept, ChunkCount: 3},
+	}}
+
+	result, err := verifyChunks(context.Background(), source, hashCache, repoDir)
+	if err != nil {
+		t.Fatalf("verifyChunks failed: %v", err)
+	}
+	if result.ChunksChecked != 4 || result.ChunksUnhashed != 1 {
+		t.Errorf("Expected 4 chunks checked, 1 without a hash, got %d and %d", result.ChunksChecked, result.ChunksUnhashed)
+	}
+	if result.MismatchCount != 1 || len(result.Mismatches) != 1 || result.Mismatches[0].ID != "tampered" {
+		t.Fatalf("Expected the tampered chunk as the only mismatch, got %+v", result.Mismatches)
+	}
+	if result.Mismatches[0].ActualHash != contentHash("func Tampered() {}") || result.Mismatches[0].StoredHash != tampered.ContentHash {
+		t.Errorf("Expected the stored and recomputed hashes, got %+v", result.Mismatches[0])
+	}
+	if len(result.OrphanedFiles) != 1 || result.OrphanedFiles[0] != deleted {
+		t.Errorf("Expected %s as the only orphaned file, got %v", deleted, result.OrphanedFiles)
+	}
+	if result.Consistent() || !strings.Contains(result.Summary(), "1 orphaned files") {
+		t.Errorf("Expected an inconsistent index, got %q", result.Summary())
+	}
+
+	// An index matching its hashes and files is consistent
+	source.chunks = source.chunks[:1]
+	result, err = verifyChunks(context.Background(), source, hashCache, repoDir)
+	if err != nil {
+		t.Fatalf("verifyChunks failed: %v", err)
+	}
+	if !result.Consistent() || result.Summary() != "Index is consistent: 1 chunks verified" {
+		t.Errorf("Expected a consistent index, got %+v", result)
+	}
+}
+
+func TestVerifyChunks_HashCache(t *testing.T) {
+	repoDir := t.TempDir()
+	var files []string
+	for _, name := range []string{"stored.go", "uncached.go", "missing.go", "empty.go"} {
+		file := filepath.Join(repoDir, name)
+		if err := os.WriteFile(file, []byte("package p\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		files = append(files, file)
+	}
+	stored, uncached, missing, empty := files[0], files[1], files[2], files[3]
+
+	chunk := func(id, filePath string) models.CodeChunk {
+		return models.CodeChunk{ID: id, RepoPath: repoDir, FilePath: filePath, Content: id, ContentHash: contentHash(id)}
+	}
+	source := &mockChunkSource{chunks: []models.CodeChunk{chunk("stored", stored), chunk("uncached", uncached)}}
+	hashCache := &models.FileHashCache{RepoPath: repoDir, Hashes: map[string]models.FileHash{
+		stored:  {Path: stored, ChunkCount: 1},
+		missing: {Path: missing, ChunkCount: 2},
+		empty:   {Path: empty}, // Produced no chunks, so none are stored
+	}}
+
+	result, err := verifyChunks(context.Background(), source, hashCache, repoDir)
+	if err != nil {
+		t.Fatalf("verifyChunks failed: %v", err)
+	}
+	if len(result.UncachedFiles) != 1 || result.UncachedFiles[0] != uncached {
+		t.Errorf("Expected %s as the only uncached file, got %v", uncached, result.UncachedFiles)
+	}
+	if len(result.MissingFiles) != 1 || result.MissingFiles[0] != missing {
+		t.Errorf("Expected %s as the only file missing its chunks, got %v", missing, result.MissingFiles)
+	}
+	if result.Consistent() {
+		t.Errorf("Expected an inconsistent index, got %q", result.Summary())
+	}
+
+	// Without a hash cache, as with incremental indexing off, stored files aren't reported
+	result, err = verifyChunks(context.Background(), source, &models.FileHashCache{Hashes: map[string]models.FileHash{}}, repoDir)
+	if err != nil {
+		t.Fatalf("verifyChunks failed: %v", err)
+	}
+	if !result.Consistent() {
+		t.Errorf("Expected a consistent index without a hash cache, got %+v", result)
+	}
+}
+
+func TestVerifyIndex_RequiresScroller(t *testing.T) {
+	idx := newTestIndexer(t)
+	idx.vectorDB = newMockVectorStore()
+	if _, err := idx.VerifyIndex(context.Background(), t.TempDir()); err == nil {
+		t.Error("Expected an error for a vector store that can't list its chunks")
+	}
+}
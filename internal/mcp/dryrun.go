@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"fmt"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/indexer"
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// DryRunReport summarizes what indexing a repository would do
+type DryRunReport struct {
+	RepoPath     string             `json:"repo_path"`
+	FilesScanned int                `json:"files_scanned"`
+	FilesChunked int                `json:"files_chunked"`
+	Chunks       int                `json:"chunks"`
+	Stats        models.IndexStats  `json:"stats"`
+	FailedFiles  []models.FileError `json:"failed_files,omitempty"`
+}
+
+// RunDryRun scans and chunks a repository using the given configuration
+// Nothing is embedded, stored or cached, and neither Ollama nor Qdrant needs to be running.
+func RunDryRun(cfg *config.Config, repoPath string) (*DryRunReport, error) {
+	normalized, err := normalizePath(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repo path: %w", err)
+	}
+
+	idx, err := indexer.NewDryRunIndexer(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create indexer: %w", err)
+	}
+
+	job, err := idx.Index(normalized, false, true)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := job.Snapshot()
+	if snapshot.Status == models.IndexStatusFailed {
+		return nil, fmt.Errorf("dry run failed: %s", snapshot.Error)
+	}
+
+	return &DryRunReport{
+		RepoPath:     normalized,
+		FilesScanned: snapshot.FilesTotal,
+		FilesChunked: snapshot.FilesIndexed,
+		Chunks:       snapshot.ChunksTotal,
+		Stats:        job.GetStats(),
+		FailedFiles:  job.GetFailedFiles(),
+	}, nil
+}
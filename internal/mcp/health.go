@@ -0,0 +1,107 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/embeddings"
+	"github.com/jamaly87/codebase-semantic-search/internal/vectordb"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// ollamaHealthChecker reports Ollama reachability and model availability
+type ollamaHealthChecker interface {
+	ModelStatus() (*embeddings.ModelStatus, error)
+}
+
+// qdrantHealthChecker reports Qdrant reachability and collection status
+type qdrantHealthChecker interface {
+	HealthCheck(ctx context.Context) (*vectordb.HealthStatus, error)
+}
+
+// DependencyStatus is the health of a single external dependency
+type DependencyStatus struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Detail  string `json:"detail,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// HealthReport aggregates the health of all external dependencies
+type HealthReport struct {
+	Healthy      bool               `json:"healthy"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// RunHealthCheck checks Ollama and Qdrant using the given configuration
+// It does not require the collection to be initialized, so it is safe to run when nothing works
+func RunHealthCheck(ctx context.Context, cfg *config.Config) *HealthReport {
+	embeddingsClient := embeddings.NewClient(&cfg.Embeddings)
+
+	vectorConfig := cfg.VectorDB
+	vectorConfig.CollectionName = cfg.ResolveCollectionName()
+	vectorDB, err := vectordb.NewClient(&vectorConfig)
+	if err != nil {
+		return &HealthReport{
+			Healthy: false,
+			Dependencies: []DependencyStatus{
+				{Name: "qdrant", Healthy: false, Error: err.Error()},
+			},
+		}
+	}
+	defer vectorDB.Close()
+
+	return checkHealth(ctx, embeddingsClient, vectorDB)
+}
+
+// checkHealth builds a health report from the given dependency checkers
+func checkHealth(ctx context.Context, ollama ollamaHealthChecker, qdrant qdrantHealthChecker) *HealthReport {
+	var deps []DependencyStatus
+
+	// Ollama: reachability and model availability are reported separately
+	modelStatus, err := ollama.ModelStatus()
+	ollamaDep := DependencyStatus{Name: "ollama", Healthy: modelStatus != nil && modelStatus.Reachable}
+	modelDep := DependencyStatus{Name: "ollama_model", Healthy: modelStatus != nil && modelStatus.ModelAvailable}
+	if modelStatus != nil {
+		modelDep.Detail = modelStatus.Model
+	}
+	if err != nil {
+		if !ollamaDep.Healthy {
+			ollamaDep.Error = err.Error()
+			modelDep.Error = "ollama unreachable"
+		} else {
+			modelDep.Error = err.Error()
+		}
+	}
+	deps = append(deps, ollamaDep, modelDep)
+
+	// Qdrant: reachability and collection status
+	qdrantStatus, err := qdrant.HealthCheck(ctx)
+	qdrantDep := DependencyStatus{Name: "qdrant", Healthy: qdrantStatus != nil && qdrantStatus.Reachable}
+	collectionDep := DependencyStatus{Name: "qdrant_collection", Healthy: qdrantStatus != nil && qdrantStatus.CollectionExists}
+	if qdrantStatus != nil {
+		if qdrantStatus.Version != "" {
+			qdrantDep.Detail = fmt.Sprintf("version %s", qdrantStatus.Version)
+		}
+		collectionDep.Detail = qdrantStatus.Collection
+	}
+	if err != nil {
+		if !qdrantDep.Healthy {
+			qdrantDep.Error = err.Error()
+			collectionDep.Error = "qdrant unreachable"
+		} else {
+			collectionDep.Error = err.Error()
+		}
+	}
+	deps = append(deps, qdrantDep, collectionDep)
+
+	report := &HealthReport{Healthy: true, Dependencies: deps}
+	for _, dep := range deps {
+		if !dep.Healthy {
+			report.Healthy = false
+			break
+		}
+	}
+
+	return report
+}
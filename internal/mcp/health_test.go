@@ -0,0 +1,121 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/embeddings"
+	"github.com/jamaly87/codebase-semantic-search/internal/vectordb"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// Mock Qdrant health checker
+type mockQdrantHealth struct {
+	status *vectordb.HealthStatus
+	err    error
+}
+
+func (m *mockQdrantHealth) HealthCheck(ctx context.Context) (*vectordb.HealthStatus, error) {
+	return m.status, m.err
+}
+
+func newOllamaBackend(t *testing.T, status int, body string) *embeddings.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return embeddings.NewClient(&config.EmbeddingsConfig{
+		Model:     "nomic-embed-text",
+		OllamaURL: server.URL,
+	})
+}
+
+func TestCheckHealth(t *testing.T) {
+	healthyQdrant := &mockQdrantHealth{
+		status: &vectordb.HealthStatus{Reachable: true, Version: "1.12.0", Collection: "code_chunks", CollectionExists: true},
+	}
+
+	tests := []struct {
+		name          string
+		ollama        *embeddings.Client
+		qdrant        *mockQdrantHealth
+		expectHealthy bool
+		unhealthy     []string // Dependencies expected to be unhealthy
+	}{
+		{
+			name:          "all healthy",
+			ollama:        newOllamaBackend(t, http.StatusOK, `{"models":[{"name":"nomic-embed-text:latest"}]}`),
+			qdrant:        healthyQdrant,
+			expectHealthy: true,
+		},
+		{
+			name:          "model missing",
+			ollama:        newOllamaBackend(t, http.StatusOK, `{"models":[]}`),
+			qdrant:        healthyQdrant,
+			expectHealthy: false,
+			unhealthy:     []string{"ollama_model"},
+		},
+		{
+			name:          "ollama down",
+			ollama:        newOllamaBackend(t, http.StatusServiceUnavailable, "unavailable"),
+			qdrant:        healthyQdrant,
+			expectHealthy: false,
+			unhealthy:     []string{"ollama", "ollama_model"},
+		},
+		{
+			name:   "qdrant down",
+			ollama: newOllamaBackend(t, http.StatusOK, `{"models":[{"name":"nomic-embed-text"}]}`),
+			qdrant: &mockQdrantHealth{
+				status: &vectordb.HealthStatus{Collection: "code_chunks"},
+				err:    errors.New("connection refused"),
+			},
+			expectHealthy: false,
+			unhealthy:     []string{"qdrant", "qdrant_collection"},
+		},
+		{
+			name:   "collection missing",
+			ollama: newOllamaBackend(t, http.StatusOK, `{"models":[{"name":"nomic-embed-text"}]}`),
+			qdrant: &mockQdrantHealth{
+				status: &vectordb.HealthStatus{Reachable: true, Collection: "code_chunks"},
+				err:    errors.New("collection code_chunks does not exist"),
+			},
+			expectHealthy: false,
+			unhealthy:     []string{"qdrant_collection"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := checkHealth(context.Background(), tt.ollama, tt.qdrant)
+
+			if report.Healthy != tt.expectHealthy {
+				t.Errorf("Expected healthy=%v, got %v", tt.expectHealthy, report.Healthy)
+			}
+
+			if len(report.Dependencies) != 4 {
+				t.Fatalf("Expected 4 dependencies, got %d", len(report.Dependencies))
+			}
+
+			unhealthy := make(map[string]bool)
+			for _, name := range tt.unhealthy {
+				unhealthy[name] = true
+			}
+
+			for _, dep := range report.Dependencies {
+				if dep.Healthy == unhealthy[dep.Name] {
+					t.Errorf("Dependency %s: expected healthy=%v, got %v (error: %s)",
+						dep.Name, !unhealthy[dep.Name], dep.Healthy, dep.Error)
+				}
+				if !dep.Healthy && dep.Error == "" {
+					t.Errorf("Dependency %s is unhealthy but has no error", dep.Name)
+				}
+			}
+		})
+	}
+}
@@ -2,31 +2,76 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/jamaly87/codebase-semantic-search/internal/embeddings"
 	"github.com/jamaly87/codebase-semantic-search/internal/indexer"
 	"github.com/jamaly87/codebase-semantic-search/internal/search"
 	"github.com/jamaly87/codebase-semantic-search/internal/vectordb"
 	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+	"github.com/jamaly87/codebase-semantic-search/pkg/logging"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
+// warmUpTimeout bounds the startup model load; large models can take a while to load
+const warmUpTimeout = 2 * time.Minute
+
+// dimensionProbeTimeout bounds the startup dimension probe, which delays the server until it
+// answers; on timeout the configured dimensions are used
+const dimensionProbeTimeout = 30 * time.Second
+
 // Server represents the MCP server
 type Server struct {
-	config    *config.Config
-	mcpServer *server.MCPServer
-	indexer   *indexer.Indexer
-	searcher  *search.Searcher
+	config           *config.Config
+	logCloser        io.Closer // Log file, nil when logging only to stderr
+	mcpServer        *server.MCPServer
+	indexer          *indexer.Indexer
+	searcher         *search.Searcher
+	embeddingsClient *embeddings.Client
+	vectorDB         *vectordb.Client
+	feedback         *search.FeedbackStore // nil unless search.feedback is enabled
+	resultCache      *search.ResultCache   // nil when search.result_cache_ttl_seconds is 0
+	shuttingDown     atomic.Bool           // Set by Shutdown; tool calls are rejected from then on
 }
 
 // NewServer creates a new MCP server instance
 func NewServer(cfg *config.Config) (*Server, error) {
+	// Set up logging before anything else can log: stdout carries the MCP protocol,
+	// so all logs go to stderr and the log file only
+	logCloser, err := logging.Setup(cfg.Logging)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up logging: %w", err)
+	}
+
+	slog.Info("Configuration loaded successfully",
+		"embedding_model", cfg.Embeddings.Model,
+		"ollama_url", cfg.Embeddings.OllamaURL,
+		"languages", "Java, Kotlin, TypeScript, JavaScript, Ruby, PHP, Swift",
+		"log_level", cfg.Logging.Level)
+	if logCloser != nil {
+		slog.Info("Logging to file", "directory", cfg.Logging.Directory)
+	}
+	for _, warning := range cfg.NormalizationWarnings() {
+		slog.Warn(warning)
+	}
+
 	// Create embeddings client
 	embeddingsClient := embeddings.NewClient(&cfg.Embeddings)
 
+	// Match the configured dimensions to the model before the collection is created with them
+	if err := detectDimensions(embeddingsClient, cfg); err != nil {
+		return nil, err
+	}
+	// The per-model collection name includes the vector size, so it is resolved after detection
+	cfg.VectorDB.CollectionName = cfg.ResolveCollectionName()
+
 	// Create vector database client
 	vectorDB, err := vectordb.NewClient(&cfg.VectorDB)
 	if err != nil {
@@ -44,14 +89,49 @@ func NewServer(cfg *config.Config) (*Server, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create indexer: %w", err)
 	}
+	embeddingsClient.SetTokenizer(idx.Tokenizer())
+
+	// Load the model in the background, so the first search doesn't pay for it
+	// and a missing model is reported early
+	go warmUpEmbeddings(embeddingsClient)
 
 	// Create searcher
 	searcher := search.NewSearcher(&cfg.Search, embeddingsClient, vectorDB)
+	reranker, err := search.NewReranker(&cfg.Search, cfg.Embeddings.OllamaURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reranker: %w", err)
+	}
+	searcher.SetReranker(reranker)
+	searcher.SetRerankerURL(cfg.Embeddings.OllamaURL)
+	searcher.SetTaskPrefixes(cfg.Embeddings.TaskPrefixes())
+
+	// Relevance feedback is opt-in
+	var feedback *search.FeedbackStore
+	if cfg.Search.Feedback {
+		feedback, err = search.NewFeedbackStore(cfg.Cache.Directory)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load relevance feedback: %w", err)
+		}
+		searcher.SetFeedback(feedback)
+	}
+
+	// Cached results are served until the repository's index changes or they expire
+	var resultCache *search.ResultCache
+	if cfg.Search.ResultCacheTTLSeconds > 0 {
+		resultCache = search.NewResultCache(time.Duration(cfg.Search.ResultCacheTTLSeconds)*time.Second, cfg.Search.ResultCacheSize)
+		searcher.SetResultCache(resultCache)
+		idx.SetOnIndexChanged(resultCache.Invalidate)
+	}
 
 	s := &Server{
-		config:   cfg,
-		indexer:  idx,
-		searcher: searcher,
+		config:           cfg,
+		logCloser:        logCloser,
+		indexer:          idx,
+		searcher:         searcher,
+		embeddingsClient: embeddingsClient,
+		vectorDB:         vectorDB,
+		feedback:         feedback,
+		resultCache:      resultCache,
 	}
 
 	// Create MCP server
@@ -68,16 +148,49 @@ func NewServer(cfg *config.Config) (*Server, error) {
 
 	s.mcpServer = mcpServer
 
-	log.Printf("MCP server initialized: %s v%s", cfg.Server.Name, cfg.Server.Version)
-	log.Printf("Registered %d tools", len(tools))
+	slog.Info("MCP server initialized", "name", cfg.Server.Name, "version", cfg.Server.Version)
+	slog.Info("Registered tools", "count", len(tools))
 
 	return s, nil
 }
 
+// detectDimensions probes the model's output dimension and reconciles the config with it
+// An unreachable Ollama is only logged, keeping the configured dimensions; a mismatch that
+// auto-detection is disabled for is returned.
+func detectDimensions(client *embeddings.Client, cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dimensionProbeTimeout)
+	defer cancel()
+
+	detected, err := client.ProbeDimension(ctx)
+	if err != nil {
+		slog.Warn("Could not detect the embedding dimension; using the configured dimensions", "error", err)
+		return nil
+	}
+	slog.Info("Detected embedding dimension", "model", cfg.Embeddings.Model, "dimension", detected)
+
+	if err := embeddings.ReconcileDimensions(cfg, detected); err != nil {
+		return fmt.Errorf("embedding dimension mismatch: %w", err)
+	}
+	return nil
+}
+
+// warmUpEmbeddings loads the embedding model, logging a warning if it is unavailable
+func warmUpEmbeddings(client *embeddings.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), warmUpTimeout)
+	defer cancel()
+
+	if err := client.WarmUp(ctx); err != nil {
+		slog.Warn("Embedding model warmup failed; searches and indexing will fail until this is fixed", "error", err)
+	}
+}
+
 // createToolHandler creates a handler function for a given tool name
 func (s *Server) createToolHandler(toolName string) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		log.Printf("Handling tool call: %s", toolName)
+		if s.shuttingDown.Load() {
+			return errorResult("server is shutting down"), nil
+		}
+		slog.Info("Handling tool call", "tool", toolName)
 
 		// Extract and type assert arguments from request
 		var args map[string]interface{}
@@ -95,33 +208,110 @@ func (s *Server) createToolHandler(toolName string) server.ToolHandlerFunc {
 		switch toolName {
 		case "semantic_search":
 			return s.handleSemanticSearch(ctx, args)
+		case "compare_queries":
+			return s.handleCompareQueries(ctx, args)
+		case "multi_search":
+			return s.handleMultiSearch(ctx, args)
+		case "find_symbol":
+			return s.handleFindSymbol(ctx, args)
+		case "list_symbols":
+			return s.handleListSymbols(ctx, args)
+		case "find_similar":
+			return s.handleFindSimilar(ctx, args)
 		case "index_codebase":
 			return s.handleIndexCodebase(ctx, args)
 		case "clear_cache":
 			return s.handleClearCache(ctx, args)
 		case "get_index_status":
 			return s.handleGetIndexStatus(ctx, args)
+		case "explain_file":
+			return s.handleExplainFile(ctx, args)
+		case "preview_chunks":
+			return s.handlePreviewChunks(ctx, args)
+		case "supported_languages":
+			return s.handleSupportedLanguages(ctx, args)
+		case "export_index":
+			return s.handleExportIndex(ctx, args)
+		case "import_index":
+			return s.handleImportIndex(ctx, args)
+		case "record_feedback":
+			return s.handleRecordFeedback(ctx, args)
+		case "healthcheck":
+			return s.handleHealthCheck(ctx, args)
+		case "collection_stats":
+			return s.handleCollectionStats(ctx, args)
+		case "reembed_index":
+			return s.handleReembedIndex(ctx, args)
+		case "reindex_metadata":
+			return s.handleReindexMetadata(ctx, args)
+		case "verify_index":
+			return s.handleVerifyIndex(ctx, args)
 		default:
 			return errorResult(fmt.Sprintf("unknown tool: %s", toolName)), nil
 		}
 	}
 }
 
-// Start starts the MCP server with stdio transport
+// Start starts the MCP server with stdio transport and runs until ctx is cancelled or stdin closes
 func (s *Server) Start(ctx context.Context) error {
-	log.Printf("Starting MCP server on stdio transport...")
+	slog.Info("Starting MCP server on stdio transport")
+
+	protocolOut, restoreStdout := reserveStdout()
+	defer restoreStdout()
 
-	// Start the server with stdio transport
-	if err := server.ServeStdio(s.mcpServer); err != nil {
+	if err := s.serveStdio(ctx, os.Stdin, protocolOut); err != nil && !errors.Is(err, context.Canceled) {
 		return fmt.Errorf("server error: %w", err)
 	}
 
 	return nil
 }
 
+// serveStdio runs the stdio transport, writing only MCP protocol frames to protocolOut
+// Transport errors are logged through slog, so they land in stderr and the log file.
+func (s *Server) serveStdio(ctx context.Context, in io.Reader, protocolOut io.Writer) error {
+	stdio := server.NewStdioServer(s.mcpServer)
+	stdio.SetErrorLogger(slog.NewLogLogger(slog.Default().Handler(), slog.LevelError))
+	return stdio.Listen(ctx, in, protocolOut)
+}
+
+// reserveStdout returns the real stdout for protocol frames and points os.Stdout at stderr,
+// so a stray fmt.Print anywhere in the process cannot corrupt the JSON-RPC stream
+// The returned function restores os.Stdout.
+func reserveStdout() (*os.File, func()) {
+	protocolOut := os.Stdout
+	os.Stdout = os.Stderr
+	return protocolOut, func() { os.Stdout = protocolOut }
+}
+
+// Shutdown stops accepting tool calls, waits for running indexing jobs to stop at a safe
+// checkpoint (or until ctx is done), then closes the server
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shuttingDown.Store(true)
+
+	var waitErr error
+	if s.indexer != nil {
+		slog.Info("Waiting for indexing jobs to stop")
+		waitErr = s.indexer.Shutdown(ctx)
+		if waitErr != nil {
+			slog.Warn("Indexing jobs did not stop in time", "error", waitErr)
+		}
+	}
+
+	return errors.Join(waitErr, s.Close())
+}
+
 // Close closes the server and cleans up resources
 func (s *Server) Close() error {
-	log.Printf("Shutting down MCP server...")
-	// TODO: Close connections to Qdrant, cleanup resources
-	return nil
+	slog.Info("Shutting down MCP server")
+	var errs []error
+	if s.indexer != nil {
+		errs = append(errs, s.indexer.Close())
+	}
+	if s.vectorDB != nil {
+		errs = append(errs, s.vectorDB.Close())
+	}
+	if s.logCloser != nil {
+		errs = append(errs, s.logCloser.Close())
+	}
+	return errors.Join(errs...)
 }
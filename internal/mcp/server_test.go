@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestServeStdio_OnlyProtocolFramesOnStdout(t *testing.T) {
+	// Stand in for the process stdout, so stray writes to it can be detected
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	realStdout := os.Stdout
+	os.Stdout = stdoutWriter
+	defer func() { os.Stdout = realStdout }()
+
+	// A tool that prints and logs every way the codebase (or a dependency) might
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	mcpServer.AddTool(mcp.NewTool("noisy"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		fmt.Println("stray print")
+		log.Printf("standard log line")
+		slog.Info("structured log line")
+		return successResult("done"), nil
+	})
+	s := &Server{mcpServer: mcpServer}
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"noisy","arguments":{}}}`,
+	}, "\n") + "\n"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	protocolOut, restoreStdout := reserveStdout()
+	err = s.serveStdio(ctx, strings.NewReader(input), protocolOut)
+	restoreStdout()
+	stdoutWriter.Close()
+	if err != nil {
+		t.Fatalf("serveStdio failed: %v", err)
+	}
+
+	output, err := io.ReadAll(stdoutReader)
+	if err != nil {
+		t.Fatalf("Failed to read stdout: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 protocol responses on stdout, got %d:\n%s", len(lines), output)
+	}
+	for _, line := range lines {
+		var frame struct {
+			JSONRPC string          `json:"jsonrpc"`
+			ID      json.RawMessage `json:"id"`
+		}
+		if err := json.Unmarshal([]byte(line), &frame); err != nil || frame.JSONRPC != "2.0" {
+			t.Errorf("Non-protocol output on stdout: %q", line)
+		}
+	}
+	if !strings.Contains(string(output), "done") {
+		t.Errorf("Expected the tool result on stdout, got:\n%s", output)
+	}
+}
+
+func TestShutdown_RejectsToolCalls(t *testing.T) {
+	s := &Server{}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	handler := s.createToolHandler("supported_languages")
+	result, err := handler(context.Background(), mcp.CallToolRequest{})
+	if err != nil {
+		t.Fatalf("Handler returned an error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "shutting down") {
+		t.Errorf("Expected tool calls to be rejected during shutdown, got %+v", result)
+	}
+}
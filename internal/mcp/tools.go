@@ -1,13 +1,24 @@
 package mcp
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
 	"github.com/jamaly87/codebase-semantic-search/internal/search"
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -23,12 +34,17 @@ func (s *Server) getTools() []mcp.Tool {
 				Properties: map[string]interface{}{
 					"query": map[string]interface{}{
 						"type":        "string",
-						"description": "Natural language search query describing what code to find. Examples: 'JWT token validation', 'CSV file parsing', 'database connection setup', 'user authentication logic', 'error handling for API requests'. Can be short phrases or questions.",
+						"description": "Natural language search query describing what code to find. Examples: 'JWT token validation', 'CSV file parsing', 'database connection setup', 'user authentication logic', 'error handling for API requests'. Can be short phrases or questions. Quote exact phrases (\"jwt token\") and use uppercase OR/AND (auth OR login) to control keyword matching.",
 					},
 					"repo_path": map[string]interface{}{
 						"type":        "string",
 						"description": "Absolute path to the repository to search",
 					},
+					"additional_repo_paths": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Absolute paths of further repositories to search together with repo_path. Results are merged by score; a repository that fails to search is reported as a warning instead of failing the whole search.",
+					},
 					"limit": map[string]interface{}{
 						"type":        "number",
 						"description": "Maximum number of results to return (default: 5)",
@@ -40,10 +56,217 @@ func (s *Server) getTools() []mcp.Tool {
 						"enum":        []string{"function", "file", "all"},
 						"default":     "all",
 					},
+					"auto_index": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Incrementally reindex the repository first if files changed since it was last indexed (default: false)",
+						"default":     false,
+					},
+					"include_content": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return each result's complete chunk content instead of a 3-line preview, so the code can be read without opening the file. Very long chunks are cut at 8000 characters (default: false)",
+						"default":     false,
+					},
+					"file_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Only search the chunks of this one file (absolute, or relative to repo_path), ranking its parts by relevance to the query. Use this to find where in a large file something happens. Cannot be combined with additional_repo_paths.",
+					},
+					"output_format": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"text", "ndjson"},
+						"description": "\"text\" (default) for a readable list, or \"ndjson\" for one JSON object per result per line (file_path, start_line, end_line, score, ..., and content with include_content), which can be processed incrementally. Warnings, if any, follow as a separate text item",
+						"default":     "text",
+					},
+					"unique_files": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return each file at most once, represented by its best-matching chunk, so results are a ranked list of distinct files. Use this to find which files implement a feature (default: false)",
+						"default":     false,
+					},
+					"exclude_paths": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Leave out files matching these patterns for this search only, without reindexing, e.g. [\"legacy/\", \"**/*_test.go\"]. Same syntax as ignore_patterns, relative to the repository; a pattern matching a directory excludes everything under it. Excluded files are dropped from the over-fetched candidates, so fewer results than the limit may be returned when they make up most of the matches.",
+					},
+					"sort_by": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{search.SortByScore, search.SortByLocation},
+						"description": "Order of the results: \"score\" (default) lists the best matches first; \"location\" lists the same top results by file path and line, for reading matches in a file top to bottom",
+						"default":     search.SortByScore,
+					},
+					"include_adjacent": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return, for each result, the chunks immediately before and after it in the same file, so the match can be read with its surrounding code. Useful for 'how does X work' questions where one chunk lacks context. Shown as previews, or in full with include_content (default: false)",
+						"default":     false,
+					},
+					"since": map[string]interface{}{
+						"type":        "string",
+						"description": "Only return code (re)indexed at or after this time, e.g. to review recently changed code: an RFC 3339 timestamp (\"2026-03-01T12:00:00Z\"), a date (\"2026-03-01\") or an age such as \"90m\", \"24h\", \"7d\" or \"2w\". With incremental indexing, a file is reindexed when it changes. Code indexed before indexing times were recorded never matches; reindex with force_reindex=true to record them",
+					},
+					"until": map[string]interface{}{
+						"type":        "string",
+						"description": "Only return code indexed at or before this time, in the same formats as since (a date includes the whole day)",
+					},
 				},
 				Required: []string{"query", "repo_path"},
 			},
 		},
+		{
+			Name:        "compare_queries",
+			Description: "Compare the semantic_search results of two phrasings of a query on the same repository. Use this when tuning a query or debugging ranking: returns the results both queries share (with each query's rank and score), and those only one of them returns.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query_a": map[string]interface{}{
+						"type":        "string",
+						"description": "First query",
+					},
+					"query_b": map[string]interface{}{
+						"type":        "string",
+						"description": "Second query",
+					},
+					"repo_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the repository to search",
+					},
+					"unique_files": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Compare distinct files, each represented by its best-matching chunk, as with semantic_search (default: false)",
+						"default":     false,
+					},
+				},
+				Required: []string{"query_a", "query_b", "repo_path"},
+			},
+		},
+		{
+			Name:        "multi_search",
+			Description: "Search a repository with several queries at once, e.g. rephrasings or related terms of one information need ('user login', 'authentication', 'sign in session'), and get one merged result list. Results are fused by reciprocal rank: code found by several queries ranks first. Use this instead of several semantic_search calls when the right wording is unclear.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"queries": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": fmt.Sprintf("Natural language queries, searched separately and merged (at most %d)", search.MaxMultiQueries),
+					},
+					"repo_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the repository to search",
+					},
+					"include_content": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include each result's complete code instead of a short preview (default: false)",
+						"default":     false,
+					},
+				},
+				Required: []string{"queries", "repo_path"},
+			},
+		},
+		{
+			Name:        "find_symbol",
+			Description: "Find code by function, method, or class name. Use this tool instead of semantic_search when the user already knows the identifier, e.g. 'where is getUserById defined?', 'show me the PaymentService class', 'jump to parseConfig'. Matches names exactly or partially (prefix/substring) without generating embeddings, so it is fast and precise. Results are ranked by how closely the name matches.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"symbol": map[string]interface{}{
+						"type":        "string",
+						"description": "Function, method, or class name (or part of one). Case-sensitive for partial matches. Examples: 'getUserById', 'PaymentService', 'parse'",
+					},
+					"repo_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the repository to search",
+					},
+					"limit": map[string]interface{}{
+						"type":        "number",
+						"description": "Maximum number of results to return (default: 5)",
+						"default":     5,
+					},
+				},
+				Required: []string{"symbol", "repo_path"},
+			},
+		},
+		{
+			Name:        "list_symbols",
+			Description: "List the functions, methods, and classes of an indexed repository with their file and line range, without code. Use this tool to get a quick outline for navigation, e.g. 'what functions are in the auth package?', 'list all classes under src/services', 'which methods mention Payment?'. Cheaper than semantic_search; no embeddings are generated.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"repo_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the repository",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Only symbols whose function or class name contains this text (case-insensitive)",
+					},
+					"file_glob": map[string]interface{}{
+						"type":        "string",
+						"description": "Only symbols in files matching this pattern, relative to the repository root. Examples: 'src/auth/**', '*.go'",
+					},
+					"limit": map[string]interface{}{
+						"type":        "number",
+						"description": "Maximum number of symbols to return (default: 200)",
+						"default":     search.DefaultSymbolListLimit,
+					},
+				},
+				Required: []string{"repo_path"},
+			},
+		},
+		{
+			Name:        "find_similar",
+			Description: "Find code similar to an existing piece of code in the index. Use this tool when the user points at specific code and asks 'where else do we do this?', 'are there duplicates of this function?', or 'find other implementations like this one'. Identify the code by file and line; the indexed chunk covering that line is used as the query and is excluded from the results.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"repo_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the repository to search",
+					},
+					"file_path": map[string]interface{}{
+						"type":        "string",
+						"description": "File containing the reference code (absolute, or relative to repo_path)",
+					},
+					"line": map[string]interface{}{
+						"type":        "number",
+						"description": "Any line inside the reference code (1-based)",
+					},
+					"limit": map[string]interface{}{
+						"type":        "number",
+						"description": "Maximum number of results to return (default: 5)",
+						"default":     5,
+					},
+				},
+				Required: []string{"repo_path", "file_path", "line"},
+			},
+		},
+		{
+			Name:        "record_feedback",
+			Description: "Record whether a search result was helpful for a query. Use this when a result clearly answered the user's question, or was clearly irrelevant. Votes are tallied per repository by file path category (test, source, generated, other) and chunk type, and once enough accumulate they nudge future rankings by at most 25%. Requires search.feedback to be enabled. Returns the feedback recorded so far for the repository.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"repo_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the repository that was searched",
+					},
+					"file_path": map[string]interface{}{
+						"type":        "string",
+						"description": "File of the result (absolute, or relative to repo_path)",
+					},
+					"helpful": map[string]interface{}{
+						"type":        "boolean",
+						"description": "true if the result was helpful, false if it was irrelevant",
+					},
+					"chunk_type": map[string]interface{}{
+						"type":        "string",
+						"description": "Type of the result as shown in the search output (function, method, class, file)",
+					},
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "The search query the result was returned for",
+					},
+				},
+				Required: []string{"repo_path", "file_path", "helpful"},
+			},
+		},
 		{
 			Name:        "index_codebase",
 			Description: "Index a code repository to enable semantic search. Use this tool when: (1) First time working with a new repository, (2) User explicitly asks to 'index', 'scan', or 'prepare' a codebase, (3) Before the first search query on a repository. This scans all code files, breaks them into chunks, generates embeddings using the local LLM, and stores them in the vector database. Supports incremental indexing (only reprocesses changed files). Required before semantic_search can work on a repository.",
@@ -59,6 +282,20 @@ func (s *Server) getTools() []mcp.Tool {
 						"description": "Force full reindex even if repository is already indexed (default: false)",
 						"default":     false,
 					},
+					"dry_run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Scan and chunk only, reporting file, chunk, and language counts without generating embeddings or storing anything. Useful for tuning ignore patterns and chunk sizes (default: false)",
+						"default":     false,
+					},
+					"changed_since": map[string]interface{}{
+						"type":        "string",
+						"description": "Git ref (commit, branch, or tag). Only files changed between this ref and the working tree are reindexed, and chunks of files deleted since are removed. Fast per-PR indexing; the repository must be a git repository",
+					},
+					"languages": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Only index files of these languages (e.g. [\"typescript\"]), leaving other languages' chunks as they are. Use after changes to one stack of a polyglot repository (default: every language)",
+					},
 				},
 				Required: []string{"repo_path"},
 			},
@@ -79,7 +316,7 @@ func (s *Server) getTools() []mcp.Tool {
 		},
 		{
 			Name:        "get_index_status",
-			Description: "Get indexing status and statistics for a repository. Use this tool when: (1) User asks if a repository is indexed or 'is this repo ready?', (2) User asks 'how many files are indexed?', (3) Checking if indexing is needed before a search, (4) User asks about index freshness or 'when was this indexed?'. Returns: total files indexed, number of code chunks, last index timestamp, and repository status.",
+			Description: "Get indexing status and statistics for a repository. Use this tool when: (1) User asks if a repository is indexed or 'is this repo ready?', (2) User asks 'how many files are indexed?', (3) Checking if indexing is needed before a search, (4) User asks about index freshness or 'when was this indexed?'. Returns: total files indexed, number of code chunks, last index timestamp, and repository status, with an estimated time left (eta) while indexing is running.",
 			InputSchema: mcp.ToolInputSchema{
 				Type: "object",
 				Properties: map[string]interface{}{
@@ -91,6 +328,152 @@ func (s *Server) getTools() []mcp.Tool {
 				Required: []string{"repo_path"},
 			},
 		},
+		{
+			Name:        "explain_file",
+			Description: "Explain why a file was or wasn't indexed. Use this tool when: (1) User asks 'why isn't my file showing up in search?', (2) A file expected in results never appears, (3) Debugging ignore patterns or size limits. Returns: detected language (or unsupported), the ignore pattern that matched, file size against the size limit, whether the file is in the index cache, and how many chunks it produced.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"repo_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the repository",
+					},
+					"file_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the file, absolute or relative to repo_path",
+					},
+				},
+				Required: []string{"repo_path", "file_path"},
+			},
+		},
+		{
+			Name:        "preview_chunks",
+			Description: "Show how a file would be chunked, without embedding or storing anything. Use this tool when: (1) Tuning chunk sizes, overlap or AST settings, (2) Search results cut a function apart or lump unrelated code together, (3) Checking whether a language gets AST chunks or falls back to token-based chunks. Returns: the chunking strategy, the token limits for the file's size, and each chunk's type, line range, name, size in bytes and tokens (and content with include_content).",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"repo_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the repository, whose settings (including its .semantic-search.yaml) are used",
+					},
+					"file_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the file, absolute or relative to repo_path",
+					},
+					"include_content": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include each chunk's content (default: false)",
+						"default":     false,
+					},
+				},
+				Required: []string{"repo_path", "file_path"},
+			},
+		},
+		{
+			Name:        "supported_languages",
+			Description: "List the languages the indexer recognizes, with their file extensions, whether AST chunking (functions, classes, methods) is available or files fall back to token-based chunks, and whether chunking.boundary_patterns customizes its chunk boundaries. Every language is built in: detection doesn't depend on the config. Use this to check why files of some language are not indexed or produce coarse chunks.",
+			InputSchema: mcp.ToolInputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "export_index",
+			Description: "Admin tool: export every indexed chunk of a repository to a JSON Lines file, one chunk per line. Use this for backups, migrating to another vector database, or offline analysis. With include_vectors the export can be imported into a fresh collection without re-embedding, and the repository's file hash cache is saved next to it as <output_path>.hashes.json so the import can restore incremental indexing.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"repo_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the indexed repository",
+					},
+					"output_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path of the JSONL file to write; an existing file is overwritten",
+					},
+					"include_vectors": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include each chunk's embedding vector (default: false)",
+						"default":     false,
+					},
+				},
+				Required: []string{"repo_path", "output_path"},
+			},
+		},
+		{
+			Name:        "import_index",
+			Description: "Admin tool: import chunks from a JSON Lines file written by export_index with include_vectors, storing them with their saved embeddings. Use this to restore an index from a backup or move it to a new collection or backend without re-embedding. Every vector must match the collection's configured dimension. The file hash cache exported alongside (<input_path>.hashes.json) is restored too, so running index_codebase afterwards only picks up files changed since the export; without it, that run reindexes every file.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"input_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path of the JSONL file to import",
+					},
+				},
+				Required: []string{"input_path"},
+			},
+		},
+		{
+			Name:        "reembed_index",
+			Description: "Admin tool: embed the already indexed chunks again with another embedding model, into a new collection sized for that model's vectors. Use this to try a different model without rescanning or rechunking the code: the content stored with each chunk is embedded again. Searches keep using the current collection; set embeddings.model and vectordb.collection_name to the new values to switch.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"model": map[string]interface{}{
+						"type":        "string",
+						"description": "Ollama embedding model to embed with (e.g. 'mxbai-embed-large')",
+					},
+					"target_collection": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the collection to create; it must not exist yet",
+					},
+					"repo_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path of the repository to re-embed (default: every repository in the collection)",
+					},
+				},
+				Required: []string{"model", "target_collection"},
+			},
+		},
+		{
+			Name:        "healthcheck",
+			Description: "Check the health of the services semantic search depends on. Use this tool FIRST when: (1) Indexing or searching fails, (2) User reports that 'nothing works', (3) User asks if the search services are running. Returns structured status for Ollama reachability, embedding model availability, Qdrant reachability, and collection status.",
+			InputSchema: mcp.ToolInputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "collection_stats",
+			Description: "Admin tool: show statistics for the whole vector collection, across all repositories. Use this to diagnose a bloated or misconfigured collection. Returns status (green/yellow/grey/red), total point and indexed vector counts, segment count, the collection's vector size next to the configured one, distance metric, and whether vectors and payloads are stored on disk or in memory.",
+			InputSchema: mcp.ToolInputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "reindex_metadata",
+			Description: "Admin tool: create any missing payload indexes (repo_path, file_path, line ranges) on the existing vector collection. Use this once after upgrading when the collection was created by an older version, to make filtered searches fast without re-embedding. Safe to run repeatedly; existing indexes are left alone.",
+			InputSchema: mcp.ToolInputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "verify_index",
+			Description: "Admin tool: audit a repository's index without reindexing. Every stored chunk's content is hashed again and compared with the hash stored when it was indexed (indexing.content_hash), files with stored chunks that no longer exist on disk are reported as orphaned, and stored files are compared with the hash cache that incremental indexing relies on. Use this when search results look stale or wrong, or after restoring or importing an index. Returns: chunks checked, chunks stored without a hash, mismatching chunks, orphaned files, files missing from the hash cache and cached files without stored chunks.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"repo_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Absolute path to the indexed repository",
+					},
+				},
+				Required: []string{"repo_path"},
+			},
+		},
 	}
 }
 
@@ -98,42 +481,640 @@ func (s *Server) getTools() []mcp.Tool {
 
 func (s *Server) handleSemanticSearch(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
 	// Extract arguments
-	query, ok := args["query"].(string)
-	if !ok || query == "" {
+	rawQuery, ok := args["query"].(string)
+	if !ok {
 		return errorResult("query is required and must be a string"), nil
 	}
+	query, truncated, err := normalizeQuery(rawQuery)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
+	repoPaths := []string{repoPath}
+	if extra, ok := args["additional_repo_paths"].([]interface{}); ok {
+		for _, p := range extra {
+			path, ok := p.(string)
+			if !ok || path == "" {
+				return errorResult("additional_repo_paths must be a list of non-empty strings"), nil
+			}
+			path, err := normalizePath(path)
+			if err != nil {
+				return errorResult(fmt.Sprintf("invalid additional_repo_paths entry: %v", err)), nil
+			}
+			repoPaths = append(repoPaths, path)
+		}
+	}
+
+	var filePath string
+	if fp, ok := args["file_path"].(string); ok && fp != "" {
+		if len(repoPaths) > 1 {
+			return errorResult("file_path cannot be combined with additional_repo_paths"), nil
+		}
+		// Chunks are stored with absolute paths, under the normalized repository path
+		if filepath.IsAbs(fp) {
+			if filePath, err = normalizePath(fp); err != nil {
+				return errorResult(fmt.Sprintf("invalid file_path: %v", err)), nil
+			}
+		} else {
+			filePath = filepath.Join(repoPath, fp)
+		}
+	}
+
+	autoIndex := false
+	if ai, ok := args["auto_index"].(bool); ok {
+		autoIndex = ai
+	}
+	includeContent, _ := args["include_content"].(bool)
+	uniqueFiles, _ := args["unique_files"].(bool)
+	outputFormat := outputFormatText
+	if f, ok := args["output_format"].(string); ok && f != "" {
+		if f != outputFormatText && f != outputFormatNDJSON {
+			return errorResult(fmt.Sprintf("output_format must be %q or %q", outputFormatText, outputFormatNDJSON)), nil
+		}
+		outputFormat = f
+	}
+	var excludePaths []string
+	if list, ok := args["exclude_paths"].([]interface{}); ok {
+		for _, p := range list {
+			pattern, ok := p.(string)
+			if !ok || strings.TrimSpace(pattern) == "" {
+				return errorResult("exclude_paths must be a list of non-empty strings"), nil
+			}
+			excludePaths = append(excludePaths, pattern)
+		}
+	}
+	includeAdjacent, _ := args["include_adjacent"].(bool)
+	sortBy := search.SortByScore
+	if sb, ok := args["sort_by"].(string); ok && sb != "" {
+		if sb != search.SortByScore && sb != search.SortByLocation {
+			return errorResult(fmt.Sprintf("sort_by must be %q or %q", search.SortByScore, search.SortByLocation)), nil
+		}
+		sortBy = sb
+	}
+	opts := search.SearchOptions{UniqueFiles: uniqueFiles, ExcludePaths: excludePaths, SortBy: sortBy, IncludeAdjacent: includeAdjacent}
+	since, until, err := timeWindowArgs(args, time.Now())
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+	if (!since.IsZero() || !until.IsZero()) && len(repoPaths) > 1 {
+		return errorResult("since and until cannot be combined with additional_repo_paths"), nil
+	}
+
+	// Note: limit is not used here - searcher uses config.Search.MaxResults
+	// chunk_type filtering can be added in future enhancement
+
+	// Reindex first if the repositories changed since they were last indexed
+	var notice string
+	if autoIndex {
+		for _, path := range repoPaths {
+			notice = strings.TrimSpace(notice + "\n" + s.reindexIfStale(path))
+		}
+	}
+	if truncated {
+		notice = strings.TrimSpace(notice + fmt.Sprintf("\n⚠️  Query truncated to its first %d characters", maxQueryChars))
+	}
+
+	// Perform semantic search
+	var results []search.SearchResult
+	if len(repoPaths) == 1 {
+		filter := models.SearchFilter{RepoPath: repoPath, FilePath: filePath, Since: since, Until: until}
+		results, err = s.searcher.SearchFiltered(ctx, query, filter, opts)
+	} else {
+		var failures []search.RepoSearchError
+		results, failures, err = s.searcher.SearchRepos(ctx, query, repoPaths, opts)
+		for _, failure := range failures {
+			notice = strings.TrimSpace(notice + fmt.Sprintf("\n⚠️  Search failed for %s, results are incomplete: %v", failure.RepoPath, failure.Err))
+		}
+	}
+	if err != nil {
+		return errorResult(fmt.Sprintf("search failed: %v", err)), nil
+	}
+
+	if outputFormat == outputFormatNDJSON {
+		return ndjsonResult(results, includeContent, notice)
+	}
+
+	// Format results for display
+	formattedResults := formatSearchResults(results, includeContent, s.searcher.PreviewOptions(repoPath))
+	if notice != "" {
+		formattedResults = notice + "\n\n" + formattedResults
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formattedResults,
+			},
+		},
+	}, nil
+}
+
+func (s *Server) handleCompareQueries(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	var queries [2]string
+	for i, name := range []string{"query_a", "query_b"} {
+		raw, ok := args[name].(string)
+		if !ok {
+			return errorResult(fmt.Sprintf("%s is required and must be a string", name)), nil
+		}
+		query, _, err := normalizeQuery(raw)
+		if err != nil {
+			return errorResult(fmt.Sprintf("%s: %v", name, err)), nil
+		}
+		queries[i] = query
+	}
+
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+	uniqueFiles, _ := args["unique_files"].(bool)
+	opts := search.SearchOptions{UniqueFiles: uniqueFiles}
+
+	var results [2][]search.SearchResult
+	for i, query := range queries {
+		results[i], err = s.searcher.SearchFiltered(ctx, query, models.SearchFilter{RepoPath: repoPath}, opts)
+		if err != nil {
+			return errorResult(fmt.Sprintf("search for %q failed: %v", query, err)), nil
+		}
+	}
+	comparison := search.CompareResults(results[0], results[1])
+
+	shared := make([]map[string]interface{}, 0, len(comparison.Shared))
+	for _, r := range comparison.Shared {
+		shared = append(shared, map[string]interface{}{
+			"location": resultLocation(r.Result.Chunk),
+			"rank_a":   r.RankA,
+			"rank_b":   r.RankB,
+			"score_a":  roundScore(r.ScoreA),
+			"score_b":  roundScore(r.ScoreB),
+		})
+	}
+	only := func(ranked []search.RankedResult) []map[string]interface{} {
+		out := make([]map[string]interface{}, 0, len(ranked))
+		for _, r := range ranked {
+			out = append(out, map[string]interface{}{
+				"location": resultLocation(r.Result.Chunk),
+				"rank":     r.Rank,
+				"score":    roundScore(r.Result.HybridScore),
+			})
+		}
+		return out
+	}
+
+	return successResult(map[string]interface{}{
+		"query_a": queries[0],
+		"query_b": queries[1],
+		"repo":    repoPath,
+		"shared":  shared,
+		"only_a":  only(comparison.OnlyA),
+		"only_b":  only(comparison.OnlyB),
+	}), nil
+}
+
+func (s *Server) handleMultiSearch(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	rawQueries, ok := args["queries"].([]interface{})
+	if !ok || len(rawQueries) == 0 {
+		return errorResult("queries is required and must be a non-empty list of strings"), nil
+	}
+	if len(rawQueries) > search.MaxMultiQueries {
+		return errorResult(fmt.Sprintf("too many queries (%d, maximum %d)", len(rawQueries), search.MaxMultiQueries)), nil
+	}
+
+	var notice string
+	queries := make([]string, 0, len(rawQueries))
+	for i, raw := range rawQueries {
+		rawQuery, ok := raw.(string)
+		if !ok {
+			return errorResult("queries must be a list of strings"), nil
+		}
+		query, truncated, err := normalizeQuery(rawQuery)
+		if err != nil {
+			return errorResult(fmt.Sprintf("queries[%d]: %v", i, err)), nil
+		}
+		if truncated {
+			notice = strings.TrimSpace(notice + fmt.Sprintf("\n⚠️  Query %d truncated to its first %d characters", i+1, maxQueryChars))
+		}
+		queries = append(queries, query)
+	}
+
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+	includeContent, _ := args["include_content"].(bool)
+
+	results, err := s.searcher.MultiSearch(ctx, queries, repoPath)
+	if err != nil {
+		return errorResult(fmt.Sprintf("search failed: %v", err)), nil
+	}
+
+	formatted := formatFusedResults(queries, results, includeContent, s.searcher.PreviewOptions(repoPath))
+	if notice != "" {
+		formatted = notice + "\n\n" + formatted
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formatted,
+			},
+		},
+	}, nil
+}
+
+// formatFusedResults formats multi_search results like semantic_search results, with the
+// fused score and the queries that found each result
+func formatFusedResults(queries []string, results []search.FusedResult, includeContent bool, preview search.PreviewOptions) string {
+	if len(results) == 0 {
+		return "No results found."
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Found %d results for %d queries:\n", len(results), len(queries)))
+	for i, query := range queries {
+		output.WriteString(fmt.Sprintf("  [%d] %s\n", i+1, query))
+	}
+	output.WriteString("\n")
+
+	for i, result := range results {
+		chunk := result.Chunk
+		matched := make([]string, len(result.Queries))
+		for j, q := range result.Queries {
+			matched[j] = fmt.Sprintf("[%d]", q+1)
+		}
+
+		output.WriteString(fmt.Sprintf("%d. %s\n", i+1, resultLocation(chunk)))
+		output.WriteString(fmt.Sprintf("   fused score: %.4f, best score: %.3f, found by: %s\n",
+			result.FusedScore, result.HybridScore, strings.Join(matched, " ")))
+		output.WriteString(fmt.Sprintf("   Language: %s, Type: %s\n", chunk.Language, chunk.ChunkType))
+
+		if includeContent {
+			writeResultContent(&output, chunk.Content)
+		} else {
+			search.WritePreview(&output, chunk.Content, preview)
+		}
+		output.WriteString("\n")
+	}
+
+	return output.String()
+}
+
+// resultLocation formats a chunk's file, line range and enclosing function or class
+func resultLocation(chunk models.CodeChunk) string {
+	location := fmt.Sprintf("%s:%d-%d", chunk.FilePath, chunk.StartLine, chunk.EndLine)
+	if chunk.FunctionName != "" {
+		location += fmt.Sprintf(" (in %s)", chunk.FunctionName)
+	} else if chunk.ClassName != "" {
+		location += fmt.Sprintf(" (in %s)", chunk.ClassName)
+	}
+	return location
+}
+
+// roundScore rounds a score to 3 decimals, as results are displayed
+func roundScore(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
+
+// Query length limits for semantic_search
+const (
+	// minQueryChars rejects queries too short to mean anything, like "a"
+	minQueryChars = 2
+	// maxQueryChars caps queries before embedding; the embeddings client would
+	// otherwise truncate them silently
+	maxQueryChars = 1000
+)
+
+// maxResultContentChars caps the content returned per result by include_content,
+// so a huge generated chunk can't blow up the response
+const maxResultContentChars = 8000
+
+// semantic_search output formats
+const (
+	outputFormatText   = "text"   // Human-readable list with previews
+	outputFormatNDJSON = "ndjson" // One JSON result object per line
+)
+
+// ndjsonResult returns results as NDJSON text; notices go in a separate content item
+// so every line of the results stays a standalone JSON object
+func ndjsonResult(results []search.SearchResult, includeContent bool, notice string) (*mcp.CallToolResult, error) {
+	var output strings.Builder
+	if err := search.WriteNDJSON(&output, results, includeContent, maxResultContentChars); err != nil {
+		return errorResult(fmt.Sprintf("failed to format results: %v", err)), nil
+	}
+
+	content := []mcp.Content{mcp.TextContent{Type: "text", Text: output.String()}}
+	if notice != "" {
+		content = append(content, mcp.TextContent{Type: "text", Text: notice})
+	}
+	return &mcp.CallToolResult{Content: content}, nil
+}
+
+// repoPathArg returns the required repo_path argument, normalized with normalizePath
+func repoPathArg(args map[string]interface{}) (string, error) {
+	repoPath, ok := args["repo_path"].(string)
+	if !ok || repoPath == "" {
+		return "", errors.New("repo_path is required and must be a string")
+	}
+	normalized, err := normalizePath(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid repo_path: %w", err)
+	}
+	return normalized, nil
+}
+
+// normalizePath returns the canonical spelling of a path: absolute, cleaned (no trailing
+// slash) and with symlinks resolved
+// The repository path is the repository's identity in the index, cache and Qdrant filters,
+// so every spelling of the same directory must map to the same string. Paths that don't
+// exist are only made absolute and cleaned, so e.g. a deleted repository's cache can still be cleared.
+func normalizePath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(absPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return absPath, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// normalizeQuery trims a search query and enforces the length limits
+// Over-long queries are cut at a character boundary and reported as truncated.
+func normalizeQuery(query string) (string, bool, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", false, fmt.Errorf("query is empty")
+	}
+	if utf8.RuneCountInString(query) < minQueryChars {
+		return "", false, fmt.Errorf("query %q is too short (minimum %d characters)", query, minQueryChars)
+	}
+
+	if utf8.RuneCountInString(query) <= maxQueryChars {
+		return query, false, nil
+	}
+
+	runes := []rune(query)
+	truncated := strings.TrimSpace(string(runes[:maxQueryChars]))
+	slog.Warn("Query too long, truncating", "chars", len(runes), "max_chars", maxQueryChars)
+	return truncated, true, nil
+}
+
+// timeWindowArgs returns the since and until arguments, zero when absent
+func timeWindowArgs(args map[string]interface{}, now time.Time) (time.Time, time.Time, error) {
+	var window [2]time.Time
+	for i, name := range []string{"since", "until"} {
+		value, ok := args[name].(string)
+		if !ok || strings.TrimSpace(value) == "" {
+			continue
+		}
+		t, err := parseTimeArg(strings.TrimSpace(value), now, name == "until")
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		window[i] = t
+	}
+	if !window[0].IsZero() && !window[1].IsZero() && window[0].After(window[1]) {
+		return time.Time{}, time.Time{}, fmt.Errorf("since (%s) is after until (%s)", window[0].Format(time.RFC3339), window[1].Format(time.RFC3339))
+	}
+	return window[0], window[1], nil
+}
+
+// parseTimeArg parses an RFC 3339 timestamp, a date, or an age before now ("90m", "24h", "7d",
+// "2w"). A date is the start of that day (UTC), or its end with endOfDay.
+// Times are cut to whole seconds, so repeated searches for the same age share cached results.
+func parseTimeArg(value string, now time.Time, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		return t, nil
+	}
+
+	units := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[value[len(value)-1]]; ok {
+		if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n >= 0 {
+			return now.Add(-time.Duration(n) * unit).Truncate(time.Second), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 timestamp, a YYYY-MM-DD date or an age like \"7d\"", value)
+}
+
+func (s *Server) handleFindSymbol(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	symbol, ok := args["symbol"].(string)
+	if !ok || symbol == "" {
+		return errorResult("symbol is required and must be a string"), nil
+	}
+
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
+	limit := 0
+	if l, ok := args["limit"].(float64); ok {
+		limit = int(l)
+	}
+
+	results, err := s.searcher.FindSymbol(ctx, symbol, repoPath, limit)
+	if err != nil {
+		return errorResult(fmt.Sprintf("symbol search failed: %v", err)), nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formatSearchResults(results, false, s.searcher.PreviewOptions(repoPath)),
+			},
+		},
+	}, nil
+}
+
+func (s *Server) handleListSymbols(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
+	var filter search.SymbolFilter
+	if name, ok := args["name"].(string); ok {
+		filter.Name = name
+	}
+	if glob, ok := args["file_glob"].(string); ok {
+		filter.FileGlob = glob
+	}
+
+	limit := 0
+	if l, ok := args["limit"].(float64); ok {
+		limit = int(l)
+	}
+
+	symbols, total, truncated, err := s.searcher.ListSymbols(ctx, repoPath, filter, limit)
+	if err != nil {
+		return errorResult(fmt.Sprintf("listing symbols failed: %v", err)), nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formatSymbolList(symbols, total, truncated),
+			},
+		},
+	}, nil
+}
+
+func (s *Server) handleFindSimilar(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
+	filePath, ok := args["file_path"].(string)
+	if !ok || filePath == "" {
+		return errorResult("file_path is required and must be a string"), nil
+	}
+	// Chunks are stored with absolute paths, under the normalized repository path
+	if filepath.IsAbs(filePath) {
+		if filePath, err = normalizePath(filePath); err != nil {
+			return errorResult(fmt.Sprintf("invalid file_path: %v", err)), nil
+		}
+	} else {
+		filePath = filepath.Join(repoPath, filePath)
+	}
+
+	line, ok := args["line"].(float64)
+	if !ok || line < 1 {
+		return errorResult("line is required and must be a positive number"), nil
+	}
+
+	limit := 0
+	if l, ok := args["limit"].(float64); ok {
+		limit = int(l)
+	}
+
+	results, err := s.searcher.FindSimilar(ctx, repoPath, filePath, int(line), limit)
+	if err != nil {
+		return errorResult(fmt.Sprintf("similarity search failed: %v", err)), nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formatSearchResults(results, false, s.searcher.PreviewOptions(repoPath)),
+			},
+		},
+	}, nil
+}
+
+// reindexIfStale triggers incremental indexing when files changed since the last index
+// Returns a notice for the search output, or an empty string if the index is fresh.
+// In background mode the search runs against the current index while reindexing proceeds.
+func (s *Server) reindexIfStale(repoPath string) string {
+	if s.indexer.IsIndexing(repoPath) {
+		return "ℹ️  Indexing already in progress; results may not reflect the latest changes."
+	}
+
+	stalePath, stale, err := s.indexer.CheckStale(repoPath)
+	if err != nil {
+		slog.Warn("Staleness check failed", "repo", repoPath, "error", err)
+		return ""
+	}
+	if !stale {
+		return ""
+	}
+
+	slog.Info("Repository is stale, reindexing before search", "repo", repoPath, "changed", stalePath)
+	job, err := s.indexer.Index(repoPath, false, false)
+	if err != nil {
+		return fmt.Sprintf("⚠️  Index is stale but reindexing failed to start: %v", err)
+	}
+
+	// The repository's config may override the global background setting
+	if job.Background {
+		return fmt.Sprintf("ℹ️  Index was stale (%s changed); reindexing started in background (job %s). Results may not reflect the latest changes.", stalePath, job.ID)
+	}
+
+	if status, reason := job.GetStatus(); status == models.IndexStatusFailed {
+		return fmt.Sprintf("⚠️  Index was stale but reindexing failed: %s", reason)
+	}
+
+	return fmt.Sprintf("ℹ️  Index was stale (%s changed); reindexed before searching.", stalePath)
+}
+
+func (s *Server) handleRecordFeedback(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	if s.feedback == nil {
+		return errorResult("relevance feedback is disabled; set search.feedback: true in the config to enable it"), nil
+	}
+
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
+	filePath, ok := args["file_path"].(string)
+	if !ok || filePath == "" {
+		return errorResult("file_path is required and must be a string"), nil
+	}
+	if filepath.IsAbs(filePath) {
+		if filePath, err = normalizePath(filePath); err != nil {
+			return errorResult(fmt.Sprintf("invalid file_path: %v", err)), nil
+		}
+	} else {
+		filePath = filepath.Join(repoPath, filePath)
+	}
 
-	repoPath, ok := args["repo_path"].(string)
-	if !ok || repoPath == "" {
-		return errorResult("repo_path is required and must be a string"), nil
+	helpful, ok := args["helpful"].(bool)
+	if !ok {
+		return errorResult("helpful is required and must be a boolean"), nil
 	}
 
-	// Note: limit is not used here - searcher uses config.Search.MaxResults
-	// chunk_type filtering can be added in future enhancement
-
-	// Perform semantic search
-	results, err := s.searcher.Search(ctx, query, repoPath)
-	if err != nil {
-		return errorResult(fmt.Sprintf("search failed: %v", err)), nil
+	var chunkType models.ChunkType
+	if t, ok := args["chunk_type"].(string); ok && t != "" {
+		chunkType = models.ChunkType(t)
+		if !slices.Contains(models.ChunkTypes, chunkType) {
+			return errorResult(fmt.Sprintf("unknown chunk_type %q (valid: %v)", t, models.ChunkTypes)), nil
+		}
 	}
+	query, _ := args["query"].(string)
 
-	// Format results for display
-	formattedResults := formatSearchResults(results)
+	event := search.FeedbackEvent{
+		RepoPath:  repoPath,
+		Query:     query,
+		FilePath:  filePath,
+		ChunkType: chunkType,
+		Helpful:   helpful,
+	}
+	if err := s.feedback.Record(event); err != nil {
+		return errorResult(fmt.Sprintf("failed to record feedback: %v", err)), nil
+	}
+	// Feedback adjusts scores, so earlier results may no longer be current
+	s.invalidateResults(repoPath)
 
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.TextContent{
-				Type: "text",
-				Text: formattedResults,
-			},
-		},
-	}, nil
+	return successResult(map[string]interface{}{
+		"recorded": event,
+		"stats":    s.feedback.Stats(repoPath),
+	}), nil
 }
 
 func (s *Server) handleIndexCodebase(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
-	repoPath, ok := args["repo_path"].(string)
-	if !ok || repoPath == "" {
-		return errorResult("repo_path is required and must be a string"), nil
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
 	}
 
 	forceReindex := false
@@ -141,24 +1122,58 @@ func (s *Server) handleIndexCodebase(ctx context.Context, args map[string]interf
 		forceReindex = fr
 	}
 
+	dryRun := false
+	if dr, ok := args["dry_run"].(bool); ok {
+		dryRun = dr
+	}
+
+	changedSince, _ := args["changed_since"].(string)
+	if changedSince != "" && (forceReindex || dryRun) {
+		return errorResult("changed_since cannot be combined with force_reindex or dry_run"), nil
+	}
+
+	var languages []string
+	if list, ok := args["languages"].([]interface{}); ok {
+		for _, l := range list {
+			name, ok := l.(string)
+			if !ok || name == "" {
+				return errorResult("languages must be a list of non-empty strings"), nil
+			}
+			languages = append(languages, strings.ToLower(name))
+		}
+	}
+	if changedSince != "" && len(languages) > 0 {
+		return errorResult("changed_since cannot be combined with languages"), nil
+	}
+
 	// Check if cache is inconsistent with Qdrant (cache says indexed but Qdrant has no chunks)
-	if !forceReindex {
+	if !forceReindex && !dryRun && changedSince == "" {
 		repoIndex, err := s.indexer.GetRepoIndex(repoPath)
 		if err == nil && repoIndex.TotalChunks == 0 && repoIndex.TotalFiles > 0 {
 			// Cache says files are indexed but Qdrant has no chunks - force reindex
-			log.Printf("Detected cache inconsistency: cache has files but Qdrant has no chunks. Forcing reindex...")
+			slog.Warn("Detected cache inconsistency: cache has files but Qdrant has no chunks, forcing reindex")
 			forceReindex = true
 		}
 	}
 
 	// Start indexing
-	job, err := s.indexer.Index(repoPath, forceReindex)
+	var job *models.IndexJob
+	if changedSince != "" {
+		job, err = s.indexer.IndexChangedSince(repoPath, changedSince)
+	} else {
+		job, err = s.indexer.IndexLanguages(repoPath, languages, forceReindex, dryRun)
+	}
 	if err != nil {
 		return errorResult(fmt.Sprintf("failed to start indexing: %v", err)), nil
 	}
 
-	// If running synchronously, wait for completion
-	if !s.config.Indexing.Background {
+	// Dry runs are always synchronous, so the job is already complete
+	if dryRun {
+		return dryRunResult(job), nil
+	}
+
+	// If running synchronously (per the repository's settings), wait for completion
+	if !job.Background {
 		// Poll for job completion
 		ticker := time.NewTicker(100 * time.Millisecond)
 		defer ticker.Stop()
@@ -172,12 +1187,13 @@ func (s *Server) handleIndexCodebase(ctx context.Context, args map[string]interf
 				if err != nil {
 					return errorResult(fmt.Sprintf("failed to get job status: %v", err)), nil
 				}
+				snapshot := currentJob.Snapshot()
 
-				// Check if job is complete
-				if currentJob.Status == "completed" || currentJob.Status == "failed" {
-					duration := currentJob.EndTime.Sub(currentJob.StartTime)
+				// Check if job is complete; the end time is recorded once it has fully finished
+				if snapshot.Status != models.IndexStatusRunning && !snapshot.EndTime.IsZero() {
+					duration := snapshot.EndTime.Sub(snapshot.StartTime)
 
-					if currentJob.Status == "failed" {
+					if snapshot.Status != models.IndexStatusCompleted {
 						// Failed indexing - provide detailed error with troubleshooting steps
 						errorMsg := fmt.Sprintf(`❌ Indexing Failed
 
@@ -194,10 +1210,10 @@ Duration: %.1fs
 4. If issue persists, try: force_reindex=true
 
 Note: Cache was NOT updated. Files will be reprocessed on next attempt.`,
-							currentJob.Error,
-							currentJob.FilesIndexed,
-							currentJob.FilesTotal,
-							currentJob.ChunksTotal,
+							snapshot.Error,
+							snapshot.FilesIndexed,
+							snapshot.FilesTotal,
+							snapshot.ChunksTotal,
 							duration.Seconds())
 
 						return errorResult(errorMsg), nil
@@ -209,11 +1225,13 @@ Note: Cache was NOT updated. Files will be reprocessed on next attempt.`,
 Files indexed: %d
 Code chunks: %d
 Duration: %.1fs
-
+%s%s
 You can now search this codebase with semantic queries.`,
-						currentJob.FilesIndexed,
-						currentJob.ChunksTotal,
-						duration.Seconds())
+						snapshot.FilesIndexed,
+						snapshot.ChunksTotal,
+						duration.Seconds(),
+						formatIndexStats(currentJob.GetStats()),
+						formatFailedFiles(currentJob.GetFailedFiles()))
 
 					return &mcp.CallToolResult{
 						Content: []mcp.Content{
@@ -234,24 +1252,35 @@ You can now search this codebase with semantic queries.`,
 		"job_id":        job.ID,
 		"repo":          repoPath,
 		"force_reindex": forceReindex,
-		"status":        job.Status,
+		"status":        job.Snapshot().Status,
 		"background":    true,
 		"note":          "Use get_index_status to check progress",
 	}
+	if len(languages) > 0 {
+		response["languages"] = languages
+	}
 
 	return successResult(response), nil
 }
 
+// invalidateResults drops the repository's cached search results, if results are cached
+func (s *Server) invalidateResults(repoPath string) {
+	if s.resultCache != nil {
+		s.resultCache.Invalidate(repoPath)
+	}
+}
+
 func (s *Server) handleClearCache(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
-	repoPath, ok := args["repo_path"].(string)
-	if !ok || repoPath == "" {
-		return errorResult("repo_path is required and must be a string"), nil
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
 	}
 
 	// Clear cache
 	if err := s.indexer.ClearCache(repoPath); err != nil {
 		return errorResult(fmt.Sprintf("failed to clear cache: %v", err)), nil
 	}
+	s.invalidateResults(repoPath)
 
 	response := map[string]interface{}{
 		"message": "Cache cleared successfully",
@@ -261,10 +1290,201 @@ func (s *Server) handleClearCache(ctx context.Context, args map[string]interface
 	return successResult(response), nil
 }
 
+func (s *Server) handleExplainFile(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+	filePath, ok := args["file_path"].(string)
+	if !ok || filePath == "" {
+		return errorResult("file_path is required and must be a string"), nil
+	}
+
+	if filepath.IsAbs(filePath) {
+		if filePath, err = normalizePath(filePath); err != nil {
+			return errorResult(fmt.Sprintf("invalid file_path: %v", err)), nil
+		}
+	}
+
+	explanation, err := s.indexer.ExplainFile(repoPath, filePath)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to explain file: %v", err)), nil
+	}
+
+	response := map[string]interface{}{
+		"summary":     explanation.Summary(),
+		"explanation": explanation,
+	}
+
+	return successResult(response), nil
+}
+
+func (s *Server) handlePreviewChunks(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+	filePath, ok := args["file_path"].(string)
+	if !ok || filePath == "" {
+		return errorResult("file_path is required and must be a string"), nil
+	}
+	if filepath.IsAbs(filePath) {
+		if filePath, err = normalizePath(filePath); err != nil {
+			return errorResult(fmt.Sprintf("invalid file_path: %v", err)), nil
+		}
+	}
+	includeContent, _ := args["include_content"].(bool)
+
+	preview, err := s.indexer.PreviewChunks(repoPath, filePath, includeContent)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to preview chunks: %v", err)), nil
+	}
+
+	return successResult(preview), nil
+}
+
+func (s *Server) handleSupportedLanguages(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	return successResult(map[string]interface{}{
+		"languages": s.indexer.SupportedLanguages(),
+	}), nil
+}
+
+func (s *Server) handleExportIndex(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+	outputPath, ok := args["output_path"].(string)
+	if !ok || outputPath == "" {
+		return errorResult("output_path is required and must be a string"), nil
+	}
+	if !filepath.IsAbs(outputPath) {
+		return errorResult("output_path must be an absolute path"), nil
+	}
+	includeVectors, _ := args["include_vectors"].(bool)
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to create export file: %v", err)), nil
+	}
+	writer := bufio.NewWriter(file)
+
+	count, err := s.vectorDB.ExportRepo(ctx, writer, repoPath, includeVectors)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	// Without vectors the export can't be imported, so the hash cache would be of no use
+	if err == nil && includeVectors {
+		err = s.exportHashCache(repoPath, hashCachePath(outputPath))
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		os.Remove(hashCachePath(outputPath))
+		return errorResult(fmt.Sprintf("failed to export index: %v", err)), nil
+	}
+
+	slog.Info("Exported index", "repo", repoPath, "output", outputPath, "chunks", count)
+
+	response := map[string]interface{}{
+		"repo_path":       repoPath,
+		"output_path":     outputPath,
+		"chunks_exported": count,
+		"include_vectors": includeVectors,
+	}
+
+	return successResult(response), nil
+}
+
+func (s *Server) handleImportIndex(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	inputPath, ok := args["input_path"].(string)
+	if !ok || inputPath == "" {
+		return errorResult("input_path is required and must be a string"), nil
+	}
+	if !filepath.IsAbs(inputPath) {
+		return errorResult("input_path must be an absolute path"), nil
+	}
+
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to open import file: %v", err)), nil
+	}
+	defer file.Close()
+
+	count, err := s.vectorDB.ImportChunks(ctx, bufio.NewReader(file))
+	// The import may touch any repository, even when it fails part way
+	if s.resultCache != nil {
+		s.resultCache.InvalidateAll()
+	}
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to import index after %d chunks: %v", count, err)), nil
+	}
+
+	slog.Info("Imported index", "input", inputPath, "chunks", count)
+
+	response := map[string]interface{}{
+		"input_path":      inputPath,
+		"chunks_imported": count,
+	}
+
+	restored, err := s.restoreHashCache(hashCachePath(inputPath))
+	if err != nil {
+		return errorResult(fmt.Sprintf("imported %d chunks, but failed to restore the hash cache: %v", count, err)), nil
+	}
+	response["hash_cache_restored"] = restored
+	if !restored {
+		response["note"] = "No hash cache was exported with this file, so the next index_codebase reindexes every file"
+	}
+
+	return successResult(response), nil
+}
+
+// hashCachePath returns the path of the hash cache exported alongside an index export
+func hashCachePath(exportPath string) string {
+	return exportPath + ".hashes.json"
+}
+
+// exportHashCache writes a repository's file hash cache to path
+func (s *Server) exportHashCache(repoPath, path string) error {
+	hashCache, err := s.indexer.HashCache(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to load hash cache: %w", err)
+	}
+	data, err := json.MarshalIndent(hashCache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal hash cache: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write hash cache: %w", err)
+	}
+	return nil
+}
+
+// restoreHashCache restores the hash cache exported to path, reporting false if there is none
+func (s *Server) restoreHashCache(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read hash cache: %w", err)
+	}
+	var hashCache models.FileHashCache
+	if err := json.Unmarshal(data, &hashCache); err != nil {
+		return false, fmt.Errorf("failed to parse hash cache: %w", err)
+	}
+	if err := s.indexer.RestoreHashCache(&hashCache); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (s *Server) handleGetIndexStatus(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
-	repoPath, ok := args["repo_path"].(string)
-	if !ok || repoPath == "" {
-		return errorResult("repo_path is required and must be a string"), nil
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
 	}
 
 	// Get repository index
@@ -276,8 +1496,133 @@ func (s *Server) handleGetIndexStatus(ctx context.Context, args map[string]inter
 	return successResult(repoIndex), nil
 }
 
+func (s *Server) handleHealthCheck(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	report := checkHealth(ctx, s.embeddingsClient, s.vectorDB)
+	return successResult(report), nil
+}
+
+// dryRunResult summarizes what indexing would do without embedding or storing anything
+func dryRunResult(job *models.IndexJob) *mcp.CallToolResult {
+	snapshot := job.Snapshot()
+	if snapshot.Status == models.IndexStatusFailed {
+		return errorResult(fmt.Sprintf("dry run failed: %s", snapshot.Error))
+	}
+
+	msg := fmt.Sprintf(`🔍 Dry Run (nothing was embedded or stored)
+
+Files scanned: %d
+Files chunked: %d
+Code chunks: %d
+%s%s`,
+		snapshot.FilesTotal,
+		snapshot.FilesIndexed,
+		snapshot.ChunksTotal,
+		formatIndexStats(job.GetStats()),
+		formatFailedFiles(job.GetFailedFiles()))
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: msg,
+			},
+		},
+	}
+}
+
 // Helper functions
 
+func (s *Server) handleCollectionStats(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	stats, err := s.vectorDB.CollectionInfo(ctx)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to get collection stats: %v", err)), nil
+	}
+
+	return successResult(stats), nil
+}
+
+func (s *Server) handleReembedIndex(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	model, ok := args["model"].(string)
+	if !ok || model == "" {
+		return errorResult("model is required and must be a string"), nil
+	}
+	collection, ok := args["target_collection"].(string)
+	if !ok || collection == "" {
+		return errorResult("target_collection is required and must be a string"), nil
+	}
+	var repoPath string
+	if _, ok := args["repo_path"]; ok {
+		var err error
+		if repoPath, err = repoPathArg(args); err != nil {
+			return errorResult(err.Error()), nil
+		}
+	}
+
+	result, err := s.indexer.Reembed(ctx, repoPath, model, collection)
+	if err != nil {
+		if result != nil {
+			return errorResult(fmt.Sprintf("failed to re-embed after %d chunks: %v", result.ChunksEmbedded, err)), nil
+		}
+		return errorResult(fmt.Sprintf("failed to re-embed: %v", err)), nil
+	}
+
+	response := map[string]interface{}{
+		"model":             result.Model,
+		"target_collection": result.Collection,
+		"vector_size":       result.VectorSize,
+		"chunks_reembedded": result.ChunksEmbedded,
+		"chunks_failed":     result.ChunksFailed,
+		"duration":          result.Duration.Round(time.Millisecond).String(),
+		// With collection_per_model, the given name is suffixed to result.Collection when set as collection_name
+		"note": fmt.Sprintf("Set embeddings.model to %q and vectordb.collection_name to %q to search with the new model", result.Model, collection),
+	}
+	if repoPath != "" {
+		response["repo_path"] = repoPath
+	}
+
+	return successResult(response), nil
+}
+
+func (s *Server) handleReindexMetadata(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	created, err := s.vectorDB.EnsureIndexes(ctx)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to create payload indexes: %v", err)), nil
+	}
+
+	message := "All payload indexes already exist"
+	if len(created) > 0 {
+		message = fmt.Sprintf("Created %d payload indexes", len(created))
+	}
+
+	response := map[string]interface{}{
+		"message": message,
+		"created": created,
+	}
+
+	return successResult(response), nil
+}
+
+func (s *Server) handleVerifyIndex(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	repoPath, err := repoPathArg(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
+	result, err := s.indexer.VerifyIndex(ctx, repoPath)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to verify index: %v", err)), nil
+	}
+
+	response := map[string]interface{}{
+		"summary":      result.Summary(),
+		"consistent":   result.Consistent(),
+		"verification": result,
+		"duration":     result.Duration.Round(time.Millisecond).String(),
+	}
+
+	return successResult(response), nil
+}
+
 func successResult(data interface{}) *mcp.CallToolResult {
 	jsonData, _ := json.MarshalIndent(data, "", "  ")
 	return &mcp.CallToolResult{
@@ -302,7 +1647,123 @@ func errorResult(message string) *mcp.CallToolResult {
 	}
 }
 
-func formatSearchResults(results []search.SearchResult) string {
+// formatIndexStats summarizes chunk breakdown and incremental skips for the index summary
+func formatIndexStats(stats models.IndexStats) string {
+	var output strings.Builder
+
+	output.WriteString(fmt.Sprintf("Files unchanged (skipped): %d\n", stats.FilesUnchanged))
+	output.WriteString(fmt.Sprintf("Files failed: %d\n", stats.FilesFailed))
+	if stats.FilesUnsupported > 0 {
+		output.WriteString(fmt.Sprintf("Files unsupported (skipped): %d\n", stats.FilesUnsupported))
+	}
+	output.WriteString(fmt.Sprintf("Bytes embedded: %d (avg %d bytes/chunk)\n", stats.BytesEmbedded, stats.AvgChunkBytes))
+
+	if len(stats.ChunksByType) > 0 {
+		types := make([]string, 0, len(stats.ChunksByType))
+		for chunkType := range stats.ChunksByType {
+			types = append(types, chunkType)
+		}
+		sort.Strings(types)
+
+		output.WriteString("Chunks by type:")
+		for _, chunkType := range types {
+			if stats.ChunksByType[chunkType] == 0 {
+				continue
+			}
+			output.WriteString(fmt.Sprintf(" %s=%d", chunkType, stats.ChunksByType[chunkType]))
+		}
+		output.WriteString("\n")
+	}
+
+	if len(stats.FilesByLanguage) > 0 {
+		languages := make([]string, 0, len(stats.FilesByLanguage))
+		for language := range stats.FilesByLanguage {
+			languages = append(languages, language)
+		}
+		sort.Strings(languages)
+
+		output.WriteString("Files by language:")
+		for _, language := range languages {
+			output.WriteString(fmt.Sprintf(" %s=%d", language, stats.FilesByLanguage[language]))
+		}
+		output.WriteString("\n")
+	}
+
+	if len(stats.ASTFallbackFiles) > 0 {
+		output.WriteString(fmt.Sprintf("AST fallback (token chunking used): %d files\n", len(stats.ASTFallbackFiles)))
+		for i, path := range stats.ASTFallbackFiles {
+			if i >= maxFailedFilesShown {
+				output.WriteString(fmt.Sprintf("  ... and %d more\n", len(stats.ASTFallbackFiles)-maxFailedFilesShown))
+				break
+			}
+			output.WriteString(fmt.Sprintf("  - %s\n", path))
+		}
+	}
+
+	return output.String()
+}
+
+// maxFailedFilesShown limits how many failed files are listed in the index summary
+const maxFailedFilesShown = 10
+
+// formatFailedFiles summarizes files that failed to chunk during indexing
+func formatFailedFiles(failed []models.FileError) string {
+	if len(failed) == 0 {
+		return ""
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("\n⚠️  %d files failed to chunk and were skipped:\n", len(failed)))
+	for i, f := range failed {
+		if i >= maxFailedFilesShown {
+			output.WriteString(fmt.Sprintf("  ... and %d more\n", len(failed)-maxFailedFilesShown))
+			break
+		}
+		output.WriteString(fmt.Sprintf("  - %s: %s\n", f.Path, f.Error))
+	}
+
+	return output.String()
+}
+
+// formatSymbolList formats symbols as an outline grouped by file
+func formatSymbolList(symbols []models.CodeChunk, total int, truncated bool) string {
+	if len(symbols) == 0 {
+		return "No symbols found."
+	}
+
+	var output strings.Builder
+	if truncated {
+		// Only the symbols collected were sorted, so these may not come first in the whole repository
+		output.WriteString(fmt.Sprintf("Found more than %d symbols and stopped listing, showing %d of those found (narrow with name or file_glob):\n", total, len(symbols)))
+	} else if total > len(symbols) {
+		output.WriteString(fmt.Sprintf("Found %d symbols, showing the first %d (narrow with name or file_glob):\n", total, len(symbols)))
+	} else {
+		output.WriteString(fmt.Sprintf("Found %d symbols:\n", total))
+	}
+
+	currentFile := ""
+	for _, symbol := range symbols {
+		if symbol.FilePath != currentFile {
+			currentFile = symbol.FilePath
+			output.WriteString(fmt.Sprintf("\n%s\n", currentFile))
+		}
+
+		name := symbol.FunctionName
+		switch {
+		case name == "":
+			name = symbol.ClassName
+		case symbol.ClassName != "":
+			name = symbol.ClassName + "." + name
+		}
+		output.WriteString(fmt.Sprintf("  %s %s (lines %d-%d)\n", symbol.ChunkType, name, symbol.StartLine, symbol.EndLine))
+	}
+
+	return output.String()
+}
+
+// formatSearchResults renders results for display, with full chunk content when includeContent
+// is set and a preview sized by preview otherwise
+func formatSearchResults(results []search.SearchResult, includeContent bool, preview search.PreviewOptions) string {
 	if len(results) == 0 {
 		return "No results found."
 	}
@@ -314,12 +1775,7 @@ func formatSearchResults(results []search.SearchResult) string {
 		chunk := result.Chunk
 
 		// Format file location
-		location := fmt.Sprintf("%s:%d-%d", chunk.FilePath, chunk.StartLine, chunk.EndLine)
-		if chunk.FunctionName != "" {
-			location += fmt.Sprintf(" (in %s)", chunk.FunctionName)
-		} else if chunk.ClassName != "" {
-			location += fmt.Sprintf(" (in %s)", chunk.ClassName)
-		}
+		location := resultLocation(chunk)
 
 		// Format score info
 		scoreInfo := fmt.Sprintf("score: %.3f", result.HybridScore)
@@ -331,28 +1787,57 @@ func formatSearchResults(results []search.SearchResult) string {
 		output.WriteString(fmt.Sprintf("%d. %s\n", i+1, location))
 		output.WriteString(fmt.Sprintf("   %s\n", scoreInfo))
 		output.WriteString(fmt.Sprintf("   Language: %s, Type: %s\n", chunk.Language, chunk.ChunkType))
-
-		// Show content preview (first 3 lines)
-		lines := strings.Split(chunk.Content, "\n")
-		previewLines := 3
-		if len(lines) < previewLines {
-			previewLines = len(lines)
+		if commit, ok := chunk.Metadata[models.MetadataLastCommit].(string); ok {
+			output.WriteString(fmt.Sprintf("   Last commit: %.12s by %v on %v\n", commit,
+				chunk.Metadata[models.MetadataLastAuthor], chunk.Metadata[models.MetadataLastCommitDate]))
 		}
 
-		output.WriteString("   Preview:\n")
-		for j := 0; j < previewLines; j++ {
-			line := strings.TrimSpace(lines[j])
-			if len(line) > 80 {
-				line = line[:80] + "..."
-			}
-			output.WriteString(fmt.Sprintf("   │ %s\n", line))
-		}
-		if len(lines) > previewLines {
-			output.WriteString(fmt.Sprintf("   │ ... (%d more lines)\n", len(lines)-previewLines))
+		// Adjacent chunks frame the result, so the window reads top to bottom
+		writeAdjacentChunk(&output, "Before", result.Before, includeContent, preview)
+		if includeContent {
+			writeResultContent(&output, chunk.Content)
+		} else {
+			search.WritePreview(&output, chunk.Content, preview)
 		}
-
+		writeAdjacentChunk(&output, "After", result.After, includeContent, preview)
 		output.WriteString("\n")
 	}
 
 	return output.String()
 }
+
+// writeAdjacentChunk writes a chunk next to a result, if any, labelled with its line range
+func writeAdjacentChunk(output *strings.Builder, label string, chunk *models.CodeChunk, includeContent bool, preview search.PreviewOptions) {
+	if chunk == nil {
+		return
+	}
+	output.WriteString(fmt.Sprintf("   %s: lines %d-%d", label, chunk.StartLine, chunk.EndLine))
+	if chunk.FunctionName != "" {
+		output.WriteString(fmt.Sprintf(" (in %s)", chunk.FunctionName))
+	} else if chunk.ClassName != "" {
+		output.WriteString(fmt.Sprintf(" (in %s)", chunk.ClassName))
+	}
+	output.WriteString("\n")
+	if includeContent {
+		writeResultContent(output, chunk.Content)
+	} else {
+		search.WritePreview(output, chunk.Content, preview)
+	}
+}
+
+// writeResultContent writes a chunk's complete content, cut at maxResultContentChars
+func writeResultContent(output *strings.Builder, content string) {
+	omitted := 0
+	if runes := []rune(content); len(runes) > maxResultContentChars {
+		omitted = len(runes) - maxResultContentChars
+		content = string(runes[:maxResultContentChars])
+	}
+
+	output.WriteString("   Content:\n")
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		output.WriteString(fmt.Sprintf("   │ %s\n", line))
+	}
+	if omitted > 0 {
+		output.WriteString(fmt.Sprintf("   │ ... (truncated, %d more characters)\n", omitted))
+	}
+}
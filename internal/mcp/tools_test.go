@@ -0,0 +1,683 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/internal/search"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	longQuery := strings.Repeat("ä", maxQueryChars+50)
+
+	tests := []struct {
+		name            string
+		query           string
+		expected        string
+		expectTruncated bool
+		expectErr       string
+	}{
+		{name: "trims whitespace", query: "  jwt validation \n", expected: "jwt validation"},
+		{name: "whitespace only", query: " \t\n ", expectErr: "query is empty"},
+		{name: "empty", query: "", expectErr: "query is empty"},
+		{name: "too short", query: " a ", expectErr: "too short"},
+		{name: "minimum length", query: "id", expected: "id"},
+		{name: "over-long is truncated", query: longQuery, expected: longQuery[:maxQueryChars*len("ä")], expectTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, truncated, err := normalizeQuery(tt.query)
+			if tt.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if query != tt.expected {
+				t.Errorf("Expected query %q, got %q", tt.expected, query)
+			}
+			if truncated != tt.expectTruncated {
+				t.Errorf("Expected truncated=%v, got %v", tt.expectTruncated, truncated)
+			}
+			if !utf8.ValidString(query) {
+				t.Error("Expected truncation at a character boundary")
+			}
+		})
+	}
+}
+
+func TestHandleSemanticSearch_RejectsBlankQuery(t *testing.T) {
+	// No searcher: a blank query must be rejected before anything is embedded
+	s := &Server{}
+
+	for _, query := range []string{"   ", "\n\t", "x"} {
+		result, err := s.handleSemanticSearch(context.Background(), map[string]interface{}{
+			"query":     query,
+			"repo_path": "/repo",
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !result.IsError {
+			t.Errorf("Expected an error result for query %q", query)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "query") {
+			t.Errorf("Expected the error to mention the query, got %q", text)
+		}
+	}
+}
+
+// stubEmbeddings returns a fixed query embedding
+type stubEmbeddings struct{}
+
+func (stubEmbeddings) GenerateEmbedding(text string) ([]float32, error) {
+	return []float32{0.1}, nil
+}
+
+// stubVectorDB returns one chunk per repository, or the error registered for it
+type stubVectorDB struct {
+	repoErrs map[string]error
+}
+
+func (db stubVectorDB) Search(ctx context.Context, embedding []float32, filter models.SearchFilter, limit int, minScore float64) ([]models.CodeChunk, []float64, error) {
+	if err := db.repoErrs[filter.RepoPath]; err != nil {
+		return nil, nil, err
+	}
+	filePath := filter.RepoPath + "/main.go"
+	if filter.FilePath != "" {
+		filePath = filter.FilePath
+	}
+	chunk := models.CodeChunk{ID: filePath, RepoPath: filter.RepoPath, FilePath: filePath, Content: "func main() {}", StartLine: 1, EndLine: 1}
+	return []models.CodeChunk{chunk}, []float64{0.9}, nil
+}
+
+func (db stubVectorDB) FindSymbols(ctx context.Context, repoPath, name string, exact bool, limit int) ([]models.CodeChunk, error) {
+	return nil, nil
+}
+
+func (db stubVectorDB) ScrollSymbols(ctx context.Context, repoPath string, visit func(chunk models.CodeChunk) bool) error {
+	return nil
+}
+
+func (db stubVectorDB) FindChunkAt(ctx context.Context, repoPath, filePath string, line int) (*models.CodeChunk, error) {
+	return nil, nil
+}
+
+func TestHandleSemanticSearch_PartialRepoFailure(t *testing.T) {
+	vectorDB := stubVectorDB{repoErrs: map[string]error{"/repo/broken": errors.New("collection unavailable")}}
+	s := &Server{searcher: search.NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, stubEmbeddings{}, vectorDB)}
+
+	result, err := s.handleSemanticSearch(context.Background(), map[string]interface{}{
+		"query":                 "main function",
+		"repo_path":             "/repo/ok",
+		"additional_repo_paths": []interface{}{"/repo/broken"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected partial results, got error result %q", result.Content[0].(mcp.TextContent).Text)
+	}
+
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "/repo/ok/main.go") {
+		t.Errorf("Expected results from the healthy repository, got %q", text)
+	}
+	if !strings.Contains(text, "Search failed for /repo/broken") {
+		t.Errorf("Expected a warning for the failed repository, got %q", text)
+	}
+
+	// Every repository failing is still an error
+	vectorDB.repoErrs["/repo/ok"] = errors.New("qdrant down")
+	result, _ = s.handleSemanticSearch(context.Background(), map[string]interface{}{
+		"query":                 "main function",
+		"repo_path":             "/repo/ok",
+		"additional_repo_paths": []interface{}{"/repo/broken"},
+	})
+	if !result.IsError {
+		t.Error("Expected an error result when every repository fails")
+	}
+}
+
+func TestHandleSemanticSearch_ExcludePaths(t *testing.T) {
+	s := &Server{searcher: search.NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, stubEmbeddings{}, stubVectorDB{})}
+
+	result, err := s.handleSemanticSearch(context.Background(), map[string]interface{}{
+		"query":                 "main function",
+		"repo_path":             "/repo/ok",
+		"additional_repo_paths": []interface{}{"/repo/other"},
+		"exclude_paths":         []interface{}{"/main.go"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Unexpected error result %q", result.Content[0].(mcp.TextContent).Text)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; strings.Contains(text, "main.go") {
+		t.Errorf("Expected excluded files never to appear, got %q", text)
+	}
+
+	result, _ = s.handleSemanticSearch(context.Background(), map[string]interface{}{
+		"query":         "main function",
+		"repo_path":     "/repo/ok",
+		"exclude_paths": []interface{}{"legacy/", 3},
+	})
+	if !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "exclude_paths") {
+		t.Error("Expected an error result for a non-string exclude_paths entry")
+	}
+}
+
+func TestHandleSemanticSearch_SortBy(t *testing.T) {
+	s := &Server{searcher: search.NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, stubEmbeddings{}, stubVectorDB{})}
+
+	result, err := s.handleSemanticSearch(context.Background(), map[string]interface{}{
+		"query":     "main function",
+		"repo_path": "/repo/ok",
+		"sort_by":   "location",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Unexpected error result %q", result.Content[0].(mcp.TextContent).Text)
+	}
+
+	result, _ = s.handleSemanticSearch(context.Background(), map[string]interface{}{
+		"query":     "main function",
+		"repo_path": "/repo/ok",
+		"sort_by":   "name",
+	})
+	if !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "sort_by") {
+		t.Error("Expected an error result for an unknown sort_by")
+	}
+}
+
+func TestTimeWindowArgs(t *testing.T) {
+	now := time.Date(2026, 3, 14, 12, 30, 45, 500, time.UTC)
+	tests := []struct {
+		args  map[string]interface{}
+		since time.Time
+		until time.Time
+	}{
+		{map[string]interface{}{}, time.Time{}, time.Time{}},
+		{map[string]interface{}{"since": "2026-03-01T08:00:00Z"}, time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC), time.Time{}},
+		{map[string]interface{}{"since": "2026-03-01", "until": "2026-03-07"}, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 7, 23, 59, 59, 0, time.UTC)},
+		{map[string]interface{}{"since": "7d"}, time.Date(2026, 3, 7, 12, 30, 45, 0, time.UTC), time.Time{}},
+		{map[string]interface{}{"since": "2w", "until": " 90m "}, time.Date(2026, 2, 28, 12, 30, 45, 0, time.UTC), time.Date(2026, 3, 14, 11, 0, 45, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		since, until, err := timeWindowArgs(tt.args, now)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", tt.args, err)
+			continue
+		}
+		if !since.Equal(tt.since) || !until.Equal(tt.until) {
+			t.Errorf("%v: expected %v to %v, got %v to %v", tt.args, tt.since, tt.until, since, until)
+		}
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"since": "last week"},
+		{"until": "7y"},
+		{"since": "-3d"},
+		{"since": "1d", "until": "2d"}, // since after until
+	} {
+		if _, _, err := timeWindowArgs(args, now); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestHandleSemanticSearch_TimeWindow(t *testing.T) {
+	s := &Server{searcher: search.NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, stubEmbeddings{}, stubVectorDB{})}
+
+	result, err := s.handleSemanticSearch(context.Background(), map[string]interface{}{
+		"query":     "payment handling",
+		"repo_path": "/repo/ok",
+		"since":     "7d",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Unexpected error result %q", result.Content[0].(mcp.TextContent).Text)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"query": "payment handling", "repo_path": "/repo/ok", "until": "yesterday"},
+		{"query": "payment handling", "repo_path": "/repo/ok", "since": "7d", "additional_repo_paths": []interface{}{"/repo/other"}},
+	} {
+		result, _ := s.handleSemanticSearch(context.Background(), args)
+		if !result.IsError {
+			t.Errorf("%v: expected an error result", args)
+		}
+	}
+}
+
+func TestFormatSymbolList(t *testing.T) {
+	symbols := []models.CodeChunk{
+		{FilePath: "/repo/Service.java", StartLine: 1, EndLine: 40, ClassName: "Service", ChunkType: models.ChunkTypeClass},
+		{FilePath: "/repo/Service.java", StartLine: 5, EndLine: 9, ClassName: "Service", FunctionName: "run", ChunkType: models.ChunkTypeMethod},
+		{FilePath: "/repo/util.ts", StartLine: 2, EndLine: 4, FunctionName: "parse", ChunkType: models.ChunkTypeFunction},
+	}
+
+	text := formatSymbolList(symbols, 3, false)
+	for _, want := range []string{"Found 3 symbols", "/repo/Service.java\n  class Service (lines 1-40)\n  method Service.run (lines 5-9)", "/repo/util.ts\n  function parse (lines 2-4)"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, text)
+		}
+	}
+	if strings.Count(text, "/repo/Service.java") != 1 {
+		t.Errorf("Expected each file listed once, got:\n%s", text)
+	}
+
+	if text := formatSymbolList(symbols[:1], 10, false); !strings.Contains(text, "showing the first 1") {
+		t.Errorf("Expected a truncation note, got:\n%s", text)
+	}
+	if text := formatSymbolList(symbols[:1], 20000, true); !strings.Contains(text, "more than 20000 symbols") {
+		t.Errorf("Expected a note that listing stopped, got:\n%s", text)
+	}
+	if text := formatSymbolList(nil, 0, false); text != "No symbols found." {
+		t.Errorf("Expected no-symbols message, got %q", text)
+	}
+}
+
+func TestFormatSearchResults_IncludeContent(t *testing.T) {
+	content := "func main() {\n    first()\n    second()\n    third()\n    fourth()\n}\n"
+	results := []search.SearchResult{{
+		Chunk:       models.CodeChunk{FilePath: "/repo/main.go", StartLine: 1, EndLine: 6, Content: content},
+		HybridScore: 0.9,
+	}}
+
+	preview := formatSearchResults(results, false, search.PreviewOptions{})
+	if strings.Contains(preview, "fourth()") {
+		t.Errorf("Expected only a preview without include_content, got %q", preview)
+	}
+	if !strings.Contains(preview, "more lines") {
+		t.Errorf("Expected the preview to note the omitted lines, got %q", preview)
+	}
+
+	full := formatSearchResults(results, true, search.PreviewOptions{})
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		if !strings.Contains(full, "│ "+line+"\n") {
+			t.Errorf("Expected full content line %q, got %q", line, full)
+		}
+	}
+	if strings.Contains(full, "Preview:") {
+		t.Errorf("Expected full content instead of a preview, got %q", full)
+	}
+
+	// Content beyond the per-result cap is cut
+	results[0].Chunk.Content = strings.Repeat("é", maxResultContentChars+5)
+	full = formatSearchResults(results, true, search.PreviewOptions{})
+	if strings.Count(full, "é") != maxResultContentChars || !strings.Contains(full, "truncated, 5 more characters") {
+		t.Errorf("Expected content cut at %d characters", maxResultContentChars)
+	}
+}
+
+func TestFormatSearchResults_Adjacent(t *testing.T) {
+	results := []search.SearchResult{{
+		Chunk:  models.CodeChunk{FilePath: "/repo/auth.go", StartLine: 10, EndLine: 12, Content: "func login() {}\n"},
+		Before: &models.CodeChunk{StartLine: 1, EndLine: 9, FunctionName: "connect", Content: "func connect() {}\n"},
+		After:  &models.CodeChunk{StartLine: 13, EndLine: 15, ClassName: "Session", Content: "func logout() {}\n"},
+	}}
+
+	output := formatSearchResults(results, true, search.PreviewOptions{})
+	before := strings.Index(output, "Before: lines 1-9 (in connect)")
+	main := strings.Index(output, "func login() {}")
+	after := strings.Index(output, "After: lines 13-15 (in Session)")
+	if before < 0 || main < 0 || after < 0 || !(before < main && main < after) {
+		t.Fatalf("Expected the before chunk, the result and the after chunk in order, got %q", output)
+	}
+	if !strings.Contains(output, "│ func connect() {}\n") || !strings.Contains(output, "│ func logout() {}\n") {
+		t.Errorf("Expected the adjacent chunks' content, got %q", output)
+	}
+
+	results[0].Before, results[0].After = nil, nil
+	if output := formatSearchResults(results, true, search.PreviewOptions{}); strings.Contains(output, "Before:") || strings.Contains(output, "After:") {
+		t.Errorf("Expected no adjacent chunks when none were attached, got %q", output)
+	}
+}
+
+func TestFormatSearchResults_Blame(t *testing.T) {
+	results := []search.SearchResult{{
+		Chunk: models.CodeChunk{FilePath: "/repo/auth.go", StartLine: 1, EndLine: 2, Content: "func login() {}\n"},
+	}}
+	if output := formatSearchResults(results, false, search.PreviewOptions{}); strings.Contains(output, "Last commit") {
+		t.Errorf("Expected no blame line without blame metadata, got %q", output)
+	}
+
+	results[0].Chunk.Metadata = map[string]interface{}{
+		models.MetadataLastCommit:     "0123456789abcdef0123456789abcdef01234567",
+		models.MetadataLastAuthor:     "alice",
+		models.MetadataLastCommitDate: "2030-01-02T03:04:05Z",
+	}
+	output := formatSearchResults(results, false, search.PreviewOptions{})
+	if !strings.Contains(output, "Last commit: 0123456789ab by alice on 2030-01-02T03:04:05Z") {
+		t.Errorf("Expected the blame line, got %q", output)
+	}
+}
+
+func TestHandleSemanticSearch_IncludeContent(t *testing.T) {
+	s := &Server{searcher: search.NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, stubEmbeddings{}, stubVectorDB{})}
+
+	for _, include := range []bool{false, true} {
+		result, err := s.handleSemanticSearch(context.Background(), map[string]interface{}{
+			"query":           "main function",
+			"repo_path":       "/repo",
+			"include_content": include,
+		})
+		if err != nil || result.IsError {
+			t.Fatalf("Unexpected failure: %v", err)
+		}
+
+		text := result.Content[0].(mcp.TextContent).Text
+		if got := strings.Contains(text, "Content:"); got != include {
+			t.Errorf("include_content=%v: expected content section %v, got %q", include, include, text)
+		}
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	repoDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(repoDir, link); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd failed: %v", err)
+	}
+	relative, err := filepath.Rel(wd, repoDir)
+	if err != nil {
+		t.Fatalf("Rel failed: %v", err)
+	}
+
+	spellings := []string{
+		repoDir,
+		repoDir + "/",
+		repoDir + "//",
+		filepath.Join(repoDir, "sub", ".."),
+		repoDir + "/.",
+		link,
+		link + "/",
+		relative,
+	}
+	for _, spelling := range spellings {
+		got, err := normalizePath(spelling)
+		if err != nil {
+			t.Errorf("normalizePath(%q) failed: %v", spelling, err)
+			continue
+		}
+		if got != repoDir {
+			t.Errorf("normalizePath(%q) = %q, expected %q", spelling, got, repoDir)
+		}
+	}
+
+	// Missing paths are still made absolute and cleaned
+	got, err := normalizePath("/no/such/repo/")
+	if err != nil || got != filepath.Clean("/no/such/repo") {
+		t.Errorf("Expected a cleaned path for a missing repository, got %q (%v)", got, err)
+	}
+}
+
+func TestHandleSemanticSearch_NormalizesRepoPath(t *testing.T) {
+	s := &Server{searcher: search.NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, stubEmbeddings{}, stubVectorDB{})}
+
+	// The stub derives result paths from the repo path it is asked to filter on
+	result, err := s.handleSemanticSearch(context.Background(), map[string]interface{}{
+		"query":     "main function",
+		"repo_path": "/repo/ok/",
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("Unexpected failure: %v", err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "/repo/ok/main.go") {
+		t.Errorf("Expected the search to filter on the normalized path /repo/ok, got %q", text)
+	}
+}
+
+func TestHandleRecordFeedback(t *testing.T) {
+	args := map[string]interface{}{
+		"repo_path":  "/repo",
+		"file_path":  "src/test/PaymentTest.java",
+		"chunk_type": "method",
+		"helpful":    false,
+	}
+
+	disabled := &Server{}
+	if result, _ := disabled.handleRecordFeedback(context.Background(), args); !result.IsError {
+		t.Error("Expected an error while feedback is disabled")
+	}
+
+	store, err := search.NewFeedbackStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFeedbackStore failed: %v", err)
+	}
+	s := &Server{feedback: store}
+
+	result, err := s.handleRecordFeedback(context.Background(), args)
+	if err != nil || result.IsError {
+		t.Fatalf("Unexpected failure: %v %+v", err, result)
+	}
+	// Relative paths resolve against the repository, like the stored chunk paths
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "/repo/src/test/PaymentTest.java") {
+		t.Errorf("Expected the file path resolved under the repository, got %q", text)
+	}
+	if got := store.Stats("/repo").Categories["path:test"]; got.Unhelpful != 1 {
+		t.Errorf("Expected one unhelpful vote for test files, got %+v", got)
+	}
+
+	args["chunk_type"] = "paragraph"
+	if result, _ := s.handleRecordFeedback(context.Background(), args); !result.IsError {
+		t.Error("Expected an error for an unknown chunk type")
+	}
+	delete(args, "helpful")
+	args["chunk_type"] = "method"
+	if result, _ := s.handleRecordFeedback(context.Background(), args); !result.IsError {
+		t.Error("Expected an error without helpful")
+	}
+}
+
+func TestHandleSemanticSearch_NDJSON(t *testing.T) {
+	s := &Server{searcher: search.NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, stubEmbeddings{}, stubVectorDB{})}
+
+	result, err := s.handleSemanticSearch(context.Background(), map[string]interface{}{
+		"query":                 "main function",
+		"repo_path":             "/repo/ok",
+		"additional_repo_paths": []interface{}{"/repo/other"},
+		"output_format":         "ndjson",
+		"include_content":       true,
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("Unexpected failure: %v", err)
+	}
+
+	text := result.Content[0].(mcp.TextContent).Text
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a line per result, got %q", text)
+	}
+	for _, line := range lines {
+		var record search.ResultRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Expected a JSON result per line, got %q: %v", line, err)
+		}
+		if record.Content != "func main() {}" {
+			t.Errorf("Expected the content with include_content, got %+v", record)
+		}
+	}
+
+	result, _ = s.handleSemanticSearch(context.Background(), map[string]interface{}{
+		"query":         "main function",
+		"repo_path":     "/repo/ok",
+		"output_format": "xml",
+	})
+	if !result.IsError {
+		t.Error("Expected an error for an unknown output_format")
+	}
+}
+
+func TestHandleSemanticSearch_FilePath(t *testing.T) {
+	s := &Server{searcher: search.NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, stubEmbeddings{}, stubVectorDB{})}
+
+	// The stub returns a chunk of the file it is asked to filter on
+	result, err := s.handleSemanticSearch(context.Background(), map[string]interface{}{
+		"query":     "main function",
+		"repo_path": "/repo/ok",
+		"file_path": "cmd/server.go",
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("Unexpected failure: %v", err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "/repo/ok/cmd/server.go") {
+		t.Errorf("Expected the search to filter on the file resolved under the repository, got %q", text)
+	}
+
+	result, _ = s.handleSemanticSearch(context.Background(), map[string]interface{}{
+		"query":                 "main function",
+		"repo_path":             "/repo/ok",
+		"file_path":             "cmd/server.go",
+		"additional_repo_paths": []interface{}{"/repo/other"},
+	})
+	if !result.IsError {
+		t.Error("Expected an error combining file_path with additional_repo_paths")
+	}
+}
+
+// queryEmbeddings embeds each query as the single value registered for it
+type queryEmbeddings map[string]float32
+
+func (e queryEmbeddings) GenerateEmbedding(text string) ([]float32, error) {
+	return []float32{e[text]}, nil
+}
+
+// queryVectorDB returns the chunks registered for a query embedding, best first
+type queryVectorDB struct {
+	stubVectorDB
+	results map[float32][]string
+}
+
+func (db queryVectorDB) Search(ctx context.Context, embedding []float32, filter models.SearchFilter, limit int, minScore float64) ([]models.CodeChunk, []float64, error) {
+	var chunks []models.CodeChunk
+	var scores []float64
+	for i, name := range db.results[embedding[0]] {
+		path := filter.RepoPath + "/" + name + ".go"
+		chunks = append(chunks, models.CodeChunk{ID: name, FilePath: path, Content: "func " + name + "() {}", StartLine: 1, EndLine: 3})
+		scores = append(scores, 0.9-float64(i)*0.1)
+	}
+	return chunks, scores, nil
+}
+
+func TestHandleCompareQueries(t *testing.T) {
+	vectorDB := queryVectorDB{results: map[float32][]string{
+		1: {"login", "session", "token"},
+		2: {"token", "jwt", "login"},
+	}}
+	embedder := queryEmbeddings{"user login": 1, "auth token": 2}
+	s := &Server{searcher: search.NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, embedder, vectorDB)}
+
+	result, err := s.handleCompareQueries(context.Background(), map[string]interface{}{
+		"query_a":   "user login",
+		"query_b":   " auth token ",
+		"repo_path": "/repo",
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("Unexpected failure: %v %+v", err, result)
+	}
+
+	var response struct {
+		QueryB string `json:"query_b"`
+		Shared []struct {
+			Location string `json:"location"`
+			RankA    int    `json:"rank_a"`
+			RankB    int    `json:"rank_b"`
+		} `json:"shared"`
+		OnlyA []struct {
+			Location string `json:"location"`
+			Rank     int    `json:"rank"`
+		} `json:"only_a"`
+		OnlyB []struct {
+			Location string `json:"location"`
+			Rank     int    `json:"rank"`
+		} `json:"only_b"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response); err != nil {
+		t.Fatalf("Expected a JSON response: %v", err)
+	}
+	if response.QueryB != "auth token" {
+		t.Errorf("Expected the normalized query, got %q", response.QueryB)
+	}
+	if len(response.Shared) != 2 || response.Shared[0].Location != "/repo/login.go:1-3" ||
+		response.Shared[0].RankA != 1 || response.Shared[0].RankB != 3 {
+		t.Errorf("Expected login and token to be shared, login ranked 1 and 3, got %+v", response.Shared)
+	}
+	if len(response.OnlyA) != 1 || response.OnlyA[0].Location != "/repo/session.go:1-3" || response.OnlyA[0].Rank != 2 {
+		t.Errorf("Expected session only for query A, got %+v", response.OnlyA)
+	}
+	if len(response.OnlyB) != 1 || response.OnlyB[0].Location != "/repo/jwt.go:1-3" || response.OnlyB[0].Rank != 2 {
+		t.Errorf("Expected jwt only for query B, got %+v", response.OnlyB)
+	}
+
+	result, _ = s.handleCompareQueries(context.Background(), map[string]interface{}{"query_a": "user login", "repo_path": "/repo"})
+	if !result.IsError {
+		t.Error("Expected an error without query_b")
+	}
+}
+
+func TestHandleMultiSearch(t *testing.T) {
+	vectorDB := queryVectorDB{results: map[float32][]string{
+		1: {"login", "session"},
+		2: {"token", "login"},
+	}}
+	embedder := queryEmbeddings{"user login": 1, "auth token": 2}
+	s := &Server{searcher: search.NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, embedder, vectorDB)}
+
+	result, err := s.handleMultiSearch(context.Background(), map[string]interface{}{
+		"queries":   []interface{}{"user login", "auth token"},
+		"repo_path": "/repo",
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("Unexpected failure: %v %+v", err, result)
+	}
+
+	text := result.Content[0].(mcp.TextContent).Text
+	first := strings.Index(text, "1. /repo/login.go")
+	if first < 0 {
+		t.Fatalf("Expected login, found by both queries, to rank first, got:\n%s", text)
+	}
+	if !strings.Contains(text[first:], "found by: [1] [2]") {
+		t.Errorf("Expected login to list both queries, got:\n%s", text)
+	}
+	if strings.Count(text, "/repo/login.go") != 1 {
+		t.Errorf("Expected login to be listed once, got:\n%s", text)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"queries": []interface{}{}, "repo_path": "/repo"},
+		{"queries": []interface{}{"user login", 42}, "repo_path": "/repo"},
+		{"queries": []interface{}{"user login", " "}, "repo_path": "/repo"},
+	} {
+		if result, _ := s.handleMultiSearch(context.Background(), args); !result.IsError {
+			t.Errorf("Expected an error for %v", args["queries"])
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package models
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // CodeChunk represents a chunk of code stored in the vector database
@@ -21,8 +25,48 @@ type CodeChunk struct {
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
 	Embedding    []float32              `json:"embedding,omitempty"`
 	IndexedAt    time.Time              `json:"indexed_at"`
+	// ContentHash is the SHA-256 of Content when indexed (indexing.content_hash), to detect drift
+	ContentHash  string                 `json:"content_hash,omitempty"`
+	// EmbedContent is the text sent for embedding when it differs from Content (e.g. comments stripped)
+	// It is not stored; Content is always what gets displayed
+	EmbedContent string                 `json:"-"`
+}
+
+// EmbeddingText returns the text to generate the embedding from
+func (c *CodeChunk) EmbeddingText() string {
+	if c.EmbedContent != "" {
+		return c.EmbedContent
+	}
+	return c.Content
 }
 
+// chunkIDNamespace is the UUIDv5 namespace for chunk IDs
+var chunkIDNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/jamaly87/codebase-semantic-search/chunk"))
+
+// ChunkID returns a deterministic ID for a chunk
+// The same content at the same location always gets the same ID, so re-indexing
+// overwrites existing points instead of creating duplicates.
+func ChunkID(repoPath, filePath string, startLine, endLine int, content string) string {
+	contentHash := sha256.Sum256([]byte(content))
+	name := fmt.Sprintf("%s\x00%s\x00%d\x00%d\x00%x", repoPath, filePath, startLine, endLine, contentHash)
+	return uuid.NewSHA1(chunkIDNamespace, []byte(name)).String()
+}
+
+// Metadata keys of the git blame info attached to chunks when indexing.git_blame is enabled
+const (
+	MetadataLastCommit     = "last_commit"      // Hash of the most recent commit touching the chunk
+	MetadataLastAuthor     = "last_author"      // Author of that commit
+	MetadataLastCommitDate = "last_commit_date" // Author date of that commit, RFC 3339
+)
+
+// MetadataImports holds the modules imported by a function or method chunk's file, comma-separated,
+// when chunking.include_imports is enabled
+const MetadataImports = "imports"
+
+// MetadataGenerated is true on chunks of files whose header carries a generated-code marker
+// (indexing.generated_markers); searches penalize them like generated paths
+const MetadataGenerated = "generated"
+
 // ChunkType defines the type of code chunk
 type ChunkType string
 
@@ -33,6 +77,9 @@ const (
 	ChunkTypeMethod   ChunkType = "method"   // Method within a class
 )
 
+// ChunkTypes lists every known chunk type
+var ChunkTypes = []ChunkType{ChunkTypeFunction, ChunkTypeFile, ChunkTypeClass, ChunkTypeMethod}
+
 // SearchResult represents a search result with score
 type SearchResult struct {
 	Chunk          CodeChunk `json:"chunk"`
@@ -52,6 +99,9 @@ type RepoIndex struct {
 	LastIndexed   time.Time         `json:"last_indexed"`
 	IndexDuration time.Duration     `json:"index_duration"`
 	Status        IndexStatus       `json:"status"`
+	// Estimated time left while indexing is running, omitted until it can be estimated
+	ETASeconds int    `json:"eta_seconds,omitempty"`
+	ETA        string `json:"eta,omitempty"` // e.g. "about 2 minutes"
 }
 
 // IndexStatus represents the current status of an indexing job
@@ -62,9 +112,12 @@ const (
 	IndexStatusRunning   IndexStatus = "running"
 	IndexStatusCompleted IndexStatus = "completed"
 	IndexStatusFailed    IndexStatus = "failed"
+	IndexStatusCancelled IndexStatus = "cancelled" // Stopped by server shutdown before anything was stored
 )
 
 // IndexJob represents a background indexing job
+// ID, RepoPath, StartTime, DryRun and Languages are set when the job is created; the other
+// fields change while it runs, so read and write them through the methods, or Snapshot.
 type IndexJob struct {
 	mu           sync.RWMutex  // mu protects all fields from concurrent access
 	ID           string        `json:"id"`
@@ -77,6 +130,187 @@ type IndexJob struct {
 	FilesIndexed int           `json:"files_indexed"`
 	ChunksTotal  int           `json:"chunks_total"`
 	Error        string        `json:"error,omitempty"`
+	FailedFiles  []FileError   `json:"failed_files,omitempty"`
+	Stats        IndexStats    `json:"stats"`
+	DryRun       bool          `json:"dry_run,omitempty"` // Scan and chunk only; nothing is embedded or stored
+	Languages    []string      `json:"languages,omitempty"` // Only files of these languages are indexed (empty = all)
+	Background   bool          `json:"background,omitempty"` // Runs in the background, per the repository's settings
+	chunksFound  int           // Chunks produced by the files processed so far
+	chunkedAt    time.Time     // When every file was chunked, zero while chunking
+	embedded     int           // Chunks whose embedding batch is done, embedded or failed
+}
+
+// IndexStats summarizes what an indexing run actually processed
+type IndexStats struct {
+	ChunksByType   map[string]int `json:"chunks_by_type"`
+	FilesUnchanged int            `json:"files_unchanged"` // Skipped by incremental indexing
+	FilesUnsupported int          `json:"files_unsupported,omitempty"` // Reached chunking with an unsupported file type, skipped
+	FilesFailed    int            `json:"files_failed"`
+	BytesEmbedded  int64          `json:"bytes_embedded"`
+	AvgChunkBytes  int            `json:"avg_chunk_bytes"`
+	FilesByLanguage map[string]int `json:"files_by_language"`
+	ASTFallbackFiles []string      `json:"ast_fallback_files,omitempty"` // AST parsing failed, token chunking used
+}
+
+// FileError records a file that could not be processed during indexing
+type FileError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// IndexJobSnapshot is a consistent copy of a job's state, taken by IndexJob.Snapshot
+type IndexJobSnapshot struct {
+	ID           string      `json:"id"`
+	RepoPath     string      `json:"repo_path"`
+	Status       IndexStatus `json:"status"`
+	Progress     float64     `json:"progress"`
+	StartTime    time.Time   `json:"start_time"`
+	EndTime      time.Time   `json:"end_time,omitempty"`
+	FilesTotal   int         `json:"files_total"`
+	FilesIndexed int         `json:"files_indexed"`
+	ChunksTotal  int         `json:"chunks_total"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// Snapshot safely retrieves the job's current state
+func (j *IndexJob) Snapshot() IndexJobSnapshot {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return IndexJobSnapshot{
+		ID:           j.ID,
+		RepoPath:     j.RepoPath,
+		Status:       j.Status,
+		Progress:     j.Progress,
+		StartTime:    j.StartTime,
+		EndTime:      j.EndTime,
+		FilesTotal:   j.FilesTotal,
+		FilesIndexed: j.FilesIndexed,
+		ChunksTotal:  j.ChunksTotal,
+		Error:        j.Error,
+	}
+}
+
+// GetStatus safely retrieves the job status and, for a failed or cancelled job, the reason
+func (j *IndexJob) GetStatus() (IndexStatus, string) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.Status, j.Error
+}
+
+// Complete safely marks the job completed
+func (j *IndexJob) Complete() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Status = IndexStatusCompleted
+}
+
+// Fail safely marks the job failed with the reason
+func (j *IndexJob) Fail(reason string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Status = IndexStatusFailed
+	j.Error = reason
+}
+
+// Cancel safely marks the job cancelled with the reason
+func (j *IndexJob) Cancel(reason string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Status = IndexStatusCancelled
+	j.Error = reason
+}
+
+// Finish safely records when the job ended
+func (j *IndexJob) Finish() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.EndTime = time.Now()
+}
+
+// SetChunksTotal safely sets the total chunks count
+func (j *IndexJob) SetChunksTotal(total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.ChunksTotal = total
+}
+
+// GetChunksTotal safely retrieves the total chunks count
+func (j *IndexJob) GetChunksTotal() int {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.ChunksTotal
+}
+
+// AddFailedFile safely records a file that failed to process
+func (j *IndexJob) AddFailedFile(path string, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.FailedFiles = append(j.FailedFiles, FileError{Path: path, Error: err.Error()})
+	j.Stats.FilesFailed = len(j.FailedFiles)
+}
+
+// RecordUnchangedFile safely counts a file skipped because it has not changed
+func (j *IndexJob) RecordUnchangedFile() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Stats.FilesUnchanged++
+}
+
+// RecordUnsupportedFile safely counts a file skipped by chunking because its type is unsupported
+func (j *IndexJob) RecordUnsupportedFile() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Stats.FilesUnsupported++
+}
+
+// RecordASTFallback safely records a file that fell back from AST to token chunking
+func (j *IndexJob) RecordASTFallback(path string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Stats.ASTFallbackFiles = append(j.Stats.ASTFallbackFiles, path)
+}
+
+// SetChunkStats safely records the chunk breakdown and embedded size
+func (j *IndexJob) SetChunkStats(chunksByType map[string]int, bytesEmbedded int64, avgChunkBytes int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Stats.ChunksByType = chunksByType
+	j.Stats.BytesEmbedded = bytesEmbedded
+	j.Stats.AvgChunkBytes = avgChunkBytes
+}
+
+// SetFilesByLanguage safely records how many chunked files there were per language
+func (j *IndexJob) SetFilesByLanguage(filesByLanguage map[string]int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Stats.FilesByLanguage = filesByLanguage
+}
+
+// GetStats safely retrieves a copy of the run statistics
+func (j *IndexJob) GetStats() IndexStats {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	stats := j.Stats
+	stats.FilesFailed = len(j.FailedFiles)
+	stats.ChunksByType = make(map[string]int, len(j.Stats.ChunksByType))
+	for k, v := range j.Stats.ChunksByType {
+		stats.ChunksByType[k] = v
+	}
+	stats.FilesByLanguage = make(map[string]int, len(j.Stats.FilesByLanguage))
+	for k, v := range j.Stats.FilesByLanguage {
+		stats.FilesByLanguage[k] = v
+	}
+	stats.ASTFallbackFiles = append([]string(nil), j.Stats.ASTFallbackFiles...)
+	return stats
+}
+
+// GetFailedFiles safely retrieves a copy of the failed files
+func (j *IndexJob) GetFailedFiles() []FileError {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	failed := make([]FileError, len(j.FailedFiles))
+	copy(failed, j.FailedFiles)
+	return failed
 }
 
 // UpdateProgress safely updates the FilesIndexed and Progress fields
@@ -94,6 +328,42 @@ func (j *IndexJob) GetProgress() (filesIndexed int, progress float64) {
 	return j.FilesIndexed, j.Progress
 }
 
+// RecordChunks safely counts chunks produced by a processed file
+func (j *IndexJob) RecordChunks(n int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.chunksFound += n
+}
+
+// MarkChunked safely records that every file has been chunked, so embedding starts
+func (j *IndexJob) MarkChunked() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.chunkedAt = time.Now()
+}
+
+// RecordEmbedded safely counts chunks whose embedding batch is done
+func (j *IndexJob) RecordEmbedded(n int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.embedded += n
+}
+
+// GetChunksEmbedded safely retrieves how many chunks' embedding batches are done
+func (j *IndexJob) GetChunksEmbedded() int {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.embedded
+}
+
+// GetChunkingProgress safely retrieves the chunks found so far and when chunking finished
+// (zero while files are still being chunked)
+func (j *IndexJob) GetChunkingProgress() (chunksFound int, chunkedAt time.Time) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.chunksFound, j.chunkedAt
+}
+
 // GetFilesTotal safely retrieves the total files count
 func (j *IndexJob) GetFilesTotal() int {
 	j.mu.RLock()
@@ -121,6 +391,16 @@ type FileHashCache struct {
 	RepoPath string               `json:"repo_path"`
 	Hashes   map[string]FileHash  `json:"hashes"`
 	UpdatedAt time.Time           `json:"updated_at"`
+	// Directory mtimes at the last index, for directories whose files were all indexed
+	DirMtimes map[string]time.Time `json:"dir_mtimes,omitempty"`
+	// Prefix prepended to documents when they were embedded; caches written before prefixes
+	// existed have none, matching the unprefixed vectors they describe
+	DocumentPrefix string `json:"document_prefix,omitempty"`
+	// Commit checked out when the last full index attached blame metadata, empty without blame
+	GitHead string `json:"git_head,omitempty"`
+	// Files the last index skipped or failed to index, with when; they have no up-to-date hash
+	// but aren't stale until they change again
+	Unindexed map[string]time.Time `json:"unindexed,omitempty"`
 }
 
 // SearchQuery represents a semantic search query
@@ -131,6 +411,16 @@ type SearchQuery struct {
 	Limit     int       `json:"limit"`
 }
 
+// SearchFilter narrows a vector search; empty fields don't filter
+type SearchFilter struct {
+	RepoPath string // Only chunks of this repository
+	FilePath string // Only chunks of this file (absolute path)
+	// Only chunks indexed within [Since, Until]; a zero time leaves that end open. Chunks
+	// stored before indexing times were recorded have none, so never match a window.
+	Since time.Time
+	Until time.Time
+}
+
 // SearchResponse contains search results
 type SearchResponse struct {
 	Results   []SearchResult `json:"results"`
@@ -0,0 +1,36 @@
+package search
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// adjacentChunkFinder is a VectorDB that can look up the chunks next to a line range of a file
+type adjacentChunkFinder interface {
+	AdjacentChunks(ctx context.Context, repoPath, filePath string, startLine, endLine int) (*models.CodeChunk, *models.CodeChunk, error)
+}
+
+// attachAdjacent sets the Before and After chunks of each result
+// Lookups that fail leave the result without them: the results themselves are still valid.
+func (s *Searcher) attachAdjacent(ctx context.Context, results []SearchResult) {
+	finder, ok := s.vectorDB.(adjacentChunkFinder)
+	if !ok {
+		slog.Debug("Vector database can't look up adjacent chunks")
+		return
+	}
+
+	for i := range results {
+		if ctx.Err() != nil {
+			return
+		}
+		chunk := results[i].Chunk
+		before, after, err := finder.AdjacentChunks(ctx, chunk.RepoPath, chunk.FilePath, chunk.StartLine, chunk.EndLine)
+		if err != nil {
+			slog.Warn("Failed to fetch adjacent chunks", "file", chunk.FilePath, "start_line", chunk.StartLine, "error", err)
+			continue
+		}
+		results[i].Before, results[i].After = before, after
+	}
+}
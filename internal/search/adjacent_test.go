@@ -0,0 +1,102 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// adjacentVectorDB is a mockVectorDB that looks up adjacent chunks like the Qdrant client
+type adjacentVectorDB struct {
+	*mockVectorDB
+	lookups int
+	err     error
+}
+
+func (m *adjacentVectorDB) AdjacentChunks(ctx context.Context, repoPath, filePath string, startLine, endLine int) (*models.CodeChunk, *models.CodeChunk, error) {
+	m.lookups++
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+	var before, after *models.CodeChunk
+	for i := range m.chunks {
+		chunk := &m.chunks[i]
+		if chunk.RepoPath != repoPath || chunk.FilePath != filePath {
+			continue
+		}
+		if chunk.StartLine < startLine && chunk.EndLine < endLine && (before == nil || chunk.StartLine > before.StartLine) {
+			before = chunk
+		}
+		if chunk.StartLine > startLine && chunk.EndLine > endLine && (after == nil || chunk.StartLine < after.StartLine) {
+			after = chunk
+		}
+	}
+	return before, after, nil
+}
+
+func TestSearchFiltered_IncludeAdjacent(t *testing.T) {
+	db := &adjacentVectorDB{mockVectorDB: &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "middle", Content: "func middle()", RepoPath: "/repo", FilePath: "/repo/a.go", StartLine: 10, EndLine: 20},
+			{ID: "first", Content: "func first()", RepoPath: "/repo", FilePath: "/repo/a.go", StartLine: 1, EndLine: 9},
+			{ID: "last", Content: "func last()", RepoPath: "/repo", FilePath: "/repo/a.go", StartLine: 21, EndLine: 30},
+			{ID: "class", Content: "type A struct", RepoPath: "/repo", FilePath: "/repo/a.go", StartLine: 1, EndLine: 30},
+			{ID: "other", Content: "func other()", RepoPath: "/repo", FilePath: "/repo/b.go", StartLine: 1, EndLine: 5},
+		},
+		scores: []float64{0.9, 0.2, 0.2, 0.2, 0.8},
+	}}
+	cfg := &config.SearchConfig{MaxResults: 2, SemanticWeight: 1}
+	searcher := NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, db)
+	filter := models.SearchFilter{RepoPath: "/repo"}
+
+	results, err := searcher.SearchFiltered(context.Background(), "unrelated", filter, SearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+	if db.lookups != 0 || results[0].Before != nil || results[0].After != nil {
+		t.Fatal("Expected no adjacent chunks unless requested")
+	}
+
+	results, err = searcher.SearchFiltered(context.Background(), "unrelated", filter, SearchOptions{IncludeAdjacent: true})
+	if err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+	if len(results) != 2 || db.lookups != 2 {
+		t.Fatalf("Expected one lookup per returned result, got %d results and %d lookups", len(results), db.lookups)
+	}
+	// The enclosing class chunk is neither before nor after the function
+	middle := results[0]
+	if middle.Chunk.ID != "middle" || middle.Before == nil || middle.Before.ID != "first" || middle.After == nil || middle.After.ID != "last" {
+		t.Errorf("Expected first and last around middle, got %+v", middle)
+	}
+	// A file's only chunk has no neighbours
+	if other := results[1]; other.Chunk.ID != "other" || other.Before != nil || other.After != nil {
+		t.Errorf("Expected no adjacent chunks for the only chunk of b.go, got %+v", other)
+	}
+
+	// A failed lookup keeps the results
+	db.err = errors.New("qdrant unavailable")
+	results, err = searcher.SearchFiltered(context.Background(), "unrelated", filter, SearchOptions{IncludeAdjacent: true, UniqueFiles: true})
+	if err != nil {
+		t.Fatalf("Expected the search to succeed without adjacent chunks, got %v", err)
+	}
+	if len(results) != 2 || results[0].Before != nil {
+		t.Errorf("Expected the results without adjacent chunks, got %+v", results)
+	}
+}
+
+func TestSearchFiltered_IncludeAdjacentUnsupported(t *testing.T) {
+	db := &mockVectorDB{
+		chunks: []models.CodeChunk{{ID: "a", Content: "func a()", RepoPath: "/repo", FilePath: "/repo/a.go", StartLine: 1, EndLine: 2}},
+		scores: []float64{0.9},
+	}
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, &mockEmbeddingsClient{embeddings: []float32{0.1}}, db)
+
+	results, err := searcher.SearchFiltered(context.Background(), "unrelated", models.SearchFilter{RepoPath: "/repo"}, SearchOptions{IncludeAdjacent: true})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Expected the result of a vector DB without adjacent lookups, got %v, %v", results, err)
+	}
+}
@@ -0,0 +1,69 @@
+package search
+
+import (
+	"fmt"
+)
+
+// QueryComparison is how the results of two queries over the same repository differ
+type QueryComparison struct {
+	Shared []SharedResult // Returned for both queries, in query A's order
+	OnlyA  []RankedResult // Returned for query A only
+	OnlyB  []RankedResult // Returned for query B only
+}
+
+// SharedResult is a result returned for both queries, with its rank (1-based) and score in each
+type SharedResult struct {
+	Result SearchResult
+	RankA  int
+	RankB  int
+	ScoreA float64
+	ScoreB float64
+}
+
+// RankedResult is a result returned for one query only, with its rank (1-based) there
+type RankedResult struct {
+	Result SearchResult
+	Rank   int
+}
+
+// CompareResults splits the results of two queries into shared results and results unique
+// to each query
+// Results are matched by chunk, so the same chunk at different ranks is shared.
+func CompareResults(a, b []SearchResult) QueryComparison {
+	ranksB := make(map[string]int, len(b))
+	for i, result := range b {
+		ranksB[resultKey(result)] = i + 1
+	}
+
+	var comparison QueryComparison
+	inA := make(map[string]bool, len(a))
+	for i, result := range a {
+		key := resultKey(result)
+		inA[key] = true
+		if rankB, ok := ranksB[key]; ok {
+			comparison.Shared = append(comparison.Shared, SharedResult{
+				Result: result,
+				RankA:  i + 1,
+				RankB:  rankB,
+				ScoreA: result.HybridScore,
+				ScoreB: b[rankB-1].HybridScore,
+			})
+			continue
+		}
+		comparison.OnlyA = append(comparison.OnlyA, RankedResult{Result: result, Rank: i + 1})
+	}
+	for i, result := range b {
+		if !inA[resultKey(result)] {
+			comparison.OnlyB = append(comparison.OnlyB, RankedResult{Result: result, Rank: i + 1})
+		}
+	}
+	return comparison
+}
+
+// resultKey identifies a result's chunk, by ID or else by location
+func resultKey(result SearchResult) string {
+	if result.Chunk.ID != "" {
+		return result.Chunk.ID
+	}
+	return fmt.Sprintf("%s:%d-%d", result.Chunk.FilePath, result.Chunk.StartLine, result.Chunk.EndLine)
+}
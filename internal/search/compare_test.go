@@ -0,0 +1,58 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+func TestCompareResults(t *testing.T) {
+	result := func(id string, score float64) SearchResult {
+		return SearchResult{Chunk: models.CodeChunk{ID: id, FilePath: "/repo/" + id + ".go"}, HybridScore: score}
+	}
+	a := []SearchResult{result("login", 0.9), result("session", 0.8), result("token", 0.7), result("logout", 0.6)}
+	b := []SearchResult{result("token", 0.95), result("jwt", 0.85), result("login", 0.75)}
+
+	comparison := CompareResults(a, b)
+
+	expectedShared := []SharedResult{
+		{RankA: 1, RankB: 3, ScoreA: 0.9, ScoreB: 0.75},
+		{RankA: 3, RankB: 1, ScoreA: 0.7, ScoreB: 0.95},
+	}
+	if len(comparison.Shared) != len(expectedShared) {
+		t.Fatalf("Expected %d shared results, got %+v", len(expectedShared), comparison.Shared)
+	}
+	for i, expected := range expectedShared {
+		got := comparison.Shared[i]
+		if got.RankA != expected.RankA || got.RankB != expected.RankB || got.ScoreA != expected.ScoreA || got.ScoreB != expected.ScoreB {
+			t.Errorf("Shared result %d: expected ranks %d/%d and scores %.2f/%.2f, got %d/%d and %.2f/%.2f", i,
+				expected.RankA, expected.RankB, expected.ScoreA, expected.ScoreB, got.RankA, got.RankB, got.ScoreA, got.ScoreB)
+		}
+	}
+	if comparison.Shared[0].Result.Chunk.ID != "login" || comparison.Shared[1].Result.Chunk.ID != "token" {
+		t.Errorf("Expected shared results in query A's order, got %s and %s",
+			comparison.Shared[0].Result.Chunk.ID, comparison.Shared[1].Result.Chunk.ID)
+	}
+
+	if len(comparison.OnlyA) != 2 || comparison.OnlyA[0].Result.Chunk.ID != "session" || comparison.OnlyA[0].Rank != 2 ||
+		comparison.OnlyA[1].Result.Chunk.ID != "logout" || comparison.OnlyA[1].Rank != 4 {
+		t.Errorf("Expected session (#2) and logout (#4) only for query A, got %+v", comparison.OnlyA)
+	}
+	if len(comparison.OnlyB) != 1 || comparison.OnlyB[0].Result.Chunk.ID != "jwt" || comparison.OnlyB[0].Rank != 2 {
+		t.Errorf("Expected jwt (#2) only for query B, got %+v", comparison.OnlyB)
+	}
+}
+
+func TestCompareResults_Disjoint(t *testing.T) {
+	a := []SearchResult{{Chunk: models.CodeChunk{FilePath: "/repo/a.go", StartLine: 1, EndLine: 5}}}
+	b := []SearchResult{{Chunk: models.CodeChunk{FilePath: "/repo/a.go", StartLine: 6, EndLine: 9}}}
+
+	comparison := CompareResults(a, b)
+	if len(comparison.Shared) != 0 || len(comparison.OnlyA) != 1 || len(comparison.OnlyB) != 1 {
+		t.Errorf("Expected different chunks of one file not to be shared, got %+v", comparison)
+	}
+
+	if comparison := CompareResults(nil, b); len(comparison.OnlyB) != 1 || len(comparison.Shared) != 0 {
+		t.Errorf("Expected every result of B to be unique when A has none, got %+v", comparison)
+	}
+}
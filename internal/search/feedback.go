@@ -0,0 +1,245 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// FeedbackFile is the name of the relevance feedback file in the cache directory
+const FeedbackFile = "feedback.json"
+
+const (
+	// maxFeedbackAdjustment bounds feedback multipliers to [1-max, 1+max]
+	maxFeedbackAdjustment = 0.25
+	// minFeedbackVotes is how many votes a category needs before its scores are adjusted
+	minFeedbackVotes = 5
+	// maxFeedbackEvents is how many recent feedback events are kept for inspection
+	maxFeedbackEvents = 500
+)
+
+// Path categories, matching the cases of calculateFilePathScore
+const (
+	PathCategoryTest      = "test"
+	PathCategorySource    = "source"
+	PathCategoryGenerated = "generated"
+	PathCategoryOther     = "other"
+)
+
+// FeedbackCounts tallies the votes for one category of results
+type FeedbackCounts struct {
+	Helpful   int `json:"helpful"`
+	Unhelpful int `json:"unhelpful"`
+}
+
+// Adjustment returns the score multiplier earned by the votes, 1 below minFeedbackVotes
+// The net share of helpful votes moves the multiplier toward its bound as votes accumulate,
+// so a handful of votes only nudges scores.
+func (c FeedbackCounts) Adjustment() float64 {
+	votes := c.Helpful + c.Unhelpful
+	if votes < minFeedbackVotes {
+		return 1
+	}
+	net := float64(c.Helpful-c.Unhelpful) / float64(votes)
+	confidence := float64(votes) / float64(votes+minFeedbackVotes)
+	return 1 + maxFeedbackAdjustment*net*confidence
+}
+
+// FeedbackEvent is one judgement of a search result
+type FeedbackEvent struct {
+	RepoPath  string           `json:"repo_path"`
+	Query     string           `json:"query,omitempty"`
+	FilePath  string           `json:"file_path"`
+	ChunkType models.ChunkType `json:"chunk_type,omitempty"`
+	Helpful   bool             `json:"helpful"`
+	Time      time.Time        `json:"time"`
+}
+
+// CategoryFeedback reports the votes of a category and the multiplier they produce
+type CategoryFeedback struct {
+	FeedbackCounts
+	Multiplier float64 `json:"multiplier"`
+}
+
+// FeedbackStats summarizes the feedback recorded for a repository
+type FeedbackStats struct {
+	RepoPath string `json:"repo_path"`
+	// Categories maps "path:<category>" and "type:<chunk type>" to their votes
+	Categories map[string]CategoryFeedback `json:"categories"`
+	MinVotes   int                         `json:"min_votes"`      // Votes needed before a category is adjusted
+	MaxAdjust  float64                     `json:"max_adjustment"` // Multipliers stay within 1 ± this
+}
+
+// feedbackData is the persisted feedback
+type feedbackData struct {
+	// Repos maps a repository to its votes per category
+	Repos  map[string]map[string]FeedbackCounts `json:"repos"`
+	Events []FeedbackEvent                      `json:"events"` // Oldest first, at most maxFeedbackEvents
+}
+
+// FeedbackStore persists relevance feedback and derives bounded score adjustments from it
+// Votes are tallied per repository by file path category and chunk type, so feedback on one
+// result nudges the ranking of similar results.
+type FeedbackStore struct {
+	path string
+	mux  sync.RWMutex
+	data feedbackData
+}
+
+// NewFeedbackStore loads the feedback recorded in cacheDir, starting empty if there is none
+func NewFeedbackStore(cacheDir string) (*FeedbackStore, error) {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	store := &FeedbackStore{
+		path: filepath.Join(cacheDir, FeedbackFile),
+		data: feedbackData{Repos: make(map[string]map[string]FeedbackCounts)},
+	}
+
+	data, err := os.ReadFile(store.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feedback file: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.data); err != nil {
+		// Feedback only nudges scores, so losing it mustn't stop the server
+		slog.Warn("Ignoring corrupt feedback file, starting with no feedback", "path", store.path, "error", err)
+		store.data = feedbackData{}
+	}
+	if store.data.Repos == nil {
+		store.data.Repos = make(map[string]map[string]FeedbackCounts)
+	}
+	return store, nil
+}
+
+// Record tallies a judgement of a search result and saves the feedback
+func (f *FeedbackStore) Record(event FeedbackEvent) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	counts := f.data.Repos[event.RepoPath]
+	if counts == nil {
+		counts = make(map[string]FeedbackCounts)
+		f.data.Repos[event.RepoPath] = counts
+	}
+	for _, category := range feedbackCategories(event.FilePath, event.ChunkType) {
+		c := counts[category]
+		if event.Helpful {
+			c.Helpful++
+		} else {
+			c.Unhelpful++
+		}
+		counts[category] = c
+	}
+
+	f.data.Events = append(f.data.Events, event)
+	if len(f.data.Events) > maxFeedbackEvents {
+		f.data.Events = f.data.Events[len(f.data.Events)-maxFeedbackEvents:]
+	}
+
+	return f.save()
+}
+
+// save writes the feedback to a temporary file and renames it over the feedback file, so a
+// crash mid-write never leaves a truncated file; the caller holds the lock
+func (f *FeedbackStore) save() error {
+	data, err := json.MarshalIndent(f.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal feedback: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), FeedbackFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create feedback file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write feedback file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write feedback file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write feedback file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to replace feedback file: %w", err)
+	}
+	return nil
+}
+
+// Adjustment returns the feedback multiplier for a result, within 1 ± maxFeedbackAdjustment
+// for each of its path category and chunk type
+func (f *FeedbackStore) Adjustment(repoPath, filePath string, chunkType models.ChunkType) float64 {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+
+	counts := f.data.Repos[repoPath]
+	if counts == nil {
+		return 1
+	}
+	multiplier := 1.0
+	for _, category := range feedbackCategories(filePath, chunkType) {
+		multiplier *= counts[category].Adjustment()
+	}
+	return multiplier
+}
+
+// Stats reports the votes and multipliers of every category with feedback for a repository
+func (f *FeedbackStore) Stats(repoPath string) FeedbackStats {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+
+	stats := FeedbackStats{
+		RepoPath:   repoPath,
+		Categories: make(map[string]CategoryFeedback),
+		MinVotes:   minFeedbackVotes,
+		MaxAdjust:  maxFeedbackAdjustment,
+	}
+	for category, counts := range f.data.Repos[repoPath] {
+		stats.Categories[category] = CategoryFeedback{FeedbackCounts: counts, Multiplier: counts.Adjustment()}
+	}
+	return stats
+}
+
+// feedbackCategories returns the categories a result's votes are tallied under
+func feedbackCategories(filePath string, chunkType models.ChunkType) []string {
+	categories := []string{"path:" + filePathCategory(filePath)}
+	if chunkType != "" {
+		categories = append(categories, "type:"+string(chunkType))
+	}
+	return categories
+}
+
+// filePathCategory classifies a file path like calculateFilePathScore does
+func filePathCategory(filePath string) string {
+	pathLower := strings.ToLower(filePath)
+	switch {
+	case isTestFile(pathLower):
+		return PathCategoryTest
+	case isMainSourceFile(pathLower):
+		return PathCategorySource
+	case isGeneratedOrVendor(pathLower):
+		return PathCategoryGenerated
+	default:
+		return PathCategoryOther
+	}
+}
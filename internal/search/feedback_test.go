@@ -0,0 +1,147 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+func TestFeedbackCounts_Adjustment(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts FeedbackCounts
+		check  func(float64) bool
+	}{
+		{"no votes", FeedbackCounts{}, func(m float64) bool { return m == 1 }},
+		{"too few votes", FeedbackCounts{Unhelpful: minFeedbackVotes - 1}, func(m float64) bool { return m == 1 }},
+		{"mostly unhelpful", FeedbackCounts{Helpful: 1, Unhelpful: 9}, func(m float64) bool { return m < 1 && m >= 1-maxFeedbackAdjustment }},
+		{"mostly helpful", FeedbackCounts{Helpful: 9, Unhelpful: 1}, func(m float64) bool { return m > 1 && m <= 1+maxFeedbackAdjustment }},
+		{"evenly split", FeedbackCounts{Helpful: 5, Unhelpful: 5}, func(m float64) bool { return m == 1 }},
+		{"bounded with many votes", FeedbackCounts{Unhelpful: 100000}, func(m float64) bool { return m > 1-maxFeedbackAdjustment }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if m := tt.counts.Adjustment(); !tt.check(m) {
+				t.Errorf("Unexpected multiplier %.4f for %+v", m, tt.counts)
+			}
+		})
+	}
+
+	// More votes in the same proportion move the multiplier further
+	few := FeedbackCounts{Unhelpful: minFeedbackVotes}.Adjustment()
+	many := FeedbackCounts{Unhelpful: 10 * minFeedbackVotes}.Adjustment()
+	if many >= few {
+		t.Errorf("Expected more votes to adjust further, got %.4f for few and %.4f for many", few, many)
+	}
+}
+
+func TestFeedbackStore_PersistsAndAdjusts(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFeedbackStore(dir)
+	if err != nil {
+		t.Fatalf("NewFeedbackStore failed: %v", err)
+	}
+
+	repo := "/repo"
+	testFile := "/repo/src/test/PaymentServiceTest.java"
+	for i := 0; i < 10; i++ {
+		if err := store.Record(FeedbackEvent{RepoPath: repo, Query: "refund", FilePath: testFile, ChunkType: models.ChunkTypeMethod, Helpful: false}); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	// Feedback survives a restart
+	reloaded, err := NewFeedbackStore(dir)
+	if err != nil {
+		t.Fatalf("Reloading feedback failed: %v", err)
+	}
+	stats := reloaded.Stats(repo)
+	if got := stats.Categories["path:test"]; got.Unhelpful != 10 || got.Multiplier >= 1 {
+		t.Errorf("Expected 10 unhelpful votes demoting test files, got %+v", got)
+	}
+	if got := stats.Categories["type:method"]; got.Unhelpful != 10 {
+		t.Errorf("Expected 10 unhelpful votes for methods, got %+v", got)
+	}
+	if len(reloaded.data.Events) != 10 || reloaded.data.Events[0].Query != "refund" {
+		t.Errorf("Expected the 10 events to be persisted, got %d", len(reloaded.data.Events))
+	}
+
+	// Similar results are demoted; other repositories and categories are not
+	if m := reloaded.Adjustment(repo, "/repo/src/test/OtherTest.java", models.ChunkTypeMethod); m >= 1 || m < (1-maxFeedbackAdjustment)*(1-maxFeedbackAdjustment) {
+		t.Errorf("Expected a bounded demotion for a similar result, got %.4f", m)
+	}
+	if m := reloaded.Adjustment(repo, "/repo/docs/readme.java", models.ChunkTypeFunction); m != 1 {
+		t.Errorf("Expected no adjustment for an unvoted category, got %.4f", m)
+	}
+	if m := reloaded.Adjustment("/other", testFile, models.ChunkTypeMethod); m != 1 {
+		t.Errorf("Expected no adjustment for another repository, got %.4f", m)
+	}
+}
+
+func TestFeedbackStore_CorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FeedbackFile)
+	if err := os.WriteFile(path, []byte(`{"repos": {`), 0644); err != nil {
+		t.Fatalf("Failed to write feedback file: %v", err)
+	}
+
+	store, err := NewFeedbackStore(dir)
+	if err != nil {
+		t.Fatalf("Expected a corrupt feedback file to be ignored, got %v", err)
+	}
+	if len(store.Stats("/repo").Categories) != 0 {
+		t.Errorf("Expected no feedback, got %+v", store.Stats("/repo"))
+	}
+
+	// Recording replaces the corrupt file, leaving no temporary files behind
+	if err := store.Record(FeedbackEvent{RepoPath: "/repo", FilePath: "/repo/main.go", Helpful: true}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if _, err := NewFeedbackStore(dir); err != nil {
+		t.Fatalf("Reloading feedback failed: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to list cache directory: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != FeedbackFile {
+		t.Errorf("Expected only %s in the cache directory, got %v", FeedbackFile, entries)
+	}
+}
+
+func TestFeedbackStore_InfluencesScoring(t *testing.T) {
+	store, err := NewFeedbackStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFeedbackStore failed: %v", err)
+	}
+	chunks := []models.CodeChunk{
+		{RepoPath: "/repo", Content: "helper one", FilePath: "/repo/scripts/one.js", ChunkType: models.ChunkTypeFunction},
+		{RepoPath: "/repo", Content: "helper two", FilePath: "/repo/scripts/two.js", ChunkType: models.ChunkTypeFunction},
+	}
+	for i := 0; i < 20; i++ {
+		store.Record(FeedbackEvent{RepoPath: "/repo", FilePath: "/repo/scripts/other.js", Helpful: true})
+	}
+
+	for _, mode := range []string{ScoringModeNormalized, ScoringModeAdditive} {
+		t.Run(mode, func(t *testing.T) {
+			cfg := &config.SearchConfig{ScoringMode: mode, SemanticWeight: 0.7, LexicalWeight: 0.3, ExactMatchBoost: 1.5}
+			searcher := NewSearcher(cfg, nil, nil)
+			searcher.SetFeedback(store)
+
+			// Opt-in: the store alone changes nothing
+			baseline := searcher.applyHybridScoring("payment", chunks, []float64{1, 1})
+
+			cfg.Feedback = true
+			adjusted := searcher.applyHybridScoring("payment", chunks, []float64{1, 1})
+			if adjusted[0].HybridScore <= baseline[0].HybridScore {
+				t.Errorf("Expected helpful votes to boost the score, got %.4f from %.4f", adjusted[0].HybridScore, baseline[0].HybridScore)
+			}
+			if mode == ScoringModeNormalized && adjusted[0].HybridScore > 1 {
+				t.Errorf("Expected normalized scores to stay within [0,1], got %.4f", adjusted[0].HybridScore)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// rrfK dampens the weight of the top ranks in reciprocal-rank fusion, so a result found by
+// several queries outranks one ranked first by a single query (60 is the customary value)
+const rrfK = 60
+
+// MaxMultiQueries caps the queries of a MultiSearch
+const MaxMultiQueries = 10
+
+// multiSearchDepth is how many times max_results each query of a MultiSearch ranks before fusion,
+// so a chunk ranked just below max_results by several queries can still make the cut
+const multiSearchDepth = 3
+
+// batchEmbeddingsClient is an EmbeddingsClient embedding several texts in one call
+type batchEmbeddingsClient interface {
+	GenerateEmbeddings(texts []string) ([][]float32, error)
+}
+
+// FusedResult is a result of a multi-query search
+type FusedResult struct {
+	SearchResult         // The best-ranked occurrence of the chunk across queries
+	FusedScore   float64 // Reciprocal-rank fusion score: sum of 1/(rrfK+rank) over the queries
+	Queries      []int   // Indexes of the queries that returned the chunk
+}
+
+// MultiSearch runs several queries (e.g. rephrasings of one information need) against a
+// repository and merges their results by reciprocal-rank fusion
+// Chunks returned by several queries are listed once, ahead of chunks ranked similarly by a
+// single query. Each query's ranking is fused multiSearchDepth times deeper than the at most
+// search.max_results results returned.
+func (s *Searcher) MultiSearch(ctx context.Context, queries []string, repoPath string) ([]FusedResult, error) {
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("no queries to search")
+	}
+	if len(queries) > MaxMultiQueries {
+		return nil, fmt.Errorf("too many queries (%d, maximum %d)", len(queries), MaxMultiQueries)
+	}
+
+	slog.Info("Searching with several queries", "queries", len(queries), "repo", repoPath)
+
+	parent := ctx
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	embeddings, err := s.embedQueries(ctx, queries)
+	if err != nil {
+		return nil, s.timeoutError(parent, ctx, fmt.Errorf("failed to generate query embeddings: %w", err))
+	}
+
+	rankings := make([][]SearchResult, len(queries))
+	for i, query := range queries {
+		rankings[i], err = s.searchRepo(ctx, query, embeddings[i], models.SearchFilter{RepoPath: repoPath}, SearchOptions{resultsFactor: multiSearchDepth})
+		if err != nil {
+			return nil, s.timeoutError(parent, ctx, fmt.Errorf("search for %q failed: %w", query, err))
+		}
+	}
+
+	fused := FuseRankings(rankings)
+	if len(fused) > s.config.MaxResults {
+		fused = fused[:s.config.MaxResults]
+	}
+	return fused, nil
+}
+
+// embedQueries embeds several search queries, in one batch when the client supports it
+func (s *Searcher) embedQueries(ctx context.Context, queries []string) ([][]float32, error) {
+	batcher, ok := s.embeddingsClient.(batchEmbeddingsClient)
+	if !ok {
+		embeddings := make([][]float32, len(queries))
+		for i, query := range queries {
+			embedding, err := s.embedQuery(ctx, query)
+			if err != nil {
+				return nil, err
+			}
+			embeddings[i] = embedding
+		}
+		return embeddings, nil
+	}
+
+	texts := make([]string, len(queries))
+	for i, query := range queries {
+		texts[i] = s.queryPrefix + query
+	}
+	type embedded struct {
+		embeddings [][]float32
+		err        error
+	}
+	done := make(chan embedded, 1)
+	go func() {
+		embeddings, err := batcher.GenerateEmbeddings(texts)
+		done <- embedded{embeddings, err}
+	}()
+	select {
+	case result := <-done:
+		if result.err == nil && len(result.embeddings) != len(queries) {
+			return nil, fmt.Errorf("got %d embeddings for %d queries", len(result.embeddings), len(queries))
+		}
+		return result.embeddings, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// FuseRankings merges ranked result lists by reciprocal-rank fusion
+// Results are matched by chunk; ties keep the better hybrid score first.
+func FuseRankings(rankings [][]SearchResult) []FusedResult {
+	var fused []FusedResult
+	positions := make(map[string]int) // Result key to index in fused
+	bestRanks := make(map[string]int)
+
+	for q, ranking := range rankings {
+		for i, result := range ranking {
+			key := resultKey(result)
+			score := 1 / float64(rrfK+i+1)
+			pos, seen := positions[key]
+			if !seen {
+				positions[key] = len(fused)
+				bestRanks[key] = i
+				fused = append(fused, FusedResult{SearchResult: result, FusedScore: score, Queries: []int{q}})
+				continue
+			}
+			fused[pos].FusedScore += score
+			fused[pos].Queries = append(fused[pos].Queries, q)
+			if i < bestRanks[key] {
+				bestRanks[key] = i
+				fused[pos].SearchResult = result
+			}
+		}
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool {
+		if fused[i].FusedScore != fused[j].FusedScore {
+			return fused[i].FusedScore > fused[j].FusedScore
+		}
+		return fused[i].HybridScore > fused[j].HybridScore
+	})
+	return fused
+}
@@ -0,0 +1,159 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+func TestFuseRankings(t *testing.T) {
+	result := func(id string, score float64) SearchResult {
+		return SearchResult{Chunk: models.CodeChunk{ID: id}, HybridScore: score}
+	}
+	// "session" is second for both queries; "login" and "jwt" each top only one
+	a := []SearchResult{result("login", 0.9), result("session", 0.8), result("cookie", 0.7)}
+	b := []SearchResult{result("jwt", 0.95), result("session", 0.85), result("oauth", 0.6)}
+
+	fused := FuseRankings([][]SearchResult{a, b})
+
+	var order []string
+	for _, r := range fused {
+		order = append(order, r.Chunk.ID)
+	}
+	want := []string{"session", "jwt", "login", "cookie", "oauth"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("Expected fused order %v, got %v", want, order)
+	}
+
+	session := fused[0]
+	if !reflect.DeepEqual(session.Queries, []int{0, 1}) {
+		t.Errorf("Expected session to be found by both queries, got %v", session.Queries)
+	}
+	if wantScore := 2.0 / (rrfK + 2); abs(session.FusedScore-wantScore) > 1e-12 {
+		t.Errorf("Expected fused score %.5f, got %.5f", wantScore, session.FusedScore)
+	}
+	// Ties on rank keep the better hybrid score first; the best-ranked occurrence is kept
+	if session.HybridScore != 0.8 {
+		t.Errorf("Expected the occurrence from the first query, got score %.2f", session.HybridScore)
+	}
+}
+
+func TestFuseRankings_KeepsBestRankedOccurrence(t *testing.T) {
+	a := []SearchResult{
+		{Chunk: models.CodeChunk{ID: "x"}, HybridScore: 0.9},
+		{Chunk: models.CodeChunk{ID: "y"}, HybridScore: 0.5},
+	}
+	b := []SearchResult{{Chunk: models.CodeChunk{ID: "y"}, HybridScore: 0.7}}
+
+	fused := FuseRankings([][]SearchResult{a, b})
+	for _, r := range fused {
+		if r.Chunk.ID == "y" && r.HybridScore != 0.7 {
+			t.Errorf("Expected y's occurrence ranked first by query B, got score %.2f", r.HybridScore)
+		}
+	}
+}
+
+// batchEmbeddings records the batches it embeds
+type batchEmbeddings struct {
+	mockEmbeddingsClient
+	batches [][]string
+}
+
+func (b *batchEmbeddings) GenerateEmbeddings(texts []string) ([][]float32, error) {
+	b.batches = append(b.batches, texts)
+	embeddings := make([][]float32, len(texts))
+	for i := range texts {
+		embeddings[i] = []float32{0.1}
+	}
+	return embeddings, nil
+}
+
+func TestMultiSearch(t *testing.T) {
+	vectorDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "1", Content: "func login()", FilePath: "/repo/auth.go"},
+			{ID: "2", Content: "func logout()", FilePath: "/repo/auth.go"},
+		},
+		scores: []float64{0.9, 0.8},
+	}
+	cfg := &config.SearchConfig{MaxResults: 5, SemanticWeight: 1}
+
+	embedder := &batchEmbeddings{}
+	searcher := NewSearcher(cfg, embedder, vectorDB)
+	searcher.SetTaskPrefixes("search_query: ", "")
+
+	results, err := searcher.MultiSearch(context.Background(), []string{"sign in", "log in"}, "/repo")
+	if err != nil {
+		t.Fatalf("MultiSearch failed: %v", err)
+	}
+
+	if want := [][]string{{"search_query: sign in", "search_query: log in"}}; !reflect.DeepEqual(embedder.batches, want) {
+		t.Errorf("Expected the queries to be embedded in one batch %v, got %v", want, embedder.batches)
+	}
+	if vectorDB.searchCalls != 2 {
+		t.Errorf("Expected one vector search per query, got %d", vectorDB.searchCalls)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected the 2 chunks once each, got %d results", len(results))
+	}
+	for _, r := range results {
+		if len(r.Queries) != 2 {
+			t.Errorf("Expected chunk %s to be found by both queries, got %v", r.Chunk.ID, r.Queries)
+		}
+	}
+
+	// Clients without batch embedding embed each query on its own
+	single := &mockEmbeddingsClient{embeddings: []float32{0.1}}
+	if _, err := NewSearcher(cfg, single, vectorDB).MultiSearch(context.Background(), []string{"sign in", "log in"}, "/repo"); err != nil {
+		t.Fatalf("MultiSearch failed: %v", err)
+	}
+	if len(single.texts) != 2 {
+		t.Errorf("Expected 2 query embeddings, got %v", single.texts)
+	}
+
+	if _, err := searcher.MultiSearch(context.Background(), make([]string, MaxMultiQueries+1), "/repo"); err == nil {
+		t.Error("Expected an error for too many queries")
+	}
+}
+
+// sequenceVectorDB returns each of its rankings in turn, one per Search call
+type sequenceVectorDB struct {
+	mockVectorDB
+	rankings [][]models.CodeChunk
+}
+
+func (db *sequenceVectorDB) Search(ctx context.Context, embedding []float32, filter models.SearchFilter, limit int, minScore float64) ([]models.CodeChunk, []float64, error) {
+	chunks := db.rankings[db.searchCalls%len(db.rankings)]
+	db.searchCalls++
+	scores := make([]float64, len(chunks))
+	for i := range chunks {
+		scores[i] = 0.9 - float64(i)/10
+	}
+	return chunks, scores, nil
+}
+
+func TestMultiSearch_FusesBeyondMaxResults(t *testing.T) {
+	chunk := func(id string) models.CodeChunk {
+		return models.CodeChunk{ID: id, Content: id, FilePath: "/repo/" + id + ".go"}
+	}
+	// Each query ranks its own chunk first and the shared one second, below max_results
+	vectorDB := &sequenceVectorDB{rankings: [][]models.CodeChunk{
+		{chunk("first"), chunk("shared")},
+		{chunk("second"), chunk("shared")},
+	}}
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 1, SemanticWeight: 1}, &batchEmbeddings{}, vectorDB)
+
+	results, err := searcher.MultiSearch(context.Background(), []string{"sign in", "log in"}, "/repo")
+	if err != nil {
+		t.Fatalf("MultiSearch failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Chunk.ID != "shared" {
+		t.Fatalf("Expected the chunk found by both queries, got %+v", results)
+	}
+	if len(results[0].Queries) != 2 {
+		t.Errorf("Expected both queries to find it, got %v", results[0].Queries)
+	}
+}
@@ -0,0 +1,103 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// ResultRecord is one search result as written by WriteNDJSON
+type ResultRecord struct {
+	Rank          int                    `json:"rank"`
+	FilePath      string                 `json:"file_path"`
+	StartLine     int                    `json:"start_line"`
+	EndLine       int                    `json:"end_line"`
+	Language      string                 `json:"language"`
+	ChunkType     models.ChunkType       `json:"chunk_type"`
+	FunctionName  string                 `json:"function_name,omitempty"`
+	ClassName     string                 `json:"class_name,omitempty"`
+	Score         float64                `json:"score"`
+	SemanticScore float64                `json:"semantic_score"`
+	RerankScore   float64                `json:"rerank_score,omitempty"`
+	ExactMatch    bool                   `json:"exact_match"`
+	Content       string                 `json:"content,omitempty"`
+	Truncated     bool                   `json:"truncated,omitempty"` // Content was cut at the size limit
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	Before        *AdjacentRecord        `json:"before,omitempty"` // Chunk before the result in its file, with include_adjacent
+	After         *AdjacentRecord        `json:"after,omitempty"`  // Chunk after the result in its file, with include_adjacent
+}
+
+// AdjacentRecord is a chunk next to a search result in its file, as written by WriteNDJSON
+type AdjacentRecord struct {
+	StartLine    int    `json:"start_line"`
+	EndLine      int    `json:"end_line"`
+	FunctionName string `json:"function_name,omitempty"`
+	ClassName    string `json:"class_name,omitempty"`
+	Content      string `json:"content,omitempty"`
+	Truncated    bool   `json:"truncated,omitempty"`
+}
+
+// WriteNDJSON writes results as newline-delimited JSON, one standalone ResultRecord per line
+// Each result is encoded and written on its own, so w receives results incrementally instead
+// of one document built in memory. With includeContent, each record carries the chunk's
+// content, cut at maxContentChars characters (0 = no limit).
+func WriteNDJSON(w io.Writer, results []SearchResult, includeContent bool, maxContentChars int) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false) // Keep <, > and & in code readable
+	for i, result := range results {
+		chunk := result.Chunk
+		record := ResultRecord{
+			Rank:          i + 1,
+			FilePath:      chunk.FilePath,
+			StartLine:     chunk.StartLine,
+			EndLine:       chunk.EndLine,
+			Language:      chunk.Language,
+			ChunkType:     chunk.ChunkType,
+			FunctionName:  chunk.FunctionName,
+			ClassName:     chunk.ClassName,
+			Score:         result.HybridScore,
+			SemanticScore: result.SemanticScore,
+			RerankScore:   result.RerankScore,
+			ExactMatch:    result.ExactMatch,
+			Metadata:      chunk.Metadata,
+		}
+		if includeContent {
+			record.Content, record.Truncated = cutContent(chunk.Content, maxContentChars)
+		}
+		record.Before = adjacentRecord(result.Before, includeContent, maxContentChars)
+		record.After = adjacentRecord(result.After, includeContent, maxContentChars)
+
+		// Encode terminates each record with a newline
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to write result %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// adjacentRecord describes an adjacent chunk, nil when there is none
+func adjacentRecord(chunk *models.CodeChunk, includeContent bool, maxContentChars int) *AdjacentRecord {
+	if chunk == nil {
+		return nil
+	}
+	record := &AdjacentRecord{
+		StartLine:    chunk.StartLine,
+		EndLine:      chunk.EndLine,
+		FunctionName: chunk.FunctionName,
+		ClassName:    chunk.ClassName,
+	}
+	if includeContent {
+		record.Content, record.Truncated = cutContent(chunk.Content, maxContentChars)
+	}
+	return record
+}
+
+// cutContent cuts content at maxContentChars characters (0 = no limit), reporting whether it did
+func cutContent(content string, maxContentChars int) (string, bool) {
+	if runes := []rune(content); maxContentChars > 0 && len(runes) > maxContentChars {
+		return string(runes[:maxContentChars]), true
+	}
+	return content, false
+}
@@ -0,0 +1,122 @@
+package search
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+func TestWriteNDJSON(t *testing.T) {
+	results := []SearchResult{
+		{
+			Chunk: models.CodeChunk{
+				FilePath: "/repo/auth.go", StartLine: 10, EndLine: 20, Language: "go",
+				ChunkType: models.ChunkTypeFunction, FunctionName: "login",
+				Content:  "func login() {\n\tif a < b && c > d {}\n}",
+				Metadata: map[string]interface{}{"last_author": "alice"},
+			},
+			HybridScore: 0.9, SemanticScore: 0.8, ExactMatch: true,
+		},
+		{
+			Chunk:       models.CodeChunk{FilePath: "/repo/user.go", StartLine: 1, EndLine: 3, Content: strings.Repeat("é", 50)},
+			HybridScore: 0.7, SemanticScore: 0.7, RerankScore: 0.5,
+		},
+	}
+
+	var output strings.Builder
+	if err := WriteNDJSON(&output, results, true, 40); err != nil {
+		t.Fatalf("WriteNDJSON failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != len(results) {
+		t.Fatalf("Expected one line per result, got %d lines:\n%s", len(lines), output.String())
+	}
+
+	// Every line is a standalone result object
+	records := make([]ResultRecord, len(lines))
+	for i, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Fatalf("Line %d is not valid JSON: %s", i+1, line)
+		}
+		if err := json.Unmarshal([]byte(line), &records[i]); err != nil {
+			t.Fatalf("Line %d is not a result object: %v", i+1, err)
+		}
+	}
+
+	first := records[0]
+	if first.Rank != 1 || first.FilePath != "/repo/auth.go" || first.StartLine != 10 || first.EndLine != 20 ||
+		first.FunctionName != "login" || first.Score != 0.9 || !first.ExactMatch {
+		t.Errorf("Unexpected first record: %+v", first)
+	}
+	if first.Content != results[0].Chunk.Content || first.Truncated {
+		t.Errorf("Expected the full content, got %q", first.Content)
+	}
+	if first.Metadata["last_author"] != "alice" {
+		t.Errorf("Expected metadata, got %v", first.Metadata)
+	}
+	if !strings.Contains(lines[0], "a < b && c > d") {
+		t.Errorf("Expected code characters to stay unescaped, got %s", lines[0])
+	}
+
+	second := records[1]
+	if second.Rank != 2 || second.RerankScore != 0.5 {
+		t.Errorf("Unexpected second record: %+v", second)
+	}
+	if second.Content != strings.Repeat("é", 40) || !second.Truncated {
+		t.Errorf("Expected content cut at 40 characters, got %q (truncated=%v)", second.Content, second.Truncated)
+	}
+
+	// Without content, records only locate and score the results
+	output.Reset()
+	if err := WriteNDJSON(&output, results, false, 0); err != nil {
+		t.Fatalf("WriteNDJSON failed: %v", err)
+	}
+	if strings.Contains(output.String(), `"content"`) {
+		t.Errorf("Expected no content without includeContent, got %s", output.String())
+	}
+}
+
+func TestWriteNDJSON_Adjacent(t *testing.T) {
+	results := []SearchResult{{
+		Chunk:  models.CodeChunk{FilePath: "/repo/auth.go", StartLine: 10, EndLine: 20, Content: "func login() {}"},
+		Before: &models.CodeChunk{StartLine: 1, EndLine: 9, FunctionName: "init", Content: strings.Repeat("b", 50)},
+		After:  &models.CodeChunk{StartLine: 21, EndLine: 30, ClassName: "Session", Content: "func logout() {}"},
+	}, {
+		Chunk: models.CodeChunk{FilePath: "/repo/user.go", StartLine: 1, EndLine: 3, Content: "type User struct{}"},
+	}}
+
+	var output strings.Builder
+	if err := WriteNDJSON(&output, results, true, 40); err != nil {
+		t.Fatalf("WriteNDJSON failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	var first, second ResultRecord
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("Invalid record: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("Invalid record: %v", err)
+	}
+
+	if first.Before == nil || first.Before.StartLine != 1 || first.Before.FunctionName != "init" ||
+		first.Before.Content != strings.Repeat("b", 40) || !first.Before.Truncated {
+		t.Errorf("Unexpected before record: %+v", first.Before)
+	}
+	if first.After == nil || first.After.ClassName != "Session" || first.After.Content != "func logout() {}" || first.After.Truncated {
+		t.Errorf("Unexpected after record: %+v", first.After)
+	}
+	if strings.Contains(lines[1], `"before"`) || strings.Contains(lines[1], `"after"`) {
+		t.Errorf("Expected no adjacent records without adjacent chunks, got %s", lines[1])
+	}
+
+	output.Reset()
+	if err := WriteNDJSON(&output, results, false, 0); err != nil {
+		t.Fatalf("WriteNDJSON failed: %v", err)
+	}
+	if strings.Contains(output.String(), `"content"`) || !strings.Contains(output.String(), `"before"`) {
+		t.Errorf("Expected adjacent line ranges without content, got %s", output.String())
+	}
+}
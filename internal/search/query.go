@@ -0,0 +1,170 @@
+package search
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// orOperator joins alternatives: `auth OR login` matches either term
+	orOperator = "OR"
+	// andOperator is accepted for readability; terms are ANDed by default
+	andOperator = "AND"
+	// scatteredPhraseWeight is the credit for a phrase whose words appear, but not together
+	scatteredPhraseWeight = 0.5
+	// minRequiredWordLen is the shortest unquoted word a group requires, like partial word matching
+	minRequiredWordLen = 3
+)
+
+// queryTerm is a single word or quoted phrase from a search query
+type queryTerm struct {
+	text   string // Lowercased term text
+	phrase bool   // True if the term is a quoted phrase of several words
+	quoted bool   // True if the term was quoted
+}
+
+// parsedQuery is a search query split into lexical match groups
+// A chunk satisfies the query when every group matches; a group matches
+// when any of its terms (OR alternatives) matches.
+type parsedQuery struct {
+	groups [][]queryTerm
+	// plain is true when the query uses no quotes or operators,
+	// in which case the original whole-query matching applies unchanged
+	plain bool
+}
+
+// parseQuery extracts quoted phrases and AND/OR groups from a query
+// Operators must be uppercase so natural-language "or" stays a normal word.
+// An unterminated quote runs to the end of the query.
+func parseQuery(query string) parsedQuery {
+	parsed := parsedQuery{plain: true}
+	var current []queryTerm
+	pendingOr := false
+
+	addTerm := func(term queryTerm) {
+		if pendingOr && len(current) > 0 {
+			current = append(current, term)
+		} else {
+			if len(current) > 0 {
+				parsed.groups = append(parsed.groups, current)
+			}
+			current = []queryTerm{term}
+		}
+		pendingOr = false
+	}
+
+	rest := query
+	for {
+		rest = strings.TrimLeft(rest, " \t\n")
+		if rest == "" {
+			break
+		}
+
+		if rest[0] == '"' {
+			parsed.plain = false
+			end := strings.IndexByte(rest[1:], '"')
+			var phrase string
+			if end == -1 {
+				phrase, rest = rest[1:], ""
+			} else {
+				phrase, rest = rest[1:end+1], rest[end+2:]
+			}
+			if words := strings.Fields(strings.ToLower(phrase)); len(words) > 0 {
+				addTerm(queryTerm{text: strings.Join(words, " "), phrase: len(words) > 1, quoted: true})
+			}
+			continue
+		}
+
+		end := strings.IndexAny(rest, " \t\n\"")
+		if end == -1 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+
+		switch word {
+		case orOperator:
+			parsed.plain = false
+			pendingOr = true
+		case andOperator:
+			parsed.plain = false
+			pendingOr = false
+		default:
+			addTerm(queryTerm{text: strings.ToLower(word)})
+		}
+	}
+
+	if len(current) > 0 {
+		parsed.groups = append(parsed.groups, current)
+	}
+	return parsed
+}
+
+// operatorMatch scores content against a query with phrases or operators
+// Returns whether every group matched, the match positions, and the fraction
+// of the query satisfied (0-1). A quoted phrase whose words only appear
+// scattered through the content earns partial credit, below a real phrase match.
+func (s *Searcher) operatorMatch(content string, query parsedQuery) (bool, []int, float64) {
+	groups := requiredGroups(query)
+	if len(groups) == 0 {
+		return false, nil, 0
+	}
+
+	var positions []int
+	matchedGroups := 0
+	credit := 0.0
+
+	for _, group := range groups {
+		groupCredit := 0.0
+		for _, term := range group {
+			if termPositions := s.matchPositions(content, term.text); len(termPositions) > 0 {
+				positions = append(positions, termPositions...)
+				groupCredit = 1
+				continue
+			}
+			if term.phrase {
+				if scattered := s.scatteredWordCredit(content, term.text); scattered > groupCredit {
+					groupCredit = scattered
+				}
+			}
+		}
+
+		if groupCredit == 1 {
+			matchedGroups++
+		}
+		credit += groupCredit
+	}
+
+	sort.Ints(positions)
+	return matchedGroups == len(groups), positions, credit / float64(len(groups))
+}
+
+// requiredGroups drops unquoted words too short to require, such as "a" or "to", from the
+// query's groups, and the groups left empty; quoting a short word requires it
+func requiredGroups(query parsedQuery) [][]queryTerm {
+	var groups [][]queryTerm
+	for _, group := range query.groups {
+		var terms []queryTerm
+		for _, term := range group {
+			if term.quoted || len(term.text) >= minRequiredWordLen {
+				terms = append(terms, term)
+			}
+		}
+		if len(terms) > 0 {
+			groups = append(groups, terms)
+		}
+	}
+	return groups
+}
+
+// scatteredWordCredit is the partial credit for a phrase whose words appear apart
+func (s *Searcher) scatteredWordCredit(content, phrase string) float64 {
+	words := strings.Fields(phrase)
+	matched := 0
+	for _, word := range words {
+		if len(s.matchPositions(content, word)) > 0 {
+			matched++
+		}
+	}
+	return float64(matched) / float64(len(words)) * scatteredPhraseWeight
+}
@@ -0,0 +1,177 @@
+package search
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		plain  bool
+		groups [][]queryTerm
+	}{
+		{
+			name:   "plain query",
+			query:  "user authentication",
+			plain:  true,
+			groups: [][]queryTerm{{{text: "user"}}, {{text: "authentication"}}},
+		},
+		{
+			name:   "lowercase or is a normal word",
+			query:  "read or write",
+			plain:  true,
+			groups: [][]queryTerm{{{text: "read"}}, {{text: "or"}}, {{text: "write"}}},
+		},
+		{
+			name:   "quoted phrase",
+			query:  `"JWT Token"`,
+			groups: [][]queryTerm{{{text: "jwt token", phrase: true, quoted: true}}},
+		},
+		{
+			name:   "OR group",
+			query:  "auth OR login",
+			groups: [][]queryTerm{{{text: "auth"}, {text: "login"}}},
+		},
+		{
+			name:  "mixed phrase, OR and AND",
+			query: `"jwt token" AND validate OR verify`,
+			groups: [][]queryTerm{
+				{{text: "jwt token", phrase: true, quoted: true}},
+				{{text: "validate"}, {text: "verify"}},
+			},
+		},
+		{
+			name:   "unterminated quote runs to the end",
+			query:  `cache "hit rate`,
+			groups: [][]queryTerm{{{text: "cache"}}, {{text: "hit rate", phrase: true, quoted: true}}},
+		},
+		{
+			name:   "quoted single word",
+			query:  `"ID" OR key`,
+			groups: [][]queryTerm{{{text: "id", quoted: true}, {text: "key"}}},
+		},
+		{
+			name:   "dangling operators are ignored",
+			query:  "OR retry AND",
+			groups: [][]queryTerm{{{text: "retry"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := parseQuery(tt.query)
+			if parsed.plain != tt.plain {
+				t.Errorf("plain = %v, expected %v", parsed.plain, tt.plain)
+			}
+			if !reflect.DeepEqual(parsed.groups, tt.groups) {
+				t.Errorf("groups = %+v, expected %+v", parsed.groups, tt.groups)
+			}
+		})
+	}
+}
+
+func TestOperatorQueryScoring(t *testing.T) {
+	cfg := &config.SearchConfig{SemanticWeight: 1, ExactMatchBoost: 1.5}
+	searcher := NewSearcher(cfg, nil, nil)
+
+	tests := []struct {
+		name        string
+		query       string
+		contents    []string
+		expectExact []bool
+		// expectedOrder lists content indexes from best to worst (all scores start equal)
+		expectedOrder []int
+	}{
+		{
+			name:          "phrase beats scattered words",
+			query:         `"jwt token"`,
+			contents:      []string{"token issued after jwt check", "parse jwt token", "unrelated"},
+			expectExact:   []bool{false, true, false},
+			expectedOrder: []int{1, 0, 2},
+		},
+		{
+			name:          "OR group matches either term",
+			query:         "auth OR login",
+			contents:      []string{"handle login form", "database pool", "auth middleware"},
+			expectExact:   []bool{true, false, true},
+			expectedOrder: []int{2, 1},
+		},
+		{
+			name:          "every group must match for an exact match",
+			query:         `"user id" AND fetch`,
+			contents:      []string{"fetch user id", "fetch the user later by id", "user id only"},
+			expectExact:   []bool{true, false, false},
+			expectedOrder: []int{0, 1, 2},
+		},
+		{
+			name:          "short unquoted words aren't required",
+			query:         `"user id" AND to OR at fetch`,
+			contents:      []string{"fetch user id", "fetch user", "user id only"},
+			expectExact:   []bool{true, false, false},
+			expectedOrder: []int{0, 1, 2},
+		},
+		{
+			name:          "quoted short words are required",
+			query:         `"id" AND fetch`,
+			contents:      []string{"fetch by id", "fetch user"},
+			expectExact:   []bool{true, false},
+			expectedOrder: []int{0, 1},
+		},
+		{
+			name:          "mixed phrase and OR group",
+			query:         `"user id" fetch OR load`,
+			contents:      []string{"fetch by user id", "load user", "user id only"},
+			expectExact:   []bool{true, false, false},
+			expectedOrder: []int{0, 1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := make([]models.CodeChunk, len(tt.contents))
+			scores := make([]float64, len(tt.contents))
+			for i, content := range tt.contents {
+				chunks[i] = models.CodeChunk{ID: string(rune('a' + i)), Content: content, FilePath: "main.go"}
+				scores[i] = 0.5
+			}
+
+			results := searcher.applyHybridScoring(tt.query, chunks, scores)
+
+			for i, expected := range tt.expectExact {
+				if results[i].ExactMatch != expected {
+					t.Errorf("Content %q: ExactMatch = %v, expected %v", tt.contents[i], results[i].ExactMatch, expected)
+				}
+			}
+			for k := 1; k < len(tt.expectedOrder); k++ {
+				better, worse := results[tt.expectedOrder[k-1]], results[tt.expectedOrder[k]]
+				if better.HybridScore <= worse.HybridScore {
+					t.Errorf("Expected %q (%.3f) to outscore %q (%.3f)",
+						better.Chunk.Content, better.HybridScore, worse.Chunk.Content, worse.HybridScore)
+				}
+			}
+		})
+	}
+}
+
+func TestPlainQueryScoringUnchanged(t *testing.T) {
+	cfg := &config.SearchConfig{SemanticWeight: 1, ExactMatchBoost: 1.5}
+	searcher := NewSearcher(cfg, nil, nil)
+
+	results := searcher.applyHybridScoring("user service", []models.CodeChunk{
+		{Content: "new UserService()"},
+		{Content: "user service factory"},
+	}, []float64{0.5, 0.5})
+
+	if results[0].ExactMatch || math.Abs(results[0].HybridScore-(0.5+partialMatchWeight)) > 1e-9 {
+		t.Errorf("Expected partial word match for scattered words, got exact=%v score=%.3f", results[0].ExactMatch, results[0].HybridScore)
+	}
+	if !results[1].ExactMatch || math.Abs(results[1].HybridScore-2.0) > 1e-9 {
+		t.Errorf("Expected whole-query exact match, got exact=%v score=%.3f", results[1].ExactMatch, results[1].HybridScore)
+	}
+}
@@ -0,0 +1,174 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// Reranker names accepted in search.reranker
+const (
+	RerankerNone   = "none"
+	RerankerOllama = "ollama"
+)
+
+const (
+	// DefaultRerankTopK is the number of top hybrid results passed to the reranker
+	DefaultRerankTopK = 20
+	// rerankMaxContentChars limits how much of each candidate is sent to the reranking model
+	rerankMaxContentChars = 2000
+)
+
+// Reranker reorders search candidates by relevance to the query
+// It runs after hybrid scoring and receives candidates best-first; it returns them in
+// the new order, and may set RerankScore on each result.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, candidates []SearchResult) ([]SearchResult, error)
+}
+
+// NoopReranker keeps the hybrid scoring order
+type NoopReranker struct{}
+
+// Rerank returns the candidates unchanged
+func (NoopReranker) Rerank(ctx context.Context, query string, candidates []SearchResult) ([]SearchResult, error) {
+	return candidates, nil
+}
+
+// NewReranker creates the reranker selected in the search configuration
+func NewReranker(cfg *config.SearchConfig, ollamaURL string) (Reranker, error) {
+	switch cfg.Reranker {
+	case "", RerankerNone:
+		return NoopReranker{}, nil
+	case RerankerOllama:
+		if cfg.RerankModel == "" {
+			return nil, fmt.Errorf("search.rerank_model is required for the %s reranker", RerankerOllama)
+		}
+		return NewOllamaReranker(ollamaURL, cfg.RerankModel), nil
+	default:
+		return nil, fmt.Errorf("unknown reranker %q (valid: %s, %s)", cfg.Reranker, RerankerNone, RerankerOllama)
+	}
+}
+
+// OllamaReranker asks an Ollama LLM to rate each candidate's relevance to the query
+// This is a simple pointwise cross-encoder: one generate call per candidate, so it adds
+// noticeable latency and is meant for users who prefer precision over speed.
+type OllamaReranker struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOllamaReranker creates a reranker using the given Ollama model
+func NewOllamaReranker(baseURL, model string) *OllamaReranker {
+	return &OllamaReranker{
+		baseURL:    baseURL,
+		model:      model,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// generateRequest represents a request to Ollama's /api/generate endpoint
+type generateRequest struct {
+	Model   string                 `json:"model"`
+	Prompt  string                 `json:"prompt"`
+	Stream  bool                   `json:"stream"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// generateResponse represents a non-streaming response from /api/generate
+type generateResponse struct {
+	Response string `json:"response"`
+}
+
+// Rerank scores every candidate with the model and sorts by that score
+// Ties keep the hybrid scoring order.
+func (r *OllamaReranker) Rerank(ctx context.Context, query string, candidates []SearchResult) ([]SearchResult, error) {
+	reranked := make([]SearchResult, len(candidates))
+	copy(reranked, candidates)
+
+	for i := range reranked {
+		score, err := r.score(ctx, query, reranked[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to rerank candidate %d: %w", i, err)
+		}
+		reranked[i].RerankScore = score
+	}
+
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].RerankScore > reranked[j].RerankScore
+	})
+	return reranked, nil
+}
+
+// score asks the model for a 0-10 relevance rating and returns it scaled to [0,1]
+func (r *OllamaReranker) score(ctx context.Context, query string, candidate SearchResult) (float64, error) {
+	content := candidate.Chunk.Content
+	if runes := []rune(content); len(runes) > rerankMaxContentChars {
+		content = string(runes[:rerankMaxContentChars])
+	}
+
+	prompt := fmt.Sprintf("Rate how relevant the code is to the search query on a scale from 0 (unrelated) to 10 (exactly what was asked for). "+
+		"Answer with the number only.\n\nQuery: %s\n\nFile: %s\nCode:\n%s\n\nRelevance:", query, candidate.Chunk.FilePath, content)
+
+	reqBody, err := json.Marshal(generateRequest{
+		Model:   r.model,
+		Prompt:  prompt,
+		Stream:  false,
+		Options: map[string]interface{}{"temperature": 0},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.baseURL+"/api/generate", bytes.NewReader(reqBody))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response generateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	rating, ok := parseRating(response.Response)
+	if !ok {
+		slog.Debug("Reranker returned no rating", "file", candidate.Chunk.FilePath, "response", response.Response)
+		return 0, nil
+	}
+	return rating / 10, nil
+}
+
+// parseRating extracts the first number from a model response, clamped to 0-10
+func parseRating(response string) (float64, bool) {
+	fields := strings.FieldsFunc(response, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r == '.')
+	})
+	for _, field := range fields {
+		if rating, err := strconv.ParseFloat(strings.Trim(field, "."), 64); err == nil {
+			return clampUnit(rating/10) * 10, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,199 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// reverseReranker reverses the candidate order, or fails with err (if set)
+type reverseReranker struct {
+	err   error
+	calls int
+	seen  int // Number of candidates in the last call
+}
+
+func (r *reverseReranker) Rerank(ctx context.Context, query string, candidates []SearchResult) ([]SearchResult, error) {
+	r.calls++
+	r.seen = len(candidates)
+	if r.err != nil {
+		return nil, r.err
+	}
+	reversed := make([]SearchResult, len(candidates))
+	for i, c := range candidates {
+		reversed[len(candidates)-1-i] = c
+	}
+	return reversed, nil
+}
+
+func newRerankTestSearcher(cfg *config.SearchConfig) *Searcher {
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "1", Content: "first", FilePath: "a.go"},
+			{ID: "2", Content: "second", FilePath: "b.go"},
+			{ID: "3", Content: "third", FilePath: "c.go"},
+			{ID: "4", Content: "fourth", FilePath: "d.go"},
+		},
+		scores: []float64{0.9, 0.8, 0.7, 0.6},
+	}
+	return NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+}
+
+func resultIDs(results []SearchResult) string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.Chunk.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestSearch_RerankerApplied(t *testing.T) {
+	tests := []struct {
+		name     string
+		topK     int
+		expected string
+		seen     int
+	}{
+		{"all candidates reranked", 10, "4,3,2", 4},
+		{"only the top K reranked", 2, "2,1,3", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searcher := newRerankTestSearcher(&config.SearchConfig{MaxResults: 3, SemanticWeight: 1, RerankTopK: tt.topK})
+			reranker := &reverseReranker{}
+			searcher.SetReranker(reranker)
+
+			results, err := searcher.Search(context.Background(), "query", "/test/repo")
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if got := resultIDs(results); got != tt.expected {
+				t.Errorf("Expected order %s, got %s", tt.expected, got)
+			}
+			if reranker.seen != tt.seen {
+				t.Errorf("Expected %d candidates reranked, got %d", tt.seen, reranker.seen)
+			}
+		})
+	}
+}
+
+func TestSearch_RerankerFailureKeepsHybridOrder(t *testing.T) {
+	searcher := newRerankTestSearcher(&config.SearchConfig{MaxResults: 3, SemanticWeight: 1})
+	reranker := &reverseReranker{err: errors.New("model not loaded")}
+	searcher.SetReranker(reranker)
+
+	results, err := searcher.Search(context.Background(), "query", "/test/repo")
+	if err != nil {
+		t.Fatalf("Expected search to succeed without reranking, got %v", err)
+	}
+	if reranker.calls != 1 {
+		t.Errorf("Expected the reranker to be called once, got %d", reranker.calls)
+	}
+	if got := resultIDs(results); got != "1,2,3" {
+		t.Errorf("Expected hybrid order 1,2,3, got %s", got)
+	}
+}
+
+func TestSearch_DefaultIsNoopReranker(t *testing.T) {
+	searcher := newRerankTestSearcher(&config.SearchConfig{MaxResults: 3, SemanticWeight: 1})
+
+	results, err := searcher.Search(context.Background(), "query", "/test/repo")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if got := resultIDs(results); got != "1,2,3" {
+		t.Errorf("Expected hybrid order 1,2,3, got %s", got)
+	}
+}
+
+func TestNewReranker(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.SearchConfig
+		expectErr bool
+	}{
+		{"empty is noop", config.SearchConfig{}, false},
+		{"none is noop", config.SearchConfig{Reranker: RerankerNone}, false},
+		{"ollama with model", config.SearchConfig{Reranker: RerankerOllama, RerankModel: "llama3"}, false},
+		{"ollama without model", config.SearchConfig{Reranker: RerankerOllama}, true},
+		{"unknown reranker", config.SearchConfig{Reranker: "cohere"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReranker(&tt.cfg, "http://localhost:11434")
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error=%v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestOllamaReranker(t *testing.T) {
+	// The model rates candidates by the marker in their content
+	ratings := map[string]string{"weak": "2", "strong": "Relevance: 9/10", "garbled": "no idea"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req generateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/api/generate" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		for marker, rating := range ratings {
+			if strings.Contains(req.Prompt, "Code:\n"+marker) {
+				json.NewEncoder(w).Encode(generateResponse{Response: rating})
+				return
+			}
+		}
+		http.Error(w, "unexpected prompt", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	candidates := []SearchResult{
+		{Chunk: models.CodeChunk{ID: "a", Content: "weak"}},
+		{Chunk: models.CodeChunk{ID: "b", Content: "garbled"}},
+		{Chunk: models.CodeChunk{ID: "c", Content: "strong"}},
+	}
+
+	reranked, err := NewOllamaReranker(server.URL, "llama3").Rerank(context.Background(), "query", candidates)
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+	if got := resultIDs(reranked); got != "c,a,b" {
+		t.Errorf("Expected order c,a,b, got %s", got)
+	}
+	if abs(reranked[0].RerankScore-0.9) > 1e-9 {
+		t.Errorf("Expected rerank score 0.9, got %.3f", reranked[0].RerankScore)
+	}
+	if candidates[0].RerankScore != 0 {
+		t.Error("Expected the input candidates to be left untouched")
+	}
+}
+
+func TestParseRating(t *testing.T) {
+	tests := []struct {
+		response string
+		expected float64
+		ok       bool
+	}{
+		{"7", 7, true},
+		{" 8.5\n", 8.5, true},
+		{"Relevance: 3/10", 3, true},
+		{"42", 10, true},
+		{"none", 0, false},
+	}
+
+	for _, tt := range tests {
+		rating, ok := parseRating(tt.response)
+		if ok != tt.ok || abs(rating-tt.expected) > 1e-9 {
+			t.Errorf("parseRating(%q) = %.1f, %v; expected %.1f, %v", tt.response, rating, ok, tt.expected, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// Result cache defaults, used when the config leaves them unset
+const (
+	// DefaultResultCacheTTL is how long cached results are served
+	DefaultResultCacheTTL = 60 * time.Second
+	// DefaultResultCacheSize is how many searches are cached at most
+	DefaultResultCacheSize = 256
+)
+
+// ResultCache keeps recent search results in memory, so repeated identical searches skip
+// embedding the query and querying the vector database
+// Entries are keyed on the normalized query and the search's filter, options and repositories,
+// and record the index version of every repository searched. Invalidate bumps a repository's
+// version whenever its index changes, so stale results are never served even before they expire.
+type ResultCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu         sync.Mutex
+	entries    map[string]resultCacheEntry
+	versions   map[string]uint64 // Index version per repository, 0 until first invalidated
+	generation uint64            // Bumped by InvalidateAll
+}
+
+// cacheStamp is the state of the index a search ran against
+type cacheStamp struct {
+	generation uint64
+	versions   map[string]uint64 // Index versions of the repositories searched
+}
+
+type resultCacheEntry struct {
+	results []SearchResult
+	stamp   cacheStamp
+	expires time.Time
+}
+
+// NewResultCache creates a result cache serving entries for ttl, holding at most maxEntries
+// Zero values use DefaultResultCacheTTL and DefaultResultCacheSize.
+func NewResultCache(ttl time.Duration, maxEntries int) *ResultCache {
+	if ttl <= 0 {
+		ttl = DefaultResultCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultResultCacheSize
+	}
+	return &ResultCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]resultCacheEntry),
+		versions:   make(map[string]uint64),
+	}
+}
+
+// Version returns the repository's index version
+func (c *ResultCache) Version(repoPath string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.versions[repoPath]
+}
+
+// Invalidate bumps the repository's index version, so results cached for it are no longer served
+func (c *ResultCache) Invalidate(repoPath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.versions[repoPath]++
+	c.evictLocked()
+}
+
+// InvalidateAll drops every cached result, e.g. after an import that may touch any repository
+func (c *ResultCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries = make(map[string]resultCacheEntry)
+}
+
+// lookup returns the cache key of a search and the current state of the indexes it reads
+// Take the stamp before searching, so results of a search overlapping a reindex aren't cached.
+func (c *ResultCache) lookup(query string, filter models.SearchFilter, opts SearchOptions, repoPaths []string) (string, cacheStamp) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stamp := cacheStamp{generation: c.generation, versions: make(map[string]uint64, len(repoPaths))}
+	for _, repoPath := range repoPaths {
+		stamp.versions[repoPath] = c.versions[repoPath]
+	}
+	key := fmt.Sprintf("%q|%+v|%+v|%q", normalizeCacheQuery(query), filter, opts, repoPaths)
+	return key, stamp
+}
+
+// get returns a copy of the cached results for key, if present, current and not expired
+func (c *ResultCache) get(key string) ([]SearchResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.liveLocked(entry) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return append([]SearchResult(nil), entry.results...), true
+}
+
+// put caches a copy of results under key
+// Results of a search that overlapped an invalidation are dropped: their stamp is outdated.
+func (c *ResultCache) put(key string, stamp cacheStamp, results []SearchResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := resultCacheEntry{
+		results: append([]SearchResult(nil), results...),
+		stamp:   stamp,
+		expires: c.now().Add(c.ttl),
+	}
+	if !c.liveLocked(entry) {
+		return
+	}
+	if len(c.entries) >= c.maxEntries {
+		c.evictLocked()
+	}
+	if len(c.entries) >= c.maxEntries {
+		// Still full of live entries: drop the one closest to expiring
+		var oldest string
+		for k, e := range c.entries {
+			if oldest == "" || e.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	c.entries[key] = entry
+}
+
+// evictLocked drops expired entries and entries of outdated index versions
+func (c *ResultCache) evictLocked() {
+	for k, entry := range c.entries {
+		if !c.liveLocked(entry) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// liveLocked reports whether an entry hasn't expired and its index versions are current
+func (c *ResultCache) liveLocked(entry resultCacheEntry) bool {
+	if c.now().After(entry.expires) || entry.stamp.generation != c.generation {
+		return false
+	}
+	for repoPath, version := range entry.stamp.versions {
+		if c.versions[repoPath] != version {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeCacheQuery collapses whitespace, so queries differing only in spacing share an entry
+func normalizeCacheQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
@@ -0,0 +1,172 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+func newCachedTestSearcher() (*Searcher, *mockEmbeddingsClient, *mockVectorDB, *ResultCache) {
+	embedder := &mockEmbeddingsClient{embeddings: []float32{0.1}}
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "a", Content: "func login()", FilePath: "/test/repo/a.go", StartLine: 1, EndLine: 5},
+			{ID: "b", Content: "func logout()", FilePath: "/test/repo/b.go", StartLine: 1, EndLine: 5},
+		},
+		scores: []float64{0.9, 0.8},
+	}
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, embedder, mockDB)
+	cache := NewResultCache(time.Minute, 10)
+	searcher.SetResultCache(cache)
+	return searcher, embedder, mockDB, cache
+}
+
+func TestResultCache_HitSkipsEmbeddingAndQuery(t *testing.T) {
+	searcher, embedder, mockDB, _ := newCachedTestSearcher()
+	filter := models.SearchFilter{RepoPath: "/test/repo"}
+
+	first, err := searcher.SearchFiltered(context.Background(), "login", filter, SearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+	// Differs only in spacing
+	second, err := searcher.SearchFiltered(context.Background(), "  login ", filter, SearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+
+	if len(embedder.texts) != 1 || mockDB.searchCalls != 1 {
+		t.Errorf("Expected one embedding and one query, got %d and %d", len(embedder.texts), mockDB.searchCalls)
+	}
+	if len(second) != len(first) || second[0].Chunk.ID != first[0].Chunk.ID {
+		t.Errorf("Expected the cached results, got %+v", second)
+	}
+
+	// Other options, filters or queries are searched
+	if _, err := searcher.SearchFiltered(context.Background(), "login", filter, SearchOptions{UniqueFiles: true}); err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+	if _, err := searcher.SearchFiltered(context.Background(), "logout", filter, SearchOptions{}); err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+	if mockDB.searchCalls != 3 {
+		t.Errorf("Expected distinct searches to miss the cache, got %d queries", mockDB.searchCalls)
+	}
+}
+
+func TestResultCache_InvalidateBustsCache(t *testing.T) {
+	searcher, _, mockDB, cache := newCachedTestSearcher()
+	filter := models.SearchFilter{RepoPath: "/test/repo"}
+
+	search := func() {
+		t.Helper()
+		if _, err := searcher.SearchFiltered(context.Background(), "login", filter, SearchOptions{}); err != nil {
+			t.Fatalf("SearchFiltered failed: %v", err)
+		}
+	}
+	search()
+	search()
+	if mockDB.searchCalls != 1 {
+		t.Fatalf("Expected the second search to be cached, got %d queries", mockDB.searchCalls)
+	}
+
+	// Reindexing another repository keeps the cached results
+	cache.Invalidate("/other/repo")
+	search()
+	if mockDB.searchCalls != 1 {
+		t.Errorf("Expected results to stay cached, got %d queries", mockDB.searchCalls)
+	}
+
+	cache.Invalidate("/test/repo")
+	if cache.Version("/test/repo") != 1 {
+		t.Errorf("Expected index version 1, got %d", cache.Version("/test/repo"))
+	}
+	search()
+	if mockDB.searchCalls != 2 {
+		t.Errorf("Expected reindexing to bust the cache, got %d queries", mockDB.searchCalls)
+	}
+
+	cache.InvalidateAll()
+	search()
+	if mockDB.searchCalls != 3 {
+		t.Errorf("Expected InvalidateAll to bust the cache, got %d queries", mockDB.searchCalls)
+	}
+}
+
+func TestResultCache_Expiry(t *testing.T) {
+	searcher, _, mockDB, cache := newCachedTestSearcher()
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	filter := models.SearchFilter{RepoPath: "/test/repo"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := searcher.SearchFiltered(context.Background(), "login", filter, SearchOptions{}); err != nil {
+			t.Fatalf("SearchFiltered failed: %v", err)
+		}
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := searcher.SearchFiltered(context.Background(), "login", filter, SearchOptions{}); err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+	if mockDB.searchCalls != 2 {
+		t.Errorf("Expected expired results to be searched again, got %d queries", mockDB.searchCalls)
+	}
+}
+
+func TestResultCache_SearchRepos(t *testing.T) {
+	searcher, embedder, mockDB, cache := newCachedTestSearcher()
+	repos := []string{"/test/repo", "/other/repo"}
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := searcher.SearchRepos(context.Background(), "login", repos, SearchOptions{}); err != nil {
+			t.Fatalf("SearchRepos failed: %v", err)
+		}
+	}
+	if len(embedder.texts) != 1 || mockDB.searchCalls != 2 {
+		t.Errorf("Expected the repeated search to be cached, got %d embeddings and %d queries", len(embedder.texts), mockDB.searchCalls)
+	}
+
+	// Reindexing any of the repositories busts the cache
+	cache.Invalidate("/other/repo")
+	if _, _, err := searcher.SearchRepos(context.Background(), "login", repos, SearchOptions{}); err != nil {
+		t.Fatalf("SearchRepos failed: %v", err)
+	}
+	if mockDB.searchCalls != 4 {
+		t.Errorf("Expected a reindexed repository to bust the cache, got %d queries", mockDB.searchCalls)
+	}
+
+	// Partial results aren't cached
+	mockDB.repoErrs = map[string]error{"/other/repo": errors.New("unavailable")}
+	for i := 0; i < 2; i++ {
+		if _, failures, err := searcher.SearchRepos(context.Background(), "logout", repos, SearchOptions{}); err != nil || len(failures) != 1 {
+			t.Fatalf("Expected one failed repository, got %v (%v)", failures, err)
+		}
+	}
+	// Each search queries both repositories
+	if mockDB.searchCalls != 8 {
+		t.Errorf("Expected partial results to be searched again, got %d queries", mockDB.searchCalls)
+	}
+}
+
+func TestResultCache_MaxEntries(t *testing.T) {
+	cache := NewResultCache(time.Minute, 2)
+	for _, query := range []string{"a", "b", "c"} {
+		key, stamp := cache.lookup(query, models.SearchFilter{}, SearchOptions{}, nil)
+		cache.put(key, stamp, nil)
+	}
+	if len(cache.entries) != 2 {
+		t.Errorf("Expected at most 2 entries, got %d", len(cache.entries))
+	}
+
+	// Results of a search overlapping a reindex aren't cached
+	key, stamp := cache.lookup("d", models.SearchFilter{RepoPath: "/repo"}, SearchOptions{}, []string{"/repo"})
+	cache.Invalidate("/repo")
+	cache.put(key, stamp, nil)
+	if _, ok := cache.get(key); ok {
+		t.Error("Expected results from before the reindex not to be cached")
+	}
+}
@@ -2,13 +2,49 @@ package search
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"path"
+	"path/filepath"
 	"sort"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/jamaly87/codebase-semantic-search/internal/models"
 	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+	"github.com/jamaly87/codebase-semantic-search/pkg/ignore"
+)
+
+// Candidate over-fetch defaults, used when the config leaves them unset
+const (
+	// DefaultRerankCandidateMultiplier is how many candidates to fetch per requested result
+	DefaultRerankCandidateMultiplier = 3
+	// DefaultMaxCandidates is the hard cap on candidates fetched for reranking
+	DefaultMaxCandidates = 100
+)
+
+
+// partialMatchWeight is the maximum boost for chunks matching only part of the query
+const partialMatchWeight = 0.3
+
+// Scoring modes for combining semantic and lexical scores
+const (
+	// ScoringModeAdditive adds match boosts to the weighted semantic score (unbounded)
+	ScoringModeAdditive = "additive"
+	// ScoringModeNormalized averages semantic and lexical scores by weights summing to 1 (within [0,1])
+	ScoringModeNormalized = "normalized"
+)
+
+const (
+	// normalizedPartialMatchScore is the lexical score of a chunk matching every query word
+	// without an exact match, relative to 1 for an exact match
+	normalizedPartialMatchScore = 0.5
+	// maxFilePathScore is the strongest file path boost, see calculateFilePathScore
+	maxFilePathScore = 1.3
 )
 
 // EmbeddingsClient interface for generating embeddings
@@ -16,9 +52,20 @@ type EmbeddingsClient interface {
 	GenerateEmbedding(text string) ([]float32, error)
 }
 
+// contextEmbeddingsClient is an EmbeddingsClient whose requests stop when their context is done
+type contextEmbeddingsClient interface {
+	GenerateEmbeddingContext(ctx context.Context, text string) ([]float32, error)
+}
+
+// ErrQueryTimeout is returned when a search takes longer than search.query_timeout_ms
+var ErrQueryTimeout = errors.New("search timed out")
+
 // VectorDB interface for vector database operations
 type VectorDB interface {
-	Search(ctx context.Context, embedding []float32, repoPath string, limit int) ([]models.CodeChunk, []float64, error)
+	Search(ctx context.Context, embedding []float32, filter models.SearchFilter, limit int, minScore float64) ([]models.CodeChunk, []float64, error)
+	FindSymbols(ctx context.Context, repoPath, name string, exact bool, limit int) ([]models.CodeChunk, error)
+	ScrollSymbols(ctx context.Context, repoPath string, visit func(chunk models.CodeChunk) bool) error
+	FindChunkAt(ctx context.Context, repoPath, filePath string, line int) (*models.CodeChunk, error)
 }
 
 // SearchResult represents a search result with scoring information
@@ -28,6 +75,11 @@ type SearchResult struct {
 	ExactMatch     bool
 	HybridScore    float64
 	MatchPositions []int
+	RerankScore    float64 // Relevance from the reranker (0-1), zero when not reranked
+	// Chunks immediately before and after this one in its file, with SearchOptions.IncludeAdjacent
+	// (nil at the start or end of the file)
+	Before *models.CodeChunk
+	After  *models.CodeChunk
 }
 
 // Searcher handles semantic search operations
@@ -35,6 +87,12 @@ type Searcher struct {
 	config           *config.SearchConfig
 	embeddingsClient EmbeddingsClient
 	vectorDB         VectorDB
+	reranker         Reranker
+	rerankerURL      string         // Ollama URL for rerankers selected by repository settings
+	queryPrefix      string         // Task prefix for query embeddings
+	documentPrefix   string         // Task prefix for documents embedded at search time
+	feedback         *FeedbackStore // Relevance feedback, nil when disabled
+	resultCache      *ResultCache   // Recent results, nil when disabled
 }
 
 // NewSearcher creates a new search service
@@ -43,29 +101,324 @@ func NewSearcher(cfg *config.SearchConfig, embeddingsClient EmbeddingsClient, ve
 		config:           cfg,
 		embeddingsClient: embeddingsClient,
 		vectorDB:         vectorDB,
+		reranker:         NoopReranker{},
 	}
 }
 
+// SetReranker sets the reranker applied after hybrid scoring
+func (s *Searcher) SetReranker(reranker Reranker) {
+	s.reranker = reranker
+}
+
+// SetRerankerURL sets the Ollama URL of rerankers that repository settings select
+// instead of the global one
+func (s *Searcher) SetRerankerURL(url string) {
+	s.rerankerURL = url
+}
+
+// SetTaskPrefixes sets the prefixes prepended to queries and to documents embedded at search time
+func (s *Searcher) SetTaskPrefixes(query, document string) {
+	s.queryPrefix = query
+	s.documentPrefix = document
+}
+
+// SetFeedback sets the relevance feedback store whose adjustments apply when search.feedback is enabled
+func (s *Searcher) SetFeedback(feedback *FeedbackStore) {
+	s.feedback = feedback
+}
+
+// SetResultCache sets the cache serving repeated identical searches
+func (s *Searcher) SetResultCache(cache *ResultCache) {
+	s.resultCache = cache
+}
+
+// forRepo returns a searcher using the repository's search settings
+// The repository's .semantic-search.yaml, if any, is merged over the global settings.
+func (s *Searcher) forRepo(repoPath string) (*Searcher, error) {
+	cfg, err := s.config.ForRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == s.config {
+		return s, nil
+	}
+
+	// The repository may turn reranking off, or pick another reranker
+	reranker := s.reranker
+	if cfg.Reranker != s.config.Reranker || cfg.RerankModel != s.config.RerankModel {
+		if reranker, err = NewReranker(cfg, s.rerankerURL); err != nil {
+			return nil, fmt.Errorf("invalid reranker for %s: %w", repoPath, err)
+		}
+	}
+
+	return &Searcher{
+		config:           cfg,
+		embeddingsClient: s.embeddingsClient,
+		vectorDB:         s.vectorDB,
+		reranker:         reranker,
+		rerankerURL:      s.rerankerURL,
+		queryPrefix:      s.queryPrefix,
+		documentPrefix:   s.documentPrefix,
+		feedback:         s.feedback,
+		resultCache:      s.resultCache,
+	}, nil
+}
+
+// SearchOptions shapes the results of a search
+type SearchOptions struct {
+	// UniqueFiles keeps only the best-scoring chunk of each file, so results are distinct files
+	UniqueFiles bool
+	// ExcludePaths drops chunks of files matching these patterns (ignore_patterns syntax, relative
+	// to the repository), or under a directory matching one. Applied to the over-fetched
+	// candidates before ranking, so results only fall short of max_results when excluded files
+	// make up most of the candidates.
+	ExcludePaths []string
+	// SortBy orders the returned results: SortByScore (or empty) or SortByLocation. Results are
+	// always selected by score; the order only changes how they are presented.
+	SortBy string
+	// IncludeAdjacent attaches to each returned result the chunks before and after it in its
+	// file, so it can be read with its surroundings. Costs two lookups per result.
+	IncludeAdjacent bool
+
+	// resultsFactor multiplies the results returned per repository (1 when zero), so MultiSearch
+	// can fuse deeper rankings than the max_results it returns
+	resultsFactor int
+}
+
+// Result orders of SearchOptions.SortBy
+const (
+	SortByScore    = "score"    // Best-scoring first
+	SortByLocation = "location" // By file path, then start line, for reading a file top to bottom
+)
+
 // Search performs a semantic search with hybrid scoring
 func (s *Searcher) Search(ctx context.Context, query string, repoPath string) ([]SearchResult, error) {
-	log.Printf("Searching for: %q in repo: %s", query, repoPath)
+	return s.SearchFiltered(ctx, query, models.SearchFilter{RepoPath: repoPath}, SearchOptions{})
+}
+
+// SearchFiltered performs a semantic search with hybrid scoring over the chunks matching filter,
+// e.g. the chunks of a single file
+func (s *Searcher) SearchFiltered(ctx context.Context, query string, filter models.SearchFilter, opts SearchOptions) ([]SearchResult, error) {
+	slog.Info("Searching", "query", query, "repo", filter.RepoPath, "file", filter.FilePath)
+
+	parent := ctx
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	var cacheKey string
+	var stamp cacheStamp
+	if s.resultCache != nil {
+		cacheKey, stamp = s.resultCache.lookup(query, filter, opts, []string{filter.RepoPath})
+		if results, ok := s.resultCache.get(cacheKey); ok {
+			slog.Info("Returning cached results", "count", len(results))
+			return results, nil
+		}
+	}
 
 	// Generate embedding for query
-	queryEmbedding, err := s.embeddingsClient.GenerateEmbedding(query)
+	queryEmbedding, err := s.embedQuery(ctx, query)
+	if err != nil {
+		return nil, s.timeoutError(parent, ctx, fmt.Errorf("failed to generate query embedding: %w", err))
+	}
+
+	results, err := s.searchRepo(ctx, query, queryEmbedding, filter, opts)
+	if err != nil {
+		return nil, s.timeoutError(parent, ctx, err)
+	}
+	sortResults(results, opts.SortBy)
+	if opts.IncludeAdjacent {
+		s.attachAdjacent(ctx, results)
+	}
+	if s.resultCache != nil {
+		s.resultCache.put(cacheKey, stamp, results)
+	}
+	return results, nil
+}
+
+// withQueryTimeout bounds a search by search.query_timeout_ms
+func (s *Searcher) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.config.QueryTimeoutMs <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(s.config.QueryTimeoutMs)*time.Millisecond)
+}
+
+// timeoutError reports err as ErrQueryTimeout when the search's own deadline passed, rather
+// than the caller's context ending
+func (s *Searcher) timeoutError(parent, ctx context.Context, err error) error {
+	if parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %dms (search.query_timeout_ms); the embedding service or vector database may be overloaded: %v",
+			ErrQueryTimeout, s.config.QueryTimeoutMs, err)
+	}
+	return err
+}
+
+// embedQuery embeds a search query, giving up when ctx is done
+// Clients that can't be cancelled are left to finish in the background.
+func (s *Searcher) embedQuery(ctx context.Context, query string) ([]float32, error) {
+	text := s.queryPrefix + query
+	if client, ok := s.embeddingsClient.(contextEmbeddingsClient); ok {
+		return client.GenerateEmbeddingContext(ctx, text)
+	}
+
+	type embedded struct {
+		embedding []float32
+		err       error
+	}
+	done := make(chan embedded, 1)
+	go func() {
+		embedding, err := s.embeddingsClient.GenerateEmbedding(text)
+		done <- embedded{embedding, err}
+	}()
+	select {
+	case result := <-done:
+		return result.embedding, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// RepoSearchError records a repository whose search failed in a multi-repo search
+type RepoSearchError struct {
+	RepoPath string
+	Err      error
+}
+
+func (e RepoSearchError) Error() string {
+	return fmt.Sprintf("%s: %v", e.RepoPath, e.Err)
+}
+
+// SearchRepos searches several repositories and merges the results by hybrid score
+// A repository whose search fails is reported in the returned errors and the results
+// of the others are still returned; the search only fails if every repository failed.
+func (s *Searcher) SearchRepos(ctx context.Context, query string, repoPaths []string, opts SearchOptions) ([]SearchResult, []RepoSearchError, error) {
+	if len(repoPaths) == 0 {
+		return nil, nil, fmt.Errorf("no repositories to search")
+	}
+
+	slog.Info("Searching repositories", "query", query, "repos", len(repoPaths))
+
+	parent := ctx
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	var cacheKey string
+	var stamp cacheStamp
+	if s.resultCache != nil {
+		cacheKey, stamp = s.resultCache.lookup(query, models.SearchFilter{}, opts, repoPaths)
+		if results, ok := s.resultCache.get(cacheKey); ok {
+			slog.Info("Returning cached results", "count", len(results))
+			return results, nil, nil
+		}
+	}
+
+	// The query embedding is shared by all repositories
+	queryEmbedding, err := s.embedQuery(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+		return nil, nil, s.timeoutError(parent, ctx, fmt.Errorf("failed to generate query embedding: %w", err))
+	}
+
+	var perRepo [][]SearchResult
+	var failures []RepoSearchError
+	for _, repoPath := range repoPaths {
+		results, err := s.searchRepo(ctx, query, queryEmbedding, models.SearchFilter{RepoPath: repoPath}, opts)
+		if err != nil {
+			slog.Warn("Repository search failed, continuing with the others", "repo", repoPath, "error", err)
+			failures = append(failures, RepoSearchError{RepoPath: repoPath, Err: err})
+			continue
+		}
+		perRepo = append(perRepo, results)
+	}
+
+	if len(failures) == len(repoPaths) {
+		err := fmt.Errorf("search failed in all %d repositories: %w", len(repoPaths), failures[0].Err)
+		return nil, failures, s.timeoutError(parent, ctx, err)
+	}
+
+	merged := mergeRepoResults(perRepo)
+	if len(merged) > s.config.MaxResults {
+		merged = merged[:s.config.MaxResults]
+	}
+	sortResults(merged, opts.SortBy)
+	if opts.IncludeAdjacent {
+		s.attachAdjacent(ctx, merged)
+	}
+
+	// Partial results aren't cached, so a failed repository is retried on the next search
+	if s.resultCache != nil && len(failures) == 0 {
+		s.resultCache.put(cacheKey, stamp, merged)
+	}
+	return merged, failures, nil
+}
+
+// mergeRepoResults merges the results of several repositories, keeping each one's own order
+// When every repository's results were reranked, they are merged by rerank score, which rates
+// relevance on one scale. Otherwise they are merged by hybrid score relative to each repository's
+// best, as repositories scored with their own settings don't share a scale.
+func mergeRepoResults(perRepo [][]SearchResult) []SearchResult {
+	reranked := true
+	for _, results := range perRepo {
+		if len(results) > 0 && results[0].RerankScore == 0 {
+			reranked = false
+		}
+	}
+
+	keys := make([][]float64, len(perRepo))
+	for i, results := range perRepo {
+		best := 0.0
+		for _, result := range results {
+			best = max(best, result.HybridScore)
+		}
+		keys[i] = make([]float64, len(results))
+		for j, result := range results {
+			switch {
+			case reranked:
+				keys[i][j] = result.RerankScore
+			case best > 0:
+				keys[i][j] = result.HybridScore / best
+			}
+		}
+	}
+
+	// Repeatedly take the best next result of any repository; ties go to the earlier repository
+	next := make([]int, len(perRepo))
+	var merged []SearchResult
+	for {
+		pick := -1
+		for i := range perRepo {
+			if next[i] < len(perRepo[i]) && (pick < 0 || keys[i][next[i]] > keys[pick][next[pick]]) {
+				pick = i
+			}
+		}
+		if pick < 0 {
+			return merged
+		}
+		merged = append(merged, perRepo[pick][next[pick]])
+		next[pick]++
+	}
+}
+
+// searchRepo runs the vector search, hybrid scoring and reranking for one repository
+func (s *Searcher) searchRepo(ctx context.Context, query string, queryEmbedding []float32, filter models.SearchFilter, opts SearchOptions) ([]SearchResult, error) {
+	s, err := s.forRepo(filter.RepoPath)
+	if err != nil {
+		return nil, err
 	}
 
 	// Search vector database
 	// Request more results than needed to allow for reranking
-	searchLimit := s.config.MaxResults * 3
-	chunks, semanticScores, err := s.vectorDB.Search(ctx, queryEmbedding, repoPath, searchLimit)
+	searchLimit := s.candidateLimit()
+	chunks, semanticScores, err := s.searchVectors(ctx, queryEmbedding, filter, searchLimit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search vector database: %w", err)
 	}
 
+	if len(opts.ExcludePaths) > 0 {
+		chunks, semanticScores = excludePaths(ignore.NewMatcher(opts.ExcludePaths), chunks, semanticScores)
+	}
+
 	if len(chunks) == 0 {
-		log.Printf("No results found for query: %q", query)
+		slog.Info("No results found", "query", query, "repo", filter.RepoPath)
 		return []SearchResult{}, nil
 	}
 
@@ -77,20 +430,140 @@ func (s *Searcher) Search(ctx context.Context, query string, repoPath string) ([
 		return results[i].HybridScore > results[j].HybridScore
 	})
 
+	results = s.rerank(ctx, query, results)
+	if opts.UniqueFiles {
+		results = uniqueFiles(results)
+	} else if s.config.MaxResultsPerFile > 0 {
+		results = capPerFile(results, s.config.MaxResultsPerFile)
+	}
+
 	// Limit to max results
-	if len(results) > s.config.MaxResults {
-		results = results[:s.config.MaxResults]
+	if maxResults := s.config.MaxResults * max(opts.resultsFactor, 1); len(results) > maxResults {
+		results = results[:maxResults]
 	}
 
-	log.Printf("Returning %d results (top score: %.3f)", len(results), results[0].HybridScore)
+	slog.Info("Returning results", "count", len(results), "top_score", results[0].HybridScore)
 	return results, nil
 }
 
+// sortResults orders results, already ranked by score, for presentation
+// SortByLocation sorts by file path, then start and end line; other values keep the ranking.
+func sortResults(results []SearchResult, sortBy string) {
+	if sortBy != SortByLocation {
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].Chunk, results[j].Chunk
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return a.EndLine < b.EndLine
+	})
+}
+
+// uniqueFiles keeps the first, best-ranked, result of each file
+func uniqueFiles(results []SearchResult) []SearchResult {
+	return capPerFile(results, 1)
+}
+
+// capPerFile keeps the first, best-ranked, maxPerFile results of each file, so lower-ranked
+// results from other files move up
+func capPerFile(results []SearchResult, maxPerFile int) []SearchResult {
+	counts := make(map[string]int, len(results))
+	capped := results[:0]
+	for _, result := range results {
+		if counts[result.Chunk.FilePath] < maxPerFile {
+			counts[result.Chunk.FilePath]++
+			capped = append(capped, result)
+		}
+	}
+	return capped
+}
+
+// excludePaths drops the chunks whose repository-relative path, or one of its parent
+// directories, matches the matcher, with their scores
+func excludePaths(matcher *ignore.Matcher, chunks []models.CodeChunk, scores []float64) ([]models.CodeChunk, []float64) {
+	keptChunks := make([]models.CodeChunk, 0, len(chunks))
+	keptScores := make([]float64, 0, len(scores))
+	for i, chunk := range chunks {
+		if pathExcluded(matcher, relativePath(chunk.RepoPath, chunk.FilePath)) {
+			continue
+		}
+		keptChunks = append(keptChunks, chunk)
+		keptScores = append(keptScores, scores[i])
+	}
+	return keptChunks, keptScores
+}
+
+// pathExcluded reports whether a relative path or one of its parent directories matches,
+// as the scanner skips everything under an ignored directory
+func pathExcluded(matcher *ignore.Matcher, relPath string) bool {
+	for p := filepath.ToSlash(relPath); p != "." && p != "/"; p = path.Dir(p) {
+		if matcher.ShouldIgnore(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchVectors queries the vector database for chunks above the minimum semantic score
+// Transient errors are already retried by the vector database client.
+func (s *Searcher) searchVectors(ctx context.Context, embedding []float32, filter models.SearchFilter, limit int) ([]models.CodeChunk, []float64, error) {
+	return s.vectorDB.Search(ctx, embedding, filter, limit, s.config.MinSemanticScore)
+}
+
+func (s *Searcher) rerank(ctx context.Context, query string, results []SearchResult) []SearchResult {
+	if s.reranker == nil || len(results) == 0 {
+		return results
+	}
+
+	topK := s.config.RerankTopK
+	if topK <= 0 {
+		topK = DefaultRerankTopK
+	}
+	if topK > len(results) {
+		topK = len(results)
+	}
+
+	reranked, err := s.reranker.Rerank(ctx, query, results[:topK])
+	if err != nil {
+		slog.Warn("Reranking failed, keeping hybrid order", "error", err)
+		return results
+	}
+	return append(reranked, results[topK:]...)
+}
+
+// candidateLimit returns how many candidates to fetch from the vector database for reranking
+// Over-fetching lets hybrid scoring promote exact matches that rank lower semantically,
+// and is bounded by MaxCandidates; the cap never cuts the fetch below MaxResults, though.
+func (s *Searcher) candidateLimit() int {
+	multiplier := s.config.RerankCandidateMultiplier
+	if multiplier <= 0 {
+		multiplier = DefaultRerankCandidateMultiplier
+	}
+	maxCandidates := s.config.MaxCandidates
+	if maxCandidates <= 0 {
+		maxCandidates = DefaultMaxCandidates
+	}
+
+	limit := min(s.config.MaxResults*multiplier, maxCandidates)
+	return max(limit, s.config.MaxResults)
+}
+
 // applyHybridScoring applies hybrid scoring: semantic similarity + exact match boost + file path scoring
+// In normalized mode the result is a weighted average of semantic and lexical scores, kept within [0,1];
+// in additive mode (the original scheme) match boosts are added to the weighted semantic score.
 func (s *Searcher) applyHybridScoring(query string, chunks []models.CodeChunk, semanticScores []float64) []SearchResult {
 	results := make([]SearchResult, len(chunks))
 	queryLower := strings.ToLower(query)
 	queryWords := strings.Fields(queryLower)
+	parsed := parseQuery(query)
+	normalized := s.config.ScoringMode == ScoringModeNormalized
+	semanticWeight, lexicalWeight := s.scoringWeights()
+	maxTypeWeight := s.maxChunkTypeWeight()
 
 	for i, chunk := range chunks {
 		result := SearchResult{
@@ -100,45 +573,55 @@ func (s *Searcher) applyHybridScoring(query string, chunks []models.CodeChunk, s
 			HybridScore:   0,
 		}
 
-		// Start with semantic score (scaled by weight)
-		hybridScore := semanticScores[i] * s.config.SemanticWeight
-
-		// Check for exact match (case-insensitive)
-		contentLower := strings.ToLower(chunk.Content)
-		if strings.Contains(contentLower, queryLower) {
-			result.ExactMatch = true
-			result.MatchPositions = findMatchPositions(contentLower, queryLower)
+		// Check for exact or partial keyword matches (case-insensitive)
+		match := s.matchLexical(strings.ToLower(chunk.Content), queryLower, queryWords, parsed)
+		result.ExactMatch = match.exact
+		result.MatchPositions = match.positions
 
-			// ADDITIVE boost for exact match (not multiplicative)
-			hybridScore += s.config.ExactMatchBoost
-			log.Printf("Exact match found in %s:%d-%d (score: %.3f + %.3f = %.3f)",
-				chunk.FilePath, chunk.StartLine, chunk.EndLine,
-				semanticScores[i]*s.config.SemanticWeight, s.config.ExactMatchBoost, hybridScore)
+		var hybridScore float64
+		if normalized {
+			hybridScore = semanticWeight*clampUnit(semanticScores[i]) + lexicalWeight*match.score()
 		} else {
-			// Partial word matching - score based on matched query words
-			matchedWords := 0
-			for _, word := range queryWords {
-				if len(word) > 2 && strings.Contains(contentLower, word) {
-					matchedWords++
-				}
+			// ADDITIVE boost for exact match (not multiplicative)
+			boost := match.boost(s.config.ExactMatchBoost)
+			if match.exact && semanticScores[i] < s.config.ExactMatchMinSemantic {
+				// A substring match in an otherwise unrelated chunk is likely coincidental
+				boost = 0
 			}
+			hybridScore = semanticScores[i]*s.config.SemanticWeight + boost
+		}
 
-			if matchedWords > 0 && len(queryWords) > 0 {
-				partialMatchBoost := (float64(matchedWords) / float64(len(queryWords))) * 0.3
-				hybridScore += partialMatchBoost
-				log.Printf("Partial match in %s:%d-%d (%d/%d words matched, boost: +%.3f)",
-					chunk.FilePath, chunk.StartLine, chunk.EndLine,
-					matchedWords, len(queryWords), partialMatchBoost)
-			}
+		if match.exact || match.fraction > 0 {
+			slog.Debug("Keyword match", "file", chunk.FilePath, "start", chunk.StartLine, "end", chunk.EndLine,
+				"exact", match.exact, "matched", match.fraction, "score", hybridScore)
 		}
 
 		// File path scoring: penalize test files, boost source files
-		pathScore := calculateFilePathScore(chunk.FilePath)
+		pathScore := chunkFilePathScore(chunk)
+		if normalized {
+			// Rescale so the strongest boost maps to 1 and scores stay within [0,1]
+			pathScore /= maxFilePathScore
+		}
 		hybridScore *= pathScore
 
 		if pathScore != 1.0 {
-			log.Printf("File path adjustment for %s: %.2fx (score: %.3f -> %.3f)",
-				chunk.FilePath, pathScore, hybridScore/pathScore, hybridScore)
+			slog.Debug("File path adjustment", "file", chunk.FilePath, "multiplier", pathScore,
+				"from", hybridScore/pathScore, "to", hybridScore)
+		}
+
+		// Chunk type weighting: precise function matches outrank diffuse whole-file chunks
+		typeWeight := s.chunkTypeWeight(chunk.ChunkType)
+		if normalized {
+			typeWeight /= maxTypeWeight
+		}
+		hybridScore *= typeWeight
+
+		// Relevance feedback: bounded nudges learned from votes on similar results
+		if s.feedback != nil && s.config.Feedback {
+			hybridScore *= s.feedback.Adjustment(chunk.RepoPath, chunk.FilePath, chunk.ChunkType)
+			if normalized {
+				hybridScore = math.Min(hybridScore, 1)
+			}
 		}
 
 		result.HybridScore = hybridScore
@@ -148,6 +631,94 @@ func (s *Searcher) applyHybridScoring(query string, chunks []models.CodeChunk, s
 	return results
 }
 
+// lexicalMatch describes how well a chunk's text matches the query keywords
+type lexicalMatch struct {
+	exact     bool
+	positions []int
+	fraction  float64 // Share of the query matched when there is no exact match (0-1)
+}
+
+// score returns the lexical component for normalized scoring, within [0,1]
+func (m lexicalMatch) score() float64 {
+	if m.exact {
+		return 1
+	}
+	return m.fraction * normalizedPartialMatchScore
+}
+
+// boost returns the lexical boost for additive scoring
+func (m lexicalMatch) boost(exactMatchBoost float64) float64 {
+	if m.exact {
+		return exactMatchBoost
+	}
+	return m.fraction * partialMatchWeight
+}
+
+// matchLexical finds exact and partial keyword matches of the query in lowercased content
+func (s *Searcher) matchLexical(contentLower, queryLower string, queryWords []string, parsed parsedQuery) lexicalMatch {
+	if !parsed.plain {
+		// Quoted phrases and AND/OR operators: every group must match for an exact match
+		exact, positions, fraction := s.operatorMatch(contentLower, parsed)
+		if exact {
+			return lexicalMatch{exact: true, positions: positions}
+		}
+		return lexicalMatch{fraction: fraction}
+	}
+
+	if positions := s.matchPositions(contentLower, queryLower); len(positions) > 0 {
+		return lexicalMatch{exact: true, positions: positions}
+	}
+
+	// Partial word matching - score based on matched query words
+	if len(queryWords) == 0 {
+		return lexicalMatch{}
+	}
+	matchedWords := 0
+	for _, word := range queryWords {
+		if len(word) > 2 && len(s.matchPositions(contentLower, word)) > 0 {
+			matchedWords++
+		}
+	}
+	return lexicalMatch{fraction: float64(matchedWords) / float64(len(queryWords))}
+}
+
+// scoringWeights returns the semantic and lexical weights for normalized scoring, summing to 1
+// LexicalWeight defaults to 1 - SemanticWeight; weights that don't sum to 1 are rescaled.
+func (s *Searcher) scoringWeights() (float64, float64) {
+	semantic := math.Max(s.config.SemanticWeight, 0)
+	lexical := s.config.LexicalWeight
+	if lexical <= 0 {
+		lexical = math.Max(1-semantic, 0)
+	}
+
+	total := semantic + lexical
+	return semantic / total, lexical / total
+}
+
+// chunkTypeWeight returns the score multiplier for a chunk type from ChunkTypeWeights
+// Types without a positive configured weight are not adjusted.
+func (s *Searcher) chunkTypeWeight(chunkType models.ChunkType) float64 {
+	if weight := s.config.ChunkTypeWeights[string(chunkType)]; weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// maxChunkTypeWeight returns the strongest chunk type boost, at least 1, so normalized
+// scoring can rescale type weights into [0,1]
+func (s *Searcher) maxChunkTypeWeight() float64 {
+	maxWeight := 1.0
+	for _, weight := range s.config.ChunkTypeWeights {
+		maxWeight = math.Max(maxWeight, weight)
+	}
+	return maxWeight
+}
+
+// clampUnit limits a score to [0,1]
+func clampUnit(score float64) float64 {
+	return math.Min(math.Max(score, 0), 1)
+}
+
 // calculateFilePathScore returns a multiplier based on file path characteristics
 // Penalizes test files, boosts main source files
 func calculateFilePathScore(filePath string) float64 {
@@ -165,13 +736,26 @@ func calculateFilePathScore(filePath string) float64 {
 
 	// Heavy penalty for generated/vendor code (0.2x)
 	if isGeneratedOrVendor(pathLower) {
-		return 0.2
+		return generatedFileScore
 	}
 
 	// Neutral for other files
 	return 1.0
 }
 
+// generatedFileScore is the multiplier of generated and vendored code
+const generatedFileScore = 0.2
+
+// chunkFilePathScore is calculateFilePathScore, also penalizing chunks of files whose header
+// marks them generated (models.MetadataGenerated) whatever their path
+func chunkFilePathScore(chunk models.CodeChunk) float64 {
+	score := calculateFilePathScore(chunk.FilePath)
+	if generated, _ := chunk.Metadata[models.MetadataGenerated].(bool); generated {
+		return math.Min(score, generatedFileScore)
+	}
+	return score
+}
+
 // isTestFile detects test files by common patterns
 func isTestFile(pathLower string) bool {
 	// Directory-based detection
@@ -223,6 +807,54 @@ func isGeneratedOrVendor(pathLower string) bool {
 		strings.Contains(pathLower, "_generated.")
 }
 
+// matchPositions finds the positions of query in content, honoring WholeWordMatch
+func (s *Searcher) matchPositions(content, query string) []int {
+	if s.config.WholeWordMatch {
+		return findWholeWordPositions(content, query)
+	}
+	return findMatchPositions(content, query)
+}
+
+// findWholeWordPositions finds positions where the query appears as a whole word
+// A match counts only if it is not directly preceded or followed by a word character,
+// so "log" matches "log.info" but not "catalog" or "logger".
+func findWholeWordPositions(content, query string) []int {
+	var positions []int
+	for _, pos := range findMatchPositions(content, query) {
+		end := pos + len(query)
+		if startsWord(content, pos, query) && endsWord(content, end, query) {
+			positions = append(positions, pos)
+		}
+	}
+	return positions
+}
+
+// startsWord reports whether a match at pos is not glued to a preceding word character
+func startsWord(content string, pos int, query string) bool {
+	if pos == 0 {
+		return true
+	}
+	first, _ := utf8.DecodeRuneInString(query)
+	prev, _ := utf8.DecodeLastRuneInString(content[:pos])
+	// A query starting with punctuation (e.g. ".log") needs no boundary on that side
+	return !isWordRune(first) || !isWordRune(prev)
+}
+
+// endsWord reports whether a match ending at end is not glued to a following word character
+func endsWord(content string, end int, query string) bool {
+	if end >= len(content) {
+		return true
+	}
+	last, _ := utf8.DecodeLastRuneInString(query)
+	next, _ := utf8.DecodeRuneInString(content[end:])
+	return !isWordRune(last) || !isWordRune(next)
+}
+
+// isWordRune reports whether r can be part of an identifier
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
 // findMatchPositions finds all positions where the query appears in the content
 func findMatchPositions(content, query string) []int {
 	var positions []int
@@ -241,7 +873,7 @@ func findMatchPositions(content, query string) []int {
 }
 
 // FormatResults formats search results for display
-func FormatResults(results []SearchResult) string {
+func FormatResults(results []SearchResult, preview PreviewOptions) string {
 	if len(results) == 0 {
 		return "No results found."
 	}
@@ -262,6 +894,9 @@ func FormatResults(results []SearchResult) string {
 
 		// Format score info
 		scoreInfo := fmt.Sprintf("score: %.3f", result.HybridScore)
+		if result.RerankScore > 0 {
+			scoreInfo += fmt.Sprintf(", rerank: %.2f", result.RerankScore)
+		}
 		if result.ExactMatch {
 			scoreInfo += " [EXACT MATCH]"
 		}
@@ -271,27 +906,70 @@ func FormatResults(results []SearchResult) string {
 		output.WriteString(fmt.Sprintf("   %s\n", scoreInfo))
 		output.WriteString(fmt.Sprintf("   Language: %s, Type: %s\n", chunk.Language, chunk.ChunkType))
 
-		// Show content preview (first 3 lines)
-		lines := strings.Split(chunk.Content, "\n")
-		previewLines := 3
-		if len(lines) < previewLines {
-			previewLines = len(lines)
-		}
-
-		output.WriteString("   Preview:\n")
-		for j := 0; j < previewLines; j++ {
-			line := strings.TrimSpace(lines[j])
-			if len(line) > 80 {
-				line = line[:80] + "..."
-			}
-			output.WriteString(fmt.Sprintf("   │ %s\n", line))
-		}
-		if len(lines) > previewLines {
-			output.WriteString(fmt.Sprintf("   │ ... (%d more lines)\n", len(lines)-previewLines))
-		}
-
+		WritePreview(&output, chunk.Content, preview)
 		output.WriteString("\n")
 	}
 
 	return output.String()
 }
+
+// Preview sizes used when SearchConfig leaves PreviewLines or PreviewLineWidth unset
+const (
+	DefaultPreviewLines     = 3
+	DefaultPreviewLineWidth = 80
+)
+
+// PreviewOptions sizes the content preview of formatted results
+type PreviewOptions struct {
+	Lines     int // Lines shown per result
+	LineWidth int // Characters per line before it is cut with "..."
+}
+
+// PreviewOptionsFrom returns the preview sizes configured in cfg, with defaults for unset values
+func PreviewOptionsFrom(cfg *config.SearchConfig) PreviewOptions {
+	opts := PreviewOptions{Lines: DefaultPreviewLines, LineWidth: DefaultPreviewLineWidth}
+	if cfg != nil && cfg.PreviewLines > 0 {
+		opts.Lines = cfg.PreviewLines
+	}
+	if cfg != nil && cfg.PreviewLineWidth > 0 {
+		opts.LineWidth = cfg.PreviewLineWidth
+	}
+	return opts
+}
+
+// PreviewOptions returns the result preview sizes for a repository, resolved through its
+// repository config like the rest of the search settings
+func (s *Searcher) PreviewOptions(repoPath string) PreviewOptions {
+	repo, err := s.forRepo(repoPath)
+	if err != nil {
+		// The search itself reports an invalid repository config
+		return PreviewOptionsFrom(s.config)
+	}
+	return PreviewOptionsFrom(repo.config)
+}
+
+// WritePreview writes the first lines of content, trimmed and cut at the line width,
+// followed by a count of the lines left out
+func WritePreview(output *strings.Builder, content string, opts PreviewOptions) {
+	if opts.Lines <= 0 {
+		opts.Lines = DefaultPreviewLines
+	}
+	if opts.LineWidth <= 0 {
+		opts.LineWidth = DefaultPreviewLineWidth
+	}
+
+	lines := strings.Split(content, "\n")
+	previewLines := min(opts.Lines, len(lines))
+
+	output.WriteString("   Preview:\n")
+	for j := 0; j < previewLines; j++ {
+		line := strings.TrimSpace(lines[j])
+		if runes := []rune(line); len(runes) > opts.LineWidth {
+			line = string(runes[:opts.LineWidth]) + "..."
+		}
+		output.WriteString(fmt.Sprintf("   │ %s\n", line))
+	}
+	if len(lines) > previewLines {
+		output.WriteString(fmt.Sprintf("   │ ... (%d more lines)\n", len(lines)-previewLines))
+	}
+}
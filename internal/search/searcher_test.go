@@ -2,8 +2,14 @@ package search
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/jamaly87/codebase-semantic-search/internal/models"
 	"github.com/jamaly87/codebase-semantic-search/pkg/config"
@@ -13,9 +19,11 @@ import (
 type mockEmbeddingsClient struct {
 	embeddings []float32
 	err        error
+	texts      []string // Texts passed to GenerateEmbedding
 }
 
 func (m *mockEmbeddingsClient) GenerateEmbedding(text string) ([]float32, error) {
+	m.texts = append(m.texts, text)
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -24,18 +32,102 @@ func (m *mockEmbeddingsClient) GenerateEmbedding(text string) ([]float32, error)
 
 // Mock vector DB client
 type mockVectorDB struct {
-	chunks []models.CodeChunk
-	scores []float64
-	err    error
+	chunks       []models.CodeChunk
+	scores       []float64
+	err          error
+	lastLimit    int
+	lastMinScore float64
+	searchCalls  int
+	failures     int              // Number of initial Search calls that fail with failureErr
+	failureErr   error            // Transient error returned while failures remain
+	repoErrs     map[string]error // Search errors for specific repositories
+	lastFilter   models.SearchFilter
 }
 
-func (m *mockVectorDB) Search(ctx context.Context, embedding []float32, repoPath string, limit int) ([]models.CodeChunk, []float64, error) {
+func (m *mockVectorDB) Search(ctx context.Context, embedding []float32, filter models.SearchFilter, limit int, minScore float64) ([]models.CodeChunk, []float64, error) {
+	m.lastLimit = limit
+	m.lastMinScore = minScore
+	m.lastFilter = filter
+	m.searchCalls++
+	if err := m.repoErrs[filter.RepoPath]; err != nil {
+		return nil, nil, err
+	}
+	if m.failures > 0 {
+		m.failures--
+		return nil, nil, m.failureErr
+	}
 	if m.err != nil {
 		return nil, nil, m.err
 	}
+	if filter.FilePath != "" || !filter.Since.IsZero() || !filter.Until.IsZero() {
+		// Like the Qdrant file_path and indexed_at filters
+		var chunks []models.CodeChunk
+		var scores []float64
+		for i, chunk := range m.chunks {
+			if filter.FilePath != "" && chunk.FilePath != filter.FilePath {
+				continue
+			}
+			if (!filter.Since.IsZero() || !filter.Until.IsZero()) && chunk.IndexedAt.IsZero() {
+				continue
+			}
+			if chunk.IndexedAt.Before(filter.Since) || (!filter.Until.IsZero() && chunk.IndexedAt.After(filter.Until)) {
+				continue
+			}
+			chunks = append(chunks, chunk)
+			scores = append(scores, m.scores[i])
+		}
+		return chunks, scores, nil
+	}
 	return m.chunks, m.scores, nil
 }
 
+func (m *mockVectorDB) FindChunkAt(ctx context.Context, repoPath, filePath string, line int) (*models.CodeChunk, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for i := range m.chunks {
+		chunk := m.chunks[i]
+		if chunk.FilePath == filePath && chunk.StartLine <= line && chunk.EndLine >= line {
+			return &chunk, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockVectorDB) FindSymbols(ctx context.Context, repoPath, name string, exact bool, limit int) ([]models.CodeChunk, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var matches []models.CodeChunk
+	for _, chunk := range m.chunks {
+		if len(matches) == limit {
+			break
+		}
+		for _, symbol := range []string{chunk.FunctionName, chunk.ClassName} {
+			if (exact && symbol == name) || (!exact && symbol != "" && strings.Contains(strings.ToLower(symbol), strings.ToLower(name))) {
+				matches = append(matches, chunk)
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+func (m *mockVectorDB) ScrollSymbols(ctx context.Context, repoPath string, visit func(chunk models.CodeChunk) bool) error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, chunk := range m.chunks {
+		if chunk.FunctionName == "" && chunk.ClassName == "" {
+			continue
+		}
+		if !visit(chunk) {
+			return nil
+		}
+	}
+	return nil
+}
+
 func TestHybridScoring(t *testing.T) {
 	cfg := &config.SearchConfig{
 		MaxResults:       5,
@@ -215,6 +307,281 @@ func TestExactMatchDetection(t *testing.T) {
 	}
 }
 
+func TestWholeWordMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		content   string
+		substring bool
+		wholeWord bool
+	}{
+		{"word inside identifier", "log", "catalog.add(item)", true, false},
+		{"word as prefix", "log", "logger.info(msg)", true, false},
+		{"standalone word", "log", "log.info(msg)", true, true},
+		{"word at end of content", "log", "write to log", true, true},
+		{"underscore joins words", "user", "current_user_id", true, false},
+		{"word inside camel case", "user", "getuserbyid()", true, false},
+		{"word between punctuation", "user", "find(user) {", true, true},
+		{"multi-word query", "user service", "new user service()", true, true},
+		{"query with leading punctuation", ".log", "console.log(x)", true, true},
+		{"no match", "auth", "database connection", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, wholeWord := range []bool{false, true} {
+				searcher := NewSearcher(&config.SearchConfig{WholeWordMatch: wholeWord}, nil, nil)
+				results := searcher.applyHybridScoring(tt.query, []models.CodeChunk{{Content: tt.content}}, []float64{0.5})
+
+				expected := tt.substring
+				if wholeWord {
+					expected = tt.wholeWord
+				}
+				if results[0].ExactMatch != expected {
+					t.Errorf("WholeWordMatch=%v: ExactMatch for %q in %q = %v, expected %v",
+						wholeWord, tt.query, tt.content, results[0].ExactMatch, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestWholeWordMatch_PartialWords(t *testing.T) {
+	chunks := []models.CodeChunk{{Content: "catalog of users"}}
+
+	substring := NewSearcher(&config.SearchConfig{SemanticWeight: 1}, nil, nil)
+	wholeWord := NewSearcher(&config.SearchConfig{SemanticWeight: 1, WholeWordMatch: true}, nil, nil)
+
+	// "log" and "user" both appear inside other words but never on their own
+	substringScore := substring.applyHybridScoring("log user", chunks, []float64{0.5})[0].HybridScore
+	wholeWordScore := wholeWord.applyHybridScoring("log user", chunks, []float64{0.5})[0].HybridScore
+
+	if substringScore <= 0.5 {
+		t.Errorf("Expected substring matching to boost partial words, got %.3f", substringScore)
+	}
+	if wholeWordScore != 0.5 {
+		t.Errorf("Expected whole-word matching to give no partial boost, got %.3f", wholeWordScore)
+	}
+}
+
+func TestNormalizedScoring_Bounds(t *testing.T) {
+	chunks := []models.CodeChunk{
+		{Content: "func ValidateToken(token string) error", FilePath: "/repo/src/main/auth/token.go"},
+		{Content: "validate the token later", FilePath: "/repo/internal/auth.go"},
+		{Content: "unrelated content", FilePath: "/repo/README.md"},
+		{Content: "validatetoken helper", FilePath: "/repo/src/test/auth_test.go"},
+		{Content: "generated validatetoken", FilePath: "/repo/target/generated/Auth.java"},
+	}
+
+	weights := []struct{ semantic, lexical float64 }{
+		{0.7, 0.3},
+		{1, 0},
+		{0, 1},
+		{2, 3},   // Rescaled to sum to 1
+		{0.7, 0}, // Lexical defaults to 1 - semantic
+	}
+
+	for _, w := range weights {
+		cfg := &config.SearchConfig{
+			ScoringMode:     ScoringModeNormalized,
+			SemanticWeight:  w.semantic,
+			LexicalWeight:   w.lexical,
+			ExactMatchBoost: 1.5, // Ignored in normalized mode
+		}
+		searcher := NewSearcher(cfg, nil, nil)
+
+		for _, semanticScore := range []float64{-0.2, 0, 0.5, 1} {
+			scores := make([]float64, len(chunks))
+			for i := range scores {
+				scores[i] = semanticScore
+			}
+
+			for _, query := range []string{"validatetoken", "validate token", `"validate token" OR auth`} {
+				for _, result := range searcher.applyHybridScoring(query, chunks, scores) {
+					if result.HybridScore < 0 || result.HybridScore > 1 {
+						t.Errorf("weights %v, semantic %.1f, query %q: score %.3f for %s outside [0,1]",
+							w, semanticScore, query, result.HybridScore, result.Chunk.FilePath)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestNormalizedScoring_Values(t *testing.T) {
+	cfg := &config.SearchConfig{ScoringMode: ScoringModeNormalized, SemanticWeight: 0.7, LexicalWeight: 0.3}
+	searcher := NewSearcher(cfg, nil, nil)
+
+	results := searcher.applyHybridScoring("user service", []models.CodeChunk{
+		{Content: "user service factory", FilePath: "/repo/src/main/UserService.java"},
+		{Content: "service for the user", FilePath: "/repo/src/main/Other.java"},
+		{Content: "nothing relevant", FilePath: "/repo/src/main/None.java"},
+	}, []float64{1, 1, 1})
+
+	expected := []float64{
+		1.0,                                   // Perfect semantic and exact match, strongest path boost
+		0.7 + 0.3*normalizedPartialMatchScore, // All query words, but not together
+		0.7,                                   // Semantic only
+	}
+	for i, want := range expected {
+		if abs(results[i].HybridScore-want) > 1e-9 {
+			t.Errorf("Result %d: expected %.3f, got %.3f", i, want, results[i].HybridScore)
+		}
+	}
+}
+
+func TestScoringWeights(t *testing.T) {
+	tests := []struct {
+		name             string
+		semantic         float64
+		lexical          float64
+		expectedSemantic float64
+	}{
+		{"already normalized", 0.6, 0.4, 0.6},
+		{"lexical defaults to remainder", 0.8, 0, 0.8},
+		{"rescaled to sum to 1", 3, 1, 0.75},
+		{"zero semantic weight is lexical only", 0, 0, 0},
+		{"semantic above 1 leaves no lexical weight", 2, 0, 1},
+		{"negative semantic ignored", -1, 0.5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searcher := NewSearcher(&config.SearchConfig{SemanticWeight: tt.semantic, LexicalWeight: tt.lexical}, nil, nil)
+			semantic, lexical := searcher.scoringWeights()
+			if abs(semantic-tt.expectedSemantic) > 1e-9 {
+				t.Errorf("semantic weight = %.3f, expected %.3f", semantic, tt.expectedSemantic)
+			}
+			if abs(semantic+lexical-1) > 1e-9 {
+				t.Errorf("weights %.3f + %.3f do not sum to 1", semantic, lexical)
+			}
+		})
+	}
+}
+
+func TestAdditiveScoringMode(t *testing.T) {
+	chunks := []models.CodeChunk{{Content: "user service factory", FilePath: "/repo/src/main/UserService.java"}}
+	scores := []float64{0.8}
+
+	// Empty mode keeps the original additive scheme for existing configurations
+	for _, mode := range []string{"", ScoringModeAdditive} {
+		cfg := &config.SearchConfig{ScoringMode: mode, SemanticWeight: 0.7, LexicalWeight: 0.3, ExactMatchBoost: 1.5}
+		got := NewSearcher(cfg, nil, nil).applyHybridScoring("user service", chunks, scores)[0].HybridScore
+
+		want := (0.8*0.7 + 1.5) * maxFilePathScore
+		if abs(got-want) > 1e-9 {
+			t.Errorf("mode %q: expected additive score %.3f, got %.3f", mode, want, got)
+		}
+	}
+}
+
+func TestExactMatchMinSemantic(t *testing.T) {
+	chunks := []models.CodeChunk{
+		{Content: "// see the retry policy in config", FilePath: "/repo/src/main/Notes.java"},
+		{Content: "func backoff(attempt int) time.Duration", FilePath: "/repo/src/main/Backoff.java"},
+	}
+	scores := []float64{0.1, 0.85}
+
+	cfg := &config.SearchConfig{ScoringMode: ScoringModeAdditive, SemanticWeight: 0.7, ExactMatchBoost: 1.5}
+	results := NewSearcher(cfg, nil, nil).applyHybridScoring("retry policy", chunks, scores)
+	if results[0].HybridScore <= results[1].HybridScore {
+		t.Fatalf("Expected the substring match to outrank without a floor, got %.3f and %.3f",
+			results[0].HybridScore, results[1].HybridScore)
+	}
+
+	// With a floor the unrelated chunk no longer gets the boost
+	cfg.ExactMatchMinSemantic = 0.3
+	results = NewSearcher(cfg, nil, nil).applyHybridScoring("retry policy", chunks, scores)
+	if results[0].HybridScore >= results[1].HybridScore {
+		t.Errorf("Expected the semantic match (%.3f) to outrank the low-similarity substring match (%.3f)",
+			results[1].HybridScore, results[0].HybridScore)
+	}
+	if !results[0].ExactMatch {
+		t.Error("Expected the chunk to still be reported as an exact match")
+	}
+
+	// Exact matches above the floor keep the boost
+	results = NewSearcher(cfg, nil, nil).applyHybridScoring("retry policy", chunks, []float64{0.5, 0.85})
+	if want := (0.5*0.7 + 1.5) * maxFilePathScore; abs(results[0].HybridScore-want) > 1e-9 {
+		t.Errorf("Expected boosted score %.3f above the floor, got %.3f", want, results[0].HybridScore)
+	}
+}
+
+func TestGeneratedChunksPenalized(t *testing.T) {
+	generated := map[string]interface{}{models.MetadataGenerated: true}
+	chunks := []models.CodeChunk{
+		{Content: "func (c *userClient) GetUser()", FilePath: "/repo/internal/api/user.pb.go", Metadata: generated},
+		{Content: "func (s *Server) GetUser()", FilePath: "/repo/internal/api/server.go"},
+		{Content: "func TestGetUser()", FilePath: "/repo/internal/api/server_test.go", Metadata: generated},
+	}
+
+	cfg := &config.SearchConfig{ScoringMode: ScoringModeAdditive, SemanticWeight: 1}
+	results := NewSearcher(cfg, nil, nil).applyHybridScoring("load profile", chunks, []float64{0.9, 0.6, 0.9})
+
+	// The generated marker penalizes a source path like a generated path would
+	if want := 0.9 * generatedFileScore; abs(results[0].HybridScore-want) > 1e-9 {
+		t.Errorf("Expected the generated chunk to score %.3f, got %.3f", want, results[0].HybridScore)
+	}
+	if results[0].HybridScore >= results[1].HybridScore {
+		t.Errorf("Expected the handwritten chunk (%.3f) to outrank the generated one (%.3f)",
+			results[1].HybridScore, results[0].HybridScore)
+	}
+	// The stronger test file penalty still applies
+	if want := 0.9 * 0.05; abs(results[2].HybridScore-want) > 1e-9 {
+		t.Errorf("Expected the generated test chunk to keep the test penalty (%.3f), got %.3f", want, results[2].HybridScore)
+	}
+}
+
+func TestChunkTypeWeights(t *testing.T) {
+	chunks := []models.CodeChunk{
+		{Content: "class PaymentService handles payments", FilePath: "/repo/src/main/PaymentService.java", ChunkType: models.ChunkTypeFile},
+		{Content: "func processPayment() error", FilePath: "/repo/src/main/PaymentService.java", ChunkType: models.ChunkTypeFunction},
+	}
+	scores := []float64{0.8, 0.8}
+
+	for _, mode := range []string{ScoringModeNormalized, ScoringModeAdditive} {
+		t.Run(mode, func(t *testing.T) {
+			cfg := &config.SearchConfig{
+				ScoringMode:      mode,
+				SemanticWeight:   0.7,
+				LexicalWeight:    0.3,
+				ExactMatchBoost:  1.5,
+				ChunkTypeWeights: config.DefaultConfig().Search.ChunkTypeWeights,
+			}
+			results := NewSearcher(cfg, nil, nil).applyHybridScoring("refund", chunks, scores)
+			if results[1].HybridScore <= results[0].HybridScore {
+				t.Errorf("Expected the function chunk (%.3f) to outrank the file chunk (%.3f) at equal semantic scores",
+					results[1].HybridScore, results[0].HybridScore)
+			}
+
+			// Without weights both chunk types score the same
+			cfg.ChunkTypeWeights = nil
+			results = NewSearcher(cfg, nil, nil).applyHybridScoring("refund", chunks, scores)
+			if abs(results[0].HybridScore-results[1].HybridScore) > 1e-9 {
+				t.Errorf("Expected equal scores without chunk type weights, got %.3f and %.3f",
+					results[0].HybridScore, results[1].HybridScore)
+			}
+		})
+	}
+
+	// Boosts above 1 are rescaled in normalized mode, keeping scores within [0,1]
+	cfg := &config.SearchConfig{
+		ScoringMode:      ScoringModeNormalized,
+		SemanticWeight:   0.7,
+		LexicalWeight:    0.3,
+		ChunkTypeWeights: map[string]float64{"function": 1.5, "file": 0.5},
+	}
+	results := NewSearcher(cfg, nil, nil).applyHybridScoring("process payment", chunks, []float64{1, 1})
+	for _, result := range results {
+		if result.HybridScore < 0 || result.HybridScore > 1 {
+			t.Errorf("Score %.3f for a %s chunk outside [0,1]", result.HybridScore, result.Chunk.ChunkType)
+		}
+	}
+	if results[1].HybridScore <= results[0].HybridScore {
+		t.Error("Expected the boosted function chunk to rank first")
+	}
+}
+
 func TestSearchResultRanking(t *testing.T) {
 	cfg := &config.SearchConfig{
 		MaxResults:      3,
@@ -273,6 +640,259 @@ func TestSearchResultRanking(t *testing.T) {
 	}
 }
 
+func TestSearchCandidateLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxResults    int
+		multiplier    int
+		maxCandidates int
+		expected      int
+	}{
+		{"defaults when unset", 5, 0, 0, 5 * DefaultRerankCandidateMultiplier},
+		{"custom multiplier", 5, 5, 100, 25},
+		{"multiplier of one disables over-fetch", 5, 1, 100, 5},
+		{"hard cap bounds over-fetch", 50, 3, 100, 100},
+		{"default cap applies when unset", 50, 3, 0, DefaultMaxCandidates},
+		{"cap never drops below max results", 500, 3, 100, 500},
+		{"custom cap", 10, 4, 20, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.SearchConfig{
+				MaxResults:                tt.maxResults,
+				SemanticWeight:            0.7,
+				RerankCandidateMultiplier: tt.multiplier,
+				MaxCandidates:             tt.maxCandidates,
+			}
+			mockDB := &mockVectorDB{
+				chunks: []models.CodeChunk{{ID: "1", Content: "content", FilePath: "a.java"}},
+				scores: []float64{0.9},
+			}
+			searcher := NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+
+			if _, err := searcher.Search(context.Background(), "query", "/test/repo"); err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if mockDB.lastLimit != tt.expected {
+				t.Errorf("Expected search limit %d, got %d", tt.expected, mockDB.lastLimit)
+			}
+		})
+	}
+}
+
+func TestSearch_RepoConfigOverrides(t *testing.T) {
+	cfg := &config.SearchConfig{MaxResults: 3, SemanticWeight: 0.7}
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "1", Content: "one", FilePath: "a.java"},
+			{ID: "2", Content: "two", FilePath: "b.java"},
+			{ID: "3", Content: "three", FilePath: "c.java"},
+		},
+		scores: []float64{0.9, 0.8, 0.7},
+	}
+	searcher := NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+
+	overridden := t.TempDir()
+	if err := os.WriteFile(filepath.Join(overridden, config.RepoConfigFile), []byte("search:\n  max_results: 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write repo config: %v", err)
+	}
+
+	results, err := searcher.Search(context.Background(), "query", overridden)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Expected the repo's max_results of 1, got %d results", len(results))
+	}
+
+	// Other repositories keep the global settings
+	results, err = searcher.Search(context.Background(), "query", t.TempDir())
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("Expected the global max_results of 3, got %d results", len(results))
+	}
+	if cfg.MaxResults != 3 {
+		t.Errorf("Expected the global config to be unchanged, got max_results %d", cfg.MaxResults)
+	}
+}
+
+func TestSearch_FailsWithoutRetrying(t *testing.T) {
+	mockDB := &mockVectorDB{
+		chunks:     []models.CodeChunk{{ID: "1", Content: "one", FilePath: "a.go"}},
+		scores:     []float64{0.9},
+		failureErr: errors.New("connection reset"),
+		failures:   1,
+	}
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 3, SemanticWeight: 1}, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+
+	// The vector database client retries transient errors, so the searcher doesn't again
+	if _, err := searcher.Search(context.Background(), "query", "/test/repo"); err == nil {
+		t.Fatal("Expected the vector database error")
+	}
+	if mockDB.searchCalls != 1 {
+		t.Errorf("Expected 1 call, got %d", mockDB.searchCalls)
+	}
+}
+
+func TestSearchRepos_PartialFailure(t *testing.T) {
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "1", Content: "one", FilePath: "a.go"},
+			{ID: "2", Content: "two", FilePath: "b.go"},
+		},
+		scores:   []float64{0.9, 0.8},
+		repoErrs: map[string]error{"/repo/broken": errors.New("collection unavailable")},
+	}
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 3, SemanticWeight: 1}, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+
+	results, failures, err := searcher.SearchRepos(context.Background(), "query", []string{"/repo/ok", "/repo/broken"}, SearchOptions{})
+	if err != nil {
+		t.Fatalf("Expected partial results, got error %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected the 2 results of the healthy repo, got %d", len(results))
+	}
+	if len(failures) != 1 || failures[0].RepoPath != "/repo/broken" {
+		t.Fatalf("Expected a failure for /repo/broken, got %v", failures)
+	}
+	if !strings.Contains(failures[0].Error(), "collection unavailable") {
+		t.Errorf("Expected the failure to carry the cause, got %q", failures[0].Error())
+	}
+}
+
+func TestSearchRepos_MergesByScore(t *testing.T) {
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "1", Content: "one", FilePath: "a.go"},
+			{ID: "2", Content: "two", FilePath: "b.go"},
+		},
+		scores: []float64{0.9, 0.8},
+	}
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 3, SemanticWeight: 1}, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+
+	results, failures, err := searcher.SearchRepos(context.Background(), "query", []string{"/repo/a", "/repo/b"}, SearchOptions{})
+	if err != nil || len(failures) != 0 {
+		t.Fatalf("Expected no failures, got %v, %v", failures, err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected results capped at max_results 3, got %d", len(results))
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i].HybridScore > results[i-1].HybridScore {
+			t.Errorf("Expected merged results sorted by score, got %v", resultIDs(results))
+		}
+	}
+}
+
+func TestMergeRepoResults(t *testing.T) {
+	result := func(id string, hybrid, rerank float64) SearchResult {
+		return SearchResult{Chunk: models.CodeChunk{ID: id}, HybridScore: hybrid, RerankScore: rerank}
+	}
+
+	tests := []struct {
+		name     string
+		perRepo  [][]SearchResult
+		expected string
+	}{
+		{
+			name: "hybrid scores relative to each repository's best",
+			perRepo: [][]SearchResult{
+				{result("a1", 0.4, 0), result("a2", 0.2, 0)},
+				{result("b1", 0.9, 0), result("b2", 0.3, 0)},
+			},
+			expected: "a1,b1,a2,b2",
+		},
+		{
+			name: "reranked order kept within each repository",
+			perRepo: [][]SearchResult{
+				{result("a1", 0.1, 0.9), result("a2", 0.8, 0.3)},
+				{result("b1", 0.5, 0.6)},
+			},
+			expected: "a1,b1,a2",
+		},
+		{
+			name: "mixed reranking falls back to hybrid scores",
+			perRepo: [][]SearchResult{
+				{result("a1", 0.4, 0.9), result("a2", 0.1, 0.8)},
+				{result("b1", 0.9, 0), result("b2", 0.6, 0)},
+			},
+			expected: "a1,b1,b2,a2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resultIDs(mergeRepoResults(tt.perRepo)); got != tt.expected {
+				t.Errorf("Expected order %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSearch_RepoRerankerOverride(t *testing.T) {
+	searcher := newRerankTestSearcher(&config.SearchConfig{MaxResults: 4, SemanticWeight: 1, Reranker: RerankerOllama, RerankModel: "llama3"})
+	reranker := &reverseReranker{}
+	searcher.SetReranker(reranker)
+
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, config.RepoConfigFile), []byte("search:\n  reranker: none\n"), 0644); err != nil {
+		t.Fatalf("Failed to write repo config: %v", err)
+	}
+
+	results, err := searcher.Search(context.Background(), "query", repo)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if got := resultIDs(results); got != "1,2,3,4" {
+		t.Errorf("Expected the hybrid order, got %s", got)
+	}
+	if reranker.calls != 0 {
+		t.Errorf("Expected the repository to turn reranking off, got %d rerank calls", reranker.calls)
+	}
+
+	// Other repositories keep the global reranker
+	if _, err := searcher.Search(context.Background(), "query", t.TempDir()); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if reranker.calls != 1 {
+		t.Errorf("Expected the global reranker elsewhere, got %d rerank calls", reranker.calls)
+	}
+}
+
+func TestSearchRepos_AllFail(t *testing.T) {
+	mockDB := &mockVectorDB{err: errors.New("qdrant down")}
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 3, SemanticWeight: 1}, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+
+	_, failures, err := searcher.SearchRepos(context.Background(), "query", []string{"/repo/a", "/repo/b"}, SearchOptions{})
+	if err == nil {
+		t.Fatal("Expected an error when every repository fails")
+	}
+	if len(failures) != 2 {
+		t.Errorf("Expected 2 failures, got %d", len(failures))
+	}
+}
+
+func TestSearch_QueryPrefix(t *testing.T) {
+	embeddings := &mockEmbeddingsClient{embeddings: []float32{0.1}}
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 3}, embeddings, &mockVectorDB{})
+	searcher.SetTaskPrefixes("search_query: ", "search_document: ")
+
+	if _, err := searcher.Search(context.Background(), "jwt validation", "/test/repo"); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if _, _, err := searcher.SearchRepos(context.Background(), "csv parsing", []string{"/a", "/b"}, SearchOptions{}); err != nil {
+		t.Fatalf("SearchRepos failed: %v", err)
+	}
+
+	expected := []string{"search_query: jwt validation", "search_query: csv parsing"}
+	if strings.Join(embeddings.texts, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected embedded texts %q, got %q", expected, embeddings.texts)
+	}
+}
+
 func TestFormatResults(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -334,7 +954,7 @@ func TestFormatResults(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			output := FormatResults(tt.results)
+			output := FormatResults(tt.results, PreviewOptions{})
 
 			for _, expected := range tt.expected {
 				if !strings.Contains(output, expected) {
@@ -352,3 +972,445 @@ func abs(x float64) float64 {
 	}
 	return x
 }
+
+func TestFormatResults_MultibytePreview(t *testing.T) {
+	results := []SearchResult{{
+		Chunk: models.CodeChunk{
+			FilePath:  "greet.ts",
+			StartLine: 1,
+			EndLine:   1,
+			Content:   "const greeting = \"" + strings.Repeat("こんにちは🌏", 20) + "\";",
+			Language:  "typescript",
+		},
+	}}
+
+	output := FormatResults(results, PreviewOptions{})
+	if !utf8.ValidString(output) {
+		t.Error("Expected the shortened preview line to be valid UTF-8")
+	}
+	if !strings.Contains(output, "...") {
+		t.Error("Expected the long preview line to be shortened")
+	}
+}
+
+func TestWritePreview(t *testing.T) {
+	content := "line one\nline two is a bit longer\nline three\nline four\nline five"
+
+	tests := []struct {
+		name     string
+		opts     PreviewOptions
+		expected []string
+		absent   []string
+	}{
+		{
+			name:     "defaults",
+			opts:     PreviewOptions{},
+			expected: []string{"│ line three\n", "│ ... (2 more lines)"},
+			absent:   []string{"line four"},
+		},
+		{
+			name:     "more lines",
+			opts:     PreviewOptions{Lines: 4},
+			expected: []string{"│ line four\n", "│ ... (1 more lines)"},
+			absent:   []string{"line five"},
+		},
+		{
+			name:     "all lines",
+			opts:     PreviewOptions{Lines: 10},
+			expected: []string{"│ line five\n"},
+			absent:   []string{"more lines"},
+		},
+		{
+			name:     "narrow lines",
+			opts:     PreviewOptions{Lines: 2, LineWidth: 8},
+			expected: []string{"│ line one\n", "│ line two...\n", "│ ... (3 more lines)"},
+			absent:   []string{"longer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output strings.Builder
+			WritePreview(&output, content, tt.opts)
+			for _, expected := range tt.expected {
+				if !strings.Contains(output.String(), expected) {
+					t.Errorf("Expected %q in preview, got:\n%s", expected, output.String())
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(output.String(), absent) {
+					t.Errorf("Expected no %q in preview, got:\n%s", absent, output.String())
+				}
+			}
+		})
+	}
+}
+
+func TestPreviewOptionsFrom(t *testing.T) {
+	if opts := PreviewOptionsFrom(&config.SearchConfig{}); opts.Lines != DefaultPreviewLines || opts.LineWidth != DefaultPreviewLineWidth {
+		t.Errorf("Expected defaults for unset sizes, got %+v", opts)
+	}
+	if opts := PreviewOptionsFrom(&config.SearchConfig{PreviewLines: 10, PreviewLineWidth: 120}); opts.Lines != 10 || opts.LineWidth != 120 {
+		t.Errorf("Expected configured sizes, got %+v", opts)
+	}
+
+	searcher := NewSearcher(&config.SearchConfig{PreviewLines: 1}, nil, nil)
+	output := FormatResults([]SearchResult{{Chunk: models.CodeChunk{FilePath: "a.go", Content: "first\nsecond"}}}, searcher.PreviewOptions(t.TempDir()))
+	if strings.Contains(output, "second") || !strings.Contains(output, "(1 more lines)") {
+		t.Errorf("Expected a one-line preview, got:\n%s", output)
+	}
+
+	// A repository's config overrides the preview sizes
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, config.RepoConfigFile), []byte("search:\n  preview_lines: 5\n"), 0644); err != nil {
+		t.Fatalf("Failed to write repo config: %v", err)
+	}
+	if opts := searcher.PreviewOptions(repo); opts.Lines != 5 {
+		t.Errorf("Expected the repository's 5 preview lines, got %+v", opts)
+	}
+}
+
+func TestSearch_PassesMinSemanticScore(t *testing.T) {
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{{ID: "1", Content: "one", FilePath: "a.go"}},
+		scores: []float64{0.9},
+	}
+	cfg := &config.SearchConfig{MaxResults: 3, SemanticWeight: 1, MinSemanticScore: 0.35}
+	searcher := NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+
+	if _, err := searcher.Search(context.Background(), "query", "/test/repo"); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if mockDB.lastMinScore != 0.35 {
+		t.Errorf("Expected the database threshold 0.35, got %v", mockDB.lastMinScore)
+	}
+}
+
+func TestSearchFiltered_SingleFile(t *testing.T) {
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "1", Content: "func parseHeader()", FilePath: "/test/repo/parser.go", ChunkType: models.ChunkTypeFunction},
+			{ID: "2", Content: "func parseBody()", FilePath: "/test/repo/parser.go", ChunkType: models.ChunkTypeFunction},
+			{ID: "3", Content: "func parseConfig()", FilePath: "/test/repo/config.go", ChunkType: models.ChunkTypeFunction},
+		},
+		scores: []float64{0.6, 0.8, 0.95},
+	}
+	cfg := &config.SearchConfig{MaxResults: 5, SemanticWeight: 1}
+	searcher := NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+
+	filter := models.SearchFilter{RepoPath: "/test/repo", FilePath: "/test/repo/parser.go"}
+	results, err := searcher.SearchFiltered(context.Background(), "parse", filter, SearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+
+	// Only the target file's chunks are considered, ranked by relevance
+	if len(results) != 2 {
+		t.Fatalf("Expected the 2 chunks of parser.go, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Chunk.FilePath != filter.FilePath {
+			t.Errorf("Expected only chunks of %s, got %s", filter.FilePath, result.Chunk.FilePath)
+		}
+	}
+	if results[0].Chunk.ID != "2" {
+		t.Errorf("Expected the more similar chunk first, got %s", results[0].Chunk.ID)
+	}
+}
+
+func TestSearchFiltered_UniqueFiles(t *testing.T) {
+	// 6 chunks across 3 files
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "a1", Content: "func a1()", FilePath: "/test/repo/a.go", StartLine: 1, EndLine: 5},
+			{ID: "a2", Content: "func a2()", FilePath: "/test/repo/a.go", StartLine: 10, EndLine: 20},
+			{ID: "b1", Content: "func b1()", FilePath: "/test/repo/b.go", StartLine: 1, EndLine: 5},
+			{ID: "b2", Content: "func b2()", FilePath: "/test/repo/b.go", StartLine: 30, EndLine: 40},
+			{ID: "b3", Content: "func b3()", FilePath: "/test/repo/b.go", StartLine: 50, EndLine: 60},
+			{ID: "c1", Content: "func c1()", FilePath: "/test/repo/c.go", StartLine: 1, EndLine: 5},
+		},
+		scores: []float64{0.7, 0.9, 0.6, 0.95, 0.5, 0.8},
+	}
+	cfg := &config.SearchConfig{MaxResults: 5, SemanticWeight: 1}
+	searcher := NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+	filter := models.SearchFilter{RepoPath: "/test/repo"}
+
+	results, err := searcher.SearchFiltered(context.Background(), "unrelated", filter, SearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("Expected 5 chunk results without unique_files, got %d", len(results))
+	}
+
+	results, err = searcher.SearchFiltered(context.Background(), "unrelated", filter, SearchOptions{UniqueFiles: true})
+	if err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+
+	// One result per file, represented by and ranked by its best chunk
+	expected := []string{"b2", "a2", "c1"}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d files, got %d results", len(expected), len(results))
+	}
+	for i, id := range expected {
+		if results[i].Chunk.ID != id {
+			t.Errorf("Result %d: expected best chunk %s, got %s", i, id, results[i].Chunk.ID)
+		}
+	}
+	if results[0].Chunk.StartLine != 30 || results[0].Chunk.EndLine != 40 {
+		t.Errorf("Expected the best chunk's line range, got %d-%d", results[0].Chunk.StartLine, results[0].Chunk.EndLine)
+	}
+}
+
+func TestSearchFiltered_MaxResultsPerFile(t *testing.T) {
+	// A hot file with the 5 best matches, and 3 weaker files
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "hot1", Content: "func hot1()", FilePath: "/test/repo/hot.go"},
+			{ID: "hot2", Content: "func hot2()", FilePath: "/test/repo/hot.go"},
+			{ID: "hot3", Content: "func hot3()", FilePath: "/test/repo/hot.go"},
+			{ID: "hot4", Content: "func hot4()", FilePath: "/test/repo/hot.go"},
+			{ID: "hot5", Content: "func hot5()", FilePath: "/test/repo/hot.go"},
+			{ID: "a1", Content: "func a1()", FilePath: "/test/repo/a.go"},
+			{ID: "b1", Content: "func b1()", FilePath: "/test/repo/b.go"},
+			{ID: "b2", Content: "func b2()", FilePath: "/test/repo/b.go"},
+		},
+		scores: []float64{0.95, 0.94, 0.93, 0.92, 0.91, 0.8, 0.7, 0.6},
+	}
+	cfg := &config.SearchConfig{MaxResults: 5, SemanticWeight: 1}
+	filter := models.SearchFilter{RepoPath: "/test/repo"}
+
+	results, err := NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB).SearchFiltered(context.Background(), "unrelated", filter, SearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+	for _, result := range results {
+		if result.Chunk.FilePath != "/test/repo/hot.go" {
+			t.Fatalf("Expected the hot file to take every place without a cap, got %s", result.Chunk.ID)
+		}
+	}
+
+	cfg.MaxResultsPerFile = 2
+	results, err = NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB).SearchFiltered(context.Background(), "unrelated", filter, SearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+
+	// The hot file's best 2 chunks, then the other files' chunks move up, several per file
+	expected := []string{"hot1", "hot2", "a1", "b1", "b2"}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+	for i, id := range expected {
+		if results[i].Chunk.ID != id {
+			t.Errorf("Result %d: expected %s, got %s", i, id, results[i].Chunk.ID)
+		}
+	}
+
+	// unique_files still keeps one chunk per file
+	results, err = NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB).SearchFiltered(context.Background(), "unrelated", filter, SearchOptions{UniqueFiles: true})
+	if err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("Expected one result for each of the 3 files with unique_files, got %d", len(results))
+	}
+}
+
+func TestSearchFiltered_ExcludePaths(t *testing.T) {
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "legacy", Content: "func a()", RepoPath: "/test/repo", FilePath: "/test/repo/legacy/auth/login.go"},
+			{ID: "nested", Content: "func b()", RepoPath: "/test/repo", FilePath: "/test/repo/src/legacy/session.go"},
+			{ID: "test", Content: "func c()", RepoPath: "/test/repo", FilePath: "/test/repo/src/auth/login_test.go"},
+			{ID: "kept1", Content: "func d()", RepoPath: "/test/repo", FilePath: "/test/repo/src/auth/login.go"},
+			{ID: "kept2", Content: "func e()", RepoPath: "/test/repo", FilePath: "/test/repo/src/legacy.go"},
+		},
+		scores: []float64{0.95, 0.9, 0.85, 0.8, 0.75},
+	}
+	cfg := &config.SearchConfig{MaxResults: 5, SemanticWeight: 1}
+	searcher := NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+	filter := models.SearchFilter{RepoPath: "/test/repo"}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		expected []string
+	}{
+		{"directory name at any depth", []string{"legacy/"}, []string{"test", "kept1", "kept2"}},
+		{"anchored directory", []string{"/legacy/**"}, []string{"nested", "test", "kept1", "kept2"}},
+		{"bare directory name", []string{"legacy"}, []string{"test", "kept1", "kept2"}},
+		{"file glob", []string{"*_test.go"}, []string{"legacy", "nested", "kept1", "kept2"}},
+		{"several patterns", []string{"legacy/", "*_test.go"}, []string{"kept1", "kept2"}},
+	}
+	for _, tt := range tests {
+		results, err := searcher.SearchFiltered(context.Background(), "unrelated", filter, SearchOptions{ExcludePaths: tt.patterns})
+		if err != nil {
+			t.Fatalf("%s: SearchFiltered failed: %v", tt.name, err)
+		}
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.Chunk.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, ids)
+		}
+	}
+
+	// The candidates themselves are left untouched
+	results, err := searcher.SearchFiltered(context.Background(), "unrelated", filter, SearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+	if len(results) != 5 || results[0].Chunk.ID != "legacy" {
+		t.Errorf("Expected every chunk without exclude_paths, got %d results", len(results))
+	}
+}
+
+func TestSearchFiltered_SortByLocation(t *testing.T) {
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "b-40", Content: "func a()", RepoPath: "/test/repo", FilePath: "/test/repo/b.go", StartLine: 40, EndLine: 50},
+			{ID: "a-10", Content: "func b()", RepoPath: "/test/repo", FilePath: "/test/repo/a.go", StartLine: 10, EndLine: 20},
+			{ID: "b-5", Content: "func c()", RepoPath: "/test/repo", FilePath: "/test/repo/b.go", StartLine: 5, EndLine: 9},
+			{ID: "a-1", Content: "func d()", RepoPath: "/test/repo", FilePath: "/test/repo/a.go", StartLine: 1, EndLine: 8},
+			{ID: "low", Content: "func e()", RepoPath: "/test/repo", FilePath: "/test/repo/0.go", StartLine: 1, EndLine: 2},
+		},
+		scores: []float64{0.95, 0.9, 0.85, 0.8, 0.1},
+	}
+	cfg := &config.SearchConfig{MaxResults: 4, SemanticWeight: 1}
+	searcher := NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+	filter := models.SearchFilter{RepoPath: "/test/repo"}
+
+	ids := func(results []SearchResult) string {
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.Chunk.ID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	// The default keeps the score order
+	for _, sortBy := range []string{"", SortByScore} {
+		results, err := searcher.SearchFiltered(context.Background(), "unrelated", filter, SearchOptions{SortBy: sortBy})
+		if err != nil {
+			t.Fatalf("SearchFiltered failed: %v", err)
+		}
+		if got := ids(results); got != "b-40,a-10,b-5,a-1" {
+			t.Errorf("Expected score order with sort_by %q, got %s", sortBy, got)
+		}
+	}
+
+	// The same top results, by file then line; the low-scoring chunk stays out
+	results, err := searcher.SearchFiltered(context.Background(), "unrelated", filter, SearchOptions{SortBy: SortByLocation})
+	if err != nil {
+		t.Fatalf("SearchFiltered failed: %v", err)
+	}
+	if got := ids(results); got != "a-1,a-10,b-5,b-40" {
+		t.Errorf("Expected location order, got %s", got)
+	}
+}
+
+func TestSearchFiltered_IndexedWindow(t *testing.T) {
+	now := time.Now()
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "old", Content: "func chargeCard()", RepoPath: "/test/repo", FilePath: "/test/repo/old.go", IndexedAt: now.AddDate(0, 0, -30)},
+			{ID: "recent", Content: "func refundPayment()", RepoPath: "/test/repo", FilePath: "/test/repo/recent.go", IndexedAt: now.Add(-time.Hour)},
+			{ID: "legacy", Content: "func payInvoice()", RepoPath: "/test/repo", FilePath: "/test/repo/legacy.go"},
+		},
+		scores: []float64{0.9, 0.8, 0.7},
+	}
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 5, SemanticWeight: 1}, &mockEmbeddingsClient{embeddings: []float32{0.1}}, mockDB)
+	searcher.SetResultCache(NewResultCache(time.Minute, 10))
+
+	search := func(filter models.SearchFilter) []string {
+		filter.RepoPath = "/test/repo"
+		results, err := searcher.SearchFiltered(context.Background(), "payments", filter, SearchOptions{})
+		if err != nil {
+			t.Fatalf("SearchFiltered failed: %v", err)
+		}
+		if mockDB.lastFilter.Since != filter.Since || mockDB.lastFilter.Until != filter.Until {
+			t.Errorf("Expected the time window to reach the vector DB, got %+v", mockDB.lastFilter)
+		}
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.Chunk.ID)
+		}
+		return ids
+	}
+
+	if got := search(models.SearchFilter{}); len(got) != 3 {
+		t.Errorf("Expected every chunk without a window, got %v", got)
+	}
+	lastWeek := now.AddDate(0, 0, -7)
+	if got := search(models.SearchFilter{Since: lastWeek}); !slices.Equal(got, []string{"recent"}) {
+		t.Errorf("Expected only the recently indexed chunk, got %v", got)
+	}
+	// Each window is cached separately
+	if got := search(models.SearchFilter{Until: lastWeek}); !slices.Equal(got, []string{"old"}) {
+		t.Errorf("Expected only the chunk indexed before last week, got %v", got)
+	}
+	if mockDB.searchCalls != 3 {
+		t.Errorf("Expected each window to be searched, got %d queries", mockDB.searchCalls)
+	}
+}
+
+// slowEmbeddingsClient embeds after a delay, or when its context is done if context-aware
+type slowEmbeddingsClient struct {
+	delay time.Duration
+}
+
+func (c slowEmbeddingsClient) GenerateEmbedding(text string) ([]float32, error) {
+	time.Sleep(c.delay)
+	return []float32{0.1}, nil
+}
+
+// slowVectorDB blocks searches until their context is done
+type slowVectorDB struct {
+	mockVectorDB
+}
+
+func (db *slowVectorDB) Search(ctx context.Context, embedding []float32, filter models.SearchFilter, limit int, minScore float64) ([]models.CodeChunk, []float64, error) {
+	<-ctx.Done()
+	return nil, nil, ctx.Err()
+}
+
+func TestSearch_QueryTimeout(t *testing.T) {
+	cfg := &config.SearchConfig{MaxResults: 5, SemanticWeight: 1, QueryTimeoutMs: 50}
+
+	tests := []struct {
+		name     string
+		searcher *Searcher
+	}{
+		{"slow embedding", NewSearcher(cfg, slowEmbeddingsClient{delay: 2 * time.Second}, &mockVectorDB{})},
+		{"slow vector search", NewSearcher(cfg, &mockEmbeddingsClient{embeddings: []float32{0.1}}, &slowVectorDB{})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			_, err := tt.searcher.Search(context.Background(), "payment", "/repo")
+			if !errors.Is(err, ErrQueryTimeout) {
+				t.Errorf("Expected ErrQueryTimeout, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Expected the search to stop at the timeout, took %v", elapsed)
+			}
+
+			_, _, err = tt.searcher.SearchRepos(context.Background(), "payment", []string{"/a", "/b"}, SearchOptions{})
+			if !errors.Is(err, ErrQueryTimeout) {
+				t.Errorf("Expected ErrQueryTimeout searching repositories, got %v", err)
+			}
+		})
+	}
+
+	// A caller's own cancellation isn't reported as a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := tests[1].searcher.Search(ctx, "payment", "/repo")
+	if err == nil || errors.Is(err, ErrQueryTimeout) {
+		t.Errorf("Expected the cancellation error, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// similarExclusionPadding is how many extra candidates are first fetched to make up for excluded
+// chunks; when more are excluded, the fetch is doubled until limit results are found
+const similarExclusionPadding = 5
+
+// FindSimilar finds chunks semantically similar to the indexed chunk covering filePath:line
+// The reference chunk's stored vector is reused when available, so no embedding is generated.
+// The reference chunk itself, and chunks overlapping it in the same file, are excluded.
+func (s *Searcher) FindSimilar(ctx context.Context, repoPath, filePath string, line int, limit int) ([]SearchResult, error) {
+	s, err := s.forRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = s.config.MaxResults
+	}
+
+	ref, err := s.vectorDB.FindChunkAt(ctx, repoPath, filePath, line)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up reference chunk: %w", err)
+	}
+	if ref == nil {
+		return nil, fmt.Errorf("no indexed chunk covers %s:%d (is the repository indexed?)", filePath, line)
+	}
+
+	slog.Info("Finding similar code", "file", ref.FilePath, "start", ref.StartLine, "end", ref.EndLine)
+
+	embedding := ref.Embedding
+	if len(embedding) == 0 {
+		embedding, err = s.embeddingsClient.GenerateEmbedding(s.documentPrefix + ref.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate embedding for reference chunk: %w", err)
+		}
+	}
+
+	var results []SearchResult
+	for fetch := limit + similarExclusionPadding; ; fetch *= 2 {
+		chunks, scores, err := s.searchVectors(ctx, embedding, models.SearchFilter{RepoPath: repoPath}, fetch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search vector database: %w", err)
+		}
+		results = similarResults(ref, chunks, scores, limit)
+		// A short page means the repository has no more chunks to fetch
+		if len(results) == limit || len(chunks) < fetch {
+			break
+		}
+	}
+
+	slog.Info("Returning similar chunks", "count", len(results))
+	return results, nil
+}
+
+// similarResults returns up to limit of chunks, excluding ref and the chunks overlapping it
+func similarResults(ref *models.CodeChunk, chunks []models.CodeChunk, scores []float64, limit int) []SearchResult {
+	results := make([]SearchResult, 0, limit)
+	for i, chunk := range chunks {
+		if chunk.ID == ref.ID {
+			continue
+		}
+		// Overlapping chunks of the same file are the same code, not a similar implementation
+		if chunk.FilePath == ref.FilePath && chunk.StartLine <= ref.EndLine && chunk.EndLine >= ref.StartLine {
+			continue
+		}
+
+		results = append(results, SearchResult{
+			Chunk:         chunk,
+			SemanticScore: scores[i],
+			HybridScore:   scores[i],
+		})
+		if len(results) == limit {
+			break
+		}
+	}
+	return results
+}
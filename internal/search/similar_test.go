@@ -0,0 +1,136 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// errAssertNotCalled fails any embedding call, proving the stored vector was reused
+var errAssertNotCalled = errors.New("embedding should not be generated")
+
+func TestFindSimilar(t *testing.T) {
+	chunks := []models.CodeChunk{
+		{ID: "ref", FilePath: "a/Parser.java", StartLine: 10, EndLine: 30, Content: "parse csv", Embedding: []float32{1, 0}},
+		{ID: "ref-part", FilePath: "a/Parser.java", StartLine: 25, EndLine: 40, Content: "parse csv (continued)"},
+		{ID: "dup", FilePath: "b/CsvReader.java", StartLine: 5, EndLine: 25, Content: "parse csv copy"},
+		{ID: "other", FilePath: "a/Parser.java", StartLine: 50, EndLine: 60, Content: "write csv"},
+		{ID: "unrelated", FilePath: "c/Auth.java", StartLine: 1, EndLine: 20, Content: "login"},
+	}
+	mockDB := &mockVectorDB{
+		chunks: chunks,
+		scores: []float64{1.0, 0.97, 0.95, 0.6, 0.2},
+	}
+	mockEmbed := &mockEmbeddingsClient{err: errAssertNotCalled}
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 5}, mockEmbed, mockDB)
+
+	results, err := searcher.FindSimilar(context.Background(), "/repo", "a/Parser.java", 15, 3)
+	if err != nil {
+		t.Fatalf("FindSimilar failed: %v", err)
+	}
+
+	expected := []string{"dup", "other", "unrelated"}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+	for i, id := range expected {
+		if results[i].Chunk.ID != id {
+			t.Errorf("Result %d: expected %s, got %s", i, id, results[i].Chunk.ID)
+		}
+	}
+	for _, result := range results {
+		if result.Chunk.ID == "ref" || result.Chunk.ID == "ref-part" {
+			t.Errorf("Reference chunk %s should be excluded from its own results", result.Chunk.ID)
+		}
+	}
+}
+
+// pagedVectorDB returns at most limit results, like Qdrant
+type pagedVectorDB struct {
+	*mockVectorDB
+	limits []int
+}
+
+func (p *pagedVectorDB) Search(ctx context.Context, embedding []float32, filter models.SearchFilter, limit int, minScore float64) ([]models.CodeChunk, []float64, error) {
+	p.limits = append(p.limits, limit)
+	chunks, scores, err := p.mockVectorDB.Search(ctx, embedding, filter, limit, minScore)
+	if len(chunks) > limit {
+		chunks, scores = chunks[:limit], scores[:limit]
+	}
+	return chunks, scores, err
+}
+
+func TestFindSimilar_FetchesPastExcludedChunks(t *testing.T) {
+	// The reference chunk is overlapped by more chunks than the initial padding
+	chunks := []models.CodeChunk{{ID: "ref", FilePath: "a/Parser.java", StartLine: 1, EndLine: 100, Embedding: []float32{1, 0}}}
+	var scores []float64
+	for i := 0; i < 2*similarExclusionPadding; i++ {
+		chunks = append(chunks, models.CodeChunk{ID: fmt.Sprintf("part%d", i), FilePath: "a/Parser.java", StartLine: i*10 + 1, EndLine: i*10 + 10})
+	}
+	chunks = append(chunks,
+		models.CodeChunk{ID: "dup", FilePath: "b/CsvReader.java", StartLine: 1, EndLine: 20},
+		models.CodeChunk{ID: "other", FilePath: "c/Writer.java", StartLine: 1, EndLine: 20},
+	)
+	for i := range chunks {
+		scores = append(scores, 1-float64(i)/100)
+	}
+	db := &pagedVectorDB{mockVectorDB: &mockVectorDB{chunks: chunks, scores: scores}}
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 5}, &mockEmbeddingsClient{err: errAssertNotCalled}, db)
+
+	results, err := searcher.FindSimilar(context.Background(), "/repo", "a/Parser.java", 1, 2)
+	if err != nil {
+		t.Fatalf("FindSimilar failed: %v", err)
+	}
+	if got := resultIDs(results); got != "dup,other" {
+		t.Errorf("Expected dup,other, got %s", got)
+	}
+	if len(db.limits) < 2 {
+		t.Errorf("Expected the fetch to grow past the excluded chunks, got fetches of %v", db.limits)
+	}
+
+	// A repository with too few chunks returns what it has
+	results, err = searcher.FindSimilar(context.Background(), "/repo", "a/Parser.java", 1, 5)
+	if err != nil {
+		t.Fatalf("FindSimilar failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected the 2 chunks of other files, got %s", resultIDs(results))
+	}
+}
+
+func TestFindSimilar_EmbedsWhenVectorMissing(t *testing.T) {
+	mockDB := &mockVectorDB{
+		chunks: []models.CodeChunk{
+			{ID: "ref", FilePath: "a.js", StartLine: 1, EndLine: 5, Content: "function a() {}"},
+			{ID: "dup", FilePath: "b.js", StartLine: 1, EndLine: 5, Content: "function b() {}"},
+		},
+		scores: []float64{1.0, 0.9},
+	}
+	embeddings := &mockEmbeddingsClient{embeddings: []float32{0.1}}
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 5}, embeddings, mockDB)
+	searcher.SetTaskPrefixes("search_query: ", "search_document: ")
+
+	results, err := searcher.FindSimilar(context.Background(), "/repo", "a.js", 3, 0)
+	if err != nil {
+		t.Fatalf("FindSimilar failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Chunk.ID != "dup" {
+		t.Errorf("Expected only the near-duplicate, got %+v", results)
+	}
+	// The reference chunk is a document, not a query
+	if len(embeddings.texts) != 1 || embeddings.texts[0] != "search_document: function a() {}" {
+		t.Errorf("Expected the reference embedded with the document prefix, got %q", embeddings.texts)
+	}
+}
+
+func TestFindSimilar_NoChunkAtLine(t *testing.T) {
+	searcher := NewSearcher(&config.SearchConfig{MaxResults: 5}, &mockEmbeddingsClient{}, &mockVectorDB{})
+
+	if _, err := searcher.FindSimilar(context.Background(), "/repo", "missing.go", 1, 5); err == nil {
+		t.Error("Expected error when no chunk covers the line")
+	}
+}
@@ -0,0 +1,204 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/ignore"
+)
+
+// Symbol match scores, from strongest to weakest
+const (
+	symbolScoreExact           = 1.0
+	symbolScoreCaseInsensitive = 0.9
+	symbolScorePrefix          = 0.6 // Plus up to 0.2 for how much of the name the query covers
+	symbolScoreSubstring       = 0.3 // Plus up to 0.2 for how much of the name the query covers
+	// symbolClassContextPenalty scales matches on a method's enclosing class,
+	// so the class chunk itself ranks above each of its methods
+	symbolClassContextPenalty = 0.5
+)
+
+const (
+	// DefaultSymbolListLimit is the number of symbols list_symbols returns by default
+	DefaultSymbolListLimit = 200
+	// maxSymbolScan bounds how many matching symbols are collected before sorting
+	maxSymbolScan = 20000
+)
+
+// SymbolFilter narrows the symbols returned by ListSymbols
+type SymbolFilter struct {
+	Name     string // Case-insensitive substring of the function or class name
+	FileGlob string // Pattern on the repository-relative path, same syntax as ignore_patterns
+}
+
+// ListSymbols returns a repository's functions, classes and methods without their content
+// Symbols are sorted by file and line and cut to limit; the second result is the total
+// number of matching symbols. At most maxSymbolScan are collected: truncated reports that more
+// matched, in which case the total is a lower bound and the symbols are the first of those collected.
+func (s *Searcher) ListSymbols(ctx context.Context, repoPath string, filter SymbolFilter, limit int) (symbols []models.CodeChunk, total int, truncated bool, err error) {
+	if limit <= 0 {
+		limit = DefaultSymbolListLimit
+	}
+
+	slog.Info("Listing symbols", "repo", repoPath, "name", filter.Name, "file_glob", filter.FileGlob)
+
+	nameLower := strings.ToLower(strings.TrimSpace(filter.Name))
+	var fileMatcher *ignore.Matcher
+	if filter.FileGlob != "" {
+		fileMatcher = ignore.NewMatcher([]string{filter.FileGlob})
+	}
+
+	err = s.vectorDB.ScrollSymbols(ctx, repoPath, func(chunk models.CodeChunk) bool {
+		if nameLower != "" &&
+			!strings.Contains(strings.ToLower(chunk.FunctionName), nameLower) &&
+			!strings.Contains(strings.ToLower(chunk.ClassName), nameLower) {
+			return true
+		}
+		if fileMatcher != nil && !fileMatcher.ShouldIgnore(relativePath(repoPath, chunk.FilePath)) {
+			return true
+		}
+		if len(symbols) == maxSymbolScan {
+			truncated = true
+			return false
+		}
+		symbols = append(symbols, chunk)
+		return true
+	})
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to list symbols: %w", err)
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].FilePath != symbols[j].FilePath {
+			return symbols[i].FilePath < symbols[j].FilePath
+		}
+		return symbols[i].StartLine < symbols[j].StartLine
+	})
+
+	total = len(symbols)
+	if len(symbols) > limit {
+		symbols = symbols[:limit]
+	}
+
+	slog.Info("Returning symbols", "count", len(symbols), "total", total, "truncated", truncated)
+	return symbols, total, truncated, nil
+}
+
+// relativePath returns filePath relative to repoPath, or filePath itself if it is outside
+func relativePath(repoPath, filePath string) string {
+	rel, err := filepath.Rel(repoPath, filePath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filePath
+	}
+	return rel
+}
+
+// FindSymbol looks up chunks by function or class name instead of vector similarity
+// Exact name matches are fetched first so they are never crowded out by partial matches,
+// then results are ranked by how closely the name matches.
+func (s *Searcher) FindSymbol(ctx context.Context, name string, repoPath string, limit int) ([]SearchResult, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("symbol name is required")
+	}
+	s, err := s.forRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = s.config.MaxResults
+	}
+
+	slog.Info("Finding symbol", "symbol", name, "repo", repoPath)
+
+	candidateLimit := max(s.candidateLimit(), limit)
+	exact, err := s.vectorDB.FindSymbols(ctx, repoPath, name, true, candidateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find symbol: %w", err)
+	}
+	partial, err := s.vectorDB.FindSymbols(ctx, repoPath, name, false, candidateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find symbol: %w", err)
+	}
+
+	results := rankSymbolMatches(name, append(exact, partial...))
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	slog.Info("Returning symbol matches", "count", len(results), "symbol", name)
+	return results, nil
+}
+
+// rankSymbolMatches scores chunks by name similarity, dropping duplicates and non-matches
+func rankSymbolMatches(name string, chunks []models.CodeChunk) []SearchResult {
+	seen := make(map[string]bool, len(chunks))
+	var results []SearchResult
+
+	for _, chunk := range chunks {
+		if seen[chunk.ID] {
+			continue
+		}
+		seen[chunk.ID] = true
+
+		score := symbolMatchScore(name, chunk.FunctionName)
+		classScore := symbolMatchScore(name, chunk.ClassName)
+		if chunk.FunctionName != "" {
+			classScore *= symbolClassContextPenalty
+		}
+		if classScore > score {
+			score = classScore
+		}
+		if score == 0 {
+			continue
+		}
+
+		results = append(results, SearchResult{
+			Chunk:       chunk,
+			ExactMatch:  score == symbolScoreExact,
+			HybridScore: score,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].HybridScore != results[j].HybridScore {
+			return results[i].HybridScore > results[j].HybridScore
+		}
+		if results[i].Chunk.FilePath != results[j].Chunk.FilePath {
+			return results[i].Chunk.FilePath < results[j].Chunk.FilePath
+		}
+		return results[i].Chunk.StartLine < results[j].Chunk.StartLine
+	})
+
+	return results
+}
+
+// symbolMatchScore returns how closely symbol matches the queried name (0 = no match)
+func symbolMatchScore(name, symbol string) float64 {
+	if symbol == "" {
+		return 0
+	}
+	if symbol == name {
+		return symbolScoreExact
+	}
+
+	nameLower := strings.ToLower(name)
+	symbolLower := strings.ToLower(symbol)
+	coverage := float64(len(nameLower)) / float64(len(symbolLower))
+
+	switch {
+	case symbolLower == nameLower:
+		return symbolScoreCaseInsensitive
+	case strings.HasPrefix(symbolLower, nameLower):
+		return symbolScorePrefix + 0.2*coverage
+	case strings.Contains(symbolLower, nameLower):
+		return symbolScoreSubstring + 0.2*coverage
+	default:
+		return 0
+	}
+}
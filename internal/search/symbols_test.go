@@ -0,0 +1,213 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+func newSymbolTestSearcher(chunks []models.CodeChunk) *Searcher {
+	cfg := &config.SearchConfig{MaxResults: 5}
+	return NewSearcher(cfg, &mockEmbeddingsClient{}, &mockVectorDB{chunks: chunks})
+}
+
+func TestFindSymbol(t *testing.T) {
+	chunks := []models.CodeChunk{
+		{ID: "1", FilePath: "UserService.java", StartLine: 1, ClassName: "UserService", ChunkType: models.ChunkTypeClass},
+		{ID: "2", FilePath: "UserService.java", StartLine: 5, ClassName: "UserService", FunctionName: "getUser", ChunkType: models.ChunkTypeMethod},
+		{ID: "3", FilePath: "UserService.java", StartLine: 9, ClassName: "UserService", FunctionName: "getUserById", ChunkType: models.ChunkTypeMethod},
+		{ID: "4", FilePath: "AdminService.java", StartLine: 3, ClassName: "AdminService", FunctionName: "forgetUser", ChunkType: models.ChunkTypeMethod},
+		{ID: "5", FilePath: "util.js", StartLine: 1, FunctionName: "parseConfig", ChunkType: models.ChunkTypeFunction},
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedOrder []string // Expected chunk IDs in order
+		expectExact   string   // ID expected to be flagged as exact match
+	}{
+		{
+			name:          "exact function name ranks first, then prefix, then substring",
+			query:         "getUser",
+			expectedOrder: []string{"2", "3", "4"},
+			expectExact:   "2",
+		},
+		{
+			name:          "case-insensitive name ranks above prefix and substring matches",
+			query:         "getuser",
+			expectedOrder: []string{"2", "3", "4"},
+		},
+		{
+			name:          "prefix match",
+			query:         "parse",
+			expectedOrder: []string{"5"},
+		},
+		{
+			name:          "class chunk ranks above its methods",
+			query:         "UserService",
+			expectedOrder: []string{"1", "2", "3"},
+			expectExact:   "1",
+		},
+		{
+			name:          "no match",
+			query:         "deleteAccount",
+			expectedOrder: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searcher := newSymbolTestSearcher(chunks)
+
+			results, err := searcher.FindSymbol(context.Background(), tt.query, "/test/repo", 10)
+			if err != nil {
+				t.Fatalf("FindSymbol failed: %v", err)
+			}
+
+			if len(results) != len(tt.expectedOrder) {
+				t.Fatalf("Expected %d results, got %d", len(tt.expectedOrder), len(results))
+			}
+			for i, id := range tt.expectedOrder {
+				if results[i].Chunk.ID != id {
+					t.Errorf("Result %d: expected chunk %s, got %s", i, id, results[i].Chunk.ID)
+				}
+				if results[i].ExactMatch != (id == tt.expectExact) {
+					t.Errorf("Result %d: expected ExactMatch=%v", i, id == tt.expectExact)
+				}
+			}
+		})
+	}
+}
+
+func TestFindSymbol_Limit(t *testing.T) {
+	searcher := newSymbolTestSearcher([]models.CodeChunk{
+		{ID: "1", FunctionName: "handleA"},
+		{ID: "2", FunctionName: "handleB"},
+		{ID: "3", FunctionName: "handleC"},
+	})
+
+	results, err := searcher.FindSymbol(context.Background(), "handle", "/test/repo", 2)
+	if err != nil {
+		t.Fatalf("FindSymbol failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results, got %d", len(results))
+	}
+
+	// A limit above the search's own result count is still filled
+	var many []models.CodeChunk
+	for i := 0; i < 40; i++ {
+		many = append(many, models.CodeChunk{ID: fmt.Sprintf("%d", i), FunctionName: fmt.Sprintf("handle%d", i)})
+	}
+	results, err = newSymbolTestSearcher(many).FindSymbol(context.Background(), "handle", "/test/repo", 30)
+	if err != nil {
+		t.Fatalf("FindSymbol failed: %v", err)
+	}
+	if len(results) != 30 {
+		t.Errorf("Expected 30 results, got %d", len(results))
+	}
+
+	if _, err := searcher.FindSymbol(context.Background(), "  ", "/test/repo", 2); err == nil {
+		t.Error("Expected error for empty symbol name")
+	}
+}
+
+func TestSymbolMatchScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		symbol string
+		want   float64
+	}{
+		{"getUser", "getUser", symbolScoreExact},
+		{"getuser", "getUser", symbolScoreCaseInsensitive},
+		{"get", "getUser", symbolScorePrefix + 0.2*3.0/7.0},
+		{"User", "getUser", symbolScoreSubstring + 0.2*4.0/7.0},
+		{"other", "getUser", 0},
+		{"getUser", "", 0},
+	}
+
+	for _, tt := range tests {
+		if got := symbolMatchScore(tt.name, tt.symbol); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("symbolMatchScore(%q, %q) = %.3f, want %.3f", tt.name, tt.symbol, got, tt.want)
+		}
+	}
+}
+
+func TestListSymbols(t *testing.T) {
+	chunks := []models.CodeChunk{
+		{ID: "1", FilePath: "/repo/src/user/UserService.java", StartLine: 1, EndLine: 40, ClassName: "UserService", ChunkType: models.ChunkTypeClass},
+		{ID: "2", FilePath: "/repo/src/user/UserService.java", StartLine: 12, EndLine: 20, FunctionName: "getUser", ClassName: "UserService", ChunkType: models.ChunkTypeMethod},
+		{ID: "3", FilePath: "/repo/src/auth/token.ts", StartLine: 3, EndLine: 9, FunctionName: "validateToken", ChunkType: models.ChunkTypeFunction},
+		{ID: "4", FilePath: "/repo/src/auth/token.ts", StartLine: 1, EndLine: 60, ChunkType: models.ChunkTypeFile},
+		{ID: "5", FilePath: "/repo/test/user.test.ts", StartLine: 5, EndLine: 15, FunctionName: "testGetUser", ChunkType: models.ChunkTypeFunction},
+	}
+	searcher := newSymbolTestSearcher(chunks)
+
+	tests := []struct {
+		name          string
+		filter        SymbolFilter
+		limit         int
+		expectedIDs   string
+		expectedTotal int
+	}{
+		{"all symbols sorted by location", SymbolFilter{}, 0, "3,1,2,5", 4},
+		{"name substring is case-insensitive", SymbolFilter{Name: "GETUSER"}, 0, "2,5", 2},
+		{"name matches the class", SymbolFilter{Name: "userservice"}, 0, "1,2", 2},
+		{"file glob", SymbolFilter{FileGlob: "src/**"}, 0, "3,1,2", 3},
+		{"file glob on the file name", SymbolFilter{FileGlob: "*.ts"}, 0, "3,5", 2},
+		{"name and file glob", SymbolFilter{Name: "user", FileGlob: "src/**"}, 0, "1,2", 2},
+		{"limit keeps the total", SymbolFilter{}, 2, "3,1", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbols, total, truncated, err := searcher.ListSymbols(context.Background(), "/repo", tt.filter, tt.limit)
+			if err != nil {
+				t.Fatalf("ListSymbols failed: %v", err)
+			}
+			if truncated {
+				t.Error("Expected the listing not to be truncated")
+			}
+			ids := make([]string, len(symbols))
+			for i, symbol := range symbols {
+				ids[i] = symbol.ID
+			}
+			if got := strings.Join(ids, ","); got != tt.expectedIDs {
+				t.Errorf("Expected symbols %s, got %s", tt.expectedIDs, got)
+			}
+			if total != tt.expectedTotal {
+				t.Errorf("Expected total %d, got %d", tt.expectedTotal, total)
+			}
+		})
+	}
+}
+
+func TestListSymbols_Truncated(t *testing.T) {
+	chunks := make([]models.CodeChunk, maxSymbolScan+1)
+	for i := range chunks {
+		chunks[i] = models.CodeChunk{ID: fmt.Sprintf("%d", i), FilePath: "/repo/main.go", StartLine: i + 1, FunctionName: fmt.Sprintf("handle%d", i)}
+	}
+	searcher := newSymbolTestSearcher(chunks)
+
+	symbols, total, truncated, err := searcher.ListSymbols(context.Background(), "/repo", SymbolFilter{}, 10)
+	if err != nil {
+		t.Fatalf("ListSymbols failed: %v", err)
+	}
+	if !truncated || total != maxSymbolScan || len(symbols) != 10 {
+		t.Errorf("Expected 10 of %d symbols, truncated, got %d of %d (truncated %v)", maxSymbolScan, len(symbols), total, truncated)
+	}
+
+	// Exactly maxSymbolScan symbols are all listed
+	symbols, total, truncated, err = newSymbolTestSearcher(chunks[:maxSymbolScan]).ListSymbols(context.Background(), "/repo", SymbolFilter{}, 10)
+	if err != nil {
+		t.Fatalf("ListSymbols failed: %v", err)
+	}
+	if truncated || total != maxSymbolScan {
+		t.Errorf("Expected all %d symbols, got %d (truncated %v)", maxSymbolScan, total, truncated)
+	}
+}
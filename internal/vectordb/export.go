@@ -0,0 +1,118 @@
+package vectordb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+)
+
+// exportBatchSize is how many points each scroll request returns when exporting
+const exportBatchSize = 512
+
+// importBatchSize is how many chunks each upsert request carries when importing
+const importBatchSize = 256
+
+// ExportRepo writes every chunk of a repository to w as JSON Lines, one models.CodeChunk per line
+// Vectors are included when withVectors is set, so the export can be imported without re-embedding.
+// Returns the number of chunks written.
+func (c *Client) ExportRepo(ctx context.Context, w io.Writer, repoPath string, withVectors bool) (int, error) {
+	return writeChunksJSONL(w, func(visit func(chunk models.CodeChunk) bool) error {
+		return c.ScrollByRepo(ctx, repoPath, exportBatchSize, withVectors, visit)
+	})
+}
+
+// writeChunksJSONL encodes each chunk produced by scroll as one line of JSON
+func writeChunksJSONL(w io.Writer, scroll func(visit func(chunk models.CodeChunk) bool) error) (int, error) {
+	encoder := json.NewEncoder(w)
+	count := 0
+	var writeErr error
+
+	err := scroll(func(chunk models.CodeChunk) bool {
+		if writeErr = encoder.Encode(chunk); writeErr != nil {
+			return false
+		}
+		count++
+		return true
+	})
+	if writeErr != nil {
+		return count, fmt.Errorf("failed to write chunk: %w", writeErr)
+	}
+	if err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// ImportChunks reads chunks written by ExportRepo from r and upserts them with their stored vectors
+// Every chunk must carry a vector matching the collection's dimension; an invalid line stops the
+// import, leaving the batches before it imported. Returns the number of chunks imported.
+func (c *Client) ImportChunks(ctx context.Context, r io.Reader) (int, error) {
+	return readChunksJSONL(r, c.config.VectorSize, importBatchSize, func(chunks []models.CodeChunk) error {
+		return c.UpsertChunks(ctx, chunks)
+	})
+}
+
+// readChunksJSONL decodes one chunk per line of JSON, validates it, and passes batches to upsert
+func readChunksJSONL(r io.Reader, vectorSize, batchSize int, upsert func(chunks []models.CodeChunk) error) (int, error) {
+	decoder := json.NewDecoder(r)
+	batch := make([]models.CodeChunk, 0, batchSize)
+	imported := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := upsert(batch); err != nil {
+			return fmt.Errorf("failed to import chunks: %w", err)
+		}
+		imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for n := 1; ; n++ {
+		var chunk models.CodeChunk
+		if err := decoder.Decode(&chunk); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return imported, fmt.Errorf("failed to decode chunk %d: %w", n, err)
+		}
+		if err := validateImportedChunk(chunk, vectorSize); err != nil {
+			return imported, fmt.Errorf("invalid chunk %d: %w", n, err)
+		}
+
+		batch = append(batch, chunk)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return imported, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return imported, err
+	}
+	return imported, nil
+}
+
+// validateImportedChunk checks that a chunk can be stored as is, without re-embedding
+func validateImportedChunk(chunk models.CodeChunk, vectorSize int) error {
+	if chunk.ID == "" {
+		return errors.New("missing id")
+	}
+	if chunk.RepoPath == "" {
+		return errors.New("missing repo_path")
+	}
+	if len(chunk.Embedding) == 0 {
+		return errors.New("missing embedding; export with vectors included")
+	}
+	if len(chunk.Embedding) != vectorSize {
+		return fmt.Errorf("embedding has %d dimensions, but the collection expects %d", len(chunk.Embedding), vectorSize)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package vectordb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+func TestWriteChunksJSONL(t *testing.T) {
+	chunks := []models.CodeChunk{
+		{ID: "a", RepoPath: "/repo", FilePath: "/repo/a.go", Content: "func A() {}\n", FunctionName: "A"},
+		{ID: "b", RepoPath: "/repo", FilePath: "/repo/b.go", Content: "func B() {}\n", Embedding: []float32{0.5, 1}},
+	}
+	scroll := func(visit func(chunk models.CodeChunk) bool) error {
+		for _, chunk := range chunks {
+			if !visit(chunk) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	count, err := writeChunksJSONL(&buf, scroll)
+	if err != nil {
+		t.Fatalf("writeChunksJSONL failed: %v", err)
+	}
+	if count != len(chunks) {
+		t.Errorf("Expected %d chunks written, got %d", len(chunks), count)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != len(chunks) {
+		t.Fatalf("Expected one line per chunk, got %d lines", len(lines))
+	}
+	var decoded models.CodeChunk
+	if err := json.Unmarshal([]byte(lines[1]), &decoded); err != nil {
+		t.Fatalf("Failed to decode line: %v", err)
+	}
+	if decoded.ID != "b" || decoded.Content != chunks[1].Content || len(decoded.Embedding) != 2 {
+		t.Errorf("Chunk did not round-trip: %+v", decoded)
+	}
+
+	// Scroll errors are returned as is
+	scrollErr := errors.New("scroll failed")
+	if _, err := writeChunksJSONL(&buf, func(func(models.CodeChunk) bool) error { return scrollErr }); !errors.Is(err, scrollErr) {
+		t.Errorf("Expected the scroll error, got %v", err)
+	}
+}
+
+func TestReadChunksJSONL(t *testing.T) {
+	exported := []models.CodeChunk{
+		{ID: "a", RepoPath: "/repo", FilePath: "/repo/a.go", Content: "func A() {}\n", Embedding: []float32{1, 0, 0}},
+		{ID: "b", RepoPath: "/repo", FilePath: "/repo/b.go", Content: "func B() {}\n", Embedding: []float32{0, 1, 0}},
+		{ID: "c", RepoPath: "/repo", FilePath: "/repo/c.go", Content: "func C() {}\n", Embedding: []float32{0, 0, 1}},
+	}
+	var buf bytes.Buffer
+	if _, err := writeChunksJSONL(&buf, func(visit func(chunk models.CodeChunk) bool) error {
+		for _, chunk := range exported {
+			visit(chunk)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("writeChunksJSONL failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	var batches [][]models.CodeChunk
+	upsert := func(chunks []models.CodeChunk) error {
+		batches = append(batches, append([]models.CodeChunk(nil), chunks...))
+		return nil
+	}
+
+	count, err := readChunksJSONL(bytes.NewReader(data), 3, 2, upsert)
+	if err != nil {
+		t.Fatalf("readChunksJSONL failed: %v", err)
+	}
+	if count != len(exported) {
+		t.Errorf("Expected %d chunks imported, got %d", len(exported), count)
+	}
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Errorf("Expected batches of 2 and 1, got %d batches", len(batches))
+	}
+	if batches[1][0].Content != exported[2].Content || batches[1][0].Embedding[2] != 1 {
+		t.Errorf("Chunk did not round-trip: %+v", batches[1][0])
+	}
+
+	// A vector size that doesn't match the collection is rejected before anything is upserted
+	batches = nil
+	_, err = readChunksJSONL(bytes.NewReader(data), 768, 2, upsert)
+	if err == nil || !strings.Contains(err.Error(), "dimensions") {
+		t.Errorf("Expected a dimension mismatch error, got %v", err)
+	}
+	if len(batches) != 0 {
+		t.Errorf("Expected nothing to be imported, got %d batches", len(batches))
+	}
+
+	// Chunks exported without vectors can't be imported
+	noVector := `{"id":"d","repo_path":"/repo","file_path":"/repo/d.go"}` + "\n"
+	if _, err := readChunksJSONL(strings.NewReader(noVector), 3, 2, upsert); err == nil {
+		t.Error("Expected an error for a chunk without an embedding")
+	}
+
+	if _, err := readChunksJSONL(strings.NewReader("not json\n"), 3, 2, upsert); err == nil {
+		t.Error("Expected an error for malformed JSON")
+	}
+}
+
+func TestExportRepo_ExportsAllChunks(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	c := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// More chunks than fit in one scroll batch, plus a chunk of another repository
+	embedding := make([]float32, cfg.VectorSize)
+	embedding[0] = 1
+	upserted := exportBatchSize + 10
+	var chunks []models.CodeChunk
+	for i := 0; i < upserted; i++ {
+		chunks = append(chunks, models.CodeChunk{
+			ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/a.go", Content: fmt.Sprintf("func F%d() {}", i),
+			StartLine: i + 1, EndLine: i + 1, Embedding: embedding,
+		})
+	}
+	chunks = append(chunks, models.CodeChunk{ID: GenerateUUID(), RepoPath: "/other", FilePath: "/other/b.go", Embedding: embedding})
+	if err := c.UpsertChunks(ctx, chunks); err != nil {
+		t.Fatalf("UpsertChunks failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	count, err := c.ExportRepo(ctx, &buf, "/repo", true)
+	if err != nil {
+		t.Fatalf("ExportRepo failed: %v", err)
+	}
+	if count != upserted {
+		t.Errorf("Expected %d chunks exported, got %d", upserted, count)
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var chunk models.CodeChunk
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatalf("Failed to decode exported line: %v", err)
+		}
+		if chunk.RepoPath != "/repo" || chunk.Content == "" {
+			t.Errorf("Unexpected chunk exported: %+v", chunk)
+		}
+		if len(chunk.Embedding) != cfg.VectorSize {
+			t.Errorf("Expected the vector to be exported, got %d dimensions", len(chunk.Embedding))
+		}
+		lines++
+	}
+	if lines != upserted {
+		t.Errorf("Expected %d lines, got %d", upserted, lines)
+	}
+}
+
+func TestImportChunks_RoundTrip(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	source := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	embedding := make([]float32, cfg.VectorSize)
+	embedding[0] = 1
+	var chunks []models.CodeChunk
+	for i := 0; i < importBatchSize+5; i++ {
+		chunks = append(chunks, models.CodeChunk{
+			ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/a.go", Content: fmt.Sprintf("func F%d() {}", i),
+			StartLine: i + 1, EndLine: i + 1, FunctionName: fmt.Sprintf("F%d", i), Embedding: embedding,
+		})
+	}
+	if err := source.UpsertChunks(ctx, chunks); err != nil {
+		t.Fatalf("UpsertChunks failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := source.ExportRepo(ctx, &buf, "/repo", true); err != nil {
+		t.Fatalf("ExportRepo failed: %v", err)
+	}
+	exported := buf.Bytes()
+
+	target := newTestClient(t, cfg)
+	count, err := target.ImportChunks(ctx, bytes.NewReader(exported))
+	if err != nil {
+		t.Fatalf("ImportChunks failed: %v", err)
+	}
+	if count != len(chunks) {
+		t.Errorf("Expected %d chunks imported, got %d", len(chunks), count)
+	}
+
+	contents := make(map[string]string, len(chunks))
+	for _, chunk := range chunks {
+		contents[chunk.ID] = chunk.Content
+	}
+	seen := 0
+	err = target.ScrollByRepo(ctx, "/repo", 0, false, func(chunk models.CodeChunk) bool {
+		seen++
+		if contents[chunk.ID] != chunk.Content {
+			t.Errorf("Chunk %s content not preserved: %q", chunk.ID, chunk.Content)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ScrollByRepo failed: %v", err)
+	}
+	if seen != len(chunks) {
+		t.Errorf("Expected %d chunks in the target collection, got %d", len(chunks), seen)
+	}
+
+	// A collection with a different dimension rejects the import
+	mismatched := cfg
+	mismatched.VectorSize = cfg.VectorSize / 2
+	other := newTestClient(t, mismatched)
+	if _, err := other.ImportChunks(ctx, bytes.NewReader(exported)); err == nil {
+		t.Error("Expected an import into a collection of a different dimension to fail")
+	}
+}
@@ -2,8 +2,14 @@ package vectordb
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jamaly87/codebase-semantic-search/internal/models"
@@ -11,11 +17,47 @@ import (
 	"github.com/qdrant/go-client/qdrant"
 )
 
+// chunkLookupLimit bounds how many overlapping chunks are considered when looking up a chunk by line
+const chunkLookupLimit = 20
+
+// symbolPageSize is how many points each scroll request returns when listing symbols
+const symbolPageSize = 256
+
+// scalarQuantile excludes extreme values when computing int8 quantization bounds
+const scalarQuantile = 0.99
+
+// ErrCollectionNotFound is returned by Initialize when the collection is missing and
+// auto-creation is disabled
+var ErrCollectionNotFound = errors.New("collection does not exist")
+
+// ErrDistanceMismatch is returned by Initialize when the existing collection was created with a
+// distance metric other than vectordb.distance_metric
+var ErrDistanceMismatch = errors.New("collection distance metric differs from the configured one")
+
+// payloadIndex describes a payload field index used by filtered searches and lookups
+type payloadIndex struct {
+	field     string
+	fieldType qdrant.FieldType
+}
+
+// payloadIndexes are the payload fields that filters match on
+var payloadIndexes = []payloadIndex{
+	{field: "repo_path", fieldType: qdrant.FieldType_FieldTypeKeyword},
+	{field: "file_path", fieldType: qdrant.FieldType_FieldTypeKeyword},
+	{field: "function_name", fieldType: qdrant.FieldType_FieldTypeKeyword},
+	{field: "class_name", fieldType: qdrant.FieldType_FieldTypeKeyword},
+	{field: "start_line", fieldType: qdrant.FieldType_FieldTypeInteger},
+	{field: "end_line", fieldType: qdrant.FieldType_FieldTypeInteger},
+	{field: indexedAtKey, fieldType: qdrant.FieldType_FieldTypeInteger},
+}
+
 // Client represents a Qdrant vector database client
 type Client struct {
-	config     *config.VectorDBConfig
-	client     *qdrant.Client
-	collection string
+	config       *config.VectorDBConfig
+	client       *qdrant.Client
+	collection   string
+	maxRetries   int           // Retries per operation on a transient error
+	retryBackoff time.Duration // Initial backoff, doubled after each retry
 }
 
 // NewClient creates a new Qdrant client
@@ -33,17 +75,37 @@ func NewClient(cfg *config.VectorDBConfig) (*Client, error) {
 	}
 
 	c := &Client{
-		config:     cfg,
-		client:     client,
-		collection: cfg.CollectionName,
+		config:       cfg,
+		client:       client,
+		collection:   cfg.CollectionName,
+		maxRetries:   cfg.MaxRetries,
+		retryBackoff: time.Duration(cfg.RetryBackoffMs) * time.Millisecond,
 	}
 
 	return c, nil
 }
 
-// Initialize initializes the Qdrant database and creates collections
+// CollectionExists reports whether the client's collection has been created
+func (c *Client) CollectionExists(ctx context.Context) (bool, error) {
+	exists, err := c.client.CollectionExists(ctx, c.collection)
+	if err != nil {
+		return false, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+	return exists, nil
+}
+
+// DeleteCollection deletes the client's collection and every chunk stored in it
+func (c *Client) DeleteCollection(ctx context.Context) error {
+	if err := c.client.DeleteCollection(ctx, c.collection); err != nil {
+		return fmt.Errorf("failed to delete collection %s: %w", c.collection, err)
+	}
+	return nil
+}
+
+// Initialize initializes the Qdrant database and creates the collection if missing,
+// unless vectordb.auto_create_collection is disabled
 func (c *Client) Initialize(ctx context.Context) error {
-	log.Printf("Initializing Qdrant collection: %s", c.collection)
+	slog.Info("Initializing Qdrant collection", "collection", c.collection)
 
 	// Check if collection exists
 	exists, err := c.client.CollectionExists(ctx, c.collection)
@@ -52,29 +114,94 @@ func (c *Client) Initialize(ctx context.Context) error {
 	}
 
 	if exists {
-		log.Printf("Collection %s already exists", c.collection)
-		return nil
+		slog.Info("Collection already exists", "collection", c.collection)
+		// The metric is fixed when a collection is created, so a changed setting would be ignored
+		existing, err := c.DistanceMetric(ctx)
+		if err != nil {
+			return err
+		}
+		return checkDistanceMetric(c.collection, c.configuredDistanceMetric(), existing)
+	}
+	if !c.config.AutoCreateCollection {
+		return fmt.Errorf("%w: %q (auto_create_collection is disabled; create it with %d dimensions first)",
+			ErrCollectionNotFound, c.collection, c.config.VectorSize)
 	}
 
 	// Create collection
-	err = c.client.CreateCollection(ctx, &qdrant.CreateCollection{
+	err = c.client.CreateCollection(ctx, c.createCollectionRequest())
+
+	if err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+
+	if _, err := c.EnsureIndexes(ctx); err != nil {
+		return err
+	}
+
+	slog.Info("Created collection", "collection", c.collection, "dimensions", c.config.VectorSize,
+		"on_disk_payload", c.config.OnDiskPayload, "scalar_quantization", c.config.ScalarQuantization)
+	return nil
+}
+
+// EnsureIndexes creates any missing payload indexes and returns the fields it indexed
+// Existing indexes are left alone, so this is safe to run repeatedly; it upgrades
+// collections created before payload indexes were added without re-embedding.
+func (c *Client) EnsureIndexes(ctx context.Context) ([]string, error) {
+	info, err := c.client.GetCollectionInfo(ctx, c.collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection info: %w", err)
+	}
+
+	var created []string
+	for _, index := range payloadIndexes {
+		if _, exists := info.GetPayloadSchema()[index.field]; exists {
+			continue
+		}
+
+		_, err := c.client.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
+			CollectionName: c.collection,
+			FieldName:      index.field,
+			FieldType:      index.fieldType.Enum(),
+			Wait:           qdrant.PtrOf(true),
+		})
+		if err != nil {
+			return created, fmt.Errorf("failed to create payload index on %s: %w", index.field, err)
+		}
+		slog.Info("Created payload index", "collection", c.collection, "field", index.field)
+		created = append(created, index.field)
+	}
+
+	return created, nil
+}
+
+// createCollectionRequest builds the collection definition from the storage settings
+func (c *Client) createCollectionRequest() *qdrant.CreateCollection {
+	vectorParams := &qdrant.VectorParams{
+		Size:     uint64(c.config.VectorSize),
+		Distance: c.getDistanceMetric(),
+	}
+
+	req := &qdrant.CreateCollection{
 		CollectionName: c.collection,
 		VectorsConfig: &qdrant.VectorsConfig{
 			Config: &qdrant.VectorsConfig_Params{
-				Params: &qdrant.VectorParams{
-					Size:     uint64(c.config.VectorSize),
-					Distance: c.getDistanceMetric(),
-				},
+				Params: vectorParams,
 			},
 		},
-	})
+		OnDiskPayload: qdrant.PtrOf(c.config.OnDiskPayload),
+	}
 
-	if err != nil {
-		return fmt.Errorf("failed to create collection: %w", err)
+	if c.config.ScalarQuantization {
+		// Search uses the int8 vectors in RAM; originals live on disk for rescoring
+		vectorParams.OnDisk = qdrant.PtrOf(true)
+		req.QuantizationConfig = qdrant.NewQuantizationScalar(&qdrant.ScalarQuantization{
+			Type:      qdrant.QuantizationType_Int8,
+			Quantile:  qdrant.PtrOf(float32(scalarQuantile)),
+			AlwaysRam: qdrant.PtrOf(true),
+		})
 	}
 
-	log.Printf("Created collection %s with %d dimensions", c.collection, c.config.VectorSize)
-	return nil
+	return req
 }
 
 // UpsertChunks inserts or updates code chunks in the vector database
@@ -83,23 +210,15 @@ func (c *Client) UpsertChunks(ctx context.Context, chunks []models.CodeChunk) er
 		return nil
 	}
 
-	log.Printf("Upserting %d chunks to Qdrant...", len(chunks))
+	slog.Debug("Upserting chunks to Qdrant", "chunks", len(chunks))
 
 	// Convert chunks to Qdrant points
 	points := make([]*qdrant.PointStruct, len(chunks))
 
 	for i, chunk := range chunks {
-		// Create payload
-		payload := map[string]*qdrant.Value{
-			"repo_path":     qdrant.NewValueString(chunk.RepoPath),
-			"file_path":     qdrant.NewValueString(chunk.FilePath),
-			"chunk_type":    qdrant.NewValueString(string(chunk.ChunkType)),
-			"content":       qdrant.NewValueString(chunk.Content),
-			"language":      qdrant.NewValueString(chunk.Language),
-			"start_line":    qdrant.NewValueInt(int64(chunk.StartLine)),
-			"end_line":      qdrant.NewValueInt(int64(chunk.EndLine)),
-			"function_name": qdrant.NewValueString(chunk.FunctionName),
-			"class_name":    qdrant.NewValueString(chunk.ClassName),
+		payload, err := chunkPayload(chunk)
+		if err != nil {
+			return err
 		}
 
 		// Convert embedding to []float32 if needed
@@ -124,21 +243,24 @@ func (c *Client) UpsertChunks(ctx context.Context, chunks []models.CodeChunk) er
 	}
 
 	// Upsert points
-	_, err := c.client.Upsert(ctx, &qdrant.UpsertPoints{
-		CollectionName: c.collection,
-		Points:         points,
+	err := c.withRetry(ctx, "upsert", func() error {
+		_, err := c.client.Upsert(ctx, &qdrant.UpsertPoints{
+			CollectionName: c.collection,
+			Points:         points,
+		})
+		return err
 	})
 
 	if err != nil {
 		return fmt.Errorf("failed to upsert points: %w", err)
 	}
 
-	log.Printf("Successfully upserted %d chunks", len(chunks))
+	slog.Debug("Upserted chunks", "chunks", len(chunks))
 	return nil
 }
 
 // Search performs a vector similarity search
-func (c *Client) Search(ctx context.Context, embedding []float32, repoPath string, limit int) ([]models.CodeChunk, []float64, error) {
+func (c *Client) Search(ctx context.Context, embedding []float32, filter models.SearchFilter, limit int, minScore float64) ([]models.CodeChunk, []float64, error) {
 	if limit <= 0 {
 		limit = 5
 	}
@@ -156,34 +278,39 @@ func (c *Client) Search(ctx context.Context, embedding []float32, repoPath strin
 		WithPayload:    &qdrant.WithPayloadSelector{SelectorOptions: &qdrant.WithPayloadSelector_Enable{Enable: true}},
 	}
 
-	// Add repo filter if specified
-	if repoPath != "" {
-		queryPoints.Filter = &qdrant.Filter{
-			Must: []*qdrant.Condition{
-				{
-					ConditionOneOf: &qdrant.Condition_Field{
-						Field: &qdrant.FieldCondition{
-							Key: "repo_path",
-							Match: &qdrant.Match{
-								MatchValue: &qdrant.Match_Keyword{
-									Keyword: repoPath,
-								},
-							},
-						},
-					},
-				},
-			},
-		}
+	// Let Qdrant drop clearly irrelevant candidates instead of transferring them
+	if minScore > 0 {
+		queryPoints.ScoreThreshold = qdrant.PtrOf(float32(minScore))
+	}
+
+	// Add repo and file filters if specified
+	var must []*qdrant.Condition
+	if filter.RepoPath != "" {
+		must = append(must, qdrant.NewMatchKeyword("repo_path", filter.RepoPath))
+	}
+	if filter.FilePath != "" {
+		must = append(must, qdrant.NewMatchKeyword("file_path", filter.FilePath))
+	}
+	if condition := indexedAtCondition(filter); condition != nil {
+		must = append(must, condition)
+	}
+	if len(must) > 0 {
+		queryPoints.Filter = &qdrant.Filter{Must: must}
 	}
 
 	// Execute search
-	results, err := c.client.Query(ctx, queryPoints)
+	var results []*qdrant.ScoredPoint
+	err := c.withRetry(ctx, "search", func() error {
+		var err error
+		results, err = c.client.Query(ctx, queryPoints)
+		return err
+	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to search: %w", err)
 	}
 
 	if len(results) == 0 {
-		log.Printf("No results found for query")
+		slog.Debug("No results found for query")
 		return []models.CodeChunk{}, []float64{}, nil
 	}
 
@@ -194,28 +321,388 @@ func (c *Client) Search(ctx context.Context, embedding []float32, repoPath strin
 	for i, result := range results {
 		// Extract score
 		scores[i] = float64(result.Score)
+		chunks[i] = chunkFromPayload(result.Id.GetUuid(), result.Payload)
+	}
+
+	slog.Debug("Found results for query", "count", len(chunks), "top_score", scores[0])
+	return chunks, scores, nil
+}
+
+// FindSymbols returns chunks whose function_name or class_name matches name, without vector search
+// With exact set, names must match exactly, through the keyword indexes on those fields; otherwise
+// any name containing the text, ignoring case, matches. Pages are scrolled until limit chunks
+// match or the repository is exhausted.
+func (c *Client) FindSymbols(ctx context.Context, repoPath, name string, exact bool, limit int) ([]models.CodeChunk, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	filter := &qdrant.Filter{}
+	if exact {
+		filter.Should = []*qdrant.Condition{
+			qdrant.NewMatchKeyword("function_name", name),
+			qdrant.NewMatchKeyword("class_name", name),
+		}
+	}
+	if repoPath != "" {
+		filter.Must = []*qdrant.Condition{qdrant.NewMatchKeyword("repo_path", repoPath)}
+	}
+
+	// Qdrant only matches text case-sensitively, so partial matches are checked here
+	nameLower := strings.ToLower(name)
+	var chunks []models.CodeChunk
+	err := c.scroll(ctx, filter, symbolPageSize, false, func(chunk models.CodeChunk) bool {
+		if !exact &&
+			!strings.Contains(strings.ToLower(chunk.FunctionName), nameLower) &&
+			!strings.Contains(strings.ToLower(chunk.ClassName), nameLower) {
+			return true
+		}
+		chunks = append(chunks, chunk)
+		return len(chunks) < limit
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find symbols: %w", err)
+	}
 
-		// Extract payload
-		payload := result.Payload
-
-		chunk := models.CodeChunk{
-			ID:           result.Id.GetUuid(),
-			RepoPath:     payload["repo_path"].GetStringValue(),
-			FilePath:     payload["file_path"].GetStringValue(),
-			ChunkType:    models.ChunkType(payload["chunk_type"].GetStringValue()),
-			Content:      payload["content"].GetStringValue(),
-			Language:     payload["language"].GetStringValue(),
-			StartLine:    int(payload["start_line"].GetIntegerValue()),
-			EndLine:      int(payload["end_line"].GetIntegerValue()),
-			FunctionName: payload["function_name"].GetStringValue(),
-			ClassName:    payload["class_name"].GetStringValue(),
+	return chunks, nil
+}
+
+// ScrollSymbols pages through a repository's named chunks (those with a function or class name)
+// Chunks are passed to visit without their content or vector, in no particular order;
+// scrolling stops early when visit returns false.
+func (c *Client) ScrollSymbols(ctx context.Context, repoPath string, visit func(chunk models.CodeChunk) bool) error {
+	limit := uint32(symbolPageSize)
+	filter := &qdrant.Filter{
+		Must: []*qdrant.Condition{qdrant.NewMatchKeyword("repo_path", repoPath)},
+	}
+	payload := qdrant.NewWithPayloadInclude("repo_path", "file_path", "chunk_type", "language",
+		"start_line", "end_line", "function_name", "class_name")
+
+	var offset *qdrant.PointId
+	for {
+		points, next, err := c.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: c.collection,
+			Filter:         filter,
+			Limit:          &limit,
+			Offset:         offset,
+			WithPayload:    payload,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scroll symbols: %w", err)
 		}
 
-		chunks[i] = chunk
+		for _, point := range points {
+			chunk := chunkFromPayload(point.Id.GetUuid(), point.Payload)
+			if chunk.FunctionName == "" && chunk.ClassName == "" {
+				continue
+			}
+			if !visit(chunk) {
+				return nil
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+		offset = next
 	}
+}
 
-	log.Printf("Found %d results for query (top score: %.3f)", len(chunks), scores[0])
-	return chunks, scores, nil
+// ScrollByRepo pages through every chunk of a repository, or of the collection when repoPath
+// is empty, batch points per request
+// Chunks are passed to visit with their full payload, and with their vector when withVectors is set;
+// scrolling stops early when visit returns false.
+func (c *Client) ScrollByRepo(ctx context.Context, repoPath string, batch int, withVectors bool, visit func(chunk models.CodeChunk) bool) error {
+	var filter *qdrant.Filter
+	if repoPath != "" {
+		filter = &qdrant.Filter{
+			Must: []*qdrant.Condition{qdrant.NewMatchKeyword("repo_path", repoPath)},
+		}
+	}
+	return c.scroll(ctx, filter, batch, withVectors, visit)
+}
+
+// scroll pages through every chunk matching filter, batch points per request, as ScrollByRepo does
+func (c *Client) scroll(ctx context.Context, filter *qdrant.Filter, batch int, withVectors bool, visit func(chunk models.CodeChunk) bool) error {
+	if batch <= 0 {
+		batch = symbolPageSize
+	}
+	limit := uint32(batch)
+
+	var offset *qdrant.PointId
+	for {
+		points, next, err := c.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: c.collection,
+			Filter:         filter,
+			Limit:          &limit,
+			Offset:         offset,
+			WithPayload:    qdrant.NewWithPayload(true),
+			WithVectors:    qdrant.NewWithVectors(withVectors),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scroll chunks: %w", err)
+		}
+
+		for _, point := range points {
+			chunk := chunkFromPayload(point.Id.GetUuid(), point.Payload)
+			if withVectors {
+				chunk.Embedding = c.storedVector(chunk.ID, point.Vectors)
+			}
+			if !visit(chunk) {
+				return nil
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+		offset = next
+	}
+}
+
+// FindChunkAt returns the narrowest chunk of filePath that covers line, including its stored vector
+// Returns nil if no indexed chunk covers the line.
+func (c *Client) FindChunkAt(ctx context.Context, repoPath, filePath string, line int) (*models.CodeChunk, error) {
+	limit := uint32(chunkLookupLimit)
+	lineValue := float64(line)
+
+	filter := fileFilter(repoPath, filePath)
+	filter.Must = append(filter.Must,
+		qdrant.NewRange("start_line", &qdrant.Range{Lte: &lineValue}),
+		qdrant.NewRange("end_line", &qdrant.Range{Gte: &lineValue}),
+	)
+
+	points, err := c.client.Scroll(ctx, &qdrant.ScrollPoints{
+		CollectionName: c.collection,
+		Filter:         filter,
+		Limit:          &limit,
+		WithPayload:    qdrant.NewWithPayload(true),
+		WithVectors:    qdrant.NewWithVectors(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find chunk: %w", err)
+	}
+
+	var best *models.CodeChunk
+	for _, point := range points {
+		chunk := chunkFromPayload(point.Id.GetUuid(), point.Payload)
+		chunk.Embedding = c.storedVector(chunk.ID, point.Vectors)
+		if best == nil || chunk.EndLine-chunk.StartLine < best.EndLine-best.StartLine {
+			best = &chunk
+		}
+	}
+
+	return best, nil
+}
+
+// AdjacentChunks returns the chunks of filePath immediately before and after the chunk spanning
+// startLine-endLine, nil where there is none
+// The chunk before is the one starting last before startLine, the chunk after the one starting
+// first after it; chunks enclosing the span (e.g. a class summary around a method) or nested in
+// it are skipped. Both are ordered by start_line, which needs its payload index.
+func (c *Client) AdjacentChunks(ctx context.Context, repoPath, filePath string, startLine, endLine int) (*models.CodeChunk, *models.CodeChunk, error) {
+	start, end := float64(startLine), float64(endLine)
+
+	before := fileFilter(repoPath, filePath)
+	before.Must = append(before.Must,
+		qdrant.NewRange("start_line", &qdrant.Range{Lt: &start}),
+		qdrant.NewRange("end_line", &qdrant.Range{Lt: &end}),
+	)
+	previous, err := c.firstChunk(ctx, before, qdrant.Direction_Desc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find the chunk before: %w", err)
+	}
+
+	after := fileFilter(repoPath, filePath)
+	after.Must = append(after.Must,
+		qdrant.NewRange("start_line", &qdrant.Range{Gt: &start}),
+		qdrant.NewRange("end_line", &qdrant.Range{Gt: &end}),
+	)
+	next, err := c.firstChunk(ctx, after, qdrant.Direction_Asc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find the chunk after: %w", err)
+	}
+
+	return previous, next, nil
+}
+
+// firstChunk returns the first chunk matching filter in start_line order, or nil
+func (c *Client) firstChunk(ctx context.Context, filter *qdrant.Filter, direction qdrant.Direction) (*models.CodeChunk, error) {
+	limit := uint32(1)
+	points, err := c.client.Scroll(ctx, &qdrant.ScrollPoints{
+		CollectionName: c.collection,
+		Filter:         filter,
+		Limit:          &limit,
+		OrderBy:        &qdrant.OrderBy{Key: "start_line", Direction: &direction},
+		WithPayload:    qdrant.NewWithPayload(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, nil
+	}
+	chunk := chunkFromPayload(points[0].Id.GetUuid(), points[0].Payload)
+	return &chunk, nil
+}
+
+// GetChunkByID retrieves a single chunk, including its stored vector, by point ID
+// The boolean result is false when no point with that ID exists.
+func (c *Client) GetChunkByID(ctx context.Context, id string) (*models.CodeChunk, bool, error) {
+	points, err := c.client.Get(ctx, &qdrant.GetPoints{
+		CollectionName: c.collection,
+		Ids:            []*qdrant.PointId{qdrant.NewIDUUID(id)},
+		WithPayload:    qdrant.NewWithPayload(true),
+		WithVectors:    qdrant.NewWithVectors(true),
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get chunk %s: %w", id, err)
+	}
+	if len(points) == 0 {
+		return nil, false, nil
+	}
+
+	chunk := chunkFromPayload(points[0].Id.GetUuid(), points[0].Payload)
+	chunk.Embedding = c.storedVector(chunk.ID, points[0].Vectors)
+	return &chunk, true, nil
+}
+
+// storedVector returns a retrieved point's vector, or nil when it doesn't have the configured
+// number of dimensions (e.g. a point written for another model), so callers treat it as missing
+// rather than querying or exporting a vector of the wrong size
+func (c *Client) storedVector(id string, vectors *qdrant.VectorsOutput) []float32 {
+	vector := vectorFromOutput(vectors)
+	if vector != nil && c.config.VectorSize > 0 && len(vector) != c.config.VectorSize {
+		slog.Warn("Ignoring stored vector of unexpected size", "id", id, "dimensions", len(vector), "expected", c.config.VectorSize)
+		return nil
+	}
+	return vector
+}
+
+// vectorFromOutput extracts the dense vector from a retrieved point
+func vectorFromOutput(vectors *qdrant.VectorsOutput) []float32 {
+	vector := vectors.GetVector()
+	if dense := vector.GetDense(); dense != nil {
+		return dense.GetData()
+	}
+	return vector.GetData()
+}
+
+// chunkFromPayload converts a stored point payload back into a CodeChunk
+// Missing or mistyped fields (e.g. data written by an older version or another client) read as
+// empty values, and line numbers are kept within a valid range.
+func chunkFromPayload(id string, payload map[string]*qdrant.Value) models.CodeChunk {
+	chunk := models.CodeChunk{
+		ID:           id,
+		RepoPath:     payloadString(payload, "repo_path"),
+		FilePath:     payloadString(payload, "file_path"),
+		ChunkType:    models.ChunkType(payloadString(payload, "chunk_type")),
+		Content:      payloadString(payload, "content"),
+		Language:     payloadString(payload, "language"),
+		FunctionName: payloadString(payload, "function_name"),
+		ClassName:    payloadString(payload, "class_name"),
+		ContentHash:  payloadString(payload, contentHashKey),
+	}
+	chunk.StartLine, chunk.EndLine = lineRange(payloadInt(payload, "start_line"), payloadInt(payload, "end_line"))
+	if indexedAt := payloadInt(payload, indexedAtKey); indexedAt > 0 {
+		chunk.IndexedAt = time.Unix(int64(indexedAt), 0)
+	}
+
+	if encoded := payload[metadataKey].GetStringValue(); encoded != "" {
+		if err := json.Unmarshal([]byte(encoded), &chunk.Metadata); err != nil {
+			slog.Warn("Ignoring unreadable chunk metadata", "id", id, "error", err)
+		}
+	}
+	return chunk
+}
+
+// payloadString reads a string payload field, "" when missing or not a string
+func payloadString(payload map[string]*qdrant.Value, key string) string {
+	if value, ok := payload[key].GetKind().(*qdrant.Value_StringValue); ok {
+		return value.StringValue
+	}
+	return ""
+}
+
+// payloadInt reads an integer payload field, also accepting whole doubles and numeric strings
+// as other clients may write them; 0 when missing or unreadable
+func payloadInt(payload map[string]*qdrant.Value, key string) int {
+	switch value := payload[key].GetKind().(type) {
+	case *qdrant.Value_IntegerValue:
+		return int(value.IntegerValue)
+	case *qdrant.Value_DoubleValue:
+		if value.DoubleValue == math.Trunc(value.DoubleValue) {
+			return int(value.DoubleValue)
+		}
+	case *qdrant.Value_StringValue:
+		if n, err := strconv.Atoi(strings.TrimSpace(value.StringValue)); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// lineRange makes stored line numbers usable: lines start at 1, and a range never ends before
+// it starts
+func lineRange(start, end int) (int, int) {
+	start = max(start, 1)
+	return start, max(end, start)
+}
+
+// metadataKey is the payload key holding a chunk's JSON-encoded Metadata
+const metadataKey = "metadata"
+
+// contentHashKey is the payload key holding a chunk's content hash, when it has one
+const contentHashKey = "content_hash"
+
+// indexedAtKey is the payload key holding when a chunk was indexed, in Unix seconds
+const indexedAtKey = "indexed_at"
+
+// chunkPayload builds the Qdrant payload for a chunk
+// Metadata is stored JSON-encoded, so any JSON value round-trips (numbers come back as float64).
+func chunkPayload(chunk models.CodeChunk) (map[string]*qdrant.Value, error) {
+	payload := map[string]*qdrant.Value{
+		"repo_path":     qdrant.NewValueString(chunk.RepoPath),
+		"file_path":     qdrant.NewValueString(chunk.FilePath),
+		"chunk_type":    qdrant.NewValueString(string(chunk.ChunkType)),
+		"content":       qdrant.NewValueString(chunk.Content),
+		"language":      qdrant.NewValueString(chunk.Language),
+		"start_line":    qdrant.NewValueInt(int64(chunk.StartLine)),
+		"end_line":      qdrant.NewValueInt(int64(chunk.EndLine)),
+		"function_name": qdrant.NewValueString(chunk.FunctionName),
+		"class_name":    qdrant.NewValueString(chunk.ClassName),
+	}
+
+	if len(chunk.Metadata) > 0 {
+		encoded, err := json.Marshal(chunk.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode metadata for chunk %s: %w", chunk.ID, err)
+		}
+		payload[metadataKey] = qdrant.NewValueString(string(encoded))
+	}
+	if chunk.ContentHash != "" {
+		payload[contentHashKey] = qdrant.NewValueString(chunk.ContentHash)
+	}
+	if !chunk.IndexedAt.IsZero() {
+		payload[indexedAtKey] = qdrant.NewValueInt(chunk.IndexedAt.Unix())
+	}
+	return payload, nil
+}
+
+// indexedAtCondition returns the condition keeping chunks indexed within the filter's time
+// window, or nil if it has none
+func indexedAtCondition(filter models.SearchFilter) *qdrant.Condition {
+	if filter.Since.IsZero() && filter.Until.IsZero() {
+		return nil
+	}
+	window := &qdrant.Range{}
+	if !filter.Since.IsZero() {
+		window.Gte = qdrant.PtrOf(float64(filter.Since.Unix()))
+	}
+	if !filter.Until.IsZero() {
+		window.Lte = qdrant.PtrOf(float64(filter.Until.Unix()))
+	}
+	return qdrant.NewRange(indexedAtKey, window)
 }
 
 // DeleteByRepo deletes all chunks for a given repository
@@ -247,26 +734,97 @@ func (c *Client) DeleteByRepo(ctx context.Context, repoPath string) error {
 	return err
 }
 
-// CountChunks returns the number of chunks for a given repository
-func (c *Client) CountChunks(ctx context.Context, repoPath string) (int, error) {
-	count, err := c.client.Count(ctx, &qdrant.CountPoints{
+// DeleteByFile deletes all chunks for a given file in a repository
+func (c *Client) DeleteByFile(ctx context.Context, repoPath, filePath string) error {
+	_, err := c.client.Delete(ctx, &qdrant.DeletePoints{
 		CollectionName: c.collection,
-		Filter: &qdrant.Filter{
-			Must: []*qdrant.Condition{
-				{
-					ConditionOneOf: &qdrant.Condition_Field{
-						Field: &qdrant.FieldCondition{
-							Key: "repo_path",
-							Match: &qdrant.Match{
-								MatchValue: &qdrant.Match_Keyword{
-									Keyword: repoPath,
-								},
+		Points: &qdrant.PointsSelector{
+			PointsSelectorOneOf: &qdrant.PointsSelector_Filter{
+				Filter: fileFilter(repoPath, filePath),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete chunks for %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// RenameFile moves all chunks of a file to a new path without re-embedding them
+// Chunk IDs are derived from the file path, so the chunks are stored again under the IDs they
+// get at newPath and the old points deleted; keeping the old IDs would let a new file at oldPath
+// overwrite them.
+func (c *Client) RenameFile(ctx context.Context, repoPath, oldPath, newPath string) error {
+	var chunks []models.CodeChunk
+	var missingVector string
+	err := c.scroll(ctx, fileFilter(repoPath, oldPath), 0, true, func(chunk models.CodeChunk) bool {
+		if chunk.Embedding == nil {
+			missingVector = chunk.ID
+			return false
+		}
+		chunk.FilePath = newPath
+		chunk.ID = models.ChunkID(chunk.RepoPath, newPath, chunk.StartLine, chunk.EndLine, chunk.Content)
+		chunks = append(chunks, chunk)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read chunks of %s: %w", oldPath, err)
+	}
+	if missingVector != "" {
+		return fmt.Errorf("failed to rename chunks from %s to %s: chunk %s has no usable stored vector", oldPath, newPath, missingVector)
+	}
+
+	if err := c.UpsertChunks(ctx, chunks); err != nil {
+		return fmt.Errorf("failed to rename chunks from %s to %s: %w", oldPath, newPath, err)
+	}
+	return c.DeleteByFile(ctx, repoPath, oldPath)
+}
+
+// fileFilter returns a filter matching all chunks of a file in a repository
+func fileFilter(repoPath, filePath string) *qdrant.Filter {
+	return &qdrant.Filter{
+		Must: []*qdrant.Condition{
+			{
+				ConditionOneOf: &qdrant.Condition_Field{
+					Field: &qdrant.FieldCondition{
+						Key: "repo_path",
+						Match: &qdrant.Match{
+							MatchValue: &qdrant.Match_Keyword{
+								Keyword: repoPath,
+							},
+						},
+					},
+				},
+			},
+			{
+				ConditionOneOf: &qdrant.Condition_Field{
+					Field: &qdrant.FieldCondition{
+						Key: "file_path",
+						Match: &qdrant.Match{
+							MatchValue: &qdrant.Match_Keyword{
+								Keyword: filePath,
 							},
 						},
 					},
 				},
 			},
 		},
+	}
+}
+
+// CountChunks returns the number of chunks for a given repository
+func (c *Client) CountChunks(ctx context.Context, repoPath string) (int, error) {
+	var count uint64
+	err := c.withRetry(ctx, "count", func() error {
+		var err error
+		count, err = c.client.Count(ctx, &qdrant.CountPoints{
+			CollectionName: c.collection,
+			Filter: &qdrant.Filter{
+				Must: []*qdrant.Condition{qdrant.NewMatchKeyword("repo_path", repoPath)},
+			},
+		})
+		return err
 	})
 
 	if err != nil {
@@ -311,6 +869,92 @@ func (c *Client) GetStats(ctx context.Context, repoPath string) (*models.RepoInd
 	}, nil
 }
 
+// CollectionStats describes the whole collection, across all repositories
+type CollectionStats struct {
+	Collection           string   `json:"collection"`
+	Status               string   `json:"status"` // green, yellow (optimizing), grey (optimization pending) or red
+	OptimizerOK          bool     `json:"optimizer_ok"`
+	OptimizerError       string   `json:"optimizer_error,omitempty"`
+	PointsCount          uint64   `json:"points_count"`
+	IndexedVectorsCount  uint64   `json:"indexed_vectors_count"`
+	SegmentsCount        uint64   `json:"segments_count"`
+	VectorSize           uint64   `json:"vector_size"`
+	ConfiguredVectorSize int      `json:"configured_vector_size"`
+	Distance             string   `json:"distance"`
+	VectorsOnDisk        bool     `json:"vectors_on_disk"`
+	PayloadOnDisk        bool     `json:"payload_on_disk"`
+	Quantized            bool     `json:"quantized"`
+	Warnings             []string `json:"warnings,omitempty"`
+}
+
+// CollectionInfo returns point and segment counts and the storage configuration of the collection
+func (c *Client) CollectionInfo(ctx context.Context) (*CollectionStats, error) {
+	info, err := c.client.GetCollectionInfo(ctx, c.collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection info: %w", err)
+	}
+	return collectionStatsFromInfo(c.collection, c.config.VectorSize, info), nil
+}
+
+// collectionStatsFromInfo converts Qdrant's collection info into CollectionStats
+func collectionStatsFromInfo(collection string, configuredVectorSize int, info *qdrant.CollectionInfo) *CollectionStats {
+	stats := &CollectionStats{
+		Collection:           collection,
+		Status:               strings.ToLower(info.GetStatus().String()),
+		OptimizerOK:          info.GetOptimizerStatus().GetOk(),
+		OptimizerError:       info.GetOptimizerStatus().GetError(),
+		PointsCount:          info.GetPointsCount(),
+		IndexedVectorsCount:  info.GetIndexedVectorsCount(),
+		SegmentsCount:        info.GetSegmentsCount(),
+		ConfiguredVectorSize: configuredVectorSize,
+	}
+
+	params := info.GetConfig().GetParams()
+	vectorParams := params.GetVectorsConfig().GetParams()
+	stats.VectorSize = vectorParams.GetSize()
+	stats.Distance = strings.ToLower(vectorParams.GetDistance().String())
+	stats.VectorsOnDisk = vectorParams.GetOnDisk()
+	stats.PayloadOnDisk = params.GetOnDiskPayload()
+	stats.Quantized = vectorParams.GetQuantizationConfig() != nil || info.GetConfig().GetQuantizationConfig() != nil
+
+	for _, warning := range info.GetWarnings() {
+		stats.Warnings = append(stats.Warnings, warning.GetMessage())
+	}
+	return stats
+}
+
+// HealthStatus describes Qdrant reachability and collection state
+type HealthStatus struct {
+	Reachable        bool   `json:"reachable"`
+	Version          string `json:"version,omitempty"`
+	Collection       string `json:"collection"`
+	CollectionExists bool   `json:"collection_exists"`
+}
+
+// HealthCheck verifies that Qdrant is reachable and the collection exists
+func (c *Client) HealthCheck(ctx context.Context) (*HealthStatus, error) {
+	status := &HealthStatus{Collection: c.collection}
+
+	reply, err := c.client.HealthCheck(ctx)
+	if err != nil {
+		return status, fmt.Errorf("failed to reach Qdrant: %w", err)
+	}
+	status.Reachable = true
+	status.Version = reply.GetVersion()
+
+	exists, err := c.client.CollectionExists(ctx, c.collection)
+	if err != nil {
+		return status, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+	status.CollectionExists = exists
+
+	if !exists {
+		return status, fmt.Errorf("collection %s does not exist", c.collection)
+	}
+
+	return status, nil
+}
+
 // Close closes the Qdrant client connection
 func (c *Client) Close() error {
 	if c.client != nil {
@@ -319,14 +963,55 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// DistanceMetric returns the distance metric the existing collection was created with, named
+// like vectordb.distance_metric ("cosine", "dot", "euclidean"; "manhattan" for collections
+// created outside this server)
+func (c *Client) DistanceMetric(ctx context.Context) (string, error) {
+	info, err := c.client.GetCollectionInfo(ctx, c.collection)
+	if err != nil {
+		return "", fmt.Errorf("failed to get collection info: %w", err)
+	}
+	return distanceMetricName(info.GetConfig().GetParams().GetVectorsConfig().GetParams().GetDistance()), nil
+}
+
+// distanceMetricName names a Qdrant distance like vectordb.distance_metric
+func distanceMetricName(distance qdrant.Distance) string {
+	switch distance {
+	case qdrant.Distance_Cosine:
+		return config.DistanceCosine
+	case qdrant.Distance_Dot:
+		return config.DistanceDot
+	case qdrant.Distance_Euclid:
+		return config.DistanceEuclidean
+	default:
+		return strings.ToLower(distance.String())
+	}
+}
+
+// configuredDistanceMetric returns the name of the metric new collections are created with
+func (c *Client) configuredDistanceMetric() string {
+	return distanceMetricName(c.getDistanceMetric())
+}
+
+// checkDistanceMetric fails when a collection's metric differs from the configured one
+func checkDistanceMetric(collection, configured, existing string) error {
+	if configured == existing {
+		return nil
+	}
+	return fmt.Errorf("%w: collection %q uses %q but vectordb.distance_metric is %q; similarity scores would "+
+		"still use %q. Delete the collection and reindex, point vectordb.collection_name at a new collection, "+
+		"or set vectordb.distance_metric back to %q",
+		ErrDistanceMismatch, collection, existing, configured, existing, existing)
+}
+
 // getDistanceMetric returns the Qdrant distance metric
 func (c *Client) getDistanceMetric() qdrant.Distance {
 	switch c.config.DistanceMetric {
-	case "cosine":
+	case config.DistanceCosine:
 		return qdrant.Distance_Cosine
-	case "dot":
+	case config.DistanceDot:
 		return qdrant.Distance_Dot
-	case "euclidean":
+	case config.DistanceEuclidean:
 		return qdrant.Distance_Euclid
 	default:
 		return qdrant.Distance_Cosine
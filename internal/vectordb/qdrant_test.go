@@ -0,0 +1,944 @@
+package vectordb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/internal/models"
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func TestCreateCollectionRequest_StorageOptions(t *testing.T) {
+	tests := []struct {
+		name          string
+		onDiskPayload bool
+		quantization  bool
+	}{
+		{"in-memory payload", false, false},
+		{"on-disk payload", true, false},
+		{"scalar quantization", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig().VectorDB
+			cfg.OnDiskPayload = tt.onDiskPayload
+			cfg.ScalarQuantization = tt.quantization
+			c := &Client{config: &cfg, collection: cfg.CollectionName}
+
+			req := c.createCollectionRequest()
+
+			if req.GetOnDiskPayload() != tt.onDiskPayload {
+				t.Errorf("Expected on_disk_payload=%v, got %v", tt.onDiskPayload, req.GetOnDiskPayload())
+			}
+
+			params := req.GetVectorsConfig().GetParams()
+			if params.GetSize() != uint64(cfg.VectorSize) {
+				t.Errorf("Expected vector size %d, got %d", cfg.VectorSize, params.GetSize())
+			}
+			if params.GetOnDisk() != tt.quantization {
+				t.Errorf("Expected original vectors on disk=%v, got %v", tt.quantization, params.GetOnDisk())
+			}
+
+			scalar := req.GetQuantizationConfig().GetScalar()
+			if !tt.quantization {
+				if req.QuantizationConfig != nil {
+					t.Error("Expected no quantization config")
+				}
+				return
+			}
+			if scalar == nil {
+				t.Fatal("Expected scalar quantization config")
+			}
+			if scalar.GetType() != qdrant.QuantizationType_Int8 {
+				t.Errorf("Expected int8 quantization, got %v", scalar.GetType())
+			}
+			if !scalar.GetAlwaysRam() {
+				t.Error("Expected quantized vectors to stay in RAM")
+			}
+		})
+	}
+}
+
+// TestInitialize_StorageOptions creates a real collection and is skipped when Qdrant is not running
+func TestInitialize_StorageOptions(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	cfg.CollectionName = fmt.Sprintf("test_storage_options_%d", time.Now().UnixNano())
+	cfg.OnDiskPayload = true
+	cfg.ScalarQuantization = true
+
+	c, err := NewClient(&cfg)
+	if err != nil {
+		t.Skipf("Qdrant not available: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if status, err := c.HealthCheck(ctx); err != nil || !status.Reachable {
+		t.Skipf("Qdrant not available: %v", err)
+	}
+
+	if err := c.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer c.client.DeleteCollection(context.Background(), cfg.CollectionName)
+
+	info, err := c.client.GetCollectionInfo(ctx, cfg.CollectionName)
+	if err != nil {
+		t.Fatalf("Failed to get collection info: %v", err)
+	}
+
+	if !info.GetConfig().GetParams().GetOnDiskPayload() {
+		t.Error("Expected collection to store payload on disk")
+	}
+	if !info.GetConfig().GetParams().GetVectorsConfig().GetParams().GetOnDisk() {
+		t.Error("Expected original vectors to be stored on disk")
+	}
+	if info.GetConfig().GetQuantizationConfig().GetScalar() == nil {
+		t.Error("Expected collection to use scalar quantization")
+	}
+}
+
+// TestInitialize_AutoCreateCollection is skipped when Qdrant is not running
+func TestInitialize_AutoCreateCollection(t *testing.T) {
+	tests := []struct {
+		name       string
+		autoCreate bool
+		wantErr    bool
+	}{
+		{"creates missing collection", true, false},
+		{"fails fast on missing collection", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig().VectorDB
+			cfg.CollectionName = fmt.Sprintf("test_auto_create_%d", time.Now().UnixNano())
+			cfg.AutoCreateCollection = tt.autoCreate
+
+			c, err := NewClient(&cfg)
+			if err != nil {
+				t.Skipf("Qdrant not available: %v", err)
+			}
+			defer c.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if status, err := c.HealthCheck(ctx); err != nil || !status.Reachable {
+				t.Skipf("Qdrant not available: %v", err)
+			}
+			defer c.client.DeleteCollection(context.Background(), cfg.CollectionName)
+
+			err = c.Initialize(ctx)
+			if tt.wantErr {
+				if !errors.Is(err, ErrCollectionNotFound) {
+					t.Errorf("Expected ErrCollectionNotFound, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+
+			exists, err := c.CollectionExists(ctx)
+			if err != nil {
+				t.Fatalf("Failed to check collection: %v", err)
+			}
+			if exists != tt.autoCreate {
+				t.Errorf("Expected collection to exist=%v, got %v", tt.autoCreate, exists)
+			}
+
+			if tt.wantErr {
+				// Once created, the collection is used without auto-creation
+				cfg.AutoCreateCollection = true
+				if err := c.Initialize(ctx); err != nil {
+					t.Fatalf("Initialize failed: %v", err)
+				}
+				cfg.AutoCreateCollection = false
+				if err := c.Initialize(ctx); err != nil {
+					t.Errorf("Expected an existing collection to initialize, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckDistanceMetric(t *testing.T) {
+	if err := checkDistanceMetric("code_chunks", config.DistanceCosine, config.DistanceCosine); err != nil {
+		t.Errorf("Expected matching metrics to pass, got %v", err)
+	}
+
+	err := checkDistanceMetric("code_chunks", config.DistanceDot, config.DistanceCosine)
+	if !errors.Is(err, ErrDistanceMismatch) {
+		t.Fatalf("Expected ErrDistanceMismatch, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `uses "cosine"`) || !strings.Contains(err.Error(), `distance_metric is "dot"`) {
+		t.Errorf("Expected the error to name both metrics, got %v", err)
+	}
+}
+
+func TestDistanceMetricName(t *testing.T) {
+	tests := []struct {
+		distance qdrant.Distance
+		want     string
+	}{
+		{qdrant.Distance_Cosine, config.DistanceCosine},
+		{qdrant.Distance_Dot, config.DistanceDot},
+		{qdrant.Distance_Euclid, config.DistanceEuclidean},
+		{qdrant.Distance_Manhattan, "manhattan"},
+	}
+	for _, tt := range tests {
+		if got := distanceMetricName(tt.distance); got != tt.want {
+			t.Errorf("Expected %v to be named %q, got %q", tt.distance, tt.want, got)
+		}
+	}
+}
+
+// TestInitialize_DistanceMismatch is skipped when Qdrant is not running
+func TestInitialize_DistanceMismatch(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	cfg.DistanceMetric = config.DistanceCosine
+	c := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	metric, err := c.DistanceMetric(ctx)
+	if err != nil {
+		t.Fatalf("DistanceMetric failed: %v", err)
+	}
+	if metric != config.DistanceCosine {
+		t.Errorf("Expected the collection to use cosine, got %q", metric)
+	}
+
+	// The same collection, configured with another metric
+	changed := *c.config
+	changed.DistanceMetric = config.DistanceDot
+	other, err := NewClient(&changed)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer other.Close()
+
+	if err := other.Initialize(ctx); !errors.Is(err, ErrDistanceMismatch) {
+		t.Errorf("Expected ErrDistanceMismatch, got %v", err)
+	}
+	if err := c.Initialize(ctx); err != nil {
+		t.Errorf("Expected the matching configuration to initialize, got %v", err)
+	}
+}
+
+// newTestClient creates a client on a fresh collection and is skipped when Qdrant is not running
+func newTestClient(t *testing.T, cfg config.VectorDBConfig) *Client {
+	t.Helper()
+	cfg.CollectionName = fmt.Sprintf("test_%s_%d", t.Name(), time.Now().UnixNano())
+
+	c, err := NewClient(&cfg)
+	if err != nil {
+		t.Skipf("Qdrant not available: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if status, err := c.HealthCheck(ctx); err != nil || !status.Reachable {
+		t.Skipf("Qdrant not available: %v", err)
+	}
+	if err := c.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	t.Cleanup(func() { c.client.DeleteCollection(context.Background(), cfg.CollectionName) })
+
+	return c
+}
+
+func TestGetChunkByID(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	c := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	embedding := make([]float32, cfg.VectorSize)
+	embedding[0] = 1
+	stored := models.CodeChunk{
+		ID:           GenerateUUID(),
+		RepoPath:     "/repo",
+		FilePath:     "/repo/service.go",
+		ChunkType:    models.ChunkTypeFunction,
+		Content:      "func Run() {}",
+		Language:     "go",
+		StartLine:    3,
+		EndLine:      5,
+		FunctionName: "Run",
+		Embedding:    embedding,
+	}
+	if err := c.UpsertChunks(ctx, []models.CodeChunk{stored}); err != nil {
+		t.Fatalf("UpsertChunks failed: %v", err)
+	}
+
+	chunk, found, err := c.GetChunkByID(ctx, stored.ID)
+	if err != nil {
+		t.Fatalf("GetChunkByID failed: %v", err)
+	}
+	if !found {
+		t.Fatal("Expected stored chunk to be found")
+	}
+	if chunk.ID != stored.ID || chunk.FilePath != stored.FilePath || chunk.Content != stored.Content {
+		t.Errorf("Retrieved chunk does not match stored chunk: %+v", chunk)
+	}
+	if chunk.StartLine != 3 || chunk.EndLine != 5 || chunk.FunctionName != "Run" {
+		t.Errorf("Retrieved chunk metadata does not match: %+v", chunk)
+	}
+	if len(chunk.Embedding) != cfg.VectorSize {
+		t.Errorf("Expected stored vector of size %d, got %d", cfg.VectorSize, len(chunk.Embedding))
+	}
+
+	chunk, found, err = c.GetChunkByID(ctx, GenerateUUID())
+	if err != nil {
+		t.Fatalf("Expected no error for a missing chunk, got %v", err)
+	}
+	if found || chunk != nil {
+		t.Errorf("Expected missing chunk to be reported as not found, got %+v", chunk)
+	}
+}
+
+func TestEnsureIndexes_Idempotent(t *testing.T) {
+	c := newTestClient(t, config.DefaultConfig().VectorDB)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Simulate a collection created before payload indexes existed
+	for _, index := range payloadIndexes {
+		_, err := c.client.DeleteFieldIndex(ctx, &qdrant.DeleteFieldIndexCollection{
+			CollectionName: c.collection,
+			FieldName:      index.field,
+			Wait:           qdrant.PtrOf(true),
+		})
+		if err != nil {
+			t.Fatalf("Failed to delete index on %s: %v", index.field, err)
+		}
+	}
+
+	created, err := c.EnsureIndexes(ctx)
+	if err != nil {
+		t.Fatalf("EnsureIndexes failed: %v", err)
+	}
+	if len(created) != len(payloadIndexes) {
+		t.Errorf("Expected %d indexes created, got %v", len(payloadIndexes), created)
+	}
+
+	created, err = c.EnsureIndexes(ctx)
+	if err != nil {
+		t.Fatalf("Second EnsureIndexes failed: %v", err)
+	}
+	if len(created) != 0 {
+		t.Errorf("Expected no indexes created on the second run, got %v", created)
+	}
+
+	info, err := c.client.GetCollectionInfo(ctx, c.collection)
+	if err != nil {
+		t.Fatalf("Failed to get collection info: %v", err)
+	}
+	for _, index := range payloadIndexes {
+		if _, ok := info.GetPayloadSchema()[index.field]; !ok {
+			t.Errorf("Expected a payload index on %s", index.field)
+		}
+	}
+}
+
+func TestScrollSymbols_Paginates(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	c := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// More named chunks than fit in one page, plus unnamed and other-repo chunks
+	embedding := make([]float32, cfg.VectorSize)
+	embedding[0] = 1
+	named := symbolPageSize + 10
+	var chunks []models.CodeChunk
+	for i := 0; i < named; i++ {
+		chunks = append(chunks, models.CodeChunk{
+			ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/a.go", Content: "func F() {}",
+			StartLine: i + 1, EndLine: i + 1, FunctionName: fmt.Sprintf("F%d", i), Embedding: embedding,
+		})
+	}
+	chunks = append(chunks,
+		models.CodeChunk{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/a.go", ChunkType: models.ChunkTypeFile, Embedding: embedding},
+		models.CodeChunk{ID: GenerateUUID(), RepoPath: "/other", FilePath: "/other/b.go", FunctionName: "G", Embedding: embedding},
+	)
+	if err := c.UpsertChunks(ctx, chunks); err != nil {
+		t.Fatalf("UpsertChunks failed: %v", err)
+	}
+
+	seen := 0
+	err := c.ScrollSymbols(ctx, "/repo", func(chunk models.CodeChunk) bool {
+		seen++
+		if chunk.FunctionName == "" || chunk.RepoPath != "/repo" {
+			t.Errorf("Unexpected chunk listed: %+v", chunk)
+		}
+		if chunk.Content != "" {
+			t.Error("Expected symbols to be listed without content")
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ScrollSymbols failed: %v", err)
+	}
+	if seen != named {
+		t.Errorf("Expected %d symbols across pages, got %d", named, seen)
+	}
+
+	// Returning false stops scrolling
+	seen = 0
+	if err := c.ScrollSymbols(ctx, "/repo", func(models.CodeChunk) bool { seen++; return seen < 3 }); err != nil {
+		t.Fatalf("ScrollSymbols failed: %v", err)
+	}
+	if seen != 3 {
+		t.Errorf("Expected scrolling to stop after 3 symbols, got %d", seen)
+	}
+}
+
+func TestFindSymbols_LimitAndCase(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	c := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Partial matches spread over more than one page, among unnamed chunks
+	embedding := make([]float32, cfg.VectorSize)
+	embedding[0] = 1
+	var chunks []models.CodeChunk
+	for i := 0; i < symbolPageSize+10; i++ {
+		chunks = append(chunks, models.CodeChunk{
+			ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/a.go", Content: "x := 1",
+			StartLine: i + 1, EndLine: i + 1, Embedding: embedding,
+		})
+	}
+	for i := 0; i < 4; i++ {
+		chunks = append(chunks, models.CodeChunk{
+			ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/b.go", Content: "func HandleX() {}",
+			StartLine: i + 1, EndLine: i + 1, FunctionName: fmt.Sprintf("HandleRequest%d", i), Embedding: embedding,
+		})
+	}
+	chunks = append(chunks, models.CodeChunk{
+		ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/c.go", Content: "func HandleRequest() {}",
+		StartLine: 1, EndLine: 1, FunctionName: "HandleRequest", Embedding: embedding,
+	})
+	if err := c.UpsertChunks(ctx, chunks); err != nil {
+		t.Fatalf("UpsertChunks failed: %v", err)
+	}
+
+	partial, err := c.FindSymbols(ctx, "/repo", "handlerequest", false, 3)
+	if err != nil {
+		t.Fatalf("FindSymbols failed: %v", err)
+	}
+	if len(partial) != 3 {
+		t.Errorf("Expected 3 case-insensitive partial matches, got %d", len(partial))
+	}
+
+	exact, err := c.FindSymbols(ctx, "/repo", "HandleRequest", true, 10)
+	if err != nil {
+		t.Fatalf("FindSymbols failed: %v", err)
+	}
+	if len(exact) != 1 || exact[0].FunctionName != "HandleRequest" {
+		t.Errorf("Expected the one exact match, got %+v", exact)
+	}
+}
+
+func TestSearch_ScoreThreshold(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	c := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// One point aligned with the query, one orthogonal to it
+	aligned := make([]float32, cfg.VectorSize)
+	aligned[0] = 1
+	orthogonal := make([]float32, cfg.VectorSize)
+	orthogonal[1] = 1
+	chunks := []models.CodeChunk{
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/match.go", Content: "func Match() {}", Embedding: aligned},
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/other.go", Content: "func Other() {}", Embedding: orthogonal},
+	}
+	if err := c.UpsertChunks(ctx, chunks); err != nil {
+		t.Fatalf("UpsertChunks failed: %v", err)
+	}
+
+	found, _, err := c.Search(ctx, aligned, models.SearchFilter{RepoPath: "/repo"}, 10, 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("Expected both points without a threshold, got %d", len(found))
+	}
+
+	found, scores, err := c.Search(ctx, aligned, models.SearchFilter{RepoPath: "/repo"}, 10, 0.5)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(found) != 1 || found[0].FilePath != "/repo/match.go" {
+		t.Errorf("Expected only the aligned point above the threshold, got %+v", found)
+	}
+	for _, score := range scores {
+		if score < 0.5 {
+			t.Errorf("Expected no score below the threshold, got %v", score)
+		}
+	}
+}
+
+func TestSearch_FileFilter(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	c := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	embedding := make([]float32, cfg.VectorSize)
+	embedding[0] = 1
+	chunks := []models.CodeChunk{
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/big.go", Content: "func A() {}", StartLine: 1, Embedding: embedding},
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/big.go", Content: "func B() {}", StartLine: 10, Embedding: embedding},
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/other.go", Content: "func C() {}", StartLine: 1, Embedding: embedding},
+		{ID: GenerateUUID(), RepoPath: "/elsewhere", FilePath: "/repo/big.go", Content: "func D() {}", StartLine: 1, Embedding: embedding},
+	}
+	if err := c.UpsertChunks(ctx, chunks); err != nil {
+		t.Fatalf("UpsertChunks failed: %v", err)
+	}
+
+	found, _, err := c.Search(ctx, embedding, models.SearchFilter{RepoPath: "/repo", FilePath: "/repo/big.go"}, 10, 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("Expected the 2 chunks of big.go in /repo, got %d", len(found))
+	}
+	for _, chunk := range found {
+		if chunk.FilePath != "/repo/big.go" || chunk.RepoPath != "/repo" {
+			t.Errorf("Expected only chunks of /repo/big.go, got %s in %s", chunk.FilePath, chunk.RepoPath)
+		}
+	}
+}
+
+func TestAdjacentChunks(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	c := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	embedding := make([]float32, cfg.VectorSize)
+	embedding[0] = 1
+	chunks := []models.CodeChunk{
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/a.go", Content: "type A struct", StartLine: 1, EndLine: 40, Embedding: embedding},
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/a.go", Content: "func first()", StartLine: 2, EndLine: 9, Embedding: embedding},
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/a.go", Content: "func middle()", StartLine: 10, EndLine: 20, Embedding: embedding},
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/a.go", Content: "func last()", StartLine: 21, EndLine: 30, Embedding: embedding},
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/b.go", Content: "func other()", StartLine: 5, EndLine: 8, Embedding: embedding},
+	}
+	if err := c.UpsertChunks(ctx, chunks); err != nil {
+		t.Fatalf("UpsertChunks failed: %v", err)
+	}
+
+	before, after, err := c.AdjacentChunks(ctx, "/repo", "/repo/a.go", 10, 20)
+	if err != nil {
+		t.Fatalf("AdjacentChunks failed: %v", err)
+	}
+	if before == nil || before.Content != "func first()" || after == nil || after.Content != "func last()" {
+		t.Errorf("Expected first and last around middle, got %+v and %+v", before, after)
+	}
+
+	before, after, err = c.AdjacentChunks(ctx, "/repo", "/repo/b.go", 5, 8)
+	if err != nil {
+		t.Fatalf("AdjacentChunks failed: %v", err)
+	}
+	if before != nil || after != nil {
+		t.Errorf("Expected no chunks around the only chunk of b.go, got %+v and %+v", before, after)
+	}
+}
+
+func TestRenameFile(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	c := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	embedding := make([]float32, cfg.VectorSize)
+	embedding[0] = 1
+	content := "func Run() {}"
+	stored := models.CodeChunk{
+		ID:        models.ChunkID("/repo", "/repo/old.go", 1, 3, content),
+		RepoPath:  "/repo",
+		FilePath:  "/repo/old.go",
+		Content:   content,
+		StartLine: 1,
+		EndLine:   3,
+		Embedding: embedding,
+	}
+	if err := c.UpsertChunks(ctx, []models.CodeChunk{stored}); err != nil {
+		t.Fatalf("UpsertChunks failed: %v", err)
+	}
+
+	if err := c.RenameFile(ctx, "/repo", "/repo/old.go", "/repo/new.go"); err != nil {
+		t.Fatalf("RenameFile failed: %v", err)
+	}
+
+	// The chunk moves to the ID it gets at the new path, freeing the old one
+	if _, found, err := c.GetChunkByID(ctx, stored.ID); err != nil || found {
+		t.Errorf("Expected the old point to be removed, got found=%v err=%v", found, err)
+	}
+	renamed, found, err := c.GetChunkByID(ctx, models.ChunkID("/repo", "/repo/new.go", 1, 3, content))
+	if err != nil || !found {
+		t.Fatalf("Expected the chunk under its new ID, got found=%v err=%v", found, err)
+	}
+	if renamed.FilePath != "/repo/new.go" || renamed.Content != content || len(renamed.Embedding) != cfg.VectorSize {
+		t.Errorf("Renamed chunk does not match: %+v", renamed)
+	}
+}
+
+func TestSearch_IndexedAtWindow(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	c := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	embedding := make([]float32, cfg.VectorSize)
+	embedding[0] = 1
+	now := time.Now()
+	chunks := []models.CodeChunk{
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/old.go", Content: "func Old() {}", IndexedAt: now.AddDate(0, 0, -30), Embedding: embedding},
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/recent.go", Content: "func Recent() {}", IndexedAt: now.Add(-time.Hour), Embedding: embedding},
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/legacy.go", Content: "func Legacy() {}", Embedding: embedding}, // No indexing time
+	}
+	if err := c.UpsertChunks(ctx, chunks); err != nil {
+		t.Fatalf("UpsertChunks failed: %v", err)
+	}
+
+	found, _, err := c.Search(ctx, embedding, models.SearchFilter{RepoPath: "/repo", Since: now.AddDate(0, 0, -7)}, 10, 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(found) != 1 || found[0].FilePath != "/repo/recent.go" {
+		t.Errorf("Expected only the chunk indexed in the last week, got %v", found)
+	}
+
+	found, _, err = c.Search(ctx, embedding, models.SearchFilter{RepoPath: "/repo", Until: now.AddDate(0, 0, -7)}, 10, 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(found) != 1 || found[0].FilePath != "/repo/old.go" {
+		t.Errorf("Expected only the chunk indexed before last week, got %v", found)
+	}
+}
+
+func TestChunkPayload_MetadataRoundTrip(t *testing.T) {
+	chunk := models.CodeChunk{
+		ID:       GenerateUUID(),
+		RepoPath: "/repo",
+		FilePath: "/repo/service.go",
+		Content:  "func Run() {}",
+		Metadata: map[string]interface{}{
+			"author":  "dev@example.com",
+			"commits": 3,
+			"tags":    []interface{}{"hot", "legacy"},
+			"owner":   map[string]interface{}{"team": "payments"},
+		},
+	}
+
+	payload, err := chunkPayload(chunk)
+	if err != nil {
+		t.Fatalf("chunkPayload failed: %v", err)
+	}
+	got := chunkFromPayload(chunk.ID, payload).Metadata
+
+	if got["author"] != "dev@example.com" {
+		t.Errorf("Expected author to round-trip, got %v", got["author"])
+	}
+	if got["commits"] != float64(3) {
+		t.Errorf("Expected commits to round-trip as a JSON number, got %v (%T)", got["commits"], got["commits"])
+	}
+	if tags, ok := got["tags"].([]interface{}); !ok || len(tags) != 2 || tags[1] != "legacy" {
+		t.Errorf("Expected tags to round-trip, got %v", got["tags"])
+	}
+	if owner, ok := got["owner"].(map[string]interface{}); !ok || owner["team"] != "payments" {
+		t.Errorf("Expected nested metadata to round-trip, got %v", got["owner"])
+	}
+
+	// Chunks without metadata store no metadata key
+	chunk.Metadata = nil
+	payload, err = chunkPayload(chunk)
+	if err != nil {
+		t.Fatalf("chunkPayload failed: %v", err)
+	}
+	if _, ok := payload[metadataKey]; ok {
+		t.Error("Expected no metadata key for a chunk without metadata")
+	}
+	if got := chunkFromPayload(chunk.ID, payload).Metadata; got != nil {
+		t.Errorf("Expected nil metadata, got %v", got)
+	}
+
+	// Values JSON cannot encode are reported
+	chunk.Metadata = map[string]interface{}{"bad": make(chan int)}
+	if _, err := chunkPayload(chunk); err == nil {
+		t.Error("Expected an error for metadata that cannot be encoded")
+	}
+}
+
+func TestChunkPayload_ContentHash(t *testing.T) {
+	chunk := models.CodeChunk{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/a.go", Content: "func A() {}", ContentHash: "abc123"}
+	payload, err := chunkPayload(chunk)
+	if err != nil {
+		t.Fatalf("chunkPayload failed: %v", err)
+	}
+	if got := chunkFromPayload(chunk.ID, payload).ContentHash; got != "abc123" {
+		t.Errorf("Expected the content hash to round-trip, got %q", got)
+	}
+
+	// Chunks indexed without a hash store no hash key
+	chunk.ContentHash = ""
+	if payload, err = chunkPayload(chunk); err != nil {
+		t.Fatalf("chunkPayload failed: %v", err)
+	}
+	if _, ok := payload[contentHashKey]; ok {
+		t.Error("Expected no content hash key for a chunk without a hash")
+	}
+}
+
+func TestChunkPayload_IndexedAt(t *testing.T) {
+	indexedAt := time.Date(2026, 3, 14, 9, 26, 53, 589793238, time.UTC)
+	chunk := models.CodeChunk{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/a.go", Content: "func A() {}", IndexedAt: indexedAt}
+	payload, err := chunkPayload(chunk)
+	if err != nil {
+		t.Fatalf("chunkPayload failed: %v", err)
+	}
+	if got := chunkFromPayload(chunk.ID, payload).IndexedAt; !got.Equal(indexedAt.Truncate(time.Second)) {
+		t.Errorf("Expected the indexing time to round-trip to the second, got %v", got)
+	}
+
+	chunk.IndexedAt = time.Time{}
+	if payload, err = chunkPayload(chunk); err != nil {
+		t.Fatalf("chunkPayload failed: %v", err)
+	}
+	if _, ok := payload[indexedAtKey]; ok {
+		t.Error("Expected no indexed_at key for a chunk without an indexing time")
+	}
+	if got := chunkFromPayload(chunk.ID, payload).IndexedAt; !got.IsZero() {
+		t.Errorf("Expected a zero indexing time when none is stored, got %v", got)
+	}
+}
+
+func TestIndexedAtCondition(t *testing.T) {
+	if condition := indexedAtCondition(models.SearchFilter{RepoPath: "/repo"}); condition != nil {
+		t.Errorf("Expected no condition without a time window, got %v", condition)
+	}
+
+	since := time.Unix(1700000000, 0)
+	window := indexedAtCondition(models.SearchFilter{Since: since}).GetField()
+	if window.GetKey() != indexedAtKey || window.GetRange().GetGte() != 1700000000 || window.GetRange().Lte != nil {
+		t.Errorf("Expected an open-ended range from since, got %v", window)
+	}
+
+	window = indexedAtCondition(models.SearchFilter{Since: since, Until: since.Add(time.Hour)}).GetField()
+	if window.GetRange().GetGte() != 1700000000 || window.GetRange().GetLte() != 1700003600 {
+		t.Errorf("Expected a range from since to until, got %v", window)
+	}
+}
+
+func TestChunkFromPayload_MissingFields(t *testing.T) {
+	// Older or foreign data: no lines, function or class, mistyped and nil values
+	payload := map[string]*qdrant.Value{
+		"repo_path":  qdrant.NewValueString("/repo"),
+		"file_path":  qdrant.NewValueString("/repo/legacy.go"),
+		"content":    qdrant.NewValueString("func Legacy() {}"),
+		"language":   nil,
+		"chunk_type": qdrant.NewValueInt(3),
+	}
+
+	chunk := chunkFromPayload("id-1", payload)
+
+	if chunk.RepoPath != "/repo" || chunk.FilePath != "/repo/legacy.go" || chunk.Content != "func Legacy() {}" {
+		t.Errorf("Expected present fields to be read, got %+v", chunk)
+	}
+	if chunk.Language != "" || chunk.ChunkType != "" || chunk.FunctionName != "" || chunk.ClassName != "" {
+		t.Errorf("Expected missing and mistyped fields to be empty, got %+v", chunk)
+	}
+	if chunk.StartLine != 1 || chunk.EndLine != 1 {
+		t.Errorf("Expected missing lines to default to 1-1, got %d-%d", chunk.StartLine, chunk.EndLine)
+	}
+	if chunk.Metadata != nil {
+		t.Errorf("Expected no metadata, got %v", chunk.Metadata)
+	}
+}
+
+func TestChunkFromPayload_LineNumbers(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     *qdrant.Value
+		end       *qdrant.Value
+		wantStart int
+		wantEnd   int
+	}{
+		{"integers", qdrant.NewValueInt(10), qdrant.NewValueInt(20), 10, 20},
+		{"whole doubles", qdrant.NewValueDouble(10), qdrant.NewValueDouble(20), 10, 20},
+		{"numeric strings", qdrant.NewValueString("10"), qdrant.NewValueString(" 20 "), 10, 20},
+		{"fractional double", qdrant.NewValueInt(10), qdrant.NewValueDouble(20.5), 10, 10},
+		{"missing end", qdrant.NewValueInt(10), nil, 10, 10},
+		{"missing start", nil, qdrant.NewValueInt(20), 1, 20},
+		{"negative", qdrant.NewValueInt(-5), qdrant.NewValueInt(-1), 1, 1},
+		{"reversed", qdrant.NewValueInt(20), qdrant.NewValueInt(10), 20, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := map[string]*qdrant.Value{"start_line": tt.start, "end_line": tt.end}
+			chunk := chunkFromPayload("id", payload)
+			if chunk.StartLine != tt.wantStart || chunk.EndLine != tt.wantEnd {
+				t.Errorf("Expected lines %d-%d, got %d-%d", tt.wantStart, tt.wantEnd, chunk.StartLine, chunk.EndLine)
+			}
+		})
+	}
+}
+
+// denseOutput wraps a vector as Qdrant returns it with a point
+func denseOutput(vector []float32) *qdrant.VectorsOutput {
+	return &qdrant.VectorsOutput{VectorsOptions: &qdrant.VectorsOutput_Vector{Vector: &qdrant.VectorOutput{Data: vector}}}
+}
+
+func TestStoredVector(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	cfg.VectorSize = 4
+	c := &Client{config: &cfg, collection: cfg.CollectionName}
+
+	if got := c.storedVector("id", denseOutput([]float32{1, 2, 3, 4})); len(got) != 4 {
+		t.Errorf("Expected the 4-dimensional vector, got %v", got)
+	}
+	if got := c.storedVector("id", denseOutput([]float32{1, 2})); got != nil {
+		t.Errorf("Expected a vector with fewer dimensions to be dropped, got %v", got)
+	}
+	if got := c.storedVector("id", nil); got != nil {
+		t.Errorf("Expected no vector, got %v", got)
+	}
+}
+
+func TestSearch_ReturnsMetadata(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	c := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	embedding := make([]float32, cfg.VectorSize)
+	embedding[0] = 1
+	stored := models.CodeChunk{
+		ID:        GenerateUUID(),
+		RepoPath:  "/repo",
+		FilePath:  "/repo/service.go",
+		Content:   "func Run() {}",
+		Embedding: embedding,
+		Metadata:  map[string]interface{}{"author": "dev@example.com", "tags": []interface{}{"hot"}},
+	}
+	if err := c.UpsertChunks(ctx, []models.CodeChunk{stored}); err != nil {
+		t.Fatalf("UpsertChunks failed: %v", err)
+	}
+
+	found, _, err := c.Search(ctx, embedding, models.SearchFilter{RepoPath: "/repo"}, 1, 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(found))
+	}
+	if found[0].Metadata["author"] != "dev@example.com" {
+		t.Errorf("Expected metadata to be returned by Search, got %v", found[0].Metadata)
+	}
+	if tags, ok := found[0].Metadata["tags"].([]interface{}); !ok || len(tags) != 1 {
+		t.Errorf("Expected tags to be returned by Search, got %v", found[0].Metadata["tags"])
+	}
+}
+
+func TestCollectionStatsFromInfo(t *testing.T) {
+	info := &qdrant.CollectionInfo{
+		Status:              qdrant.CollectionStatus_Yellow,
+		OptimizerStatus:     &qdrant.OptimizerStatus{Ok: true},
+		SegmentsCount:       4,
+		PointsCount:         qdrant.PtrOf(uint64(1200)),
+		IndexedVectorsCount: qdrant.PtrOf(uint64(1000)),
+		Config: &qdrant.CollectionConfig{
+			Params: &qdrant.CollectionParams{
+				OnDiskPayload: true,
+				VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
+					Size: 768, Distance: qdrant.Distance_Cosine, OnDisk: qdrant.PtrOf(true),
+				}),
+			},
+			QuantizationConfig: qdrant.NewQuantizationScalar(&qdrant.ScalarQuantization{Type: qdrant.QuantizationType_Int8}),
+		},
+		Warnings: []*qdrant.CollectionWarning{{Message: "too many segments"}},
+	}
+
+	stats := collectionStatsFromInfo("code_chunks", 256, info)
+
+	if stats.Status != "yellow" || !stats.OptimizerOK {
+		t.Errorf("Unexpected status: %+v", stats)
+	}
+	if stats.PointsCount != 1200 || stats.IndexedVectorsCount != 1000 || stats.SegmentsCount != 4 {
+		t.Errorf("Unexpected counts: %+v", stats)
+	}
+	if stats.VectorSize != 768 || stats.ConfiguredVectorSize != 256 || stats.Distance != "cosine" {
+		t.Errorf("Unexpected vector settings: %+v", stats)
+	}
+	if !stats.VectorsOnDisk || !stats.PayloadOnDisk || !stats.Quantized {
+		t.Errorf("Unexpected storage settings: %+v", stats)
+	}
+	if len(stats.Warnings) != 1 || stats.Warnings[0] != "too many segments" {
+		t.Errorf("Expected the collection warning, got %v", stats.Warnings)
+	}
+}
+
+func TestCollectionInfo_MatchesConfig(t *testing.T) {
+	cfg := config.DefaultConfig().VectorDB
+	c := newTestClient(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	embedding := make([]float32, cfg.VectorSize)
+	embedding[0] = 1
+	chunks := []models.CodeChunk{
+		{ID: GenerateUUID(), RepoPath: "/repo", FilePath: "/repo/a.go", Embedding: embedding},
+		{ID: GenerateUUID(), RepoPath: "/other", FilePath: "/other/b.go", Embedding: embedding},
+	}
+	if err := c.UpsertChunks(ctx, chunks); err != nil {
+		t.Fatalf("UpsertChunks failed: %v", err)
+	}
+
+	stats, err := c.CollectionInfo(ctx)
+	if err != nil {
+		t.Fatalf("CollectionInfo failed: %v", err)
+	}
+	if stats.VectorSize != uint64(cfg.VectorSize) || stats.ConfiguredVectorSize != cfg.VectorSize {
+		t.Errorf("Expected vector size %d, got %d (configured %d)", cfg.VectorSize, stats.VectorSize, stats.ConfiguredVectorSize)
+	}
+	if stats.PayloadOnDisk != cfg.OnDiskPayload {
+		t.Errorf("Expected payload on disk=%v, got %v", cfg.OnDiskPayload, stats.PayloadOnDisk)
+	}
+	// Points are counted across all repositories
+	if stats.PointsCount != uint64(len(chunks)) {
+		t.Errorf("Expected %d points, got %d", len(chunks), stats.PointsCount)
+	}
+	if stats.SegmentsCount == 0 {
+		t.Error("Expected at least one segment")
+	}
+}
@@ -0,0 +1,43 @@
+package vectordb
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// isTransient reports whether a Qdrant error is likely to clear up on its own,
+// like a restarting server or a network blip
+// Permanent errors such as InvalidArgument or NotFound are never retried.
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// withRetry runs a Qdrant operation, retrying transient errors with exponential backoff
+// Retries stop when ctx is done; the last error is returned unchanged.
+func (c *Client) withRetry(ctx context.Context, operation string, fn func() error) error {
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= c.maxRetries || !isTransient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		slog.Warn("Retrying Qdrant operation", "operation", operation, "attempt", attempt+1,
+			"max_retries", c.maxRetries, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,77 @@
+package vectordb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyOperation fails with the given errors in turn, then succeeds
+type flakyOperation struct {
+	errs  []error
+	calls int
+}
+
+func (f *flakyOperation) run() error {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return f.errs[f.calls-1]
+	}
+	return nil
+}
+
+func TestWithRetry(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	deadline := status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	invalid := status.Error(codes.InvalidArgument, "wrong vector size")
+
+	tests := []struct {
+		name          string
+		errs          []error
+		maxRetries    int
+		expectedCalls int
+		expectErr     bool
+	}{
+		{"succeeds first time", nil, 3, 1, false},
+		{"retries transient errors until success", []error{unavailable, deadline}, 3, 3, false},
+		{"gives up after max retries", []error{unavailable, unavailable, unavailable}, 2, 3, true},
+		{"does not retry permanent errors", []error{invalid}, 3, 1, true},
+		{"does not retry plain errors", []error{errors.New("boom")}, 3, 1, true},
+		{"no retries configured", []error{unavailable}, 0, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{maxRetries: tt.maxRetries, retryBackoff: time.Millisecond}
+			op := &flakyOperation{errs: tt.errs}
+
+			err := c.withRetry(context.Background(), "test", op.run)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error %v, got %v", tt.expectErr, err)
+			}
+			if op.calls != tt.expectedCalls {
+				t.Errorf("Expected %d calls, got %d", tt.expectedCalls, op.calls)
+			}
+		})
+	}
+}
+
+func TestWithRetry_StopsWhenContextDone(t *testing.T) {
+	c := &Client{maxRetries: 5, retryBackoff: time.Hour}
+	op := &flakyOperation{errs: []error{status.Error(codes.Unavailable, "down")}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := c.withRetry(ctx, "test", op.run)
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected the last Qdrant error, got %v", err)
+	}
+	if op.calls != 1 {
+		t.Errorf("Expected no retry after the context ended, got %d calls", op.calls)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -31,6 +32,9 @@ type ServerConfig struct {
 type ChunkingConfig struct {
 	MaxLines           int  `yaml:"max_lines"`
 	OverlapLines       int  `yaml:"overlap_lines"`
+	// Overlap as a fraction of each chunk's size (e.g. 0.2), so it scales with the adaptive
+	// token limits. 0 keeps the absolute overlap; values must be below 1.
+	OverlapFraction    float64 `yaml:"overlap_fraction"`
 	RespectBoundaries  bool `yaml:"respect_boundaries"`
 	// Adaptive chunking: different token limits based on file size
 	SmallFileMaxTokens int  `yaml:"small_file_max_tokens"` // Files < 1000 lines
@@ -39,21 +43,128 @@ type ChunkingConfig struct {
 	// Hierarchical chunking: split large classes/interfaces
 	EnableHierarchicalChunking bool `yaml:"enable_hierarchical_chunking"`
 	MaxChunkSizeBytes          int  `yaml:"max_chunk_size_bytes"` // Max size before splitting
+	// Comment stripping: embed code without comments, while results still show the original text
+	StripComments   bool `yaml:"strip_comments"`
+	KeepDocComments bool `yaml:"keep_doc_comments"` // Keep /** */ doc comments when stripping
+	// Import context: attach the file's imports to function and method chunks and embed them
+	// with the chunk. Improves matching on library usage, at the cost of larger embedding inputs.
+	IncludeImports bool `yaml:"include_imports"`
+	// Text embedded for function and method chunks: "full" (default) the whole chunk, "signature"
+	// the leading doc comment and signature, "head" the first EmbedHeadLines lines. Results
+	// always show the full chunk; shorter texts are cheaper to embed and match on intent.
+	EmbedStrategy  string `yaml:"embed_strategy"`
+	EmbedHeadLines int    `yaml:"embed_head_lines"` // Lines embedded by "head" (default 10)
+	// Name context: prepend the file path (relative to the repository) and the function/class
+	// name to the embedded text, so name-oriented queries find chunks whose body doesn't repeat
+	// the name. Stored content is unchanged; the extra words can add noise to content queries.
+	EmbedFilePath   bool `yaml:"embed_file_path"`
+	EmbedSymbolName bool `yaml:"embed_symbol_name"`
+	// Chunk types to index (function, class, method, file); empty indexes every type
+	IndexChunkTypes []string `yaml:"index_chunk_types"`
+	// Tokenizer for token budgets and truncation: a tiktoken encoding ("cl100k_base", the default),
+	// an OpenAI model name, or "approximate" (4 characters per token) for other models
+	TokenizerEncoding string `yaml:"tokenizer_encoding"`
+	// Extra regexes per language (e.g. "java", "typescript") marking lines where the token
+	// chunker may split, in addition to the built-in function/class patterns. Lines are matched
+	// with surrounding whitespace trimmed.
+	BoundaryPatterns map[string][]string `yaml:"boundary_patterns"`
+	// Files of unsupported extensions: "report" (default) skips them and counts them in the
+	// scan's skip reasons, "skip" leaves them out silently, and "text" chunks text files (e.g.
+	// SQL, config) as plain text by tokens, so they can be searched. Binary files are never indexed.
+	UnsupportedFiles string `yaml:"unsupported_files"`
+	// Chunks below MinChunkLength (e.g. one-line getters) are dropped, unless every chunk of
+	// the file is; per-language values (e.g. "java") in MinChunkLengthByLanguage replace it
+	MinChunkLength           MinChunkLength            `yaml:"min_chunk_length"`
+	MinChunkLengthByLanguage map[string]MinChunkLength `yaml:"min_chunk_length_by_language"`
+}
+
+// MinChunkLength is the size below which a chunk is too trivial to index (0 = no minimum)
+type MinChunkLength struct {
+	Chars int `yaml:"chars"` // Characters of content, leading and trailing whitespace excluded
+	Lines int `yaml:"lines"`
 }
 
 type IndexingConfig struct {
 	BatchSize       int  `yaml:"batch_size"`
 	MaxFileSizeMB   int  `yaml:"max_file_size_mb"`
-	ParallelWorkers int  `yaml:"parallel_workers"`
+	ParallelWorkers int  `yaml:"parallel_workers"` // Concurrent embedding requests to Ollama
+	ChunkWorkers    int  `yaml:"chunk_workers"`    // Concurrent file chunkers (0 = number of CPUs)
 	Background      bool `yaml:"background"`
 	Incremental     bool `yaml:"incremental"`
+	// Line-count filters to skip trivial or enormous files (0 = no limit)
+	MinLines int `yaml:"min_lines"`
+	MaxLines int `yaml:"max_lines"`
+	// Reuse the cached file list of directories whose mtime is unchanged, without reading
+	// their files. Misses files edited in place (which doesn't touch the directory mtime).
+	SkipUnchangedDirs bool `yaml:"skip_unchanged_dirs"`
+	// Abort indexing when a run would cover more files, or embed more chunks, than this
+	// (0 = no limit). Guards against indexing a huge tree through a too-narrow ignore list.
+	MaxFiles  int `yaml:"max_files"`
+	MaxChunks int `yaml:"max_chunks"`
+	// Don't descend more than this many directory levels below the repository root
+	// (0 = unlimited). Files directly in the root are level 0.
+	MaxDepth int `yaml:"max_depth"`
+	// Attach git blame info (last commit, author, date) to chunks of git repositories.
+	// Runs git blame on every reindexed file, so it slows indexing down noticeably. Files touched
+	// by commits made since the last index are reindexed too, as their blame changed.
+	GitBlame bool `yaml:"git_blame"`
+	// Header comments marking a file as generated, e.g. "Code generated ... DO NOT EDIT."
+	// A marker counts only at the start of a comment line in the file's leading comment block.
+	// Chunks of such files are ranked down in searches, or the files are skipped with SkipGenerated.
+	GeneratedMarkers []string `yaml:"generated_markers"`
+	SkipGenerated    bool     `yaml:"skip_generated"`
+	// Store a SHA-256 hash of each chunk's content in its payload, so verify_index can detect
+	// payloads that no longer match what was indexed
+	ContentHash bool `yaml:"content_hash"`
 }
 
 type SearchConfig struct {
 	MaxResults         int     `yaml:"max_results"`
+	// Results from any one file at most (0 = no cap); lower-ranked results of other files
+	// fill the freed places. unique_files searches keep one result per file regardless.
+	MaxResultsPerFile int `yaml:"max_results_per_file"`
 	SemanticWeight     float64 `yaml:"semantic_weight"`
 	ExactMatchBoost    float64 `yaml:"exact_match_boost"`
 	MinScoreThreshold  float64 `yaml:"min_score_threshold"`
+	// Minimum raw similarity for Qdrant to return a candidate (0 = no threshold); dropped
+	// server-side before hybrid scoring and reranking, unlike MinScoreThreshold
+	MinSemanticScore float64 `yaml:"min_semantic_score"`
+	// Minimum semantic score for a chunk to get ExactMatchBoost in additive mode (0 = always
+	// boosted), so a coincidental substring match in an unrelated chunk can't outrank relevant results
+	ExactMatchMinSemantic float64 `yaml:"exact_match_min_semantic"`
+	// Scoring mode: "normalized" keeps hybrid scores within [0,1] using SemanticWeight and
+	// LexicalWeight (rescaled to sum to 1); "additive" (or empty) adds ExactMatchBoost instead
+	ScoringMode   string  `yaml:"scoring_mode"`
+	LexicalWeight float64 `yaml:"lexical_weight"`
+	// Match query terms only at word boundaries ("log" no longer matches "catalog")
+	WholeWordMatch bool `yaml:"whole_word_match"`
+	// Over-fetch for reranking: fetch MaxResults * RerankCandidateMultiplier candidates,
+	// never more than MaxCandidates (but at least MaxResults). Higher values improve recall
+	// at the cost of memory and latency.
+	RerankCandidateMultiplier int `yaml:"rerank_candidate_multiplier"`
+	MaxCandidates             int `yaml:"max_candidates"`
+	// Optional reranking of the top results after hybrid scoring: "none" (default) or "ollama",
+	// which asks RerankModel to rate each candidate. Adds one LLM call per candidate.
+	Reranker    string `yaml:"reranker"`
+	RerankModel string `yaml:"rerank_model"`
+	RerankTopK  int    `yaml:"rerank_top_k"` // Top results to rerank (default 20)
+	// Score multipliers per chunk type ("file", "function", "class", "method"); unlisted types
+	// keep their score. Below 1 demotes a type, e.g. diffuse whole-file chunks.
+	ChunkTypeWeights map[string]float64 `yaml:"chunk_type_weights"`
+	// Learn bounded score adjustments per file path category and chunk type from
+	// record_feedback votes, persisted in the cache directory (opt-in)
+	Feedback bool `yaml:"feedback"`
+	// Content preview of formatted results: lines shown per result, and characters per line
+	// before it is cut with "..." (0 = defaults of 3 lines and 80 characters)
+	PreviewLines     int `yaml:"preview_lines"`
+	PreviewLineWidth int `yaml:"preview_line_width"`
+	// Serve repeated identical searches from memory for this many seconds (0 = no caching);
+	// reindexing, clearing or importing an index invalidates its cached results
+	ResultCacheTTLSeconds int `yaml:"result_cache_ttl_seconds"`
+	ResultCacheSize       int `yaml:"result_cache_size"` // Searches cached at most (default 256)
+	// Give up on a search after this many milliseconds, covering the query embedding, the
+	// vector search and reranking (0 = no limit)
+	QueryTimeoutMs int `yaml:"query_timeout_ms"`
 }
 
 type EmbeddingsConfig struct {
@@ -65,6 +176,42 @@ type EmbeddingsConfig struct {
 	ContextLength int    `yaml:"context_length"`
 	Normalize     bool   `yaml:"normalize"`
 	UseMRL        bool   `yaml:"use_mrl"` // Enable MRL dimension truncation
+	// Probe the model's output dimension at startup and adjust full_dimension and
+	// vectordb.vector_size to it; when false, a mismatch fails startup instead
+	AutoDetectDimension bool `yaml:"auto_detect_dimension"`
+	// How long Ollama keeps the model loaded after each request: a duration ("10m"), seconds,
+	// or -1 to keep it loaded; empty uses Ollama's default (5 minutes)
+	KeepAlive string `yaml:"keep_alive"`
+	// Retry policy for failed embedding batches
+	MaxRetries     int `yaml:"max_retries"`      // Retries per failed batch (0 = no retries)
+	RetryBackoffMs int `yaml:"retry_backoff_ms"` // Initial backoff, doubled after each retry
+	// Requests in flight to Ollama at once, across all batches; the HTTP connection pool is
+	// sized to match so connections are reused (0 = 16)
+	MaxConcurrentRequests int `yaml:"max_concurrent_requests"`
+	// Task prefixes for instructed models; unset uses the model's recommended prefixes, "" disables them
+	QueryPrefix    *string `yaml:"query_prefix"`
+	DocumentPrefix *string `yaml:"document_prefix"`
+}
+
+// Task prefixes recommended for nomic-embed-text
+const (
+	NomicQueryPrefix    = "search_query: "
+	NomicDocumentPrefix = "search_document: "
+)
+
+// TaskPrefixes returns the prefixes prepended to search queries and indexed documents
+// Prefixes left unset default to the nomic ones for nomic models and to none otherwise.
+func (c *EmbeddingsConfig) TaskPrefixes() (query, document string) {
+	if strings.HasPrefix(strings.ToLower(c.Model), "nomic-embed") {
+		query, document = NomicQueryPrefix, NomicDocumentPrefix
+	}
+	if c.QueryPrefix != nil {
+		query = *c.QueryPrefix
+	}
+	if c.DocumentPrefix != nil {
+		document = *c.DocumentPrefix
+	}
+	return query, document
 }
 
 type VectorDBConfig struct {
@@ -73,6 +220,16 @@ type VectorDBConfig struct {
 	DistanceMetric string `yaml:"distance_metric"`
 	VectorSize     int    `yaml:"vector_size"`
 	OnDiskPayload  bool   `yaml:"on_disk_payload"`
+	// Enable int8 scalar quantization: quantized vectors stay in RAM, originals move to disk
+	ScalarQuantization bool `yaml:"scalar_quantization"`
+	// Suffix the collection name with the embedding model and vector size,
+	// so switching models uses a separate, correctly sized collection
+	CollectionPerModel bool `yaml:"collection_per_model"`
+	// Create the collection when it's missing; when false, a missing collection is an error,
+	// for deployments that create and size their collections explicitly
+	AutoCreateCollection bool `yaml:"auto_create_collection"`
+	MaxRetries           int  `yaml:"max_retries"`      // Retries per operation on a transient error (0 = no retries)
+	RetryBackoffMs       int  `yaml:"retry_backoff_ms"` // Initial backoff, doubled after each retry
 }
 
 type CacheConfig struct {
@@ -84,6 +241,7 @@ type CacheConfig struct {
 
 type LoggingConfig struct {
 	Enabled    bool   `yaml:"enabled"`
+	Level      string `yaml:"level"` // debug, info, warn or error
 	Directory  string `yaml:"directory"`
 	MaxSizeMB  int    `yaml:"max_size_mb"`
 	MaxBackups int    `yaml:"max_backups"`
@@ -93,12 +251,18 @@ type LoggingConfig struct {
 
 type IgnoreConfig struct {
 	Patterns []string `yaml:"patterns"`
+	// Match patterns regardless of letter case, for case-insensitive filesystems (macOS, Windows)
+	CaseInsensitive bool `yaml:"case_insensitive"`
 }
 
 type LanguagesConfig struct {
 	Java       LanguageConfig `yaml:"java"`
 	TypeScript LanguageConfig `yaml:"typescript"`
 	JavaScript LanguageConfig `yaml:"javascript"`
+	Kotlin     LanguageConfig `yaml:"kotlin"`
+	Ruby       LanguageConfig `yaml:"ruby"`
+	PHP        LanguageConfig `yaml:"php"`
+	Swift      LanguageConfig `yaml:"swift"`
 }
 
 type LanguageConfig struct {
@@ -128,6 +292,60 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// Distance metrics accepted in vectordb.distance_metric
+const (
+	DistanceCosine    = "cosine"
+	DistanceDot       = "dot"
+	DistanceEuclidean = "euclidean"
+)
+
+// NormalizationWarnings reports embeddings.normalize / vectordb.distance_metric pairings
+// that probably don't do what was intended. Recommended: cosine with either setting,
+// or dot/euclidean with normalize: false to keep vector magnitudes meaningful.
+func (c *Config) NormalizationWarnings() []string {
+	metric := c.VectorDB.DistanceMetric
+	switch metric {
+	case "", DistanceCosine:
+		return nil
+	case DistanceDot, DistanceEuclidean:
+		if c.Embeddings.Normalize {
+			return []string{fmt.Sprintf("vectordb.distance_metric %q with embeddings.normalize: true ranks exactly like cosine; "+
+				"use distance_metric: cosine, or set normalize: false if vector magnitude should count", metric)}
+		}
+		return nil
+	default:
+		return []string{fmt.Sprintf("unknown vectordb.distance_metric %q, using cosine (valid: %s, %s, %s)",
+			metric, DistanceCosine, DistanceDot, DistanceEuclidean)}
+	}
+}
+
+// ResolveCollectionName returns the Qdrant collection to use
+// With collection_per_model enabled, the configured name is suffixed with the
+// model and vector size (e.g. code_chunks_nomic_embed_text_256). Load keeps the configured
+// name, so it is resolved once the vector size is final (detecting the dimension may change it).
+func (c *Config) ResolveCollectionName() string {
+	if !c.VectorDB.CollectionPerModel {
+		return c.VectorDB.CollectionName
+	}
+	return fmt.Sprintf("%s_%s_%d", c.VectorDB.CollectionName, sanitizeCollectionPart(c.Embeddings.Model), c.VectorDB.VectorSize)
+}
+
+// sanitizeCollectionPart lowercases s and replaces runs of non-alphanumeric characters with underscores
+func sanitizeCollectionPart(s string) string {
+	var b strings.Builder
+	lastUnderscore := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			lastUnderscore = false
+		} else if !lastUnderscore {
+			b.WriteRune('_')
+			lastUnderscore = true
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
@@ -144,19 +362,46 @@ func DefaultConfig() *Config {
 			LargeFileMaxTokens:  150, // ~600 chars
 			EnableHierarchicalChunking: true,
 			MaxChunkSizeBytes:          4000, // 4KB before splitting
+			StripComments:              false,
+			KeepDocComments:            true,
+			EmbedStrategy:              "full",
+			EmbedHeadLines:             10,
+			TokenizerEncoding:          "cl100k_base",
+			UnsupportedFiles:           "report",
 		},
 		Indexing: IndexingConfig{
 			BatchSize:       100,
 			MaxFileSizeMB:   1,
 			ParallelWorkers: runtime.NumCPU(),
+			ChunkWorkers:    runtime.NumCPU(),
 			Background:      true,
 			Incremental:     true,
+			MinLines:        0, // No minimum
+			MaxLines:        0, // No maximum
+			MaxFiles:        0, // No limit
+			MaxChunks:       0, // No limit
+			// Go's "Code generated ... DO NOT EDIT.", Facebook's @generated, .NET's <auto-generated>
+			GeneratedMarkers: []string{"Code generated", "DO NOT EDIT", "@generated", "<auto-generated"},
+			ContentHash:      true,
 		},
 		Search: SearchConfig{
 			MaxResults:        5,
 			SemanticWeight:    0.7,
 			ExactMatchBoost:   1.5,
 			MinScoreThreshold: 0.5,
+			WholeWordMatch:    false, // Substring matching ("log" matches "catalog")
+			ScoringMode:       "normalized",
+			LexicalWeight:     0.3,
+			RerankCandidateMultiplier: 3,
+			MaxCandidates:             100,
+			Reranker:                  "none", // Off by default for latency
+			RerankTopK:                20,
+			ChunkTypeWeights:          map[string]float64{"file": 0.85},
+			PreviewLines:              3,
+			PreviewLineWidth:          80,
+			ResultCacheTTLSeconds:     60,
+			ResultCacheSize:           256,
+			QueryTimeoutMs:            30000,
 		},
 		Embeddings: EmbeddingsConfig{
 			Model:         "nomic-embed-text",
@@ -167,13 +412,23 @@ func DefaultConfig() *Config {
 			ContextLength: 8192,
 			Normalize:     true,
 			UseMRL:        true, // Enable MRL truncation
+			MaxRetries:     3,
+			RetryBackoffMs: 500,
+
+			AutoDetectDimension: true, // Probe the model's dimension at startup
+			KeepAlive:           "10m", // Stay loaded between indexing phases and searches
 		},
 		VectorDB: VectorDBConfig{
 			Type:           "embedded",
 			CollectionName: "code_chunks",
-			DistanceMetric: "cosine",
+			DistanceMetric: DistanceCosine,
 			VectorSize:     256,  // Match MRL dimension
 			OnDiskPayload:  true,
+			ScalarQuantization: false,
+			CollectionPerModel: false,
+			AutoCreateCollection: true,
+			MaxRetries:         3,
+			RetryBackoffMs:     250,
 		},
 		Cache: CacheConfig{
 			Enabled:        true,
@@ -183,6 +438,7 @@ func DefaultConfig() *Config {
 		},
 		Logging: LoggingConfig{
 			Enabled:    true,
+			Level:      "info",
 			Directory:  "~/.semantic-search/logs",
 			MaxSizeMB:  10,
 			MaxBackups: 5,
@@ -218,6 +474,22 @@ func DefaultConfig() *Config {
 				Extensions: []string{".js", ".jsx", ".mjs", ".cjs"},
 				Parser:     "tree-sitter-javascript",
 			},
+			Kotlin: LanguageConfig{
+				Extensions: []string{".kt", ".kts"},
+				Parser:     "tree-sitter-kotlin",
+			},
+			Ruby: LanguageConfig{
+				Extensions: []string{".rb"},
+				Parser:     "tree-sitter-ruby",
+			},
+			PHP: LanguageConfig{
+				Extensions: []string{".php"},
+				Parser:     "tree-sitter-php",
+			},
+			Swift: LanguageConfig{
+				Extensions: []string{".swift"},
+				Parser:     "tree-sitter-swift",
+			},
 		},
 	}
 }
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveCollectionName(t *testing.T) {
+	cfg := DefaultConfig()
+	if got := cfg.ResolveCollectionName(); got != "code_chunks" {
+		t.Errorf("Expected default collection name, got %s", got)
+	}
+
+	cfg.VectorDB.CollectionPerModel = true
+	cfg.VectorDB.VectorSize = 256
+	small := cfg.ResolveCollectionName()
+	if small != "code_chunks_nomic_embed_text_256" {
+		t.Errorf("Unexpected collection name: %s", small)
+	}
+
+	cfg.VectorDB.VectorSize = 768
+	large := cfg.ResolveCollectionName()
+	if large == small {
+		t.Errorf("Expected distinct collections for different dimensions, both got %s", small)
+	}
+
+	cfg.Embeddings.Model = "mxbai-embed-large:latest"
+	if got := cfg.ResolveCollectionName(); got != "code_chunks_mxbai_embed_large_latest_768" {
+		t.Errorf("Unexpected collection name for model with tag: %s", got)
+	}
+}
+
+func TestLoad_KeepsBaseCollectionName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("vectordb:\n  collection_per_model: true\n  vector_size: 256\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("SEMANTIC_SEARCH_CONFIG", path)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.VectorDB.CollectionName != "code_chunks" {
+		t.Errorf("Expected the configured collection name, got %s", cfg.VectorDB.CollectionName)
+	}
+
+	// A vector size changed after loading (e.g. by dimension detection) is used in the name
+	cfg.VectorDB.VectorSize = 768
+	if got := cfg.ResolveCollectionName(); got != "code_chunks_nomic_embed_text_768" {
+		t.Errorf("Expected the collection named for the final vector size, got %s", got)
+	}
+}
+
+func TestTaskPrefixes(t *testing.T) {
+	empty := ""
+	custom := "query: "
+
+	tests := []struct {
+		name           string
+		cfg            EmbeddingsConfig
+		expectQuery    string
+		expectDocument string
+	}{
+		{"nomic defaults", EmbeddingsConfig{Model: "nomic-embed-text"}, NomicQueryPrefix, NomicDocumentPrefix},
+		{"nomic with tag", EmbeddingsConfig{Model: "nomic-embed-text:v1.5"}, NomicQueryPrefix, NomicDocumentPrefix},
+		{"other model has none", EmbeddingsConfig{Model: "all-minilm"}, "", ""},
+		{"explicitly disabled", EmbeddingsConfig{Model: "nomic-embed-text", QueryPrefix: &empty, DocumentPrefix: &empty}, "", ""},
+		{"custom query prefix", EmbeddingsConfig{Model: "nomic-embed-text", QueryPrefix: &custom}, custom, NomicDocumentPrefix},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, document := tt.cfg.TaskPrefixes()
+			if query != tt.expectQuery || document != tt.expectDocument {
+				t.Errorf("Expected prefixes (%q, %q), got (%q, %q)", tt.expectQuery, tt.expectDocument, query, document)
+			}
+		})
+	}
+}
+
+func TestNormalizationWarnings(t *testing.T) {
+	tests := []struct {
+		metric     string
+		normalize  bool
+		expectWarn bool
+	}{
+		{DistanceCosine, true, false},
+		{DistanceCosine, false, false},
+		{"", true, false},
+		{DistanceDot, true, true},
+		{DistanceDot, false, false},
+		{DistanceEuclidean, true, true},
+		{DistanceEuclidean, false, false},
+		{"manhattan", false, true},
+	}
+
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.VectorDB.DistanceMetric = tt.metric
+		cfg.Embeddings.Normalize = tt.normalize
+
+		warnings := cfg.NormalizationWarnings()
+		if (len(warnings) > 0) != tt.expectWarn {
+			t.Errorf("metric %q, normalize %v: expected warning=%v, got %v", tt.metric, tt.normalize, tt.expectWarn, warnings)
+		}
+		if tt.metric != "" && len(warnings) > 0 && !strings.Contains(warnings[0], tt.metric) {
+			t.Errorf("Expected the warning to name the metric %q, got %q", tt.metric, warnings[0])
+		}
+	}
+}
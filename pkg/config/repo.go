@@ -0,0 +1,95 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepoConfigFile is the per-repository config file, merged over the global config
+const RepoConfigFile = ".semantic-search.yaml"
+
+// repoOverrides lists the sections a repository config may override
+// Server, embeddings, vector DB, cache and logging settings are shared by every
+// repository, so setting them in a repository config is an error.
+type repoOverrides struct {
+	Chunking  *ChunkingConfig  `yaml:"chunking"`
+	Indexing  *IndexingConfig  `yaml:"indexing"`
+	Search    *SearchConfig    `yaml:"search"`
+	Ignore    *IgnoreConfig    `yaml:"ignore_patterns"`
+	Languages *LanguagesConfig `yaml:"supported_languages"`
+}
+
+// ForRepo returns the configuration for a repository
+// Fields present in the repository's .semantic-search.yaml replace the global values;
+// everything else keeps the global setting. Returns c itself if the repository has no config file.
+func (c *Config) ForRepo(repoPath string) (*Config, error) {
+	data, err := readRepoConfig(repoPath)
+	if err != nil || data == nil {
+		return c, err
+	}
+
+	merged := *c
+	// Decoding writes into existing maps, so give the copy its own
+	merged.Search.ChunkTypeWeights = maps.Clone(c.Search.ChunkTypeWeights)
+	merged.Chunking.BoundaryPatterns = maps.Clone(c.Chunking.BoundaryPatterns)
+	merged.Chunking.MinChunkLengthByLanguage = maps.Clone(c.Chunking.MinChunkLengthByLanguage)
+	overrides := repoOverrides{
+		Chunking:  &merged.Chunking,
+		Indexing:  &merged.Indexing,
+		Search:    &merged.Search,
+		Ignore:    &merged.Ignore,
+		Languages: &merged.Languages,
+	}
+	if err := decodeRepoConfig(repoPath, data, &overrides); err != nil {
+		return nil, err
+	}
+
+	return &merged, nil
+}
+
+// ForRepo returns the search configuration for a repository, see Config.ForRepo
+func (s *SearchConfig) ForRepo(repoPath string) (*SearchConfig, error) {
+	data, err := readRepoConfig(repoPath)
+	if err != nil || data == nil {
+		return s, err
+	}
+
+	merged := *s
+	merged.ChunkTypeWeights = maps.Clone(s.ChunkTypeWeights)
+	if err := decodeRepoConfig(repoPath, data, &repoOverrides{Search: &merged}); err != nil {
+		return nil, err
+	}
+
+	return &merged, nil
+}
+
+// readRepoConfig reads the repository config file, returning nil if there is none
+func readRepoConfig(repoPath string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(repoPath, RepoConfigFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", RepoConfigFile, err)
+	}
+	return data, nil
+}
+
+// decodeRepoConfig decodes a repository config over the given sections
+// Only keys present in the file are written, so unset fields keep their current values.
+func decodeRepoConfig(repoPath string, data []byte, overrides *repoOverrides) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(overrides); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid %s in %s: %w", RepoConfigFile, repoPath, err)
+	}
+	return nil
+}
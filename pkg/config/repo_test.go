@@ -0,0 +1,167 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeRepoConfig(t *testing.T, content string) string {
+	t.Helper()
+	repoDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoDir, RepoConfigFile), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write repo config: %v", err)
+	}
+	return repoDir
+}
+
+func TestForRepo_Overrides(t *testing.T) {
+	global := DefaultConfig()
+	repoDir := writeRepoConfig(t, `
+chunking:
+  max_chunk_size_bytes: 1500
+search:
+  max_results: 20
+ignore_patterns:
+  patterns: ["fixtures/**"]
+`)
+
+	cfg, err := global.ForRepo(repoDir)
+	if err != nil {
+		t.Fatalf("ForRepo failed: %v", err)
+	}
+
+	if cfg.Chunking.MaxChunkSizeBytes != 1500 {
+		t.Errorf("Expected chunk size override 1500, got %d", cfg.Chunking.MaxChunkSizeBytes)
+	}
+	if cfg.Search.MaxResults != 20 {
+		t.Errorf("Expected max_results override 20, got %d", cfg.Search.MaxResults)
+	}
+	if !reflect.DeepEqual(cfg.Ignore.Patterns, []string{"fixtures/**"}) {
+		t.Errorf("Expected ignore patterns override, got %v", cfg.Ignore.Patterns)
+	}
+
+	// Fields absent from the repo config keep the global values
+	if cfg.Chunking.SmallFileMaxTokens != global.Chunking.SmallFileMaxTokens {
+		t.Errorf("Expected small_file_max_tokens %d from global config, got %d",
+			global.Chunking.SmallFileMaxTokens, cfg.Chunking.SmallFileMaxTokens)
+	}
+	if cfg.Search.SemanticWeight != global.Search.SemanticWeight {
+		t.Errorf("Expected semantic_weight %.2f from global config, got %.2f", global.Search.SemanticWeight, cfg.Search.SemanticWeight)
+	}
+	if cfg.Embeddings.Model != global.Embeddings.Model {
+		t.Errorf("Expected embeddings from global config, got %q", cfg.Embeddings.Model)
+	}
+
+	// The global config itself is untouched
+	defaults := DefaultConfig()
+	if global.Chunking.MaxChunkSizeBytes != defaults.Chunking.MaxChunkSizeBytes || global.Search.MaxResults != defaults.Search.MaxResults {
+		t.Error("Expected the global config to be unchanged")
+	}
+	if !reflect.DeepEqual(global.Ignore.Patterns, defaults.Ignore.Patterns) {
+		t.Error("Expected the global ignore patterns to be unchanged")
+	}
+}
+
+func TestForRepo_NoRepoConfig(t *testing.T) {
+	global := DefaultConfig()
+
+	cfg, err := global.ForRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("ForRepo failed: %v", err)
+	}
+	if cfg != global {
+		t.Error("Expected the global config when the repo has no config file")
+	}
+
+	search, err := global.Search.ForRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("SearchConfig.ForRepo failed: %v", err)
+	}
+	if search != &global.Search {
+		t.Error("Expected the global search config when the repo has no config file")
+	}
+}
+
+func TestForRepo_EmptyFile(t *testing.T) {
+	global := DefaultConfig()
+
+	cfg, err := global.ForRepo(writeRepoConfig(t, ""))
+	if err != nil {
+		t.Fatalf("ForRepo failed: %v", err)
+	}
+	if !reflect.DeepEqual(cfg, global) {
+		t.Error("Expected an empty repo config to change nothing")
+	}
+}
+
+func TestForRepo_SharedSectionsRejected(t *testing.T) {
+	repoDir := writeRepoConfig(t, "embeddings:\n  model: all-minilm\n")
+
+	_, err := DefaultConfig().ForRepo(repoDir)
+	if err == nil || !strings.Contains(err.Error(), RepoConfigFile) {
+		t.Fatalf("Expected an error for overriding a shared section, got %v", err)
+	}
+}
+
+func TestSearchConfigForRepo(t *testing.T) {
+	global := DefaultConfig().Search
+	repoDir := writeRepoConfig(t, "search:\n  whole_word_match: true\nchunking:\n  max_lines: 80\n")
+
+	search, err := global.ForRepo(repoDir)
+	if err != nil {
+		t.Fatalf("ForRepo failed: %v", err)
+	}
+	if !search.WholeWordMatch {
+		t.Error("Expected whole_word_match override")
+	}
+	if search.MaxResults != global.MaxResults {
+		t.Errorf("Expected max_results %d from global config, got %d", global.MaxResults, search.MaxResults)
+	}
+}
+
+func TestForRepo_ChunkTypeWeightsDoNotLeak(t *testing.T) {
+	global := DefaultConfig()
+	repoDir := writeRepoConfig(t, "search:\n  chunk_type_weights:\n    file: 1.0\n    method: 1.2\n")
+
+	cfg, err := global.ForRepo(repoDir)
+	if err != nil {
+		t.Fatalf("ForRepo failed: %v", err)
+	}
+	if cfg.Search.ChunkTypeWeights["file"] != 1.0 || cfg.Search.ChunkTypeWeights["method"] != 1.2 {
+		t.Errorf("Expected repository chunk type weights, got %v", cfg.Search.ChunkTypeWeights)
+	}
+
+	search, err := global.Search.ForRepo(repoDir)
+	if err != nil {
+		t.Fatalf("SearchConfig.ForRepo failed: %v", err)
+	}
+	if search.ChunkTypeWeights["method"] != 1.2 {
+		t.Errorf("Expected repository chunk type weights, got %v", search.ChunkTypeWeights)
+	}
+
+	if !reflect.DeepEqual(global.Search.ChunkTypeWeights, DefaultConfig().Search.ChunkTypeWeights) {
+		t.Errorf("Expected the global chunk type weights to be unchanged, got %v", global.Search.ChunkTypeWeights)
+	}
+}
+
+func TestForRepo_MinChunkLengthByLanguageDoesNotLeak(t *testing.T) {
+	global := DefaultConfig()
+	global.Chunking.MinChunkLengthByLanguage = map[string]MinChunkLength{"java": {Lines: 3}}
+	repoDir := writeRepoConfig(t, "chunking:\n  min_chunk_length:\n    chars: 30\n  min_chunk_length_by_language:\n    typescript:\n      chars: 50\n")
+
+	cfg, err := global.ForRepo(repoDir)
+	if err != nil {
+		t.Fatalf("ForRepo failed: %v", err)
+	}
+	if cfg.Chunking.MinChunkLength.Chars != 30 || cfg.Chunking.MinChunkLengthByLanguage["typescript"].Chars != 50 ||
+		cfg.Chunking.MinChunkLengthByLanguage["java"].Lines != 3 {
+		t.Errorf("Expected the repository minimums merged over the global ones, got %+v and %v",
+			cfg.Chunking.MinChunkLength, cfg.Chunking.MinChunkLengthByLanguage)
+	}
+	if _, ok := global.Chunking.MinChunkLengthByLanguage["typescript"]; ok || global.Chunking.MinChunkLength.Chars != 0 {
+		t.Errorf("Expected the global minimums to be unchanged, got %+v and %v", global.Chunking.MinChunkLength, global.Chunking.MinChunkLengthByLanguage)
+	}
+}
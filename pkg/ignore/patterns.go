@@ -7,7 +7,8 @@ import (
 
 // Matcher matches file paths against ignore patterns
 type Matcher struct {
-	patterns []string
+	patterns        []string
+	caseInsensitive bool
 }
 
 // NewMatcher creates a new pattern matcher
@@ -17,72 +18,104 @@ func NewMatcher(patterns []string) *Matcher {
 	}
 }
 
+// SetCaseInsensitive makes patterns match paths regardless of letter case,
+// as on case-insensitive filesystems (macOS, Windows); matching is case-sensitive by default
+func (m *Matcher) SetCaseInsensitive(caseInsensitive bool) {
+	m.caseInsensitive = caseInsensitive
+}
+
 // ShouldIgnore returns true if the path matches any ignore pattern
 func (m *Matcher) ShouldIgnore(path string) bool {
+	_, ok := m.MatchingPattern(path)
+	return ok
+}
+
+// MatchingPattern returns the first ignore pattern that matches the path
+func (m *Matcher) MatchingPattern(path string) (string, bool) {
 	// Normalize path separators
 	path = filepath.ToSlash(path)
+	if m.caseInsensitive {
+		path = strings.ToLower(path)
+	}
 
 	for _, pattern := range m.patterns {
 		if m.matchPattern(path, pattern) {
-			return true
+			return pattern, true
 		}
 	}
 
-	return false
+	return "", false
 }
 
 // matchPattern checks if a path matches a pattern
+// Patterns follow .gitignore conventions:
+//   - A pattern without a slash, like "*.log" or "Makefile", matches the last element of the
+//     path (a file or directory name) at any depth, never the names of its parent directories
+//   - A single name followed by "/**" or "/", like "node_modules/**", matches a directory of that
+//     name at any depth and everything under it
+//   - Any other pattern with a slash, like "src/gen/**" or "docs/*.md", is anchored to the
+//     repository root; a leading "/" anchors a pattern explicitly, e.g. "/build/**"
+//   - "**" matches zero or more directories; "*", "?" and "[...]" never match across a "/"
+//
+// Parent directories are not checked here: the scanner matches each directory as it walks the
+// tree and skips ignored ones.
 func (m *Matcher) matchPattern(path, pattern string) bool {
 	// Normalize pattern
 	pattern = filepath.ToSlash(pattern)
+	if m.caseInsensitive {
+		pattern = strings.ToLower(pattern)
+	}
 
-	// Handle ** for recursive matching
-	if strings.Contains(pattern, "**") {
-		// Convert ** to * for filepath.Match
-		parts := strings.Split(pattern, "**")
+	// A trailing slash means the directory and its contents
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return false
+	}
 
-		// If pattern is like "node_modules/**", match if path starts with "node_modules/"
-		if len(parts) > 0 && parts[0] != "" {
-			prefix := strings.TrimSuffix(parts[0], "/")
-			if strings.HasPrefix(path, prefix+"/") || path == prefix {
-				return true
-			}
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+
+	if !anchored {
+		switch {
+		case len(patternParts) == 1:
+			// File or directory name at any depth
+			return matchParts(patternParts, pathParts[len(pathParts)-1:])
+		case len(patternParts) == 2 && patternParts[1] == "**":
+			// Directory name at any depth, with everything under it
+			patternParts = append([]string{"**"}, patternParts...)
 		}
+	}
+
+	return matchParts(patternParts, pathParts)
+}
 
-		// If pattern is like "**/target/**", match if path contains "/target/"
-		for _, part := range parts {
-			if part != "" && part != "/" {
-				part = strings.Trim(part, "/")
-				if strings.Contains(path, "/"+part+"/") || strings.HasPrefix(path, part+"/") || strings.HasSuffix(path, "/"+part) {
+// matchParts matches path elements against pattern elements, where a "**" element
+// matches zero or more path elements
+func matchParts(patternParts, pathParts []string) bool {
+	for len(patternParts) > 0 {
+		if patternParts[0] == "**" {
+			for i := 0; i <= len(pathParts); i++ {
+				if matchParts(patternParts[1:], pathParts[i:]) {
 					return true
 				}
 			}
+			return false
 		}
-	}
 
-	// Try exact match first
-	matched, err := filepath.Match(pattern, path)
-	if err == nil && matched {
-		return true
-	}
-
-	// Try matching just the filename
-	filename := filepath.Base(path)
-	matched, err = filepath.Match(pattern, filename)
-	if err == nil && matched {
-		return true
-	}
-
-	// Check if any parent directory matches
-	dir := filepath.Dir(path)
-	for dir != "." && dir != "/" {
-		if filepath.Base(dir) == strings.TrimSuffix(pattern, "/**") {
-			return true
+		if len(pathParts) == 0 {
+			return false
+		}
+		if matched, err := filepath.Match(patternParts[0], pathParts[0]); err != nil || !matched {
+			return false
 		}
-		dir = filepath.Dir(dir)
+		patternParts, pathParts = patternParts[1:], pathParts[1:]
 	}
 
-	return false
+	return len(pathParts) == 0
 }
 
 // DefaultPatterns returns the default ignore patterns
@@ -0,0 +1,100 @@
+package ignore
+
+import "testing"
+
+func TestMatcher_Semantics(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		match   bool
+	}{
+		// Names without a slash match the last path element at any depth
+		{"file glob at root", "*.log", "debug.log", true},
+		{"file glob nested", "*.log", "logs/app/debug.log", true},
+		{"file glob does not match parent directory", "*.log", "archive.log/Main.java", false},
+		{"file glob is not a substring match", "*.log", "debug.log.java", false},
+		{"exact file name", "Makefile", "tools/Makefile", true},
+		{"directory name itself", "vendor", "src/vendor", true},
+		{"glob does not cross slash", "*.java", "src/Main.java", true},
+
+		// A single name with /** or / is a directory at any depth
+		{"directory at root", "node_modules/**", "node_modules/lib/index.js", true},
+		{"directory nested", "node_modules/**", "web/node_modules/lib/index.js", true},
+		{"directory path itself", "node_modules/**", "web/node_modules", true},
+		{"directory prefix is not a match", "build/**", "builder/Main.java", false},
+		{"directory name as file suffix is not a match", "build/**", "src/build.gradle", false},
+		{"trailing slash", "target/", "module/target/classes/A.class", true},
+		{"trailing slash does not match prefix", "target/", "targets/A.java", false},
+
+		// Other patterns with a slash are anchored to the repository root
+		{"anchored prefix", "src/gen/**", "src/gen/Model.java", true},
+		{"anchored prefix nested elsewhere", "src/gen/**", "lib/src/gen/Model.java", false},
+		{"anchored file glob", "docs/*.md", "docs/intro.md", true},
+		{"anchored file glob too deep", "docs/*.md", "docs/api/intro.md", false},
+		{"leading slash anchors a directory", "/build/**", "build/out.js", true},
+		{"leading slash directory nested", "/build/**", "app/build/out.js", false},
+		{"leading slash anchors a name", "/Main.java", "Main.java", true},
+		{"leading slash name nested", "/Main.java", "src/Main.java", false},
+
+		// ** matches zero or more directories
+		{"leading ** at root", "**/*.min.js", "app.min.js", true},
+		{"leading ** nested", "**/*.min.js", "static/js/app.min.js", true},
+		{"leading ** wrong suffix", "**/*.min.js", "static/js/app.js", false},
+		{"** in the middle, zero dirs", "src/**/test/*.java", "src/test/A.java", true},
+		{"** in the middle, several dirs", "src/**/test/*.java", "src/a/b/test/A.java", true},
+		{"** in the middle, not anchored elsewhere", "src/**/test/*.java", "lib/src/test/A.java", false},
+		{"surrounding **", "**/target/**", "a/b/target/c/D.class", true},
+		{"surrounding ** partial name", "**/target/**", "a/targets/D.class", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher := NewMatcher([]string{tt.pattern})
+			if got := matcher.ShouldIgnore(tt.path); got != tt.match {
+				t.Errorf("Pattern %q on %q: expected %v, got %v", tt.pattern, tt.path, tt.match, got)
+			}
+		})
+	}
+}
+
+func TestMatcher_CaseSensitivity(t *testing.T) {
+	tests := []struct {
+		pattern          string
+		path             string
+		sensitiveMatch   bool
+		insensitiveMatch bool
+	}{
+		{"NODE_MODULES/**", "node_modules/lodash/index.js", false, true},
+		{"node_modules/**", "Node_Modules/lodash/index.js", false, true},
+		{"node_modules/**", "node_modules/lodash/index.js", true, true},
+		{"*.iml", "Project.IML", false, true},
+		{"**/*.min.js", "static/App.MIN.JS", false, true},
+		{"**/Target/**", "module/target/classes/A.java", false, true},
+		{"build/**", "src/Builder.java", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			matcher := NewMatcher([]string{tt.pattern})
+			if got := matcher.ShouldIgnore(tt.path); got != tt.sensitiveMatch {
+				t.Errorf("Case-sensitive: expected %v, got %v", tt.sensitiveMatch, got)
+			}
+
+			matcher.SetCaseInsensitive(true)
+			if got := matcher.ShouldIgnore(tt.path); got != tt.insensitiveMatch {
+				t.Errorf("Case-insensitive: expected %v, got %v", tt.insensitiveMatch, got)
+			}
+		})
+	}
+}
+
+func TestMatcher_CaseInsensitiveReportsOriginalPattern(t *testing.T) {
+	matcher := NewMatcher([]string{"NODE_MODULES/**"})
+	matcher.SetCaseInsensitive(true)
+
+	pattern, ok := matcher.MatchingPattern("node_modules/a.js")
+	if !ok || pattern != "NODE_MODULES/**" {
+		t.Errorf("Expected the pattern as configured, got %q (%v)", pattern, ok)
+	}
+}
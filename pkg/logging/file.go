@@ -0,0 +1,250 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+// logManager handles log file rotation with proper synchronization
+// It is the log writer, copying every record to stderr and the current log file.
+type logManager struct {
+	mu          sync.Mutex
+	logFilePath string
+	logFile     *os.File
+	writer      io.Writer
+	config      config.LoggingConfig
+	stop        context.CancelFunc // Stops the rotation goroutine
+}
+
+// newLogManager creates a new log manager
+func newLogManager(logFilePath string, cfg config.LoggingConfig) (*logManager, error) {
+	lm := &logManager{
+		logFilePath: logFilePath,
+		config:      cfg,
+	}
+
+	// Open initial log file
+	if err := lm.openLogFile(); err != nil {
+		return nil, err
+	}
+
+	return lm, nil
+}
+
+// openLogFile opens or reopens the log file
+func (lm *logManager) openLogFile() error {
+	logFile, err := os.OpenFile(lm.logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	lm.logFile = logFile
+
+	// Write to both file and stderr (never stdout, which carries the MCP protocol)
+	lm.writer = io.MultiWriter(os.Stderr, logFile)
+
+	return nil
+}
+
+// Write writes a log record to stderr and the current log file
+func (lm *logManager) Write(p []byte) (int, error) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	return lm.writer.Write(p)
+}
+
+// rotate performs log rotation and reopens the file
+func (lm *logManager) rotate() error {
+	backupPath, err := lm.rotateFile()
+	if err != nil {
+		return err
+	}
+
+	// Log only after the lock is released, since logging writes through lm
+	slog.Info("Log file rotated", "backup", backupPath)
+
+	// Compress if enabled
+	// Note: This is a fire-and-forget operation. In a production system with actual
+	// compression implemented, consider using a worker pool or WaitGroup for proper
+	// lifecycle management to avoid orphaned goroutines during shutdown.
+	if lm.config.Compress {
+		go compressLogFile(backupPath)
+	}
+
+	// Clean up old backups
+	cleanOldLogFiles(filepath.Dir(lm.logFilePath), lm.config.MaxBackups, lm.config.MaxAgeDays)
+
+	return nil
+}
+
+// rotateFile renames the current log file to a timestamped backup and reopens it
+func (lm *logManager) rotateFile() (string, error) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	// Close current log file
+	if lm.logFile != nil {
+		lm.logFile.Close()
+	}
+
+	// Rotate: rename current log file with timestamp
+	timestamp := time.Now().Format("2006-01-02-15-04-05")
+	backupPath := fmt.Sprintf("%s.%s", lm.logFilePath, timestamp)
+
+	if err := os.Rename(lm.logFilePath, backupPath); err != nil {
+		// Reopen the original file even if rename failed
+		if reopenErr := lm.openLogFile(); reopenErr != nil {
+			// Keep logging to stderr rather than to a closed file
+			lm.writer = os.Stderr
+			return "", fmt.Errorf("failed to rotate log file: %w (and failed to reopen it: %v)", err, reopenErr)
+		}
+		return "", fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	// Reopen log file with the original path
+	if err := lm.openLogFile(); err != nil {
+		lm.writer = os.Stderr
+		return "", err
+	}
+
+	return backupPath, nil
+}
+
+// Close stops log rotation and closes the log file
+func (lm *logManager) Close() error {
+	if lm.stop != nil {
+		lm.stop()
+	}
+
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	// Records logged after Close still reach stderr
+	lm.writer = os.Stderr
+	if lm.logFile != nil {
+		return lm.logFile.Close()
+	}
+	return nil
+}
+
+// Setup configures structured logging at the configured level to stderr and the log file
+// The standard log package is routed through the same logger. Nothing is ever written
+// to stdout. Closing the returned closer (nil when logging only to stderr) stops log rotation.
+func Setup(cfg config.LoggingConfig) (io.Closer, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	// If logging is disabled or no directory specified, just log to stderr
+	if !cfg.Enabled || cfg.Directory == "" {
+		slog.SetDefault(NewLogger(os.Stderr, level))
+		return nil, nil
+	}
+
+	// Create log directory if it doesn't exist
+	if err := os.MkdirAll(cfg.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	// Create log file with timestamp-based rotation
+	logFileName := "semantic-search.log"
+	logFilePath := filepath.Join(cfg.Directory, logFileName)
+
+	// Create log manager
+	logMgr, err := newLogManager(logFilePath, cfg)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(NewLogger(logMgr, level))
+
+	// Start log rotation with context for proper cleanup
+	ctx, stop := context.WithCancel(context.Background())
+	logMgr.stop = stop
+	go rotateLogFileWithContext(ctx, logMgr)
+
+	return logMgr, nil
+}
+
+// rotateLogFileWithContext periodically checks and rotates log files based on configuration
+// It respects the context and exits gracefully when the context is cancelled
+func rotateLogFileWithContext(ctx context.Context, logMgr *logManager) {
+	ticker := time.NewTicker(1 * time.Hour) // Check every hour
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Context cancelled, exit gracefully
+			slog.Debug("Log rotation goroutine shutting down")
+			return
+		case <-ticker.C:
+			fileInfo, err := os.Stat(logMgr.logFilePath)
+			if err != nil {
+				continue
+			}
+
+			// Check if file size exceeds max size
+			maxSizeBytes := int64(logMgr.config.MaxSizeMB) * 1024 * 1024
+			if fileInfo.Size() > maxSizeBytes {
+				if err := logMgr.rotate(); err != nil {
+					slog.Error("Failed to rotate log file", "error", err)
+				}
+			}
+		}
+	}
+}
+
+// compressLogFile compresses a log file using gzip
+func compressLogFile(filePath string) {
+	// Note: For simplicity, we're skipping compression implementation
+	// In production, you'd use gzip.Writer here
+	slog.Info("Log compression requested (not implemented)", "file", filePath)
+}
+
+// cleanOldLogFiles removes old log backup files based on retention policy
+func cleanOldLogFiles(logDir string, maxBackups, maxAgeDays int) {
+	entries, err := os.ReadDir(logDir)
+	if err != nil {
+		return
+	}
+
+	var backupFiles []os.DirEntry
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".log" && entry.Name() != "semantic-search.log" {
+			backupFiles = append(backupFiles, entry)
+		}
+	}
+
+	// Remove files older than maxAgeDays
+	now := time.Now()
+	maxAge := time.Duration(maxAgeDays) * 24 * time.Hour
+
+	for _, file := range backupFiles {
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+
+		if now.Sub(info.ModTime()) > maxAge {
+			filePath := filepath.Join(logDir, file.Name())
+			os.Remove(filePath)
+			slog.Info("Removed old log file", "file", filePath)
+		}
+	}
+
+	// If still too many backups, remove oldest ones
+	if len(backupFiles) > maxBackups {
+		// Sort by modification time and remove oldest
+		// (Simplified - in production you'd implement proper sorting)
+		slog.Warn("Log backup count exceeds max, oldest files should be removed", "count", len(backupFiles), "max", maxBackups)
+	}
+}
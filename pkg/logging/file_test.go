@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jamaly87/codebase-semantic-search/pkg/config"
+)
+
+func TestSetup_LogsToFileNeverStdout(t *testing.T) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	realStdout := os.Stdout
+	os.Stdout = stdoutWriter
+	defer func() { os.Stdout = realStdout }()
+
+	logDir := t.TempDir()
+	closer, err := Setup(config.LoggingConfig{Enabled: true, Directory: logDir, Level: "info"})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	slog.Info("structured record", "job", "job-1")
+	slog.Debug("debug record")
+	log.Printf("standard log record")
+
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	stdoutWriter.Close()
+
+	stdout, _ := io.ReadAll(stdoutReader)
+	if len(stdout) > 0 {
+		t.Errorf("Expected nothing on stdout, got: %s", stdout)
+	}
+
+	data, err := os.ReadFile(filepath.Join(logDir, "semantic-search.log"))
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	logged := string(data)
+	for _, want := range []string{"structured record", "job=job-1", "standard log record"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("Expected log file to contain %q, got: %s", want, logged)
+		}
+	}
+	if strings.Contains(logged, "debug record") {
+		t.Errorf("Expected debug record to be suppressed at info level, got: %s", logged)
+	}
+}
+
+func TestSetup_InvalidLevel(t *testing.T) {
+	if _, err := Setup(config.LoggingConfig{Level: "loud"}); err == nil {
+		t.Error("Expected error for invalid log level")
+	}
+}
@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  slog.Level
+		expectErr bool
+	}{
+		{"", slog.LevelInfo, false},
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warning", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", slog.LevelInfo, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, err := ParseLevel(tt.name)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Expected error=%v, got %v", tt.expectErr, err)
+			}
+			if level != tt.expected {
+				t.Errorf("Expected level %v, got %v", tt.expected, level)
+			}
+		})
+	}
+}
+
+func TestNewLogger_SuppressesDebugAtInfo(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, slog.LevelInfo)
+
+	logger.Debug("Progress", "job", "job-1", "files", 10)
+	logger.Info("Indexing completed successfully", "job", "job-1")
+
+	output := buf.String()
+	if strings.Contains(output, "Progress") {
+		t.Errorf("Expected debug log to be suppressed at info level, got: %s", output)
+	}
+	if !strings.Contains(output, "Indexing completed successfully") || !strings.Contains(output, "job=job-1") {
+		t.Errorf("Expected info log with structured attributes, got: %s", output)
+	}
+}
+
+func TestNewLogger_DebugLevelIncludesDebug(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, slog.LevelDebug)
+
+	logger.Debug("Progress", "files", 10)
+
+	if !strings.Contains(buf.String(), "Progress") {
+		t.Errorf("Expected debug log at debug level, got: %s", buf.String())
+	}
+}